@@ -0,0 +1,252 @@
+// Package anonymize extracts structurally faithful but anonymized samples of inputs, e.g. to attach to
+// bug reports or to build test fixtures from production files, given an omni.2.1 schema annotated with
+// the `sensitive_fields` of its records.
+package anonymize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/anonymize/fake"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// Options are the options of Extract.
+type Options struct {
+	// Every keeps every Every-th record, starting from the first one. 0 is the same as 1, i.e. keeping
+	// all the records.
+	Every int
+	// Max is the max number of records kept. 0 is unlimited.
+	Max int
+	// Seed seeds the fakes; see fake.Mask.
+	Seed int64
+	// Ctx is the context of the transform reading the input, e.g. with its external properties. It's
+	// copied, with RawRecordOnly on.
+	Ctx *transformctx.Ctx
+}
+
+// Stats are the stats of an Extract.
+type Stats struct {
+	// Records is the number of records read from the input.
+	Records int
+	// Kept is the number of records written, anonymized, into the sample.
+	Kept int
+	// Values is the number of distinct sensitive values replaced in the records kept.
+	Values int
+}
+
+type sensitiveFields struct {
+	SensitiveFields []string `json:"sensitive_fields"`
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Extract writes into w an anonymized sample of the input, of the given name, of an omni.2.1 schema: the
+// records kept, as per Options.Every and Options.Max, with the values of their `sensitive_fields`
+// replaced by their fake.Masks, and the rest of the input, e.g. the envelopes, headers and trailers around the
+// records, as is. The records not kept are dropped, thus counts in trailers, if any, aren't adjusted.
+//
+// The records are located in the input by their byte offsets, or else by their raw bytes, as reported by
+// the readers (see schemahandler.RecordOffsetter and schemahandler.RawBytesRecord), and the values by
+// their text, as is or escaped for XML or JSON. Extract fails, rather than leak any value unmasked, if a
+// record or a sensitive value can't be located, e.g. a CSV field with escaped quotes or an EDI element
+// with release characters, or if the input fails to read. The input must be in UTF-8.
+func Extract(schema omniparser.Schema, name string, input []byte, w io.Writer, opts Options) (Stats, error) {
+	var stats Stats
+	var fields sensitiveFields
+	if err := json.Unmarshal(schema.Content(), &fields); err != nil {
+		return stats, err
+	}
+	if len(fields.SensitiveFields) == 0 {
+		return stats, errors.New("schema has no 'sensitive_fields'")
+	}
+	if _, ok := schema.Header().ParserSettings.UTF8Content(input); !ok {
+		return stats, fmt.Errorf("input '%s' isn't in UTF-8", name)
+	}
+	ctx := transformctx.Ctx{}
+	if opts.Ctx != nil {
+		ctx = *opts.Ctx
+	}
+	ctx.RawRecordOnly = true
+	if bytes.HasPrefix(input, utf8BOM) {
+		// the readers' offsets are the ones after the BOM.
+		if _, err := w.Write(utf8BOM); err != nil {
+			return stats, err
+		}
+		input = input[len(utf8BOM):]
+	}
+	tfm, err := schema.NewTransform(name, bytes.NewReader(input), &ctx)
+	if err != nil {
+		return stats, err
+	}
+	every := opts.Every
+	if every <= 0 {
+		every = 1
+	}
+	values := map[string]bool{}
+	cursor := 0
+	for {
+		if _, err := tfm.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, err
+		}
+		stats.Records++
+		rawRecord, err := tfm.RawRecord()
+		if err != nil {
+			return stats, err
+		}
+		begin, end, err := locate(rawRecord, input, cursor)
+		if err != nil {
+			return stats, fmt.Errorf("record %d: %s", stats.Records, err.Error())
+		}
+		if _, err := w.Write(input[cursor:begin]); err != nil {
+			return stats, err
+		}
+		cursor = end
+		if (stats.Records-1)%every != 0 || (opts.Max > 0 && stats.Kept >= opts.Max) {
+			continue
+		}
+		anonymized, err := anonymizeRecord(rawRecord, input[begin:end], fields.SensitiveFields, opts.Seed, values)
+		if err != nil {
+			return stats, fmt.Errorf("record %d: %s", stats.Records, err.Error())
+		}
+		if _, err := w.Write(anonymized); err != nil {
+			return stats, err
+		}
+		stats.Kept++
+	}
+	_, err = w.Write(input[cursor:])
+	stats.Values = len(values)
+	return stats, err
+}
+
+// locate returns the range of bytes of the raw record in the input, at or after the cursor.
+func locate(rawRecord schemahandler.RawRecord, input []byte, cursor int) (int, int, error) {
+	if o, ok := rawRecord.(schemahandler.RecordOffsetter); ok {
+		if begin, end, ok := o.RecordOffsets(); ok && int64(cursor) <= begin && begin <= end && end <= int64(len(input)) {
+			return int(begin), int(end), nil
+		}
+	}
+	if rb, ok := rawRecord.(schemahandler.RawBytesRecord); ok && len(rb.RawBytes()) > 0 {
+		raw := rb.RawBytes()
+		// readers may report the lines of a record with '\n' line endings regardless of the input's, and
+		// with the last line terminated even if the input's isn't.
+		for _, candidate := range [][]byte{
+			raw,
+			bytes.ReplaceAll(raw, []byte("\n"), []byte("\r\n")),
+			bytes.TrimSuffix(raw, []byte("\n")),
+			bytes.TrimSuffix(bytes.ReplaceAll(raw, []byte("\n"), []byte("\r\n")), []byte("\r\n")),
+		} {
+			if i := bytes.Index(input[cursor:], candidate); i >= 0 && len(candidate) > 0 {
+				return cursor + i, cursor + i + len(candidate), nil
+			}
+		}
+	}
+	return 0, 0, errors.New("unable to locate the record in the input")
+}
+
+// anonymizeRecord returns a copy of the bytes of the raw record with the values of the sensitive fields
+// replaced by their masks, adding the values replaced to values.
+func anonymizeRecord(rawRecord schemahandler.RawRecord, raw []byte, xpaths []string, seed int64,
+	values map[string]bool) ([]byte, error) {
+	n, ok := rawRecord.Raw().(*idr.Node)
+	if !ok {
+		return nil, errors.New("raw record isn't an IDR node")
+	}
+	var texts []string
+	seen := map[string]bool{}
+	for _, xpath := range xpaths {
+		matches, err := idr.MatchAll(n, xpath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensitive field xpath '%s': %s", xpath, err.Error())
+		}
+		for _, m := range matches {
+			if text := m.InnerText(); strings.TrimSpace(text) != "" && !seen[text] {
+				seen[text] = true
+				texts = append(texts, text)
+			}
+		}
+	}
+	// replaces the longer values first, so the values containing others are replaced as a whole.
+	sort.SliceStable(texts, func(i, j int) bool { return len(texts[i]) > len(texts[j]) })
+	out := append([]byte(nil), raw...)
+	for _, text := range texts {
+		masked := fake.Mask(text, seed)
+		replaced := false
+		for _, escape := range escapes {
+			from, to := escape(text), escape(masked)
+			// checks the record as is, as the value may be part of a longer value already replaced.
+			if bytes.Contains(raw, from) {
+				out = replace(out, from, to)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			return nil, fmt.Errorf("unable to locate the value of a sensitive field in the record")
+		}
+		values[text] = true
+	}
+	return out, nil
+}
+
+// escapes are the forms a value may take in the raw bytes of a record, tried in order.
+var escapes = []func(string) []byte{
+	func(s string) []byte { return []byte(s) },
+	func(s string) []byte { return []byte(xmlEscaper.Replace(s)) },
+	func(s string) []byte {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(s)
+		// strips the quotes and the trailing '\n'.
+		return buf.Bytes()[1 : buf.Len()-2]
+	},
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// replace replaces the occurrences of from in b bounded on both sides, e.g. not part of a longer word,
+// nor of an XML name or a JSON key; or, if there are none, all the occurrences.
+func replace(b, from, to []byte) []byte {
+	var bounded []int
+	for i := 0; ; {
+		j := bytes.Index(b[i:], from)
+		if j < 0 {
+			break
+		}
+		begin, end := i+j, i+j+len(from)
+		if (begin == 0 || !nameByte(b[begin-1]) && b[begin-1] != '<' && b[begin-1] != '/' && b[begin-1] != '&') &&
+			(end == len(b) || !nameByte(b[end]) && b[end] != '=' && !bytes.HasPrefix(b[end:], []byte(`":`))) {
+			bounded = append(bounded, begin)
+		}
+		i = begin + 1
+	}
+	if len(bounded) == 0 {
+		return bytes.ReplaceAll(b, from, to)
+	}
+	var out []byte
+	last := 0
+	for _, begin := range bounded {
+		if begin < last {
+			// overlaps the previous occurrence replaced.
+			continue
+		}
+		out = append(append(out, b[last:begin]...), to...)
+		last = begin + len(from)
+	}
+	return append(out, b[last:]...)
+}
+
+func nameByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
@@ -0,0 +1,141 @@
+package anonymize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/logward/omniparser"
+)
+
+const xmlSchema = `{
+	"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+	"sensitive_fields": [ "name", "card", "@id" ],
+	"transform_declarations": {
+		"FINAL_OUTPUT": { "xpath": "/customers/customer", "object": {
+			"name": { "xpath": "name" },
+			"card": { "xpath": "card" }
+		}}
+	}
+}`
+
+const xmlInput = `<?xml version="1.0"?>
+<customers region="EU">
+	<customer id="C-0012"><name>Jane Doe &amp; Co</name><card>4111-1111-1111-1111</card><tier>gold</tier></customer>
+	<customer id="C-0034"><name>John Roe</name><card>5500-0000-0000-0004</card><tier>silver</tier></customer>
+	<customer id="C-0012"><name>Jane Doe &amp; Co</name><card>4111-1111-1111-1111</card><tier>gold</tier></customer>
+</customers>
+`
+
+const csvSchema = `{
+	"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+	"file_declaration": {
+		"delimiter": ",",
+		"records": [
+			{ "header": "^NAME,PHONE,CITY$", "min": 1, "max": 1 },
+			{ "is_target": true, "columns": [ { "name": "NAME" }, { "name": "PHONE" }, { "name": "CITY" } ] }
+		]
+	},
+	"sensitive_fields": [ "NAME", "PHONE" ],
+	"transform_declarations": {
+		"FINAL_OUTPUT": { "object": { "name": { "xpath": "NAME" } } }
+	}
+}`
+
+func newSchema(t *testing.T, content string) omniparser.Schema {
+	schema, err := omniparser.NewSchema("test-schema", strings.NewReader(content))
+	require.NoError(t, err)
+	return schema
+}
+
+func TestExtract_XML(t *testing.T) {
+	var out bytes.Buffer
+	stats, err := Extract(newSchema(t, xmlSchema), "test-input", []byte(xmlInput), &out, Options{Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Records: 3, Kept: 3, Values: 6}, stats)
+	sample := out.String()
+	for _, value := range []string{"Jane", "Doe", "John", "4111", "5500", "C-0012", "C-0034"} {
+		assert.NotContains(t, sample, value)
+	}
+	for _, value := range []string{`<customers region="EU">`, "<tier>gold</tier>", "<tier>silver</tier>", "&amp;"} {
+		assert.Contains(t, sample, value)
+	}
+	lines := strings.Split(sample, "\n")
+	require.Len(t, lines, 7)
+	// the same values have the same fakes.
+	assert.Equal(t, lines[2], lines[4])
+	assert.Equal(t, len(strings.Split(xmlInput, "\n")[3]), len(lines[3]))
+
+	// the sample is of the same structure as the input.
+	stats, err = Extract(newSchema(t, xmlSchema), "test-sample", out.Bytes(), &bytes.Buffer{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Records)
+}
+
+func TestExtract_CSV_EveryAndMax(t *testing.T) {
+	for _, eol := range []string{"\n", "\r\n"} {
+		input := strings.Join([]string{
+			"NAME,PHONE,CITY", "Ann,555-0100,Oslo", "Bob,555-0101,Rome", "Cid,555-0102,Lima", "Dee,555-0103,Nice",
+		}, eol)
+		var out bytes.Buffer
+		stats, err := Extract(newSchema(t, csvSchema), "test-input", []byte(input), &out,
+			Options{Every: 2, Max: 1, Seed: 1})
+		require.NoError(t, err)
+		assert.Equal(t, Stats{Records: 4, Kept: 1, Values: 2}, stats)
+		lines := strings.Split(out.String(), eol)
+		require.Len(t, lines, 3)
+		assert.Equal(t, "NAME,PHONE,CITY", lines[0])
+		assert.Regexp(t, `^[A-Z][a-z]{2},[0-9]{3}-[0-9]{4},Oslo$`, lines[1])
+		assert.NotEqual(t, "Ann,555-0100,Oslo", lines[1])
+		assert.Equal(t, "", lines[2])
+	}
+}
+
+func TestExtract_BOM(t *testing.T) {
+	var out bytes.Buffer
+	_, err := Extract(newSchema(t, csvSchema), "test-input",
+		[]byte("\xEF\xBB\xBFNAME,PHONE,CITY\nAnn,555-0100,Oslo\n"), &out, Options{})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out.Bytes(), utf8BOM))
+	assert.NotContains(t, out.String(), "Ann")
+}
+
+func TestExtract_Failures(t *testing.T) {
+	_, err := Extract(newSchema(t, strings.Replace(csvSchema, `"sensitive_fields": [ "NAME", "PHONE" ],`, "", 1)),
+		"test-input", []byte("NAME,PHONE,CITY\n"), &bytes.Buffer{}, Options{})
+	assert.EqualError(t, err, "schema has no 'sensitive_fields'")
+
+	// the escaped quote is in the input, but not in the value.
+	var out bytes.Buffer
+	_, err = Extract(newSchema(t, csvSchema), "test-input",
+		[]byte("NAME,PHONE,CITY\n\"A\"\"nn\",555-0100,Oslo\n"), &out, Options{})
+	assert.EqualError(t, err, "record 1: unable to locate the value of a sensitive field in the record")
+	assert.NotContains(t, out.String(), "nn")
+
+	// the header line is missing.
+	_, err = Extract(newSchema(t, csvSchema), "test-input", []byte("Ann,555-0100,Oslo\n"), &bytes.Buffer{}, Options{})
+	assert.Error(t, err)
+}
+
+func TestReplace(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		b        string
+		from, to string
+		expected string
+	}{
+		{name: "bounded", b: "<name>name</name>", from: "name", to: "abcd", expected: "<name>abcd</name>"},
+		{name: "attribute name", b: `<a id="id"/>`, from: "id", to: "xy", expected: `<a id="xy"/>`},
+		{name: "json key", b: `{"city":"city"}`, from: "city", to: "abcd", expected: `{"city":"abcd"}`},
+		{name: "not a word", b: "Ann,Annie", from: "Ann", to: "Xyz", expected: "Xyz,Annie"},
+		{name: "unbounded only", b: "ID123", from: "123", to: "456", expected: "ID456"},
+		{name: "none", b: "abc", from: "x", to: "y", expected: "abc"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, string(replace([]byte(test.b), []byte(test.from), []byte(test.to))))
+		})
+	}
+}
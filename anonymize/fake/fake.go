@@ -0,0 +1,92 @@
+// Package fake generates fakes of sensitive values, e.g. names and addresses, for anonymizing inputs
+// and generating test data. The fakes are derived from the values and a seed only: the same values have
+// the same fakes with the same seed, so the joins across records are preserved, and the values can't be
+// told from their fakes without the seed.
+package fake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// newValueRand returns a source of pseudo-random values derived from a value and a seed only.
+func newValueRand(value string, seed int64) *rand.Rand {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(seed))
+	_, _ = h.Write(b[:])
+	_, _ = h.Write([]byte(value))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// Mask returns a format-preserving fake of a value: each digit is replaced by a random digit, each
+// letter by a random ASCII letter of the same case, and any other character, e.g. a space, '-' or '@',
+// is kept, so the fake has the same shape and number of characters as the value, e.g. "AB-1234" may
+// become "QZ-8071".
+func Mask(value string, seed int64) string {
+	r := newValueRand(value, seed)
+	var sb strings.Builder
+	for _, c := range value {
+		switch {
+		case unicode.IsDigit(c):
+			sb.WriteByte(byte('0' + r.Intn(10)))
+		case unicode.IsUpper(c):
+			sb.WriteByte(byte('A' + r.Intn(26)))
+		case unicode.IsLetter(c):
+			sb.WriteByte(byte('a' + r.Intn(26)))
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+var (
+	firstNames = []string{
+		"Alex", "Avery", "Bailey", "Cameron", "Casey", "Charlie", "Dakota", "Drew", "Emerson", "Finley",
+		"Harper", "Hayden", "Jamie", "Jordan", "Kai", "Logan", "Morgan", "Parker", "Quinn", "Reese",
+		"Riley", "Rowan", "Sage", "Skyler", "Taylor",
+	}
+	lastNames = []string{
+		"Abbott", "Barker", "Carver", "Dalton", "Ellis", "Fletcher", "Garner", "Hollis", "Ingram", "Jensen",
+		"Keller", "Lambert", "Mercer", "Norris", "Oakley", "Porter", "Quincy", "Ramsey", "Sutton", "Tanner",
+		"Underwood", "Vance", "Walsh", "Yates", "Zimmer",
+	}
+	streetNames = []string{
+		"Ash", "Birch", "Cedar", "Chestnut", "Elm", "Hickory", "Juniper", "Laurel", "Maple", "Oak", "Pine",
+		"Poplar", "Spruce", "Sycamore", "Walnut", "Willow",
+	}
+	streetSuffixes = []string{"Ave", "Blvd", "Ct", "Dr", "Ln", "Pl", "Rd", "St", "Way"}
+)
+
+// Name returns a fake person name of a value, e.g. "Jordan Mercer", in upper case if the value is, or
+// "" if the value is.
+func Name(value string, seed int64) string {
+	if value == "" {
+		return ""
+	}
+	r := newValueRand(value, seed)
+	return matchCase(value, firstNames[r.Intn(len(firstNames))]+" "+lastNames[r.Intn(len(lastNames))])
+}
+
+// Address returns a fake street address of a value, e.g. "4821 Maple Ave", in upper case if the value
+// is, or "" if the value is.
+func Address(value string, seed int64) string {
+	if value == "" {
+		return ""
+	}
+	r := newValueRand(value, seed)
+	return matchCase(value, fmt.Sprintf("%d %s %s",
+		1+r.Intn(9999), streetNames[r.Intn(len(streetNames))], streetSuffixes[r.Intn(len(streetSuffixes))]))
+}
+
+func matchCase(value, fake string) string {
+	if strings.ToUpper(value) == value && strings.ToLower(value) != value {
+		return strings.ToUpper(fake)
+	}
+	return fake
+}
@@ -0,0 +1,33 @@
+package fake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMask(t *testing.T) {
+	masked := Mask("AB-1234 cd@x.io", 7)
+	assert.Regexp(t, `^[A-Z]{2}-[0-9]{4} [a-z]{2}@[a-z]\.[a-z]{2}$`, masked)
+	assert.NotEqual(t, "AB-1234 cd@x.io", masked)
+	assert.Equal(t, masked, Mask("AB-1234 cd@x.io", 7))
+	assert.NotEqual(t, masked, Mask("AB-1234 cd@x.io", 8))
+	assert.Equal(t, "", Mask("", 7))
+}
+
+func TestName(t *testing.T) {
+	name := Name("Jane Doe", 7)
+	assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, name)
+	assert.Equal(t, name, Name("Jane Doe", 7))
+	assert.Regexp(t, `^[A-Z]+ [A-Z]+$`, Name("JANE DOE", 7))
+	assert.Equal(t, "", Name("", 7))
+}
+
+func TestAddress(t *testing.T) {
+	address := Address("1 Main St", 7)
+	assert.Regexp(t, `^[0-9]{1,4} [A-Z][a-z]+ [A-Z][a-z]+$`, address)
+	assert.Equal(t, address, Address("1 Main St", 7))
+	assert.NotEqual(t, address, Address("2 Main St", 7))
+	assert.Regexp(t, `^[0-9]{1,4} [A-Z]+ [A-Z]+$`, Address("1 MAIN ST", 7))
+	assert.Equal(t, "", Address("", 7))
+}
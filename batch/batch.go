@@ -0,0 +1,176 @@
+// Package batch transforms many inputs against one schema in parallel, with a pool of workers,
+// isolating the inputs from one another: a failure, or even a panic, of one input doesn't affect
+// the others.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// Input is one input of a batch, either a file at Path or a Reader.
+type Input struct {
+	// Name is the name of the input. Defaults to Path if empty.
+	Name string
+	// Path is the path of the input file, opened and closed by Run. Ignored if Reader is set.
+	Path string
+	// Reader is the input stream. If it's an io.Closer, it's closed by Run once done.
+	Reader io.Reader
+	// Ctx is the transform context of the input. Defaults to an empty one if nil. Each input must have
+	// its own Ctx, as a Ctx can't be shared across transforms running in parallel.
+	Ctx *transformctx.Ctx
+	// Tenant is the tenant the input belongs to, e.g. the customer of a multi-tenant service. The inputs
+	// are handed out to the workers round-robin across the tenants, so a tenant with many inputs doesn't
+	// hold up the others, and within the quotas of the tenants (see Options.TenantQuota). The inputs
+	// without Tenant make up one tenant.
+	Tenant string
+}
+
+func (in Input) name() string {
+	if in.Name != "" {
+		return in.Name
+	}
+	return in.Path
+}
+
+// Options contains the options of Run.
+type Options struct {
+	// Workers is the number of inputs transformed in parallel. Defaults to runtime.NumCPU() if not
+	// positive.
+	Workers int
+	// OnRecord, if set, is called with each record transformed successfully from an input. It's called
+	// from the workers concurrently, so it must be goroutine-safe; the records of one input are, however,
+	// passed in order. A non-nil error returned stops the transform of the input with the error.
+	OnRecord func(in Input, record []byte) error
+	// TenantQuota, if positive, is the max number of inputs of the same tenant (see Input.Tenant)
+	// transformed in parallel, so a tenant with many or huge inputs can't take up all the workers.
+	TenantQuota int
+	// TenantQuotas overrides TenantQuota for the tenants in it, where a non-positive quota means no
+	// limit.
+	TenantQuotas map[string]int
+}
+
+func (opts Options) quota(tenant string) int {
+	quota, found := opts.TenantQuotas[tenant]
+	if !found {
+		quota = opts.TenantQuota
+	}
+	if quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+// Result is the outcome of the transform of one input.
+type Result struct {
+	// Name is the name of the input.
+	Name string
+	// Records is the number of records transformed successfully.
+	Records int
+	// Errors contains the continuable errors, i.e. records skipped.
+	Errors []error
+	// Err is the fatal error the transform of the input stopped with, if any, e.g. the input can't be
+	// opened, the transform panicked, or the batch is canceled before the input is transformed.
+	Err error
+}
+
+// Failed tells if the input isn't transformed completely, or any of its records is skipped.
+func (r Result) Failed() bool {
+	return r.Err != nil || len(r.Errors) > 0
+}
+
+// Run transforms the inputs against the schema in parallel, and returns their results, in the order of
+// the inputs. Once ctx is done, inputs not transformed yet fail with ctx.Err(), and the ones being
+// transformed stop after their current records.
+func Run(ctx context.Context, schema omniparser.Schema, inputs []Input, opts Options) []Result {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	results := make([]Result, len(inputs))
+	s := newScheduler(inputs, opts)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i, tenant, ok := s.take()
+				if !ok {
+					return
+				}
+				// runOne fails right away with ctx.Err() if ctx is done.
+				results[i] = runOne(ctx, schema, inputs[i], opts)
+				s.release(tenant)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func runOne(ctx context.Context, schema omniparser.Schema, in Input, opts Options) (result Result) {
+	result.Name = in.name()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	reader := in.Reader
+	if reader == nil {
+		f, err := os.Open(in.Path)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		reader = f
+	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	tctx := in.Ctx
+	if tctx == nil {
+		tctx = &transformctx.Ctx{}
+	}
+	transform, err := schema.NewTransform(result.Name, reader, tctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return result
+		}
+		record, err := transform.Read()
+		switch {
+		case err == io.EOF:
+			return result
+		case errs.IsErrTransformFailed(err):
+			result.Errors = append(result.Errors, err)
+			continue
+		case err != nil:
+			result.Err = err
+			return result
+		}
+		result.Records++
+		if opts.OnRecord != nil {
+			if err := opts.OnRecord(in, record); err != nil {
+				result.Err = err
+				return result
+			}
+		}
+	}
+}
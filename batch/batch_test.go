@@ -0,0 +1,183 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func newTestSchema(t *testing.T) omniparser.Schema {
+	s, err := omniparser.NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"c": { "custom_func": { "name": "dateTimeToRFC3339", "args": [ { "xpath": "c" }, { "const": "" }, { "const": "" } ] } }
+			} }
+		}
+	}`))
+	assert.NoError(t, err)
+	return s
+}
+
+type testCloser struct {
+	io.Reader
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) { panic("boom") }
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.xml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("<a><b><c>2020-01-05</c></b></a>"), 0644))
+
+	closer := &testCloser{Reader: strings.NewReader("<a><b><c>2020-01-04</c></b></a>")}
+	ctx := &transformctx.Ctx{}
+	inputs := []Input{
+		{Name: "good", Reader: strings.NewReader("<a><b><c>2020-01-01</c></b><b><c>2020-01-02</c></b></a>")},
+		{Name: "skipped", Reader: strings.NewReader("<a><b><c>bad</c></b><b><c>2020-01-03</c></b></a>")},
+		{Name: "closer", Reader: closer, Ctx: ctx},
+		{Path: path},
+		{Path: filepath.Join(dir, "missing.xml")},
+		{Name: "not xml", Reader: strings.NewReader("<a>")},
+		{Name: "panic", Reader: panicReader{}},
+	}
+	var mu sync.Mutex
+	var records []string
+	results := Run(context.Background(), newTestSchema(t), inputs, Options{
+		Workers: 3,
+		OnRecord: func(in Input, record []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, in.name()+":"+string(record))
+			return nil
+		},
+	})
+	assert.Equal(t, len(inputs), len(results))
+
+	assert.Equal(t, Result{Name: "good", Records: 2}, results[0])
+	assert.False(t, results[0].Failed())
+
+	assert.Equal(t, "skipped", results[1].Name)
+	assert.Equal(t, 1, results[1].Records)
+	assert.Equal(t, 1, len(results[1].Errors))
+	assert.NoError(t, results[1].Err)
+	assert.True(t, results[1].Failed())
+
+	assert.Equal(t, Result{Name: "closer", Records: 1}, results[2])
+	assert.True(t, closer.closed)
+	assert.Equal(t, "closer", ctx.InputName)
+
+	assert.Equal(t, Result{Name: path, Records: 1}, results[3])
+
+	assert.Equal(t, filepath.Join(dir, "missing.xml"), results[4].Name)
+	assert.True(t, os.IsNotExist(results[4].Err))
+
+	assert.Equal(t, "not xml", results[5].Name)
+	assert.Error(t, results[5].Err)
+	assert.True(t, results[5].Failed())
+
+	assert.Equal(t, "panic", results[6].Name)
+	assert.Error(t, results[6].Err)
+	assert.Equal(t, "panic: boom", results[6].Err.Error())
+
+	sort.Strings(records)
+	assert.Equal(t, []string{
+		path + `:{"c":"2020-01-05T00:00:00"}`,
+		`closer:{"c":"2020-01-04T00:00:00"}`,
+		`good:{"c":"2020-01-01T00:00:00"}`,
+		`good:{"c":"2020-01-02T00:00:00"}`,
+		`skipped:{"c":"2020-01-03T00:00:00"}`,
+	}, records)
+}
+
+func TestRun_OnRecordError(t *testing.T) {
+	results := Run(context.Background(), newTestSchema(t), []Input{
+		{Name: "in", Reader: strings.NewReader("<a><b><c>2020-01-01</c></b><b><c>2020-01-02</c></b></a>")},
+	}, Options{OnRecord: func(Input, []byte) error { return errors.New("sink failure") }})
+	assert.Equal(t, []Result{{Name: "in", Records: 1, Err: errors.New("sink failure")}}, results)
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := Run(ctx, newTestSchema(t), []Input{
+		{Name: "in1", Reader: strings.NewReader("<a><b><c>2020-01-01</c></b></a>")},
+		{Name: "in2", Reader: strings.NewReader("<a><b><c>2020-01-02</c></b></a>")},
+	}, Options{Workers: 1})
+	assert.Equal(t, []Result{{Name: "in1", Err: context.Canceled}, {Name: "in2", Err: context.Canceled}}, results)
+}
+
+func TestRun_CanceledMidway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	results := Run(ctx, newTestSchema(t), []Input{
+		{Name: "in", Reader: strings.NewReader("<a><b><c>2020-01-01</c></b><b><c>2020-01-02</c></b></a>")},
+	}, Options{OnRecord: func(Input, []byte) error {
+		cancel()
+		return nil
+	}})
+	assert.Equal(t, []Result{{Name: "in", Records: 1, Err: context.Canceled}}, results)
+}
+
+func TestRun_TenantQuota(t *testing.T) {
+	var inputs []Input
+	for i := 0; i < 6; i++ {
+		inputs = append(inputs, Input{
+			Name:   fmt.Sprintf("big%d", i),
+			Reader: strings.NewReader("<a><b><c>2020-01-01</c></b></a>"),
+			Tenant: "big",
+		})
+	}
+	inputs = append(inputs, Input{Name: "small", Reader: strings.NewReader("<a><b><c>2020-01-01</c></b></a>")})
+	var mu sync.Mutex
+	running, maxRunning := map[string]int{}, map[string]int{}
+	results := Run(context.Background(), newTestSchema(t), inputs, Options{
+		Workers:     4,
+		TenantQuota: 2,
+		OnRecord: func(in Input, _ []byte) error {
+			mu.Lock()
+			running[in.Tenant]++
+			if running[in.Tenant] > maxRunning[in.Tenant] {
+				maxRunning[in.Tenant] = running[in.Tenant]
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running[in.Tenant]--
+			mu.Unlock()
+			return nil
+		},
+	})
+	for i, result := range results {
+		assert.Equal(t, Result{Name: inputs[i].Name, Records: 1}, result)
+	}
+	assert.True(t, maxRunning["big"] <= 2)
+	assert.Equal(t, 1, maxRunning[""])
+}
+
+func TestRun_NoInputs(t *testing.T) {
+	assert.Equal(t, []Result{}, Run(context.Background(), newTestSchema(t), nil, Options{}))
+}
@@ -0,0 +1,70 @@
+package batch
+
+import "sync"
+
+// tenantQueue contains the inputs of a tenant waiting to be transformed.
+type tenantQueue struct {
+	quota   int // 0 means unlimited.
+	indexes []int
+	running int
+}
+
+// scheduler hands the inputs out to the workers, round-robin across the tenants, in the order they
+// first appear in the inputs, and in the order of the inputs within a tenant, so that a tenant with many
+// inputs doesn't hold up the others, and within the quotas of the tenants.
+type scheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tenants []*tenantQueue
+	next    int // the tenant to start the next round-robin from.
+	pending int
+}
+
+func newScheduler(inputs []Input, opts Options) *scheduler {
+	s := &scheduler{pending: len(inputs)}
+	s.cond = sync.NewCond(&s.mu)
+	byTenant := map[string]*tenantQueue{}
+	for i, in := range inputs {
+		t, found := byTenant[in.Tenant]
+		if !found {
+			t = &tenantQueue{quota: opts.quota(in.Tenant)}
+			byTenant[in.Tenant] = t
+			s.tenants = append(s.tenants, t)
+		}
+		t.indexes = append(t.indexes, i)
+	}
+	return s
+}
+
+// take returns the index of the next input to transform, and the queue of its tenant, to be released
+// once the input is transformed. It blocks while the tenants of all the inputs pending are at their
+// quotas, and returns false once no input is pending.
+func (s *scheduler) take() (int, *tenantQueue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.pending > 0 {
+		for i := range s.tenants {
+			t := s.tenants[(s.next+i)%len(s.tenants)]
+			if len(t.indexes) == 0 || (t.quota > 0 && t.running >= t.quota) {
+				continue
+			}
+			s.next = (s.next + i + 1) % len(s.tenants)
+			index := t.indexes[0]
+			t.indexes = t.indexes[1:]
+			t.running++
+			s.pending--
+			return index, t, true
+		}
+		s.cond.Wait()
+	}
+	return 0, nil, false
+}
+
+// release tells an input of the tenant is transformed, making room for its other inputs, if at its
+// quota.
+func (s *scheduler) release(t *tenantQueue) {
+	s.mu.Lock()
+	t.running--
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
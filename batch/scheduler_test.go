@@ -0,0 +1,58 @@
+package batch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_RoundRobin(t *testing.T) {
+	s := newScheduler([]Input{
+		{Tenant: "a"}, {Tenant: "a"}, {Tenant: "b"}, {Tenant: "a"}, {Tenant: "c"}, {Tenant: "c"},
+	}, Options{})
+	var order []int
+	for {
+		i, _, ok := s.take()
+		if !ok {
+			break
+		}
+		order = append(order, i)
+	}
+	assert.Equal(t, []int{0, 2, 4, 1, 5, 3}, order)
+}
+
+func TestScheduler_Quotas(t *testing.T) {
+	s := newScheduler([]Input{
+		{Tenant: "a"}, {Tenant: "a"}, {Tenant: "a"}, {Tenant: "b"}, {Tenant: "b"}, {},
+	}, Options{TenantQuota: 1, TenantQuotas: map[string]int{"b": 0}})
+	take := func() int {
+		i, _, ok := s.take()
+		assert.True(t, ok)
+		return i
+	}
+	assert.Equal(t, 0, take())
+	assert.Equal(t, 3, take())
+	assert.Equal(t, 5, take())
+	// "a" is at its quota, "b" has none.
+	i, tenantB, ok := s.take()
+	assert.True(t, ok)
+	assert.Equal(t, 4, i)
+
+	taken := make(chan int)
+	go func() {
+		i, _, _ := s.take()
+		taken <- i
+	}()
+	// only "a" has inputs pending, but is at its quota until 0 is released.
+	s.release(tenantB)
+	select {
+	case i := <-taken:
+		assert.Fail(t, "take should have been blocked", "took %d", i)
+	default:
+	}
+	s.mu.Lock()
+	tenantA := s.tenants[0]
+	s.mu.Unlock()
+	s.release(tenantA)
+	assert.Equal(t, 1, <-taken)
+}
@@ -0,0 +1,238 @@
+// Package calendar contains business calendars, i.e. the weekends, holidays and business hours of e.g. a
+// carrier or a warehouse, for computing business days and SLA deadlines.
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of the holidays of a Calendar in JSON.
+const DateLayout = "2006-01-02"
+
+// maxDaysScanned bounds the search for the next business day, so that a calendar without any business
+// day (e.g. all days are weekend days) can't loop forever.
+const maxDaysScanned = 366 * 10
+
+var errNoBusinessDay = errors.New("no business day found")
+
+// BusinessHours are the daily business hours of a Calendar, in "HH:MM" (24-hour clock).
+type BusinessHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Calendar is a business calendar.
+type Calendar struct {
+	// Name is the name the calendar is referenced by, e.g. "us_carrier".
+	Name string `json:"name"`
+	// TimeZone is the IANA time zone of the calendar, e.g. "America/New_York". Default to UTC.
+	TimeZone string `json:"timezone,omitempty"`
+	// Weekend are the English names of the weekdays that aren't business days, e.g. ["friday",
+	// "saturday"]. Default to Saturday and Sunday.
+	Weekend []string `json:"weekend,omitempty"`
+	// Holidays are the dates, in DateLayout, that aren't business days.
+	Holidays []string `json:"holidays,omitempty"`
+	// BusinessHours, if set, are the hours of a business day that count towards a business duration.
+	// Otherwise, business days count entirely.
+	BusinessHours *BusinessHours `json:"business_hours,omitempty"`
+
+	loc        *time.Location
+	weekend    map[time.Weekday]bool
+	holidays   map[string]bool
+	start, end time.Duration // business hours, as offsets from the beginning of a day.
+}
+
+var weekdays = func() map[string]time.Weekday {
+	m := map[string]time.Weekday{}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		m[strings.ToLower(d.String())] = d
+	}
+	return m
+}()
+
+func parseHHMM(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		// "24:00" is a valid end of business hours.
+		if s == "24:00" {
+			return 24 * time.Hour, nil
+		}
+		return 0, fmt.Errorf("invalid time '%s'", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (c *Calendar) validate() error {
+	if c.Name == "" {
+		return errors.New("'name' must not be empty")
+	}
+	var err error
+	if c.loc, err = time.LoadLocation(c.TimeZone); err != nil {
+		return fmt.Errorf("invalid 'timezone' '%s'", c.TimeZone)
+	}
+	c.weekend = map[time.Weekday]bool{}
+	weekend := c.Weekend
+	if weekend == nil {
+		weekend = []string{"saturday", "sunday"}
+	}
+	for _, name := range weekend {
+		d, found := weekdays[strings.ToLower(name)]
+		if !found {
+			return fmt.Errorf("invalid 'weekend' day '%s'", name)
+		}
+		c.weekend[d] = true
+	}
+	c.holidays = map[string]bool{}
+	for _, h := range c.Holidays {
+		if _, err := time.Parse(DateLayout, h); err != nil {
+			return fmt.Errorf("invalid holiday '%s'", h)
+		}
+		c.holidays[h] = true
+	}
+	c.start, c.end = 0, 24*time.Hour
+	if c.BusinessHours != nil {
+		if c.start, err = parseHHMM(c.BusinessHours.Start); err != nil {
+			return fmt.Errorf("invalid 'business_hours.start': %s", err.Error())
+		}
+		if c.end, err = parseHHMM(c.BusinessHours.End); err != nil {
+			return fmt.Errorf("invalid 'business_hours.end': %s", err.Error())
+		}
+		if c.end <= c.start {
+			return fmt.Errorf("'business_hours.end' '%s' must be after 'business_hours.start' '%s'",
+				c.BusinessHours.End, c.BusinessHours.Start)
+		}
+	}
+	return nil
+}
+
+// Location returns the time zone of the calendar.
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// IsBusinessDay tells if the day of t, in the calendar's time zone, is a business day, i.e. neither a
+// weekend day nor a holiday.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(c.loc)
+	return !c.weekend[t.Weekday()] && !c.holidays[t.Format(DateLayout)]
+}
+
+// AddBusinessDays adds n business days to t, in the calendar's time zone, keeping the time of day, e.g.
+// adding 1 business day to a Friday returns the next Monday, if it's a business day. A negative n
+// subtracts business days. If n is 0 and t isn't on a business day, t is moved forward to the next
+// business day.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) (time.Time, error) {
+	t = t.In(c.loc)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	scanned := 0
+	for !c.IsBusinessDay(t) {
+		if t, scanned = t.AddDate(0, 0, step), scanned+1; scanned > maxDaysScanned {
+			return time.Time{}, errNoBusinessDay
+		}
+	}
+	for ; n > 0; n-- {
+		t = t.AddDate(0, 0, step)
+		for !c.IsBusinessDay(t) {
+			if t, scanned = t.AddDate(0, 0, step), scanned+1; scanned > maxDaysScanned {
+				return time.Time{}, errNoBusinessDay
+			}
+		}
+	}
+	return t, nil
+}
+
+// AddBusinessDuration adds a business duration to t, i.e. only the time within the business hours of
+// business days counts, and returns the resulting time in the calendar's time zone. E.g. with business
+// hours of 09:00 to 17:00, adding 4 hours to 15:00 on a Friday returns 11:00 on the next Monday, if it's
+// a business day. It's typically used to compute an SLA deadline from an event timestamp.
+func (c *Calendar) AddBusinessDuration(t time.Time, d time.Duration) (time.Time, error) {
+	if d < 0 {
+		return time.Time{}, fmt.Errorf("negative duration '%s'", d)
+	}
+	t = t.In(c.loc)
+	for scanned := 0; ; scanned++ {
+		if scanned > maxDaysScanned {
+			return time.Time{}, errNoBusinessDay
+		}
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+		if c.IsBusinessDay(day) {
+			start, end := day.Add(c.start), day.Add(c.end)
+			if t.Before(start) {
+				t = start
+			}
+			if t.Before(end) {
+				available := end.Sub(t)
+				if d <= available {
+					return t.Add(d), nil
+				}
+				d -= available
+			}
+		}
+		t = day.AddDate(0, 0, 1)
+	}
+}
+
+// Registry contains named calendars.
+type Registry struct {
+	calendars map[string]*Calendar
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{calendars: map[string]*Calendar{}}
+}
+
+// Load reads in and validates a JSON array of Calendar, and adds them into the registry.
+func (r *Registry) Load(reader io.Reader) error {
+	var calendars []*Calendar
+	if err := json.NewDecoder(reader).Decode(&calendars); err != nil {
+		return err
+	}
+	for i, c := range calendars {
+		if err := r.Add(c); err != nil {
+			return fmt.Errorf("calendar[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// Add validates and adds a Calendar into the registry, replacing the one of the same name, if any.
+func (r *Registry) Add(c *Calendar) error {
+	if c == nil {
+		return errors.New("calendar is empty")
+	}
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("calendar '%s': %s", c.Name, err.Error())
+	}
+	r.calendars[c.Name] = c
+	return nil
+}
+
+// Get returns the calendar of a given name.
+func (r *Registry) Get(name string) (*Calendar, error) {
+	c, found := r.calendars[name]
+	if !found {
+		return nil, fmt.Errorf("calendar '%s' not found", name)
+	}
+	return c, nil
+}
+
+// Default returns the default calendar, used when no calendar is named: in UTC, with Saturday and
+// Sunday as the weekend, no holidays, and business days counting entirely.
+func Default() *Calendar {
+	return defaultCalendar
+}
+
+var defaultCalendar = func() *Calendar {
+	c := &Calendar{Name: "default"}
+	_ = c.validate()
+	return c
+}()
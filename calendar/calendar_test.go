@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCalendar(t *testing.T) *Calendar {
+	r := NewRegistry()
+	assert.NoError(t, r.Load(strings.NewReader(`[
+		{
+			"name": "us_carrier",
+			"timezone": "America/New_York",
+			"holidays": [ "2024-01-15" ],
+			"business_hours": { "start": "09:00", "end": "17:00" }
+		}
+	]`)))
+	c, err := r.Get("us_carrier")
+	assert.NoError(t, err)
+	return c
+}
+
+func TestRegistry_Load(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		json string
+		err  string
+	}{
+		{name: "invalid json", json: `{`, err: "unexpected EOF"},
+		{name: "empty name", json: `[{}]`, err: "calendar[0]: calendar '': 'name' must not be empty"},
+		{
+			name: "invalid timezone", json: `[{"name": "a", "timezone": "Mars/Base"}]`,
+			err: "calendar[0]: calendar 'a': invalid 'timezone' 'Mars/Base'",
+		},
+		{
+			name: "invalid weekend", json: `[{"name": "a"}, {"name": "b", "weekend": ["funday"]}]`,
+			err: "calendar[1]: calendar 'b': invalid 'weekend' day 'funday'",
+		},
+		{
+			name: "invalid holiday", json: `[{"name": "a", "holidays": ["2024/01/01"]}]`,
+			err: "calendar[0]: calendar 'a': invalid holiday '2024/01/01'",
+		},
+		{
+			name: "invalid business hours", json: `[{"name": "a", "business_hours": {"start": "9am", "end": "17:00"}}]`,
+			err: "calendar[0]: calendar 'a': invalid 'business_hours.start': invalid time '9am'",
+		},
+		{
+			name: "business hours end before start", json: `[{"name": "a", "business_hours": {"start": "17:00", "end": "09:00"}}]`,
+			err: "calendar[0]: calendar 'a': 'business_hours.end' '09:00' must be after 'business_hours.start' '17:00'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewRegistry().Load(strings.NewReader(test.json))
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry()
+	assert.Error(t, r.Add(nil))
+	assert.NoError(t, r.Add(&Calendar{Name: "a", Weekend: []string{"Friday", "Saturday"}}))
+	c, err := r.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, c.Location())
+	assert.True(t, c.IsBusinessDay(time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)))  // Sunday
+	assert.False(t, c.IsBusinessDay(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC))) // Friday
+	_, err = r.Get("b")
+	assert.Error(t, err)
+	assert.Equal(t, "calendar 'b' not found", err.Error())
+}
+
+func TestCalendar_IsBusinessDay(t *testing.T) {
+	c := testCalendar(t)
+	assert.True(t, c.IsBusinessDay(time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)))  // Friday
+	assert.False(t, c.IsBusinessDay(time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC))) // Saturday
+	assert.False(t, c.IsBusinessDay(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	// 2024-01-06 02:00 UTC is still Friday in New York.
+	assert.True(t, c.IsBusinessDay(time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC)))
+	assert.False(t, Default().IsBusinessDay(time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC)))
+}
+
+func TestCalendar_AddBusinessDays(t *testing.T) {
+	c := testCalendar(t)
+	ny := c.Location()
+	for _, test := range []struct {
+		name     string
+		t        time.Time
+		n        int
+		expected time.Time
+	}{
+		{name: "over weekend", t: time.Date(2024, 1, 5, 10, 30, 0, 0, ny), n: 1, expected: time.Date(2024, 1, 8, 10, 30, 0, 0, ny)},
+		{name: "over holiday", t: time.Date(2024, 1, 12, 8, 0, 0, 0, ny), n: 1, expected: time.Date(2024, 1, 16, 8, 0, 0, 0, ny)},
+		{name: "multiple", t: time.Date(2024, 1, 10, 0, 0, 0, 0, ny), n: 5, expected: time.Date(2024, 1, 18, 0, 0, 0, 0, ny)},
+		{name: "zero on business day", t: time.Date(2024, 1, 10, 0, 0, 0, 0, ny), n: 0, expected: time.Date(2024, 1, 10, 0, 0, 0, 0, ny)},
+		{name: "zero on weekend", t: time.Date(2024, 1, 6, 0, 0, 0, 0, ny), n: 0, expected: time.Date(2024, 1, 8, 0, 0, 0, 0, ny)},
+		{name: "from weekend", t: time.Date(2024, 1, 6, 0, 0, 0, 0, ny), n: 1, expected: time.Date(2024, 1, 9, 0, 0, 0, 0, ny)},
+		{name: "negative", t: time.Date(2024, 1, 16, 0, 0, 0, 0, ny), n: -1, expected: time.Date(2024, 1, 12, 0, 0, 0, 0, ny)},
+		{name: "negative from weekend", t: time.Date(2024, 1, 7, 0, 0, 0, 0, ny), n: -1, expected: time.Date(2024, 1, 4, 0, 0, 0, 0, ny)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := c.AddBusinessDays(test.t, test.n)
+			assert.NoError(t, err)
+			assert.True(t, test.expected.Equal(result), result.String())
+		})
+	}
+}
+
+func TestCalendar_AddBusinessDays_NoBusinessDay(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.Add(&Calendar{
+		Name: "never", Weekend: []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}}))
+	c, _ := r.Get("never")
+	_, err := c.AddBusinessDays(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 1)
+	assert.Equal(t, errNoBusinessDay, err)
+	_, err = c.AddBusinessDuration(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), time.Hour)
+	assert.Equal(t, errNoBusinessDay, err)
+}
+
+func TestCalendar_AddBusinessDuration(t *testing.T) {
+	c := testCalendar(t)
+	ny := c.Location()
+	for _, test := range []struct {
+		name     string
+		t        time.Time
+		d        time.Duration
+		expected time.Time
+	}{
+		{name: "same day", t: time.Date(2024, 1, 10, 10, 0, 0, 0, ny), d: 4 * time.Hour, expected: time.Date(2024, 1, 10, 14, 0, 0, 0, ny)},
+		{name: "end of day", t: time.Date(2024, 1, 10, 13, 0, 0, 0, ny), d: 4 * time.Hour, expected: time.Date(2024, 1, 10, 17, 0, 0, 0, ny)},
+		{name: "before hours", t: time.Date(2024, 1, 10, 6, 0, 0, 0, ny), d: time.Hour, expected: time.Date(2024, 1, 10, 10, 0, 0, 0, ny)},
+		{name: "after hours", t: time.Date(2024, 1, 10, 20, 0, 0, 0, ny), d: time.Hour, expected: time.Date(2024, 1, 11, 10, 0, 0, 0, ny)},
+		{name: "over weekend", t: time.Date(2024, 1, 5, 15, 0, 0, 0, ny), d: 4 * time.Hour, expected: time.Date(2024, 1, 8, 11, 0, 0, 0, ny)},
+		{name: "over holiday", t: time.Date(2024, 1, 12, 16, 0, 0, 0, ny), d: 2 * time.Hour, expected: time.Date(2024, 1, 16, 10, 0, 0, 0, ny)},
+		{name: "multiple days", t: time.Date(2024, 1, 9, 9, 0, 0, 0, ny), d: 20 * time.Hour, expected: time.Date(2024, 1, 11, 13, 0, 0, 0, ny)},
+		{name: "zero", t: time.Date(2024, 1, 6, 12, 0, 0, 0, ny), expected: time.Date(2024, 1, 8, 9, 0, 0, 0, ny)},
+		{
+			name: "default calendar counts whole days", t: time.Date(2024, 1, 5, 20, 0, 0, 0, time.UTC), d: 8 * time.Hour,
+			expected: time.Date(2024, 1, 8, 4, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cal := c
+			if test.t.Location() == time.UTC {
+				cal = Default()
+			}
+			result, err := cal.AddBusinessDuration(test.t, test.d)
+			assert.NoError(t, err)
+			assert.True(t, test.expected.Equal(result), result.String())
+		})
+	}
+	_, err := c.AddBusinessDuration(time.Now(), -time.Hour)
+	assert.Error(t, err)
+	assert.Equal(t, "negative duration '-1h0m0s'", err.Error())
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/logward/omniparser"
+	omniout "github.com/logward/omniparser/output"
 	"github.com/logward/omniparser/transformctx"
 )
 
@@ -31,6 +32,7 @@ var (
 	}
 	schema string
 	input  string
+	output string
 	stream bool
 )
 
@@ -40,6 +42,8 @@ func init() {
 
 	transformCmd.Flags().StringVarP(
 		&input, "input", "i", "", "input file (optional; if not specified, stdin/pipe is used)")
+	transformCmd.Flags().StringVarP(
+		&output, "output", "o", "", "output file (optional; if not specified, stdout is used). It's only created, or replaced, if the transform succeeds")
 	transformCmd.Flags().BoolVarP(
 		&stream, "stream", "", false, "if specified, each record will be a standalone/full JSON blob and printed out immediately once transform is done")
 }
@@ -84,6 +88,21 @@ func doTransform() error {
 		return err
 	}
 
+	if strs.IsStrNonBlank(output) {
+		sink, err := omniout.NewStagedFile(output)
+		if err != nil {
+			return err
+		}
+		if err = writeRecords(transform, sink); err != nil {
+			_ = sink.Abort()
+			return err
+		}
+		return sink.Commit()
+	}
+	return writeRecords(transform, os.Stdout)
+}
+
+func writeRecords(transform omniparser.Transform, out io.Writer) error {
 	doOne := func() (string, error) {
 		b, err := transform.Read()
 		if err != nil {
@@ -105,7 +124,7 @@ func doTransform() error {
 	record, err := doOne()
 	if err == io.EOF {
 		if !stream {
-			fmt.Println("[]")
+			fmt.Fprintln(out, "[]")
 		}
 		return nil
 	}
@@ -122,7 +141,7 @@ func doTransform() error {
 		rparen = ""
 	}
 
-	fmt.Printf(lparen, record)
+	fmt.Fprintf(out, lparen, record)
 	for {
 		record, err = doOne()
 		if err == io.EOF {
@@ -131,8 +150,8 @@ func doTransform() error {
 		if err != nil {
 			return err
 		}
-		fmt.Printf(delim, record)
+		fmt.Fprintf(out, delim, record)
 	}
-	fmt.Println(rparen)
-	return nil
+	_, err = fmt.Fprintln(out, rparen)
+	return err
 }
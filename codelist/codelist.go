@@ -0,0 +1,132 @@
+package codelist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// DateLayout is the layout of the effective dates of a List in JSON.
+const DateLayout = "2006-01-02"
+
+// List is a set of allowed codes, e.g. an X12 qualifier code list, of a particular version of a
+// standard, effective during a particular period of time.
+type List struct {
+	// Name is the name the list is referenced by, e.g. "x12.entity_identifier_code".
+	Name string `json:"name"`
+	// Version is the version of the standard the list belongs to, e.g. "004010" or "005010". An empty
+	// Version matches all versions, and is only used when no list of the exact version is effective.
+	Version string `json:"version,omitempty"`
+	// EffectiveFrom is the first day the list is effective, in DateLayout. Empty means since forever.
+	EffectiveFrom string `json:"effective_from,omitempty"`
+	// EffectiveTo is the last day the list is effective, in DateLayout. Empty means until forever.
+	EffectiveTo string `json:"effective_to,omitempty"`
+	// Codes maps the allowed codes to their descriptions.
+	Codes map[string]string `json:"codes"`
+
+	from, to time.Time
+}
+
+// Contains tells if a code is allowed by the list.
+func (l *List) Contains(code string) bool {
+	_, found := l.Codes[code]
+	return found
+}
+
+// effectiveAt tells if the list is effective on the day of t. A zero t matches all lists.
+func (l *List) effectiveAt(t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return (l.from.IsZero() || !day.Before(l.from)) && (l.to.IsZero() || !day.After(l.to))
+}
+
+func (l *List) validate() error {
+	if l.Name == "" {
+		return errors.New("'name' must not be empty")
+	}
+	var err error
+	if l.EffectiveFrom != "" {
+		if l.from, err = time.Parse(DateLayout, l.EffectiveFrom); err != nil {
+			return fmt.Errorf("invalid 'effective_from' '%s'", l.EffectiveFrom)
+		}
+	}
+	if l.EffectiveTo != "" {
+		if l.to, err = time.Parse(DateLayout, l.EffectiveTo); err != nil {
+			return fmt.Errorf("invalid 'effective_to' '%s'", l.EffectiveTo)
+		}
+	}
+	if !l.from.IsZero() && !l.to.IsZero() && l.to.Before(l.from) {
+		return fmt.Errorf("'effective_to' '%s' is before 'effective_from' '%s'", l.EffectiveTo, l.EffectiveFrom)
+	}
+	return nil
+}
+
+// Registry contains the code lists of all versions and effective periods, from which the one in effect
+// for a document is selected by its version and date.
+type Registry struct {
+	lists map[string][]*List
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{lists: map[string][]*List{}}
+}
+
+// Load reads in and validates a JSON array of List, and adds them into the registry.
+func (r *Registry) Load(reader io.Reader) error {
+	var lists []*List
+	if err := json.NewDecoder(reader).Decode(&lists); err != nil {
+		return err
+	}
+	for i, l := range lists {
+		if err := r.Add(l); err != nil {
+			return fmt.Errorf("code list[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// Add validates and adds a List into the registry.
+func (r *Registry) Add(l *List) error {
+	if l == nil {
+		return errors.New("code list is empty")
+	}
+	if err := l.validate(); err != nil {
+		return fmt.Errorf("code list '%s': %s", l.Name, err.Error())
+	}
+	r.lists[l.Name] = append(r.lists[l.Name], l)
+	return nil
+}
+
+// Select returns the list of a given name, in effect for a document of a given version on a given date.
+// A list of the exact version is preferred over the one of an empty version; among the lists effective
+// on the date, the one effective the latest is preferred. A zero date matches lists of all effective
+// periods.
+func (r *Registry) Select(name, version string, date time.Time) (*List, error) {
+	var candidates []*List
+	for _, l := range r.lists[name] {
+		if (l.Version == version || l.Version == "") && l.effectiveAt(date) {
+			candidates = append(candidates, l)
+		}
+	}
+	if len(candidates) == 0 {
+		if _, found := r.lists[name]; !found {
+			return nil, fmt.Errorf("code list '%s' not found", name)
+		}
+		return nil, fmt.Errorf("code list '%s' of version '%s' not effective on %s",
+			name, version, date.Format(DateLayout))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if (ci.Version == "") != (cj.Version == "") {
+			return ci.Version != ""
+		}
+		return ci.from.After(cj.from)
+	})
+	return candidates[0], nil
+}
@@ -0,0 +1,105 @@
+package codelist
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLists = `[
+	{ "name": "entity", "version": "004010", "codes": { "ST": "Ship To", "BT": "Bill-to-Party" } },
+	{ "name": "entity", "version": "005010", "effective_to": "2023-12-31",
+		"codes": { "ST": "Ship To", "BT": "Bill-to-Party", "SF": "Ship From" } },
+	{ "name": "entity", "version": "005010", "effective_from": "2024-01-01",
+		"codes": { "ST": "Ship To", "SF": "Ship From" } },
+	{ "name": "entity", "codes": { "ZZ": "Mutually Defined" } }
+]`
+
+func day(s string) time.Time {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRegistry_Select(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.Load(strings.NewReader(testLists)))
+	for _, test := range []struct {
+		name        string
+		list        string
+		version     string
+		date        time.Time
+		expectedBT  bool
+		expectedZZ  bool
+		expectedErr string
+	}{
+		{name: "4010", list: "entity", version: "004010", date: day("2024-06-01"), expectedBT: true},
+		{name: "5010 before revision", list: "entity", version: "005010", date: day("2023-12-31"), expectedBT: true},
+		{name: "5010 after revision", list: "entity", version: "005010", date: day("2024-01-01")},
+		{
+			name: "5010 on a date with time", list: "entity", version: "005010",
+			date: time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC), expectedBT: true,
+		},
+		{name: "5010 no date prefers latest", list: "entity", version: "005010"},
+		{name: "other version falls back to versionless", list: "entity", version: "003040", expectedZZ: true},
+		{name: "list not found", list: "unknown", expectedErr: "code list 'unknown' not found"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := r.Select(test.list, test.version, test.date)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, l)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBT, l.Contains("BT"))
+			assert.Equal(t, test.expectedZZ, l.Contains("ZZ"))
+		})
+	}
+}
+
+func TestRegistry_Select_NotEffective(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.Add(&List{Name: "entity", Version: "005010", EffectiveFrom: "2024-01-01"}))
+	_, err := r.Select("entity", "005010", day("2023-06-30"))
+	assert.Error(t, err)
+	assert.Equal(t, "code list 'entity' of version '005010' not effective on 2023-06-30", err.Error())
+}
+
+func TestRegistry_Load_Failure(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "invalid json", input: `{`, err: "unexpected EOF"},
+		{name: "null list", input: `[ null ]`, err: "code list[0]: code list is empty"},
+		{name: "missing name", input: `[ {} ]`, err: "code list[0]: code list '': 'name' must not be empty"},
+		{
+			name:  "invalid effective_from",
+			input: `[ { "name": "a" }, { "name": "b", "effective_from": "20240101" } ]`,
+			err:   "code list[1]: code list 'b': invalid 'effective_from' '20240101'",
+		},
+		{
+			name:  "invalid effective_to",
+			input: `[ { "name": "a", "effective_to": "x" } ]`,
+			err:   "code list[0]: code list 'a': invalid 'effective_to' 'x'",
+		},
+		{
+			name:  "effective_to before effective_from",
+			input: `[ { "name": "a", "effective_from": "2024-01-02", "effective_to": "2024-01-01" } ]`,
+			err:   "code list[0]: code list 'a': 'effective_to' '2024-01-01' is before 'effective_from' '2024-01-02'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewRegistry().Load(strings.NewReader(test.input))
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
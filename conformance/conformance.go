@@ -0,0 +1,228 @@
+// Package conformance runs a corpus of sample inputs against a schema and checks the transform outputs
+// and errors against the expected ones, producing a machine-readable report, e.g. for automating the QA
+// of a new trading partner's schema.
+//
+// A corpus is a directory of cases, each of which is made of files named after the case:
+//   - `<case>.input` or `<case>.input.<ext>`, the input, e.g. `810_ok.input.edi`;
+//   - `<case>.output.json`, the expected output: a JSON array of the transformed records, in order;
+//   - `<case>.errors.json`, optional, the expected errors: a JSON array of the messages of the errors,
+//     both the continuable ones of the skipped records and the fatal one the transform stops with,
+//     in order. No error is expected if it doesn't exist.
+//
+// The input is transformed with the case name as its input name, so the expected error messages,
+// which usually contain the input name, don't depend on the location of the corpus.
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+const (
+	inputSuffix  = ".input"
+	outputSuffix = ".output.json"
+	errorsSuffix = ".errors.json"
+)
+
+// Report is the outcome of running a corpus.
+type Report struct {
+	Cases  []CaseResult `json:"cases"`
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+}
+
+// CaseResult is the outcome of one case of a corpus.
+type CaseResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Err is why the case couldn't be run, e.g. its expected output is missing or not a JSON array.
+	Err string `json:"error,omitempty"`
+	// Records is the number of records transformed successfully.
+	Records int `json:"records"`
+	// Mismatches are the differences between the actual and the expected outputs and errors.
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Mismatch is a difference between an actual and an expected record or error.
+type Mismatch struct {
+	// Kind is either "record" or "error".
+	Kind string `json:"kind"`
+	// Index is the 0-based index of the record or error, among the records or the errors.
+	Index int `json:"index"`
+	// Expected is the expected record or error message, in JSON; nil if an unexpected one is produced.
+	Expected json.RawMessage `json:"expected"`
+	// Actual is the actual record or error message, in JSON; nil if an expected one isn't produced.
+	Actual json.RawMessage `json:"actual"`
+}
+
+// Run runs the cases of the corpus in dir, sorted by their names, against the schema. An error is
+// returned only if dir can't be read; the failures of individual cases are in the report.
+func Run(schema omniparser.Schema, dir string) (Report, error) {
+	names, inputs, err := findCases(dir)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Cases: make([]CaseResult, 0, len(names))}
+	for _, name := range names {
+		result := runCase(schema, dir, name, inputs[name])
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Cases = append(report.Cases, result)
+	}
+	return report, nil
+}
+
+// findCases returns the sorted names of the cases in dir, and the file names of their inputs.
+func findCases(dir string) ([]string, map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	inputs := map[string]string{}
+	var names []string
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		i := strings.Index(f.Name(), inputSuffix)
+		if i <= 0 {
+			continue
+		}
+		if rest := f.Name()[i+len(inputSuffix):]; rest != "" && !strings.HasPrefix(rest, ".") {
+			continue
+		}
+		name := f.Name()[:i]
+		if _, found := inputs[name]; !found {
+			names = append(names, name)
+		}
+		inputs[name] = f.Name()
+	}
+	sort.Strings(names)
+	return names, inputs, nil
+}
+
+func runCase(schema omniparser.Schema, dir, name, input string) CaseResult {
+	result := CaseResult{Name: name}
+	expectedRecords, err := readJSONArray(filepath.Join(dir, name+outputSuffix), false)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	expectedErrors, err := readJSONArray(filepath.Join(dir, name+errorsSuffix), true)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	records, errors, err := transform(schema, filepath.Join(dir, input), name)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	result.Records = len(records)
+	result.Mismatches = append(
+		compare("record", expectedRecords, records), compare("error", expectedErrors, errors)...)
+	result.Passed = len(result.Mismatches) == 0
+	return result
+}
+
+// readJSONArray reads the elements of the JSON array in a file. If optional, a missing file is an
+// empty array.
+func readJSONArray(path string, optional bool) ([]json.RawMessage, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("'%s' not found", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	var elems []json.RawMessage
+	if err = json.Unmarshal(b, &elems); err != nil {
+		return nil, fmt.Errorf("'%s' must be a JSON array: %s", filepath.Base(path), err.Error())
+	}
+	return elems, nil
+}
+
+// transform transforms an input and returns the transformed records, and the messages of the errors,
+// in JSON. An error is returned only if the transform can't be started.
+func transform(schema omniparser.Schema, path, name string) ([]json.RawMessage, []json.RawMessage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	tfm, err := schema.NewTransform(name, f, &transformctx.Ctx{})
+	if err != nil {
+		return nil, nil, err
+	}
+	var records, errors []json.RawMessage
+	for {
+		record, err := tfm.Read()
+		if err == io.EOF {
+			return records, errors, nil
+		}
+		if err != nil {
+			msg, _ := json.Marshal(err.Error())
+			errors = append(errors, msg)
+			if !errs.IsErrTransformFailed(err) {
+				return records, errors, nil
+			}
+			continue
+		}
+		records = append(records, record)
+	}
+}
+
+// compare compares the actual records or errors with the expected ones, one by one, in order.
+func compare(kind string, expected, actual []json.RawMessage) []Mismatch {
+	var mismatches []Mismatch
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		var e, a json.RawMessage
+		if i < len(expected) {
+			e = compact(expected[i])
+		}
+		if i < len(actual) {
+			a = compact(actual[i])
+		}
+		if e != nil && a != nil && jsonEqual(e, a) {
+			continue
+		}
+		mismatches = append(mismatches, Mismatch{Kind: kind, Index: i, Expected: e, Actual: a})
+	}
+	return mismatches
+}
+
+func compact(j json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, j); err != nil {
+		return j
+	}
+	return buf.Bytes()
+}
+
+// jsonEqual tells if two JSON values are semantically equal, e.g. regardless of the order of the
+// object keys.
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
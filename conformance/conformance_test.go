@@ -0,0 +1,95 @@
+package conformance
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser"
+)
+
+func newTestSchema(t *testing.T) omniparser.Schema {
+	s, err := omniparser.NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"c": { "custom_func": { "name": "dateTimeToRFC3339", "args": [ { "xpath": "c" }, { "const": "" }, { "const": "" } ] } },
+				"d": { "xpath": "d" }
+			} }
+		}
+	}`))
+	assert.NoError(t, err)
+	return s
+}
+
+func writeCorpus(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "conformance")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeCorpus(t, map[string]string{
+		"1_good.input.xml": "<a><b><c>2020-01-01</c><d>x</d></b><b><c>2020-01-02</c></b></a>",
+		// key order and whitespace don't matter.
+		"1_good.output.json":   `[ {"d": "x", "c": "2020-01-01T00:00:00"}, {"c": "2020-01-02T00:00:00"} ]`,
+		"2_errors.input":       "<a><b><c>bad</c></b><b><c>2020-01-03</c></b><b>",
+		"2_errors.output.json": `[ {"c": "2020-01-03T00:00:00"} ]`,
+		"2_errors.errors.json": `[
+			"input '2_errors' near line 1, col 21, byte offset 20: fail to transform. err: 'FINAL_OUTPUT.c.custom_func(dateTimeToRFC3339)' failed: unable to parse 'bad' in any supported date/time format",
+			"input '2_errors' near line 1, col 48, byte offset 47: XML syntax error on line 1: unexpected EOF"
+		]`,
+		"3_mismatches.input.xml":   "<a><b><c>2020-01-04</c></b><b><c>bad</c></b></a>",
+		"3_mismatches.output.json": `[ {"c": "2020-01-05T00:00:00"}, {"c": "2020-01-06T00:00:00"} ]`,
+		"4_no_output.input.xml":    "<a/>",
+		"5_bad_errors.input.xml":   "<a/>",
+		"5_bad_errors.output.json": "[]",
+		"5_bad_errors.errors.json": "[",
+		"6_not_a_case.xml":         "<a/>",
+		"6_not_a_case.inputs":      "<a/>",
+	})
+	defer os.RemoveAll(dir)
+	report, err := Run(newTestSchema(t), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Passed)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, []CaseResult{
+		{Name: "1_good", Passed: true, Records: 2},
+		{Name: "2_errors", Passed: true, Records: 1},
+		{
+			Name:    "3_mismatches",
+			Records: 1,
+			Mismatches: []Mismatch{
+				{
+					Kind:     "record",
+					Index:    0,
+					Expected: json.RawMessage(`{"c":"2020-01-05T00:00:00"}`),
+					Actual:   json.RawMessage(`{"c":"2020-01-04T00:00:00"}`),
+				},
+				{Kind: "record", Index: 1, Expected: json.RawMessage(`{"c":"2020-01-06T00:00:00"}`)},
+				{
+					Kind:  "error",
+					Index: 0,
+					Actual: json.RawMessage(`"input '3_mismatches' near line 1, col 45, byte offset 44: ` +
+						`fail to transform. err: 'FINAL_OUTPUT.c.custom_func(dateTimeToRFC3339)' failed: ` +
+						`unable to parse 'bad' in any supported date/time format"`),
+				},
+			},
+		},
+		{Name: "4_no_output", Err: "'4_no_output.output.json' not found"},
+		{Name: "5_bad_errors", Err: "'5_bad_errors.errors.json' must be a JSON array: unexpected end of JSON input"},
+	}, report.Cases)
+}
+
+func TestRun_DirNotFound(t *testing.T) {
+	_, err := Run(newTestSchema(t), "not-found")
+	assert.Error(t, err)
+}
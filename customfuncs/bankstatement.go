@@ -0,0 +1,173 @@
+package customfuncs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+const (
+	mt940DateLayout = "060102"
+	isoDateLayout   = "2006-01-02"
+)
+
+var (
+	amountRegexp = regexp.MustCompile(`^[0-9]*[.,]?[0-9]*$`)
+	// MT940 balance field (e.g. :60F:, :62F:, :64:): 1!a6!n3!a15d
+	mt940BalanceRegexp = regexp.MustCompile(`^([CD])([0-9]{6})([A-Z]{3})([0-9]+,[0-9]*)$`)
+	// MT940 statement line field (:61:) first line: 6!n[4!n]2a[1!a]15d1!a3!c16x[//16x]
+	mt940StatementLineRegexp = regexp.MustCompile(
+		`^([0-9]{6})([0-9]{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]*)([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+)
+
+// normalizeAmount validates a decimal amount with either ',' or '.' as decimal separator, as seen in
+// MT940 and CAMT, and returns it with '.' as the decimal separator.
+func normalizeAmount(amount string) (string, error) {
+	amount = strings.TrimSpace(amount)
+	if !amountRegexp.MatchString(amount) || strings.Trim(amount, ".,") == "" {
+		return "", fmt.Errorf("invalid amount '%s'", amount)
+	}
+	amount = strings.Replace(amount, ",", ".", 1)
+	if strings.HasPrefix(amount, ".") {
+		amount = "0" + amount
+	}
+	return strings.TrimSuffix(amount, "."), nil
+}
+
+// markSign returns -1 for a debit mark and 1 for a credit mark. Reversal marks (MT940 'RC' and 'RD')
+// carry the opposite sign of what they reverse.
+func markSign(mark string) (int, error) {
+	switch strings.ToUpper(strings.TrimSpace(mark)) {
+	case "C", "CRDT", "RD":
+		return 1, nil
+	case "D", "DBIT", "RC":
+		return -1, nil
+	default:
+		return 0, fmt.Errorf("invalid debit/credit mark '%s'", mark)
+	}
+}
+
+// SignedAmount converts a debit/credit mark and an unsigned amount into a signed amount: debit amounts
+// are negative and credit amounts are positive. Supported marks are MT940 'C', 'D', 'RC' (reversal of
+// credit) and 'RD' (reversal of debit), and CAMT 'CRDT' and 'DBIT'. The amount can use either ',' or
+// '.' as decimal separator; the result always uses '.'. If amount is empty, an empty string is returned.
+func SignedAmount(_ *transformctx.Ctx, mark, amount string) (string, error) {
+	if strings.TrimSpace(amount) == "" {
+		return "", nil
+	}
+	sign, err := markSign(mark)
+	if err != nil {
+		return "", err
+	}
+	amount, err = normalizeAmount(amount)
+	if err != nil {
+		return "", err
+	}
+	if sign < 0 && strings.Trim(amount, "0.") != "" {
+		amount = "-" + amount
+	}
+	return amount, nil
+}
+
+// MT940Balance parses an MT940 balance field value (e.g. of tag :60F:, :62F: or :64:, such as
+// "C201225EUR1234,56") and returns the component asked for: "mark", "date" (in "YYYY-MM-DD"),
+// "currency", "amount" (unsigned, with '.' as decimal separator) or "signed_amount".
+func MT940Balance(ctx *transformctx.Ctx, value, component string) (string, error) {
+	m := mt940BalanceRegexp.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return "", fmt.Errorf("invalid MT940 balance '%s'", value)
+	}
+	switch component {
+	case "mark":
+		return m[1], nil
+	case "date":
+		return mt940Date(m[2])
+	case "currency":
+		return m[3], nil
+	case "amount":
+		return normalizeAmount(m[4])
+	case "signed_amount":
+		return SignedAmount(ctx, m[1], m[4])
+	default:
+		return "", fmt.Errorf("unknown MT940 balance component '%s'", component)
+	}
+}
+
+// MT940StatementLine parses an MT940 statement line field value (tag :61:, such as
+// "2012241224D100,00NTRFINV-2020-001//B20122400001"), either just its first line or, e.g. as read by
+// the swiftmt file format, along with its supplementary details line, and returns the component asked
+// for: "value_date", "entry_date" (both in "YYYY-MM-DD"; entry_date is "" if not present), "mark",
+// "funds_code", "amount" (unsigned, with '.' as decimal separator), "signed_amount",
+// "transaction_type", "customer_reference", "bank_reference" or "supplementary_details" ("" if not
+// present).
+func MT940StatementLine(ctx *transformctx.Ctx, value, component string) (string, error) {
+	line, details := strings.TrimSpace(value), ""
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line, details = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	m := mt940StatementLineRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return "", fmt.Errorf("invalid MT940 statement line '%s'", value)
+	}
+	switch component {
+	case "supplementary_details":
+		return details, nil
+	case "value_date":
+		return mt940Date(m[1])
+	case "entry_date":
+		return mt940EntryDate(m[1], m[2])
+	case "mark":
+		return m[3], nil
+	case "funds_code":
+		return m[4], nil
+	case "amount":
+		return normalizeAmount(m[5])
+	case "signed_amount":
+		return SignedAmount(ctx, m[3], m[5])
+	case "transaction_type":
+		return m[6], nil
+	case "customer_reference":
+		return m[7], nil
+	case "bank_reference":
+		return m[8], nil
+	default:
+		return "", fmt.Errorf("unknown MT940 statement line component '%s'", component)
+	}
+}
+
+func mt940Date(yymmdd string) (string, error) {
+	t, err := time.Parse(mt940DateLayout, yymmdd)
+	if err != nil {
+		return "", fmt.Errorf("invalid MT940 date '%s'", yymmdd)
+	}
+	return t.Format(isoDateLayout), nil
+}
+
+// mt940EntryDate returns the entry date, which only has MMDD in MT940, with the year taken from the
+// value date. An entry date and value date are at most days apart, so the year is adjusted when they
+// sit on different sides of a year boundary.
+func mt940EntryDate(valueDate, mmdd string) (string, error) {
+	if mmdd == "" {
+		return "", nil
+	}
+	vd, err := time.Parse(mt940DateLayout, valueDate)
+	if err != nil {
+		return "", fmt.Errorf("invalid MT940 date '%s'", valueDate)
+	}
+	// parse with a leap year so that "0229" is accepted.
+	ed, err := time.Parse("20060102", "2000"+mmdd)
+	if err != nil {
+		return "", fmt.Errorf("invalid MT940 entry date '%s'", mmdd)
+	}
+	year := vd.Year()
+	switch diff := int(ed.Month()) - int(vd.Month()); {
+	case diff > 6:
+		year--
+	case diff < -6:
+		year++
+	}
+	return time.Date(year, ed.Month(), ed.Day(), 0, 0, 0, 0, time.UTC).Format(isoDateLayout), nil
+}
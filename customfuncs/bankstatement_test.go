@@ -0,0 +1,120 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedAmount(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mark     string
+		amount   string
+		expected string
+		err      string
+	}{
+		{name: "mt940 credit", mark: "C", amount: "1234,56", expected: "1234.56"},
+		{name: "mt940 debit", mark: "D", amount: "1234,56", expected: "-1234.56"},
+		{name: "mt940 reversal of credit", mark: "RC", amount: "10,", expected: "-10"},
+		{name: "mt940 reversal of debit", mark: "RD", amount: "10,5", expected: "10.5"},
+		{name: "camt credit", mark: "CRDT", amount: "99.90", expected: "99.90"},
+		{name: "camt debit", mark: " dbit ", amount: " .5 ", expected: "-0.5"},
+		{name: "zero debit", mark: "D", amount: "0,00", expected: "0.00"},
+		{name: "empty amount", mark: "", amount: "", expected: ""},
+		{name: "invalid mark", mark: "X", amount: "1", err: "invalid debit/credit mark 'X'"},
+		{name: "invalid amount", mark: "C", amount: "1,2,3", err: "invalid amount '1,2,3'"},
+		{name: "separator only", mark: "C", amount: ",", err: "invalid amount ','"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := SignedAmount(nil, test.mark, test.amount)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, s)
+			}
+		})
+	}
+}
+
+func TestMT940Balance(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		value     string
+		component string
+		expected  string
+		err       string
+	}{
+		{name: "mark", value: "C201225EUR1234,56", component: "mark", expected: "C"},
+		{name: "date", value: "C201225EUR1234,56", component: "date", expected: "2020-12-25"},
+		{name: "currency", value: "C201225EUR1234,56", component: "currency", expected: "EUR"},
+		{name: "amount", value: "D201225EUR1234,56", component: "amount", expected: "1234.56"},
+		{name: "signed_amount", value: "D201225EUR1234,56", component: "signed_amount", expected: "-1234.56"},
+		{name: "invalid value", value: "C2012EUR1,00", component: "mark", err: "invalid MT940 balance 'C2012EUR1,00'"},
+		{name: "invalid date", value: "C201325EUR1,00", component: "date", err: "invalid MT940 date '201325'"},
+		{name: "unknown component", value: "C201225EUR1,00", component: "x", err: "unknown MT940 balance component 'x'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := MT940Balance(nil, test.value, test.component)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, s)
+			}
+		})
+	}
+}
+
+func TestMT940StatementLine(t *testing.T) {
+	const full = "2012241224DR100,00NTRFINV-2020-001//B20122400001"
+	for _, test := range []struct {
+		name      string
+		value     string
+		component string
+		expected  string
+		err       string
+	}{
+		{name: "value_date", value: full, component: "value_date", expected: "2020-12-24"},
+		{name: "entry_date", value: full, component: "entry_date", expected: "2020-12-24"},
+		{name: "entry_date absent", value: "201224C5,NMSCNONREF", component: "entry_date", expected: ""},
+		{name: "entry_date year end", value: "2012311231C5,NMSCNONREF", component: "entry_date", expected: "2020-12-31"},
+		{name: "entry_date across year end", value: "2012310104C5,NMSCNONREF", component: "entry_date", expected: "2021-01-04"},
+		{name: "entry_date across year start", value: "2101041231C5,NMSCNONREF", component: "entry_date", expected: "2020-12-31"},
+		{name: "entry_date leap day", value: "2002290229C5,NMSCNONREF", component: "entry_date", expected: "2020-02-29"},
+		{name: "invalid entry_date", value: "2012241324C5,NMSCNONREF", component: "entry_date", err: "invalid MT940 entry date '1324'"},
+		{name: "mark", value: full, component: "mark", expected: "D"},
+		{name: "reversal mark", value: "201224RC5,NMSCNONREF", component: "mark", expected: "RC"},
+		{name: "funds_code", value: full, component: "funds_code", expected: "R"},
+		{name: "amount", value: full, component: "amount", expected: "100.00"},
+		{name: "signed_amount", value: full, component: "signed_amount", expected: "-100.00"},
+		{name: "reversal signed_amount", value: "201224RC5,NMSCNONREF", component: "signed_amount", expected: "-5"},
+		{name: "transaction_type", value: full, component: "transaction_type", expected: "NTRF"},
+		{name: "customer_reference", value: full, component: "customer_reference", expected: "INV-2020-001"},
+		{name: "bank_reference", value: full, component: "bank_reference", expected: "B20122400001"},
+		{name: "bank_reference absent", value: "201224C5,NMSCNONREF", component: "bank_reference", expected: ""},
+		{name: "with supplementary_details", value: full + "\r\nACME SUPPLIES", component: "bank_reference", expected: "B20122400001"},
+		{name: "supplementary_details", value: full + "\r\nACME SUPPLIES", component: "supplementary_details", expected: "ACME SUPPLIES"},
+		{name: "supplementary_details absent", value: full, component: "supplementary_details", expected: ""},
+		{name: "invalid value", value: "201224X5,NMSC", component: "mark", err: "invalid MT940 statement line '201224X5,NMSC'"},
+		{name: "invalid value_date", value: "201324C5,NMSCNONREF", component: "value_date", err: "invalid MT940 date '201324'"},
+		{name: "unknown component", value: full, component: "x", err: "unknown MT940 statement line component 'x'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := MT940StatementLine(nil, test.value, test.component)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, s)
+			}
+		})
+	}
+}
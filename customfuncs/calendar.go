@@ -0,0 +1,109 @@
+package customfuncs
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/logward/omniparser/calendar"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// dateOnlyRegexp matches dates without time, X12 CCYYMMDD or ISO 8601, whose results are dates as well.
+var dateOnlyRegexp = regexp.MustCompile(`^[0-9]{4}-?[0-9]{2}-?[0-9]{2}$`)
+
+func selectCalendar(ctx *transformctx.Ctx, name string) (*calendar.Calendar, error) {
+	if name == "" {
+		return calendar.Default(), nil
+	}
+	if ctx == nil || ctx.Calendars == nil {
+		return nil, errors.New("calendars are not available")
+	}
+	return ctx.Calendars.Get(name)
+}
+
+// parseCalendarDateTime parses a datetime string intelligently; if it doesn't contain TZ info, it's in
+// the time zone of the calendar.
+func parseCalendarDateTime(c *calendar.Calendar, datetime string) (time.Time, error) {
+	tz := c.Location().String()
+	t, _, err := parseDateTime(datetime, "", false, tz, tz)
+	return t, err
+}
+
+// IsBusinessDay tells, with "true" or "false", if the day of a 'datetime' string, parsed intelligently,
+// is a business day in the business calendar of a given name, in the Calendars of the ctx. An empty
+// calendar name selects the default calendar, with Saturday and Sunday as the weekend and no holidays.
+// 'datetime' without TZ info is in the time zone of the calendar. If 'datetime' is empty, an empty string
+// is returned.
+func IsBusinessDay(ctx *transformctx.Ctx, datetime, calendarName string) (string, error) {
+	if datetime == "" {
+		return "", nil
+	}
+	c, err := selectCalendar(ctx, calendarName)
+	if err != nil {
+		return "", err
+	}
+	t, err := parseCalendarDateTime(c, datetime)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatBool(c.IsBusinessDay(t)), nil
+}
+
+// AddBusinessDays adds a number of business days, negative to subtract, to a 'datetime' string, parsed
+// intelligently, in the business calendar of a given name (see IsBusinessDay), keeping its time of day.
+// If 'datetime' is a date only, e.g. "2024-01-05" or "20240105", the result is a date in "YYYY-MM-DD";
+// otherwise, it's an RFC3339 datetime in the time zone of the calendar. If 'datetime' is empty, an empty
+// string is returned.
+func AddBusinessDays(ctx *transformctx.Ctx, datetime, days, calendarName string) (string, error) {
+	if datetime == "" {
+		return "", nil
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil {
+		return "", fmt.Errorf("days must be an integer, but got '%s'", days)
+	}
+	c, err := selectCalendar(ctx, calendarName)
+	if err != nil {
+		return "", err
+	}
+	t, err := parseCalendarDateTime(c, datetime)
+	if err != nil {
+		return "", err
+	}
+	if t, err = c.AddBusinessDays(t, n); err != nil {
+		return "", err
+	}
+	if dateOnlyRegexp.MatchString(datetime) {
+		return t.Format(calendar.DateLayout), nil
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// SLADeadline computes an SLA deadline by adding a business 'duration' (e.g. "4h" or "90m") to an event
+// 'datetime' string, parsed intelligently, i.e. only the time within the business hours of the business
+// days of the business calendar of a given name (see IsBusinessDay) counts. The result is an RFC3339
+// datetime in the time zone of the calendar. If 'datetime' is empty, an empty string is returned.
+func SLADeadline(ctx *transformctx.Ctx, datetime, duration, calendarName string) (string, error) {
+	if datetime == "" {
+		return "", nil
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return "", fmt.Errorf("invalid duration '%s'", duration)
+	}
+	c, err := selectCalendar(ctx, calendarName)
+	if err != nil {
+		return "", err
+	}
+	t, err := parseCalendarDateTime(c, datetime)
+	if err != nil {
+		return "", err
+	}
+	if t, err = c.AddBusinessDuration(t, d); err != nil {
+		return "", err
+	}
+	return t.Format(time.RFC3339), nil
+}
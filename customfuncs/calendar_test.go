@@ -0,0 +1,141 @@
+package customfuncs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/calendar"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func testCalendarCtx(t *testing.T) *transformctx.Ctx {
+	r := calendar.NewRegistry()
+	assert.NoError(t, r.Load(strings.NewReader(`[
+		{
+			"name": "us_carrier",
+			"timezone": "America/New_York",
+			"holidays": [ "2024-01-15" ],
+			"business_hours": { "start": "09:00", "end": "17:00" }
+		}
+	]`)))
+	return &transformctx.Ctx{Calendars: r}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	ctx := testCalendarCtx(t)
+	for _, test := range []struct {
+		name     string
+		ctx      *transformctx.Ctx
+		datetime string
+		calendar string
+		expected string
+		err      string
+	}{
+		{name: "empty datetime", datetime: "", expected: ""},
+		{name: "default calendar weekday", datetime: "2024-01-05", expected: "true"},
+		{name: "default calendar weekend", datetime: "20240106", expected: "false"},
+		{name: "default calendar in utc", datetime: "2024-01-06T02:00:00Z", expected: "false"},
+		{name: "holiday", ctx: ctx, datetime: "2024-01-15", calendar: "us_carrier", expected: "false"},
+		{name: "in calendar time zone", ctx: ctx, datetime: "2024-01-06T02:00:00Z", calendar: "us_carrier", expected: "true"},
+		{name: "no calendars", datetime: "2024-01-05", calendar: "us_carrier", err: "calendars are not available"},
+		{name: "calendar not found", ctx: ctx, datetime: "2024-01-05", calendar: "eu", err: "calendar 'eu' not found"},
+		{name: "invalid datetime", datetime: "not a date", err: "unable to parse 'not a date' in any supported date/time format"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := IsBusinessDay(test.ctx, test.datetime, test.calendar)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
+
+func TestAddBusinessDays(t *testing.T) {
+	ctx := testCalendarCtx(t)
+	for _, test := range []struct {
+		name     string
+		datetime string
+		days     string
+		calendar string
+		expected string
+		err      string
+	}{
+		{name: "empty datetime", datetime: "", days: "1", expected: ""},
+		{name: "date over weekend", datetime: "2024-01-05", days: "1", expected: "2024-01-08"},
+		{name: "x12 date", datetime: "20240105", days: "2", expected: "2024-01-09"},
+		{name: "date over holiday", datetime: "2024-01-12", days: "1", calendar: "us_carrier", expected: "2024-01-16"},
+		{name: "negative", datetime: "2024-01-16", days: "-1", calendar: "us_carrier", expected: "2024-01-12"},
+		{
+			name: "datetime keeps time of day", datetime: "2024-01-12T10:30:00", days: "1", calendar: "us_carrier",
+			expected: "2024-01-16T10:30:00-05:00",
+		},
+		{
+			name: "datetime in calendar time zone", datetime: "2024-01-12T15:30:00Z", days: "1", calendar: "us_carrier",
+			expected: "2024-01-16T10:30:00-05:00",
+		},
+		{name: "invalid days", datetime: "2024-01-05", days: "one", err: "days must be an integer, but got 'one'"},
+		{name: "calendar not found", datetime: "2024-01-05", days: "1", calendar: "eu", err: "calendar 'eu' not found"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := AddBusinessDays(ctx, test.datetime, test.days, test.calendar)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
+
+func TestSLADeadline(t *testing.T) {
+	ctx := testCalendarCtx(t)
+	for _, test := range []struct {
+		name     string
+		datetime string
+		duration string
+		calendar string
+		expected string
+		err      string
+	}{
+		{name: "empty datetime", datetime: "", duration: "4h", expected: ""},
+		{
+			name: "over weekend", datetime: "2024-01-05T15:00:00-05:00", duration: "4h", calendar: "us_carrier",
+			expected: "2024-01-08T11:00:00-05:00",
+		},
+		{
+			name: "over holiday from utc", datetime: "2024-01-12T21:00:00Z", duration: "90m", calendar: "us_carrier",
+			expected: "2024-01-16T09:30:00-05:00",
+		},
+		{
+			name: "default calendar", datetime: "2024-01-05T20:00:00Z", duration: "8h",
+			expected: "2024-01-08T04:00:00Z",
+		},
+		{name: "invalid duration", datetime: "2024-01-05", duration: "2d", err: "invalid duration '2d'"},
+		{
+			name: "negative duration", datetime: "2024-01-05", duration: "-1h",
+			err: "negative duration '-1h0m0s'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := SLADeadline(ctx, test.datetime, test.duration, test.calendar)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
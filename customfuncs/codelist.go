@@ -0,0 +1,65 @@
+package customfuncs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/logward/omniparser/codelist"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// codeListDateLayouts are the layouts of the dates code lists are selected by: X12 CCYYMMDD and YYMMDD,
+// and ISO 8601.
+var codeListDateLayouts = []string{"20060102", "060102", codelist.DateLayout}
+
+func selectCodeList(ctx *transformctx.Ctx, name, version, date string) (*codelist.List, error) {
+	if ctx == nil || ctx.CodeLists == nil {
+		return nil, errors.New("code lists are not available")
+	}
+	var t time.Time
+	if date != "" {
+		var err error
+		for _, layout := range codeListDateLayouts {
+			if len(layout) != len(date) {
+				continue
+			}
+			if t, err = time.Parse(layout, date); err == nil {
+				break
+			}
+		}
+		if t.IsZero() {
+			return nil, fmt.Errorf("invalid date '%s'", date)
+		}
+	}
+	return ctx.CodeLists.Select(ctx.Profile.CodeListName(name), version, t)
+}
+
+// CodeListLookup returns the description of a code in the code list of a given name, which can be
+// mapped to another by the Profile of the ctx, in effect for a document of a given version on a given date. An empty date selects the code list regardless of its
+// effective period. It fails if the code isn't in the code list.
+func CodeListLookup(ctx *transformctx.Ctx, name, version, date, code string) (string, error) {
+	l, err := selectCodeList(ctx, name, version, date)
+	if err != nil {
+		return "", err
+	}
+	desc, found := l.Codes[code]
+	if !found {
+		return "", fmt.Errorf("code '%s' not in code list '%s' of version '%s'", code, name, version)
+	}
+	return desc, nil
+}
+
+// CodeListValidate returns a code as is if it's in the code list of a given name, in effect for a
+// document of a given version on a given date, or fails otherwise. An empty code is always valid, so
+// optional elements can be validated as well. An empty date selects the code list regardless of its
+// effective period.
+func CodeListValidate(ctx *transformctx.Ctx, name, version, date, code string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+	if _, err := CodeListLookup(ctx, name, version, date, code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
@@ -0,0 +1,71 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/codelist"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestCodeListLookupAndValidate(t *testing.T) {
+	r := codelist.NewRegistry()
+	assert.NoError(t, r.Add(&codelist.List{
+		Name: "entity", Version: "005010", EffectiveTo: "2023-12-31",
+		Codes: map[string]string{"BT": "Bill-to-Party", "ST": "Ship To"},
+	}))
+	assert.NoError(t, r.Add(&codelist.List{
+		Name: "entity", Version: "005010", EffectiveFrom: "2024-01-01",
+		Codes: map[string]string{"ST": "Ship To"},
+	}))
+	ctx := &transformctx.Ctx{CodeLists: r}
+	for _, test := range []struct {
+		name     string
+		ctx      *transformctx.Ctx
+		date     string
+		code     string
+		expected string
+		err      string
+	}{
+		{name: "CCYYMMDD", ctx: ctx, date: "20231231", code: "BT", expected: "Bill-to-Party"},
+		{name: "YYMMDD", ctx: ctx, date: "240101", code: "ST", expected: "Ship To"},
+		{name: "ISO", ctx: ctx, date: "2024-01-01", code: "ST", expected: "Ship To"},
+		{name: "no date", ctx: ctx, code: "ST", expected: "Ship To"},
+		{
+			name: "code not effective", ctx: ctx, date: "20240101", code: "BT",
+			err: "code 'BT' not in code list 'entity' of version '005010'",
+		},
+		{name: "invalid date", ctx: ctx, date: "2024/01/01", code: "ST", err: "invalid date '2024/01/01'"},
+		{name: "no code lists", ctx: &transformctx.Ctx{}, code: "ST", err: "code lists are not available"},
+		{name: "nil ctx", code: "ST", err: "code lists are not available"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			desc, err := CodeListLookup(test.ctx, "entity", "005010", test.date, test.code)
+			code, verr := CodeListValidate(test.ctx, "entity", "005010", test.date, test.code)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", desc)
+				assert.Error(t, verr)
+				assert.Equal(t, test.err, verr.Error())
+				assert.Equal(t, "", code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, desc)
+				assert.NoError(t, verr)
+				assert.Equal(t, test.code, code)
+			}
+		})
+	}
+	// profile maps the code list name.
+	assert.NoError(t, r.Add(&codelist.List{Name: "acme.entity", Codes: map[string]string{"ZZ": "Mutually Defined"}}))
+	ctx.Profile = &transformctx.Profile{CodeLists: map[string]string{"entity": "acme.entity"}}
+	desc, err := CodeListLookup(ctx, "entity", "005010", "", "ZZ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Mutually Defined", desc)
+	// empty code is always valid.
+	code, err := CodeListValidate(&transformctx.Ctx{}, "entity", "005010", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", code)
+}
@@ -0,0 +1,132 @@
+package customfuncs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+// iso4217Exponents maps the ISO 4217 currency codes to their exponents, i.e. the number of decimal places
+// between the major and the minor units. Currencies not listed in iso4217NonDefaultExponents have the
+// exponent of 2.
+var iso4217Exponents = func() map[string]int {
+	m := map[string]int{}
+	for _, code := range strings.Fields(iso4217DefaultExponentCurrencies) {
+		m[code] = 2
+	}
+	for code, exp := range iso4217NonDefaultExponents {
+		m[code] = exp
+	}
+	return m
+}()
+
+const iso4217DefaultExponentCurrencies = `
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD BTN BWP BYN BZD
+	CAD CDF CHE CHF CHW CNY COP COU CRC CUC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL
+	GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD
+	LSL MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN
+	PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL
+	THB TJS TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED VES WST XCD XCG YER ZAR ZMW ZWG ZWL`
+
+var iso4217NonDefaultExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+var (
+	minorUnitsRegexp    = regexp.MustCompile(`^([+-]?)([0-9]+)$`)
+	decimalAmountRegexp = regexp.MustCompile(`^([+-]?)([0-9]*)(?:\.([0-9]*))?$`)
+)
+
+func currencyExponent(currency string) (int, error) {
+	exp, found := iso4217Exponents[strings.ToUpper(strings.TrimSpace(currency))]
+	if !found {
+		return 0, fmt.Errorf("unknown ISO 4217 currency code '%s'", currency)
+	}
+	return exp, nil
+}
+
+// signedDigits returns the digits, without leading zeros (but at least "0"), prefixed by "-" if negative
+// and not zero.
+func signedDigits(sign, digits string) string {
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+	if sign == "-" {
+		return "-" + digits
+	}
+	return digits
+}
+
+// CurrencyExponent returns the ISO 4217 exponent of a currency, i.e. the number of decimal places of its
+// minor unit, e.g. "2" for "USD", "0" for "JPY" and "3" for "BHD". It fails if the currency code is unknown.
+func CurrencyExponent(_ *transformctx.Ctx, currency string) (string, error) {
+	exp, err := currencyExponent(currency)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(exp), nil
+}
+
+// MinorUnitsToAmount converts an integer amount in the minor unit of a currency (e.g. cents) into a
+// decimal amount in its major unit, with as many decimal places as the ISO 4217 exponent of the
+// currency, e.g. "12345" of "USD" into "123.45", "12345" of "JPY" into "12345", and "12345" of "BHD"
+// into "12.345". The conversion is exact. If minor is empty, an empty string is returned.
+func MinorUnitsToAmount(_ *transformctx.Ctx, minor, currency string) (string, error) {
+	minor = strings.TrimSpace(minor)
+	if minor == "" {
+		return "", nil
+	}
+	exp, err := currencyExponent(currency)
+	if err != nil {
+		return "", err
+	}
+	m := minorUnitsRegexp.FindStringSubmatch(minor)
+	if m == nil {
+		return "", fmt.Errorf("invalid minor units amount '%s'", minor)
+	}
+	digits := m[2]
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	intPart, fracPart := signedDigits(m[1], digits[:len(digits)-exp]), digits[len(digits)-exp:]
+	if intPart == "0" && m[1] == "-" && strings.Trim(fracPart, "0") != "" {
+		intPart = "-0"
+	}
+	if exp == 0 {
+		return intPart, nil
+	}
+	return intPart + "." + fracPart, nil
+}
+
+// AmountToMinorUnits converts a decimal amount in the major unit of a currency into an integer amount in
+// its minor unit, as per the ISO 4217 exponent of the currency, e.g. "123.45" of "USD" into "12345",
+// "123.4" of "USD" into "12340", and "12.345" of "BHD" into "12345". The conversion is exact: it fails if
+// the amount has more (non-zero) decimal places than the exponent of the currency, e.g. "1.5" of "JPY".
+// If amount is empty, an empty string is returned.
+func AmountToMinorUnits(_ *transformctx.Ctx, amount, currency string) (string, error) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" {
+		return "", nil
+	}
+	exp, err := currencyExponent(currency)
+	if err != nil {
+		return "", err
+	}
+	m := decimalAmountRegexp.FindStringSubmatch(amount)
+	if m == nil || m[2]+m[3] == "" {
+		return "", fmt.Errorf("invalid amount '%s'", amount)
+	}
+	fracPart := strings.TrimRight(m[3], "0")
+	if len(fracPart) > exp {
+		return "", fmt.Errorf("amount '%s' has more decimal places than the %d of currency '%s'",
+			amount, exp, currency)
+	}
+	return signedDigits(m[1], m[2]+fracPart+strings.Repeat("0", exp-len(fracPart))), nil
+}
@@ -0,0 +1,99 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrencyExponent(t *testing.T) {
+	for currency, expected := range map[string]string{"USD": "2", "jpy": "0", " BHD ": "3", "CLF": "4"} {
+		s, err := CurrencyExponent(nil, currency)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, s)
+	}
+	_, err := CurrencyExponent(nil, "XYZ")
+	assert.Error(t, err)
+	assert.Equal(t, "unknown ISO 4217 currency code 'XYZ'", err.Error())
+}
+
+func TestMinorUnitsToAmount(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		minor    string
+		currency string
+		expected string
+		err      string
+	}{
+		{name: "empty", minor: " ", currency: "USD", expected: ""},
+		{name: "USD", minor: "12345", currency: "USD", expected: "123.45"},
+		{name: "USD less than 1", minor: "5", currency: "usd", expected: "0.05"},
+		{name: "USD negative less than 1", minor: "-5", currency: "USD", expected: "-0.05"},
+		{name: "USD negative", minor: "-12345", currency: "USD", expected: "-123.45"},
+		{name: "USD leading zeros", minor: "+000100", currency: "USD", expected: "1.00"},
+		{name: "USD negative zero", minor: "-0", currency: "USD", expected: "0.00"},
+		{name: "JPY", minor: "12345", currency: "JPY", expected: "12345"},
+		{name: "BHD", minor: "12345", currency: "BHD", expected: "12.345"},
+		{name: "CLF", minor: "1", currency: "CLF", expected: "0.0001"},
+		{name: "beyond int64", minor: "123456789012345678901234", currency: "EUR", expected: "1234567890123456789012.34"},
+		{name: "invalid minor", minor: "12.5", currency: "USD", err: "invalid minor units amount '12.5'"},
+		{name: "unknown currency", minor: "1", currency: "US", err: "unknown ISO 4217 currency code 'US'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := MinorUnitsToAmount(nil, test.minor, test.currency)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
+
+func TestAmountToMinorUnits(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		amount   string
+		currency string
+		expected string
+		err      string
+	}{
+		{name: "empty", amount: "", currency: "USD", expected: ""},
+		{name: "USD", amount: "123.45", currency: "USD", expected: "12345"},
+		{name: "USD one decimal", amount: "123.4", currency: "USD", expected: "12340"},
+		{name: "USD no decimals", amount: "123", currency: "USD", expected: "12300"},
+		{name: "USD trailing zeros", amount: "1.2300", currency: "USD", expected: "123"},
+		{name: "USD less than 1", amount: ".05", currency: "USD", expected: "5"},
+		{name: "USD negative", amount: "-0.05", currency: "USD", expected: "-5"},
+		{name: "USD zero", amount: "-0.00", currency: "USD", expected: "0"},
+		{name: "JPY", amount: "1000", currency: "JPY", expected: "1000"},
+		{name: "JPY with zero decimals", amount: "1000.0", currency: "JPY", expected: "1000"},
+		{name: "BHD", amount: "12.345", currency: "BHD", expected: "12345"},
+		{
+			name: "JPY with decimals", amount: "1.5", currency: "JPY",
+			err: "amount '1.5' has more decimal places than the 0 of currency 'JPY'",
+		},
+		{
+			name: "USD with 3 decimals", amount: "1.005", currency: "USD",
+			err: "amount '1.005' has more decimal places than the 2 of currency 'USD'",
+		},
+		{name: "invalid amount", amount: "1,000.00", currency: "USD", err: "invalid amount '1,000.00'"},
+		{name: "no digits", amount: ".", currency: "USD", err: "invalid amount '.'"},
+		{name: "unknown currency", amount: "1", currency: "", err: "unknown ISO 4217 currency code ''"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := AmountToMinorUnits(nil, test.amount, test.currency)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
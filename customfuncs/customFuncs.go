@@ -1,6 +1,9 @@
 package customfuncs
 
 import (
+	"fmt"
+	"math/rand"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -30,16 +33,42 @@ func Merge(funcs ...CustomFuncs) CustomFuncs {
 // for all versions of schemas.
 var CommonCustomFuncs = map[string]CustomFuncType{
 	// keep these custom funcs lexically sorted
+	"addBusinessDays":         AddBusinessDays,
+	"amountToMinorUnits":      AmountToMinorUnits,
 	"coalesce":                Coalesce,
+	"codeListLookup":          CodeListLookup,
+	"codeListValidate":        CodeListValidate,
 	"concat":                  Concat,
+	"convertUnit":             ConvertUnit,
+	"currencyExponent":        CurrencyExponent,
 	"dateTimeLayoutToRFC3339": DateTimeLayoutToRFC3339,
 	"dateTimeToEpoch":         DateTimeToEpoch,
 	"dateTimeToRFC3339":       DateTimeToRFC3339,
 	"epochToDateTimeRFC3339":  EpochToDateTimeRFC3339,
+	"externalProperty":        ExternalProperty,
+	"fakeAddress":             FakeAddress,
+	"fakeName":                FakeName,
+	"formatPreservingMask":    FormatPreservingMask,
+	"gs1AI":                   GS1AI,
+	"gs1ElementStrings":       GS1ElementStrings,
+	"iataAirportLookup":       IATAAirportLookup,
+	"icaoAirportLookup":       ICAOAirportLookup,
+	"isBusinessDay":           IsBusinessDay,
 	"lower":                   Lower,
+	"minorUnitsToAmount":      MinorUnitsToAmount,
+	"mt940Balance":            MT940Balance,
+	"mt940StatementLine":      MT940StatementLine,
 	"now":                     Now,
+	"random":                  Random,
+	"signedAmount":            SignedAmount,
+	"slaDeadline":             SLADeadline,
+	"today":                   Today,
+	"unLocodeLookup":          UNLocodeLookup,
 	"upper":                   Upper,
 	"uuidv3":                  UUIDv3,
+	"uuidv4":                  UUIDv4,
+	"x12AckCode":              X12AckCode,
+	"x12Text":                 X12Text,
 }
 
 // Coalesce returns the first non-empty string of the input strings. If no input strings are given or
@@ -63,6 +92,17 @@ func Concat(_ *transformctx.Ctx, strs ...string) (string, error) {
 	return w.String(), nil
 }
 
+// ExternalProperty returns the value of an external property, including the transport metadata of the
+// input stream (see transformctx.Ctx.Transport) with names like "transport.message_id". If the property
+// isn't found, an empty string is returned.
+func ExternalProperty(ctx *transformctx.Ctx, name string) (string, error) {
+	if ctx == nil {
+		return "", nil
+	}
+	v, _ := ctx.External(name)
+	return v, nil
+}
+
 // Lower lowers the case of an input string.
 func Lower(_ *transformctx.Ctx, s string) (string, error) {
 	return strings.ToLower(s), nil
@@ -77,3 +117,45 @@ func Upper(_ *transformctx.Ctx, s string) (string, error) {
 func UUIDv3(_ *transformctx.Ctx, s string) (string, error) {
 	return uuid.NewMD5(uuid.Nil, []byte(s)).String(), nil
 }
+
+// UUIDv4 returns a random UUID, which is pseudo-random if transformctx.Ctx.Determinism is set.
+func UUIDv4(ctx *transformctx.Ctx) (string, error) {
+	var u uuid.UUID
+	var err error
+	if r := ctx.Rand(); r != nil {
+		u, err = uuid.NewRandomFromReader(r)
+	} else {
+		u, err = uuid.NewRandom()
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// Random returns a random integer between min and max, inclusive, which is pseudo-random if
+// transformctx.Ctx.Determinism is set.
+func Random(ctx *transformctx.Ctx, min, max string) (string, error) {
+	minNum, err := strconv.ParseInt(min, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("min must be an integer, but got '%s'", min)
+	}
+	maxNum, err := strconv.ParseInt(max, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("max must be an integer, but got '%s'", max)
+	}
+	if maxNum < minNum {
+		return "", fmt.Errorf("max (%d) must be no less than min (%d)", maxNum, minNum)
+	}
+	span := maxNum - minNum + 1
+	if span <= 0 {
+		return "", fmt.Errorf("range from min (%d) to max (%d) is too large", minNum, maxNum)
+	}
+	var n int64
+	if r := ctx.Rand(); r != nil {
+		n = minNum + r.Int63n(span)
+	} else {
+		n = minNum + rand.Int63n(span)
+	}
+	return strconv.FormatInt(n, 10), nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/bradleyjkemp/cupaloy"
 	"github.com/jf-tech/go-corelib/jsons"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
 )
 
 func TestDumpCommonCustomFuncNames(t *testing.T) {
@@ -86,6 +88,28 @@ func TestConcat(t *testing.T) {
 	}
 }
 
+func TestExternalProperty(t *testing.T) {
+	s, err := ExternalProperty(nil, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "", s)
+
+	ctx := &transformctx.Ctx{
+		ExternalProperties: map[string]string{"a": "1"},
+		Transport:          &transformctx.Transport{PartnerID: "ACME"},
+	}
+	s, err = ExternalProperty(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", s)
+
+	s, err = ExternalProperty(ctx, "transport.partner_id")
+	assert.NoError(t, err)
+	assert.Equal(t, "ACME", s)
+
+	s, err = ExternalProperty(ctx, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "", s)
+}
+
 func TestLower(t *testing.T) {
 	s, err := Lower(nil, "")
 	assert.NoError(t, err)
@@ -115,3 +139,89 @@ func TestUUIDv3(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "522ec739-ca63-3ec5-b082-08ce08ad65e2", result)
 }
+
+func TestUUIDv4(t *testing.T) {
+	u1, err := UUIDv4(nil)
+	assert.NoError(t, err)
+	u2, err := UUIDv4(nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, u1, u2)
+
+	ctx := func() *transformctx.Ctx {
+		return &transformctx.Ctx{
+			Determinism: &transformctx.Determinism{Seed: 42},
+			Counters:    transformctx.RecordCounters{Number: 1},
+		}
+	}
+	u1, err = UUIDv4(ctx())
+	assert.NoError(t, err)
+	u2, err = UUIDv4(ctx())
+	assert.NoError(t, err)
+	assert.Equal(t, u1, u2)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, u1)
+}
+
+func TestRandom(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		min      string
+		max      string
+		err      string
+		expected string
+	}{
+		{
+			name: "invalid min",
+			min:  "a",
+			max:  "1",
+			err:  "min must be an integer, but got 'a'",
+		},
+		{
+			name: "invalid max",
+			min:  "1",
+			max:  "1.5",
+			err:  "max must be an integer, but got '1.5'",
+		},
+		{
+			name: "max less than min",
+			min:  "2",
+			max:  "1",
+			err:  "max (1) must be no less than min (2)",
+		},
+		{
+			name: "range too large",
+			min:  "-9223372036854775808",
+			max:  "9223372036854775807",
+			err:  "range from min (-9223372036854775808) to max (9223372036854775807) is too large",
+		},
+		{
+			name:     "single value",
+			min:      "-7",
+			max:      "-7",
+			expected: "-7",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Random(nil, test.min, test.max)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+
+	ctx := func() *transformctx.Ctx {
+		return &transformctx.Ctx{
+			Determinism: &transformctx.Determinism{Seed: 42},
+			Counters:    transformctx.RecordCounters{Number: 1},
+		}
+	}
+	r1, err := Random(ctx(), "1", "1000000000")
+	assert.NoError(t, err)
+	r2, err := Random(ctx(), "1", "1000000000")
+	assert.NoError(t, err)
+	assert.Equal(t, r1, r2)
+}
@@ -173,7 +173,24 @@ func EpochToDateTimeRFC3339(_ *transformctx.Ctx, epoch, unit string, tz ...strin
 	return rfc3339(t.In(loc), true), nil
 }
 
-// Now returns the current time in UTC in RFC3339 format.
-func Now(_ *transformctx.Ctx) (string, error) {
-	return rfc3339(time.Now().UTC(), true), nil
+// Now returns the current time, as told by transformctx.Ctx.Now, in UTC in RFC3339 format.
+func Now(ctx *transformctx.Ctx) (string, error) {
+	return rfc3339(ctx.Now().UTC(), true), nil
+}
+
+// Today returns the current date, as told by transformctx.Ctx.Now, in the given timezone 'tz', if
+// specified, or UTC otherwise, in the "2006-01-02" format.
+func Today(ctx *transformctx.Ctx, tz ...string) (string, error) {
+	if len(tz) > 1 {
+		return "", fmt.Errorf("cannot specify tz argument more than once")
+	}
+	timezone := "UTC"
+	if len(tz) == 1 {
+		timezone = tz[0]
+	}
+	loc, err := caches.GetTimeLocation(timezone)
+	if err != nil {
+		return "", err
+	}
+	return ctx.Now().In(loc).Format("2006-01-02"), nil
 }
@@ -2,8 +2,11 @@ package customfuncs
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
 )
 
 func TestDateTimeToRFC3339(t *testing.T) {
@@ -368,3 +371,77 @@ func TestNow(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, len(now) > 0)
 }
+
+func TestNow_Determinism(t *testing.T) {
+	now, err := Now(&transformctx.Ctx{Determinism: &transformctx.Determinism{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "1970-01-01T00:00:00Z", now)
+	now, err = Now(&transformctx.Ctx{Determinism: &transformctx.Determinism{
+		Now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 3600))}})
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-01-02T02:04:05Z", now)
+}
+
+func TestNow_Clock(t *testing.T) {
+	now, err := Now(&transformctx.Ctx{Clock: transformctx.ClockFunc(func() time.Time {
+		return time.Date(2021, 6, 7, 8, 9, 10, 0, time.FixedZone("", -3600))
+	})})
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-06-07T09:09:10Z", now)
+}
+
+func TestToday(t *testing.T) {
+	// 2021-06-07T23:30:00Z, which is already 2021-06-08 in Tokyo.
+	clock := transformctx.ClockFunc(func() time.Time { return time.Date(2021, 6, 7, 23, 30, 0, 0, time.UTC) })
+	for _, test := range []struct {
+		name     string
+		ctx      *transformctx.Ctx
+		tz       []string
+		err      string
+		expected string
+	}{
+		{
+			name:     "utc",
+			ctx:      &transformctx.Ctx{Clock: clock},
+			expected: "2021-06-07",
+		},
+		{
+			name:     "with tz",
+			ctx:      &transformctx.Ctx{Clock: clock},
+			tz:       []string{"Asia/Tokyo"},
+			expected: "2021-06-08",
+		},
+		{
+			name:     "deterministic",
+			ctx:      &transformctx.Ctx{Clock: clock, Determinism: &transformctx.Determinism{}},
+			expected: "1970-01-01",
+		},
+		{
+			name: "invalid tz",
+			ctx:  &transformctx.Ctx{Clock: clock},
+			tz:   []string{"Unknown/Nowhere"},
+			err:  "unknown time zone Unknown/Nowhere",
+		},
+		{
+			name: "multiple tz",
+			ctx:  &transformctx.Ctx{Clock: clock},
+			tz:   []string{"UTC", "UTC"},
+			err:  "cannot specify tz argument more than once",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Today(test.ctx, test.tz...)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+	today, err := Today(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(today))
+}
@@ -0,0 +1,223 @@
+package customfuncs
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// FuncDoc documents a custom func, for tools such as schema authoring UIs.
+type FuncDoc struct {
+	// Args are the names of the args, in the order they're specified in a schema's `custom_func.args`.
+	Args []string
+	// Doc is a short description of what the custom func does.
+	Doc string
+}
+
+// CommonCustomFuncDocs documents the CommonCustomFuncs.
+var CommonCustomFuncDocs = map[string]FuncDoc{
+	// keep these custom funcs lexically sorted
+	"addBusinessDays": {
+		Args: []string{"datetime", "days", "calendar"},
+		Doc:  "adds a number of business days, in the given business calendar, to a datetime string.",
+	},
+	"amountToMinorUnits": {
+		Args: []string{"amount", "currency"},
+		Doc:  "converts a decimal amount into an integer amount in the minor unit of the currency, as per its ISO 4217 exponent.",
+	},
+	"coalesce": {
+		Args: []string{"strs"},
+		Doc:  "returns the first non-empty string of the input strings, or an empty string if none.",
+	},
+	"codeListLookup": {
+		Args: []string{"name", "version", "date", "code"},
+		Doc:  "returns the description of a code in the code list of the given name, in effect for the given version and date.",
+	},
+	"codeListValidate": {
+		Args: []string{"name", "version", "date", "code"},
+		Doc:  "returns a code as is if it's in the code list of the given name, in effect for the given version and date.",
+	},
+	"concat": {
+		Args: []string{"strs"},
+		Doc:  "concatenates a number of strings together.",
+	},
+	"convertUnit": {
+		Args: []string{"value", "from", "to", "decimals"},
+		Doc:  "converts a weight, length, volume or temperature value from one unit to another, optionally rounded to the given decimal places.",
+	},
+	"currencyExponent": {
+		Args: []string{"currency"},
+		Doc:  "returns the ISO 4217 exponent of a currency, i.e. the number of decimal places of its minor unit.",
+	},
+	"dateTimeLayoutToRFC3339": {
+		Args: []string{"datetime", "layout", "layoutTZ", "fromTZ", "toTZ"},
+		Doc:  "parses a datetime string according to a given layout, and returns it in RFC3339 format.",
+	},
+	"dateTimeToEpoch": {
+		Args: []string{"datetime", "fromTZ", "unit"},
+		Doc:  "parses a datetime string intelligently, and returns its epoch number in the given unit.",
+	},
+	"dateTimeToRFC3339": {
+		Args: []string{"datetime", "fromTZ", "toTZ"},
+		Doc:  "parses a datetime string intelligently, and returns it in RFC3339 format.",
+	},
+	"epochToDateTimeRFC3339": {
+		Args: []string{"epoch", "unit", "tz"},
+		Doc:  "translates an epoch timestamp in the given unit into an RFC3339 formatted datetime string.",
+	},
+	"externalProperty": {
+		Args: []string{"name"},
+		Doc:  "returns the value of an external property, or an empty string if not found.",
+	},
+	"fakeAddress": {
+		Args: []string{"value", "seed"},
+		Doc:  "returns a fake street address of a value, the same for the same value and seed.",
+	},
+	"fakeName": {
+		Args: []string{"value", "seed"},
+		Doc:  "returns a fake person name of a value, the same for the same value and seed.",
+	},
+	"formatPreservingMask": {
+		Args: []string{"value", "seed"},
+		Doc:  "replaces the digits and letters of a value with random ones of the same kind and case, the same for the same value and seed.",
+	},
+	"gs1AI": {
+		Args: []string{"s", "ai"},
+		Doc:  "parses a GS1 element string and returns the value of the given application identifier.",
+	},
+	"gs1ElementStrings": {
+		Args: []string{"s"},
+		Doc:  "parses a GS1 element string into an object keyed by the application identifier names.",
+	},
+	"iataAirportLookup": {
+		Args: []string{"code", "field"},
+		Doc:  "returns the name, city, country, iata or icao field of the airport of an IATA airport code.",
+	},
+	"icaoAirportLookup": {
+		Args: []string{"code", "field"},
+		Doc:  "returns the name, city, country, iata or icao field of the airport of an ICAO airport code.",
+	},
+	"isBusinessDay": {
+		Args: []string{"datetime", "calendar"},
+		Doc:  "tells if the day of a datetime string is a business day in the given business calendar.",
+	},
+	"lower": {
+		Args: []string{"s"},
+		Doc:  "lowers the case of an input string.",
+	},
+	"minorUnitsToAmount": {
+		Args: []string{"minor", "currency"},
+		Doc:  "converts an integer amount in the minor unit of a currency into a decimal amount, as per its ISO 4217 exponent.",
+	},
+	"mt940Balance": {
+		Args: []string{"value", "component"},
+		Doc:  "parses an MT940 balance field value and returns the given component of it.",
+	},
+	"mt940StatementLine": {
+		Args: []string{"value", "component"},
+		Doc:  "parses an MT940 statement line field value and returns the given component of it.",
+	},
+	"now": {
+		Doc: "returns the current time, as told by the transform's clock, in UTC in RFC3339 format.",
+	},
+	"random": {
+		Args: []string{"min", "max"},
+		Doc:  "returns a random integer between min and max, inclusive, pseudo-random if the transform is deterministic.",
+	},
+	"signedAmount": {
+		Args: []string{"mark", "amount"},
+		Doc:  "converts a debit/credit mark and an unsigned amount into a signed amount.",
+	},
+	"slaDeadline": {
+		Args: []string{"datetime", "duration", "calendar"},
+		Doc:  "adds a business duration, counting only the business hours of the given business calendar, to an event datetime string.",
+	},
+	"today": {
+		Args: []string{"tz"},
+		Doc:  "returns the current date, as told by the transform's clock, in the given timezone, or UTC, as 2006-01-02.",
+	},
+	"unLocodeLookup": {
+		Args: []string{"code", "field"},
+		Doc:  "returns the name, subdivision or country field of the location of a UN/LOCODE.",
+	},
+	"upper": {
+		Args: []string{"s"},
+		Doc:  "uppers the case of an input string.",
+	},
+	"uuidv3": {
+		Args: []string{"s"},
+		Doc:  "uses MD5 to produce a consistent/stable UUID for an input string.",
+	},
+	"uuidv4": {
+		Doc: "returns a random UUID, pseudo-random if the transform is deterministic.",
+	},
+	"x12AckCode": {
+		Args: []string{"ackType", "accepted", "received"},
+		Doc:  "returns the X12 999/997 or 824 acknowledgment code of a number of accepted records out of the received ones.",
+	},
+	"x12Text": {
+		Args: []string{"s", "maxLen"},
+		Doc:  "makes a string safe for an X12 free-form element by replacing delimiter characters, and truncates it to maxLen.",
+	},
+}
+
+// ArgDesc describes an arg of a custom func.
+type ArgDesc struct {
+	Name string `json:"name,omitempty"` // empty if not documented.
+	Type string `json:"type"`           // Go type of the arg, e.g. "string" or "interface {}".
+}
+
+// FuncDesc describes a custom func, for tools such as schema authoring UIs.
+type FuncDesc struct {
+	Name string `json:"name"`
+	// Args are the args to be specified in a schema's `custom_func.args`, i.e. excluding the leading
+	// *transformctx.Ctx and, if NodeArg, the contextual *idr.Node.
+	Args []ArgDesc `json:"args"`
+	// Variadic is true if the last arg can be specified any number of times, including zero.
+	Variadic bool `json:"variadic,omitempty"`
+	// NodeArg is true if the custom func operates on the contextual *idr.Node.
+	NodeArg bool   `json:"node_arg,omitempty"`
+	Result  string `json:"result"` // Go type of the result.
+	Doc     string `json:"doc,omitempty"`
+}
+
+// Describe describes the custom funcs, sorted by their names, with their arg names and docs looked
+// up in docs, if any. Entries of funcs that aren't functions are skipped.
+func Describe(funcs CustomFuncs, docs map[string]FuncDoc) []FuncDesc {
+	nodeType := reflect.TypeOf((*idr.Node)(nil))
+	var descs []FuncDesc
+	for name, fn := range funcs {
+		fnType := reflect.TypeOf(fn)
+		if fnType == nil || fnType.Kind() != reflect.Func || fnType.NumIn() < 1 || fnType.NumOut() < 1 {
+			continue
+		}
+		doc := docs[name]
+		desc := FuncDesc{
+			Name:     name,
+			Args:     []ArgDesc{},
+			Variadic: fnType.IsVariadic(),
+			Result:   fnType.Out(0).String(),
+			Doc:      doc.Doc,
+		}
+		first := 1 // skip the ctx.
+		if fnType.NumIn() >= 2 && fnType.In(1) == nodeType {
+			desc.NodeArg = true
+			first++
+		}
+		for i := first; i < fnType.NumIn(); i++ {
+			argType := fnType.In(i)
+			if desc.Variadic && i == fnType.NumIn()-1 {
+				argType = argType.Elem()
+			}
+			arg := ArgDesc{Type: argType.String()}
+			if i-first < len(doc.Args) {
+				arg.Name = doc.Args[i-first]
+			}
+			desc.Args = append(desc.Args, arg)
+		}
+		descs = append(descs, desc)
+	}
+	sort.Slice(descs, func(i, j int) bool { return descs[i].Name < descs[j].Name })
+	return descs
+}
@@ -0,0 +1,58 @@
+package customfuncs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestDescribe(t *testing.T) {
+	descs := Describe(
+		CustomFuncs{
+			"b_node":     func(*transformctx.Ctx, *idr.Node, string, ...interface{}) (interface{}, error) { return nil, nil },
+			"a_variadic": func(*transformctx.Ctx, ...string) (string, error) { return "", nil },
+			"c_no_args":  func(*transformctx.Ctx) (string, error) { return "", nil },
+			"d_not_func": "not a func",
+			"e_no_ctx":   func() {},
+		},
+		map[string]FuncDoc{
+			"b_node":     {Args: []string{"js", "args"}, Doc: "node doc"},
+			"a_variadic": {Doc: "variadic doc"},
+		})
+	assert.Equal(t, []FuncDesc{
+		{
+			Name:     "a_variadic",
+			Args:     []ArgDesc{{Type: "string"}},
+			Variadic: true,
+			Result:   "string",
+			Doc:      "variadic doc",
+		},
+		{
+			Name:     "b_node",
+			Args:     []ArgDesc{{Name: "js", Type: "string"}, {Name: "args", Type: "interface {}"}},
+			Variadic: true,
+			NodeArg:  true,
+			Result:   "interface {}",
+			Doc:      "node doc",
+		},
+		{
+			Name:   "c_no_args",
+			Args:   []ArgDesc{},
+			Result: "string",
+		},
+	}, descs)
+}
+
+func TestCommonCustomFuncDocs(t *testing.T) {
+	assert.Equal(t, len(CommonCustomFuncs), len(CommonCustomFuncDocs))
+	for name, fn := range CommonCustomFuncs {
+		doc, found := CommonCustomFuncDocs[name]
+		assert.True(t, found, name)
+		assert.NotEmpty(t, doc.Doc, name)
+		assert.Equal(t, reflect.TypeOf(fn).NumIn()-1, len(doc.Args), name)
+	}
+}
@@ -0,0 +1,54 @@
+package customfuncs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/logward/omniparser/anonymize/fake"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func fakeSeed(seed []string) (int64, error) {
+	if len(seed) > 1 {
+		return 0, fmt.Errorf("cannot specify seed argument more than once")
+	}
+	if len(seed) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(seed[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seed must be an integer, but got '%s'", seed[0])
+	}
+	return n, nil
+}
+
+// FakeName returns a fake person name of a value, derived from the value and the optional integer seed
+// only, so the same values have the same fakes. See fake.Name.
+func FakeName(_ *transformctx.Ctx, value string, seed ...string) (string, error) {
+	n, err := fakeSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	return fake.Name(value, n), nil
+}
+
+// FakeAddress returns a fake street address of a value, derived from the value and the optional integer
+// seed only, so the same values have the same fakes. See fake.Address.
+func FakeAddress(_ *transformctx.Ctx, value string, seed ...string) (string, error) {
+	n, err := fakeSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	return fake.Address(value, n), nil
+}
+
+// FormatPreservingMask returns a fake of a value of the same shape, with its digits and letters replaced
+// by random ones, derived from the value and the optional integer seed only, so the same values have the
+// same fakes. See fake.Mask.
+func FormatPreservingMask(_ *transformctx.Ctx, value string, seed ...string) (string, error) {
+	n, err := fakeSeed(seed)
+	if err != nil {
+		return "", err
+	}
+	return fake.Mask(value, n), nil
+}
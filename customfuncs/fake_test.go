@@ -0,0 +1,38 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/anonymize/fake"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestFakeFuncs(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		f        func(_ *transformctx.Ctx, value string, seed ...string) (string, error)
+		expected func(value string, seed int64) string
+	}{
+		{name: "fakeName", f: FakeName, expected: fake.Name},
+		{name: "fakeAddress", f: FakeAddress, expected: fake.Address},
+		{name: "formatPreservingMask", f: FormatPreservingMask, expected: fake.Mask},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := test.f(nil, "Jane Doe, 12 Elm St")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected("Jane Doe, 12 Elm St", 0), s)
+			s, err = test.f(nil, "Jane Doe, 12 Elm St", "42")
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected("Jane Doe, 12 Elm St", 42), s)
+			s, err = test.f(nil, "")
+			assert.NoError(t, err)
+			assert.Equal(t, "", s)
+			_, err = test.f(nil, "x", "abc")
+			assert.EqualError(t, err, "seed must be an integer, but got 'abc'")
+			_, err = test.f(nil, "x", "1", "2")
+			assert.EqualError(t, err, "cannot specify seed argument more than once")
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package customfuncs
+
+import (
+	"fmt"
+
+	"github.com/logward/omniparser/geo"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func geoRegistry(ctx *transformctx.Ctx) *geo.Registry {
+	if ctx == nil || ctx.Geo == nil {
+		return geo.Builtin()
+	}
+	return ctx.Geo
+}
+
+// UNLocodeLookup looks up a UN/LOCODE (e.g. "NLRTM", case insensitive, optionally with a space after
+// the country code) in the Geo registry of the ctx, or the built-in one if not set, and returns the
+// field asked for of the location: "name" (the default if field is empty), "subdivision" or "country".
+// If code is empty, an empty string is returned. It fails if the code isn't found.
+func UNLocodeLookup(ctx *transformctx.Ctx, code, field string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+	l, found := geoRegistry(ctx).Location(code)
+	if !found {
+		return "", fmt.Errorf("UN/LOCODE '%s' not found", code)
+	}
+	switch field {
+	case "", "name":
+		return l.Name, nil
+	case "subdivision":
+		return l.Subdivision, nil
+	case "country":
+		return l.Country, nil
+	default:
+		return "", fmt.Errorf("unknown UN/LOCODE field '%s'", field)
+	}
+}
+
+func airportField(a *geo.Airport, field string) (string, error) {
+	switch field {
+	case "", "name":
+		return a.Name, nil
+	case "city":
+		return a.City, nil
+	case "country":
+		return a.Country, nil
+	case "iata":
+		return a.IATA, nil
+	case "icao":
+		return a.ICAO, nil
+	default:
+		return "", fmt.Errorf("unknown airport field '%s'", field)
+	}
+}
+
+// IATAAirportLookup looks up a 3-letter IATA airport code in the Geo registry of the ctx, or the
+// built-in one if not set, and returns the field asked for of the airport: "name" (the default if
+// field is empty), "city", "country", "iata" or "icao". If code is empty, an empty string is returned.
+// It fails if the code isn't found.
+func IATAAirportLookup(ctx *transformctx.Ctx, code, field string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+	a, found := geoRegistry(ctx).AirportByIATA(code)
+	if !found {
+		return "", fmt.Errorf("IATA airport code '%s' not found", code)
+	}
+	return airportField(a, field)
+}
+
+// ICAOAirportLookup looks up a 4-letter ICAO airport code in the Geo registry of the ctx, or the
+// built-in one if not set, and returns the field asked for of the airport, same as IATAAirportLookup.
+// If code is empty, an empty string is returned. It fails if the code isn't found.
+func ICAOAirportLookup(ctx *transformctx.Ctx, code, field string) (string, error) {
+	if code == "" {
+		return "", nil
+	}
+	a, found := geoRegistry(ctx).AirportByICAO(code)
+	if !found {
+		return "", fmt.Errorf("ICAO airport code '%s' not found", code)
+	}
+	return airportField(a, field)
+}
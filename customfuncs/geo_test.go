@@ -0,0 +1,66 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/geo"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestGeoLookups(t *testing.T) {
+	r := geo.NewRegistry()
+	assert.NoError(t, r.AddLocation(geo.Location{Code: "USNYC", Name: "New York City", Subdivision: "NY"}))
+	for _, test := range []struct {
+		name     string
+		fn       func(*transformctx.Ctx, string, string) (string, error)
+		ctx      *transformctx.Ctx
+		code     string
+		field    string
+		expected string
+		err      string
+	}{
+		{name: "locode name", fn: UNLocodeLookup, code: "DEHAM", expected: "Hamburg"},
+		{name: "locode subdivision", fn: UNLocodeLookup, code: "DE HAM", field: "subdivision", expected: "HH"},
+		{name: "locode country", fn: UNLocodeLookup, code: "deham", field: "country", expected: "DE"},
+		{
+			name: "locode override", fn: UNLocodeLookup, ctx: &transformctx.Ctx{Geo: r}, code: "USNYC",
+			expected: "New York City",
+		},
+		{name: "locode empty", fn: UNLocodeLookup, code: "", expected: ""},
+		{name: "locode not found", fn: UNLocodeLookup, code: "XXXXX", err: "UN/LOCODE 'XXXXX' not found"},
+		{
+			name: "locode unknown field", fn: UNLocodeLookup, code: "DEHAM", field: "city",
+			err: "unknown UN/LOCODE field 'city'",
+		},
+		{
+			name: "iata name", fn: IATAAirportLookup, ctx: &transformctx.Ctx{}, code: "FRA",
+			expected: "Frankfurt Airport",
+		},
+		{name: "iata city", fn: IATAAirportLookup, code: "fra", field: "city", expected: "Frankfurt am Main"},
+		{name: "iata to icao", fn: IATAAirportLookup, code: "FRA", field: "icao", expected: "EDDF"},
+		{name: "iata empty", fn: IATAAirportLookup, code: "", expected: ""},
+		{name: "iata not found", fn: IATAAirportLookup, code: "XXX", err: "IATA airport code 'XXX' not found"},
+		{name: "icao country", fn: ICAOAirportLookup, code: "KMEM", field: "country", expected: "US"},
+		{name: "icao to iata", fn: ICAOAirportLookup, code: "KMEM", field: "iata", expected: "MEM"},
+		{name: "icao empty", fn: ICAOAirportLookup, code: "", expected: ""},
+		{name: "icao not found", fn: ICAOAirportLookup, code: "XXXX", err: "ICAO airport code 'XXXX' not found"},
+		{
+			name: "airport unknown field", fn: ICAOAirportLookup, code: "KMEM", field: "subdivision",
+			err: "unknown airport field 'subdivision'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := test.fn(test.ctx, test.code, test.field)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package customfuncs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jf-tech/go-corelib/maths"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+const gs1GroupSeparator = '\x1d' // FNC1 in a raw (non human readable) element string.
+
+type gs1ValueKind int
+
+const (
+	gs1Alphanumeric gs1ValueKind = iota
+	gs1Numeric
+	gs1CheckDigit // numeric, with a GS1 mod 10 check digit as the last digit.
+	gs1Date       // YYMMDD
+	gs1Decimal    // numeric, with the number of implied decimal places as the last digit of the AI.
+)
+
+// gs1AIDecl describes a GS1 application identifier.
+type gs1AIDecl struct {
+	ai     string
+	name   string
+	length int // if fixed is true, the exact length of the value; otherwise, the max length.
+	fixed  bool
+	kind   gs1ValueKind
+}
+
+// gs1AIDecls contains the application identifiers commonly seen in logistic labels and despatch
+// advices (e.g. EANCOM DESADV and X12 856). For decimal AIs, ai excludes the last decimal places digit.
+var gs1AIDecls = []gs1AIDecl{
+	{ai: "00", name: "sscc", length: 18, fixed: true, kind: gs1CheckDigit},
+	{ai: "01", name: "gtin", length: 14, fixed: true, kind: gs1CheckDigit},
+	{ai: "02", name: "content", length: 14, fixed: true, kind: gs1CheckDigit},
+	{ai: "10", name: "batch_lot", length: 20},
+	{ai: "11", name: "production_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "12", name: "due_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "13", name: "packaging_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "15", name: "best_before_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "16", name: "sell_by_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "17", name: "expiry_date", length: 6, fixed: true, kind: gs1Date},
+	{ai: "20", name: "variant", length: 2, fixed: true, kind: gs1Numeric},
+	{ai: "21", name: "serial", length: 20},
+	{ai: "22", name: "cpv", length: 20},
+	{ai: "30", name: "variable_count", length: 8, kind: gs1Numeric},
+	{ai: "37", name: "count", length: 8, kind: gs1Numeric},
+	{ai: "240", name: "additional_id", length: 30},
+	{ai: "241", name: "customer_part_number", length: 30},
+	{ai: "250", name: "secondary_serial", length: 30},
+	{ai: "310", name: "net_weight_kg", length: 6, fixed: true, kind: gs1Decimal},
+	{ai: "330", name: "gross_weight_kg", length: 6, fixed: true, kind: gs1Decimal},
+	{ai: "390", name: "amount", length: 15, kind: gs1Decimal},
+	{ai: "392", name: "price", length: 15, kind: gs1Decimal},
+	{ai: "400", name: "order_number", length: 30},
+	{ai: "401", name: "ginc", length: 30},
+	{ai: "402", name: "gsin", length: 17, fixed: true, kind: gs1CheckDigit},
+	{ai: "403", name: "routing_code", length: 30},
+	{ai: "410", name: "ship_to_gln", length: 13, fixed: true, kind: gs1CheckDigit},
+	{ai: "411", name: "bill_to_gln", length: 13, fixed: true, kind: gs1CheckDigit},
+	{ai: "412", name: "purchase_from_gln", length: 13, fixed: true, kind: gs1CheckDigit},
+	{ai: "413", name: "ship_for_gln", length: 13, fixed: true, kind: gs1CheckDigit},
+	{ai: "414", name: "location_gln", length: 13, fixed: true, kind: gs1CheckDigit},
+	{ai: "420", name: "ship_to_postal_code", length: 20},
+	{ai: "422", name: "origin_country", length: 3, fixed: true, kind: gs1Numeric},
+}
+
+// gs1Element is a single application identifier and its value, parsed out of an element string.
+type gs1Element struct {
+	decl  *gs1AIDecl
+	ai    string // the full AI, including the decimal places digit for decimal AIs.
+	value string // normalized value.
+}
+
+func lookupGS1AI(s string) (*gs1AIDecl, string) {
+	for i := range gs1AIDecls {
+		decl := &gs1AIDecls[i]
+		aiLen := len(decl.ai)
+		if decl.kind == gs1Decimal {
+			aiLen++
+		}
+		if len(s) >= aiLen && strings.HasPrefix(s, decl.ai) {
+			ai := s[:aiLen]
+			if decl.kind == gs1Decimal && (ai[aiLen-1] < '0' || ai[aiLen-1] > '9') {
+				continue
+			}
+			return decl, ai
+		}
+	}
+	return nil, ""
+}
+
+// parseGS1ElementStrings parses a GS1 element string, either human readable, with AIs in
+// parentheses, e.g. "(00)012345678901234560(10)LOT42", or raw, with variable length values
+// terminated by FNC1 (the ASCII group separator), and an optional symbology identifier prefix,
+// e.g. "]C1", as returned by barcode scanners.
+func parseGS1ElementStrings(s string) ([]gs1Element, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 3 && s[0] == ']' {
+		s = s[3:]
+	}
+	var elems []gs1Element
+	for len(s) > 0 {
+		var raw string
+		if s[0] == gs1GroupSeparator {
+			s = s[1:]
+			continue
+		}
+		parenthesized := s[0] == '('
+		if parenthesized {
+			end := strings.IndexByte(s, ')')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ')' in '%s'", s)
+			}
+			raw, s = s[1:end], s[end+1:]
+		}
+		decl, ai := lookupGS1AI(raw + s)
+		if decl == nil || (parenthesized && ai != raw) {
+			return nil, fmt.Errorf("unknown application identifier at '%s'", raw+s)
+		}
+		s = (raw + s)[len(ai):]
+		var value string
+		switch {
+		case parenthesized:
+			end := strings.IndexAny(s, string(gs1GroupSeparator)+"(")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		case decl.fixed:
+			n := maths.MinInt(decl.length, len(s))
+			value, s = s[:n], s[n:]
+		default:
+			end := strings.IndexByte(s, gs1GroupSeparator)
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		if decl.fixed && len(value) != decl.length {
+			return nil, fmt.Errorf("application identifier (%s) needs %d characters, but got %d",
+				ai, decl.length, len(value))
+		}
+		if len(value) > decl.length {
+			return nil, fmt.Errorf("application identifier (%s) allows at most %d characters, but got %d",
+				ai, decl.length, len(value))
+		}
+		value, err := normalizeGS1Value(decl, ai, value)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, gs1Element{decl: decl, ai: ai, value: value})
+	}
+	return elems, nil
+}
+
+func normalizeGS1Value(decl *gs1AIDecl, ai, value string) (string, error) {
+	if decl.kind == gs1Alphanumeric {
+		return value, nil
+	}
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return "", fmt.Errorf("application identifier (%s) value '%s' is not numeric", ai, value)
+	}
+	switch decl.kind {
+	case gs1CheckDigit:
+		if !gs1CheckDigitValid(value) {
+			return "", fmt.Errorf("application identifier (%s) value '%s' has invalid check digit", ai, value)
+		}
+	case gs1Date:
+		return gs1DateValue(ai, value)
+	case gs1Decimal:
+		decimals := int(ai[len(ai)-1] - '0')
+		if decimals > len(value) {
+			value = strings.Repeat("0", decimals-len(value)) + value
+		}
+		intPart := strings.TrimLeft(value[:len(value)-decimals], "0")
+		if intPart == "" {
+			intPart = "0"
+		}
+		if decimals == 0 {
+			return intPart, nil
+		}
+		return intPart + "." + value[len(value)-decimals:], nil
+	}
+	return value, nil
+}
+
+// gs1CheckDigitValid verifies the GS1 mod 10 check digit, the last digit of the numeric value.
+func gs1CheckDigitValid(value string) bool {
+	sum := 0
+	for i := len(value) - 2; i >= 0; i-- {
+		d := int(value[i] - '0')
+		if (len(value)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10-sum%10)%10 == int(value[len(value)-1]-'0')
+}
+
+// gs1DateValue converts a YYMMDD date into "YYYY-MM-DD". The year is assumed to be in the 2000s.
+// Per GS1 spec, day "00" means the last day of the month.
+func gs1DateValue(ai, yymmdd string) (string, error) {
+	day := yymmdd[4:]
+	if day == "00" {
+		day = "01"
+	}
+	t, err := time.Parse("20060102", "20"+yymmdd[:4]+day)
+	if err != nil {
+		return "", fmt.Errorf("application identifier (%s) value '%s' is not a valid date", ai, yymmdd)
+	}
+	if yymmdd[4:] == "00" {
+		t = t.AddDate(0, 1, -1)
+	}
+	return t.Format(isoDateLayout), nil
+}
+
+// GS1ElementStrings parses a GS1 element string (e.g. a GS1-128 barcode content or an EDI field
+// carrying one) into an object keyed by the application identifiers' names, e.g. "sscc", "gtin",
+// "batch_lot" and "expiry_date". Both the human readable form, with application identifiers in
+// parentheses, and the raw form, with variable length values terminated by FNC1 (ASCII group
+// separator), are supported. Dates are converted into "YYYY-MM-DD" and implied decimal places are
+// applied to measures and amounts. If s is empty, nil is returned.
+func GS1ElementStrings(_ *transformctx.Ctx, s string) (interface{}, error) {
+	elems, err := parseGS1ElementStrings(s)
+	if err != nil || len(elems) == 0 {
+		return nil, err
+	}
+	m := make(map[string]interface{}, len(elems))
+	for _, e := range elems {
+		m[e.decl.name] = e.value
+	}
+	return m, nil
+}
+
+// GS1AI parses a GS1 element string, the same way as GS1ElementStrings, and returns the value of
+// the given application identifier, e.g. "00" or "17". For decimal application identifiers, such as
+// "3103", either the full AI or its first 3 digits can be given. If the application identifier isn't
+// present, an empty string is returned.
+func GS1AI(_ *transformctx.Ctx, s, ai string) (string, error) {
+	elems, err := parseGS1ElementStrings(s)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range elems {
+		if e.ai == ai || e.decl.ai == ai {
+			return e.value, nil
+		}
+	}
+	return "", nil
+}
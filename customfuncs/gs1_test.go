@@ -0,0 +1,139 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGS1ElementStrings(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		s        string
+		expected interface{}
+		err      string
+	}{
+		{
+			name:     "empty",
+			s:        "  ",
+			expected: nil,
+		},
+		{
+			name: "human readable",
+			s:    "(00)106141411234567897(17)251200(10)LOT42(3103)001250",
+			expected: map[string]interface{}{
+				"sscc":          "106141411234567897",
+				"expiry_date":   "2025-12-31",
+				"batch_lot":     "LOT42",
+				"net_weight_kg": "1.250",
+			},
+		},
+		{
+			name: "raw with symbology identifier and FNC1",
+			s:    "]C10109506000134352" + "10LOT42\x1d" + "21S/N 1\x1d" + "15240229" + "3922999",
+			expected: map[string]interface{}{
+				"gtin":             "09506000134352",
+				"batch_lot":        "LOT42",
+				"serial":           "S/N 1",
+				"best_before_date": "2024-02-29",
+				"price":            "9.99",
+			},
+		},
+		{
+			name: "raw without FNC1 after last variable length value",
+			s:    "4104012345000016" + "37120",
+			expected: map[string]interface{}{
+				"ship_to_gln": "4012345000016",
+				"count":       "120",
+			},
+		},
+		{
+			name: "unknown ai",
+			s:    "(99)ABC",
+			err:  "unknown application identifier at '99ABC'",
+		},
+		{
+			name: "ai in parentheses too long",
+			s:    "(100)ABC",
+			err:  "unknown application identifier at '100ABC'",
+		},
+		{
+			name: "missing closing parenthesis",
+			s:    "(00106141411234567897",
+			err:  "missing ')' in '(00106141411234567897'",
+		},
+		{
+			name: "fixed length value too short",
+			s:    "0010614141123",
+			err:  "application identifier (00) needs 18 characters, but got 11",
+		},
+		{
+			name: "fixed length value too long in human readable",
+			s:    "(17)2512310",
+			err:  "application identifier (17) needs 6 characters, but got 7",
+		},
+		{
+			name: "variable length value too long",
+			s:    "(10)123456789012345678901",
+			err:  "application identifier (10) allows at most 20 characters, but got 21",
+		},
+		{
+			name: "non numeric",
+			s:    "(37)12A",
+			err:  "application identifier (37) value '12A' is not numeric",
+		},
+		{
+			name: "invalid check digit",
+			s:    "(00)106141411234567890",
+			err:  "application identifier (00) value '106141411234567890' has invalid check digit",
+		},
+		{
+			name: "invalid date",
+			s:    "(17)251331",
+			err:  "application identifier (17) value '251331' is not a valid date",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := GS1ElementStrings(nil, test.s)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, v)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, v)
+			}
+		})
+	}
+}
+
+func TestGS1AI(t *testing.T) {
+	const s = "(00)106141411234567897(17)251231(3102)012345(3920)5"
+	for _, test := range []struct {
+		name     string
+		s        string
+		ai       string
+		expected string
+		err      string
+	}{
+		{name: "sscc", s: s, ai: "00", expected: "106141411234567897"},
+		{name: "date", s: s, ai: "17", expected: "2025-12-31"},
+		{name: "decimal by full ai", s: s, ai: "3102", expected: "123.45"},
+		{name: "decimal by ai prefix", s: s, ai: "310", expected: "123.45"},
+		{name: "decimal with fewer digits than decimal places", s: "(3923)5", ai: "392", expected: "0.005"},
+		{name: "not present", s: s, ai: "10", expected: ""},
+		{name: "invalid", s: "(00)1", ai: "00", err: "application identifier (00) needs 18 characters, but got 1"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := GS1AI(nil, test.s, test.ai)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", v)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, v)
+			}
+		})
+	}
+}
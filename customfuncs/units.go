@@ -0,0 +1,115 @@
+package customfuncs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+type unit struct {
+	dimension string
+	factor    float64 // value in the dimension's base unit of 1 of this unit.
+}
+
+// units are the units supported by ConvertUnit, keyed by their lower-cased names. Besides the common
+// symbols, the X12 unit codes (DE 355) of cubic meter ("CR"), cubic feet ("CF"), Celsius ("CE") and
+// Fahrenheit ("FA") are supported. Factors are exact, as per the international yard and pound.
+var units = map[string]unit{
+	"kg":  {"weight", 1},
+	"lb":  {"weight", 0.45359237},
+	"lbs": {"weight", 0.45359237},
+	"cm":  {"length", 1},
+	"in":  {"length", 2.54},
+	"m3":  {"volume", 1},
+	"cr":  {"volume", 1},
+	"ft3": {"volume", 0.028316846592},
+	"cf":  {"volume", 0.028316846592},
+	// temperatures aren't proportional, thus special-cased in convert.
+	"c":  {"temperature", 0},
+	"ce": {"temperature", 0},
+	"f":  {"temperature", 0},
+	"fa": {"temperature", 0},
+}
+
+func lookupUnit(name string) (unit, error) {
+	u, found := units[strings.ToLower(strings.TrimSpace(name))]
+	if !found {
+		return unit{}, fmt.Errorf("unknown unit '%s'", name)
+	}
+	return u, nil
+}
+
+func isFahrenheit(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return name == "f" || name == "fa"
+}
+
+func convert(v float64, from, to string) (float64, error) {
+	fromUnit, err := lookupUnit(from)
+	if err != nil {
+		return 0, err
+	}
+	toUnit, err := lookupUnit(to)
+	if err != nil {
+		return 0, err
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return 0, fmt.Errorf("unable to convert '%s' (%s) to '%s' (%s)",
+			from, fromUnit.dimension, to, toUnit.dimension)
+	}
+	if fromUnit.dimension != "temperature" {
+		return v * fromUnit.factor / toUnit.factor, nil
+	}
+	if isFahrenheit(from) {
+		v = (v - 32) * 5 / 9
+	}
+	if isFahrenheit(to) {
+		v = v*9/5 + 32
+	}
+	return v, nil
+}
+
+// round rounds v to the given number of decimal places, half away from zero. v is first rounded to 12
+// significant digits, so that floating point errors of the conversion (e.g. 1.005 turning into
+// 1.00499999...) don't affect the rounding.
+func round(v float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	scaled, _ := strconv.ParseFloat(strconv.FormatFloat(v*p, 'g', 12, 64), 64)
+	return math.Round(scaled) / p
+}
+
+// ConvertUnit converts a numeric value from one unit to another of the same dimension: weight ("kg",
+// "lb"), length ("cm", "in"), volume ("m3", "ft3") and temperature ("C", "F"). Unit names are case
+// insensitive, and the X12 codes "CR" (cubic meter), "CF" (cubic feet), "CE" (Celsius) and "FA"
+// (Fahrenheit) are supported as well. If decimals isn't empty, the result is rounded, half away from
+// zero, to that many decimal places, and formatted with exactly that many; otherwise it's formatted
+// with as many as needed. If value is empty, an empty string is returned.
+func ConvertUnit(_ *transformctx.Ctx, value, from, to, decimals string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid value '%s'", value)
+	}
+	v, err = convert(v, from, to)
+	if err != nil {
+		return "", err
+	}
+	if decimals == "" {
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	d, err := strconv.Atoi(decimals)
+	if err != nil || d < 0 {
+		return "", fmt.Errorf("decimals must be a non-negative integer, but got '%s'", decimals)
+	}
+	v = round(v, d)
+	if v == 0 {
+		v = 0 // no "-0".
+	}
+	return strconv.FormatFloat(v, 'f', d, 64), nil
+}
@@ -0,0 +1,58 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertUnit(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		value    string
+		from     string
+		to       string
+		decimals string
+		expected string
+		err      string
+	}{
+		{name: "empty value", value: " ", from: "kg", to: "lb", expected: ""},
+		{name: "kg to lb", value: "10", from: "kg", to: "lb", decimals: "2", expected: "22.05"},
+		{name: "lb to kg", value: "1", from: "LB", to: "KG", expected: "0.45359237"},
+		{name: "lbs to kg", value: "2.5", from: "lbs", to: "kg", decimals: "0", expected: "1"},
+		{name: "cm to in", value: "254", from: "cm", to: "in", expected: "100"},
+		{name: "in to cm", value: "12", from: "in", to: "cm", decimals: "1", expected: "30.5"},
+		{name: "m3 to ft3", value: "1", from: "m3", to: "ft3", decimals: "3", expected: "35.315"},
+		{name: "x12 codes CF to CR", value: "100", from: "CF", to: "CR", decimals: "4", expected: "2.8317"},
+		{name: "C to F", value: "-40", from: "C", to: "F", expected: "-40"},
+		{name: "F to C", value: "98.6", from: "f", to: "c", decimals: "1", expected: "37.0"},
+		{name: "x12 codes CE to FA", value: "2", from: "CE", to: "FA", decimals: "2", expected: "35.60"},
+		{name: "same unit", value: "1.005", from: "kg", to: "kg", decimals: "2", expected: "1.01"},
+		{name: "round half away from zero", value: "-1.005", from: "kg", to: "kg", decimals: "2", expected: "-1.01"},
+		{name: "no negative zero", value: "-0.001", from: "kg", to: "kg", decimals: "1", expected: "0.0"},
+		{name: "invalid value", value: "abc", from: "kg", to: "lb", err: "invalid value 'abc'"},
+		{name: "unknown unit", value: "1", from: "kg", to: "stone", err: "unknown unit 'stone'"},
+		{
+			name:  "different dimensions",
+			value: "1", from: "kg", to: "cm",
+			err: "unable to convert 'kg' (weight) to 'cm' (length)",
+		},
+		{
+			name:  "invalid decimals",
+			value: "1", from: "kg", to: "lb", decimals: "-1",
+			err: "decimals must be a non-negative integer, but got '-1'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ConvertUnit(nil, test.value, test.from, test.to, test.decimals)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package customfuncs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+// X12AckCode returns the X12 acknowledgment code of a number of accepted records (e.g. transaction
+// sets) out of a number of received records, for the given acknowledgment transaction set:
+//   - "999" (and "997"), for AK901 or IK501: "A" (accepted), "P" (partially accepted) or "R" (rejected).
+//   - "824", for OTI01: "TA" (accepted), "TE" (accepted with errors) or "TR" (rejected).
+//
+// accepted can also be a boolean, such as transformctx.ValidationResult.Accepted, in which case
+// received can be left empty and defaults to 1.
+func X12AckCode(_ *transformctx.Ctx, ackType, accepted, received string) (string, error) {
+	acceptedNum, err := strconv.Atoi(accepted)
+	if err != nil {
+		b, berr := strconv.ParseBool(accepted)
+		if berr != nil {
+			return "", fmt.Errorf("accepted must be an integer or a boolean, but got '%s'", accepted)
+		}
+		acceptedNum = 0
+		if b {
+			acceptedNum = 1
+		}
+	}
+	receivedNum := 1
+	if received != "" {
+		if receivedNum, err = strconv.Atoi(received); err != nil {
+			return "", fmt.Errorf("received must be an integer, but got '%s'", received)
+		}
+	}
+	if acceptedNum < 0 || acceptedNum > receivedNum {
+		return "", fmt.Errorf("accepted (%d) must be between 0 and received (%d)", acceptedNum, receivedNum)
+	}
+	var codes [3]string // all accepted, some accepted, none accepted.
+	switch ackType {
+	case "997", "999":
+		codes = [3]string{"A", "P", "R"}
+	case "824":
+		codes = [3]string{"TA", "TE", "TR"}
+	default:
+		return "", fmt.Errorf("unsupported ack type '%s'", ackType)
+	}
+	switch acceptedNum {
+	case receivedNum:
+		return codes[0], nil
+	case 0:
+		return codes[2], nil
+	}
+	return codes[1], nil
+}
+
+// x12Delims are the characters commonly used as X12 delimiters, which can't appear in element data.
+const x12Delims = "~*:^|>\r\n"
+
+// X12Text makes a string, such as an error message, safe for an X12 free-form element (e.g. 824 NTE02
+// or 999 IK3/IK4 notes): characters commonly used as X12 delimiters are replaced by spaces, runs of
+// whitespaces are collapsed, and the result is truncated to maxLen characters if maxLen isn't empty.
+func X12Text(_ *transformctx.Ctx, s, maxLen string) (string, error) {
+	s = strings.Join(strings.Fields(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(x12Delims, r) {
+			return ' '
+		}
+		return r
+	}, s)), " ")
+	if maxLen == "" {
+		return s, nil
+	}
+	n, err := strconv.Atoi(maxLen)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("maxLen must be a non-negative integer, but got '%s'", maxLen)
+	}
+	if runes := []rune(s); len(runes) > n {
+		s = strings.TrimRight(string(runes[:n]), " ")
+	}
+	return s, nil
+}
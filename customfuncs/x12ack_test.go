@@ -0,0 +1,90 @@
+package customfuncs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestX12AckCode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		ackType  string
+		accepted string
+		received string
+		expected string
+		err      string
+	}{
+		{name: "999 all accepted", ackType: "999", accepted: "3", received: "3", expected: "A"},
+		{name: "999 partially accepted", ackType: "999", accepted: "2", received: "3", expected: "P"},
+		{name: "999 none accepted", ackType: "999", accepted: "0", received: "3", expected: "R"},
+		{name: "997 same as 999", ackType: "997", accepted: "1", received: "2", expected: "P"},
+		{name: "999 boolean accepted", ackType: "999", accepted: "true", expected: "A"},
+		{name: "999 boolean rejected", ackType: "999", accepted: "false", expected: "R"},
+		{name: "824 all accepted", ackType: "824", accepted: "2", received: "2", expected: "TA"},
+		{name: "824 partially accepted", ackType: "824", accepted: "1", received: "2", expected: "TE"},
+		{name: "824 boolean rejected", ackType: "824", accepted: "false", expected: "TR"},
+		{name: "nothing received", ackType: "999", accepted: "0", received: "0", expected: "A"},
+		{
+			name: "unsupported ack type", ackType: "855", accepted: "1",
+			err: "unsupported ack type '855'",
+		},
+		{
+			name: "invalid accepted", ackType: "999", accepted: "x",
+			err: "accepted must be an integer or a boolean, but got 'x'",
+		},
+		{
+			name: "invalid received", ackType: "999", accepted: "1", received: "x",
+			err: "received must be an integer, but got 'x'",
+		},
+		{
+			name: "accepted more than received", ackType: "999", accepted: "3", received: "2",
+			err: "accepted (3) must be between 0 and received (2)",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := X12AckCode(nil, test.ackType, test.accepted, test.received)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, code)
+			}
+		})
+	}
+}
+
+func TestX12Text(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		s        string
+		maxLen   string
+		expected string
+		err      string
+	}{
+		{name: "empty", s: "", expected: ""},
+		{
+			name:     "delimiters and whitespaces",
+			s:        "  segment 'N1*ST' failed:\r\n invalid~value  ",
+			expected: "segment 'N1 ST' failed invalid value",
+		},
+		{name: "truncated", s: "unable to convert value", maxLen: "10", expected: "unable to"},
+		{name: "not truncated", s: "short", maxLen: "10", expected: "short"},
+		{name: "multi-byte truncated", s: "héllo wörld", maxLen: "8", expected: "héllo wö"},
+		{name: "invalid maxLen", s: "x", maxLen: "-1", err: "maxLen must be a non-negative integer, but got '-1'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := X12Text(nil, test.s, test.maxLen)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, s)
+			}
+		})
+	}
+}
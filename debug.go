@@ -0,0 +1,42 @@
+package omniparser
+
+import (
+	"errors"
+	"io"
+
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// ErrDebugNotSupported indicates the handler of a schema doesn't support DebugRecord.
+var ErrDebugNotSupported = errors.New("schema handler doesn't support record debugging")
+
+// DebugRecord ingests the first record of an input and evaluates the target on it with tracing on, for
+// tools such as interactive mapping debuggers. The target is a `transform_declarations` name, such as
+// "FINAL_OUTPUT" (the default, if the target is empty) or a template name, or, if there is no decl of
+// that name, an xpath queried on the record. ctx is optional.
+//
+// For schemas handled by the builtin 'omni.2.1' schema handler, the JSON trace returned contains the
+// record's IDR and, for each transform evaluated, the node it's evaluated on, the xpath queries done
+// and the nodes they matched, the inputs and result of the custom_func invoked, and its value or error.
+func DebugRecord(s Schema, name string, input io.Reader, target string, ctx *transformctx.Ctx) ([]byte, error) {
+	sc, ok := s.(*schema)
+	if !ok {
+		return nil, ErrDebugNotSupported
+	}
+	debugger, ok := sc.handler.(schemahandler.RecordDebugger)
+	if !ok {
+		return nil, ErrDebugNotSupported
+	}
+	br, err := sc.wrapInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if ctx == nil {
+		ctx = &transformctx.Ctx{}
+	}
+	if ctx.InputName != name {
+		ctx.InputName = name
+	}
+	return debugger.DebugRecord(ctx, br, target)
+}
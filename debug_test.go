@@ -0,0 +1,48 @@
+package omniparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestDebugRecord(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": { "c": { "xpath": "c" } } }
+		}
+	}`))
+	assert.NoError(t, err)
+	ctx := &transformctx.Ctx{}
+	trace, err := DebugRecord(s, "test-input", strings.NewReader(`<a><b><c>1</c></b><b><c>2</c></b></a>`), "", ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-input", ctx.InputName)
+	var v map[string]interface{}
+	assert.NoError(t, json.Unmarshal(trace, &v))
+	assert.Equal(t, map[string]interface{}{"c": "1"}, v["trace"].(map[string]interface{})["value"])
+
+	trace, err = DebugRecord(s, "test-input", strings.NewReader(`<a><b><c>3</c></b></a>`), "c", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(trace, &v))
+	assert.Equal(t, []interface{}{"3"}, v["trace"].(map[string]interface{})["value"])
+}
+
+func TestDebugRecord_NotSupported(t *testing.T) {
+	s, err := NewSchema("test-schema",
+		strings.NewReader(`{"parser_settings": {"version": "9999", "file_format_type": "exe" }}`),
+		Extension{
+			CreateSchemaHandler: func(_ *schemahandler.CreateCtx) (schemahandler.SchemaHandler, error) {
+				return nil, nil
+			},
+		})
+	assert.NoError(t, err)
+	trace, err := DebugRecord(s, "test-input", strings.NewReader(""), "", nil)
+	assert.Equal(t, ErrDebugNotSupported, err)
+	assert.Nil(t, trace)
+}
@@ -0,0 +1,41 @@
+package omniparser
+
+import (
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/extensions/omniv21"
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+)
+
+// Format describes a schema version and file format type combination supported out of the box.
+type Format struct {
+	Version        string `json:"version"`          // `parser_settings.version`.
+	FileFormatType string `json:"file_format_type"` // `parser_settings.file_format_type`.
+	Deprecated     bool   `json:"deprecated,omitempty"`
+}
+
+// SupportedFormats returns the schema versions and file format types supported by the builtin schema
+// handler, for tools such as schema authoring UIs. Formats supported by Extensions aren't included.
+func SupportedFormats() []Format {
+	var formats []Format
+	for _, t := range omniv21.FileFormatTypes() {
+		formats = append(formats, Format{
+			Version:        omniv21.Version(),
+			FileFormatType: t.Name,
+			Deprecated:     t.Deprecated,
+		})
+	}
+	return formats
+}
+
+// BuiltinCustomFuncs describes the builtin custom funcs available to schemas handled by the builtin
+// schema handler, for tools such as schema authoring UIs. To describe the custom funcs of an Extension,
+// use customfuncs.Describe.
+func BuiltinCustomFuncs() []customfuncs.FuncDesc {
+	docs := map[string]customfuncs.FuncDoc{}
+	for _, d := range []map[string]customfuncs.FuncDoc{customfuncs.CommonCustomFuncDocs, v21.OmniV21CustomFuncDocs} {
+		for name, doc := range d {
+			docs[name] = doc
+		}
+	}
+	return customfuncs.Describe(defaultExt.CustomFuncs, docs)
+}
@@ -0,0 +1,28 @@
+package omniparser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportedFormats(t *testing.T) {
+	formats := SupportedFormats()
+	assert.Contains(t, formats, Format{Version: "omni.2.1", FileFormatType: "csv2"})
+	assert.Contains(t, formats, Format{Version: "omni.2.1", FileFormatType: "csv", Deprecated: true})
+	assert.Contains(t, formats, Format{Version: "omni.2.1", FileFormatType: "edi"})
+}
+
+func TestBuiltinCustomFuncs(t *testing.T) {
+	descs := BuiltinCustomFuncs()
+	assert.Equal(t, len(defaultExt.CustomFuncs), len(descs))
+	concatFound := false
+	for _, desc := range descs {
+		assert.NotEmpty(t, desc.Doc, desc.Name)
+		if desc.Name == "concat" {
+			concatFound = true
+			assert.True(t, desc.Variadic)
+		}
+	}
+	assert.True(t, concatFound)
+}
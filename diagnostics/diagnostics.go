@@ -0,0 +1,362 @@
+// Package diagnostics reports the problems of a schema as structured diagnostics located in the schema
+// content, for tools such as editor plugins and language servers to show them inline while schemas
+// are being authored.
+package diagnostics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/validation"
+)
+
+// Severity is the severity of a Diagnostic. The values are the same as LSP's DiagnosticSeverity.
+type Severity int
+
+const (
+	SeverityError       Severity = 1
+	SeverityWarning     Severity = 2
+	SeverityInformation Severity = 3
+	SeverityHint        Severity = 4
+)
+
+// Codes of the Diagnostics.
+const (
+	CodeJSONSyntax = "json_syntax"
+	// CodeJSONSchemaPrefix prefixes the type of a JSON schema violation, e.g. "json_schema.required".
+	CodeJSONSchemaPrefix     = "json_schema."
+	CodeSchemaNotSupported   = "schema_not_supported"
+	CodeUnknownCustomFunc    = "unknown_custom_func"
+	CodeUnknownTemplate      = "unknown_template"
+	CodeUnknownConstant      = "unknown_constant"
+	CodeInvalidSchema        = "invalid_schema"
+	CodeDeprecatedFileFormat = "deprecated_file_format"
+)
+
+// Position is a 0-based position in a schema. Like LSP's, Character counts UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a schema. End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Fix is a suggested fix to a Diagnostic: replacing the text in Range with NewText.
+type Fix struct {
+	Title   string `json:"title"`
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// Diagnostic is a problem found in a schema.
+type Diagnostic struct {
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Fix      *Fix     `json:"fix,omitempty"`
+}
+
+// Diagnose validates a schema the same way omniparser.NewSchema does, with the same optional exts,
+// and returns the problems found, or nil if none. Like omniparser.NewSchema, it stops at the first
+// problem found beyond the JSON syntax and JSON schema checks, so fixing a problem may reveal the
+// next one.
+func Diagnose(schemaName string, schemaContent []byte, exts ...omniparser.Extension) []Diagnostic {
+	var v interface{}
+	if err := json.Unmarshal(schemaContent, &v); err != nil {
+		return []Diagnostic{syntaxDiagnostic(schemaContent, err)}
+	}
+	l := newLocator(schemaContent)
+	var diags []Diagnostic
+	_, err := omniparser.NewSchema(schemaName, bytes.NewReader(schemaContent), exts...)
+	var errValidation *validation.ErrValidation
+	switch {
+	case err == nil:
+	case errors.As(err, &errValidation):
+		for _, violation := range errValidation.Violations {
+			diags = append(diags, violationDiagnostic(l, violation))
+		}
+	case err == errs.ErrSchemaNotSupported:
+		diags = append(diags, notSupportedDiagnostic(l, err))
+	default:
+		diags = append(diags, schemaDiagnostic(l, err, exts))
+	}
+	return append(diags, deprecationDiagnostics(l)...)
+}
+
+func syntaxDiagnostic(content []byte, err error) Diagnostic {
+	offset := 0
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset is right after the offending byte.
+		offset = int(syntaxErr.Offset) - 1
+	}
+	if offset < 0 || offset >= len(content) {
+		offset = 0
+	}
+	l := newLineLocator(content)
+	end := offset
+	if offset < len(content) {
+		end++
+	}
+	return Diagnostic{
+		Range:    l.rangeOf(offset, end),
+		Severity: SeverityError,
+		Code:     CodeJSONSyntax,
+		Message:  err.Error(),
+	}
+}
+
+func violationDiagnostic(l *locator, v validation.Violation) Diagnostic {
+	path := v.Field
+	if path == "(root)" {
+		path = ""
+	}
+	d := Diagnostic{
+		Range:    l.anchorRange(path),
+		Severity: SeverityError,
+		Code:     CodeJSONSchemaPrefix + v.Type,
+		Message:  v.String(),
+	}
+	switch v.Type {
+	case "additional_property_not_allowed":
+		if property, ok := v.Details["property"].(string); ok {
+			if r, found := l.keyRange(joinPath(path, property)); found {
+				d.Range = r
+			}
+		}
+	case "enum":
+		value, ok := l.stringValue(path)
+		allowed, _ := v.Details["allowed"].(string)
+		var allowedValues []interface{}
+		if !ok || json.Unmarshal([]byte("["+allowed+"]"), &allowedValues) != nil {
+			break
+		}
+		var candidates []string
+		for _, a := range allowedValues {
+			if s, ok := a.(string); ok {
+				candidates = append(candidates, s)
+			}
+		}
+		d.Fix = replaceFix(l, path, value, candidates)
+	}
+	return d
+}
+
+func notSupportedDiagnostic(l *locator, err error) Diagnostic {
+	version, _ := l.stringValue("parser_settings.version")
+	fileFormatType, _ := l.stringValue("parser_settings.file_format_type")
+	d := Diagnostic{
+		Severity: SeverityError,
+		Code:     CodeSchemaNotSupported,
+		Message: fmt.Sprintf("%s: no schema handler supports version '%s' with file_format_type '%s'",
+			err, version, fileFormatType),
+	}
+	var versions, fileFormatTypes []string
+	for _, f := range omniparser.SupportedFormats() {
+		versions = append(versions, f.Version)
+		if f.Version == version {
+			fileFormatTypes = append(fileFormatTypes, f.FileFormatType)
+		}
+	}
+	path, candidates := "parser_settings.version", versions
+	if len(fileFormatTypes) > 0 {
+		path, candidates = "parser_settings.file_format_type", fileFormatTypes
+	}
+	d.Range = l.anchorRange(path)
+	current, _ := l.stringValue(path)
+	d.Fix = replaceFix(l, path, current, candidates)
+	return d
+}
+
+var (
+	quotedRegexp            = regexp.MustCompile(`'([^']*)'`)
+	unknownCustomFuncRegexp = regexp.MustCompile(`unknown custom_func '([^']*)' on '([^']*)'`)
+	unknownTemplateRegexp   = regexp.MustCompile(`'([^']*)' contains non-existing template reference '([^']*)'`)
+	unknownConstantRegexp   = regexp.MustCompile(`'([^']*)' contains non-existing constant reference '([^']*)'`)
+)
+
+const (
+	transformDeclarationsFailed = "'transform_declarations' validation failed: "
+	recordOrderFailed           = "'record_order' validation failed: "
+)
+
+// schemaDiagnostic locates an error, other than a JSON schema violation, returned by omniparser.NewSchema,
+// by resolving the transform fqdns quoted in the error message.
+func schemaDiagnostic(l *locator, err error, exts []omniparser.Extension) Diagnostic {
+	msg := err.Error()
+	d := Diagnostic{Severity: SeverityError, Code: CodeInvalidSchema, Message: msg}
+	switch {
+	case strings.Contains(msg, transformDeclarationsFailed):
+		d.Range, _ = l.keyRange("transform_declarations")
+		// skip the schema name quoted before, which could be mistaken for an fqdn.
+		msg = msg[strings.Index(msg, transformDeclarationsFailed)+len(transformDeclarationsFailed):]
+	case strings.Contains(msg, recordOrderFailed):
+		d.Range, _ = l.keyRange("record_order")
+		msg = msg[strings.Index(msg, recordOrderFailed)+len(recordOrderFailed):]
+	case l.has("file_declaration"):
+		d.Range, _ = l.keyRange("file_declaration")
+		return d
+	default:
+		return d
+	}
+	if path, ok := bestResolved(l, msg); ok {
+		d.Range = l.anchorRange(path)
+	}
+	for _, unknown := range []struct {
+		re         *regexp.Regexp
+		nameGroup  int
+		fqdnGroup  int
+		code       string
+		field      string
+		candidates func() []string
+	}{
+		{unknownCustomFuncRegexp, 1, 2, CodeUnknownCustomFunc, "custom_func.name", func() []string {
+			return customFuncNames(exts)
+		}},
+		{unknownTemplateRegexp, 2, 1, CodeUnknownTemplate, "template", func() []string {
+			return l.keys["transform_declarations"]
+		}},
+		{unknownConstantRegexp, 2, 1, CodeUnknownConstant, "constant", func() []string {
+			return l.keys["constants"]
+		}},
+	} {
+		m := unknown.re.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		d.Code = unknown.code
+		path, ok := l.resolveFQDN(m[unknown.fqdnGroup])
+		if !ok || !l.has(joinPath(path, unknown.field)) {
+			break
+		}
+		path = joinPath(path, unknown.field)
+		d.Range = l.anchorRange(path)
+		d.Fix = replaceFix(l, path, m[unknown.nameGroup], unknown.candidates())
+		break
+	}
+	return d
+}
+
+// bestResolved returns the path of the decl of the first fully resolved fqdn quoted in msg or, if
+// none, of the deepest decl partially resolved.
+func bestResolved(l *locator, msg string) (string, bool) {
+	best := ""
+	for _, m := range quotedRegexp.FindAllStringSubmatch(msg, -1) {
+		path, ok := l.resolveFQDN(m[1])
+		if ok {
+			return path, true
+		}
+		if len(path) > len(best) {
+			best = path
+		}
+	}
+	return best, best != ""
+}
+
+func customFuncNames(exts []omniparser.Extension) []string {
+	var names []string
+	for _, ext := range exts {
+		for name := range ext.CustomFuncs {
+			names = append(names, name)
+		}
+	}
+	for _, f := range omniparser.BuiltinCustomFuncs() {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func deprecationDiagnostics(l *locator) []Diagnostic {
+	version, _ := l.stringValue("parser_settings.version")
+	fileFormatType, _ := l.stringValue("parser_settings.file_format_type")
+	for _, f := range omniparser.SupportedFormats() {
+		if f.Deprecated && f.Version == version && f.FileFormatType == fileFormatType {
+			return []Diagnostic{{
+				Range:    l.anchorRange("parser_settings.file_format_type"),
+				Severity: SeverityWarning,
+				Code:     CodeDeprecatedFileFormat,
+				Message:  fmt.Sprintf("file_format_type '%s' is deprecated", fileFormatType),
+			}}
+		}
+	}
+	return nil
+}
+
+// replaceFix suggests replacing the string value at the path with the candidate closest to it, if
+// any candidate is close enough.
+func replaceFix(l *locator, path, value string, candidates []string) *Fix {
+	closest, ok := closestMatch(value, candidates)
+	r, found := l.valueRange(path)
+	if !ok || !found {
+		return nil
+	}
+	newText, _ := json.Marshal(closest)
+	return &Fix{Title: fmt.Sprintf("Change to '%s'", closest), Range: r, NewText: string(newText)}
+}
+
+// closestMatch returns the candidate with the smallest case-insensitive edit distance to s, if the
+// distance is small enough for the candidate to be a likely fix of a typo in s.
+func closestMatch(s string, candidates []string) (string, bool) {
+	candidates = append([]string(nil), candidates...)
+	sort.Strings(candidates)
+	lower := []rune(strings.ToLower(s))
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		if c == s {
+			continue
+		}
+		if dist := editDistance(lower, []rune(strings.ToLower(c))); bestDist < 0 || dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	maxDist := len(lower) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+	if bestDist < 0 || bestDist > maxDist {
+		return "", false
+	}
+	return best, true
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a int, rest ...int) int {
+	for _, r := range rest {
+		if r < a {
+			a = r
+		}
+	}
+	return a
+}
@@ -0,0 +1,282 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// at returns the range of the first occurrence of the needle in the ASCII schema.
+func at(schema, needle string) Range {
+	start := strings.Index(schema, needle)
+	pos := func(offset int) Position {
+		return Position{
+			Line:      strings.Count(schema[:offset], "\n"),
+			Character: offset - (strings.LastIndex(schema[:offset], "\n") + 1),
+		}
+	}
+	return Range{Start: pos(start), End: pos(start + len(needle))}
+}
+
+func TestDiagnose_NoProblem(t *testing.T) {
+	assert.Nil(t, Diagnose("test", []byte(`{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } }
+}`)))
+}
+
+func TestDiagnose_JSONSyntax(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." }, ]
+}`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, "]"),
+		Severity: SeverityError,
+		Code:     CodeJSONSyntax,
+		Message:  "invalid character ']' looking for beginning of object key string",
+	}}, Diagnose("test", []byte(schema)))
+
+	schema = `{ "parser_settings":`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, ":"),
+		Severity: SeverityError,
+		Code:     CodeJSONSyntax,
+		Message:  "unexpected end of JSON input",
+	}}, Diagnose("test", []byte(schema)))
+}
+
+func TestDiagnose_JSONSchemaViolations(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json", "encoding": "UTF-8", "unknown": 1 },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } }
+}`
+	assert.Equal(t, []Diagnostic{
+		{
+			Range:    at(schema, `"UTF-8"`),
+			Severity: SeverityError,
+			Code:     "json_schema.enum",
+			Message: `parser_settings.encoding: parser_settings.encoding must be one of the following: ` +
+				`"utf-8", "iso-8859-1", "windows-1252", "utf-16le", "utf-16be", "auto"`,
+			Fix: &Fix{Title: "Change to 'utf-8'", Range: at(schema, `"UTF-8"`), NewText: `"utf-8"`},
+		},
+		{
+			Range:    at(schema, `"unknown"`),
+			Severity: SeverityError,
+			Code:     "json_schema.additional_property_not_allowed",
+			Message:  "parser_settings: Additional property unknown is not allowed",
+		},
+	}, Diagnose("test", []byte(schema)))
+
+	schema = `{ "parser_settings": { "version": "omni.2.1", "file_format_type": "json" } }`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, "{"),
+		Severity: SeverityError,
+		Code:     "json_schema.required",
+		Message:  "(root): transform_declarations is required",
+	}}, Diagnose("test", []byte(schema)))
+}
+
+func TestDiagnose_SchemaNotSupported(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "jsn" },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } }
+}`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, `"jsn"`),
+		Severity: SeverityError,
+		Code:     CodeSchemaNotSupported,
+		Message:  "schema not supported: no schema handler supports version 'omni.2.1' with file_format_type 'jsn'",
+		Fix:      &Fix{Title: "Change to 'json'", Range: at(schema, `"jsn"`), NewText: `"json"`},
+	}}, Diagnose("test", []byte(schema)))
+
+	schema = `{ "parser_settings": { "version": "omni.2.2", "file_format_type": "json" } }`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, `"omni.2.2"`),
+		Severity: SeverityError,
+		Code:     CodeSchemaNotSupported,
+		Message:  "schema not supported: no schema handler supports version 'omni.2.2' with file_format_type 'json'",
+		Fix:      &Fix{Title: "Change to 'omni.2.1'", Range: at(schema, `"omni.2.2"`), NewText: `"omni.2.1"`},
+	}}, Diagnose("test", []byte(schema)))
+}
+
+func TestDiagnose_UnknownReferences(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		schema string
+		exts   []omniparser.Extension
+		code   string
+		msg    string
+		at     string
+		fix    string
+	}{
+		{
+			name: "unknown custom_func in template",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": {
+    "FINAL_OUTPUT": { "object": { "a.b": { "template": "t" } } },
+    "t": { "object": { "c": { "custom_func": { "name": "uppper", "args": [ { "xpath": "." } ] } } } }
+  }
+}`,
+			code: CodeUnknownCustomFunc,
+			msg:  "unknown custom_func 'uppper' on 'FINAL_OUTPUT.a%.b.c'",
+			at:   `"uppper"`,
+			fix:  "upper",
+		},
+		{
+			name: "unknown custom_func with extension",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": {
+    "FINAL_OUTPUT": { "custom_func": { "name": "my_fnuc" } }
+  }
+}`,
+			exts: []omniparser.Extension{{
+				CustomFuncs: customfuncs.CustomFuncs{
+					"my_func": func(_ *transformctx.Ctx) (string, error) { return "", nil },
+				},
+			}},
+			code: CodeUnknownCustomFunc,
+			msg:  "unknown custom_func 'my_fnuc' on 'FINAL_OUTPUT'",
+			at:   `"my_fnuc"`,
+			fix:  "my_func",
+		},
+		{
+			name: "unknown template in array, no close match",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": {
+    "FINAL_OUTPUT": { "array": [ { "xpath": "." }, { "template": "item_tmpl" } ] },
+    "item_template": { "xpath": "." }
+  }
+}`,
+			code: CodeUnknownTemplate,
+			msg:  "'FINAL_OUTPUT.elem[2]' contains non-existing template reference 'item_tmpl'",
+			at:   `"item_tmpl"`,
+		},
+		{
+			name: "unknown constant in custom_func arg",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "constants": { "currency": "USD" },
+  "transform_declarations": {
+    "FINAL_OUTPUT": { "object": { "ccy": { "custom_func": { "name": "lower", "args": [ { "constant": "curency" } ] } } } }
+  }
+}`,
+			code: CodeUnknownConstant,
+			msg:  "'FINAL_OUTPUT.ccy.custom_func(lower).arg[1]' contains non-existing constant reference 'curency'",
+			at:   `"curency"`,
+			fix:  "currency",
+		},
+		{
+			name: "other transform error",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": {
+    "FINAL_OUTPUT": { "object": { "x": { "xpath": "a", "xpath_dynamic": { "const": "b" } } } }
+  }
+}`,
+			code: CodeInvalidSchema,
+			msg:  "'FINAL_OUTPUT.x' cannot set both 'xpath' and 'xpath_dynamic' at the same time",
+			at:   `"x"`,
+		},
+		{
+			name: "circular template reference",
+			schema: `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "transform_declarations": {
+    "t1": { "template": "t1" },
+    "FINAL_OUTPUT": { "template": "t1" }
+  }
+}`,
+			code: CodeInvalidSchema,
+			msg:  "template circular reference detected: 't1' (line 4) -> 't1' (line 4)",
+			at:   `"t1"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			at := at(test.schema, test.at)
+			expected := Diagnostic{
+				Range:    at,
+				Severity: SeverityError,
+				Code:     test.code,
+				Message:  "schema 'test' 'transform_declarations' validation failed: " + test.msg,
+			}
+			if test.fix != "" {
+				expected.Fix = &Fix{Title: "Change to '" + test.fix + "'", Range: at, NewText: `"` + test.fix + `"`}
+			}
+			assert.Equal(t, []Diagnostic{expected}, Diagnose("test", []byte(test.schema), test.exts...))
+		})
+	}
+}
+
+func TestDiagnose_FileDeclaration(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+  "file_declaration": { "delimiter": ",", "records": [ { "name": "a", "is_target": true }, { "name": "b", "is_target": true } ] },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } }
+}`
+	diags := Diagnose("test", []byte(schema))
+	assert.Equal(t, 1, len(diags))
+	assert.Equal(t, at(schema, `"file_declaration"`), diags[0].Range)
+	assert.Equal(t, CodeInvalidSchema, diags[0].Code)
+	assert.Contains(t, diags[0].Message, "is_target")
+}
+
+func TestDiagnose_DeprecatedFileFormat(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "csv" },
+  "file_declaration": { "delimiter": ",", "header_row_index": 1, "data_row_index": 2, "columns": [ { "name": "a" } ] },
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "a" } }
+}`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, `"csv"`),
+		Severity: SeverityWarning,
+		Code:     CodeDeprecatedFileFormat,
+		Message:  "file_format_type 'csv' is deprecated",
+	}}, Diagnose("test", []byte(schema)))
+}
+
+func TestClosestMatch(t *testing.T) {
+	for _, test := range []struct {
+		s          string
+		candidates []string
+		expected   string
+		expectedOK bool
+	}{
+		{"uppper", []string{"lower", "upper"}, "upper", true},
+		{"UTF-8", []string{"utf-16le", "utf-8"}, "utf-8", true},
+		{"ab", []string{"xb", "cd", "ax"}, "ax", true},
+		{"xyz", []string{"abc"}, "", false},
+		{"upper", []string{"upper"}, "", false},
+		{"a", nil, "", false},
+	} {
+		t.Run(test.s, func(t *testing.T) {
+			closest, ok := closestMatch(test.s, test.candidates)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expected, closest)
+		})
+	}
+}
+
+func TestDiagnose_RecordOrder(t *testing.T) {
+	schema := `{
+  "parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+  "file_declaration": {},
+  "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } },
+  "record_order": { "key": { "custom_func": { "name": "lowr", "args": [ { "xpath": "a" } ] } }, "window": 10 }
+}`
+	assert.Equal(t, []Diagnostic{{
+		Range:    at(schema, `"lowr"`),
+		Severity: SeverityError,
+		Code:     CodeUnknownCustomFunc,
+		Message:  "schema 'test' 'record_order' validation failed: unknown custom_func 'lowr' on 'record_order.key'",
+		Fix:      &Fix{Title: "Change to 'lower'", Range: at(schema, `"lowr"`), NewText: `"lower"`},
+	}}, Diagnose("test", []byte(schema)))
+}
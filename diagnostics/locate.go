@@ -0,0 +1,259 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jf-tech/go-corelib/strs"
+)
+
+// span is the location of a value, and of its key if any, in a schema, as byte offsets.
+type span struct {
+	keyStart, keyEnd int // -1 if the value has no key, i.e. it's the root or an array element.
+	start, end       int
+}
+
+// locator locates the values of a schema by their paths. A path, like the `Field` of a JSON schema
+// violation, consists of the object keys and array indexes leading to the value, delimited by ".";
+// the path of the root value is "".
+type locator struct {
+	content    []byte
+	lineStarts []int
+	spans      map[string]span
+	keys       map[string][]string // keys of each object, in the order they appear, keyed by its path.
+}
+
+// newLocator creates a locator for the schema content, which must be valid JSON.
+func newLocator(content []byte) *locator {
+	l := newLineLocator(content)
+	l.spans, l.keys = map[string]span{}, map[string][]string{}
+	l.scan(0, "", -1, -1)
+	return l
+}
+
+// newLineLocator creates a locator that can only convert byte offsets into Positions, for content that
+// isn't valid JSON.
+func newLineLocator(content []byte) *locator {
+	l := &locator{content: content, lineStarts: []int{0}}
+	for i, b := range content {
+		if b == '\n' {
+			l.lineStarts = append(l.lineStarts, i+1)
+		}
+	}
+	return l
+}
+
+func joinPath(path string, seg string) string {
+	if path == "" {
+		return seg
+	}
+	return path + "." + seg
+}
+
+// scan records the span of the value starting at or after offset i, and of its descendants, and
+// returns the offset right after the value.
+func (l *locator) scan(i int, path string, keyStart, keyEnd int) int {
+	i = l.skipSpace(i)
+	start := i
+	switch l.content[i] {
+	case '{':
+		l.keys[path] = []string{}
+		i = l.skipSpace(i + 1)
+		for l.content[i] != '}' {
+			ks := i
+			i = l.skipString(i)
+			ke := i
+			var key string
+			_ = json.Unmarshal(l.content[ks:ke], &key)
+			l.keys[path] = append(l.keys[path], key)
+			i = l.skipSpace(l.skipSpace(i) + 1) // skip the ':'.
+			i = l.skipSpace(l.scan(i, joinPath(path, key), ks, ke))
+			if l.content[i] == ',' {
+				i = l.skipSpace(i + 1)
+			}
+		}
+		i++
+	case '[':
+		i = l.skipSpace(i + 1)
+		for index := 0; l.content[i] != ']'; index++ {
+			i = l.skipSpace(l.scan(i, joinPath(path, strconv.Itoa(index)), -1, -1))
+			if l.content[i] == ',' {
+				i = l.skipSpace(i + 1)
+			}
+		}
+		i++
+	case '"':
+		i = l.skipString(i)
+	default:
+		for i < len(l.content) && !strings.ContainsRune(",}] \t\r\n", rune(l.content[i])) {
+			i++
+		}
+	}
+	// Like json.Unmarshal, the last one wins if an object has duplicate keys.
+	l.spans[path] = span{keyStart: keyStart, keyEnd: keyEnd, start: start, end: i}
+	return i
+}
+
+func (l *locator) skipSpace(i int) int {
+	for i < len(l.content) && strings.ContainsRune(" \t\r\n", rune(l.content[i])) {
+		i++
+	}
+	return i
+}
+
+// skipString returns the offset right after the string starting at offset i.
+func (l *locator) skipString(i int) int {
+	for i++; l.content[i] != '"'; i++ {
+		if l.content[i] == '\\' {
+			i++
+		}
+	}
+	return i + 1
+}
+
+func (l *locator) has(path string) bool {
+	_, found := l.spans[path]
+	return found
+}
+
+// stringValue returns the value at the path, if it's a string.
+func (l *locator) stringValue(path string) (string, bool) {
+	s, found := l.spans[path]
+	if !found || l.content[s.start] != '"' {
+		return "", false
+	}
+	var v string
+	_ = json.Unmarshal(l.content[s.start:s.end], &v)
+	return v, true
+}
+
+// position converts a byte offset into a Position.
+func (l *locator) position(offset int) Position {
+	line := sort.Search(len(l.lineStarts), func(i int) bool { return l.lineStarts[i] > offset }) - 1
+	character := 0
+	for b := l.content[l.lineStarts[line]:offset]; len(b) > 0; {
+		r, size := utf8.DecodeRune(b)
+		b = b[size:]
+		// like LSP, characters are counted in UTF-16 code units.
+		character++
+		if r >= 0x10000 {
+			character++
+		}
+	}
+	return Position{Line: line, Character: character}
+}
+
+func (l *locator) rangeOf(start, end int) Range {
+	return Range{Start: l.position(start), End: l.position(end)}
+}
+
+// keyRange returns the range of the key of the value at the path, if the value has a key.
+func (l *locator) keyRange(path string) (Range, bool) {
+	s, found := l.spans[path]
+	if !found || s.keyStart < 0 {
+		return Range{}, false
+	}
+	return l.rangeOf(s.keyStart, s.keyEnd), true
+}
+
+// valueRange returns the range of the value at the path.
+func (l *locator) valueRange(path string) (Range, bool) {
+	s, found := l.spans[path]
+	if !found {
+		return Range{}, false
+	}
+	return l.rangeOf(s.start, s.end), true
+}
+
+// anchorRange returns the range a problem with the value at the path is best shown at: the value
+// itself if it's a string, number, boolean or null; otherwise, to not highlight an entire object
+// or array, its key, or its opening brace or bracket if it has no key.
+func (l *locator) anchorRange(path string) Range {
+	s, found := l.spans[path]
+	switch {
+	case !found:
+		return Range{}
+	case l.content[s.start] != '{' && l.content[s.start] != '[':
+		return l.rangeOf(s.start, s.end)
+	case s.keyStart >= 0:
+		return l.rangeOf(s.keyStart, s.keyEnd)
+	default:
+		return l.rangeOf(s.start, s.start+1)
+	}
+}
+
+var (
+	argRegexp  = regexp.MustCompile(`^arg\[(\d+)\]$`)
+	elemRegexp = regexp.MustCompile(`^elem\[(\d+)\]$`)
+)
+
+// resolveFQDN resolves the fqdn of a transform, as used in the transform validation error messages,
+// e.g. "FINAL_OUTPUT.items.elem[1].custom_func(concat).arg[2]" or "record_order.key", into the path of
+// its decl in the schema, following template references where needed. If the fqdn can't be fully
+// resolved, it returns the path of the deepest decl resolved and false. If not even the first segment
+// of the fqdn can be resolved, it returns "" and false.
+func (l *locator) resolveFQDN(fqdn string) (string, bool) {
+	var segs []string
+	for _, seg := range strs.SplitWithEsc(fqdn, strs.FQDNDelimiter, strs.FQDNEsc) {
+		segs = append(segs, strs.Unescape(seg, strs.FQDNEsc))
+	}
+	if len(segs) == 0 {
+		return "", false
+	}
+	path := joinPath("transform_declarations", segs[0])
+	// the keys of `record_order` are decls outside of `transform_declarations`.
+	if segs[0] == "record_order" && len(segs) > 1 {
+		path, segs = joinPath(segs[0], segs[1]), segs[1:]
+	}
+	if !l.has(path) {
+		return "", false
+	}
+	for _, seg := range segs[1:] {
+		next, found := l.step(path, seg)
+		// the decl at the path may be a template reference, in which case the fqdn continues into
+		// the template referenced. A hop limit guards against circular references.
+		for hops := 0; !found && hops < 64; hops++ {
+			name, ok := l.stringValue(joinPath(path, "template"))
+			if !ok || !l.has(joinPath("transform_declarations", name)) {
+				break
+			}
+			path = joinPath("transform_declarations", name)
+			next, found = l.step(path, seg)
+		}
+		if !found {
+			return path, false
+		}
+		path = next
+	}
+	return path, true
+}
+
+// step returns the path of the child decl identified by the fqdn segment seg of the decl at the path.
+func (l *locator) step(path, seg string) (string, bool) {
+	var candidates []string
+	switch {
+	case seg == "xpath_dynamic" || seg == "key":
+		candidates = append(candidates, joinPath(path, seg))
+	case strings.HasPrefix(seg, "custom_func(") && strings.HasSuffix(seg, ")"):
+		candidates = append(candidates, joinPath(path, "custom_func"))
+	case argRegexp.MatchString(seg):
+		n, _ := strconv.Atoi(argRegexp.FindStringSubmatch(seg)[1])
+		candidates = append(candidates, fmt.Sprintf("%s.args.%d", path, n-1))
+	case elemRegexp.MatchString(seg):
+		n, _ := strconv.Atoi(elemRegexp.FindStringSubmatch(seg)[1])
+		candidates = append(candidates, fmt.Sprintf("%s.array.%d", path, n-1))
+	}
+	// an object field may be named like any of the above.
+	candidates = append(candidates, joinPath(joinPath(path, "object"), seg))
+	for _, c := range candidates {
+		if l.has(c) {
+			return c, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package diagnostics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocator(t *testing.T) {
+	l := newLocator([]byte("{\n  \"a\\\"b\": [ 1, \"😀x\", { \"c\": null } ],\n  \"d\": {}\n}"))
+	assert.Equal(t, []string{`a"b`, "d"}, l.keys[""])
+	assert.Equal(t, []string{"c"}, l.keys[`a"b.2`])
+	r, ok := l.keyRange(`a"b`)
+	assert.True(t, ok)
+	assert.Equal(t, Range{Start: Position{1, 2}, End: Position{1, 8}}, r)
+	_, ok = l.keyRange(`a"b.0`)
+	assert.False(t, ok)
+	r, ok = l.valueRange(`a"b.1`)
+	assert.True(t, ok)
+	// the emoji takes 2 UTF-16 code units.
+	assert.Equal(t, Range{Start: Position{1, 15}, End: Position{1, 20}}, r)
+	s, ok := l.stringValue(`a"b.1`)
+	assert.True(t, ok)
+	assert.Equal(t, "😀x", s)
+	_, ok = l.stringValue(`a"b.0`)
+	assert.False(t, ok)
+	assert.Equal(t, Range{Start: Position{1, 22}, End: Position{1, 23}}, l.anchorRange(`a"b.2`))
+	assert.Equal(t, Range{Start: Position{1, 29}, End: Position{1, 33}}, l.anchorRange(`a"b.2.c`))
+	assert.Equal(t, Range{Start: Position{2, 2}, End: Position{2, 5}}, l.anchorRange("d"))
+	assert.Equal(t, Range{Start: Position{0, 0}, End: Position{0, 1}}, l.anchorRange(""))
+	assert.Equal(t, Range{}, l.anchorRange("x"))
+}
+
+func TestLocator_ResolveFQDN(t *testing.T) {
+	l := newLocator([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"a.b": { "template": "t1" },
+				"elem[1]": { "const": "x" },
+				"c": { "xpath_dynamic": { "custom_func": { "name": "f", "args": [ { "const": "1" }, { "template": "t2" } ] } } }
+			} },
+			"t1": { "template": "t2" },
+			"t2": { "array": [ { "const": "y", "key": { "const": "k" } } ] }
+		}
+	}`))
+	for _, test := range []struct {
+		fqdn         string
+		expectedPath string
+		expectedOK   bool
+	}{
+		{"FINAL_OUTPUT", "transform_declarations.FINAL_OUTPUT", true},
+		{"FINAL_OUTPUT.a%.b", "transform_declarations.FINAL_OUTPUT.object.a.b", true},
+		{"FINAL_OUTPUT.a%.b.elem[1].key", "transform_declarations.t2.array.0.key", true},
+		{"FINAL_OUTPUT.elem[1]", "transform_declarations.FINAL_OUTPUT.object.elem[1]", true},
+		{"FINAL_OUTPUT.c.xpath_dynamic.custom_func(f).arg[1]",
+			"transform_declarations.FINAL_OUTPUT.object.c.xpath_dynamic.custom_func.args.0", true},
+		{"FINAL_OUTPUT.c.xpath_dynamic.custom_func(f).arg[2].elem[1]", "transform_declarations.t2.array.0", true},
+		{"FINAL_OUTPUT.c.xpath_dynamic.custom_func(f).arg[3]",
+			"transform_declarations.FINAL_OUTPUT.object.c.xpath_dynamic.custom_func", false},
+		{"FINAL_OUTPUT.a%.b.elem[2]", "transform_declarations.t2", false},
+		{"t3.x", "", false},
+		{"", "", false},
+	} {
+		t.Run(test.fqdn, func(t *testing.T) {
+			path, ok := l.resolveFQDN(test.fqdn)
+			assert.Equal(t, test.expectedPath, path)
+			assert.Equal(t, test.expectedOK, ok)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// ErrorBudget is the number of failed records, i.e. the ones Read returns errs.ErrTransformFailed for,
+// a Transform tolerates before aborting the whole input, for feeds where partial output is worse than
+// none at all. The zero value tolerates no failed record at all.
+type ErrorBudget struct {
+	// MaxFailed is the max number of failed records tolerated or, if Percent is set, the max percentage
+	// of failed records out of all the records read.
+	MaxFailed float64
+	// Percent tells if MaxFailed is a percentage. A percentage is checked at the end of the input and,
+	// if MinRecords isn't 0, upon every failed record once at least MinRecords records are read, so
+	// that an input going bad early is aborted without being read through.
+	Percent bool
+	// MinRecords is the number of records read after which a percentage is checked upon every failed
+	// record. 0 means a percentage is only checked at the end of the input.
+	MinRecords int
+}
+
+// String returns the budget in the form ParseErrorBudget takes, e.g. "10" or "5%".
+func (b ErrorBudget) String() string {
+	s := strconv.FormatFloat(b.MaxFailed, 'f', -1, 64)
+	if b.Percent {
+		s += "%"
+	}
+	return s
+}
+
+// ParseErrorBudget parses an error budget from either an absolute number of failed records, e.g. "10",
+// or a percentage of failed records, e.g. "5%" or "0.5%", e.g. from a command line flag.
+func ParseErrorBudget(s string) (ErrorBudget, error) {
+	var b ErrorBudget
+	numStr := strings.TrimSpace(s)
+	if strings.HasSuffix(numStr, "%") {
+		b.Percent = true
+		numStr = strings.TrimSpace(strings.TrimSuffix(numStr, "%"))
+		f, err := strconv.ParseFloat(numStr, 64)
+		if err != nil {
+			return ErrorBudget{}, fmt.Errorf("invalid error budget '%s'", s)
+		}
+		b.MaxFailed = f
+	} else {
+		n, err := strconv.Atoi(numStr)
+		if err != nil {
+			return ErrorBudget{}, fmt.Errorf("invalid error budget '%s'", s)
+		}
+		b.MaxFailed = float64(n)
+	}
+	if err := b.validate(); err != nil {
+		return ErrorBudget{}, err
+	}
+	return b, nil
+}
+
+func (b ErrorBudget) validate() error {
+	if b.MaxFailed < 0 || (b.Percent && b.MaxFailed > 100) || b.MinRecords < 0 {
+		return fmt.Errorf("invalid error budget '%s', min records %d", b, b.MinRecords)
+	}
+	return nil
+}
+
+// exceeded tells if the failed records of the records read exceed the budget.
+func (b ErrorBudget) exceeded(records, failed int) bool {
+	if !b.Percent {
+		return float64(failed) > b.MaxFailed
+	}
+	return records > 0 && float64(failed)*100 > b.MaxFailed*float64(records)
+}
+
+// maxFirstErrors is the max number of the first errors kept by ErrErrorBudgetExceeded.
+const maxFirstErrors = 3
+
+// ErrErrorBudgetExceeded is returned by a Transform created by WithErrorBudget once its failed records
+// exceed the budget. This is a fatal error: future calls to Read will always return the same error.
+type ErrErrorBudgetExceeded struct {
+	// Records is the number of records read, successfully or not, when the transform is aborted.
+	Records int
+	// Failed is the number of failed records when the transform is aborted.
+	Failed int
+	// Budget is the budget exceeded.
+	Budget ErrorBudget
+	// FirstErrors are the messages of the first few errors of the failed records, for the cause of a
+	// mostly broken input to be told from the error alone.
+	FirstErrors []string
+}
+
+// Error implements the error interface.
+func (e ErrErrorBudgetExceeded) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "error budget exceeded: %d of %d records failed", e.Failed, e.Records)
+	if e.Budget.Percent {
+		fmt.Fprintf(&sb, " (%s%%), more than the %s allowed",
+			strconv.FormatFloat(math.Round(float64(e.Failed)*10000/float64(e.Records))/100, 'f', -1, 64), e.Budget)
+	} else {
+		fmt.Fprintf(&sb, ", more than the %s allowed", e.Budget)
+	}
+	if len(e.FirstErrors) > 0 {
+		fmt.Fprintf(&sb, "; first errors: %s", strings.Join(e.FirstErrors, "; "))
+	}
+	return sb.String()
+}
+
+// IsErrErrorBudgetExceeded tells if an error is of ErrErrorBudgetExceeded, or wraps one.
+func IsErrErrorBudgetExceeded(err error) bool {
+	var e ErrErrorBudgetExceeded
+	return errors.As(err, &e)
+}
+
+// WithErrorBudget wraps a Transform, freshly created and not yet read, so that it aborts with an
+// ErrErrorBudgetExceeded once its failed records exceed the budget: in place of the failed record that
+// exceeds it or, for a percentage only checked at the end of the input, in place of io.EOF. The
+// records returned before the abort are still returned, so a caller wanting all or nothing must hold
+// them back till io.EOF. The abort isn't seen by the wrapped Transform's listeners, but is reflected
+// in the Summary.
+func WithErrorBudget(t Transform, budget ErrorBudget) (Transform, error) {
+	if err := budget.validate(); err != nil {
+		return nil, err
+	}
+	return &budgetedTransform{Transform: t, budget: budget}, nil
+}
+
+type budgetedTransform struct {
+	Transform
+	budget      ErrorBudget
+	records     int
+	failed      int
+	firstErrors []string
+	err         error             // ErrErrorBudgetExceeded, io.EOF or fatal error.
+	summary     *TransformSummary // the summary upon the abort, if the budget is exceeded.
+}
+
+// Read implements Transform.Read.
+func (b *budgetedTransform) Read() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	record, err := b.Transform.Read()
+	switch {
+	case err == nil:
+		b.records++
+	case errs.IsErrTransformFailed(err):
+		b.records++
+		b.failed++
+		if len(b.firstErrors) < maxFirstErrors {
+			b.firstErrors = append(b.firstErrors, err.Error())
+		}
+		if (!b.budget.Percent || (b.budget.MinRecords > 0 && b.records >= b.budget.MinRecords)) &&
+			b.budget.exceeded(b.records, b.failed) {
+			return nil, b.abort()
+		}
+	case err == io.EOF:
+		if b.budget.exceeded(b.records, b.failed) {
+			return nil, b.abort()
+		}
+		b.err = err
+	default:
+		b.err = err
+	}
+	return record, err
+}
+
+func (b *budgetedTransform) abort() error {
+	b.err = ErrErrorBudgetExceeded{
+		Records:     b.records,
+		Failed:      b.failed,
+		Budget:      b.budget,
+		FirstErrors: b.firstErrors,
+	}
+	summary := summaryOf(b.Transform)
+	summary.Done = true
+	summary.Error = b.err.Error()
+	if summary.ErrorCounts == nil {
+		summary.ErrorCounts = map[string]int{}
+	}
+	summary.ErrorCounts[ErrCodeFatal]++
+	b.summary = &summary
+	return b.err
+}
+
+// RawRecord implements Transform.RawRecord.
+func (b *budgetedTransform) RawRecord() (schemahandler.RawRecord, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.Transform.RawRecord()
+}
+
+// AddListener implements Listenable, registering the Listener with the wrapped Transform, if it's
+// Listenable.
+func (b *budgetedTransform) AddListener(l Listener) {
+	addListener(b.Transform, l)
+}
+
+// Stats implements StatsReporter, returning the running totals of the wrapped Transform.
+func (b *budgetedTransform) Stats() TransformStats {
+	return statsOf(b.Transform)
+}
+
+// Summary implements SummaryReporter. Once the budget is exceeded, the summary is final as of the
+// abort, with the ErrErrorBudgetExceeded as its fatal error.
+func (b *budgetedTransform) Summary() TransformSummary {
+	if b.summary != nil {
+		return *b.summary
+	}
+	return summaryOf(b.Transform)
+}
+
+// Preview implements Previewer, previewing the wrapped Transform, not subject to the budget.
+func (b *budgetedTransform) Preview(n int) (*Preview, error) {
+	return previewOf(b.Transform, n)
+}
@@ -0,0 +1,176 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestParseErrorBudget(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		expected ErrorBudget
+		err      string
+	}{
+		{s: "0", expected: ErrorBudget{}},
+		{s: " 10 ", expected: ErrorBudget{MaxFailed: 10}},
+		{s: "5%", expected: ErrorBudget{MaxFailed: 5, Percent: true}},
+		{s: "0.5 %", expected: ErrorBudget{MaxFailed: 0.5, Percent: true}},
+		{s: "100%", expected: ErrorBudget{MaxFailed: 100, Percent: true}},
+		{s: "", err: "invalid error budget ''"},
+		{s: "1.5", err: "invalid error budget '1.5'"},
+		{s: "x%", err: "invalid error budget 'x%'"},
+		{s: "-1", err: "invalid error budget '-1', min records 0"},
+		{s: "101%", err: "invalid error budget '101%', min records 0"},
+	} {
+		t.Run(test.s, func(t *testing.T) {
+			b, err := ParseErrorBudget(test.s)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, ErrorBudget{}, b)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, b)
+		})
+	}
+}
+
+func TestWithErrorBudget(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		budget   ErrorBudget
+		expected []string
+		err      string
+	}{
+		{
+			name:     "absolute within budget",
+			budget:   ErrorBudget{MaxFailed: 1},
+			expected: []string{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"},
+		},
+		{
+			name:     "absolute exceeded",
+			budget:   ErrorBudget{},
+			expected: []string{"2020-01-01", "2020-01-02"},
+			err:      "error budget exceeded: 1 of 3 records failed, more than the 0 allowed; first errors: ",
+		},
+		{
+			name:     "percent within budget",
+			budget:   ErrorBudget{MaxFailed: 20, Percent: true},
+			expected: []string{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"},
+		},
+		{
+			name:     "percent exceeded at the end",
+			budget:   ErrorBudget{MaxFailed: 10, Percent: true},
+			expected: []string{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"},
+			err:      "error budget exceeded: 1 of 5 records failed (20%), more than the 10% allowed; first errors: ",
+		},
+		{
+			name:     "percent exceeded after min records",
+			budget:   ErrorBudget{MaxFailed: 10, Percent: true, MinRecords: 3},
+			expected: []string{"2020-01-01", "2020-01-02"},
+			err:      "error budget exceeded: 1 of 3 records failed (33.33%), more than the 10% allowed; first errors: ",
+		},
+		{
+			name:     "percent not checked before min records",
+			budget:   ErrorBudget{MaxFailed: 25, Percent: true, MinRecords: 4},
+			expected: []string{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tfm, err := newPushTestSchema(t).NewTransform(
+				"test-input", strings.NewReader(limitsTestInput), &transformctx.Ctx{})
+			assert.NoError(t, err)
+			tfm, err = WithErrorBudget(tfm, test.budget)
+			assert.NoError(t, err)
+			var records []string
+			for {
+				record, err := tfm.Read()
+				if err == io.EOF {
+					assert.Empty(t, test.err)
+					break
+				}
+				if errs.IsErrTransformFailed(err) {
+					records = append(records, "error")
+					continue
+				}
+				if IsErrErrorBudgetExceeded(err) {
+					assert.True(t, strings.HasPrefix(err.Error(), test.err))
+					// the abort is returned repeatedly, and is final in the summary.
+					_, err2 := tfm.Read()
+					assert.Equal(t, err, err2)
+					_, err2 = tfm.RawRecord()
+					assert.Equal(t, err, err2)
+					summary := tfm.(SummaryReporter).Summary()
+					assert.True(t, summary.Done)
+					assert.Equal(t, err.Error(), summary.Error)
+					assert.Equal(t, 1, summary.ErrorCounts[ErrCodeFatal])
+					break
+				}
+				assert.NoError(t, err)
+				records = append(records, string(record)[6:16])
+			}
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestWithErrorBudget_FirstErrors(t *testing.T) {
+	continuableErr := errors.New("continuable error")
+	var readCalls []testReadCall
+	for i := 0; i < 5; i++ {
+		readCalls = append(readCalls, testReadCall{err: continuableErr})
+	}
+	tfm, err := WithErrorBudget(&transform{ingester: &testIngester{
+		readCalls:       readCalls,
+		continuableErrs: map[error]bool{continuableErr: true},
+	}}, ErrorBudget{MaxFailed: 3})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := tfm.Read()
+		assert.True(t, errs.IsErrTransformFailed(err))
+	}
+	_, err = tfm.Read()
+	assert.True(t, IsErrErrorBudgetExceeded(err))
+	assert.Equal(t,
+		"error budget exceeded: 4 of 4 records failed, more than the 3 allowed; first errors: "+
+			"continuable error; continuable error; continuable error",
+		err.Error())
+	assert.Len(t, err.(ErrErrorBudgetExceeded).FirstErrors, maxFirstErrors)
+}
+
+func TestWithErrorBudget_FatalError(t *testing.T) {
+	tfm, err := WithErrorBudget(&transform{ingester: &testIngester{
+		readCalls: []testReadCall{{result: []byte("1st good read")}, {err: errors.New("fatal error")}},
+	}}, ErrorBudget{})
+	assert.NoError(t, err)
+	_, err = tfm.Read()
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		record, err := tfm.Read()
+		assert.Error(t, err)
+		assert.Equal(t, "fatal error", err.Error())
+		assert.Nil(t, record)
+	}
+}
+
+func TestWithErrorBudget_Failure(t *testing.T) {
+	tfm, err := WithErrorBudget(&transform{ingester: &testIngester{}}, ErrorBudget{MaxFailed: 5, MinRecords: -1})
+	assert.Error(t, err)
+	assert.Equal(t, "invalid error budget '5', min records -1", err.Error())
+	assert.Nil(t, tfm)
+}
+
+func TestIsErrErrorBudgetExceeded(t *testing.T) {
+	assert.True(t, IsErrErrorBudgetExceeded(ErrErrorBudgetExceeded{}))
+	assert.True(t, IsErrErrorBudgetExceeded(fmt.Errorf("input 'test-input': %w", ErrErrorBudgetExceeded{})))
+	assert.False(t, IsErrErrorBudgetExceeded(io.EOF))
+}
@@ -0,0 +1,99 @@
+package omniparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/input"
+)
+
+// errorDumpWindow is the number of most recently read input bytes an errorDumper retains, beyond the
+// dump size: the readers read ahead into their buffers, so an input error is usually reported well
+// behind the last byte read.
+const errorDumpWindow = 64 * 1024
+
+// errorDumper fills in the errs.ErrInput.Dump of the input errors of a Transform, as enabled by the
+// schema's 'parser_settings.error_dump_bytes'. It's given the input after decoding, whose offsets and
+// lines are the ones the readers report. An in-memory input is dumped from directly; any other input
+// is read through the errorDumper, which retains a window of the most recently read bytes.
+type errorDumper struct {
+	r      io.Reader // nil for an in-memory input.
+	size   int       // number of bytes to dump.
+	window []byte    // the retained bytes, or the whole in-memory input.
+	start  int64     // input offset of window[0].
+	lines  int       // number of '\n' before window[0].
+}
+
+// newErrorDumper creates an errorDumper of an input, and returns the input to be read instead.
+func newErrorDumper(in io.Reader, size int) (*errorDumper, io.Reader) {
+	if m, ok := in.(input.InMemory); ok && m.Bytes() != nil {
+		return &errorDumper{size: size, window: m.Bytes()}, in
+	}
+	d := &errorDumper{r: in, size: size}
+	return d, d
+}
+
+func (d *errorDumper) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	d.window = append(d.window, p[:n]...)
+	if keep := errorDumpWindow + d.size; len(d.window) > 2*keep {
+		drop := len(d.window) - keep
+		d.lines += bytes.Count(d.window[:drop], []byte{'\n'})
+		d.start += int64(drop)
+		d.window = append(d.window[:0], d.window[drop:]...)
+	}
+	return n, err
+}
+
+// fill fills in the Dump of the input error err is or wraps, if any, if its position is still within
+// the retained bytes.
+func (d *errorDumper) fill(err error) {
+	var inputErr *errs.ErrInput
+	if !errors.As(err, &inputErr) || inputErr.Dump != "" {
+		return
+	}
+	at, ok := d.locate(inputErr)
+	if !ok {
+		return
+	}
+	// centers the dump around the error, starting on a whole dump line.
+	from, to := at-int64(d.size/2), at+int64(d.size-d.size/2)
+	from -= from % 16
+	if from < d.start {
+		from = d.start
+	}
+	if end := d.start + int64(len(d.window)); to > end {
+		to = end
+	}
+	if from >= to {
+		return
+	}
+	inputErr.Dump = errs.Dump(d.window[from-d.start:to-d.start], from, at)
+}
+
+// locate returns the input offset of an input error, by its Offset if known, or by its Line and
+// Column otherwise.
+func (d *errorDumper) locate(inputErr *errs.ErrInput) (int64, bool) {
+	if inputErr.Offset >= 0 {
+		return inputErr.Offset, inputErr.Offset >= d.start
+	}
+	if inputErr.Line <= 0 || inputErr.Line-1 < d.lines {
+		return 0, false
+	}
+	// the line either starts in the window, after its (Line-1-lines)th '\n', or, if that's 0,
+	// at or before the window start.
+	at := d.start
+	for i := d.lines; i < inputErr.Line-1; i++ {
+		nl := bytes.IndexByte(d.window[at-d.start:], '\n')
+		if nl < 0 {
+			return 0, false
+		}
+		at += int64(nl) + 1
+	}
+	if inputErr.Column > 0 {
+		at += int64(inputErr.Column) - 1
+	}
+	return at, true
+}
@@ -0,0 +1,98 @@
+package omniparser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/input"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func newErrorDumpTestTransform(t *testing.T, parserSettings string, in io.Reader, sections ...string) Transform {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": `+parserSettings+`,`+strings.Join(sections, "")+`
+		"file_declaration": {
+			"delimiter": ",",
+			"records": [ { "is_target": true, "columns": [ { "name": "A", "index": 1 } ] } ]
+		},
+		"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "A" } } } }
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", in, &transformctx.Ctx{})
+	assert.NoError(t, err)
+	return tfm
+}
+
+func readUntilErr(t *testing.T, tfm Transform) error {
+	for {
+		_, err := tfm.Read()
+		if err != nil {
+			assert.NotEqual(t, io.EOF, err)
+			return err
+		}
+	}
+}
+
+func TestTransform_ErrorDump(t *testing.T) {
+	const settings = `{ "version": "omni.2.1", "file_format_type": "csv2", "error_dump_bytes": 32 }`
+	for _, test := range []struct {
+		name  string
+		input func(string) io.Reader
+	}{
+		{"streamed", func(s string) io.Reader { return strings.NewReader(s) }},
+		{"in-memory", func(s string) io.Reader { return input.NewBytesReader([]byte(s)) }},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// the input error is on line 4.
+			tfm := newErrorDumpTestTransform(t, settings, test.input("a1\r\na2\r\na3\r\na\"4\r\na5\r\n"))
+			err := readUntilErr(t, tfm)
+			var inputErr *errs.ErrInput
+			assert.True(t, errors.As(err, &inputErr))
+			assert.Equal(t, 4, inputErr.Line)
+			assert.Equal(t,
+				"> 00000000  61 31 0d 0a 61 32 0d 0a  61 33 0d 0a 61 22 34 0d  |a1\\r\\na2\\r\\na3\\r\\na\"4\\r|\n"+
+					"  00000010  0a 61 35 0d 0a                                    |\\na5\\r\\n|\n",
+				inputErr.Dump)
+			assert.Contains(t, err.Error(), "; input around the error:\n> 00000000")
+		})
+	}
+}
+
+func TestTransform_ErrorDump_Window(t *testing.T) {
+	const settings = `{ "version": "omni.2.1", "file_format_type": "csv2", "error_dump_bytes": 16 }`
+	// the input error is way beyond the bytes retained.
+	lines := 2 * errorDumpWindow
+	prefix := strings.Repeat("a\n", lines)
+	in := prefix + "b\"\nc\n" + strings.Repeat("d\n", 1000)
+	tfm := newErrorDumpTestTransform(t, settings, strings.NewReader(in))
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(readUntilErr(t, tfm), &inputErr))
+	assert.True(t, tfm.(*transform).dumper.start > 0)
+	assert.Equal(t, lines+1, inputErr.Line)
+	at := int64(len(prefix))
+	assert.Equal(t, errs.Dump([]byte(in[at-16:at+8]), at-16, at), inputErr.Dump)
+}
+
+func TestTransform_ErrorDump_Disabled(t *testing.T) {
+	tfm := newErrorDumpTestTransform(t, `{ "version": "omni.2.1", "file_format_type": "csv2" }`,
+		strings.NewReader("a\"1\n"))
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(readUntilErr(t, tfm), &inputErr))
+	assert.Equal(t, "", inputErr.Dump)
+}
+
+func TestTransform_ErrorDump_InputSort(t *testing.T) {
+	// the lines reported are the ones of the sorted input, thus the input isn't dumped.
+	tfm := newErrorDumpTestTransform(t,
+		`{ "version": "omni.2.1", "file_format_type": "csv2", "error_dump_bytes": 32 }`,
+		strings.NewReader("c\nb\"\na\n"), `"input_sort": { "keys": [ { "column": 1 } ] },`)
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(readUntilErr(t, tfm), &inputErr))
+	assert.Equal(t, 2, inputErr.Line)
+	assert.Equal(t, "", inputErr.Dump)
+}
@@ -0,0 +1,64 @@
+package errs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dumpBytesPerLine is the number of bytes on each line of a Dump.
+const dumpBytesPerLine = 16
+
+// Dump returns a hex dump of b, whose first byte is at the 0-based input offset base, for diagnosing
+// input errors. Each line has the input offset, the hex of up to 16 bytes and the bytes as text, with
+// non-printable bytes escaped (e.g. "\r", "\t", "\x00") so line endings and stray control bytes are
+// visible. Lines are aligned on multiples of 16 input offsets, and the line with the byte at the input
+// offset at, if any, is marked with '>':
+//
+//	  00000000  61 31 2c 62 0d 0a 61 32  2c 62 0d 0a 61 33 2c 00  |a1,b\r\na2,b\r\na3,\x00|
+//	> 00000010  62 0d 0a                                          |b\r\n|
+func Dump(b []byte, base, at int64) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for lineStart := base - base%dumpBytesPerLine; lineStart < base+int64(len(b)); lineStart += dumpBytesPerLine {
+		mark := ' '
+		if at >= lineStart && at < lineStart+dumpBytesPerLine {
+			mark = '>'
+		}
+		fmt.Fprintf(&sb, "%c %08x  ", mark, lineStart)
+		var text strings.Builder
+		for i := int64(0); i < dumpBytesPerLine; i++ {
+			if i == dumpBytesPerLine/2 {
+				sb.WriteByte(' ')
+			}
+			offset := lineStart + i
+			if offset < base || offset >= base+int64(len(b)) {
+				sb.WriteString("   ")
+				continue
+			}
+			c := b[offset-base]
+			fmt.Fprintf(&sb, "%02x ", c)
+			text.WriteString(escapeByte(c))
+		}
+		fmt.Fprintf(&sb, " |%s|\n", text.String())
+	}
+	return sb.String()
+}
+
+func escapeByte(c byte) string {
+	switch {
+	case c == '\r':
+		return `\r`
+	case c == '\n':
+		return `\n`
+	case c == '\t':
+		return `\t`
+	case c == '\\':
+		return `\\`
+	case c < 0x20 || c > 0x7e:
+		return fmt.Sprintf(`\x%02x`, c)
+	default:
+		return string(c)
+	}
+}
@@ -14,12 +14,32 @@ type ErrTransformFailed string
 // Error implements the error interface
 func (e ErrTransformFailed) Error() string { return string(e) }
 
-// IsErrTransformFailed tells if an error is of ErrTransformFailed.
+// IsErrTransformFailed tells if an error is of ErrTransformFailed, or wraps one, e.g. one from
+// WrapTransformFailed.
 func IsErrTransformFailed(err error) bool {
-	switch err.(type) {
-	case ErrTransformFailed:
+	var e ErrTransformFailed
+	return errors.As(err, &e)
+}
+
+// WrapTransformFailed returns an ErrTransformFailed, with the message of err, that also wraps err, so
+// that the cause can still be inspected with errors.Is and errors.As.
+func WrapTransformFailed(err error) error {
+	return &transformFailedErr{err: err}
+}
+
+type transformFailedErr struct {
+	err error
+}
+
+func (e *transformFailedErr) Error() string { return e.err.Error() }
+
+func (e *transformFailedErr) Unwrap() error { return e.err }
+
+// As lets errors.As find the ErrTransformFailed the error is.
+func (e *transformFailedErr) As(target interface{}) bool {
+	if t, ok := target.(*ErrTransformFailed); ok {
+		*t = ErrTransformFailed(e.err.Error())
 		return true
-	default:
-		return false
 	}
+	return false
 }
@@ -1,6 +1,8 @@
 package errs
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"testing"
 
@@ -12,3 +14,47 @@ func TestIsErrTransformFailed(t *testing.T) {
 	assert.Equal(t, "test", ErrTransformFailed("test").Error())
 	assert.False(t, IsErrTransformFailed(io.EOF))
 }
+
+func TestWrapTransformFailed(t *testing.T) {
+	cause := &ErrInput{Format: "csv2", Input: "test", Line: 3, Offset: -1, Reason: "bad", Err: errors.New("bad")}
+	err := WrapTransformFailed(cause)
+	assert.True(t, IsErrTransformFailed(err))
+	assert.True(t, IsErrTransformFailed(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, "bad", err.Error())
+	var inputErr *ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Same(t, cause, inputErr)
+	assert.True(t, errors.Is(err, cause))
+	var transformFailed ErrTransformFailed
+	assert.True(t, errors.As(err, &transformFailed))
+	assert.Equal(t, ErrTransformFailed("bad"), transformFailed)
+}
+
+func TestErrInput(t *testing.T) {
+	formatErr := ErrTransformFailed("input 'test' line 3: bad")
+	var err error = &ErrInput{Format: "csv2", Input: "test", Line: 3, Offset: -1, Reason: "bad", Err: formatErr}
+	assert.Equal(t, "input 'test' line 3: bad", err.Error())
+	var inputErr *ErrInput
+	assert.True(t, errors.As(fmt.Errorf("wrapped: %w", err), &inputErr))
+	assert.Equal(t, 3, inputErr.Line)
+	assert.Equal(t, "bad", inputErr.Reason)
+	assert.True(t, errors.Is(err, formatErr))
+	assert.False(t, errors.As(io.EOF, &inputErr))
+}
+
+func TestErrInput_Dump(t *testing.T) {
+	err := &ErrInput{Offset: 2, Err: ErrTransformFailed("bad"), Dump: "  00000000  61\n"}
+	assert.Equal(t, "bad; input around the error:\n  00000000  61\n", err.Error())
+}
+
+func TestDump(t *testing.T) {
+	assert.Equal(t,
+		"  00000000  61 31 2c 62 0d 0a 61 32  2c 62 0d 0a 61 33 2c 00  |a1,b\\r\\na2,b\\r\\na3,\\x00|\n"+
+			"> 00000010  62 5c 09 c3 a9 0a                                 |b\\\\\\t\\xc3\\xa9\\n|\n",
+		Dump([]byte("a1,b\r\na2,b\r\na3,\x00b\\\té\n"), 0, 17))
+	// lines are aligned on offsets, and the error may be outside of the dump.
+	assert.Equal(t,
+		"  00000010           61 32 0d 0a 61  33                       |a2\\r\\na3|\n",
+		Dump([]byte("a2\r\na3"), 19, 100))
+	assert.Equal(t, "", Dump(nil, 5, 5))
+}
@@ -0,0 +1,46 @@
+package errs
+
+// ErrInput is the common error of the file format readers about the content of an input, e.g. it's
+// corrupted or doesn't conform to the schema. It wraps the format specific error (e.g.
+// edi.ErrInvalidEDI), which can still be checked with its IsErrXXX helper, while cross-format error
+// handling can get the details of any format with errors.As:
+//
+//	var inputErr *errs.ErrInput
+//	if errors.As(err, &inputErr) {
+//		log(inputErr.Format, inputErr.Line, inputErr.Reason)
+//	}
+type ErrInput struct {
+	// Format is the file format of the input, e.g. "edi" or "csv2".
+	Format string
+	// Input is the name of the input.
+	Input string
+	// Line is the 1-based line number where the error is, or 0 if unknown.
+	Line int
+	// Column is the 1-based column number, in bytes, where the error is, or 0 if unknown.
+	Column int
+	// Offset is the 0-based byte offset where the error is, or -1 if unknown.
+	Offset int64
+	// Segment is the 1-based number of the format specific unit the error is in, e.g. the EDI
+	// segment, the ISO 8583 message or the PDF page, or 0 if not applicable.
+	Segment int
+	// Reason is what's wrong, without the input name and the position.
+	Reason string
+	// Err is the format specific error, whose message contains the input name, the position and
+	// the reason.
+	Err error
+	// Dump is the Dump of the input bytes around the error, or empty if not enabled by the schema's
+	// 'parser_settings.error_dump_bytes' or if the bytes are no longer available.
+	Dump string
+}
+
+// Error implements the error interface. It's the message of the format specific error, followed by
+// the Dump, if any.
+func (e *ErrInput) Error() string {
+	if e.Dump == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + "; input around the error:\n" + e.Dump
+}
+
+// Unwrap returns the format specific error.
+func (e *ErrInput) Unwrap() error { return e.Err }
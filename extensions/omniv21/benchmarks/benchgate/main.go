@@ -0,0 +1,73 @@
+// Command benchgate compares two `go test -bench` outputs, a baseline and a current one, and exits
+// with status 1 if any benchmark metric regressed beyond a threshold:
+//
+//	go run github.com/logward/omniparser/extensions/omniv21/benchmarks/benchgate old.txt new.txt
+//
+// See doc/benchmarks.md for details.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/logward/omniparser/extensions/omniv21/benchmarks"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 10, "Regression threshold in percent.")
+	units := flag.String("units", "ns/op,allocs/op", "Comma separated units of the metrics to compare.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: benchgate [flags] baseline.txt current.txt\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	baseline, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	current, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fail(err)
+	}
+	deltas := benchmarks.Compare(baseline, current, strings.Split(*units, ",")...)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "benchmark\tunit\tbaseline\tcurrent\tchange\t")
+	for _, d := range deltas {
+		mark := ""
+		if d.Regressed(*threshold / 100) {
+			mark = "REGRESSED"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.4g\t%.4g\t%+.2f%%\t%s\n",
+			d.Name, d.Unit, d.Baseline, d.Current, d.Change*100, mark)
+	}
+	_ = w.Flush()
+	if regressions := benchmarks.Regressions(deltas, *threshold/100); len(regressions) > 0 {
+		fmt.Printf("\n%d metric(s) regressed by more than %g%%\n", len(regressions), *threshold)
+		os.Exit(1)
+	}
+}
+
+func parseFile(path string) (map[string]benchmarks.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	results, err := benchmarks.ParseResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %s", path, err.Error())
+	}
+	return results, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(2)
+}
@@ -0,0 +1,106 @@
+// Package benchmarks provides a benchmark corpus and harness for catching performance regressions of
+// omniparser readers and transforms.
+//
+// The corpus consists of schemas for common formats (X12 837 and 856, CSV, XML and JSON) and generators
+// producing deterministic, multi-MB inputs for them, so nothing large needs to be checked in or
+// downloaded. Downstream users can run the corpus in their own module, against the omniparser version
+// they depend on, before and after an upgrade:
+//
+//	func BenchmarkOmniparser(b *testing.B) { benchmarks.Benchmark(b) }
+//
+// and benchmark their own schemas and inputs with Run. The results of two runs can be compared with
+// Compare, or the benchgate command, which fails when any benchmark regresses beyond a threshold.
+// See doc/benchmarks.md for details.
+package benchmarks
+
+import (
+	"bytes"
+	"embed"
+	"io"
+	"testing"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/transformctx"
+)
+
+//go:embed corpus/*.schema.json
+var schemas embed.FS
+
+// Corpus is a benchmark input format: a schema, and a generator of inputs for it.
+type Corpus struct {
+	// Name is the name of the corpus, also used as the name of its sub-benchmark in Benchmark.
+	Name string
+	// Schema is the omniparser schema content for the corpus inputs.
+	Schema []byte
+	// Generate returns a deterministic input containing n records. A record is what the schema's
+	// FINAL_OUTPUT transforms, e.g. a transaction set for EDI, or a row for CSV.
+	Generate func(n int) []byte
+	// Records is the number of records Benchmark uses, which makes an input of a few MB.
+	Records int
+}
+
+// NewSchema creates the omniparser schema of the corpus.
+func (c Corpus) NewSchema() (omniparser.Schema, error) {
+	return omniparser.NewSchema(c.Name, bytes.NewReader(c.Schema))
+}
+
+func mustReadSchema(name string) []byte {
+	b, err := schemas.ReadFile("corpus/" + name + ".schema.json")
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Corpora returns all the corpora in the benchmark suite.
+func Corpora() []Corpus {
+	return []Corpus{
+		{Name: "x12_837", Schema: mustReadSchema("x12_837"), Generate: GenerateX12837, Records: 2000},
+		{Name: "x12_856", Schema: mustReadSchema("x12_856"), Generate: GenerateX12856, Records: 1000},
+		{Name: "csv", Schema: mustReadSchema("csv"), Generate: GenerateCSV, Records: 25000},
+		{Name: "xml", Schema: mustReadSchema("xml"), Generate: GenerateXML, Records: 5000},
+		{Name: "json", Schema: mustReadSchema("json"), Generate: GenerateJSON, Records: 5000},
+	}
+}
+
+// Benchmark runs all the corpora, each as a sub-benchmark, with their default number of records.
+// Inputs are generated before the timer starts.
+func Benchmark(b *testing.B) {
+	for _, c := range Corpora() {
+		c := c
+		b.Run(c.Name, func(b *testing.B) {
+			schema, err := c.NewSchema()
+			if err != nil {
+				b.Fatal(err)
+			}
+			Run(b, schema, c.Generate(c.Records))
+		})
+	}
+}
+
+// Run benchmarks the ingestion of input with schema end to end: each iteration creates a transform
+// and reads all the records out of it. Besides the standard ns/op, B/op and allocs/op, it reports
+// throughput (MB/s) and records/op, so results of inputs of different sizes can be compared.
+func Run(b *testing.B, schema omniparser.Schema, input []byte) {
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	records := 0
+	for i := 0; i < b.N; i++ {
+		transform, err := schema.NewTransform("bench", bytes.NewReader(input), &transformctx.Ctx{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			_, err = transform.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			records++
+		}
+	}
+	b.ReportMetric(float64(records)/float64(b.N), "records/op")
+}
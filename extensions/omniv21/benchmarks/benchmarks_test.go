@@ -0,0 +1,55 @@
+package benchmarks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestCorpora(t *testing.T) {
+	for _, c := range Corpora() {
+		t.Run(c.Name, func(t *testing.T) {
+			assert.Equal(t, c.Generate(3), c.Generate(3))
+			schema, err := c.NewSchema()
+			assert.NoError(t, err)
+			transform, err := schema.NewTransform(c.Name, bytes.NewReader(c.Generate(3)), &transformctx.Ctx{})
+			assert.NoError(t, err)
+			var records []interface{}
+			for {
+				b, err := transform.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				var record interface{}
+				assert.NoError(t, json.Unmarshal(b, &record))
+				records = append(records, record)
+			}
+			assert.Equal(t, 3, len(records))
+			cupaloy.SnapshotT(t, jsons.BPM(records))
+		})
+	}
+}
+
+func TestCorpora_DefaultInputSize(t *testing.T) {
+	for _, c := range Corpora() {
+		size := len(c.Generate(c.Records))
+		assert.True(t, size > 1<<20 && size < 16<<20, "corpus '%s' default input size: %d", c.Name, size)
+	}
+}
+
+// BenchmarkCorpora/x12_837 	       3	 845978449 ns/op	   3.38 MB/s	      2000 records/op	162051752 B/op	 4498079 allocs/op
+// BenchmarkCorpora/x12_856 	       3	 707305130 ns/op	   2.18 MB/s	      1000 records/op	177420061 B/op	 6628326 allocs/op
+// BenchmarkCorpora/csv     	       3	 869035167 ns/op	   3.01 MB/s	     25000 records/op	244043688 B/op	 6700363 allocs/op
+// BenchmarkCorpora/xml     	       3	 988964998 ns/op	   3.89 MB/s	      5000 records/op	133764693 B/op	 4083936 allocs/op
+// BenchmarkCorpora/json    	       3	 503315843 ns/op	   7.22 MB/s	      5000 records/op	103266730 B/op	 3391710 allocs/op
+func BenchmarkCorpora(b *testing.B) {
+	Benchmark(b)
+}
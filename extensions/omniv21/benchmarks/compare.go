@@ -0,0 +1,149 @@
+package benchmarks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the metrics of a benchmark, keyed by unit, e.g. "ns/op", "B/op", "allocs/op", "MB/s".
+type Result map[string]float64
+
+// gomaxprocsSuffix is the "-8" like suffix `go test -bench` appends to benchmark names. It's removed so
+// results from machines with different number of CPUs can still be compared.
+var gomaxprocsSuffix = regexp.MustCompile(`-[0-9]+$`)
+
+// ParseResults parses the output of `go test -bench` into benchmark results keyed by benchmark name
+// (less the GOMAXPROCS suffix). Non-benchmark lines are ignored. When a benchmark is run multiple times
+// (e.g. with `-count 10`), the median of each metric is used, which is robust against outlier runs.
+func ParseResults(r io.Reader) (map[string]Result, error) {
+	samples := map[string]map[string][]float64{}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		// a benchmark line: name, iterations, followed by one or more value/unit pairs.
+		if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := gomaxprocsSuffix.ReplaceAllString(fields[0], "")
+		if samples[name] == nil {
+			samples[name] = map[string][]float64{}
+		}
+		for i := 2; i < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value '%s' of '%s'", lineNum, fields[i], fields[i+1])
+			}
+			samples[name][fields[i+1]] = append(samples[name][fields[i+1]], v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	results := map[string]Result{}
+	for name, units := range samples {
+		results[name] = Result{}
+		for unit, values := range units {
+			results[name][unit] = median(values)
+		}
+	}
+	return results, nil
+}
+
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// Delta is the change of a benchmark metric between a baseline and a current result.
+type Delta struct {
+	Name     string
+	Unit     string
+	Baseline float64
+	Current  float64
+	// Change is the relative change from Baseline to Current, e.g. 0.1 for 10% increase.
+	Change float64
+}
+
+// Worse returns the relative change in the "worse" direction: for throughput units ("/s", e.g.
+// "MB/s"), higher is better; for everything else (e.g. "ns/op", "allocs/op"), lower is better.
+func (d Delta) Worse() float64 {
+	if strings.HasSuffix(d.Unit, "/s") {
+		return -d.Change
+	}
+	return d.Change
+}
+
+// Regressed returns true if the metric got worse by more than threshold, e.g. 0.1 for 10%.
+func (d Delta) Regressed(threshold float64) bool {
+	return d.Worse() > threshold
+}
+
+// Compare compares the given units of the benchmarks present in both baseline and current, and
+// returns the deltas ordered by benchmark name then unit. Benchmarks or units missing on either side
+// are skipped, as are metrics with a zero baseline (e.g. 0 allocs/op) unless current is non-zero, in
+// which case the change is reported as +100%.
+func Compare(baseline, current map[string]Result, units ...string) []Delta {
+	var deltas []Delta
+	for name, b := range baseline {
+		c, ok := current[name]
+		if !ok {
+			continue
+		}
+		for _, unit := range units {
+			bv, bok := b[unit]
+			cv, cok := c[unit]
+			if !bok || !cok {
+				continue
+			}
+			d := Delta{Name: name, Unit: unit, Baseline: bv, Current: cv}
+			switch {
+			case bv != 0:
+				d.Change = (cv - bv) / bv
+			case cv != 0:
+				d.Change = 1
+			}
+			deltas = append(deltas, d)
+		}
+	}
+	sort.SliceStable(deltas, func(i, j int) bool {
+		if deltas[i].Name != deltas[j].Name {
+			return deltas[i].Name < deltas[j].Name
+		}
+		return indexOf(units, deltas[i].Unit) < indexOf(units, deltas[j].Unit)
+	})
+	return deltas
+}
+
+func indexOf(strs []string, s string) int {
+	for i := range strs {
+		if strs[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Regressions returns the deltas that regressed by more than threshold, e.g. 0.1 for 10%.
+func Regressions(deltas []Delta, threshold float64) []Delta {
+	var regressions []Delta
+	for _, d := range deltas {
+		if d.Regressed(threshold) {
+			regressions = append(regressions, d)
+		}
+	}
+	return regressions
+}
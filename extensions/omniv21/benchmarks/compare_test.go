@@ -0,0 +1,95 @@
+package benchmarks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseResults(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		expected map[string]Result
+		err      string
+	}{
+		{
+			name: "success",
+			input: `goos: linux
+goarch: amd64
+pkg: github.com/logward/omniparser/extensions/omniv21/benchmarks
+BenchmarkCorpora/csv-8         	       3	 400000000 ns/op	   2.50 MB/s	 1000 B/op	  20 allocs/op	 25000 records/op
+BenchmarkCorpora/csv-8         	       3	 100000000 ns/op	  10.00 MB/s	 1000 B/op	  20 allocs/op	 25000 records/op
+BenchmarkCorpora/csv-8         	       3	 200000000 ns/op	   5.00 MB/s	 1000 B/op	  20 allocs/op	 25000 records/op
+BenchmarkCorpora/json-16       	       2	 100 ns/op
+BenchmarkCorpora/json-16       	       2	 300 ns/op
+BenchmarkFailed --- FAIL
+PASS
+ok  	github.com/logward/omniparser/extensions/omniv21/benchmarks	12.345s
+`,
+			expected: map[string]Result{
+				"BenchmarkCorpora/csv": {
+					"ns/op": 200000000, "MB/s": 5, "B/op": 1000, "allocs/op": 20, "records/op": 25000,
+				},
+				"BenchmarkCorpora/json": {"ns/op": 200},
+			},
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: map[string]Result{},
+		},
+		{
+			name:  "invalid value",
+			input: "BenchmarkX-8  10  abc ns/op",
+			err:   "line 1: invalid value 'abc' of 'ns/op'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := ParseResults(strings.NewReader(test.input))
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, results)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, results)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := map[string]Result{
+		"BenchmarkA":       {"ns/op": 100, "allocs/op": 10, "MB/s": 50},
+		"BenchmarkB":       {"ns/op": 100, "allocs/op": 0},
+		"BenchmarkRemoved": {"ns/op": 100},
+	}
+	current := map[string]Result{
+		"BenchmarkA":   {"ns/op": 120, "allocs/op": 10, "MB/s": 40},
+		"BenchmarkB":   {"ns/op": 95, "allocs/op": 2},
+		"BenchmarkNew": {"ns/op": 100},
+	}
+	deltas := Compare(baseline, current, "ns/op", "allocs/op", "MB/s", "B/op")
+	assert.Equal(t, []Delta{
+		{Name: "BenchmarkA", Unit: "ns/op", Baseline: 100, Current: 120, Change: 0.2},
+		{Name: "BenchmarkA", Unit: "allocs/op", Baseline: 10, Current: 10, Change: 0},
+		{Name: "BenchmarkA", Unit: "MB/s", Baseline: 50, Current: 40, Change: -0.2},
+		{Name: "BenchmarkB", Unit: "ns/op", Baseline: 100, Current: 95, Change: -0.05},
+		{Name: "BenchmarkB", Unit: "allocs/op", Baseline: 0, Current: 2, Change: 1},
+	}, deltas)
+	assert.Equal(t, []Delta{
+		{Name: "BenchmarkA", Unit: "ns/op", Baseline: 100, Current: 120, Change: 0.2},
+		{Name: "BenchmarkA", Unit: "MB/s", Baseline: 50, Current: 40, Change: -0.2},
+		{Name: "BenchmarkB", Unit: "allocs/op", Baseline: 0, Current: 2, Change: 1},
+	}, Regressions(deltas, 0.1))
+	assert.Nil(t, Regressions(deltas, 1))
+}
+
+func TestDelta_Worse(t *testing.T) {
+	assert.Equal(t, 0.5, Delta{Unit: "ns/op", Change: 0.5}.Worse())
+	assert.Equal(t, -0.5, Delta{Unit: "MB/s", Change: 0.5}.Worse())
+	assert.True(t, Delta{Unit: "allocs/op", Change: 0.11}.Regressed(0.1))
+	assert.False(t, Delta{Unit: "allocs/op", Change: 0.1}.Regressed(0.1))
+}
@@ -0,0 +1,241 @@
+package benchmarks
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// all generators use a fixed seed, so the same n always produces the same input.
+const seed = 20240101
+
+var (
+	firstNames = []string{"JOHN", "MARY", "JAMES", "PATRICIA", "ROBERT", "JENNIFER", "MICHAEL", "LINDA"}
+	lastNames  = []string{"SMITH", "JOHNSON", "WILLIAMS", "BROWN", "JONES", "GARCIA", "MILLER", "DAVIS"}
+	cities     = []struct{ city, state, zip, country string }{
+		{"SEATTLE", "WA", "98101", "US"},
+		{"AUSTIN", "TX", "73301", "US"},
+		{"CHICAGO", "IL", "60601", "US"},
+		{"TORONTO", "ON", "M5H2N2", "CA"},
+		{"DENVER", "CO", "80202", "US"},
+	}
+	products = []struct{ sku, upc, desc string }{
+		{"SKU-1001", "012345678905", "Widget, small"},
+		{"SKU-1002", "012345678912", "Widget, large"},
+		{"SKU-2001", "012345678929", "Gadget \"Pro\""},
+		{"SKU-3001", "012345678936", "Sprocket 10mm"},
+		{"SKU-3002", "012345678943", "Sprocket 12mm"},
+	}
+	procedures = []string{"99213", "99214", "87880", "36415", "93000", "71046"}
+	diagnoses  = []string{"J029", "R509", "I10", "E119", "M545", "Z0000"}
+	baseDate   = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func date(r *rand.Rand, layout string) string {
+	return baseDate.AddDate(0, 0, r.Intn(365)).Format(layout)
+}
+
+func money(r *rand.Rand, max int) string {
+	return fmt.Sprintf("%d.%02d", 1+r.Intn(max), r.Intn(100))
+}
+
+func x12Envelope(body *bytes.Buffer, funcID string, transactions int) []byte {
+	var b bytes.Buffer
+	b.WriteString("ISA*00*          *00*          *ZZ*SUBMITTER      *ZZ*RECEIVER       *240101*1200*^*00501*000000001*0*P*:~\n")
+	fmt.Fprintf(&b, "GS*%s*SUBMITTER*RECEIVER*20240101*1200*1*X*005010~\n", funcID)
+	b.Write(body.Bytes())
+	fmt.Fprintf(&b, "GE*%d*1~\nIEA*1*000000001~\n", transactions)
+	return b.Bytes()
+}
+
+// GenerateX12837 generates an X12 837 (health care claim, professional) interchange with n
+// transaction sets. Each transaction set has a billing provider with 1 to 3 subscribers, each with
+// 1 or 2 claims of 1 to 4 service lines.
+func GenerateX12837(n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	for i := 1; i <= n; i++ {
+		segs := 0
+		seg := func(format string, args ...interface{}) {
+			fmt.Fprintf(&b, format, args...)
+			b.WriteString("~\n")
+			segs++
+		}
+		seg("ST*837*%04d*005010X222A1", i)
+		seg("BHT*0019*00*REF%06d*%s*1200*CH", i, date(r, "20060102"))
+		seg("NM1*41*2*ACME BILLING SERVICE*****46*SUB%05d", r.Intn(100000))
+		seg("PER*IC*JANE DOE*TE*5555551234")
+		seg("NM1*40*2*GLOBAL CLEARINGHOUSE*****46*RCV001")
+		hl := 1
+		seg("HL*%d**20*1", hl)
+		seg("PRV*BI*PXC*207Q00000X")
+		loc := cities[r.Intn(len(cities))]
+		seg("NM1*85*2*%s MEDICAL GROUP*****XX*%010d", loc.city, 1000000000+r.Intn(899999999))
+		seg("N3*%d MAIN STREET", 100+r.Intn(9000))
+		seg("N4*%s*%s*%s", loc.city, loc.state, loc.zip)
+		seg("REF*EI*%09d", r.Intn(1000000000))
+		for s := 1 + r.Intn(3); s > 0; s-- {
+			hl++
+			seg("HL*%d*1*22*0", hl)
+			seg("SBR*P*18*GRP%04d******CI", r.Intn(10000))
+			loc = cities[r.Intn(len(cities))]
+			seg("NM1*IL*1*%s*%s****MI*MBR%08d",
+				lastNames[r.Intn(len(lastNames))], firstNames[r.Intn(len(firstNames))], r.Intn(100000000))
+			seg("N3*%d OAK AVENUE", 1+r.Intn(9999))
+			seg("N4*%s*%s*%s", loc.city, loc.state, loc.zip)
+			seg("DMG*D8*%s*%s",
+				baseDate.AddDate(-20-r.Intn(60), 0, -r.Intn(365)).Format("20060102"), []string{"M", "F"}[r.Intn(2)])
+			seg("NM1*PR*2*HEALTH PLAN %d*****PI*PAYER%03d", r.Intn(10), r.Intn(1000))
+			for c := 1 + r.Intn(2); c > 0; c-- {
+				lines := 1 + r.Intn(4)
+				seg("CLM*CLM%08d*%s***11:B:1*Y*A*Y*Y", r.Intn(100000000), money(r, 2000))
+				seg("DTP*431*D8*%s", date(r, "20060102"))
+				seg("REF*D9*CN%010d", r.Intn(1000000000))
+				seg("HI*ABK:%s*ABF:%s", diagnoses[r.Intn(len(diagnoses))], diagnoses[r.Intn(len(diagnoses))])
+				for l := 1; l <= lines; l++ {
+					seg("LX*%d", l)
+					seg("SV1*HC:%s*%s*UN*%d***1", procedures[r.Intn(len(procedures))], money(r, 500), 1+r.Intn(3))
+					seg("DTP*472*D8*%s", date(r, "20060102"))
+				}
+			}
+		}
+		seg("SE*%d*%04d", segs+1, i)
+	}
+	return x12Envelope(&b, "HC", n)
+}
+
+// GenerateX12856 generates an X12 856 (ship notice/manifest) interchange with n transaction sets.
+// Each transaction set has a shipment with 1 to 3 orders, each with 1 to 3 packs of 1 to 5 items.
+func GenerateX12856(n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	for i := 1; i <= n; i++ {
+		segs := 0
+		seg := func(format string, args ...interface{}) {
+			fmt.Fprintf(&b, format, args...)
+			b.WriteString("~\n")
+			segs++
+		}
+		seg("ST*856*%04d", i)
+		seg("BSN*00*SHP%08d*%s*%02d%02d", r.Intn(100000000), date(r, "20060102"), r.Intn(24), r.Intn(60))
+		hl := 1
+		seg("HL*%d**S", hl)
+		seg("TD1*CTN25*%d****G*%s*LB", 1+r.Intn(50), money(r, 900))
+		seg("TD5*B*2*UPSN*M")
+		seg("REF*BM*BOL%09d", r.Intn(1000000000))
+		seg("DTM*011*%s", date(r, "20060102"))
+		from, to := cities[r.Intn(len(cities))], cities[r.Intn(len(cities))]
+		seg("N1*SF*ACME DISTRIBUTION*92*DC%03d", r.Intn(1000))
+		seg("N3*%d INDUSTRIAL PKWY", 100+r.Intn(9000))
+		seg("N4*%s*%s*%s*%s", from.city, from.state, from.zip, from.country)
+		seg("N1*ST*RETAIL STORE %d*92*ST%04d", r.Intn(1000), r.Intn(10000))
+		seg("N3*%d MARKET STREET", 1+r.Intn(9999))
+		seg("N4*%s*%s*%s*%s", to.city, to.state, to.zip, to.country)
+		items := 0
+		for o := 1 + r.Intn(3); o > 0; o-- {
+			hl++
+			seg("HL*%d*1*O", hl)
+			order := hl
+			seg("PRF*PO%08d***%s", r.Intn(100000000), date(r, "20060102"))
+			for p := 1 + r.Intn(3); p > 0; p-- {
+				hl++
+				seg("HL*%d*%d*P", hl, order)
+				pack := hl
+				seg("MAN*GM*00%016d", r.Int63n(10000000000000000))
+				for it := 1 + r.Intn(5); it > 0; it-- {
+					hl++
+					items++
+					prod := products[r.Intn(len(products))]
+					seg("HL*%d*%d*I", hl, pack)
+					seg("LIN**UP*%s*VN*%s", prod.upc, prod.sku)
+					seg("SN1**%d*EA", 1+r.Intn(48))
+					seg("PID*F****%s", prod.desc)
+				}
+			}
+		}
+		seg("CTT*%d", items)
+		seg("SE*%d*%04d", segs+1, i)
+	}
+	return x12Envelope(&b, "SH", n)
+}
+
+// GenerateCSV generates a CSV of order lines with a header row and n data rows. Some descriptions are
+// quoted and contain commas or escaped quotes.
+func GenerateCSV(n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	b.WriteString("order_id,order_date,customer_id,customer_name,sku,description,quantity,unit_price,currency,ship_city,ship_country\n")
+	for i := 1; i <= n; i++ {
+		prod := products[r.Intn(len(products))]
+		loc := cities[r.Intn(len(cities))]
+		fmt.Fprintf(&b, "ORD%08d,%s,CUST%05d,%s %s,%s,\"%s\",%d,%s,USD,%s,%s\n",
+			i, date(r, "2006-01-02T15:04:05"), r.Intn(100000),
+			firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))],
+			prod.sku, strings.ReplaceAll(prod.desc, `"`, `""`), 1+r.Intn(20), money(r, 200),
+			loc.city, loc.country)
+	}
+	return b.Bytes()
+}
+
+// GenerateXML generates an XML document with n orders, each with 1 to 6 lines.
+func GenerateXML(n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<orders>\n")
+	for i := 1; i <= n; i++ {
+		loc := cities[r.Intn(len(cities))]
+		first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+		fmt.Fprintf(&b, "  <order id=\"ORD%08d\">\n", i)
+		fmt.Fprintf(&b, "    <date>%s</date>\n", date(r, "2006-01-02T15:04:05Z07:00"))
+		fmt.Fprintf(&b, "    <customer id=\"CUST%05d\"><name>%s %s</name><email>%s.%s@example.com</email></customer>\n",
+			r.Intn(100000), first, last, strings.ToLower(first), strings.ToLower(last))
+		fmt.Fprintf(&b, "    <shipTo><street>%d Main St</street><city>%s</city><country code=\"%s\"/></shipTo>\n",
+			1+r.Intn(9999), loc.city, loc.country)
+		b.WriteString("    <lines>\n")
+		for l := 1 + r.Intn(6); l > 0; l-- {
+			prod := products[r.Intn(len(products))]
+			fmt.Fprintf(&b,
+				"      <line sku=\"%s\"><description>%s</description><quantity>%d</quantity><price currency=\"USD\">%s</price></line>\n",
+				prod.sku, strings.ReplaceAll(prod.desc, `"`, "&quot;"), 1+r.Intn(20), money(r, 200))
+		}
+		b.WriteString("    </lines>\n  </order>\n")
+	}
+	b.WriteString("</orders>\n")
+	return b.Bytes()
+}
+
+// GenerateJSON generates a JSON document with n orders, each with 1 to 6 lines.
+func GenerateJSON(n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	b.WriteString("{\n  \"orders\": [\n")
+	for i := 1; i <= n; i++ {
+		loc := cities[r.Intn(len(cities))]
+		first, last := firstNames[r.Intn(len(firstNames))], lastNames[r.Intn(len(lastNames))]
+		fmt.Fprintf(&b, "    {\n      \"id\": \"ORD%08d\",\n      \"date\": \"%s\",\n", i, date(r, "2006-01-02T15:04:05Z07:00"))
+		fmt.Fprintf(&b, "      \"customer\": { \"id\": \"CUST%05d\", \"name\": \"%s %s\", \"email\": \"%s.%s@example.com\" },\n",
+			r.Intn(100000), first, last, strings.ToLower(first), strings.ToLower(last))
+		fmt.Fprintf(&b, "      \"ship_to\": { \"street\": \"%d Main St\", \"city\": \"%s\", \"country\": \"%s\" },\n",
+			1+r.Intn(9999), loc.city, loc.country)
+		b.WriteString("      \"lines\": [\n")
+		for l := 1 + r.Intn(6); l > 0; l-- {
+			prod := products[r.Intn(len(products))]
+			fmt.Fprintf(&b,
+				"        { \"sku\": \"%s\", \"description\": \"%s\", \"quantity\": %d, \"unit_price\": %s, \"currency\": \"USD\" }",
+				prod.sku, strings.ReplaceAll(prod.desc, `"`, `\"`), 1+r.Intn(20), money(r, 200))
+			if l > 1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "      ],\n      \"gift\": %t\n    }", r.Intn(4) == 0)
+		if i < n {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("  ]\n}\n")
+	return b.Bytes()
+}
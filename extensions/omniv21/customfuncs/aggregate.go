@@ -0,0 +1,51 @@
+package customfuncs
+
+import (
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// Aggregator is a Go-implemented aggregate function that folds over a set of IDR nodes, e.g. to compute the
+// chargeable weight of a shipment over all of its HL/LIN items. It's turned into a custom func by Aggregate.
+type Aggregator struct {
+	// Init returns the initial accumulator, given the extra args of the custom func invocation. If nil, the
+	// initial accumulator is nil.
+	Init func(ctx *transformctx.Ctx, args []string) (interface{}, error)
+	// Step folds a node into the accumulator and returns the new accumulator. Required.
+	Step func(ctx *transformctx.Ctx, acc interface{}, n *idr.Node) (interface{}, error)
+	// Finish turns the final accumulator into the result of the aggregation. If nil, the final accumulator
+	// is the result.
+	Finish func(ctx *transformctx.Ctx, acc interface{}) (interface{}, error)
+}
+
+// Aggregate turns an Aggregator into a custom func, which can then be registered in the CustomFuncs of an
+// omniparser.Extension under a name of choice. The custom func's first arg is an xpath, queried against
+// the contextual node, whose matched nodes, in document order, are folded over; the rest of its args are
+// passed to Init.
+func Aggregate(agg Aggregator) customfuncs.CustomFuncType {
+	if agg.Step == nil {
+		panic("Aggregator.Step must not be nil")
+	}
+	return func(ctx *transformctx.Ctx, n *idr.Node, xpath string, args ...string) (interface{}, error) {
+		nodes, err := idr.MatchAll(n, xpath)
+		if err != nil {
+			return nil, err
+		}
+		var acc interface{}
+		if agg.Init != nil {
+			if acc, err = agg.Init(ctx, args); err != nil {
+				return nil, err
+			}
+		}
+		for _, node := range nodes {
+			if acc, err = agg.Step(ctx, acc, node); err != nil {
+				return nil, err
+			}
+		}
+		if agg.Finish == nil {
+			return acc, nil
+		}
+		return agg.Finish(ctx, acc)
+	}
+}
@@ -0,0 +1,116 @@
+package customfuncs
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
+)
+
+type aggregateFunc = func(*transformctx.Ctx, *idr.Node, string, ...string) (interface{}, error)
+
+// chargeableWeight sums, over all the items, the greater of the actual weight and the volumetric weight
+// (volume divided by a divisor given as the arg), e.g. for air freight.
+var chargeableWeight = Aggregator{
+	Init: func(_ *transformctx.Ctx, args []string) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("divisor is required")
+		}
+		divisor, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{divisor, 0}, nil
+	},
+	Step: func(_ *transformctx.Ctx, acc interface{}, n *idr.Node) (interface{}, error) {
+		weight, err := idr.MatchSingle(n, "weight")
+		if err != nil {
+			return nil, err
+		}
+		volume, err := idr.MatchSingle(n, "volume")
+		if err != nil {
+			return nil, err
+		}
+		w, err := strconv.ParseFloat(weight.InnerText(), 64)
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(volume.InnerText(), 64)
+		if err != nil {
+			return nil, err
+		}
+		state := acc.([]float64)
+		state[1] += math.Max(w, v/state[0])
+		return state, nil
+	},
+	Finish: func(_ *transformctx.Ctx, acc interface{}) (interface{}, error) {
+		return acc.([]float64)[1], nil
+	},
+}
+
+func TestAggregate(t *testing.T) {
+	r, err := idr.NewJSONStreamReader(strings.NewReader(`{
+		"items": [
+			{ "weight": "10", "volume": "60000" },
+			{ "weight": "8", "volume": "6000" },
+			{ "weight": "x", "volume": "6000" }
+		]
+	}`), ".")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	f := Aggregate(chargeableWeight).(aggregateFunc)
+
+	v, err := f(nil, n, "items/*[position() < 3]", "5000")
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, v)
+
+	v, err = f(nil, n, "nothing", "5000")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, v)
+
+	_, err = f(nil, n, "items/*", "5000")
+	assert.Error(t, err)
+	assert.Equal(t, `strconv.ParseFloat: parsing "x": invalid syntax`, err.Error())
+
+	_, err = f(nil, n, "items/*")
+	assert.Error(t, err)
+	assert.Equal(t, "divisor is required", err.Error())
+
+	_, err = f(nil, n, "[", "5000")
+	assert.Error(t, err)
+	assert.Equal(t, "xpath '[' compilation failed: expression must evaluate to a node-set", err.Error())
+}
+
+func TestAggregate_NoInitNoFinish(t *testing.T) {
+	n := idr.CreateNode(idr.ElementNode, "PO1")
+	for _, name := range []string{"LIN", "PID", "LIN"} {
+		idr.AddChild(n, idr.CreateNode(idr.ElementNode, name))
+	}
+	count := Aggregate(Aggregator{
+		Step: func(_ *transformctx.Ctx, acc interface{}, _ *idr.Node) (interface{}, error) {
+			if acc == nil {
+				return 1, nil
+			}
+			return acc.(int) + 1, nil
+		},
+	}).(aggregateFunc)
+	v, err := count(nil, n, "LIN")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+	v, err = count(nil, n, "SDQ")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+}
+
+func TestAggregate_NoStep(t *testing.T) {
+	assert.PanicsWithValue(t, "Aggregator.Step must not be nil", func() {
+		Aggregate(Aggregator{})
+	})
+}
@@ -1,6 +1,10 @@
 package customfuncs
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+
 	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/idr"
 	"github.com/logward/omniparser/transformctx"
@@ -10,11 +14,125 @@ import (
 var OmniV21CustomFuncs = map[string]customfuncs.CustomFuncType{
 	// keep these custom funcs lexically sorted
 	"copy":                    CopyFunc,
+	"emitted_count":           EmittedCount,
+	"eval_xpath":              EvalXPath,
 	"javascript":              JavaScript,
 	"javascript_with_context": JavaScriptWithContext,
+	"node_position":           NodePosition,
+	"record_id":               RecordID,
+	"record_number":           RecordNumber,
+	"record_number_in_group":  RecordNumberInGroup,
+}
+
+// OmniV21CustomFuncDocs documents the OmniV21CustomFuncs.
+var OmniV21CustomFuncDocs = map[string]customfuncs.FuncDoc{
+	// keep these custom funcs lexically sorted
+	"copy": {
+		Doc: "copies the contextual node and returns it as an object, array or value.",
+	},
+	"emitted_count": {
+		Doc: "returns the number of records successfully transformed before the current one.",
+	},
+	"eval_xpath": {
+		Args: []string{"xpath"},
+		Doc:  "evaluates an xpath, computed at transform time, against the contextual node and returns the text of the matched node.",
+	},
+	"javascript": {
+		Args: []string{"js", "args"},
+		Doc:  "runs a javascript, with the args given as pairs of variable names and values.",
+	},
+	"javascript_with_context": {
+		Args: []string{"js", "args"},
+		Doc:  "runs a javascript, with the args given as pairs of variable names and values, and the contextual node as '_node'.",
+	},
+	"node_position": {
+		Doc: "returns the 1-based position of the contextual node among its siblings of the same name.",
+	},
+	"record_id": {
+		Doc: "returns a deterministic ID of the current record.",
+	},
+	"record_number": {
+		Doc: "returns the 1-based ordinal of the current record in the input.",
+	},
+	"record_number_in_group": {
+		Doc: "returns the 1-based ordinal of the current record among the records sharing its parent.",
+	},
 }
 
 // CopyFunc copies the current contextual idr.Node and returns it as a JSON marshaling friendly interface{}.
 func CopyFunc(_ *transformctx.Ctx, n *idr.Node) (interface{}, error) {
 	return idr.J2NodeToInterface(n, true), nil
 }
+
+// RecordID returns the deterministic ID of the record currently being transformed. The ID is derived
+// from the record's checksum, its ordinal in the input and its source position range, thus stays the
+// same across replays/retries of the same input.
+func RecordID(ctx *transformctx.Ctx) (string, error) {
+	if ctx == nil || ctx.RecordID == nil {
+		return "", errors.New("record id is not available")
+	}
+	return ctx.RecordID(), nil
+}
+
+var errCountersNotAvailable = errors.New("record counters are not available")
+
+// RecordNumber returns the 1-based ordinal of the record currently being transformed in the input.
+func RecordNumber(ctx *transformctx.Ctx) (string, error) {
+	if ctx == nil || ctx.Counters.Number == 0 {
+		return "", errCountersNotAvailable
+	}
+	return strconv.Itoa(ctx.Counters.Number), nil
+}
+
+// RecordNumberInGroup returns the 1-based ordinal of the record currently being transformed among the
+// consecutive records sharing the same parent in the input, e.g. the transaction sets of an EDI
+// functional group.
+func RecordNumberInGroup(ctx *transformctx.Ctx) (string, error) {
+	if ctx == nil || ctx.Counters.Number == 0 {
+		return "", errCountersNotAvailable
+	}
+	return strconv.Itoa(ctx.Counters.NumberInGroup), nil
+}
+
+// EmittedCount returns the number of records successfully transformed before the record currently
+// being transformed.
+func EmittedCount(ctx *transformctx.Ctx) (string, error) {
+	if ctx == nil || ctx.Counters.Number == 0 {
+		return "", errCountersNotAvailable
+	}
+	return strconv.Itoa(ctx.Counters.Emitted), nil
+}
+
+// NodePosition returns the 1-based position of the current contextual idr.Node among its siblings of
+// the same name, e.g. the line number of a line item segment within a transaction.
+func NodePosition(_ *transformctx.Ctx, n *idr.Node) (string, error) {
+	pos := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == n.Type && s.Data == n.Data {
+			pos++
+		}
+	}
+	return strconv.Itoa(pos), nil
+}
+
+// EvalXPath evaluates an xpath, typically computed at transform time from other values (e.g. with
+// 'concat'), against the current contextual idr.Node and returns the text of the matched node. This
+// allows the source location of a value to depend on a qualifier, e.g.
+// "../REF[REF01='" + qualifier + "']/REF02". If the xpath matches no node, an empty string is returned;
+// if it matches more than one node, it fails.
+func EvalXPath(_ *transformctx.Ctx, n *idr.Node, xpath string) (string, error) {
+	if xpath == "" {
+		return "", errors.New("xpath must not be empty")
+	}
+	// The xpath is computed per node, thus not worth caching its compilation.
+	m, err := idr.MatchSingle(n, xpath, idr.DisableXPathCache)
+	switch {
+	case err == idr.ErrNoMatch:
+		return "", nil
+	case err == idr.ErrMoreThanExpected:
+		return "", fmt.Errorf("xpath query '%s' yielded more than one result", xpath)
+	case err != nil:
+		return "", err
+	}
+	return m.InnerText(), nil
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/jf-tech/go-corelib/jsons"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
 )
 
 func TestDumpOmniV21CustomFuncNames(t *testing.T) {
@@ -31,3 +33,97 @@ func TestCopyFunc(t *testing.T) {
 	assert.NoError(t, err)
 	cupaloy.SnapshotT(t, jsons.BPM(dest))
 }
+
+func TestRecordID(t *testing.T) {
+	_, err := RecordID(nil)
+	assert.Error(t, err)
+	assert.Equal(t, "record id is not available", err.Error())
+	_, err = RecordID(&transformctx.Ctx{})
+	assert.Error(t, err)
+	id, err := RecordID(&transformctx.Ctx{RecordID: func() string { return "abc" }})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", id)
+}
+
+func TestRecordCounters(t *testing.T) {
+	for _, f := range []func(*transformctx.Ctx) (string, error){RecordNumber, RecordNumberInGroup, EmittedCount} {
+		_, err := f(nil)
+		assert.Error(t, err)
+		assert.Equal(t, "record counters are not available", err.Error())
+		_, err = f(&transformctx.Ctx{})
+		assert.Error(t, err)
+	}
+	ctx := &transformctx.Ctx{Counters: transformctx.RecordCounters{Number: 5, NumberInGroup: 2, Emitted: 3}}
+	s, err := RecordNumber(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", s)
+	s, err = RecordNumberInGroup(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", s)
+	s, err = EmittedCount(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", s)
+}
+
+func TestNodePosition(t *testing.T) {
+	n := idr.CreateNode(idr.ElementNode, "PO1")
+	var children []*idr.Node
+	for _, name := range []string{"LIN", "PID", "LIN", "LIN"} {
+		child := idr.CreateNode(idr.ElementNode, name)
+		idr.AddChild(n, child)
+		children = append(children, child)
+	}
+	for i, expected := range []string{"1", "1", "2", "3"} {
+		s, err := NodePosition(nil, children[i])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, s)
+	}
+}
+
+func TestEvalXPath(t *testing.T) {
+	j := `{ "refs": [ { "q": "PO", "v": "123" }, { "q": "BM", "v": "456" }, { "q": "BM", "v": "789" } ] }`
+	r, err := idr.NewJSONStreamReader(strings.NewReader(j), ".")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name     string
+		xpath    string
+		expected string
+		err      string
+	}{
+		{name: "match", xpath: "refs/*[q='PO']/v", expected: "123"},
+		{name: "no match", xpath: "refs/*[q='CN']/v", expected: ""},
+		{
+			name: "more than one match", xpath: "refs/*[q='BM']/v",
+			err: "xpath query 'refs/*[q='BM']/v' yielded more than one result",
+		},
+		{name: "empty xpath", xpath: "", err: "xpath must not be empty"},
+		{
+			name: "invalid xpath", xpath: "refs/*[",
+			err: "xpath 'refs/*[' compilation failed: expression must evaluate to a node-set",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := EvalXPath(nil, n, test.xpath)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
+
+func TestOmniV21CustomFuncDocs(t *testing.T) {
+	assert.Equal(t, len(OmniV21CustomFuncs), len(OmniV21CustomFuncDocs))
+	for _, desc := range customfuncs.Describe(OmniV21CustomFuncs, OmniV21CustomFuncDocs) {
+		assert.NotEmpty(t, desc.Doc, desc.Name)
+		for _, arg := range desc.Args {
+			assert.NotEmpty(t, arg.Name, desc.Name)
+		}
+	}
+}
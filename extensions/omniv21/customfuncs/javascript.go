@@ -2,7 +2,9 @@ package customfuncs
 
 import (
 	"fmt"
+	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/jf-tech/go-corelib/caches"
@@ -70,7 +72,7 @@ func getNodeJSON(n *idr.Node) string {
 	return j.(string)
 }
 
-func execProgram(program *goja.Program, args map[string]interface{}) (goja.Value, error) {
+func execProgram(ctx *transformctx.Ctx, program *goja.Program, args map[string]interface{}) (goja.Value, error) {
 	var vm *goja.Runtime
 	var poolObj interface{}
 	if disableCaching {
@@ -85,11 +87,22 @@ func execProgram(program *goja.Program, args map[string]interface{}) (goja.Value
 			for arg := range args {
 				_ = vm.GlobalObject().Delete(arg)
 			}
+			// and the custom sources, if any.
+			vm.SetRandSource(rand.Float64)
+			vm.SetTimeSource(time.Now)
 		}
 		if poolObj != nil {
 			jsRuntimePool.Put(poolObj)
 		}
 	}()
+	if ctx != nil && (ctx.Clock != nil || ctx.Determinism != nil) {
+		// `Date` follows the transform's clock.
+		vm.SetTimeSource(ctx.Now)
+	}
+	if r := ctx.Rand(); r != nil {
+		// pins `Math.random()` if the transform is deterministic.
+		vm.SetRandSource(r.Float64)
+	}
 	for arg, val := range args {
 		vm.Set(arg, val)
 	}
@@ -98,7 +111,7 @@ func execProgram(program *goja.Program, args map[string]interface{}) (goja.Value
 
 // JavaScriptWithContext is a custom_func that runs a javascript with optional arguments and
 // with contextual '_node' JSON, if idr.Node is provided.
-func JavaScriptWithContext(_ *transformctx.Ctx, n *idr.Node, js string, args ...interface{}) (interface{}, error) {
+func JavaScriptWithContext(ctx *transformctx.Ctx, n *idr.Node, js string, args ...interface{}) (interface{}, error) {
 	if len(args)%2 != 0 {
 		return nil, fmt.Errorf("number of args must be even, but got %d", len(args))
 	}
@@ -113,7 +126,7 @@ func JavaScriptWithContext(_ *transformctx.Ctx, n *idr.Node, js string, args ...
 	if n != nil {
 		vmArgs[argNameNode] = getNodeJSON(n)
 	}
-	v, err := execProgram(program, vmArgs)
+	v, err := execProgram(ctx, program, vmArgs)
 	if err != nil {
 		return nil, err
 	}
@@ -4,10 +4,12 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
 )
 
 const (
@@ -156,6 +158,37 @@ func TestJavaScriptClearVarsAfterRunProgram(t *testing.T) {
 	assert.Equal(t, int64(30), r)
 }
 
+func TestJavaScriptDeterminism(t *testing.T) {
+	prepCachesForTest(withCache)
+	ctx := func() *transformctx.Ctx {
+		return &transformctx.Ctx{
+			Determinism: &transformctx.Determinism{Now: time.Unix(1234567890, 0), Seed: 42},
+			Counters:    transformctx.RecordCounters{Number: 1},
+		}
+	}
+	r1, err := JavaScript(ctx(), `Math.random() + ',' + Date.now()`)
+	assert.NoError(t, err)
+	r2, err := JavaScript(ctx(), `Math.random() + ',' + Date.now()`)
+	assert.NoError(t, err)
+	assert.Equal(t, r1, r2)
+	assert.True(t, strings.HasSuffix(r1.(string), ",1234567890000"))
+	// the pooled runtime is no longer pinned.
+	r, err := JavaScript(nil, `Date.now()`)
+	assert.NoError(t, err)
+	assert.True(t, r.(int64) > 1234567890000)
+}
+
+func TestJavaScriptClock(t *testing.T) {
+	prepCachesForTest(withCache)
+	ctx := &transformctx.Ctx{Clock: transformctx.ClockFunc(func() time.Time { return time.Unix(1234567890, 0) })}
+	r, err := JavaScript(ctx, `Date.now()`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234567890000), r)
+	r, err = JavaScript(nil, `Date.now()`)
+	assert.NoError(t, err)
+	assert.True(t, r.(int64) > 1234567890000)
+}
+
 // go test -bench=. -benchmem -benchtime=30s
 // BenchmarkJavaScriptWithNoCache-8             	  225940	    160696 ns/op	  136620 B/op	    1698 allocs/op
 // BenchmarkJavaScriptWithCache-8               	22289469	      1612 ns/op	     140 B/op	       9 allocs/op
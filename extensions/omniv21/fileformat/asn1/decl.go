@@ -0,0 +1,83 @@
+package asn1
+
+import (
+	"fmt"
+)
+
+// ASN.1 tag classes, as encoded in the top 2 bits of the identifier octet.
+const (
+	classUniversal   = 0
+	classApplication = 1
+	classContext     = 2
+	classPrivate     = 3
+)
+
+var classNames = [...]string{"UNIVERSAL", "APPLICATION", "CONTEXT", "PRIVATE"}
+
+// Value types of primitive (non-constructed) elements.
+const (
+	typeInteger = "integer"
+	typeBoolean = "boolean"
+	typeString  = "string"
+	typeHex     = "hex"
+	typeBCD     = "bcd"
+	typeTBCD    = "tbcd"
+	typeOID     = "oid"
+)
+
+type tagKey struct {
+	class  int
+	number int
+}
+
+func (k tagKey) String() string {
+	return fmt.Sprintf("%s %d", classNames[k.class], k.number)
+}
+
+// elemName returns the IDR element name used for an element with an undeclared tag,
+// e.g. 'APPLICATION_4'.
+func (k tagKey) elemName() string {
+	return fmt.Sprintf("%s_%d", classNames[k.class], k.number)
+}
+
+// TagDecl describes how an ASN.1 element of a particular tag is decoded into IDR. Tag declarations
+// are scoped: the 'children' of a TagDecl take precedence over the tag declarations of its ancestors
+// (and the top level tag declarations) when decoding the elements nested inside it. This allows
+// context-specific tags (e.g. '[0]'), which are only unique within their enclosing type, to be
+// declared properly.
+type TagDecl struct {
+	// Tag is in the form of '[<class> <number>]', e.g. '[APPLICATION 4]', where class is one of
+	// 'UNIVERSAL', 'APPLICATION', 'CONTEXT' and 'PRIVATE'. If class is omitted, e.g. '[0]', it is
+	// context-specific, same as in ASN.1 notation. The square brackets are optional.
+	Tag string `json:"tag"`
+	// Name is the IDR element name.
+	Name string `json:"name"`
+	// Type specifies how the content of a primitive element is decoded. If omitted, it is inferred
+	// for UNIVERSAL tags, and defaults to 'hex' for all other tags. Ignored for constructed elements.
+	Type     *string    `json:"type,omitempty"`
+	IsTarget bool       `json:"is_target,omitempty"`
+	Children []*TagDecl `json:"children,omitempty"`
+
+	key      tagKey
+	parent   *TagDecl
+	children map[tagKey]*TagDecl
+}
+
+// FileDecl describes ASN.1 BER/DER specific schema settings for omniparser reader.
+type FileDecl struct {
+	TagDecls []*TagDecl `json:"tag_declarations"`
+
+	tags   map[tagKey]*TagDecl
+	target *TagDecl
+}
+
+// lookup finds the TagDecl for a tag, starting from the children of scope, then scope's ancestors,
+// and finally the top level tag declarations. nil scope means top level.
+func (d *FileDecl) lookup(scope *TagDecl, key tagKey) *TagDecl {
+	for s := scope; s != nil; s = s.parent {
+		if tagDecl, found := s.children[key]; found {
+			return tagDecl
+		}
+	}
+	return d.tags[key]
+}
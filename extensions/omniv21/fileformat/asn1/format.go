@@ -0,0 +1,84 @@
+package asn1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/validation"
+)
+
+const (
+	fileFormatASN1 = "asn1"
+)
+
+type asn1FileFormat struct {
+	schemaName string
+}
+
+// NewASN1FileFormat creates a FileFormat for ASN.1 BER/DER encoded input.
+func NewASN1FileFormat(schemaName string) fileformat.FileFormat {
+	return &asn1FileFormat{schemaName: schemaName}
+}
+
+type asn1FormatRuntime struct {
+	Decl  *FileDecl `json:"file_declaration"`
+	XPath string
+}
+
+func (f *asn1FileFormat) ValidateSchema(
+	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatASN1 {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	err := validation.SchemaValidate(f.schemaName, schemaContent, v21validation.JSONSchemaASN1FileDeclaration)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	var runtime asn1FormatRuntime
+	_ = json.Unmarshal(schemaContent, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	err = f.validateFileDecl(runtime.Decl)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if runtime.XPath != "" {
+		_, err := caches.GetXPathExpr(runtime.XPath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s",
+				runtime.XPath, err.Error())
+		}
+	}
+	return &runtime, nil
+}
+
+func (f *asn1FileFormat) validateFileDecl(decl *FileDecl) error {
+	err := (&validateCtx{}).validateFileDecl(decl)
+	if err != nil {
+		return f.FmtErr(err.Error())
+	}
+	return err
+}
+
+func (f *asn1FileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	rt := runtime.(*asn1FormatRuntime)
+	return NewReader(name, r, rt.Decl, rt.XPath)
+}
+
+func (f *asn1FileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,77 @@
+package asn1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatASN1,
+			schema:      `{"file_declaration": { "tag_declarations": [ { "tag": "[APP 1]", "name": "a" } ] }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.tag_declarations.0.tag: Does not match pattern '^\[?\s*((UNIVERSAL|APPLICATION|CONTEXT|PRIVATE)\s+)?[0-9]+\s*\]?$'`,
+		},
+		{
+			name:   "file_declaration validation fail",
+			format: fileFormatASN1,
+			schema: `{"file_declaration": { "tag_declarations": [
+				{ "tag": "[1]", "name": "a" }, { "tag": "[CONTEXT 1]", "name": "b" } ] }}`,
+			expectedErr: `schema 'test-schema': tag 'CONTEXT 1' of 'b' is already declared by 'a' in the same scope`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatASN1,
+			schema:      `{"file_declaration": { "tag_declarations": [] }}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatASN1,
+			schema:      `{"file_declaration": { "tag_declarations": [] }}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatASN1,
+			schema: `{"file_declaration": { "tag_declarations": [ { "tag": "[APPLICATION 9]", "name": "a", "is_target": true } ] }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[b='1'] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewASN1FileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*asn1FormatRuntime)
+			assert.Equal(t, ".[b='1']", rt.XPath)
+			assert.Equal(t, "a", rt.Decl.target.Name)
+			r, err := NewASN1FileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package asn1
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidBER indicates the BER/DER input is corrupted or truncated. Once the tag-length-value
+// structure is broken, there is no reliable way to find the next element, so this is a fatal,
+// non-continuable error.
+type ErrInvalidBER string
+
+func (e ErrInvalidBER) Error() string { return string(e) }
+
+// IsErrInvalidBER checks if the `err` is of ErrInvalidBER type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidBER(err error) bool {
+	var e ErrInvalidBER
+	return errors.As(err, &e)
+}
+
+const indefinite = -1
+
+type header struct {
+	key         tagKey
+	constructed bool
+	length      int64 // content length, or indefinite.
+}
+
+func (h header) isEOC() bool {
+	return h.key == tagKey{} && !h.constructed && h.length == 0
+}
+
+// frame is a constructed element the reader has stepped into (but not fully decoded into IDR)
+// while looking for target elements.
+type frame struct {
+	scope *TagDecl
+	end   int64 // offset where the element's content ends, or indefinite.
+}
+
+type reader struct {
+	inputName   string
+	r           *bufio.Reader
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	offset      int64 // number of bytes consumed from the input so far.
+	stack       []frame
+}
+
+// Read returns the next target element as a record. If no tag declaration is marked 'is_target',
+// each top level element is a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		if len(r.stack) > 0 {
+			top := r.stack[len(r.stack)-1]
+			if top.end != indefinite && r.offset >= top.end {
+				if r.offset > top.end {
+					return nil, r.invalidBER("element content overruns its enclosing element")
+				}
+				r.stack = r.stack[:len(r.stack)-1]
+				continue
+			}
+		}
+		h, err := r.readHeader()
+		if err == io.EOF && len(r.stack) == 0 {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.isEOC() {
+			if len(r.stack) == 0 {
+				// zero octets padding after top level elements, seen in some block based files.
+				continue
+			}
+			if r.stack[len(r.stack)-1].end != indefinite {
+				return nil, r.invalidBER("unexpected end-of-contents octets")
+			}
+			r.stack = r.stack[:len(r.stack)-1]
+			continue
+		}
+		if len(r.stack) > 0 {
+			top := r.stack[len(r.stack)-1]
+			if top.end != indefinite && h.length != indefinite && r.offset+h.length > top.end {
+				return nil, r.invalidBER("element content overruns its enclosing element")
+			}
+		}
+		scope := r.scope()
+		tagDecl := r.decl.lookup(scope, h.key)
+		if (r.decl.target == nil && len(r.stack) == 0) || (tagDecl != nil && tagDecl == r.decl.target) {
+			n, err := r.decodeElement(h, tagDecl, scope)
+			if err != nil {
+				return nil, err
+			}
+			if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+				idr.RemoveAndReleaseTree(n)
+				continue
+			}
+			return n, nil
+		}
+		if !h.constructed {
+			if err = r.skip(h.length); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if tagDecl != nil {
+			scope = tagDecl
+		}
+		end := int64(indefinite)
+		if h.length != indefinite {
+			end = r.offset + h.length
+		}
+		r.stack = append(r.stack, frame{scope: scope, end: end})
+	}
+}
+
+func (r *reader) scope() *TagDecl {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return r.stack[len(r.stack)-1].scope
+}
+
+func (r *reader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.offset++
+	}
+	return b, err
+}
+
+// readHeader reads the identifier and length octets of an element. io.EOF is returned only if
+// the input ends cleanly right before an element.
+func (r *reader) readHeader() (header, error) {
+	var h header
+	b, err := r.readByte()
+	if err == io.EOF {
+		return h, io.EOF
+	}
+	if err != nil {
+		return h, r.invalidBER("unable to read element: %s", err.Error())
+	}
+	h.key.class = int(b >> 6)
+	h.constructed = b&0x20 != 0
+	h.key.number = int(b & 0x1f)
+	if h.key.number == 0x1f {
+		// high tag number form: base 128, most significant first, bit 8 set on all but the last.
+		h.key.number = 0
+		for i := 0; ; i++ {
+			if b, err = r.readByte(); err != nil {
+				return h, r.unexpectedEOF(err)
+			}
+			if i >= 4 {
+				return h, r.invalidBER("tag number too large")
+			}
+			h.key.number = h.key.number<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+		}
+	}
+	if b, err = r.readByte(); err != nil {
+		return h, r.unexpectedEOF(err)
+	}
+	switch {
+	case b < 0x80:
+		h.length = int64(b)
+	case b == 0x80:
+		if !h.constructed {
+			return h, r.invalidBER("indefinite length on primitive element '%s'", h.key)
+		}
+		h.length = indefinite
+	default:
+		n := int(b & 0x7f)
+		if n > 7 {
+			return h, r.invalidBER("length of element '%s' too large", h.key)
+		}
+		for i := 0; i < n; i++ {
+			if b, err = r.readByte(); err != nil {
+				return h, r.unexpectedEOF(err)
+			}
+			h.length = h.length<<8 | int64(b)
+		}
+	}
+	return h, nil
+}
+
+func (r *reader) unexpectedEOF(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return r.invalidBER("unable to read element: %s", err.Error())
+}
+
+func (r *reader) skip(n int64) error {
+	discarded, err := io.CopyN(ioutil.Discard, r.r, n)
+	r.offset += discarded
+	if err != nil {
+		return r.unexpectedEOF(err)
+	}
+	return nil
+}
+
+// decodeElement decodes an entire element, whose header has just been read, into IDR.
+func (r *reader) decodeElement(h header, tagDecl, scope *TagDecl) (*idr.Node, error) {
+	name := h.key.elemName()
+	if tagDecl != nil {
+		name = tagDecl.Name
+		scope = tagDecl
+	}
+	n := idr.CreateNode(idr.ElementNode, name)
+	if !h.constructed {
+		// not using a pre-allocated buffer as a corrupted length could be arbitrarily large.
+		content, err := ioutil.ReadAll(io.LimitReader(r.r, h.length))
+		r.offset += int64(len(content))
+		if err == nil && int64(len(content)) < h.length {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			idr.RemoveAndReleaseTree(n)
+			return nil, r.unexpectedEOF(err)
+		}
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, decodeValue(valueType(tagDecl, h.key), content)))
+		return n, nil
+	}
+	end := r.offset + h.length
+	for h.length == indefinite || r.offset < end {
+		child, err := r.readHeader()
+		if err == nil && child.isEOC() && h.length == indefinite {
+			return n, nil
+		}
+		if err == nil && child.isEOC() {
+			err = r.invalidBER("unexpected end-of-contents octets")
+		}
+		if err == io.EOF {
+			err = r.unexpectedEOF(err)
+		}
+		var childNode *idr.Node
+		if err == nil {
+			childNode, err = r.decodeElement(child, r.decl.lookup(scope, child.key), scope)
+		}
+		if err != nil {
+			idr.RemoveAndReleaseTree(n)
+			return nil, err
+		}
+		idr.AddChild(n, childNode)
+	}
+	if r.offset > end {
+		idr.RemoveAndReleaseTree(n)
+		return nil, r.invalidBER("element content overruns its enclosing element '%s'", name)
+	}
+	return n, nil
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidBER(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(format, args...))
+}
+
+func (r *reader) fmtErrStr(format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' at offset %d: %s", r.inputName, r.offset, fmt.Sprintf(format, args...))
+}
+
+// invalidBER creates an ErrInvalidBER, wrapped in an errs.ErrInput.
+func (r *reader) invalidBER(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatASN1,
+		Input:  r.inputName,
+		Offset: r.offset,
+		Reason: reason,
+		Err:    ErrInvalidBER(r.fmtErrStr("%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for ASN.1 BER/DER file format.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		r:           bufio.NewReader(src),
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
@@ -0,0 +1,282 @@
+package asn1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// tlv encodes an element with definite length. id is the identifier octet(s).
+func tlv(id string, content ...string) string {
+	c := strings.Join(content, "")
+	n := len(c)
+	switch {
+	case n < 0x80:
+		return id + string([]byte{byte(n)}) + c
+	case n < 0x100:
+		return id + "\x81" + string([]byte{byte(n)}) + c
+	default:
+		return id + "\x82" + string([]byte{byte(n >> 8), byte(n)}) + c
+	}
+}
+
+// tlvIndefinite encodes a constructed element with indefinite length.
+func tlvIndefinite(id string, content ...string) string {
+	return id + "\x80" + strings.Join(content, "") + "\x00\x00"
+}
+
+// A simplified TAP3 like structure:
+//
+//	TransferBatch [APPLICATION 1]
+//	  BatchControlInfo [APPLICATION 4]
+//	    Sender [APPLICATION 196]
+//	    FileSequenceNumber [APPLICATION 109]
+//	  CallEventDetails [APPLICATION 3]
+//	    MobileOriginatedCall [APPLICATION 9] *
+//	      Imsi [APPLICATION 129]
+//	      ChargeAmount [0]
+//	      <undeclared> [APPLICATION 7]
+//	  Notes [0]
+var testInput = tlv("\x61", // [APPLICATION 1], constructed.
+	tlv("\x64", // [APPLICATION 4], constructed.
+		tlv("\x5f\x81\x44", "DEUD2"), // [APPLICATION 196]
+		tlv("\x5f\x6d", "\x00\x07"),  // [APPLICATION 109]
+	),
+	tlvIndefinite("\x63", // [APPLICATION 3], constructed, indefinite.
+		tlv("\x69", // [APPLICATION 9], constructed.
+			tlv("\x5f\x81\x01", "\x62\x02\x10\x32\x54\xf6"),
+			tlv("\x80", "\x01\x2c"),
+			tlv("\x47", "\xab"),
+		),
+		tlvIndefinite("\x69",
+			tlv("\x5f\x81\x01", "\x62\x02\x10\x32\x54\xf7"),
+			tlv("\x80", "\xfe"),
+		),
+	),
+	tlv("\x80", "end"),
+) + "\x00\x00\x00\x00"
+
+const testDecl = `{
+	"tag_declarations": [
+		{ "tag": "[APPLICATION 1]", "name": "TransferBatch" },
+		{ "tag": "[APPLICATION 4]", "name": "BatchControlInfo" },
+		{ "tag": "[APPLICATION 196]", "name": "Sender", "type": "string" },
+		{ "tag": "[APPLICATION 109]", "name": "FileSequenceNumber", "type": "integer" },
+		{ "tag": "[APPLICATION 3]", "name": "CallEventDetails" },
+		{ "tag": "[APPLICATION 129]", "name": "Imsi", "type": "tbcd" },
+		{ "tag": "[0]", "name": "Notes", "type": "string" },
+		{ "tag": "[APPLICATION 9]", "name": "MobileOriginatedCall", "is_target": %IS_TARGET%, "children": [
+			{ "tag": "[0]", "name": "ChargeAmount", "type": "integer" }
+		]}
+	]
+}`
+
+func testFileDecl(t *testing.T, withTarget bool) *FileDecl {
+	var decl FileDecl
+	s := strings.Replace(testDecl, "%IS_TARGET%", "false", 1)
+	if withTarget {
+		s = strings.Replace(testDecl, "%IS_TARGET%", "true", 1)
+	}
+	assert.NoError(t, json.Unmarshal([]byte(s), &decl))
+	assert.NoError(t, (&validateCtx{}).validateFileDecl(&decl))
+	return &decl
+}
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		input      string
+		withTarget bool
+		xpath      string
+		expected   []string
+	}{
+		{
+			name:       "with target",
+			input:      testInput,
+			withTarget: true,
+			expected: []string{
+				`{"APPLICATION_7":"ab","ChargeAmount":"300","Imsi":"26200123456"}`,
+				`{"ChargeAmount":"-2","Imsi":"26200123457"}`,
+			},
+		},
+		{
+			name:       "with target and xpath",
+			input:      testInput,
+			withTarget: true,
+			xpath:      ".[ChargeAmount < 0]",
+			expected: []string{
+				`{"ChargeAmount":"-2","Imsi":"26200123457"}`,
+			},
+		},
+		{
+			name:  "without target",
+			input: testInput + tlv("\x02", "\x01"),
+			expected: []string{
+				`{"BatchControlInfo":{"FileSequenceNumber":"7","Sender":"DEUD2"},` +
+					`"CallEventDetails":[` +
+					`{"APPLICATION_7":"ab","ChargeAmount":"300","Imsi":"26200123456"},` +
+					`{"ChargeAmount":"-2","Imsi":"26200123457"}],"Notes":"end"}`,
+				`"1"`,
+			},
+		},
+		{
+			name:       "target skipped among primitives",
+			input:      tlv("\x04", "x") + tlv("\x30", tlv("\x04", "y"), tlv("\x69", tlv("\x80", "\x05"))),
+			withTarget: true,
+			expected:   []string{`{"ChargeAmount":"5"}`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testFileDecl(t, test.withTarget), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_Failures(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		input      string
+		withTarget bool
+		expErr     string
+	}{
+		{
+			name:   "truncated header",
+			input:  "\x30",
+			expErr: "input 'test-input' at offset 1: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "truncated high tag number",
+			input:  "\x5f\x81",
+			expErr: "input 'test-input' at offset 2: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "tag number too large",
+			input:  "\x5f\x81\x81\x81\x81\x81\x01",
+			expErr: "input 'test-input' at offset 6: tag number too large",
+		},
+		{
+			name:   "truncated long form length",
+			input:  "\x04\x82\x01",
+			expErr: "input 'test-input' at offset 3: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "length too large",
+			input:  "\x04\x88",
+			expErr: "input 'test-input' at offset 2: length of element 'UNIVERSAL 4' too large",
+		},
+		{
+			name:   "indefinite length on primitive",
+			input:  "\x04\x80",
+			expErr: "input 'test-input' at offset 2: indefinite length on primitive element 'UNIVERSAL 4'",
+		},
+		{
+			name:   "truncated primitive content",
+			input:  "\x04\x05abc",
+			expErr: "input 'test-input' at offset 5: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "truncated constructed content",
+			input:  "\x30\x05\x04\x01a",
+			expErr: "input 'test-input' at offset 5: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "truncated indefinite content",
+			input:  "\x30\x80\x04\x01a",
+			expErr: "input 'test-input' at offset 5: unable to read element: unexpected EOF",
+		},
+		{
+			name:   "child overruns parent",
+			input:  "\x30\x02\x04\x01ab",
+			expErr: "input 'test-input' at offset 5: element content overruns its enclosing element 'UNIVERSAL_16'",
+		},
+		{
+			name:   "unexpected EOC",
+			input:  "\x30\x02\x00\x00",
+			expErr: "input 'test-input' at offset 4: unexpected end-of-contents octets",
+		},
+		{
+			name:       "unexpected EOC while looking for target",
+			input:      "\x30\x02\x00\x00",
+			withTarget: true,
+			expErr:     "input 'test-input' at offset 4: unexpected end-of-contents octets",
+		},
+		{
+			name:       "child overruns parent while looking for target",
+			input:      "\x30\x02\x30\x01a",
+			withTarget: true,
+			expErr:     "input 'test-input' at offset 4: element content overruns its enclosing element",
+		},
+		{
+			name:       "truncated skipped content",
+			input:      "\x04\x05abc",
+			withTarget: true,
+			expErr:     "input 'test-input' at offset 5: unable to read element: unexpected EOF",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testFileDecl(t, test.withTarget), "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidBER(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+		})
+	}
+}
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", &failingReader{}, testFileDecl(t, false), "")
+	assert.NoError(t, err)
+	_, err = r.Read()
+	assert.Error(t, err)
+	assert.Equal(t, "input 'test-input' at offset 0: unable to read element: read failure", err.Error())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testFileDecl(t, false), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidBER("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
+
+func TestFmtErr(t *testing.T) {
+	r := &reader{inputName: "test-input", offset: 12}
+	assert.Equal(t, "input 'test-input' at offset 12: test 1", r.FmtErr("test %d", 1).Error())
+}
@@ -0,0 +1,67 @@
+package asn1
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var tagRegexp = regexp.MustCompile(`^\[?\s*(?:(UNIVERSAL|APPLICATION|CONTEXT|PRIVATE)\s+)?([0-9]+)\s*\]?$`)
+
+func parseTag(tag string) (tagKey, error) {
+	m := tagRegexp.FindStringSubmatch(strings.TrimSpace(tag))
+	if m == nil {
+		return tagKey{}, fmt.Errorf("invalid tag '%s'", tag)
+	}
+	num, err := strconv.Atoi(m[2])
+	if err != nil {
+		return tagKey{}, fmt.Errorf("invalid tag '%s'", tag)
+	}
+	key := tagKey{class: classContext, number: num}
+	for i, name := range classNames {
+		if m[1] == name {
+			key.class = i
+		}
+	}
+	return key, nil
+}
+
+type validateCtx struct {
+	fileDecl *FileDecl
+}
+
+func (ctx *validateCtx) validateFileDecl(fileDecl *FileDecl) error {
+	ctx.fileDecl = fileDecl
+	fileDecl.tags = map[tagKey]*TagDecl{}
+	return ctx.validateTagDecls(nil, fileDecl.TagDecls, fileDecl.tags)
+}
+
+func (ctx *validateCtx) validateTagDecls(parent *TagDecl, tagDecls []*TagDecl, scope map[tagKey]*TagDecl) error {
+	for _, tagDecl := range tagDecls {
+		key, err := parseTag(tagDecl.Tag)
+		if err != nil {
+			return err
+		}
+		if dup, found := scope[key]; found {
+			return fmt.Errorf("tag '%s' of '%s' is already declared by '%s' in the same scope",
+				key, tagDecl.Name, dup.Name)
+		}
+		tagDecl.key, tagDecl.parent = key, parent
+		scope[key] = tagDecl
+		if tagDecl.IsTarget {
+			if ctx.fileDecl.target != nil {
+				return fmt.Errorf("a second tag declaration ('%s') with 'is_target' = true is not allowed",
+					tagDecl.Name)
+			}
+			ctx.fileDecl.target = tagDecl
+		}
+		if len(tagDecl.Children) > 0 {
+			tagDecl.children = map[tagKey]*TagDecl{}
+			if err := ctx.validateTagDecls(tagDecl, tagDecl.Children, tagDecl.children); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package asn1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTag(t *testing.T) {
+	for _, test := range []struct {
+		tag    string
+		exp    tagKey
+		expErr string
+	}{
+		{tag: "[APPLICATION 4]", exp: tagKey{class: classApplication, number: 4}},
+		{tag: " UNIVERSAL 16 ", exp: tagKey{class: classUniversal, number: 16}},
+		{tag: "[0]", exp: tagKey{class: classContext, number: 0}},
+		{tag: "CONTEXT 3", exp: tagKey{class: classContext, number: 3}},
+		{tag: "[PRIVATE 196]", exp: tagKey{class: classPrivate, number: 196}},
+		{tag: "[APP 1]", expErr: "invalid tag '[APP 1]'"},
+		{tag: "[99999999999999999999]", expErr: "invalid tag '[99999999999999999999]'"},
+	} {
+		t.Run(test.tag, func(t *testing.T) {
+			key, err := parseTag(test.tag)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.exp, key)
+		})
+	}
+}
+
+func TestValidateFileDecl(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		decl   string
+		expErr string
+	}{
+		{
+			name: "success",
+			decl: `{"tag_declarations": [
+				{ "tag": "[APPLICATION 1]", "name": "a", "children": [ { "tag": "[0]", "name": "a0", "is_target": true } ] },
+				{ "tag": "[0]", "name": "b" }
+			]}`,
+		},
+		{
+			name:   "invalid tag",
+			decl:   `{"tag_declarations": [ { "tag": "x", "name": "a" } ]}`,
+			expErr: "invalid tag 'x'",
+		},
+		{
+			name: "duplicate tag in the same scope",
+			decl: `{"tag_declarations": [
+				{ "tag": "[APPLICATION 1]", "name": "a", "children": [
+					{ "tag": "[0]", "name": "a0" }, { "tag": "CONTEXT 0", "name": "a1" } ] }
+			]}`,
+			expErr: "tag 'CONTEXT 0' of 'a1' is already declared by 'a0' in the same scope",
+		},
+		{
+			name: "second target",
+			decl: `{"tag_declarations": [
+				{ "tag": "[1]", "name": "a", "is_target": true, "children": [ { "tag": "[2]", "name": "b", "is_target": true } ] }
+			]}`,
+			expErr: "a second tag declaration ('b') with 'is_target' = true is not allowed",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var decl FileDecl
+			assert.NoError(t, json.Unmarshal([]byte(test.decl), &decl))
+			err := (&validateCtx{}).validateFileDecl(&decl)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			a := decl.tags[tagKey{class: classApplication, number: 1}]
+			assert.Equal(t, "a", a.Name)
+			assert.Equal(t, a.Children[0], decl.target)
+			assert.Equal(t, a.Children[0], decl.lookup(a, tagKey{class: classContext}))
+			assert.Equal(t, "b", decl.lookup(nil, tagKey{class: classContext}).Name)
+			assert.Equal(t, a, decl.lookup(a.Children[0], tagKey{class: classApplication, number: 1}))
+			assert.Nil(t, decl.lookup(a, tagKey{class: classPrivate}))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package asn1
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// universalTypes maps UNIVERSAL tag numbers to their default value types. UNIVERSAL tags not listed
+// here are decoded as 'hex'.
+var universalTypes = map[int]string{
+	1:  typeBoolean, // BOOLEAN
+	2:  typeInteger, // INTEGER
+	5:  typeString,  // NULL (empty)
+	6:  typeOID,     // OBJECT IDENTIFIER
+	10: typeInteger, // ENUMERATED
+	12: typeString,  // UTF8String
+	18: typeString,  // NumericString
+	19: typeString,  // PrintableString
+	20: typeString,  // TeletexString
+	22: typeString,  // IA5String
+	23: typeString,  // UTCTime
+	24: typeString,  // GeneralizedTime
+	25: typeString,  // GraphicString
+	26: typeString,  // VisibleString
+	27: typeString,  // GeneralString
+}
+
+func valueType(tagDecl *TagDecl, key tagKey) string {
+	if tagDecl != nil && tagDecl.Type != nil {
+		return *tagDecl.Type
+	}
+	if key.class == classUniversal {
+		if t, found := universalTypes[key.number]; found {
+			return t
+		}
+	}
+	return typeHex
+}
+
+// decodeValue converts the content octets of a primitive element into a string per value type.
+func decodeValue(valueType string, b []byte) string {
+	switch valueType {
+	case typeInteger:
+		if len(b) == 0 {
+			return "0"
+		}
+		// two's complement, big endian.
+		n := new(big.Int).SetBytes(b)
+		if b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+		}
+		return n.String()
+	case typeBoolean:
+		for _, c := range b {
+			if c != 0 {
+				return "true"
+			}
+		}
+		return "false"
+	case typeString:
+		return string(b)
+	case typeBCD, typeTBCD:
+		return decodeBCD(b, valueType == typeTBCD)
+	case typeOID:
+		return decodeOID(b)
+	default:
+		return hex.EncodeToString(b)
+	}
+}
+
+// decodeBCD decodes packed binary coded decimal digits. 'bcd' has the high nibble first; 'tbcd'
+// (telephony BCD, used for e.g. IMSI and MSISDN) has the low nibble first. Filler nibbles (0xF) are
+// dropped and non-decimal nibbles are rendered as hex digits.
+func decodeBCD(b []byte, swapped bool) string {
+	const digits = "0123456789ABCDE"
+	var sb strings.Builder
+	for _, c := range b {
+		nibbles := [2]byte{c >> 4, c & 0x0f}
+		if swapped {
+			nibbles[0], nibbles[1] = nibbles[1], nibbles[0]
+		}
+		for _, n := range nibbles {
+			if n != 0x0f {
+				sb.WriteByte(digits[n])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// decodeOID decodes an OBJECT IDENTIFIER into its dotted form, e.g. '1.2.840.113549'. Malformed
+// content is rendered as hex.
+func decodeOID(b []byte) string {
+	var arcs []string
+	var v uint64
+	for i, c := range b {
+		if v > (1<<57)-1 {
+			return hex.EncodeToString(b)
+		}
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return hex.EncodeToString(b)
+			}
+			continue
+		}
+		if len(arcs) == 0 {
+			first := uint64(2)
+			if v < 80 {
+				first = v / 40
+			}
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(v-first*40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
@@ -0,0 +1,54 @@
+package asn1
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueType(t *testing.T) {
+	assert.Equal(t, typeInteger, valueType(nil, tagKey{class: classUniversal, number: 2}))
+	assert.Equal(t, typeHex, valueType(nil, tagKey{class: classUniversal, number: 3}))
+	assert.Equal(t, typeHex, valueType(nil, tagKey{class: classApplication, number: 2}))
+	assert.Equal(t, typeHex, valueType(&TagDecl{}, tagKey{class: classApplication, number: 2}))
+	assert.Equal(t, typeTBCD, valueType(&TagDecl{Type: strs.StrPtr(typeTBCD)}, tagKey{class: classUniversal, number: 2}))
+}
+
+func TestDecodeValue(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		valueType string
+		b         []byte
+		exp       string
+	}{
+		{name: "integer empty", valueType: typeInteger, b: nil, exp: "0"},
+		{name: "integer positive", valueType: typeInteger, b: []byte{0x00, 0xff}, exp: "255"},
+		{name: "integer negative", valueType: typeInteger, b: []byte{0xff, 0x00}, exp: "-256"},
+		{
+			name:      "integer big",
+			valueType: typeInteger,
+			b:         []byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0},
+			exp:       "18446744073709551616",
+		},
+		{name: "boolean false", valueType: typeBoolean, b: []byte{0}, exp: "false"},
+		{name: "boolean true", valueType: typeBoolean, b: []byte{0xff}, exp: "true"},
+		{name: "string", valueType: typeString, b: []byte("D2DEUR"), exp: "D2DEUR"},
+		{name: "hex", valueType: typeHex, b: []byte{0xab, 0x01}, exp: "ab01"},
+		{name: "bcd", valueType: typeBCD, b: []byte{0x20, 0x21, 0x03, 0x1f}, exp: "2021031"},
+		{name: "tbcd", valueType: typeTBCD, b: []byte{0x62, 0x02, 0xa1, 0xf3}, exp: "26201A3"},
+		{name: "oid", valueType: typeOID, b: []byte{0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d}, exp: "1.2.840.113549"},
+		{name: "oid joint-iso-itu-t", valueType: typeOID, b: []byte{0x81, 0x34, 0x03}, exp: "2.100.3"},
+		{name: "oid truncated", valueType: typeOID, b: []byte{0x2a, 0x86}, exp: "2a86"},
+		{
+			name:      "oid arc overflow",
+			valueType: typeOID,
+			b:         []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+			exp:       "ffffffffffffffffff7f",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.exp, decodeValue(test.valueType, test.b))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package cargoimp
+
+// defaultMessageTypes are the Cargo-IMP message identifiers recognized without declaration.
+var defaultMessageTypes = []string{
+	"CSD", // consignment security declaration
+	"FBL", // freight booked list
+	"FFA", // freight booking answer
+	"FFM", // flight manifest
+	"FFR", // freight booking request
+	"FHL", // house waybill / consolidation list
+	"FMA", // acknowledgement
+	"FNA", // error
+	"FSA", // status answer
+	"FSU", // status update
+	"FWB", // air waybill
+	"FWR", // air waybill request
+	"FZB", // house waybill
+}
+
+// FileDecl describes Cargo-IMP specific schema settings for omniparser reader.
+type FileDecl struct {
+	// MessageTypes are additional message identifiers, e.g. 'FDA', to recognize on top of the default
+	// ones.
+	MessageTypes []string `json:"message_types,omitempty"`
+
+	messageTypes map[string]bool
+}
+
+func (d *FileDecl) buildMessageTypes() {
+	d.messageTypes = make(map[string]bool, len(defaultMessageTypes)+len(d.MessageTypes))
+	for _, t := range defaultMessageTypes {
+		d.messageTypes[t] = true
+	}
+	for _, t := range d.MessageTypes {
+		d.messageTypes[t] = true
+	}
+}
@@ -0,0 +1,76 @@
+package cargoimp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/validation"
+)
+
+const (
+	fileFormatCargoIMP = "cargoimp"
+)
+
+type cargoIMPFileFormat struct {
+	schemaName string
+}
+
+// NewCargoIMPFileFormat creates a FileFormat for IATA Cargo-IMP messages.
+func NewCargoIMPFileFormat(schemaName string) fileformat.FileFormat {
+	return &cargoIMPFileFormat{schemaName: schemaName}
+}
+
+type cargoIMPFormatRuntime struct {
+	Decl  *FileDecl `json:"file_declaration"`
+	XPath string
+}
+
+func (f *cargoIMPFileFormat) ValidateSchema(
+	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatCargoIMP {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	err := validation.SchemaValidate(f.schemaName, schemaContent, v21validation.JSONSchemaCargoIMPFileDeclaration)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	var runtime cargoIMPFormatRuntime
+	_ = json.Unmarshal(schemaContent, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if runtime.Decl == nil {
+		// file_declaration is optional.
+		runtime.Decl = &FileDecl{}
+	}
+	runtime.Decl.buildMessageTypes()
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if runtime.XPath != "" {
+		_, err := caches.GetXPathExpr(runtime.XPath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s",
+				runtime.XPath, err.Error())
+		}
+	}
+	return &runtime, nil
+}
+
+func (f *cargoIMPFileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	rt := runtime.(*cargoIMPFormatRuntime)
+	return NewReader(name, r, rt.Decl, rt.XPath)
+}
+
+func (f *cargoIMPFileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,71 @@
+package cargoimp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatCargoIMP,
+			schema:      `{"file_declaration": { "message_types": [ "fwb" ] }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.message_types.0: Does not match pattern '^[A-Z]{3}$'`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatCargoIMP,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatCargoIMP,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatCargoIMP,
+			schema: `{"file_declaration": { "message_types": [ "FDA" ] }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[message_type='FWB'] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewCargoIMPFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*cargoIMPFormatRuntime)
+			assert.Equal(t, ".[message_type='FWB']", rt.XPath)
+			assert.True(t, rt.Decl.messageTypes["FDA"])
+			assert.True(t, rt.Decl.messageTypes["FWB"])
+			r, err := NewCargoIMPFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
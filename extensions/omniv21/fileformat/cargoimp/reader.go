@@ -0,0 +1,219 @@
+package cargoimp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/ios"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidCargoIMP indicates the input can't be read. This is a fatal, non-continuable error.
+// Note errors in a message (e.g. a line that is neither a segment nor a continuation line) are
+// continuable: the reader simply moves onto the next message.
+type ErrInvalidCargoIMP string
+
+func (e ErrInvalidCargoIMP) Error() string { return string(e) }
+
+// IsErrInvalidCargoIMP checks if the `err` is of ErrInvalidCargoIMP type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidCargoIMP(err error) bool {
+	var e ErrInvalidCargoIMP
+	return errors.As(err, &e)
+}
+
+const (
+	awbSegName = "AWB"
+	lineName   = "line"
+	fieldName  = "field"
+)
+
+var (
+	messageIDRegexp = regexp.MustCompile(`^([A-Z]{3})/([0-9]{1,3})$`)
+	segTagRegexp    = regexp.MustCompile(`^[A-Z]{3}(/|$)`)
+	// the untagged consignment detail line, e.g. "125-12345675LHRJFK/T2K30.5", of FWB, FSU, etc.
+	awbRegexp = regexp.MustCompile(`^[0-9]{3}-[0-9]{8}`)
+	// Type B (SITA/ARINC) envelope lines, e.g. "QK LHRFMBA", ".JFKXXXX 251200", and end of message "=".
+	typeBRegexp = regexp.MustCompile(`^(Q[A-Z] |\.|=)`)
+)
+
+type reader struct {
+	inputName      string
+	r              *bufio.Reader
+	decl           *FileDecl
+	targetXPath    *xpath.Expr
+	lineNum        int    // 1-based line number of the last line read.
+	pending        string // a message identifier line read ahead, which starts the next message.
+	pendingLineNum int
+	msgLineNum     int // line number of the current message's identifier line.
+}
+
+// Read returns the next message as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		n, err := r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+func (r *reader) readLine() (string, error) {
+	line, err := ios.ByteReadLine(r.r)
+	if err == io.EOF {
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", r.invalidCargoIMP(r.lineNum+1, "unable to read line: %s", err.Error())
+	}
+	r.lineNum++
+	return strings.TrimRight(string(line), " \t"), nil
+}
+
+func (r *reader) isMessageID(line string) bool {
+	m := messageIDRegexp.FindStringSubmatch(line)
+	return m != nil && r.decl.messageTypes[m[1]]
+}
+
+func (r *reader) readMessage() (*idr.Node, error) {
+	// anything before a message identifier line, e.g. Type B envelope, is skipped.
+	for r.pending == "" {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if r.isMessageID(line) {
+			r.pending, r.pendingLineNum = line, r.lineNum
+		}
+	}
+	m := messageIDRegexp.FindStringSubmatch(r.pending)
+	r.pending, r.msgLineNum = "", r.pendingLineNum
+	root := idr.CreateNode(idr.DocumentNode, "")
+	addText(root, "message_type", m[1])
+	addText(root, "version", m[2])
+	var seg *idr.Node
+	var msgErr error
+	for {
+		line, err := r.readLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			idr.RemoveAndReleaseTree(root)
+			return nil, err
+		}
+		if r.isMessageID(line) {
+			r.pending, r.pendingLineNum = line, r.lineNum
+			break
+		}
+		if msgErr != nil {
+			// skip the rest of a broken message.
+			continue
+		}
+		switch {
+		case line == "" || typeBRegexp.MatchString(line):
+		case line[0] == '/':
+			if seg == nil {
+				msgErr = errors.New(r.fmtErrStr(r.lineNum, "continuation line '%s' without a segment", line))
+				continue
+			}
+			addLine(seg, line[1:])
+		case segTagRegexp.MatchString(line):
+			seg = idr.CreateNode(idr.ElementNode, line[:3])
+			idr.AddChild(root, seg)
+			addLine(seg, strings.TrimPrefix(line[3:], "/"))
+		case awbRegexp.MatchString(line):
+			seg = idr.CreateNode(idr.ElementNode, awbSegName)
+			idr.AddChild(root, seg)
+			addLine(seg, line)
+		default:
+			msgErr = errors.New(r.fmtErrStr(r.lineNum, "unexpected line '%s'", line))
+		}
+	}
+	if msgErr != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, msgErr
+	}
+	return root, nil
+}
+
+func addText(parent *idr.Node, name, value string) {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+}
+
+// addLine adds a 'line' element with the slant ('/') separated 'field's of a segment line, less
+// the segment tag or the leading slant of a continuation line.
+func addLine(seg *idr.Node, content string) {
+	line := idr.CreateNode(idr.ElementNode, lineName)
+	idr.AddChild(seg, line)
+	if content == "" {
+		return
+	}
+	for _, f := range strings.Split(content, "/") {
+		addText(line, fieldName, f)
+	}
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidCargoIMP(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.msgLineNum, format, args...))
+}
+
+func (r *reader) fmtErrStr(lineNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' line %d: %s", r.inputName, lineNum, fmt.Sprintf(format, args...))
+}
+
+// invalidCargoIMP creates an ErrInvalidCargoIMP, wrapped in an errs.ErrInput.
+func (r *reader) invalidCargoIMP(lineNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatCargoIMP,
+		Input:  r.inputName,
+		Line:   lineNum,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidCargoIMP(r.fmtErrStr(lineNum, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for IATA Cargo-IMP file format. decl must have been validated.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		r:           bufio.NewReader(src),
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
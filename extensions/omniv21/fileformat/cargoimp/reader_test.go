@@ -0,0 +1,170 @@
+package cargoimp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func testDecl(messageTypes ...string) *FileDecl {
+	decl := &FileDecl{MessageTypes: messageTypes}
+	decl.buildMessageTypes()
+	return decl
+}
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+const (
+	testFWB = "FWB/16\n" +
+		"125-12345675LHRJFK/T2K30.5\n" +
+		"FLT/BA0117/25\n" +
+		"SHP\n" +
+		"/ACME LTD\n" +
+		"/1 HIGH ST//X\n" +
+		"CNE\n" +
+		"/EXAMPLE INC\n"
+	testFHL = "FHL/4\n" +
+		"MBI/125-12345675LHRJFK/T2K30.5\n" +
+		"HBS/HAWB1/LHRJFK/1/K10.5//BOOKS\n" +
+		"HBS/HAWB2/LHRJFK/1/K20//TOYS\n"
+	// note idr.JSONify2 collapses an element with a single kind of children into an array.
+	testFHLJSON = `{"HBS":[{"line":["HAWB1","LHRJFK","1","K10.5","","BOOKS"]},` +
+		`{"line":["HAWB2","LHRJFK","1","K20","","TOYS"]}],` +
+		`"MBI":{"line":["125-12345675LHRJFK","T2K30.5"]},` +
+		`"message_type":"FHL","version":"4"}`
+)
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		decl     *FileDecl
+		xpath    string
+		expected []string
+	}{
+		{
+			name:  "type b envelope, multiple messages",
+			input: "QK LHRFMBA\r\n.JFKXXXX 251200\r\n" + strings.Replace(testFWB, "\n", "\r\n", -1) + "=\n\n" + testFHL,
+			decl:  testDecl(),
+			expected: []string{
+				`{"AWB":{"line":["125-12345675LHRJFK","T2K30.5"]},` +
+					`"CNE":[{},{"field":"EXAMPLE INC"}],` +
+					`"FLT":{"line":["BA0117","25"]},` +
+					`"SHP":[{},{"field":"ACME LTD"},["1 HIGH ST","","X"]],` +
+					`"message_type":"FWB","version":"16"}`,
+				testFHLJSON,
+			},
+		},
+		{
+			name:     "with xpath",
+			input:    testFWB + testFHL,
+			decl:     testDecl(),
+			xpath:    ".[message_type='FHL']",
+			expected: []string{testFHLJSON},
+		},
+		{
+			name:     "declared message type",
+			input:    "XYZ/1\nABC/1\n",
+			decl:     testDecl("XYZ"),
+			expected: []string{`{"ABC":{"line":{"field":"1"}},"message_type":"XYZ","version":"1"}`},
+		},
+		{
+			name:     "no message",
+			input:    "hello\nXYZ/1\n",
+			decl:     testDecl(),
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), test.decl, test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_MessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		msg            string
+		expErr         string
+		nextMsgLineNum int
+	}{
+		{
+			name:           "continuation line without segment",
+			msg:            "FWB/16\n/ACME LTD\nFLT/BA0117/25\n",
+			expErr:         "input 'test-input' line 2: continuation line '/ACME LTD' without a segment",
+			nextMsgLineNum: 4,
+		},
+		{
+			name:           "unexpected line",
+			msg:            "FWB/16\nFLT/BA0117/25\nhello\nSHP\n",
+			expErr:         "input 'test-input' line 3: unexpected line 'hello'",
+			nextMsgLineNum: 5,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.msg+testFHL), testDecl(), "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+			// reader moves onto the next message.
+			n, err = r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, testFHLJSON, idr.JSONify2(n))
+			assert.Equal(t, fmt.Sprintf("input 'test-input' line %d: test", test.nextMsgLineNum), r.FmtErr("test").Error())
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", io.MultiReader(strings.NewReader(testFHL), failingReader{}), testDecl(), "")
+	assert.NoError(t, err)
+	_, err = readAll(t, r)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidCargoIMP(err))
+	assert.False(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' line 5: unable to read line: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testDecl(), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidCargoIMP("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
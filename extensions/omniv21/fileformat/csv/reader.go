@@ -11,6 +11,7 @@ import (
 	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/maths"
 
+	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -20,14 +21,10 @@ type ErrInvalidHeader string
 
 func (e ErrInvalidHeader) Error() string { return string(e) }
 
-// IsErrInvalidHeader checks if the `err` is of ErrInvalidHeader type.
+// IsErrInvalidHeader checks if the `err` is of ErrInvalidHeader type, or wraps one, e.g. in an errs.ErrInput.
 func IsErrInvalidHeader(err error) bool {
-	switch err.(type) {
-	case ErrInvalidHeader:
-		return true
-	default:
-		return false
-	}
+	var e ErrInvalidHeader
+	return errors.As(err, &e)
 }
 
 type reader struct {
@@ -69,22 +66,22 @@ func (r *reader) checkHeader() error {
 	}
 	err = r.jumpTo(*r.decl.HeaderRowIndex - 1)
 	if err != nil {
-		return ErrInvalidHeader(r.fmtErrStr("unable to read header: %s", err.Error()))
+		return r.invalidHeader("unable to read header: %s", err.Error())
 	}
 	header, err = r.r.Read()
 	if err != nil {
-		return ErrInvalidHeader(r.fmtErrStr("unable to read header: %s", err.Error()))
+		return r.invalidHeader("unable to read header: %s", err.Error())
 	}
 	if len(header) < len(r.decl.Columns) {
-		return ErrInvalidHeader(r.fmtErrStr(
+		return r.invalidHeader(
 			"actual header column size (%d) is less than the size (%d) declared in file_declaration.columns in schema",
-			len(header), len(r.decl.Columns)))
+			len(header), len(r.decl.Columns))
 	}
 	for index, column := range r.decl.Columns {
 		if strings.TrimSpace(header[index]) != strings.TrimSpace(column.Name) {
-			return ErrInvalidHeader(r.fmtErrStr(
+			return r.invalidHeader(
 				"header column[%d] '%s' does not match declared column name '%s' in schema",
-				index+1, strings.TrimSpace(header[index]), strings.TrimSpace(column.Name)))
+				index+1, strings.TrimSpace(header[index]), strings.TrimSpace(column.Name))
 		}
 	}
 skipToDataRow:
@@ -143,6 +140,19 @@ func (r *reader) fmtErrStr(format string, args ...interface{}) string {
 	return fmt.Sprintf("input '%s' line %d: %s", r.inputName, r.r.LineNum(), fmt.Sprintf(format, args...))
 }
 
+// invalidHeader creates an ErrInvalidHeader, wrapped in an errs.ErrInput.
+func (r *reader) invalidHeader(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatCSV,
+		Input:  r.inputName,
+		Line:   r.r.LineNum(),
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidHeader(r.fmtErrStr("%s", reason)),
+	}
+}
+
 // NewReader creates an FormatReader for CSV file format.
 func NewReader(inputName string, r io.Reader, decl *FileDecl, xpathStr string) (*reader, error) {
 	var expr *xpath.Expr
@@ -14,7 +14,7 @@ import (
 func BenchmarkRawSeg(b *testing.B) {
 	rawSegName := "test"
 	rawSegData := []byte("test data")
-	r := ediReader{
+	r := Reader{
 		unprocessedRawSeg: newRawSeg(),
 	}
 	for i := 0; i < b.N; i++ {
@@ -31,7 +31,7 @@ func BenchmarkRawSeg(b *testing.B) {
 // Adding a benchmark for stack operation to ensure there is no alloc:
 // BenchmarkGrownShrinkStack-8    	12901227	        89.0 ns/op	       0 B/op	       0 allocs/op
 func BenchmarkGrownShrinkStack(b *testing.B) {
-	r := ediReader{
+	r := Reader{
 		stack: newStack(),
 	}
 	for i := 0; i < b.N; i++ {
@@ -558,7 +558,7 @@ var (
 	// we can do this (reusing reader and its RawSeg again & again in benchmark
 	// because there is no release-char thus there is no data modification in
 	// RawSeg.Elems
-	benchRawSegToNodeReader = &ediReader{unprocessedRawSeg: benchRawSegToNodeRawSeg}
+	benchRawSegToNodeReader = &Reader{unprocessedRawSeg: benchRawSegToNodeRawSeg}
 )
 
 // BenchmarkRawSegToNode-8                               	  674935	      1777 ns/op	     864 B/op	       9 allocs/op
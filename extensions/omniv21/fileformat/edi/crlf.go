@@ -0,0 +1,68 @@
+package edi
+
+import (
+	"io"
+)
+
+// crlfDroppingReader drops the CRs and LFs, other than the escaped ones, i.e. preceded by an effective
+// release character sequence, from the underlying io.Reader; used for ignore_crlf. An escaped CR
+// directly followed by a LF keeps the LF as well, so an escaped CRLF line break stays intact. Same as
+// escapedAt, consecutive release character sequences escape each other. The release character sequence
+// is tracked across Read calls, so it can be split across the reads of the underlying io.Reader.
+type crlfDroppingReader struct {
+	r       io.Reader
+	esc     []byte
+	matched int  // the number of bytes of esc matched by the latest bytes read.
+	escaped bool // if the next byte is escaped.
+	keepLF  bool // if the previous byte is an escaped CR.
+}
+
+func newCRLFDroppingReader(r io.Reader, esc []byte) *crlfDroppingReader {
+	return &crlfDroppingReader{r: r, esc: esc}
+}
+
+func (r *crlfDroppingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.r.Read(p)
+		n = r.filter(p[:n])
+		// keeps reading if all the bytes read in are dropped, as 0 bytes with no error discourages callers.
+		if n > 0 || err != nil || len(p) == 0 {
+			return n, err
+		}
+	}
+}
+
+// filter drops the unescaped CRs and LFs from b in place, returning the number of bytes kept.
+func (r *crlfDroppingReader) filter(b []byte) int {
+	n := 0
+	for _, c := range b {
+		keepLF := r.keepLF
+		r.keepLF = false
+		if len(r.esc) > 0 {
+			if c != r.esc[r.matched] && r.matched > 0 {
+				// a partial release character sequence is data, so it's what the escape, if any, escapes.
+				r.matched, r.escaped = 0, false
+			}
+			if c == r.esc[r.matched] {
+				if r.matched++; r.matched == len(r.esc) {
+					r.matched = 0
+					r.escaped = !r.escaped
+				}
+				b[n] = c
+				n++
+				continue
+			}
+		}
+		switch {
+		case c == '\r' && r.escaped:
+			r.keepLF = true
+		case c == '\n' && (r.escaped || keepLF):
+		case c == '\r' || c == '\n':
+			continue
+		}
+		r.escaped = false
+		b[n] = c
+		n++
+	}
+	return n
+}
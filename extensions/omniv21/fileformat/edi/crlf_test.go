@@ -0,0 +1,80 @@
+package edi
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRLFDroppingReader(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		esc      string
+		expected string
+	}{
+		{name: "no esc", input: "A*1~\r\nB*2~\n", expected: "A*1~B*2~"},
+		{name: "escaped LF", input: "A*x?\ny~\nB~", esc: "?", expected: "A*x?\ny~B~"},
+		{name: "escaped CRLF", input: "A*x?\r\ny~\r\nB~", esc: "?", expected: "A*x?\r\ny~B~"},
+		{name: "escaped esc", input: "A*x??\ny~", esc: "?", expected: "A*x??y~"},
+		{name: "escape of non-CRLF", input: "A*x?*\ny~", esc: "?", expected: "A*x?*y~"},
+		{name: "multi-byte esc", input: "A*x<>\ny<\n>~", esc: "<>", expected: "A*x<>\ny<>~"},
+		{name: "multi-byte esc escaped", input: "A*x<><>\ny~", esc: "<>", expected: "A*x<><>y~"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, r := range []io.Reader{
+				newCRLFDroppingReader(strings.NewReader(test.input), []byte(test.esc)),
+				// release character sequences split across reads.
+				newCRLFDroppingReader(iotest.OneByteReader(strings.NewReader(test.input)), []byte(test.esc)),
+			} {
+				b, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, string(b))
+			}
+		})
+	}
+}
+
+func TestNonValidatingReader_EscapedCRLF(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		decl     FileDecl
+		expected []string
+	}{
+		{
+			name:     "ignore_crlf keeps escaped line breaks",
+			input:    "NTE*line 1?\r\nline 2~\r\nNTE*line 3~\r\n",
+			decl:     FileDecl{SegDelim: "~", ElemDelim: "*", ReleaseChar: strs.StrPtr("?"), IgnoreCRLF: true},
+			expected: []string{"line 1\r\nline 2", "line 3"},
+		},
+		{
+			name:     "multi-rune delimiters",
+			input:    "NTE::a?::b~~NTE::c~~",
+			decl:     FileDecl{SegDelim: "~~", ElemDelim: "::", ReleaseChar: strs.StrPtr("?")},
+			expected: []string{"a::b", "c"},
+		},
+		{
+			name:     "escaped CR before LF segment delimiter",
+			input:    "NTE*a?\r\nNTE*b\r\n",
+			decl:     FileDecl{SegDelim: "\n", ElemDelim: "*", ReleaseChar: strs.StrPtr("?")},
+			expected: []string{"a\r", "b"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNonValidatingReader(strings.NewReader(test.input), &test.decl)
+			for _, expected := range test.expected {
+				rawSeg, err := r.Read()
+				assert.NoError(t, err)
+				assert.Equal(t, "NTE", rawSeg.Name)
+				assert.Equal(t, expected, string(strs.ByteUnescape(rawSeg.Elems[1].Data, r.releaseChar.b, true)))
+			}
+			_, err := r.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
@@ -1,12 +1,91 @@
 package edi
 
+import (
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+// Supported values of the `file_declaration` level `empty_segments`, `inter_segment_whitespace` and
+// `truncated_last_segment` settings, which control how the garbage some VANs emit between segments, and
+// inputs cut off in transit, are handled.
+const (
+	// ToleranceError fails the read. It's the default of `empty_segments`.
+	ToleranceError = "error"
+	// ToleranceSkip drops the empty segment, the whitespace before a segment, or the truncated last
+	// segment. It's the default of `truncated_last_segment`.
+	ToleranceSkip = "skip"
+	// TolerancePreserve keeps the empty segment as a raw segment with an empty name, or the whitespace
+	// as part of the segment following it. It's the default of `inter_segment_whitespace`.
+	TolerancePreserve = "preserve"
+	// ToleranceWarn does what TolerancePreserve does, and reports a warning. Only supported by
+	// `truncated_last_segment`.
+	ToleranceWarn = "warn"
+)
+
+// Supported values of the `file_declaration` level `raw_segment_delimiter` setting, which controls
+// whether the raw bytes of the segments, i.e. RawSeg.Raw and Reader.RawBytes, include their segment
+// delimiters.
+const (
+	// RawSegDelimKeep includes the segment delimiters in the raw bytes. It's the default.
+	RawSegDelimKeep = "keep"
+	// RawSegDelimDrop excludes the segment delimiters from the raw bytes.
+	RawSegDelimDrop = "drop"
+)
+
 // FileDecl describes EDI specific schema settings for omniparser reader.
 type FileDecl struct {
-	SegDelim    string     `json:"segment_delimiter,omitempty"`
-	ElemDelim   string     `json:"element_delimiter,omitempty"`
-	CompDelim   *string    `json:"component_delimiter,omitempty"`
-	RepDelim    *string    `json:"repetition_delimiter,omitempty"`
-	ReleaseChar *string    `json:"release_character,omitempty"`
-	IgnoreCRLF  bool       `json:"ignore_crlf,omitempty"`
-	SegDecls    []*SegDecl `json:"segment_declarations,omitempty"`
+	SegDelim    string  `json:"segment_delimiter,omitempty"`
+	ElemDelim   string  `json:"element_delimiter,omitempty"`
+	CompDelim   *string `json:"component_delimiter,omitempty"`
+	RepDelim    *string `json:"repetition_delimiter,omitempty"`
+	ReleaseChar *string `json:"release_character,omitempty"`
+	IgnoreCRLF  bool    `json:"ignore_crlf,omitempty"`
+	Trim        *string `json:"trim,omitempty"`
+	// EmptySegments controls how empty segments, i.e. consecutive segment delimiters, are handled.
+	EmptySegments *string `json:"empty_segments,omitempty"`
+	// InterSegmentWhitespace controls how whitespace (spaces, tabs, CRs and LFs) between a segment
+	// delimiter and the next segment is handled. Lines with only CRs and/or LFs, when the segment
+	// delimiter is a LF, are always skipped.
+	InterSegmentWhitespace *string `json:"inter_segment_whitespace,omitempty"`
+	// TruncatedLastSegment controls how the trailing data of the input not terminated by the segment
+	// delimiter, i.e. a last segment cut off, e.g. by a network transfer, is handled. Trailing
+	// whitespace is always ignored.
+	TruncatedLastSegment *string `json:"truncated_last_segment,omitempty"`
+	// RawSegDelim controls whether the raw bytes of the segments include their segment delimiters.
+	RawSegDelim *string `json:"raw_segment_delimiter,omitempty"`
+	// StrictISA requires the input to start with an X12 ISA segment of the exact fixed widths, whose
+	// delimiters agree with the declared ones.
+	StrictISA bool `json:"strict_isa,omitempty"`
+	// Dictionary, if set, adds nodes of friendly names to the segments read for their elements named
+	// in it.
+	Dictionary *Dictionary `json:"dictionary,omitempty"`
+	// PositionalElements adds nodes named after the segments and the positions of their elements and
+	// components, e.g. "REF02" and "REF04-02", to the segments read, for each of their elements present.
+	// It's on if any xpath uses the positional element addressing shorthand; see CompileXPath.
+	PositionalElements bool `json:"positional_elements,omitempty"`
+	// SkipUnreferencedElements skips creating the IDR nodes of the elements, including those added by
+	// Dictionary and PositionalElements, not referenced by any transform; see markSkippedElements.
+	SkipUnreferencedElements bool       `json:"skip_unreferenced_elements,omitempty"`
+	SegDecls                 []*SegDecl `json:"segment_declarations,omitempty"`
+
+	dict *dictionary           // internal computed field
+	refs *transform.References // internal computed field; non-nil if any element is skipped.
+}
+
+// markSkippedElements marks the declared elements that are not referenced by any transform as
+// skipped, unless the value of their segment, or of any of its enclosing segments, is read as a whole.
+// It also keeps refs for the reader to skip the unreferenced elements added by Dictionary and
+// PositionalElements in the same way.
+func (d *FileDecl) markSkippedElements(refs *transform.References) {
+	var mark func(segDecls []*SegDecl, valueRead bool)
+	mark = func(segDecls []*SegDecl, valueRead bool) {
+		for _, segDecl := range segDecls {
+			segDecl.valueRead = valueRead || refs.ValueRead(segDecl.Name)
+			for i := range segDecl.Elems {
+				segDecl.Elems[i].skip = !segDecl.valueRead && !refs.Referenced(segDecl.Elems[i].Name)
+			}
+			mark(segDecl.Children, segDecl.valueRead)
+		}
+	}
+	mark(d.SegDecls, false)
+	d.refs = refs
 }
@@ -0,0 +1,233 @@
+package edi
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported values of the `file_declaration.dictionary.standard` setting.
+const (
+	DictionaryX12     = "x12"
+	DictionaryEDIFACT = "edifact"
+)
+
+// Dictionary names the elements of EDI segments, so that each segment read has, in addition to its
+// declared elements, a node for each of its elements, or components, named in the dictionary and present
+// in the input, named after the segment, the element's position and its name, e.g.
+// "BEG03_PurchaseOrderNumber", or, for a component, also its position, e.g.
+// "REF04-02_ReferenceIdentification". These friendly names can be used in XPaths alongside the declared
+// element names, making schemas readable to non-EDI experts.
+type Dictionary struct {
+	// Standard, if set, is the built-in dictionary of the most common segments of the standard, "x12"
+	// or "edifact", which Segments extends or overrides.
+	Standard *string `json:"standard,omitempty"`
+	// Segments maps segment names to the names of their elements, keyed by the elements' 1-based
+	// positions, e.g. "03", or, for components, by the elements' and components' positions, e.g.
+	// "04-02". An element's name names its first component if the element is a composite one.
+	Segments map[string]map[string]string `json:"segments,omitempty"`
+}
+
+type dictKey struct {
+	elemIndex, compIndex int // compIndex is 0 for the name of an element.
+}
+
+// dictionary is a Dictionary resolved for the reader: the node names keyed by segment names and element
+// and component positions.
+type dictionary struct {
+	nodeNames map[string]map[dictKey]string
+}
+
+func (d dictionary) nodeName(segName string, elemIndex, compIndex int) (string, bool) {
+	names, found := d.nodeNames[segName]
+	if !found {
+		return "", false
+	}
+	if name, found := names[dictKey{elemIndex, compIndex}]; found {
+		return name, true
+	}
+	if compIndex == 1 {
+		name, found := names[dictKey{elemIndex, 0}]
+		return name, found
+	}
+	return "", false
+}
+
+func parseDictKey(key string) (dictKey, bool) {
+	parts := strings.Split(key, "-")
+	if len(parts) > 2 {
+		return dictKey{}, false
+	}
+	var indexes [2]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 || n > 99 {
+			return dictKey{}, false
+		}
+		indexes[i] = n
+	}
+	return dictKey{elemIndex: indexes[0], compIndex: indexes[1]}, true
+}
+
+func validName(name string) bool {
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// resolve validates the dictionary and resolves it, merged into the built-in dictionary of its standard
+// if any, for the reader.
+func (d *Dictionary) resolve() (*dictionary, error) {
+	segments := map[string]map[string]string{}
+	if d.Standard != nil {
+		for seg, names := range builtinDictionaries[*d.Standard] {
+			segments[seg] = map[string]string{}
+			for key, name := range names {
+				segments[seg][key] = name
+			}
+		}
+	}
+	for seg, names := range d.Segments {
+		if segments[seg] == nil {
+			segments[seg] = map[string]string{}
+		}
+		for key, name := range names {
+			segments[seg][key] = name
+		}
+	}
+	resolved := &dictionary{nodeNames: map[string]map[dictKey]string{}}
+	var segNames []string
+	for seg := range segments {
+		segNames = append(segNames, seg)
+	}
+	// validates in a deterministic order, so the same dictionary fails with the same error.
+	sort.Strings(segNames)
+	for _, seg := range segNames {
+		resolved.nodeNames[seg] = map[dictKey]string{}
+		for _, key := range sortedKeys(segments[seg]) {
+			k, ok := parseDictKey(key)
+			if !ok {
+				return nil, fmt.Errorf(
+					"dictionary segment '%s' has invalid element position '%s', expected e.g. '03' or '04-02'", seg, key)
+			}
+			name := segments[seg][key]
+			if !validName(name) {
+				return nil, fmt.Errorf(
+					"dictionary segment '%s' element '%s' has invalid name '%s', expected letters, digits and '_' only",
+					seg, key, name)
+			}
+			nodeName := fmt.Sprintf("%s%02d_%s", seg, k.elemIndex, name)
+			if k.compIndex > 0 {
+				nodeName = fmt.Sprintf("%s%02d-%02d_%s", seg, k.elemIndex, k.compIndex, name)
+			}
+			resolved.nodeNames[seg][k] = nodeName
+		}
+	}
+	return resolved, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// builtinDictionaries are the built-in dictionaries of the most common segments of the standards, keyed
+// by Dictionary.Standard.
+var builtinDictionaries = map[string]map[string]map[string]string{
+	DictionaryX12: {
+		"ISA": {
+			"01": "AuthorizationInformationQualifier", "02": "AuthorizationInformation",
+			"03": "SecurityInformationQualifier", "04": "SecurityInformation",
+			"05": "InterchangeSenderIDQualifier", "06": "InterchangeSenderID",
+			"07": "InterchangeReceiverIDQualifier", "08": "InterchangeReceiverID",
+			"09": "InterchangeDate", "10": "InterchangeTime", "11": "RepetitionSeparator",
+			"12": "InterchangeControlVersionNumber", "13": "InterchangeControlNumber",
+			"14": "AcknowledgmentRequested", "15": "InterchangeUsageIndicator", "16": "ComponentElementSeparator",
+		},
+		"GS": {
+			"01": "FunctionalIdentifierCode", "02": "ApplicationSendersCode", "03": "ApplicationReceiversCode",
+			"04": "Date", "05": "Time", "06": "GroupControlNumber", "07": "ResponsibleAgencyCode",
+			"08": "VersionReleaseIndustryIdentifierCode",
+		},
+		"ST": {"01": "TransactionSetIdentifierCode", "02": "TransactionSetControlNumber"},
+		"BEG": {
+			"01": "TransactionSetPurposeCode", "02": "PurchaseOrderTypeCode", "03": "PurchaseOrderNumber",
+			"04": "ReleaseNumber", "05": "Date",
+		},
+		"BIG": {"01": "InvoiceDate", "02": "InvoiceNumber", "03": "PurchaseOrderDate", "04": "PurchaseOrderNumber"},
+		"BSN": {"01": "TransactionSetPurposeCode", "02": "ShipmentIdentification", "03": "Date", "04": "Time"},
+		"REF": {
+			"01": "ReferenceIdentificationQualifier", "02": "ReferenceIdentification", "03": "Description",
+			"04-01": "ReferenceIdentificationQualifier", "04-02": "ReferenceIdentification",
+		},
+		"DTM": {"01": "DateTimeQualifier", "02": "Date", "03": "Time", "04": "TimeCode"},
+		"N1": {
+			"01": "EntityIdentifierCode", "02": "Name", "03": "IdentificationCodeQualifier",
+			"04": "IdentificationCode",
+		},
+		"N2": {"01": "Name", "02": "Name2"},
+		"N3": {"01": "AddressInformation", "02": "AddressInformation2"},
+		"N4": {"01": "CityName", "02": "StateOrProvinceCode", "03": "PostalCode", "04": "CountryCode"},
+		"PER": {
+			"01": "ContactFunctionCode", "02": "Name", "03": "CommunicationNumberQualifier",
+			"04": "CommunicationNumber",
+		},
+		"PO1": {
+			"01": "AssignedIdentification", "02": "QuantityOrdered", "03": "UnitOrBasisForMeasurementCode",
+			"04": "UnitPrice", "05": "BasisOfUnitPriceCode", "06": "ProductServiceIDQualifier",
+			"07": "ProductServiceID",
+		},
+		"IT1": {
+			"01": "AssignedIdentification", "02": "QuantityInvoiced", "03": "UnitOrBasisForMeasurementCode",
+			"04": "UnitPrice", "05": "BasisOfUnitPriceCode", "06": "ProductServiceIDQualifier",
+			"07": "ProductServiceID",
+		},
+		"PID": {"01": "ItemDescriptionType", "05": "Description"},
+		"TDS": {"01": "Amount"},
+		"CTT": {"01": "NumberOfLineItems", "02": "HashTotal"},
+		"SE":  {"01": "NumberOfIncludedSegments", "02": "TransactionSetControlNumber"},
+		"GE":  {"01": "NumberOfTransactionSetsIncluded", "02": "GroupControlNumber"},
+		"IEA": {"01": "NumberOfIncludedFunctionalGroups", "02": "InterchangeControlNumber"},
+	},
+	DictionaryEDIFACT: {
+		"UNB": {
+			"01-01": "SyntaxIdentifier", "01-02": "SyntaxVersionNumber",
+			"02-01": "SenderIdentification", "02-02": "SenderPartnerIdentificationCodeQualifier",
+			"03-01": "RecipientIdentification", "03-02": "RecipientPartnerIdentificationCodeQualifier",
+			"04-01": "Date", "04-02": "Time", "05": "InterchangeControlReference",
+		},
+		"UNH": {
+			"01": "MessageReferenceNumber", "02-01": "MessageType", "02-02": "MessageVersionNumber",
+			"02-03": "MessageReleaseNumber", "02-04": "ControllingAgency",
+		},
+		"BGM": {"01-01": "DocumentNameCode", "02-01": "DocumentIdentifier", "03": "MessageFunctionCode"},
+		"DTM": {"01-01": "DateOrTimeOrPeriodFunctionCodeQualifier", "01-02": "DateOrTimeOrPeriodText",
+			"01-03": "DateOrTimeOrPeriodFormatCode"},
+		"RFF": {"01-01": "ReferenceCodeQualifier", "01-02": "ReferenceIdentifier"},
+		"NAD": {
+			"01": "PartyFunctionCodeQualifier", "02-01": "PartyIdentifier",
+			"02-03": "CodeListResponsibleAgencyCode", "04-01": "PartyName", "05-01": "StreetAndNumber",
+			"06": "CityName", "08": "PostalIdentificationCode", "09": "CountryIdentifier",
+		},
+		"CUX": {"01-01": "CurrencyUsageCodeQualifier", "01-02": "CurrencyIdentificationCode"},
+		"LIN": {"01": "LineItemIdentifier", "03-01": "ItemIdentifier", "03-02": "ItemTypeIdentificationCode"},
+		"PIA": {"01": "ProductIdentifierCodeQualifier", "02-01": "ItemIdentifier",
+			"02-02": "ItemTypeIdentificationCode"},
+		"IMD": {"01": "DescriptionFormatCode", "03-04": "ItemDescription"},
+		"QTY": {"01-01": "QuantityTypeCodeQualifier", "01-02": "Quantity", "01-03": "MeasurementUnitCode"},
+		"PRI": {"01-01": "PriceCodeQualifier", "01-02": "PriceAmount"},
+		"MOA": {"01-01": "MonetaryAmountTypeCodeQualifier", "01-02": "MonetaryAmount"},
+		"UNS": {"01": "SectionIdentification"},
+		"CNT": {"01-01": "ControlTotalTypeCodeQualifier", "01-02": "ControlTotalQuantity"},
+		"UNT": {"01": "NumberOfSegmentsInMessage", "02": "MessageReferenceNumber"},
+		"UNZ": {"01": "InterchangeControlCount", "02": "InterchangeControlReference"},
+	},
+}
@@ -0,0 +1,98 @@
+package edi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func TestDictionary(t *testing.T) {
+	decl, err := ParseFileDecl([]byte(`{
+		"segment_delimiter": "~",
+		"element_delimiter": "*",
+		"component_delimiter": ":",
+		"trim": "both",
+		"dictionary": {
+			"standard": "x12",
+			"segments": { "BEG": { "05": "OrderDate" }, "ZZZ": { "01": "Custom" } }
+		},
+		"segment_declarations": [
+			{ "name": "BEG", "is_target": true, "max": -1, "elements": [ { "name": "po", "index": 3 } ] },
+			{ "name": "REF", "max": -1 }
+		]
+	}`))
+	assert.NoError(t, err)
+	reader, err := NewReader("test-input", strings.NewReader("BEG*00*SA* PO-1 **20210101~REF*BM*X:Y:Z~"), decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"BEG01_TransactionSetPurposeCode":"00","BEG02_PurchaseOrderTypeCode":"SA",`+
+			`"BEG03_PurchaseOrderNumber":"PO-1","BEG04_ReleaseNumber":"","BEG05_OrderDate":"20210101","po":"PO-1"}`,
+		idr.JSONify2(n))
+	po, err := idr.MatchSingle(n, "BEG03_PurchaseOrderNumber")
+	assert.NoError(t, err)
+	assert.Equal(t, "PO-1", po.InnerText())
+}
+
+func TestDictionary_Components(t *testing.T) {
+	decl, err := ParseFileDecl([]byte(`{
+		"segment_delimiter": "~",
+		"element_delimiter": "*",
+		"component_delimiter": ":",
+		"dictionary": { "standard": "x12" },
+		"segment_declarations": [ { "name": "REF", "is_target": true, "max": -1 } ]
+	}`))
+	assert.NoError(t, err)
+	reader, err := NewReader("test-input", strings.NewReader("REF*BM*123**XY:456~"), decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"REF01_ReferenceIdentificationQualifier":"BM","REF02_ReferenceIdentification":"123",`+
+			`"REF03_Description":"","REF04-01_ReferenceIdentificationQualifier":"XY",`+
+			`"REF04-02_ReferenceIdentification":"456"}`,
+		idr.JSONify2(n))
+	id, err := idr.MatchSingle(n, "REF04-02_ReferenceIdentification")
+	assert.NoError(t, err)
+	assert.Equal(t, "456", id.InnerText())
+}
+
+func TestDictionary_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		dictionary string
+		err        string
+	}{
+		{
+			name:       "invalid standard",
+			dictionary: `{ "standard": "hl7" }`,
+			err:        "schema 'file_declaration' validation failed",
+		},
+		{
+			name:       "invalid position",
+			dictionary: `{ "segments": { "REF": { "4.2": "X" } } }`,
+			err:        "dictionary segment 'REF' has invalid element position '4.2', expected e.g. '03' or '04-02'",
+		},
+		{
+			name:       "invalid name",
+			dictionary: `{ "segments": { "REF": { "02": "Reference Id" } } }`,
+			err: "dictionary segment 'REF' element '02' has invalid name 'Reference Id', " +
+				"expected letters, digits and '_' only",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseFileDecl([]byte(`{
+				"segment_delimiter": "~",
+				"element_delimiter": "*",
+				"dictionary": ` + test.dictionary + `,
+				"segment_declarations": [ { "name": "REF", "is_target": true } ]
+			}`))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
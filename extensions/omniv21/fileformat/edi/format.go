@@ -13,6 +13,8 @@ import (
 	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/transformctx"
 	"github.com/logward/omniparser/validation"
 )
 
@@ -30,8 +32,9 @@ func NewEDIFileFormat(schemaName string) fileformat.FileFormat {
 }
 
 type ediFormatRuntime struct {
-	Decl  *FileDecl `json:"file_declaration"`
-	XPath string
+	Decl           *FileDecl             `json:"file_declaration"`
+	ParserSettings header.ParserSettings `json:"parser_settings"`
+	XPath          string
 }
 
 func (f *ediFileFormat) ValidateSchema(
@@ -54,6 +57,23 @@ func (f *ediFileFormat) ValidateSchema(
 	if finalOutputDecl == nil {
 		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
 	}
+	err = transform.RewriteXPaths(finalOutputDecl, func(xpath string) (string, error) {
+		compiled, err := CompileXPath(xpath)
+		if compiled != xpath {
+			// the positional element nodes the compiled xpaths query are needed.
+			runtime.Decl.PositionalElements = true
+		}
+		return compiled, err
+	})
+	if err != nil {
+		return nil, f.FmtErr("%s", err.Error())
+	}
+	// analyzed after the rewrite, for the positional element nodes queried to be referenced.
+	if runtime.Decl.SkipUnreferencedElements {
+		if refs := transform.AnalyzeReferences(finalOutputDecl); refs != nil {
+			runtime.Decl.markSkippedElements(refs)
+		}
+	}
 	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
 	if runtime.XPath != "" {
 		_, err := caches.GetXPathExpr(runtime.XPath)
@@ -66,17 +86,79 @@ func (f *ediFileFormat) ValidateSchema(
 }
 
 func (f *ediFileFormat) validateFileDecl(decl *FileDecl) error {
-	err := (&ediValidateCtx{}).validateFileDecl(decl)
+	err := decl.Validate()
 	if err != nil {
 		return f.FmtErr(err.Error())
 	}
 	return err
 }
 
+// ParseFileDecl parses and validates a EDI `file_declaration`, in JSON, for creating Readers with
+// NewReader without an omni schema.
+func ParseFileDecl(declJSON []byte) (*FileDecl, error) {
+	content := []byte(`{"file_declaration":` + string(declJSON) + `}`)
+	err := validation.SchemaValidate("file_declaration", content, v21validation.JSONSchemaEDIFileDeclaration)
+	if err != nil {
+		return nil, err
+	}
+	var runtime ediFormatRuntime
+	_ = json.Unmarshal(content, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if err = runtime.Decl.Validate(); err != nil {
+		return nil, err
+	}
+	return runtime.Decl, nil
+}
+
+// Validate validates an EDI `file_declaration` built in code, resolving its `dictionary`, if any.
+func (d *FileDecl) Validate() error {
+	return (&ediValidateCtx{}).validateFileDecl(d)
+}
+
 func (f *ediFileFormat) CreateFormatReader(
 	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
 	edi := runtime.(*ediFormatRuntime)
-	return NewReader(name, r, edi.Decl, edi.XPath)
+	reader, err := NewReader(name, r, edi.Decl, edi.XPath)
+	if err != nil {
+		return nil, err
+	}
+	if strs.StrPtrOrElse(edi.ParserSettings.InvalidUTF8, "") == header.InvalidUTF8Bytes {
+		reader.r.WithBytePositions()
+	}
+	return reader, nil
+}
+
+// ApplyProfile implements fileformat.ProfileApplier: a profile can override the delimiters and the
+// release character, and its strictness turns the `strict_isa`, `empty_segments` and
+// `inter_segment_whitespace` settings all on (error) or all off (skip).
+func (f *ediFileFormat) ApplyProfile(
+	runtime interface{}, profile *transformctx.Profile) (interface{}, error) {
+	edi := *runtime.(*ediFormatRuntime)
+	decl := *edi.Decl
+	if d := profile.Delimiters; d != nil {
+		decl.SegDelim = strs.StrPtrOrElse(d.Segment, decl.SegDelim)
+		decl.ElemDelim = strs.StrPtrOrElse(d.Element, decl.ElemDelim)
+		if d.Component != nil {
+			decl.CompDelim = d.Component
+		}
+		if d.Repetition != nil {
+			decl.RepDelim = d.Repetition
+		}
+		if d.Release != nil {
+			decl.ReleaseChar = d.Release
+		}
+	}
+	if profile.Strict != nil {
+		tolerance := ToleranceSkip
+		if *profile.Strict {
+			tolerance = ToleranceError
+		}
+		decl.StrictISA = *profile.Strict
+		decl.EmptySegments = strs.StrPtr(tolerance)
+		decl.InterSegmentWhitespace = strs.StrPtr(tolerance)
+		decl.TruncatedLastSegment = strs.StrPtr(tolerance)
+	}
+	edi.Decl = &decl
+	return &edi, nil
 }
 
 func (f *ediFileFormat) FmtErr(format string, args ...interface{}) error {
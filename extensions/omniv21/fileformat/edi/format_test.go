@@ -10,8 +10,10 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
 )
 
 func TestValidateSchema(t *testing.T) {
@@ -187,3 +189,177 @@ func TestCreateFormatReader(t *testing.T) {
 	assert.Equal(t, io.EOF, err)
 	assert.Nil(t, n)
 }
+
+func TestCreateFormatReader_SkipUnreferencedElements(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations([]byte(`
+		{
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": {
+					"e1": { "xpath": "ISA/e1" },
+					"ref2": { "xpath": "REF#02" },
+					"n1": { "xpath": "N1" }
+				}}
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	format := NewEDIFileFormat("test")
+	rt, err := format.ValidateSchema(fileFormatEDI, []byte(`{
+		"file_declaration": {
+			"segment_delimiter": "~",
+			"element_delimiter": "*",
+			"skip_unreferenced_elements": true,
+			"segment_declarations": [
+				{
+					"name": "GS",
+					"is_target": true,
+					"child_segments": [
+						{
+							"name": "ISA",
+							"elements": [
+								{ "name": "e1", "index": 1 },
+								{ "name": "e2", "index": 2 },
+								{ "name": "e3", "index": 3, "default": "c" }
+							]
+						},
+						{ "name": "REF", "elements": [ { "name": "qualifier", "index": 1 } ] },
+						{ "name": "N1", "elements": [ { "name": "name", "index": 2 } ] }
+					]
+				}
+			]
+		}
+	}`), finalOutputDecl)
+	assert.NoError(t, err)
+	reader, err := format.CreateFormatReader(
+		"test", strings.NewReader("GS~ISA*a*b~REF*PO*123~N1*ST*acme~"), rt)
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	// e2, e3 (missing, thus defaulted), qualifier and the positional nodes of ISA and REF other than
+	// REF02 are skipped, while all the element nodes of N1 are kept, as its entire value is read.
+	assert.Equal(t,
+		`{"ISA":{"e1":"a"},`+
+			`"N1":{"N100":"N1","N100-01":"N1","N101":"ST","N101-01":"ST","N102":"acme","N102-01":"acme","name":"acme"},`+
+			`"REF":{"REF02":"123"}}`,
+		idr.JSONify2(n))
+	assert.Equal(t, "GS~ISA*a*b~REF*PO*123~N1*ST*acme~", string(reader.(*Reader).RecordBytes()))
+	reader.Release(n)
+}
+
+func TestCreateFormatReader_InvalidUTF8Bytes(t *testing.T) {
+	format := NewEDIFileFormat("test")
+	fileDecl := `{
+		"parser_settings": { "invalid_utf8": "bytes" },
+		"file_declaration": {
+			"segment_delimiter": "~",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{ "name": "ISA", "is_target": true, "min": 1, "elements": [ { "name": "e1", "index": 1 } ] }
+			]
+		}
+	}`
+	rt, err := format.ValidateSchema(fileFormatEDI, []byte(fileDecl), &transform.Decl{XPath: strs.StrPtr(".")})
+	assert.NoError(t, err)
+	reader, err := format.CreateFormatReader("test", strings.NewReader("ISA*\xff\xfe~GS*é~"), rt)
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	_, err = reader.Read()
+	assert.Error(t, err)
+	assert.Equal(t, "input 'test' at segment no.2 (byte[14,14], line 1, col 14, byte offset 13): segment 'GS' is either not declared in schema or appears in an invalid order", err.Error())
+}
+
+func TestApplyProfile(t *testing.T) {
+	format := NewEDIFileFormat("test")
+	fileDecl := `{
+		"file_declaration": {
+			"segment_delimiter": "~",
+			"element_delimiter": "*",
+			"component_delimiter": ":",
+			"segment_declarations": [ { "name": "ISA", "is_target": true, "elements": [ { "name": "e1", "index": 1 } ] } ]
+		}
+	}`
+	rt, err := format.ValidateSchema(fileFormatEDI, []byte(fileDecl), &transform.Decl{XPath: strs.StrPtr(".")})
+	assert.NoError(t, err)
+	applier := format.(fileformat.ProfileApplier)
+	lenient, strict := false, true
+
+	// no overrides.
+	applied, err := applier.ApplyProfile(rt, &transformctx.Profile{})
+	assert.NoError(t, err)
+	assert.Equal(t, rt, applied)
+
+	applied, err = applier.ApplyProfile(rt, &transformctx.Profile{
+		Delimiters: &transformctx.Delimiters{
+			Segment: strs.StrPtr("\n"), Element: strs.StrPtr("|"), Release: strs.StrPtr("?"),
+		},
+		Strict: &lenient,
+	})
+	assert.NoError(t, err)
+	decl := applied.(*ediFormatRuntime).Decl
+	assert.Equal(t, "\n", decl.SegDelim)
+	assert.Equal(t, "|", decl.ElemDelim)
+	assert.Equal(t, ":", *decl.CompDelim)
+	assert.Nil(t, decl.RepDelim)
+	assert.Equal(t, "?", *decl.ReleaseChar)
+	assert.False(t, decl.StrictISA)
+	assert.Equal(t, ToleranceSkip, *decl.EmptySegments)
+	assert.Equal(t, ToleranceSkip, *decl.InterSegmentWhitespace)
+	assert.Equal(t, ToleranceSkip, *decl.TruncatedLastSegment)
+	// the schema's runtime is intact.
+	orig := rt.(*ediFormatRuntime).Decl
+	assert.Equal(t, "~", orig.SegDelim)
+	assert.Nil(t, orig.ReleaseChar)
+	assert.Nil(t, orig.EmptySegments)
+
+	reader, err := format.CreateFormatReader("test", strings.NewReader("ISA|e1\n\nISA|e?|2\n"), applied)
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"e1":"e1"}`, idr.JSONify2(n))
+	reader.Release(n)
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"e1":"e|2"}`, idr.JSONify2(n))
+
+	applied, err = applier.ApplyProfile(rt, &transformctx.Profile{Strict: &strict})
+	assert.NoError(t, err)
+	decl = applied.(*ediFormatRuntime).Decl
+	assert.True(t, decl.StrictISA)
+	assert.Equal(t, ToleranceError, *decl.EmptySegments)
+	assert.Equal(t, ToleranceError, *decl.InterSegmentWhitespace)
+	assert.Equal(t, ToleranceError, *decl.TruncatedLastSegment)
+}
+
+func TestParseFileDecl(t *testing.T) {
+	decl, err := ParseFileDecl([]byte(`{
+		"segment_delimiter": "~",
+		"element_delimiter": "*",
+		"segment_declarations": [
+			{ "name": "ISA", "is_target": true, "max": -1, "elements": [ { "name": "e1", "index": 1 } ] }
+		]
+	}`))
+	assert.NoError(t, err)
+	reader, err := NewReader("test-input", strings.NewReader("ISA*1~ISA*2~"), decl, "")
+	assert.NoError(t, err)
+	for _, expected := range []string{`{"e1":"1"}`, `{"e1":"2"}`} {
+		n, err := reader.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, idr.JSONify2(n))
+		reader.Release(n)
+	}
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = ParseFileDecl([]byte(`{ "segment_delimiter": "~" }`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema 'file_declaration' validation failed")
+
+	_, err = ParseFileDecl([]byte(`{
+		"segment_delimiter": "~",
+		"element_delimiter": "*",
+		"segment_declarations": [ { "name": "ISA", "is_target": true }, { "name": "IEA", "is_target": true } ]
+	}`))
+	assert.Error(t, err)
+	assert.Equal(t, "a second segment/group ('IEA') with 'is_target' = true is not allowed", err.Error())
+}
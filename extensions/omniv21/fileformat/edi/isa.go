@@ -0,0 +1,69 @@
+package edi
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// isaElemWidths are the fixed widths of ISA01 through ISA16 of an X12 interchange control header.
+var isaElemWidths = [...]int{2, 10, 2, 10, 2, 15, 2, 15, 6, 4, 1, 5, 9, 1, 1, 1}
+
+// isaLen is the fixed length of an ISA segment: the segment name, 16 element delimiters, 16 elements,
+// and the segment terminator.
+const isaLen = 106
+
+// validateISA validates the first segment of an input is an ISA segment of the exact fixed widths
+// required by X12, and the delimiters derived from its fixed positions - element delimiter at the 4th
+// character, component element separator (ISA16) at the 105th and segment terminator at the 106th -
+// agree with the ones declared in the schema. The token includes the segment delimiter.
+func (r *NonValidatingReader) validateISA(token []byte) error {
+	if len(r.elemDelim.b) != 1 {
+		return ErrInvalidEDI("strict_isa requires a single-character element_delimiter")
+	}
+	seg := r.segData(token)
+	if !bytes.HasPrefix(seg, []byte("ISA")) {
+		return ErrInvalidEDI(fmt.Sprintf("first segment must be 'ISA', but got '%s'", isaQuote(token, 3)))
+	}
+	if len(seg) < 4 || seg[3] != r.elemDelim.b[0] {
+		return ErrInvalidEDI(fmt.Sprintf(
+			"ISA character 4 must be element_delimiter '%s', but got '%s'", r.elemDelim.b, isaQuote(seg[3:], 1)))
+	}
+	pos := 4
+	for i, width := range isaElemWidths[:len(isaElemWidths)-1] {
+		actual := bytes.IndexByte(seg[pos:], r.elemDelim.b[0])
+		if actual < 0 {
+			return ErrInvalidEDI(fmt.Sprintf("ISA must have %d elements, but got only %d", len(isaElemWidths), i+1))
+		}
+		if actual != width {
+			return ErrInvalidEDI(fmt.Sprintf(
+				"ISA%02d at character %d must be %d characters wide, but got %d: '%s'",
+				i+1, pos+1, width, actual, seg[pos:pos+actual]))
+		}
+		pos += width + 1
+	}
+	// ISA16 is the single character right before the segment terminator: anything else in between, be
+	// it an element delimiter or not, means the segment terminator isn't where it must be.
+	if pos == len(seg) {
+		return ErrInvalidEDI(fmt.Sprintf("ISA16 at character %d must be 1 characters wide, but got 0", pos+1))
+	}
+	if pos+1 < len(seg) {
+		return ErrInvalidEDI(fmt.Sprintf("ISA character %d must be segment_delimiter '%s', but got '%s'",
+			isaLen, r.segDelim.b, isaQuote(seg[pos+1:], 1)))
+	}
+	if isa16 := seg[isaLen-2]; len(r.compDelim.b) > 0 && !bytes.Equal(r.compDelim.b, []byte{isa16}) {
+		return ErrInvalidEDI(fmt.Sprintf(
+			"ISA16 at character %d must be component_delimiter '%s', but got '%c'", isaLen-1, r.compDelim.b, isa16))
+	}
+	return nil
+}
+
+// isaQuote returns up to the first n runes of b for error messages.
+func isaQuote(b []byte, n int) string {
+	end := 0
+	for ; n > 0 && end < len(b); n-- {
+		_, size := utf8.DecodeRune(b[end:])
+		end += size
+	}
+	return string(b[:end])
+}
@@ -0,0 +1,119 @@
+package edi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+const testISA = "ISA*00*          *00*          *ZZ*SUBMITTER      *ZZ*RECEIVER       *240101*1200*^*00501*000000001*0*P*:~"
+
+func TestNonValidatingReader_StrictISA(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		input  string
+		decl   FileDecl
+		expErr string
+	}{
+		{
+			name:  "valid",
+			input: testISA + "GS*PO~",
+			decl:  FileDecl{SegDelim: "~", ElemDelim: "*", CompDelim: strs.StrPtr(":"), StrictISA: true},
+		},
+		{
+			name:  "valid; CRLF after segment terminator",
+			input: strings.Replace(testISA, "~", "\r\n", 1) + "GS*PO\r\n",
+			decl:  FileDecl{SegDelim: "\n", ElemDelim: "*", StrictISA: true},
+		},
+		{
+			name:  "not strict",
+			input: "ISA*00*0~",
+			decl:  FileDecl{SegDelim: "~", ElemDelim: "*"},
+		},
+		{
+			name:   "multi-char element delimiter",
+			input:  testISA,
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "**", StrictISA: true},
+			expErr: "strict_isa requires a single-character element_delimiter",
+		},
+		{
+			name:   "first segment not ISA",
+			input:  "GS*PO~",
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "first segment must be 'ISA', but got 'GS*'",
+		},
+		{
+			name:   "element delimiter mismatch",
+			input:  strings.ReplaceAll(testISA, "*", "|"),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA character 4 must be element_delimiter '*', but got '|'",
+		},
+		{
+			name:   "element too narrow",
+			input:  strings.Replace(testISA, "SUBMITTER      ", "SUBMITTER     ", 1),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA06 at character 36 must be 15 characters wide, but got 14: 'SUBMITTER     '",
+		},
+		{
+			name:   "element too wide",
+			input:  strings.Replace(testISA, "*1200*", "*12000*", 1),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA10 at character 78 must be 4 characters wide, but got 5: '12000'",
+		},
+		{
+			name:   "too few elements",
+			input:  "ISA*00*          ~",
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA must have 16 elements, but got only 2",
+		},
+		{
+			name:   "too many elements",
+			input:  strings.Replace(testISA, ":~", ":*X~", 1),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA character 106 must be segment_delimiter '~', but got '*'",
+		},
+		{
+			name:   "segment terminator inside element",
+			input:  strings.Replace(testISA, "RECEIVER       ", "RECEIVER~      ", 1),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA must have 16 elements, but got only 8",
+		},
+		{
+			name:   "wrong segment terminator",
+			input:  strings.Replace(testISA, ":~", ":\n", 1) + "GS*PO~",
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA character 106 must be segment_delimiter '~', but got '\n'",
+		},
+		{
+			name:   "empty ISA16",
+			input:  strings.Replace(testISA, ":~", "~", 1),
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", StrictISA: true},
+			expErr: "ISA16 at character 105 must be 1 characters wide, but got 0",
+		},
+		{
+			name:   "ISA16 component delimiter mismatch",
+			input:  testISA,
+			decl:   FileDecl{SegDelim: "~", ElemDelim: "*", CompDelim: strs.StrPtr(">"), StrictISA: true},
+			expErr: "ISA16 at character 105 must be component_delimiter '>', but got ':'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNonValidatingReader(strings.NewReader(test.input), &test.decl)
+			_, err := r.Read()
+			if test.expErr == "" {
+				assert.NoError(t, err)
+				// only the first segment is subject to strict_isa.
+				_, err = r.Read()
+				if err != nil {
+					assert.Equal(t, "EOF", err.Error())
+				}
+				return
+			}
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidEDI(err))
+			assert.Equal(t, test.expErr, err.Error())
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// positionalMark starts the positional element addressing shorthand in XPaths. It's not valid in
+// XPath, thus never ambiguous.
+const positionalMark = '#'
+
+// positionalNodeName returns the name of the node of an element, or of a component if compIndex > 0,
+// of a segment, as added by the reader when positional elements are on, e.g. "REF05", or "REF05-02".
+func positionalNodeName(segName string, elemIndex, compIndex int) string {
+	if compIndex > 0 {
+		return fmt.Sprintf("%s%02d-%02d", segName, elemIndex, compIndex)
+	}
+	return fmt.Sprintf("%s%02d", segName, elemIndex)
+}
+
+// CompileXPath compiles the positional element addressing shorthand in an XPath into the XPath querying
+// the positional element nodes of the segments (see FileDecl.PositionalElements). In the shorthand, an
+// element of a segment is addressed by its 1-based position, and optionally the 1-based position of one
+// of its components, after a '#', e.g.:
+//   - "REF#02" is element 2 of segment REF, i.e. "REF/REF02";
+//   - "REF#05.2" is component 2 of element 5 of REF, i.e. "REF/REF05-02";
+//   - "REF[#01='BM']#05.2" is component 2 of element 5 of the REF whose element 1 is 'BM', i.e.
+//     "REF[REF01='BM']/REF05-02": in a predicate of a segment, '#' without a segment name addresses
+//     the elements of that segment.
+//
+// Element 5 means its first component if it's a composite element. The XPaths without any shorthand are
+// returned as is, and so are the '#'s in string literals.
+func CompileXPath(xpath string) (string, error) {
+	if !strings.ContainsRune(xpath, positionalMark) {
+		return xpath, nil
+	}
+	var sb strings.Builder
+	// predicateSegs are the names of the segments whose predicates, i.e. '[...]', are open.
+	var predicateSegs []string
+	// lastName is the name step right before the current position, if any, and lastPredicateSeg the
+	// segment of the predicate right before it, if any, e.g. "REF" after "REF[...]".
+	lastName, lastPredicateSeg := "", ""
+	var quote byte
+	for i := 0; i < len(xpath); {
+		c := xpath[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			sb.WriteByte(c)
+			i++
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			predicateSegs = append(predicateSegs, lastName)
+		case c == ']':
+			if len(predicateSegs) == 0 {
+				return "", fmt.Errorf("unbalanced ']' in xpath '%s'", xpath)
+			}
+			lastPredicateSeg = predicateSegs[len(predicateSegs)-1]
+			predicateSegs = predicateSegs[:len(predicateSegs)-1]
+			sb.WriteByte(c)
+			lastName = ""
+			i++
+			continue
+		case c == positionalMark:
+			elemIndex, compIndex, n, err := parsePosition(xpath[i+1:])
+			if err != nil {
+				return "", fmt.Errorf("invalid positional element '%s' in xpath '%s': %s",
+					xpath[i:i+1+n], xpath, err.Error())
+			}
+			switch {
+			case isSegName(lastName):
+				sb.WriteString("/" + positionalNodeName(lastName, elemIndex, compIndex))
+			case isSegName(lastPredicateSeg) && i > 0 && xpath[i-1] == ']':
+				sb.WriteString("/" + positionalNodeName(lastPredicateSeg, elemIndex, compIndex))
+			case lastName == "" && len(predicateSegs) > 0 && isSegName(predicateSegs[len(predicateSegs)-1]):
+				sb.WriteString(positionalNodeName(predicateSegs[len(predicateSegs)-1], elemIndex, compIndex))
+			default:
+				return "", fmt.Errorf(
+					"positional element '%s' in xpath '%s' has no segment", xpath[i:i+1+n], xpath)
+			}
+			i += 1 + n
+			lastName, lastPredicateSeg = "", ""
+			continue
+		}
+		if isNameByte(c) {
+			j := i
+			for j < len(xpath) && isNameByte(xpath[j]) {
+				j++
+			}
+			lastName = xpath[i:j]
+			sb.WriteString(lastName)
+			i = j
+			continue
+		}
+		sb.WriteByte(c)
+		lastName, lastPredicateSeg = "", ""
+		i++
+	}
+	return sb.String(), nil
+}
+
+// parsePosition parses the "NN" or "NN.MM" after a positionalMark, returning the positions and the
+// number of bytes parsed.
+func parsePosition(s string) (elemIndex, compIndex, n int, err error) {
+	digits := func(from int) int {
+		j := from
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		return j
+	}
+	n = digits(0)
+	if n == 0 {
+		return 0, 0, 0, fmt.Errorf("expected element position, e.g. '#05' or '#05.2'")
+	}
+	elemIndex, _ = strconv.Atoi(s[:n])
+	if n < len(s) && s[n] == '.' {
+		end := digits(n + 1)
+		if end == n+1 {
+			return 0, 0, end, fmt.Errorf("expected component position after '.'")
+		}
+		compIndex, _ = strconv.Atoi(s[n+1 : end])
+		n = end
+		if compIndex < 1 || compIndex > 99 {
+			return 0, 0, n, fmt.Errorf("component position must be between 1 and 99")
+		}
+	}
+	if elemIndex < 1 || elemIndex > 99 {
+		return 0, 0, n, fmt.Errorf("element position must be between 1 and 99")
+	}
+	return elemIndex, compIndex, n, nil
+}
+
+func isNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' ||
+		c == '.' || c >= 0x80
+}
+
+func isSegName(name string) bool {
+	return name != "" && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z' || name[0] == '_')
+}
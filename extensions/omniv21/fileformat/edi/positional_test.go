@@ -0,0 +1,98 @@
+package edi
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	"github.com/logward/omniparser/idr"
+)
+
+func TestCompileXPath(t *testing.T) {
+	for _, test := range []struct {
+		xpath    string
+		expected string
+		err      string
+	}{
+		{xpath: "REF/e1", expected: "REF/e1"},
+		{xpath: "REF#02", expected: "REF/REF02"},
+		{xpath: "REF#5.2", expected: "REF/REF05-02"},
+		{xpath: "REF[#01='BM']#05.2", expected: "REF[REF01='BM']/REF05-02"},
+		{xpath: "../N1_LOOP/N1[#01 = 'ST' and #03='92']#04", expected: "../N1_LOOP/N1[N101 = 'ST' and N103='92']/N104"},
+		{xpath: "REF[2]#02", expected: "REF[2]/REF02"},
+		{xpath: "concat(REF#01, '#02')", expected: "concat(REF/REF01, '#02')"},
+		{xpath: "REF[N1#01='x']", expected: "REF[N1/N101='x']"},
+		{xpath: "#01", err: "positional element '#01' in xpath '#01' has no segment"},
+		{xpath: "REF#", err: "invalid positional element '#' in xpath 'REF#': expected element position, e.g. '#05' or '#05.2'"},
+		{xpath: "REF#1.", err: "invalid positional element '#1.' in xpath 'REF#1.': expected component position after '.'"},
+		{xpath: "REF#100", err: "invalid positional element '#100' in xpath 'REF#100': element position must be between 1 and 99"},
+		{xpath: "REF]#01", err: "unbalanced ']' in xpath 'REF]#01'"},
+	} {
+		t.Run(test.xpath, func(t *testing.T) {
+			compiled, err := CompileXPath(test.xpath)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, compiled)
+		})
+	}
+}
+
+func TestPositionalElements(t *testing.T) {
+	schema := []byte(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "edi" },
+		"file_declaration": {
+			"segment_delimiter": "~",
+			"element_delimiter": "*",
+			"component_delimiter": ":",
+			"segment_declarations": [
+				{ "name": "ORD", "type": "segment_group", "is_target": true, "max": -1, "child_segments": [
+					{ "name": "BEG" },
+					{ "name": "REF", "min": 0, "max": -1 }
+				]}
+			]
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": ".[BEG#01='00']", "object": {
+				"po": { "xpath": "BEG#03" },
+				"bm": { "xpath": "REF[#01='BM']#04.2" },
+				"refs": { "array": [ { "xpath": "REF#02" } ] }
+			}}
+		}
+	}`)
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(schema, nil, nil)
+	assert.NoError(t, err)
+	runtime, err := NewEDIFileFormat("test").ValidateSchema(fileFormatEDI, schema, finalOutputDecl)
+	assert.NoError(t, err)
+	assert.True(t, runtime.(*ediFormatRuntime).Decl.PositionalElements)
+	assert.Equal(t, ".[BEG/BEG01='00']", runtime.(*ediFormatRuntime).XPath)
+	assert.Equal(t, "REF[REF01='BM']/REF04-02", *finalOutputDecl.Object["bm"].XPath)
+
+	reader, err := NewEDIFileFormat("test").CreateFormatReader("test-input",
+		strings.NewReader("BEG*00*SA*PO-1~REF*IA*123~REF*BM*456**X:Y~BEG*01*SA*PO-2~"), runtime)
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	for xpath, expected := range map[string]string{"po": "PO-1", "bm": "Y"} {
+		m, err := idr.MatchSingle(n, *finalOutputDecl.Object[xpath].XPath)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, m.InnerText())
+	}
+	refs, err := idr.MatchAll(n, *finalOutputDecl.Object["refs"].Array[0].XPath)
+	assert.NoError(t, err)
+	assert.Len(t, refs, 2)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err) // the second ORD isn't a target.
+
+	schema = []byte(strings.Replace(string(schema), `"REF#02"`, `"#02"`, 1))
+	finalOutputDecl, err = transform.ValidateTransformDeclarations(schema, nil, nil)
+	assert.NoError(t, err)
+	_, err = NewEDIFileFormat("test").ValidateSchema(fileFormatEDI, schema, finalOutputDecl)
+	assert.EqualError(t, err,
+		"schema 'test': 'FINAL_OUTPUT.refs.elem[1]': positional element '#02' in xpath '#02' has no segment")
+}
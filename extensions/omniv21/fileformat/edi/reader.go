@@ -7,9 +7,11 @@ import (
 
 	"github.com/antchfx/xpath"
 	"github.com/jf-tech/go-corelib/caches"
-	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/strs"
 
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -28,7 +30,11 @@ func newStack() []stackEntry {
 	return make([]stackEntry, 0, defaultStackDepth)
 }
 
-type ediReader struct {
+// Reader is the full EDI FormatReader: it reads the segments of an EDI input, validates them against
+// the segment declarations of a FileDecl, and returns the target ones as IDR nodes. It can be used on
+// its own, without an omni schema, see NewReader. For reading raw segments without any validation, see
+// NonValidatingReader.
+type Reader struct {
 	inputName         string
 	releaseChar       strPtrByte
 	r                 *NonValidatingReader
@@ -36,6 +42,18 @@ type ediReader struct {
 	target            *idr.Node
 	targetXPath       *xpath.Expr
 	unprocessedRawSeg RawSeg
+	dict              *dictionary
+	positional        bool
+	refs              *transform.References // if non-nil, unreferenced elements are skipped.
+	fileTrim          fileformat.TrimPolicy
+	segBegin, segEnd  int                 // segment range consumed by the last Read call.
+	posBegin, posEnd  fileformat.Position // source position of the segments consumed by the last Read call.
+	recBytes          []byte              // raw bytes of the segments consumed by the last Read call.
+	warnTruncated     bool
+	warnings          []error // warnings raised during the last Read call.
+	// dropDepth is the depth, i.e. the stack length, of the instance of a segment, or a segment group,
+	// exceeding its max being dropped, if any, or 0.
+	dropDepth int
 }
 
 func inRange(i, lowerBoundInclusive, upperBoundInclusive int) bool {
@@ -47,7 +65,7 @@ func inRange(i, lowerBoundInclusive, upperBoundInclusive int) bool {
 // the stack) is assumed. Note caller NEVER owns the memory of the returned
 // entry, thus caller can use the pointer and its data values inside locally
 // but should never cache/save it somewhere for later usage.
-func (r *ediReader) stackTop(frame ...int) *stackEntry {
+func (r *Reader) stackTop(frame ...int) *stackEntry {
 	nth := 0
 	if len(frame) == 1 {
 		nth = frame[0]
@@ -62,7 +80,7 @@ func (r *ediReader) stackTop(frame ...int) *stackEntry {
 // FRAME to caller. Note caller NEVER owns the memory of the returned entry, thus caller can
 // use the pointer and its data values inside locally but should never cache/save it somewhere
 // for later usage.
-func (r *ediReader) shrinkStack() *stackEntry {
+func (r *Reader) shrinkStack() *stackEntry {
 	if len(r.stack) < 1 {
 		panic("stack length is empty")
 	}
@@ -74,15 +92,15 @@ func (r *ediReader) shrinkStack() *stackEntry {
 }
 
 // growStack adds a new stack entry to the top of the stack.
-func (r *ediReader) growStack(e stackEntry) {
+func (r *Reader) growStack(e stackEntry) {
 	r.stack = append(r.stack, e)
 }
 
-func (r *ediReader) resetRawSeg() {
+func (r *Reader) resetRawSeg() {
 	resetRawSeg(&r.unprocessedRawSeg)
 }
 
-func (r *ediReader) getUnprocessedRawSeg() (RawSeg, error) {
+func (r *Reader) getUnprocessedRawSeg() (RawSeg, error) {
 	if r.unprocessedRawSeg.valid {
 		return r.unprocessedRawSeg, nil
 	}
@@ -91,14 +109,19 @@ func (r *ediReader) getUnprocessedRawSeg() (RawSeg, error) {
 	case err == io.EOF:
 		return RawSeg{}, io.EOF
 	case err != nil:
-		return RawSeg{}, ErrInvalidEDI(r.fmtErrStr(err.Error()))
+		return RawSeg{}, r.invalidEDI(err.Error())
+	}
+	if r.warnTruncated && r.r.Truncated() {
+		r.warnings = append(r.warnings, r.invalidEDI(
+			"last segment '%s' is not terminated by segment_delimiter, input might be truncated",
+			isaQuote(rawSeg.Raw, 20)))
 	}
 	r.unprocessedRawSeg = rawSeg
 	r.unprocessedRawSeg.valid = true
 	return r.unprocessedRawSeg, nil
 }
 
-func (r *ediReader) rawSegToNode(segDecl *SegDecl) (*idr.Node, error) {
+func (r *Reader) rawSegToNode(segDecl *SegDecl) (*idr.Node, error) {
 	if !r.unprocessedRawSeg.valid {
 		panic("unprocessedRawSeg is not valid")
 	}
@@ -111,17 +134,27 @@ func (r *ediReader) rawSegToNode(segDecl *SegDecl) (*idr.Node, error) {
 		}
 		for _, rawElem := range r.unprocessedRawSeg.Elems {
 			if rawElem.ElemIndex == elemDecl.Index && rawElem.CompIndex == elemDecl.compIndex() {
+				found = true
+				if elemDecl.skip {
+					// only its presence is checked, so a skipped missing element fails the same way.
+					continue
+				}
 				elemN := idr.CreateNode(idr.ElementNode, elemDecl.Name)
 				idr.AddChild(n, elemN)
-				data := string(strs.ByteUnescape(rawElem.Data, r.releaseChar.b, true))
+				data := elemDecl.trim.Apply(string(strs.ByteUnescape(rawElem.Data, r.releaseChar.b, true)))
 				elemV := idr.CreateNode(idr.TextNode, data)
 				idr.AddChild(elemN, elemV)
-				found = true
 			}
 		}
 		if found {
 			continue
 		}
+		dataFromDefaultIndex, foundInMap := indexOptional[elemDecl.compIndex()]
+		if elemDecl.skip && (elemDecl.EmptyIfMissing || elemDecl.Default != nil ||
+			elemDecl.DefaultElement != nil || foundInMap) {
+			// a skipped missing element with a default isn't an error, and its node isn't needed.
+			continue
+		}
 		if elemDecl.EmptyIfMissing || elemDecl.Default != nil {
 			elemN := idr.CreateNode(idr.ElementNode, elemDecl.Name)
 			idr.AddChild(n, elemN)
@@ -137,7 +170,6 @@ func (r *ediReader) rawSegToNode(segDecl *SegDecl) (*idr.Node, error) {
 		// 	component E 2, C : 1 C : M (nul), E : O (not)
 		// 	component E 2, C : 2 C : O (not), E : O (not)
 
-		dataFromDefaultIndex, foundInMap := indexOptional[elemDecl.compIndex()]
 		if elemDecl.DefaultElement != nil || foundInMap {
 			elemN := idr.CreateNode(idr.ElementNode, elemDecl.Name)
 			idr.AddChild(n, elemN)
@@ -151,23 +183,59 @@ func (r *ediReader) rawSegToNode(segDecl *SegDecl) (*idr.Node, error) {
 			idr.AddChild(elemN, elemV)
 			continue
 		}
-		return nil, ErrInvalidEDI(
-			r.fmtErrStr("unable to find element '%s' on segment '%s'", elemDecl.Name, segDecl.fqdn))
+		return nil, r.invalidEDI("unable to find element '%s' on segment '%s'", elemDecl.Name, segDecl.fqdn)
+	}
+	if r.dict != nil {
+		for _, rawElem := range r.unprocessedRawSeg.Elems {
+			if name, found := r.dict.nodeName(r.unprocessedRawSeg.Name, rawElem.ElemIndex, rawElem.CompIndex); found {
+				r.addElemNode(n, segDecl, name, rawElem)
+			}
+		}
+	}
+	if r.positional {
+		for _, rawElem := range r.unprocessedRawSeg.Elems {
+			segName := r.unprocessedRawSeg.Name
+			if rawElem.CompIndex == 1 {
+				r.addElemNode(n, segDecl, positionalNodeName(segName, rawElem.ElemIndex, 0), rawElem)
+			}
+			r.addElemNode(
+				n, segDecl, positionalNodeName(segName, rawElem.ElemIndex, rawElem.CompIndex), rawElem)
+		}
 	}
 	return n, nil
 }
 
+// addElemNode adds to n a node of the data of a raw element, trimmed as per the file level `trim`, e.g.
+// for the elements named in the dictionary, unless the node is skipped as unreferenced.
+func (r *Reader) addElemNode(n *idr.Node, segDecl *SegDecl, name string, rawElem RawSegElem) {
+	if r.refs != nil && !segDecl.valueRead && !r.refs.Referenced(name) {
+		return
+	}
+	elemN := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(n, elemN)
+	data := r.fileTrim.Apply(string(strs.ByteUnescape(rawElem.Data, r.releaseChar.b, true)))
+	idr.AddChild(elemN, idr.CreateNode(idr.TextNode, data))
+}
+
 // segDone wraps up the processing of an instance of current segment (which includes the processing of
 // the instances of its child segments). segDone marks streaming target if necessary. If the number of
 // instance occurrences is over the current segment's max limit, segDone calls segNext to move to the
 // next segment in sequence; If the number of instances is still within max limit, segDone does no more
 // action so the current segment will remain on top of the stack and potentially process more instances
 // of this segment. Note: segDone is potentially recursive: segDone -> segNext -> segDone -> ...
-func (r *ediReader) segDone() {
+func (r *Reader) segDone() {
 	cur := r.stackTop()
 	cur.curChild = 0
 	cur.occurred++
-	if cur.segDecl.IsTarget {
+	if r.dropDepth == len(r.stack) {
+		idr.RemoveAndReleaseTree(cur.segNode)
+		cur.segNode = nil
+		r.dropDepth = 0
+	} else if cur.segDecl.IsTarget && r.dropDepth > 0 {
+		// the target is in an instance of a segment group being dropped.
+		idr.RemoveAndReleaseTree(cur.segNode)
+		cur.segNode = nil
+	} else if cur.segDecl.IsTarget {
 		if r.target != nil {
 			panic("r.target != nil")
 		}
@@ -181,7 +249,8 @@ func (r *ediReader) segDone() {
 			cur.segNode = nil
 		}
 	}
-	if cur.occurred < cur.segDecl.maxOccurs() {
+	if cur.occurred < cur.segDecl.maxOccurs() || cur.segDecl.exceedsMax() != ToleranceError {
+		// more instances, even exceeding max if tolerated, can be processed.
 		return
 	}
 	// we're here because `cur.occurred >= cur.segDecl.maxOccurs()`
@@ -196,16 +265,16 @@ func (r *ediReader) segDone() {
 // to the next segment. If the current segment has a subsequent sibling, that sibling will be the next segment;
 // If not, it indicates the current segment's parent segment is fully done its processing, thus parent's segDone
 // is called. Note: segNext is potentially recursive: segNext -> segDone -> segNext -> ...
-func (r *ediReader) segNext() error {
+func (r *Reader) segNext() error {
 	cur := r.stackTop()
 	if cur.occurred < cur.segDecl.minOccurs() {
 		// the current values of [begin, end] cover the current instance of the current seg. But the error
 		// we're about to create is about the missing of next instance of the current seg. So just use
 		// 'end' as 'begin' to make the error msg less confusing.
-		return ErrInvalidEDI(r.fmtErrStr2(
-			r.r.SegCount(), r.r.RuneEnd(), r.r.RuneEnd(),
+		return r.invalidEDI2(
+			r.r.SegCount(), r.r.RuneEnd(), r.r.RuneEnd(), r.r.PosEnd(),
 			"segment '%s' needs min occur %d, but only got %d",
-			strs.FirstNonBlank(cur.segDecl.fqdn, cur.segDecl.Name), cur.segDecl.minOccurs(), cur.occurred))
+			strs.FirstNonBlank(cur.segDecl.fqdn, cur.segDecl.Name), cur.segDecl.minOccurs(), cur.occurred)
 	}
 	if len(r.stack) <= 1 {
 		return nil
@@ -225,14 +294,18 @@ func (r *ediReader) segNext() error {
 // if the segment data matches what's the current segment decl we're processing: if matches, great, creates a new
 // instance of the current segment decl with the data; if not, we call segNext to move the next segment decl inline, and
 // continue the for-loop so next iteration, the same unprocessed data will be matched against the new segment decl.
-func (r *ediReader) Read() (*idr.Node, error) {
+func (r *Reader) Read() (*idr.Node, error) {
 	if r.target != nil {
 		// This is just in case Release() isn't called by ingester.
 		idr.RemoveAndReleaseTree(r.target)
 		r.target = nil
 	}
+	segBegin := r.segsConsumed() + 1
+	r.recBytes = r.recBytes[:0]
+	r.warnings = r.warnings[:0]
 	for {
 		if r.target != nil {
+			r.segBegin, r.segEnd = segBegin, r.segsConsumed()
 			return r.target, nil
 		}
 		rawSeg, err := r.getUnprocessedRawSeg()
@@ -257,12 +330,12 @@ func (r *ediReader) Read() (*idr.Node, error) {
 			return nil, err
 		}
 		cur := r.stackTop()
-		if !cur.segDecl.matchSegName(rawSeg.Name) {
+		if !r.matchSegName(rawSeg.Name) {
 			if len(r.stack) <= 1 {
-				return nil, ErrInvalidEDI(r.fmtErrStr2(
-					r.r.SegCount(), r.r.RuneEnd(), r.r.RuneEnd(),
+				return nil, r.invalidEDI2(
+					r.r.SegCount(), r.r.RuneEnd(), r.r.RuneEnd(), r.r.PosEnd(),
 					"segment '%s' is either not declared in schema or appears in an invalid order",
-					rawSeg.Name))
+					rawSeg.Name)
 			}
 			err = r.segNext()
 			if err != nil {
@@ -270,11 +343,43 @@ func (r *ediReader) Read() (*idr.Node, error) {
 			}
 			continue
 		}
+		if cur.occurred >= cur.segDecl.maxOccurs() {
+			// only possible if the segment's `exceeds_max` tolerates it.
+			action := "accepted"
+			if cur.segDecl.exceedsMax() == ExceedsMaxTruncate {
+				action = "dropped"
+				if r.dropDepth == 0 {
+					r.dropDepth = len(r.stack)
+				}
+			}
+			r.warnings = append(r.warnings, r.invalidEDI(
+				"segment '%s' occurs more than max %d times, the extra occurrence is %s",
+				strs.FirstNonBlank(cur.segDecl.fqdn, cur.segDecl.Name), cur.segDecl.maxOccurs(), action))
+		}
+		if cur.segDecl.isWildcard() {
+			// the segments matched by a wildcard are skipped: counted, but not added to the IDR.
+			if len(r.recBytes) == 0 {
+				r.posBegin = r.r.PosBegin()
+			}
+			r.posEnd = r.r.PosEnd()
+			r.recBytes = append(r.recBytes, rawSeg.Raw...)
+			r.resetRawSeg()
+			if r.dropDepth == len(r.stack) {
+				r.dropDepth = 0
+			}
+			r.segDone()
+			continue
+		}
 		if !cur.segDecl.isGroup() {
 			cur.segNode, err = r.rawSegToNode(cur.segDecl)
 			if err != nil {
 				return nil, err
 			}
+			if len(r.recBytes) == 0 {
+				r.posBegin = r.r.PosBegin()
+			}
+			r.posEnd = r.r.PosEnd()
+			r.recBytes = append(r.recBytes, rawSeg.Raw...)
 			r.resetRawSeg()
 		} else {
 			cur.segNode = idr.CreateNode(idr.ElementNode, cur.segDecl.Name)
@@ -290,33 +395,123 @@ func (r *ediReader) Read() (*idr.Node, error) {
 	}
 }
 
-func (r *ediReader) Release(n *idr.Node) {
+// matchSegName tells if a segment name matches the current segment decl. A wildcard segment decl matches
+// any segment name not matched by the segment decls that may come next instead, i.e. the subsequent
+// siblings of the wildcard and of its ancestors, and the ancestors themselves, as they may repeat.
+func (r *Reader) matchSegName(segName string) bool {
+	cur := r.stackTop()
+	if !cur.segDecl.isWildcard() {
+		return cur.segDecl.matchSegName(segName)
+	}
+	for frame := 0; frame < len(r.stack)-1; frame++ {
+		if frame > 0 && r.stackTop(frame).segDecl.matchSegName(segName) {
+			return false
+		}
+		parent := r.stackTop(frame + 1)
+		for _, sibling := range parent.segDecl.Children[parent.curChild+1:] {
+			if sibling.matchSegName(segName) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// segsConsumed returns the number of segments that have been fully processed, i.e. excluding the
+// segment read in but not yet processed, if any.
+func (r *Reader) segsConsumed() int {
+	if r.unprocessedRawSeg.valid {
+		return r.r.SegCount() - 1
+	}
+	return r.r.SegCount()
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of segment
+// numbers consumed by the last successful Read call.
+func (r *Reader) RecordPosition() (int, int) {
+	return r.segBegin, r.segEnd
+}
+
+// SourcePosition implements fileformat.SourcePositionReporter, returning the positions of the beginning
+// of the first segment and the end of the last segment consumed by the last successful Read call.
+func (r *Reader) SourcePosition() (fileformat.Position, fileformat.Position) {
+	return r.posBegin, r.posEnd
+}
+
+// RecordBytes implements fileformat.RecordBytesReporter, returning the raw bytes of the last record
+// read, if unreferenced elements are skipped; or nil otherwise.
+func (r *Reader) RecordBytes() []byte {
+	if r.refs == nil {
+		return nil
+	}
+	return r.recBytes
+}
+
+// RawBytes implements fileformat.RawBytesReporter, returning the raw bytes, including segment
+// delimiters unless raw_segment_delimiter is `drop`, of the segments consumed by the last
+// successful Read call.
+func (r *Reader) RawBytes() []byte {
+	return r.recBytes
+}
+
+// Warnings implements fileformat.WarningReporter, returning the warnings, each an errs.ErrInput wrapping
+// an ErrInvalidEDI, raised by the last Read call: the truncated last segment warning, if
+// truncated_last_segment is `warn` and the call read in the truncated last segment, and one for each
+// segment instance exceeding its max read in by the call, if the segment's `exceeds_max` tolerates it.
+func (r *Reader) Warnings() []error {
+	if len(r.warnings) == 0 {
+		return nil
+	}
+	return r.warnings
+}
+
+func (r *Reader) Release(n *idr.Node) {
 	if r.target == n {
 		r.target = nil
 	}
 	idr.RemoveAndReleaseTree(n)
 }
 
-func (r *ediReader) IsContinuableError(err error) bool {
+func (r *Reader) IsContinuableError(err error) bool {
 	return !IsErrInvalidEDI(err) && err != io.EOF
 }
 
-func (r *ediReader) FmtErr(format string, args ...interface{}) error {
+func (r *Reader) FmtErr(format string, args ...interface{}) error {
 	return errors.New(r.fmtErrStr(format, args...))
 }
 
-func (r *ediReader) fmtErrStr(format string, args ...interface{}) string {
-	return r.fmtErrStr2(r.r.SegCount(), r.r.RuneBegin(), r.r.RuneEnd(), format, args...)
+func (r *Reader) fmtErrStr(format string, args ...interface{}) string {
+	return r.fmtErrStr2(r.r.SegCount(), r.r.RuneBegin(), r.r.RuneEnd(), r.r.PosBegin(), format, args...)
 }
 
-func (r *ediReader) fmtErrStr2(segCount, runeBegin, runeEnd int, format string, args ...interface{}) string {
-	return fmt.Sprintf("input '%s' at segment no.%d (char[%d,%d]): %s",
-		r.inputName, segCount, runeBegin, runeEnd, fmt.Sprintf(format, args...))
+// invalidEDI creates an ErrInvalidEDI, wrapped in an errs.ErrInput, about the current segment.
+func (r *Reader) invalidEDI(format string, args ...interface{}) error {
+	return r.invalidEDI2(r.r.SegCount(), r.r.RuneBegin(), r.r.RuneEnd(), r.r.PosBegin(), format, args...)
 }
 
-const (
-	scannerFlags = ios.ScannerByDelimFlagEofNotAsDelim | ios.ScannerByDelimFlagIncludeDelimInReturn
-)
+func (r *Reader) invalidEDI2(
+	segCount, runeBegin, runeEnd int, pos fileformat.Position, format string, args ...interface{}) error {
+	return &errs.ErrInput{
+		Format:  fileFormatEDI,
+		Input:   r.inputName,
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Offset:  pos.Offset,
+		Segment: segCount,
+		Reason:  fmt.Sprintf(format, args...),
+		Err:     ErrInvalidEDI(r.fmtErrStr2(segCount, runeBegin, runeEnd, pos, format, args...)),
+	}
+}
+
+func (r *Reader) fmtErrStr2(
+	segCount, runeBegin, runeEnd int, pos fileformat.Position, format string, args ...interface{}) string {
+	unit := "char"
+	if r.r.BytePositions() {
+		unit = "byte"
+	}
+	return fmt.Sprintf("input '%s' at segment no.%d (%s[%d,%d], %s): %s",
+		r.inputName, segCount, unit, runeBegin, runeEnd, pos, fmt.Sprintf(format, args...))
+}
 
 var (
 	// ReaderBufSize is the default buf size for EDI reader. Making it too small might increase
@@ -326,8 +521,10 @@ var (
 	ReaderBufSize = 128
 )
 
-// NewReader creates an FormatReader for EDI file format.
-func NewReader(inputName string, r io.Reader, decl *FileDecl, targetXPath string) (*ediReader, error) {
+// NewReader creates a Reader for EDI file format. decl must have been validated, i.e. it's either from
+// ParseFileDecl or FileDecl.Validate has been called on it. If targetXPath isn't empty, only the target
+// segments matching it are returned.
+func NewReader(inputName string, r io.Reader, decl *FileDecl, targetXPath string) (*Reader, error) {
 	targetXPathExpr, err := func() (*xpath.Expr, error) {
 		if targetXPath == "" || targetXPath == "." {
 			return nil, nil
@@ -337,13 +534,18 @@ func NewReader(inputName string, r io.Reader, decl *FileDecl, targetXPath string
 	if err != nil {
 		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
 	}
-	reader := &ediReader{
+	reader := &Reader{
 		inputName:         inputName,
 		r:                 NewNonValidatingReader(r, decl),
 		releaseChar:       newStrPtrByte(decl.ReleaseChar),
+		warnTruncated:     strs.StrPtrOrElse(decl.TruncatedLastSegment, ToleranceSkip) == ToleranceWarn,
 		stack:             newStack(),
 		targetXPath:       targetXPathExpr,
 		unprocessedRawSeg: newRawSeg(),
+		dict:              decl.dict,
+		positional:        decl.PositionalElements,
+		refs:              decl.refs,
+		fileTrim:          fileformat.ResolveTrimPolicy(decl.Trim),
 	}
 	reader.growStack(stackEntry{
 		segDecl: &SegDecl{
@@ -1,15 +1,16 @@
 package edi
 
 import (
-	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"unicode/utf8"
-	"encoding/json"
 
-	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 )
 
 // ErrInvalidEDI indicates the EDI content is corrupted. This is a fatal, non-continuable error.
@@ -17,14 +18,10 @@ type ErrInvalidEDI string
 
 func (e ErrInvalidEDI) Error() string { return string(e) }
 
-// IsErrInvalidEDI checks if the `err` is of ErrInvalidEDI type.
+// IsErrInvalidEDI checks if the `err` is of ErrInvalidEDI type, or wraps one, e.g. in an errs.ErrInput.
 func IsErrInvalidEDI(err error) bool {
-	switch err.(type) {
-	case ErrInvalidEDI:
-		return true
-	default:
-		return false
-	}
+	var e ErrInvalidEDI
+	return errors.As(err, &e)
 }
 
 // RawSegElem represents an element or a component of a raw segment of an EDI document.
@@ -44,7 +41,7 @@ type RawSegElem struct {
 type RawSeg struct {
 	valid bool         // only for internal use.
 	Name  string       // name of the segment, e.g. 'ISA', 'GS', etc.
-	Raw   []byte       // the raw data of the entire segment, including segment delimiter. not owned, no mod!
+	Raw   []byte       // raw data of the entire segment, see raw_segment_delimiter. not owned, no mod!
 	Elems []RawSegElem // all the broken down pieces of elements/components of the segment.
 }
 
@@ -70,26 +67,23 @@ func resetRawSeg(raw *RawSeg) {
 	raw.Elems = raw.Elems[:0]
 }
 
+// runeCountAndHasOnlyCRLF returns the number of runes in b, with each byte of invalid UTF-8 byte
+// sequences counted as one rune, and whether b has only CR and/or LF.
 func runeCountAndHasOnlyCRLF(b []byte) (int, bool) {
 	runeCount := 0
 	onlyCRLF := true
-	for {
+	for len(b) > 0 {
 		r, size := utf8.DecodeRune(b)
-		if r == utf8.RuneError {
-			return runeCount, onlyCRLF
-		}
 		if r != '\n' && r != '\r' {
 			onlyCRLF = false
 		}
 		runeCount++
 		b = b[size:]
 	}
+	return runeCount, onlyCRLF
 }
 
-var (
-	crBytes = []byte("\r")
-	lfBytes = []byte("\n")
-)
+var crBytes = []byte("\r")
 
 type strPtrByte struct {
 	strptr *string
@@ -110,31 +104,72 @@ func newStrPtrByte(strptr *string) strPtrByte {
 // NonValidatingReader is an EDI segment reader that only reads out raw segments (its elements and components)
 // directly without doing any segment structural/hierarchical validation.
 type NonValidatingReader struct {
-	scanner            *bufio.Scanner
+	pr                 *fileformat.PositionReader
+	scanner            *segScanner
 	segDelim           strPtrByte
 	elemDelim          strPtrByte
 	compDelim          strPtrByte
 	repDelim           strPtrByte
 	releaseChar        strPtrByte
+	emptySegs          string
+	whitespace         string
+	strictISA          bool
+	truncatedLastSeg   string
+	truncated          bool // if the segment being read is the truncated last segment.
+	dropRawSegDelim    bool // if the segment delimiter is excluded from RawSeg.Raw.
+	bytePositions      bool
 	runeBegin, runeEnd int
+	byteEnd            int64
+	posBegin, posEnd   fileformat.Position
 	segCount           int
 	rawSeg             RawSeg
+	// scratch buffers for splitting a segment into elements, repetitions and components; reused
+	// across segments to avoid allocations.
+	elemsBuf, repsBuf, compsBuf [][]byte
 }
 
 // Read returns a raw segment of an EDI document. Note all the []byte are not a copy, so READONLY,
 // no modification.
 func (r *NonValidatingReader) Read() (RawSeg, error) {
 	var token []byte
-	for r.scanner.Scan() {
+	for r.scan() {
 		b := r.scanner.Bytes()
 		// In rare occasions inputs are not strict EDI per se - they sometimes have trailing empty lines
 		// with only CR and/or LF. Let's be not so strict and ignore those lines.
 		count, onlyCRLF := runeCountAndHasOnlyCRLF(b)
+		if r.bytePositions {
+			count = len(b)
+		}
 		r.runeBegin = r.runeEnd
 		r.runeEnd += count
+		r.posBegin = r.pr.Position(r.byteEnd)
+		r.byteEnd += int64(len(b))
+		r.posEnd = r.pr.Position(r.byteEnd)
 		if onlyCRLF {
 			continue
 		}
+		if lead := r.leadingWhitespace(b); lead > 0 {
+			switch r.whitespace {
+			case ToleranceSkip:
+				b = b[lead:]
+				if len(b) == 0 {
+					// trailing whitespace at the end of the input.
+					continue
+				}
+			case ToleranceError:
+				r.segCount++
+				return RawSeg{}, ErrInvalidEDI("unexpected whitespace before segment")
+			}
+		}
+		if len(b) == len(r.segDelim.b) && r.emptySegs == ToleranceSkip && !r.truncated {
+			continue
+		}
+		if r.truncated && r.truncatedLastSeg == ToleranceError {
+			r.segCount++
+			return RawSeg{}, ErrInvalidEDI(fmt.Sprintf(
+				"last segment '%s' is not terminated by segment_delimiter, input might be truncated",
+				isaQuote(b, 20)))
+		}
 		token = b
 		break
 	}
@@ -151,34 +186,64 @@ func (r *NonValidatingReader) Read() (RawSeg, error) {
 	if token == nil {
 		return RawSeg{}, io.EOF
 	}
+	if r.strictISA && r.segCount == 1 {
+		if err = r.validateISA(token); err != nil {
+			return RawSeg{}, err
+		}
+	}
 	if err = r.readToken(token, &r.rawSeg); err != nil {
 		return RawSeg{}, err
 	}
 	return r.rawSeg, nil
 }
 
+// scan advances the scanner to the next segment. Once the scanner stops at the end of the input, the
+// trailing data not terminated by the segment delimiter, unless it's only whitespace, is the truncated
+// last segment, which, unless truncated_last_segment is `skip`, is returned as a segment as well, with
+// r.truncated set.
+func (r *NonValidatingReader) scan() bool {
+	r.truncated = false
+	if r.scanner.Scan() {
+		return true
+	}
+	if r.truncatedLastSeg == ToleranceSkip || r.scanner.Err() != nil {
+		return false
+	}
+	r.truncated = len(bytes.TrimLeft(r.scanner.Trailing(), " \t\r\n")) > 0
+	return r.truncated
+}
+
+// segData returns the data of a segment token, i.e. without its trailing segment delimiter.
+func (r *NonValidatingReader) segData(token []byte) []byte {
+	data := token
+	if !r.truncated {
+		data = token[:len(token)-len(r.segDelim.b)]
+	}
+	// In rare occasions, input uses '\n' as segment delimiter, but '\r' somehow
+	// gets included as well (more common in business platform running on Windows)
+	// Drop that '\r' as well, unless it's escaped.
+	if *r.segDelim.strptr == "\n" && bytes.HasSuffix(data, crBytes) &&
+		!escapedAt(data, len(data)-len(crBytes), r.releaseChar.b) {
+		data = data[:len(data)-utf8.RuneLen('\r')]
+	}
+	return data
+}
+
 func (r *NonValidatingReader) readToken(token []byte, rawSeg *RawSeg) error {
 	resetRawSeg(rawSeg)
 	// Remember the token is a reference into the actual scanner, so do not modify.
 	rawSeg.Raw = token
-	// First we need to "drop" the trailing segment delimiter.
-	noSegDelim := token[:len(token)-len(r.segDelim.b)]
-	// In rare occasions, input uses '\n' as segment delimiter, but '\r' somehow
-	// gets included as well (more common in business platform running on Windows)
-	// Drop that '\r' as well.
-	if *r.segDelim.strptr == "\n" && bytes.HasSuffix(noSegDelim, crBytes) {
-		noSegDelim = noSegDelim[:len(noSegDelim)-utf8.RuneLen('\r')]
+	if r.dropRawSegDelim && !r.truncated {
+		rawSeg.Raw = token[:len(token)-len(r.segDelim.b)]
 	}
-	for i, elem := range strs.ByteSplitWithEsc(noSegDelim, r.elemDelim.b, r.releaseChar.b, defaultElemsPerSeg) {
+	noSegDelim := r.segData(token)
+	r.elemsBuf = splitWithEsc(r.elemsBuf[:0], noSegDelim, r.elemDelim.b, r.releaseChar.b)
+	for i, elem := range r.elemsBuf {
 		// If an element value contains repetition delimiters, that value is really a concatenation
-		// of multiple element values.
-		var elemVals [][]byte
-		if len(r.repDelim.b) != 0 {
-			elemVals = strs.ByteSplitWithEsc(elem, r.repDelim.b, r.releaseChar.b, defaultRepsPerElem)
-		} else {
-			elemVals = [][]byte{elem}
-		}
-		for _, elemVal := range elemVals {
+		// of multiple element values. Note if there is no repetition delimiter, splitWithEsc returns
+		// the element value itself.
+		r.repsBuf = splitWithEsc(r.repsBuf[:0], elem, r.repDelim.b, r.releaseChar.b)
+		for _, elemVal := range r.repsBuf {
 			if len(r.compDelim.b) == 0 {
 				// if we don't have comp delimiter, treat the entire element as one component.
 				rawSeg.Elems = append(
@@ -193,7 +258,8 @@ func (r *NonValidatingReader) readToken(token []byte, rawSeg *RawSeg) error {
 					})
 				continue
 			}
-			for j, comp := range strs.ByteSplitWithEsc(elemVal, r.compDelim.b, r.releaseChar.b, defaultCompsPerElem) {
+			r.compsBuf = splitWithEsc(r.compsBuf[:0], elemVal, r.compDelim.b, r.releaseChar.b)
+			for j, comp := range r.compsBuf {
 				rawSeg.Elems = append(
 					rawSeg.Elems,
 					RawSegElem{
@@ -205,18 +271,46 @@ func (r *NonValidatingReader) readToken(token []byte, rawSeg *RawSeg) error {
 		}
 	}
 	if len(rawSeg.Elems) == 0 || len(rawSeg.Elems[0].Data) == 0 {
-		return ErrInvalidEDI("missing segment name")
+		if len(noSegDelim) > 0 || r.emptySegs != TolerancePreserve {
+			return ErrInvalidEDI("missing segment name")
+		}
 	}
 	rawSeg.Name = string(rawSeg.Elems[0].Data)
 	rawSeg.valid = true
+	return nil
+}
 
-	b, err := json.Marshal(r.rawSeg)
-	if err != nil {
-		return nil
+// Truncated tells if the segment returned by the most recent Read call is the truncated last segment,
+// i.e. the trailing data of the input not terminated by the segment delimiter, which is only returned
+// if truncated_last_segment is `preserve` or `warn`.
+func (r *NonValidatingReader) Truncated() bool {
+	return r.truncated
+}
+
+// leadingWhitespace returns the number of whitespace bytes at the beginning of a segment, excluding
+// its segment delimiter.
+func (r *NonValidatingReader) leadingWhitespace(seg []byte) int {
+	end := len(seg)
+	if bytes.HasSuffix(seg, r.segDelim.b) {
+		end -= len(r.segDelim.b)
 	}
-	fmt.Println(string(b))
-	
-	return nil
+	n := 0
+	for n < end && strings.IndexByte(" \t\r\n", seg[n]) >= 0 {
+		n++
+	}
+	return n
+}
+
+// WithBytePositions makes the reader count positions, i.e. RuneBegin and RuneEnd, in bytes instead of
+// runes, so they stay accurate even if the input contains invalid UTF-8 byte sequences.
+func (r *NonValidatingReader) WithBytePositions() *NonValidatingReader {
+	r.bytePositions = true
+	return r
+}
+
+// BytePositions tells if the reader counts positions in bytes instead of runes.
+func (r *NonValidatingReader) BytePositions() bool {
+	return r.bytePositions
 }
 
 // RuneBegin returns the current reader's beginning rune position.
@@ -229,6 +323,17 @@ func (r *NonValidatingReader) RuneEnd() int {
 	return r.runeEnd
 }
 
+// PosBegin returns the position, in bytes and line/column, of the current reader's beginning. Note if
+// ignore_crlf is set, the CR/LF removed from the input aren't counted.
+func (r *NonValidatingReader) PosBegin() fileformat.Position {
+	return r.posBegin
+}
+
+// PosEnd returns the position, in bytes and line/column, of the current reader's ending.
+func (r *NonValidatingReader) PosEnd() fileformat.Position {
+	return r.posEnd
+}
+
 // SegCount returns the current reader's segment count.
 func (r *NonValidatingReader) SegCount() int {
 	return r.segCount
@@ -242,20 +347,30 @@ func NewNonValidatingReader(r io.Reader, decl *FileDecl) *NonValidatingReader {
 	repDelim := newStrPtrByte(decl.RepDelim)
 	releaseChar := newStrPtrByte(decl.ReleaseChar)
 	if decl.IgnoreCRLF {
-		r = ios.NewBytesReplacingReader(r, crBytes, nil)
-		r = ios.NewBytesReplacingReader(r, lfBytes, nil)
+		r = newCRLFDroppingReader(r, releaseChar.b)
 	}
-	scanner := ios.NewScannerByDelim3(r, segDelim.b, releaseChar.b, scannerFlags, make([]byte, ReaderBufSize))
+	pr := fileformat.NewPositionReader(r)
 	return &NonValidatingReader{
-		scanner:     scanner,
-		segDelim:    segDelim,
-		elemDelim:   elemDelim,
-		compDelim:   compDelim,
-		repDelim:    repDelim,
-		releaseChar: releaseChar,
-		runeBegin:   1,
-		runeEnd:     1,
-		segCount:    0,
-		rawSeg:      newRawSeg(),
+		pr:               pr,
+		scanner:          newSegScanner(pr, segDelim.b, releaseChar.b, make([]byte, ReaderBufSize)),
+		segDelim:         segDelim,
+		elemDelim:        elemDelim,
+		compDelim:        compDelim,
+		repDelim:         repDelim,
+		releaseChar:      releaseChar,
+		emptySegs:        strs.StrPtrOrElse(decl.EmptySegments, ToleranceError),
+		whitespace:       strs.StrPtrOrElse(decl.InterSegmentWhitespace, TolerancePreserve),
+		strictISA:        decl.StrictISA,
+		truncatedLastSeg: strs.StrPtrOrElse(decl.TruncatedLastSegment, ToleranceSkip),
+		dropRawSegDelim:  strs.StrPtrOrElse(decl.RawSegDelim, RawSegDelimKeep) == RawSegDelimDrop,
+		runeBegin:        1,
+		runeEnd:          1,
+		posBegin:         fileformat.Position{Line: 1, Column: 1},
+		posEnd:           fileformat.Position{Line: 1, Column: 1},
+		segCount:         0,
+		rawSeg:           newRawSeg(),
+		elemsBuf:         make([][]byte, 0, defaultElemsPerSeg),
+		repsBuf:          make([][]byte, 0, defaultRepsPerElem),
+		compsBuf:         make([][]byte, 0, defaultCompsPerElem),
 	}
 }
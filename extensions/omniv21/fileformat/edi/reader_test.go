@@ -13,6 +13,8 @@ import (
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -25,7 +27,7 @@ func TestIsErrInvalidEDI(t *testing.T) {
 func TestRawSeg(t *testing.T) {
 	rawSegName := "test"
 	rawSegData := []byte("test data")
-	r := ediReader{
+	r := Reader{
 		unprocessedRawSeg: newRawSeg(),
 	}
 	assert.False(t, r.unprocessedRawSeg.valid)
@@ -47,7 +49,7 @@ func TestRawSeg(t *testing.T) {
 }
 
 func TestStack(t *testing.T) {
-	r := ediReader{
+	r := Reader{
 		stack: newStack(),
 	}
 	assert.Equal(t, 0, len(r.stack))
@@ -141,6 +143,24 @@ func TestRuneCountAndHasOnlyCRLF(t *testing.T) {
 			expectedCount:    4,
 			expectedOnlyCRLF: false,
 		},
+		{
+			name:             "invalid UTF-8 bytes counted one rune each",
+			input:            []byte("a\xff\xfeb"),
+			expectedCount:    4,
+			expectedOnlyCRLF: false,
+		},
+		{
+			name:             "invalid UTF-8 byte only",
+			input:            []byte{0xff},
+			expectedCount:    1,
+			expectedOnlyCRLF: false,
+		},
+		{
+			name:             "encoded U+FFFD",
+			input:            []byte("\uFFFDa\n"),
+			expectedCount:    3,
+			expectedOnlyCRLF: false,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			count, onlyCRLF := runeCountAndHasOnlyCRLF(test.input)
@@ -150,6 +170,122 @@ func TestRuneCountAndHasOnlyCRLF(t *testing.T) {
 	}
 }
 
+func TestNonValidatingReader_BytePositions(t *testing.T) {
+	decl := &FileDecl{SegDelim: "~", ElemDelim: "*"}
+	input := "A*\xff\xfe~B*é~"
+	for _, test := range []struct {
+		name          string
+		bytePositions bool
+		expected      [][2]int
+	}{
+		{name: "runes", expected: [][2]int{{1, 6}, {6, 10}}},
+		{name: "bytes", bytePositions: true, expected: [][2]int{{1, 6}, {6, 11}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNonValidatingReader(strings.NewReader(input), decl)
+			if test.bytePositions {
+				assert.Same(t, r, r.WithBytePositions())
+			}
+			assert.Equal(t, test.bytePositions, r.BytePositions())
+			for _, expected := range test.expected {
+				_, err := r.Read()
+				assert.NoError(t, err)
+				assert.Equal(t, expected, [2]int{r.RuneBegin(), r.RuneEnd()})
+			}
+			_, err := r.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestNonValidatingReader_TruncatedLastSegment(t *testing.T) {
+	input := "ISA*1~IEA*1*00000\r\n"
+	for _, test := range []struct {
+		name     string
+		policy   *string
+		expected []string
+		err      string
+	}{
+		{name: "default", expected: []string{"ISA"}},
+		{name: "skip", policy: strs.StrPtr(ToleranceSkip), expected: []string{"ISA"}},
+		{name: "preserve", policy: strs.StrPtr(TolerancePreserve), expected: []string{"ISA", "IEA"}},
+		{name: "warn", policy: strs.StrPtr(ToleranceWarn), expected: []string{"ISA", "IEA"}},
+		{
+			name:     "error",
+			policy:   strs.StrPtr(ToleranceError),
+			expected: []string{"ISA"},
+			err:      "last segment 'IEA*1*00000\r\n' is not terminated by segment_delimiter, input might be truncated",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNonValidatingReader(
+				strings.NewReader(input), &FileDecl{SegDelim: "~", ElemDelim: "*", TruncatedLastSegment: test.policy})
+			var names []string
+			var err error
+			for {
+				var rawSeg RawSeg
+				rawSeg, err = r.Read()
+				if err != nil {
+					break
+				}
+				names = append(names, rawSeg.Name)
+				assert.Equal(t, rawSeg.Name == "IEA", r.Truncated())
+				if r.Truncated() {
+					assert.Equal(t, "00000\r\n", string(rawSeg.Elems[2].Data))
+					assert.Equal(t, fileformat.Position{Offset: 19, Line: 2, Column: 1}, r.PosEnd())
+				}
+			}
+			assert.Equal(t, test.expected, names)
+			if test.err == "" {
+				assert.Equal(t, io.EOF, err)
+			} else {
+				assert.True(t, IsErrInvalidEDI(err))
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, 2, r.SegCount())
+			}
+		})
+	}
+	// trailing whitespace isn't a truncated last segment.
+	r := NewNonValidatingReader(
+		strings.NewReader("ISA*1~ \r\n"),
+		&FileDecl{SegDelim: "~", ElemDelim: "*", TruncatedLastSegment: strs.StrPtr(ToleranceError)})
+	_, err := r.Read()
+	assert.NoError(t, err)
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestNonValidatingReader_RawSegDelim(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		policy   *string
+		expected []string
+	}{
+		{name: "default", expected: []string{"ISA*1~~", "GS*2~~", "IEA"}},
+		{name: "keep", policy: strs.StrPtr(RawSegDelimKeep), expected: []string{"ISA*1~~", "GS*2~~", "IEA"}},
+		{name: "drop", policy: strs.StrPtr(RawSegDelimDrop), expected: []string{"ISA*1", "GS*2", "IEA"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewNonValidatingReader(strings.NewReader("ISA*1~~GS*2~~IEA"), &FileDecl{
+				SegDelim:             "~~",
+				ElemDelim:            "*",
+				TruncatedLastSegment: strs.StrPtr(TolerancePreserve),
+				RawSegDelim:          test.policy,
+			})
+			var raws []string
+			for {
+				rawSeg, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				raws = append(raws, string(rawSeg.Raw))
+			}
+			assert.Equal(t, test.expected, raws)
+		})
+	}
+}
+
 func verifyErr(t *testing.T, expectedErr string, actual error) {
 	if expectedErr == "" {
 		assert.NoError(t, actual)
@@ -189,7 +325,7 @@ func TestGetUnprocessedRawSeg(t *testing.T) {
 				ElemDelim: ":",
 			},
 			expected: []result{
-				{rawSeg: RawSeg{}, err: `input 'test' at segment no.1 (char[1,1]): cannot read segment, err: read failure`},
+				{rawSeg: RawSeg{}, err: `input 'test' at segment no.1 (char[1,1], line 1, col 1, byte offset 0): cannot read segment, err: read failure`},
 			},
 		},
 		{
@@ -244,7 +380,162 @@ func TestGetUnprocessedRawSeg(t *testing.T) {
 				ElemDelim: "*",
 			},
 			expected: []result{
-				{rawSeg: RawSeg{}, err: `input 'test' at segment no.1 (char[1,2]): missing segment name`},
+				{rawSeg: RawSeg{}, err: `input 'test' at segment no.1 (char[1,2], line 1, col 1, byte offset 0): missing segment name`},
+			},
+		},
+		{
+			name:  "empty seg; empty_segments skip",
+			input: strings.NewReader("|seg1|||seg2|"),
+			decl: FileDecl{
+				SegDelim:      "|",
+				ElemDelim:     "*",
+				EmptySegments: strs.StrPtr(ToleranceSkip),
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg2",
+						Raw:   []byte("seg2|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg2")}},
+					},
+				},
+				{rawSeg: RawSeg{}, err: io.EOF.Error()},
+			},
+		},
+		{
+			name:  "empty seg; empty_segments preserve",
+			input: strings.NewReader("seg1||"),
+			decl: FileDecl{
+				SegDelim:      "|",
+				ElemDelim:     "*",
+				EmptySegments: strs.StrPtr(TolerancePreserve),
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "",
+						Raw:   []byte("|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("")}},
+					},
+				},
+				{rawSeg: RawSeg{}, err: io.EOF.Error()},
+			},
+		},
+		{
+			name:  "empty seg; empty_segments error",
+			input: strings.NewReader("seg1||"),
+			decl: FileDecl{
+				SegDelim:      "|",
+				ElemDelim:     "*",
+				EmptySegments: strs.StrPtr(ToleranceError),
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{rawSeg: RawSeg{}, err: `input 'test' at segment no.2 (char[6,7], line 1, col 6, byte offset 5): missing segment name`},
+			},
+		},
+		{
+			name:  "whitespace between segs; inter_segment_whitespace skip",
+			input: strings.NewReader("seg1| \t\r\nseg2*e1|  | \n"),
+			decl: FileDecl{
+				SegDelim:               "|",
+				ElemDelim:              "*",
+				EmptySegments:          strs.StrPtr(ToleranceSkip),
+				InterSegmentWhitespace: strs.StrPtr(ToleranceSkip),
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg2",
+						Raw:   []byte("seg2*e1|"),
+						Elems: []RawSegElem{
+							{ElemIndex: 0, CompIndex: 1, Data: []byte("seg2")},
+							{ElemIndex: 1, CompIndex: 1, Data: []byte("e1")},
+						},
+					},
+				},
+				{rawSeg: RawSeg{}, err: io.EOF.Error()},
+			},
+		},
+		{
+			name:  "whitespace between segs; inter_segment_whitespace error",
+			input: strings.NewReader("seg1| seg2|"),
+			decl: FileDecl{
+				SegDelim:               "|",
+				ElemDelim:              "*",
+				InterSegmentWhitespace: strs.StrPtr(ToleranceError),
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{rawSeg: RawSeg{}, err: `input 'test' at segment no.2 (char[6,12], line 1, col 6, byte offset 5): unexpected whitespace before segment`},
+			},
+		},
+		{
+			name:  "whitespace between segs; inter_segment_whitespace preserve",
+			input: strings.NewReader("seg1| seg2|"),
+			decl: FileDecl{
+				SegDelim:  "|",
+				ElemDelim: "*",
+			},
+			expected: []result{
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  "seg1",
+						Raw:   []byte("seg1|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte("seg1")}},
+					},
+				},
+				{
+					rawSeg: RawSeg{
+						valid: true,
+						Name:  " seg2",
+						Raw:   []byte(" seg2|"),
+						Elems: []RawSegElem{{ElemIndex: 0, CompIndex: 1, Data: []byte(" seg2")}},
+					},
+				},
+				{rawSeg: RawSeg{}, err: io.EOF.Error()},
 			},
 		},
 		{
@@ -350,7 +641,7 @@ func TestGetUnprocessedRawSeg(t *testing.T) {
 
 func TestRawSegToNode(t *testing.T) {
 	assert.PanicsWithValue(t, "unprocessedRawSeg is not valid", func() {
-		_, _ = (&ediReader{unprocessedRawSeg: RawSeg{valid: false}}).rawSegToNode(nil)
+		_, _ = (&Reader{unprocessedRawSeg: RawSeg{valid: false}}).rawSegToNode(nil)
 	})
 
 	for _, test := range []struct {
@@ -383,7 +674,7 @@ func TestRawSegToNode(t *testing.T) {
 				},
 				fqdn: "ISA",
 			},
-			err:      `input 'test' at segment no.3 (char[10,20]): unable to find element 'e3' on segment 'ISA'`,
+			err:      `input 'test' at segment no.3 (char[10,20], line 1, col 10, byte offset 9): unable to find element 'e3' on segment 'ISA'`,
 			expected: "",
 		},
 		{
@@ -416,11 +707,17 @@ func TestRawSegToNode(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := ediReader{
+			r := Reader{
 				inputName:         "test",
 				releaseChar:       newStrPtrByte(strs.StrPtr("?")),
 				unprocessedRawSeg: test.rawSeg,
-				r:                 &NonValidatingReader{runeBegin: 10, runeEnd: 20, segCount: 3},
+				r: &NonValidatingReader{
+					runeBegin: 10,
+					runeEnd:   20,
+					posBegin:  fileformat.Position{Offset: 9, Line: 1, Column: 10},
+					posEnd:    fileformat.Position{Offset: 19, Line: 1, Column: 20},
+					segCount:  3,
+				},
 			}
 			n, err := r.rawSegToNode(test.decl)
 			if test.err != "" {
@@ -553,7 +850,7 @@ func TestSegDoneSegNext(t *testing.T) {
 			target:      nil,
 			callSegDone: false,
 			panicStr:    "",
-			err:         `input 'test' at segment no.3 (char[20,20]): segment 'C' needs min occur 1, but only got 0`,
+			err:         `input 'test' at segment no.3 (char[20,20], line 1, col 20, byte offset 19): segment 'C' needs min occur 1, but only got 0`,
 		},
 		{
 			name: "root-A-C, C segDone, C over max, A becomes target, but r.target not nil",
@@ -581,11 +878,17 @@ func TestSegDoneSegNext(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &ediReader{
+			r := &Reader{
 				inputName: "test",
 				stack:     test.stack,
 				target:    test.target,
-				r:         &NonValidatingReader{runeBegin: 10, runeEnd: 20, segCount: 3},
+				r: &NonValidatingReader{
+					runeBegin: 10,
+					runeEnd:   20,
+					posBegin:  fileformat.Position{Offset: 9, Line: 1, Column: 10},
+					posEnd:    fileformat.Position{Offset: 19, Line: 1, Column: 20},
+					segCount:  3,
+				},
 			}
 			var err error
 			testCall := func() {
@@ -989,8 +1292,325 @@ func TestRelease(t *testing.T) {
 }
 
 func TestIsContinuableError(t *testing.T) {
-	r := &ediReader{r: &NonValidatingReader{}}
+	r := &Reader{r: &NonValidatingReader{}}
 	assert.True(t, r.IsContinuableError(r.FmtErr("some error")))
 	assert.False(t, r.IsContinuableError(ErrInvalidEDI("invalid EDI")))
 	assert.False(t, r.IsContinuableError(io.EOF))
 }
+
+func TestRecordPosition(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{
+					"name": "ISA",
+					"is_target": true,
+					"max": -1,
+					"child_segments": [
+						{ "name": "GS", "max": -1 }
+					]
+				},
+				{ "name": "IEA" }
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test", strings.NewReader("ISA\nGS\nGS\nISA\nGS\nIEA\n"), &decl, "")
+	assert.NoError(t, err)
+	begin, end := reader.RecordPosition()
+	assert.Equal(t, 0, begin)
+	assert.Equal(t, 0, end)
+	// The first target spans segments 1-3; the look-ahead segment 4 (the 2nd ISA) must not be counted.
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	begin, end = reader.RecordPosition()
+	assert.Equal(t, 1, begin)
+	assert.Equal(t, 3, end)
+	assert.Equal(t, "ISA\nGS\nGS\n", string(reader.RawBytes()))
+	posBegin, posEnd := reader.SourcePosition()
+	assert.Equal(t, fileformat.Position{Offset: 0, Line: 1, Column: 1}, posBegin)
+	assert.Equal(t, fileformat.Position{Offset: 10, Line: 4, Column: 1}, posEnd)
+	// The second target starts from the previously looked-ahead segment and is followed by IEA.
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	begin, end = reader.RecordPosition()
+	assert.Equal(t, 4, begin)
+	assert.Equal(t, 5, end)
+	assert.Equal(t, "ISA\nGS\n", string(reader.RawBytes()))
+	posBegin, posEnd = reader.SourcePosition()
+	assert.Equal(t, fileformat.Position{Offset: 10, Line: 4, Column: 1}, posBegin)
+	assert.Equal(t, fileformat.Position{Offset: 17, Line: 6, Column: 1}, posEnd)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRead_ErrInput(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{ "name": "ISA", "is_target": true, "min": 1 },
+				{ "name": "IEA", "min": 1 }
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test", strings.NewReader("ISA\nGS\n"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	_, err = reader.Read()
+	assert.True(t, IsErrInvalidEDI(err))
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "edi", inputErr.Format)
+	assert.Equal(t, "test", inputErr.Input)
+	assert.Equal(t, 2, inputErr.Segment)
+	assert.Equal(t, 3, inputErr.Line)
+	assert.Equal(t, 1, inputErr.Column)
+	assert.Equal(t, int64(7), inputErr.Offset)
+	assert.Equal(t, "segment 'IEA' needs min occur 1, but only got 0", inputErr.Reason)
+	assert.Equal(t,
+		"input 'test' at segment no.2 (char[8,8], line 3, col 1, byte offset 7): segment 'IEA' needs min occur 1, but only got 0",
+		err.Error())
+}
+
+func TestRead_TruncatedLastSegmentWarning(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"truncated_last_segment": "warn",
+			"segment_declarations": [
+				{ "name": "ISA", "is_target": true, "max": -1 }
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test", strings.NewReader("ISA*1\nISA*2\nISA*"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	assert.Nil(t, reader.Warnings())
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	assert.Nil(t, reader.Warnings())
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "ISA*", string(reader.RawBytes()))
+	reader.Release(n)
+	assert.Equal(t, 1, len(reader.Warnings()))
+	assert.True(t, IsErrInvalidEDI(reader.Warnings()[0]))
+	assert.Equal(t,
+		"input 'test' at segment no.3 (char[13,17], line 3, col 1, byte offset 12): "+
+			"last segment 'ISA*' is not terminated by segment_delimiter, input might be truncated",
+		reader.Warnings()[0].Error())
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRead_ExceedsMax(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		exceedsMax string
+		expected   []string
+		warnings   []int
+	}{
+		{
+			name:       "truncate",
+			exceedsMax: ExceedsMaxTruncate,
+			expected: []string{
+				`{"ITM":[{"e":"a"},{"e":"b"}],"id":"1"}`,
+				`{"ITM":{"e":"d"},"id":"2"}`,
+			},
+			warnings: []int{1, 0},
+		},
+		{
+			name:       "accept",
+			exceedsMax: ExceedsMaxAccept,
+			expected: []string{
+				`{"ITM":[{"e":"a"},{"e":"b"},{"e":"c"}],"id":"1"}`,
+				`{"ITM":{"e":"d"},"id":"2"}`,
+			},
+			warnings: []int{1, 0},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var decl FileDecl
+			err := json.Unmarshal([]byte(`
+				{
+					"segment_delimiter": "\n",
+					"element_delimiter": "*",
+					"segment_declarations": [
+						{
+							"name": "ORD", "is_target": true, "max": -1,
+							"elements": [ { "name": "id", "index": 1 } ],
+							"child_segments": [
+								{
+									"name": "ITM", "min": 0, "max": 2, "exceeds_max": "`+test.exceedsMax+`",
+									"elements": [ { "name": "e", "index": 1 } ]
+								}
+							]
+						}
+					]
+				}`), &decl)
+			assert.NoError(t, err)
+			reader, err := NewReader("test", strings.NewReader("ORD*1\nITM*a\nITM*b\nITM*c\nORD*2\nITM*d\n"), &decl, "")
+			assert.NoError(t, err)
+			for i, expected := range test.expected {
+				n, err := reader.Read()
+				assert.NoError(t, err)
+				assert.Equal(t, expected, idr.JSONify2(n))
+				reader.Release(n)
+				assert.Equal(t, test.warnings[i], len(reader.Warnings()))
+				for _, warning := range reader.Warnings() {
+					assert.True(t, IsErrInvalidEDI(warning))
+					assert.Equal(t,
+						"input 'test' at segment no.4 (char[19,25], line 4, col 1, byte offset 18): "+
+							"segment 'ITM' occurs more than max 2 times, the extra occurrence is "+
+							map[string]string{ExceedsMaxTruncate: "dropped", ExceedsMaxAccept: "accepted"}[test.exceedsMax],
+						warning.Error())
+				}
+			}
+			_, err = reader.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestRead_ExceedsMax_GroupTruncated(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{
+					"name": "ORD", "max": -1,
+					"child_segments": [
+						{
+							"name": "party", "type": "segment_group", "max": 1, "exceeds_max": "truncate",
+							"child_segments": [
+								{ "name": "N1", "is_target": true, "elements": [ { "name": "name", "index": 1 } ] },
+								{ "name": "N3", "min": 0 }
+							]
+						}
+					]
+				}
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test", strings.NewReader("ORD\nN1*a\nN3\nN1*b\nN3\nORD\nN1*c\n"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"a"}`, idr.JSONify2(n))
+	reader.Release(n)
+	assert.Nil(t, reader.Warnings())
+	// the second instance of the group, including its target N1*b, is dropped.
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"c"}`, idr.JSONify2(n))
+	reader.Release(n)
+	assert.Equal(t, 1, len(reader.Warnings()))
+	assert.Contains(t, reader.Warnings()[0].Error(),
+		"segment 'party' occurs more than max 1 times, the extra occurrence is dropped")
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRead_Wildcard(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{
+					"name": "ORD", "is_target": true, "max": -1,
+					"elements": [ { "name": "id", "index": 1 } ],
+					"child_segments": [
+						{ "name": "ITM", "min": 0, "max": -1, "elements": [ { "name": "e", "index": 1 } ] },
+						{ "name": "*", "min": 0, "max": -1 },
+						{ "name": "TOT", "min": 0, "elements": [ { "name": "t", "index": 1 } ] }
+					]
+				}
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test",
+		strings.NewReader("ORD*1\nITM*a\nFOO*x\nBAR\nTOT*9\nORD*2\nITM*b\nFOO\n"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ITM":{"e":"a"},"TOT":{"t":"9"},"id":"1"}`, idr.JSONify2(n))
+	assert.Equal(t, "ORD*1\nITM*a\nFOO*x\nBAR\nTOT*9\n", string(reader.RawBytes()))
+	reader.Release(n)
+	n, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ITM":{"e":"b"},"id":"2"}`, idr.JSONify2(n))
+	reader.Release(n)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestRead_Wildcard_ExceedsMax(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"segment_declarations": [
+				{
+					"name": "ORD", "is_target": true, "max": -1,
+					"child_segments": [ { "name": "*", "min": 0, "max": 1 } ]
+				}
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	reader, err := NewReader("test", strings.NewReader("ORD\nFOO\nBAR\n"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	reader.Release(n)
+	_, err = reader.Read()
+	assert.True(t, IsErrInvalidEDI(err))
+	assert.Contains(t, err.Error(), "segment 'BAR' is either not declared in schema or appears in an invalid order")
+}
+
+func TestRead_Trim(t *testing.T) {
+	var decl FileDecl
+	err := json.Unmarshal([]byte(`
+		{
+			"segment_delimiter": "\n",
+			"element_delimiter": "*",
+			"trim": "both",
+			"segment_declarations": [
+				{
+					"name": "ISA",
+					"is_target": true,
+					"elements": [
+						{ "name": "e1", "index": 1 },
+						{ "name": "e2", "index": 2, "trim": "none" },
+						{ "name": "e3", "index": 3, "default": " x " }
+					]
+				}
+			]
+		}`), &decl)
+	assert.NoError(t, err)
+	assert.NoError(t, (&ediValidateCtx{}).validateFileDecl(&decl))
+	reader, err := NewReader("test", strings.NewReader("ISA* 0 * 1 \n"), &decl, "")
+	assert.NoError(t, err)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	// Defaults are taken as is.
+	assert.Equal(t, `{"e1":"0","e2":" 1 ","e3":" x "}`, idr.JSONify2(n))
+}
@@ -0,0 +1,131 @@
+package edi
+
+import (
+	"errors"
+	"io"
+
+	"github.com/jf-tech/go-corelib/maths"
+)
+
+const (
+	maxConsecutiveEmptyReads = 100
+	minSegScannerBufGrowth   = 64
+)
+
+// errNoProgress is returned when the underlying io.Reader keeps returning no data and no error.
+var errNoProgress = errors.New("multiple Read calls return no data or error")
+
+// segScanner scans EDI segments out of an io.Reader. It's a purpose-built replacement of a
+// bufio.Scanner with a delimiter split func:
+//   - it remembers how far a partial segment has been searched, so when a segment spans across
+//     multiple reads, previously searched bytes aren't searched again;
+//   - its buffer grows as needed, so there is no limit on segment length (bufio.Scanner fails
+//     segments longer than 64KB);
+//   - the buffer is only compacted when it's full, and only the partial segment is moved.
+//
+// Same as bufio.Scanner, the segment returned by Bytes() includes the segment delimiter and points
+// into the scanner's internal buffer, thus only valid until the next Scan() call. Trailing data
+// not terminated by the segment delimiter at the end of input isn't returned by Scan(), but by
+// Trailing().
+type segScanner struct {
+	r       io.Reader
+	delim   []byte
+	esc     []byte
+	buf     []byte
+	start   int // buf[start:end] is the data read in but not yet returned.
+	end     int
+	scanned int // buf[start:start+scanned] has been searched and has no segment delimiter.
+	token   []byte
+	err     error
+	eof     bool
+}
+
+func newSegScanner(r io.Reader, delim, esc, buf []byte) *segScanner {
+	return &segScanner{r: r, delim: delim, esc: esc, buf: buf}
+}
+
+// Scan advances the scanner to the next segment, which will then be available through Bytes().
+// It returns false when the scan stops, either by reaching the end of the input or an error;
+// Err() returns the error or nil if it was io.EOF.
+func (s *segScanner) Scan() bool {
+	s.token = nil
+	for {
+		if i := s.index(); i >= 0 {
+			s.token = s.buf[s.start : s.start+i+len(s.delim)]
+			s.start += i + len(s.delim)
+			s.scanned = 0
+			return true
+		}
+		if s.eof || s.err != nil {
+			return false
+		}
+		s.fill()
+	}
+}
+
+// Bytes returns the segment, including the segment delimiter, found by the last Scan() call.
+func (s *segScanner) Bytes() []byte {
+	return s.token
+}
+
+// Trailing returns, once Scan() has returned false at the end of the input, the trailing data not
+// terminated by the segment delimiter, and consumes it, so the following calls return nothing. It
+// returns nil if the scan hasn't reached the end of the input.
+func (s *segScanner) Trailing() []byte {
+	if !s.eof || s.err != nil {
+		return nil
+	}
+	b := s.buf[s.start:s.end]
+	s.start = s.end
+	s.token = b
+	return b
+}
+
+// Err returns the first non-EOF error encountered by the scanner.
+func (s *segScanner) Err() error {
+	return s.err
+}
+
+// index returns the index, relative to s.start, of the first unescaped segment delimiter in
+// buf[s.start:s.end], or -1 if not found. If not found, s.scanned is advanced to the furthest
+// position from which a delimiter can still begin once more data is read in.
+func (s *segScanner) index() int {
+	data := s.buf[s.start:s.end]
+	i := indexWithEsc(data, s.scanned, s.delim, s.esc)
+	if i < 0 {
+		if s.scanned = len(data) - len(s.delim) + 1; s.scanned < 0 {
+			s.scanned = 0
+		}
+	}
+	return i
+}
+
+// fill reads more data into the buffer, making room first by either moving the unreturned data to
+// the beginning of the buffer, or, if the unreturned data occupies the entire buffer, growing it.
+func (s *segScanner) fill() {
+	if s.end == len(s.buf) {
+		if s.start > 0 {
+			s.end = copy(s.buf, s.buf[s.start:s.end])
+			s.start = 0
+		} else {
+			newBuf := make([]byte, len(s.buf)+maths.MaxInt(len(s.buf), minSegScannerBufGrowth))
+			copy(newBuf, s.buf[:s.end])
+			s.buf = newBuf
+		}
+	}
+	for loop := 0; loop < maxConsecutiveEmptyReads; loop++ {
+		n, err := s.r.Read(s.buf[s.end:])
+		s.end += n
+		switch {
+		case err == io.EOF:
+			s.eof = true
+			return
+		case err != nil:
+			s.err = err
+			return
+		case n > 0:
+			return
+		}
+	}
+	s.err = errNoProgress
+}
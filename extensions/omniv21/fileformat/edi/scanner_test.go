@@ -0,0 +1,176 @@
+package edi
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jf-tech/go-corelib/ios"
+	"github.com/stretchr/testify/assert"
+)
+
+type emptyReader struct{}
+
+func (emptyReader) Read([]byte) (int, error) { return 0, nil }
+
+func TestSegScanner(t *testing.T) {
+	longSeg := "LONG*" + strings.Repeat("x", 100000) + "~"
+	for _, test := range []struct {
+		name     string
+		input    io.Reader
+		delim    string
+		esc      string
+		bufSize  int
+		expected []string
+		trailing string
+		err      string
+	}{
+		{
+			name:     "empty input",
+			input:    strings.NewReader(""),
+			delim:    "~",
+			bufSize:  8,
+			expected: nil,
+		},
+		{
+			name:     "multiple segments; trailing data without delimiter left to Trailing",
+			input:    strings.NewReader("ISA*1~GS*2~ST*3~trailing"),
+			delim:    "~",
+			bufSize:  4,
+			expected: []string{"ISA*1~", "GS*2~", "ST*3~"},
+			trailing: "trailing",
+		},
+		{
+			name:     "escaped delimiter and escaped escape",
+			input:    strings.NewReader("UNA?'x'FTX+a??'FTX+b???'c'"),
+			delim:    "'",
+			esc:      "?",
+			bufSize:  3,
+			expected: []string{"UNA?'x'", "FTX+a??'", "FTX+b???'c'"},
+		},
+		{
+			name:     "multi-byte delimiter and escape split across reads",
+			input:    iotest.OneByteReader(strings.NewReader("a~|b@@@@~|c@@~|d~|")),
+			delim:    "~|",
+			esc:      "@@",
+			bufSize:  2,
+			expected: []string{"a~|", "b@@@@~|", "c@@~|d~|"},
+		},
+		{
+			name:     "multi-byte utf-8 delimiter",
+			input:    strings.NewReader("a§b§"),
+			delim:    "§",
+			bufSize:  1,
+			expected: []string{"a§", "b§"},
+		},
+		{
+			name:     "segment longer than bufio.MaxScanTokenSize",
+			input:    strings.NewReader("ISA~" + longSeg + "IEA~"),
+			delim:    "~",
+			bufSize:  128,
+			expected: []string{"ISA~", longSeg, "IEA~"},
+		},
+		{
+			name:     "zero size buffer",
+			input:    strings.NewReader("a~b~"),
+			delim:    "~",
+			bufSize:  0,
+			expected: []string{"a~", "b~"},
+		},
+		{
+			name:     "read error after some segments",
+			input:    io.MultiReader(strings.NewReader("a~b~c"), iotest.ErrReader(errors.New("read failure"))),
+			delim:    "~",
+			bufSize:  16,
+			expected: []string{"a~", "b~"},
+			err:      "read failure",
+		},
+		{
+			name:     "no progress",
+			input:    emptyReader{},
+			delim:    "~",
+			bufSize:  16,
+			expected: nil,
+			err:      "multiple Read calls return no data or error",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := newSegScanner(test.input, []byte(test.delim), []byte(test.esc), make([]byte, test.bufSize))
+			var segs []string
+			for s.Scan() {
+				segs = append(segs, string(s.Bytes()))
+			}
+			assert.Equal(t, test.expected, segs)
+			assert.Nil(t, s.Bytes())
+			assert.Equal(t, test.trailing, string(s.Trailing()))
+			assert.Empty(t, s.Trailing())
+			if test.err != "" {
+				assert.Error(t, s.Err())
+				assert.Equal(t, test.err, s.Err().Error())
+			} else {
+				assert.NoError(t, s.Err())
+			}
+		})
+	}
+}
+
+func TestSegScanner_SameAsScannerByDelim(t *testing.T) {
+	input := benchInputWithCompAndRelease + benchInputNoCompNoReleaseChar
+	expected := ios.NewScannerByDelim3(strings.NewReader(input), []byte("'"), []byte("?"),
+		ios.ScannerByDelimFlagEofNotAsDelim|ios.ScannerByDelimFlagIncludeDelimInReturn, make([]byte, 16))
+	s := newSegScanner(iotest.HalfReader(strings.NewReader(input)), []byte("'"), []byte("?"), make([]byte, 16))
+	for expected.Scan() {
+		assert.True(t, s.Scan())
+		assert.Equal(t, string(expected.Bytes()), string(s.Bytes()))
+	}
+	assert.False(t, s.Scan())
+	assert.NoError(t, s.Err())
+}
+
+func scanSegs(scanner interface{ Scan() bool }) int {
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+	return count
+}
+
+var benchInputLargeSegs = strings.Repeat("BIN*"+strings.Repeat("0123456789", 2000)+"~", 50)
+
+// BenchmarkSegScanner_LargeSegs-8 	   10000	    106210 ns/op	   65441 B/op	      11 allocs/op
+func BenchmarkSegScanner_LargeSegs(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		scanSegs(newSegScanner(strings.NewReader(benchInputLargeSegs), []byte("~"), nil, make([]byte, ReaderBufSize)))
+	}
+}
+
+// BenchmarkScannerByDelim_LargeSegs-8 	   10000	    114145 ns/op	   65649 B/op	      13 allocs/op
+func BenchmarkScannerByDelim_LargeSegs(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := ios.NewScannerByDelim3(strings.NewReader(benchInputLargeSegs), []byte("~"), nil,
+			ios.ScannerByDelimFlagEofNotAsDelim|ios.ScannerByDelimFlagIncludeDelimInReturn,
+			make([]byte, ReaderBufSize))
+		scanSegs(s)
+	}
+}
+
+// BenchmarkSegScanner_SmallSegs-8 	   10000	    102964 ns/op	     172 B/op	       3 allocs/op
+func BenchmarkSegScanner_SmallSegs(b *testing.B) {
+	input := strings.Repeat(benchInputNoCompNoReleaseChar, 20)
+	for i := 0; i < b.N; i++ {
+		scanSegs(newSegScanner(strings.NewReader(input), []byte("\n"), nil, make([]byte, ReaderBufSize)))
+	}
+}
+
+// BenchmarkScannerByDelim_SmallSegs-8 	    8919	    114697 ns/op	     381 B/op	       5 allocs/op
+func BenchmarkScannerByDelim_SmallSegs(b *testing.B) {
+	input := strings.Repeat(benchInputNoCompNoReleaseChar, 20)
+	for i := 0; i < b.N; i++ {
+		s := ios.NewScannerByDelim3(strings.NewReader(input), []byte("\n"), nil,
+			ios.ScannerByDelimFlagEofNotAsDelim|ios.ScannerByDelimFlagIncludeDelimInReturn,
+			make([]byte, ReaderBufSize))
+		scanSegs(s)
+	}
+}
@@ -2,6 +2,9 @@ package edi
 
 import (
 	"github.com/jf-tech/go-corelib/maths"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 )
 
 // variable/func naming guide:
@@ -27,6 +30,9 @@ const (
 const (
 	fqdnDelim   = "/"
 	rootSegName = "#root"
+	// segNameWildcard is the name of the wildcard segment decls, which match, and skip, the segments
+	// not declared otherwise; see Reader.matchSegName.
+	segNameWildcard = "*"
 )
 
 // section {
@@ -53,6 +59,10 @@ type Elem struct {
 	EmptyIfMissing bool    `json:"empty_if_missing,omitempty"` // Deprecated, use Default
 	Default        *string `json:"default,omitempty"`
 	DefaultElement *string `json:"default_element,omitempty"`
+	Trim           *string `json:"trim,omitempty"` // overrides file_declaration level `trim`.
+
+	trim fileformat.TrimPolicy
+	skip bool // not referenced by any transform; no need to create its IDR node.
 }
 
 func (e Elem) compIndex() int {
@@ -62,6 +72,16 @@ func (e Elem) compIndex() int {
 	return *e.CompIndex
 }
 
+// Supported values, in addition to ToleranceError (the default), of the segment level `exceeds_max`
+// setting, which controls how the instances of a segment, or a segment group, occurring more than its
+// `max` times are handled. Both report a warning for each such instance.
+const (
+	// ExceedsMaxTruncate drops the extra instance, along with its child segments, if any.
+	ExceedsMaxTruncate = "truncate"
+	// ExceedsMaxAccept keeps the extra instance, as if it were within max.
+	ExceedsMaxAccept = "accept"
+)
+
 // SegDecl describes an EDI segment declaration/settings.
 type SegDecl struct {
 	Name     string     `json:"name,omitempty"`
@@ -71,13 +91,20 @@ type SegDecl struct {
 	Max      *int       `json:"max,omitempty"`
 	Elems    []Elem     `json:"elements,omitempty"`
 	Children []*SegDecl `json:"child_segments,omitempty"`
-	fqdn     string     // internal computed field
+	// ExceedsMax controls how the instances of the segment exceeding Max are handled.
+	ExceedsMax *string `json:"exceeds_max,omitempty"`
+	fqdn       string  // internal computed field
+	valueRead  bool    // internal computed field; the value of the segment, or an ancestor, is read.
 }
 
 func (d *SegDecl) isGroup() bool {
 	return d.Type != nil && *d.Type == segTypeGroup
 }
 
+func (d *SegDecl) isWildcard() bool {
+	return !d.isGroup() && d.Name == segNameWildcard
+}
+
 func (d *SegDecl) minOccurs() int {
 	switch d.Min {
 	case nil:
@@ -101,6 +128,10 @@ func (d *SegDecl) maxOccurs() int {
 	}
 }
 
+func (d *SegDecl) exceedsMax() string {
+	return strs.StrPtrOrElse(d.ExceedsMax, ToleranceError)
+}
+
 func (d *SegDecl) matchSegName(segName string) bool {
 	switch d.isGroup() {
 	case true:
@@ -0,0 +1,56 @@
+package edi
+
+import (
+	"bytes"
+)
+
+// indexWithEsc returns the index of the first delim in s[from:] that isn't escaped by esc, or -1
+// if not found. The returned index is relative to s, not s[from:]. Same as strs.ByteIndexWithEsc,
+// but with byte-optimized search for single-byte delimiters, the most common case in EDI.
+func indexWithEsc(s []byte, from int, delim, esc []byte) int {
+	for from <= len(s) {
+		var i int
+		if len(delim) == 1 {
+			i = bytes.IndexByte(s[from:], delim[0])
+		} else {
+			i = bytes.Index(s[from:], delim)
+		}
+		if i < 0 {
+			return -1
+		}
+		from += i
+		if !escapedAt(s, from, esc) {
+			return from
+		}
+		from++
+	}
+	return -1
+}
+
+// escapedAt checks if there is an effective escape sequence directly preceding s[i]. Note escape
+// sequences can escape each other, so we need to backtrack over all the consecutive ones.
+func escapedAt(s []byte, i int, esc []byte) bool {
+	if len(esc) == 0 {
+		return false
+	}
+	escFound := 0
+	for i >= len(esc) && bytes.Equal(s[i-len(esc):i], esc) {
+		escFound++
+		i -= len(esc)
+	}
+	return escFound%2 == 1
+}
+
+// splitWithEsc splits s by delim, with escape sequence esc taken into account, and appends the
+// pieces to dst. It's the allocation-free version of strs.ByteSplitWithEsc: callers can reuse dst
+// across calls. Same as strs.ByteSplitWithEsc, the pieces aren't unescaped.
+func splitWithEsc(dst [][]byte, s, delim, esc []byte) [][]byte {
+	if len(delim) == 0 {
+		return append(dst, s)
+	}
+	for i := indexWithEsc(s, 0, delim, esc); i >= 0; i = indexWithEsc(s, 0, delim, esc) {
+		dst = append(dst, s[:i])
+		s = s[i+len(delim):]
+	}
+	return append(dst, s)
+}
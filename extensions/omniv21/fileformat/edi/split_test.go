@@ -0,0 +1,92 @@
+package edi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWithEsc(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		s        string
+		delim    string
+		esc      string
+		expected []string
+	}{
+		{name: "empty", s: "", delim: "*", expected: []string{""}},
+		{name: "no delim", s: "abc", delim: "*", expected: []string{"abc"}},
+		{name: "empty delim", s: "a*b", delim: "", expected: []string{"a*b"}},
+		{name: "single byte delim", s: "ISA*00**P*>", delim: "*", expected: []string{"ISA", "00", "", "P", ">"}},
+		{name: "multi byte delim", s: "a~|b~|", delim: "~|", expected: []string{"a", "b", ""}},
+		{name: "multi byte utf-8 delim", s: "a§b", delim: "§", expected: []string{"a", "b"}},
+		{name: "escaped delim", s: "FTX+a?+b+c", delim: "+", esc: "?", expected: []string{"FTX", "a?+b", "c"}},
+		{name: "escaped escape", s: "FTX+a??+b", delim: "+", esc: "?", expected: []string{"FTX", "a??", "b"}},
+		{name: "escaped escape and delim", s: "a???+b+", delim: "+", esc: "?", expected: []string{"a???+b", ""}},
+		{name: "multi byte escape", s: "a@@+b@@@@+c", delim: "+", esc: "@@", expected: []string{"a@@+b@@@@", "c"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dst := make([][]byte, 0, 2)
+			dst = append(dst, []byte("existing"))
+			splits := splitWithEsc(dst, []byte(test.s), []byte(test.delim), []byte(test.esc))
+			var actual []string
+			for _, b := range splits[1:] {
+				actual = append(actual, string(b))
+			}
+			assert.Equal(t, "existing", string(splits[0]))
+			assert.Equal(t, test.expected, actual)
+			if test.delim != "" {
+				var fromStrs []string
+				for _, b := range strs.ByteSplitWithEsc([]byte(test.s), []byte(test.delim), []byte(test.esc), 0) {
+					fromStrs = append(fromStrs, string(b))
+				}
+				assert.Equal(t, fromStrs, actual)
+			}
+		})
+	}
+}
+
+func TestIndexWithEsc(t *testing.T) {
+	assert.Equal(t, 4, indexWithEsc([]byte("a?*b*c"), 0, []byte("*"), []byte("?")))
+	assert.Equal(t, 4, indexWithEsc([]byte("a?*b*c"), 3, []byte("*"), []byte("?")))
+	assert.Equal(t, -1, indexWithEsc([]byte("a?*b*c"), 5, []byte("*"), []byte("?")))
+	assert.Equal(t, -1, indexWithEsc([]byte("a?*b*c"), 6, []byte("*"), []byte("?")))
+	assert.Equal(t, 1, indexWithEsc([]byte("a**"), 0, []byte("**"), nil))
+}
+
+var benchSplitSeg = []byte(
+	"ISA*00*          *00*          *02*CPC            *ZZ*00602679321    *191103*1800*U*00401*000001644*0*P*>")
+
+// BenchmarkSplitWithEsc_NoEsc-8 	 5496278	       212.6 ns/op	       0 B/op	       0 allocs/op
+func BenchmarkSplitWithEsc_NoEsc(b *testing.B) {
+	dst := make([][]byte, 0, defaultElemsPerSeg)
+	for i := 0; i < b.N; i++ {
+		dst = splitWithEsc(dst[:0], benchSplitSeg, []byte("*"), nil)
+	}
+}
+
+// BenchmarkByteSplitWithEsc_NoEsc-8 	 2721728	       434.3 ns/op	     416 B/op	       1 allocs/op
+func BenchmarkByteSplitWithEsc_NoEsc(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		strs.ByteSplitWithEsc(benchSplitSeg, []byte("*"), nil, defaultElemsPerSeg)
+	}
+}
+
+var benchSplitSegWithEsc = []byte(strings.Replace(string(benchSplitSeg), "CPC", "C?*C", 1))
+
+// BenchmarkSplitWithEsc_WithEsc-8 	 4638768	       262.6 ns/op	       0 B/op	       0 allocs/op
+func BenchmarkSplitWithEsc_WithEsc(b *testing.B) {
+	dst := make([][]byte, 0, defaultElemsPerSeg)
+	for i := 0; i < b.N; i++ {
+		dst = splitWithEsc(dst[:0], benchSplitSegWithEsc, []byte("*"), []byte("?"))
+	}
+}
+
+// BenchmarkByteSplitWithEsc_WithEsc-8 	 1457662	       761.3 ns/op	     896 B/op	       1 allocs/op
+func BenchmarkByteSplitWithEsc_WithEsc(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		strs.ByteSplitWithEsc(benchSplitSegWithEsc, []byte("*"), []byte("?"), defaultElemsPerSeg)
+	}
+}
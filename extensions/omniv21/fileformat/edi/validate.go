@@ -5,13 +5,24 @@ import (
 	"fmt"
 
 	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 )
 
 type ediValidateCtx struct {
 	seenTarget bool
+	trim       *string
 }
 
 func (ctx *ediValidateCtx) validateFileDecl(fileDecl *FileDecl) error {
+	ctx.trim = fileDecl.Trim
+	if fileDecl.Dictionary != nil {
+		dict, err := fileDecl.Dictionary.resolve()
+		if err != nil {
+			return err
+		}
+		fileDecl.dict = dict
+	}
 	for _, segDecl := range fileDecl.SegDecls {
 		if err := ctx.validateSegDecl(segDecl.Name, segDecl); err != nil {
 			return err
@@ -35,9 +46,21 @@ func (ctx *ediValidateCtx) validateSegDecl(segFQDN string, segDecl *SegDecl) err
 		}
 		ctx.seenTarget = true
 	}
+	for i := range segDecl.Elems {
+		segDecl.Elems[i].trim = fileformat.ResolveTrimPolicy(segDecl.Elems[i].Trim, ctx.trim)
+	}
+	if segDecl.isWildcard() && (segDecl.IsTarget || len(segDecl.Elems) > 0 || len(segDecl.Children) > 0) {
+		return fmt.Errorf(
+			"wildcard segment '%s' cannot have 'is_target' = true, elements or child segments", segFQDN)
+	}
 	if segDecl.isGroup() && len(segDecl.Children) <= 0 {
 		return fmt.Errorf("segment_group '%s' must have at least one child segment/segment_group", segFQDN)
 	}
+	if segDecl.isGroup() && segDecl.Children[0].isWildcard() {
+		return fmt.Errorf(
+			"segment_group '%s' cannot have a wildcard segment as its first child, which identifies the group",
+			segFQDN)
+	}
 	for _, child := range segDecl.Children {
 		err := ctx.validateSegDecl(strs.BuildFQDN2(fqdnDelim, segFQDN, child.Name), child)
 		if err != nil {
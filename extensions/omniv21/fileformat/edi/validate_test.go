@@ -6,6 +6,8 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 )
 
 func TestValidateFileDecl_Empty(t *testing.T) {
@@ -45,11 +47,35 @@ func TestValidateFileDecl_SegGroupHasNoChildren(t *testing.T) {
 	assert.Equal(t, `segment_group 'A' must have at least one child segment/segment_group`, err.Error())
 }
 
+func TestValidateFileDecl_Wildcard(t *testing.T) {
+	err := (&ediValidateCtx{}).validateFileDecl(&FileDecl{
+		SegDecls: []*SegDecl{
+			{Name: "A", Children: []*SegDecl{{Name: segNameWildcard, IsTarget: true}}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t,
+		`wildcard segment 'A/*' cannot have 'is_target' = true, elements or child segments`, err.Error())
+
+	err = (&ediValidateCtx{}).validateFileDecl(&FileDecl{
+		SegDecls: []*SegDecl{
+			{Name: "A", Type: strs.StrPtr(segTypeGroup), IsTarget: true, Children: []*SegDecl{
+				{Name: segNameWildcard}, {Name: "B"},
+			}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t,
+		`segment_group 'A' cannot have a wildcard segment as its first child, which identifies the group`,
+		err.Error())
+}
+
 func TestValidateFileDecl_Success(t *testing.T) {
 	elem1 := Elem{Name: "be1", Index: 1}
 	elem2 := Elem{Name: "be2c1", Index: 2, CompIndex: testlib.IntPtr(1)}
-	elem3 := Elem{Name: "be2c2", Index: 2, CompIndex: testlib.IntPtr(2)}
+	elem3 := Elem{Name: "be2c2", Index: 2, CompIndex: testlib.IntPtr(2), Trim: strs.StrPtr("both")}
 	fd := &FileDecl{
+		Trim: strs.StrPtr("right"),
 		SegDecls: []*SegDecl{
 			{Name: "A", Children: []*SegDecl{
 				{Name: "B", IsTarget: true, Elems: []Elem{elem3, elem1, elem2}},
@@ -61,5 +87,6 @@ func TestValidateFileDecl_Success(t *testing.T) {
 	assert.Equal(t, "A", fd.SegDecls[0].fqdn)
 	assert.Nil(t, fd.SegDecls[0].Elems)
 	assert.Equal(t, "A/B", fd.SegDecls[0].Children[0].fqdn)
+	elem1.trim, elem2.trim, elem3.trim = fileformat.TrimRight, fileformat.TrimRight, fileformat.TrimBoth
 	assert.Equal(t, []Elem{elem3, elem1, elem2}, fd.SegDecls[0].Children[0].Elems)
 }
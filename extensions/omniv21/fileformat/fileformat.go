@@ -6,6 +6,7 @@ import (
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
 )
 
 // FileFormat defines a specific file format.
@@ -39,3 +40,43 @@ type FormatReader interface {
 	// file name and (approx.) error location, such as line number)
 	errs.CtxAwareErr
 }
+
+// RecordPositionReporter is an optional interface a FormatReader can implement to report the range
+// of source positions (e.g. line numbers for flat files, segment numbers for EDI) consumed to produce
+// the record returned by the most recent successful Read call. Both begin and end are 1-based and
+// inclusive. Implementing this interface allows record IDs to be derived from source positions.
+type RecordPositionReporter interface {
+	RecordPosition() (begin, end int)
+}
+
+// RecordBytesReporter is an optional interface a FormatReader can implement if the IDR nodes it returns
+// don't necessarily contain all the data of the records, e.g. when unreferenced columns are skipped. It
+// returns the raw bytes of the record returned by the most recent successful Read call, which are then
+// used, instead of the IDR node, to compute the record's checksum. nil is returned if the IDR node does
+// contain all the data of the record.
+type RecordBytesReporter interface {
+	RecordBytes() []byte
+}
+
+// RawBytesReporter is an optional interface a FormatReader can implement to expose the raw bytes of
+// the record returned by the most recent successful Read call, as read from the input. The returned
+// slice may point into the FormatReader's internal buffer, thus is READONLY and only valid until the
+// next Read call.
+type RawBytesReporter interface {
+	RawBytes() []byte
+}
+
+// WarningReporter is an optional interface a FormatReader can implement to report the problems in the
+// input it tolerated, e.g. the truncated last segment of an EDI input, during the most recent Read call,
+// regardless of its outcome. nil is returned if there is none.
+type WarningReporter interface {
+	Warnings() []error
+}
+
+// ProfileApplier is an optional interface a FileFormat can implement to apply the overrides of a
+// transformctx.Profile, such as delimiters, to the runtime data returned by ValidateSchema. It returns
+// the runtime data with the overrides applied, used for creating the FormatReader of an input stream;
+// the original runtime data must not be modified, as it's shared by all the input streams.
+type ProfileApplier interface {
+	ApplyProfile(formatRuntime interface{}, profile *transformctx.Profile) (interface{}, error)
+}
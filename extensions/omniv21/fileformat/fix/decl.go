@@ -0,0 +1,96 @@
+package fix
+
+import (
+	"fmt"
+)
+
+const defaultDelimiter = "\x01"
+
+// GroupDecl describes a repeating group of FIX messages, e.g. the parties group, whose number of
+// instances is the value of its count field, e.g. NoPartyIDs (453), and whose instances each start with
+// its first field, e.g. PartyID (448).
+type GroupDecl struct {
+	// CountTag is the tag of the field with the number of instances of the group, e.g. 453.
+	CountTag int `json:"count_tag"`
+	// Tags are the tags of the fields of the group, the first one being the field each instance starts
+	// with, e.g. [448, 447, 452].
+	Tags []int `json:"tags"`
+	// Groups are the repeating groups nested in the group, e.g. the party sub-IDs group (802) of the
+	// parties group.
+	Groups []*GroupDecl `json:"groups,omitempty"`
+
+	tags   map[int]bool
+	groups map[int]*GroupDecl // keyed by count tag.
+}
+
+func (g *GroupDecl) elemName() string {
+	return tagName(g.CountTag)
+}
+
+// FileDecl describes FIX specific schema settings for omniparser reader. All settings are optional.
+type FileDecl struct {
+	// Delimiter is the field delimiter, which defaults to SOH (0x01). Logs of FIX messages often use
+	// '|' instead.
+	Delimiter *string `json:"delimiter,omitempty"`
+	// VerifyChecksum tells if the CheckSum (10) field of each message is verified.
+	VerifyChecksum bool `json:"verify_checksum,omitempty"`
+	// Groups are the repeating groups of the messages, which become nested elements rather than flat
+	// repeated fields.
+	Groups []*GroupDecl `json:"groups,omitempty"`
+
+	groups map[int]*GroupDecl // keyed by count tag.
+}
+
+func (d *FileDecl) delimiter() byte {
+	if d.Delimiter == nil {
+		return defaultDelimiter[0]
+	}
+	return (*d.Delimiter)[0]
+}
+
+// build validates the declarations and builds the lookups of the groups.
+func (d *FileDecl) build() error {
+	if d.Delimiter != nil {
+		if c := (*d.Delimiter)[0]; c == '=' || (c >= '0' && c <= '9') || c == '\r' || c == '\n' {
+			return fmt.Errorf("'delimiter' '%s' is invalid", *d.Delimiter)
+		}
+	}
+	groups, err := buildGroups(d.Groups, nil)
+	if err != nil {
+		return err
+	}
+	d.groups = groups
+	return nil
+}
+
+func buildGroups(decls []*GroupDecl, parent *GroupDecl) (map[int]*GroupDecl, error) {
+	groups := map[int]*GroupDecl{}
+	for _, g := range decls {
+		if groups[g.CountTag] != nil {
+			return nil, fmt.Errorf("group %d is declared more than once", g.CountTag)
+		}
+		if parent != nil && parent.tags[g.CountTag] {
+			return nil, fmt.Errorf("group %d is also a field of group %d", g.CountTag, parent.CountTag)
+		}
+		g.tags = map[int]bool{}
+		for _, tag := range g.Tags {
+			if tag == g.CountTag || g.tags[tag] {
+				return nil, fmt.Errorf("group %d has invalid or duplicate field %d", g.CountTag, tag)
+			}
+			g.tags[tag] = true
+		}
+		nested, err := buildGroups(g.Groups, g)
+		if err != nil {
+			return nil, err
+		}
+		g.groups = nested
+		groups[g.CountTag] = g
+	}
+	return groups, nil
+}
+
+// tagName returns the element name of a field or group, e.g. `T35`, as element names can't start with
+// a digit.
+func tagName(tag int) string {
+	return fmt.Sprintf("T%d", tag)
+}
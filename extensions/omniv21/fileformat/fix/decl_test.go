@@ -0,0 +1,84 @@
+package fix
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	decl := &FileDecl{}
+	assert.NoError(t, decl.build())
+	assert.Equal(t, byte(0x01), decl.delimiter())
+	assert.Empty(t, decl.groups)
+
+	decl = &FileDecl{
+		Delimiter: strs.StrPtr("|"),
+		Groups: []*GroupDecl{
+			{CountTag: 453, Tags: []int{448, 447, 452}, Groups: []*GroupDecl{{CountTag: 802, Tags: []int{523, 803}}}},
+			{CountTag: 78, Tags: []int{79, 80}},
+		},
+	}
+	assert.NoError(t, decl.build())
+	assert.Equal(t, byte('|'), decl.delimiter())
+	assert.Equal(t, 2, len(decl.groups))
+	assert.Equal(t, "T453", decl.groups[453].elemName())
+	assert.True(t, decl.groups[453].tags[452])
+	assert.Equal(t, []int{523, 803}, decl.groups[453].groups[802].Tags)
+	assert.True(t, decl.groups[453].groups[802].tags[803])
+	assert.Equal(t, []int{79, 80}, decl.groups[78].Tags)
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		decl   *FileDecl
+		expErr string
+	}{
+		{
+			name:   "invalid delimiter",
+			decl:   &FileDecl{Delimiter: strs.StrPtr("=")},
+			expErr: "'delimiter' '=' is invalid",
+		},
+		{
+			name:   "digit delimiter",
+			decl:   &FileDecl{Delimiter: strs.StrPtr("1")},
+			expErr: "'delimiter' '1' is invalid",
+		},
+		{
+			name: "duplicate group",
+			decl: &FileDecl{Groups: []*GroupDecl{
+				{CountTag: 453, Tags: []int{448}}, {CountTag: 453, Tags: []int{447}}}},
+			expErr: "group 453 is declared more than once",
+		},
+		{
+			name: "nested group count tag also a field",
+			decl: &FileDecl{Groups: []*GroupDecl{
+				{CountTag: 453, Tags: []int{448, 802}, Groups: []*GroupDecl{{CountTag: 802, Tags: []int{523}}}}}},
+			expErr: "group 802 is also a field of group 453",
+		},
+		{
+			name:   "duplicate field",
+			decl:   &FileDecl{Groups: []*GroupDecl{{CountTag: 453, Tags: []int{448, 447, 448}}}},
+			expErr: "group 453 has invalid or duplicate field 448",
+		},
+		{
+			name:   "count tag as field",
+			decl:   &FileDecl{Groups: []*GroupDecl{{CountTag: 453, Tags: []int{453}}}},
+			expErr: "group 453 has invalid or duplicate field 453",
+		},
+		{
+			name: "invalid nested group",
+			decl: &FileDecl{Groups: []*GroupDecl{
+				{CountTag: 453, Tags: []int{448}, Groups: []*GroupDecl{{CountTag: 802, Tags: []int{523, 523}}}}}},
+			expErr: "group 802 has invalid or duplicate field 523",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.decl.build()
+			assert.Error(t, err)
+			assert.Equal(t, test.expErr, err.Error())
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package fix
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/validation"
+)
+
+const (
+	fileFormatFIX = "fix"
+)
+
+type fixFileFormat struct {
+	schemaName string
+}
+
+// NewFIXFileFormat creates a FileFormat for FIX tag=value messages.
+func NewFIXFileFormat(schemaName string) fileformat.FileFormat {
+	return &fixFileFormat{schemaName: schemaName}
+}
+
+type fixFormatRuntime struct {
+	Decl  *FileDecl `json:"file_declaration"`
+	XPath string
+}
+
+func (f *fixFileFormat) ValidateSchema(
+	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatFIX {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	err := validation.SchemaValidate(f.schemaName, schemaContent, v21validation.JSONSchemaFIXFileDeclaration)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	var runtime fixFormatRuntime
+	_ = json.Unmarshal(schemaContent, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if runtime.Decl == nil {
+		// file_declaration is optional.
+		runtime.Decl = &FileDecl{}
+	}
+	err = runtime.Decl.build()
+	if err != nil {
+		return nil, f.FmtErr("%s", err.Error())
+	}
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if runtime.XPath != "" {
+		_, err := caches.GetXPathExpr(runtime.XPath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s",
+				runtime.XPath, err.Error())
+		}
+	}
+	return &runtime, nil
+}
+
+func (f *fixFileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	rt := runtime.(*fixFormatRuntime)
+	return NewReader(name, r, rt.Decl, rt.XPath)
+}
+
+func (f *fixFileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,78 @@
+package fix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatFIX,
+			schema:      `{"file_declaration": { "delimiter": "||" }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.delimiter: String length must be less than or equal to 1`,
+		},
+		{
+			name:   "file_declaration validation fail",
+			format: fileFormatFIX,
+			schema: `{"file_declaration": { "groups": [
+				{ "count_tag": 453, "tags": [ 448 ] }, { "count_tag": 453, "tags": [ 447 ] } ] }}`,
+			expectedErr: `schema 'test-schema': group 453 is declared more than once`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatFIX,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatFIX,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatFIX,
+			schema: `{"file_declaration": { "delimiter": "|", "groups": [ { "count_tag": 453, "tags": [ 448, 447 ] } ] }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[T35='8'] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewFIXFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*fixFormatRuntime)
+			assert.Equal(t, ".[T35='8']", rt.XPath)
+			assert.Equal(t, byte('|'), rt.Decl.delimiter())
+			assert.NotNil(t, rt.Decl.groups[453])
+			r, err := NewFIXFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidFIX indicates the input can't be read. This is a fatal, non-continuable error. Note errors
+// in a message (e.g. a group with fewer instances than its count field says) are continuable: the reader
+// simply moves onto the next message.
+type ErrInvalidFIX string
+
+func (e ErrInvalidFIX) Error() string { return string(e) }
+
+// IsErrInvalidFIX checks if the `err` is of ErrInvalidFIX type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidFIX(err error) bool {
+	var e ErrInvalidFIX
+	return errors.As(err, &e)
+}
+
+const (
+	// beginString is how a message starts, i.e. its BeginString (8) field, e.g. `8=FIX.4.4` or
+	// `8=FIXT.1.1`.
+	beginString = "8=FIX"
+	// maxFieldSize is the max size of a field, e.g. of an XmlData (213) field.
+	maxFieldSize = 64 * 1024 * 1024
+)
+
+type field struct {
+	tag   int
+	value string
+}
+
+type reader struct {
+	inputName   string
+	s           *bufio.Scanner
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	msgNum      int    // 1-based number of the last message read.
+	pending     string // a BeginString field read ahead, which starts the next message.
+}
+
+// Read returns the next message as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		fields, err := r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		n, err := r.buildMessage(fields)
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+// splitFields returns a bufio.SplitFunc returning the fields terminated by the delimiter or by line
+// breaks, as messages are commonly logged one per line. The empty ones in between are skipped by
+// nextField.
+func splitFields(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		for i, c := range data {
+			if c == delim || c == '\n' || c == '\r' {
+				return i + 1, data[:i], nil
+			}
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+func (r *reader) nextField() (string, error) {
+	for r.s.Scan() {
+		if f := r.s.Text(); f != "" {
+			return f, nil
+		}
+	}
+	if err := r.s.Err(); err != nil {
+		return "", r.invalidFIX(r.msgNum, "unable to read field: %s", err.Error())
+	}
+	return "", io.EOF
+}
+
+// readMessage returns the fields of the next message, from its BeginString (8) field till its CheckSum
+// (10) field. Anything before the BeginString field, e.g. the timestamps of a log, is skipped.
+func (r *reader) readMessage() ([]string, error) {
+	begin := r.pending
+	r.pending = ""
+	for begin == "" {
+		f, err := r.nextField()
+		if err != nil {
+			return nil, err
+		}
+		if i := strings.Index(f, beginString); i >= 0 {
+			begin = f[i:]
+		}
+	}
+	r.msgNum++
+	fields := []string{begin}
+	for {
+		f, err := r.nextField()
+		if err == io.EOF {
+			return nil, r.fmtErr("message isn't terminated by a CheckSum (10) field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(f, "8=") {
+			r.pending = f
+			return nil, r.fmtErr("message isn't terminated by a CheckSum (10) field")
+		}
+		fields = append(fields, f)
+		if strings.HasPrefix(f, "10=") {
+			return fields, nil
+		}
+	}
+}
+
+// checksum returns the checksum of the fields, i.e. the sum of their bytes, each followed by a SOH, as
+// the standard defines it, whatever the delimiter, modulo 256.
+func checksum(fields []string) int {
+	sum := 0
+	for _, f := range fields {
+		for i := 0; i < len(f); i++ {
+			sum += int(f[i])
+		}
+		sum++ // the SOH.
+	}
+	return sum % 256
+}
+
+func (r *reader) buildMessage(raw []string) (*idr.Node, error) {
+	fields := make([]field, len(raw))
+	for i, f := range raw {
+		eq := strings.IndexByte(f, '=')
+		if eq < 0 {
+			return nil, r.fmtErr("invalid field '%s'", f)
+		}
+		tag, err := strconv.Atoi(f[:eq])
+		if err != nil || tag <= 0 {
+			return nil, r.fmtErr("invalid field '%s'", f)
+		}
+		fields[i] = field{tag: tag, value: f[eq+1:]}
+	}
+	if r.decl.VerifyChecksum {
+		last := fields[len(fields)-1].value
+		if expected := checksum(raw[:len(raw)-1]); last != fmt.Sprintf("%03d", expected) {
+			return nil, r.fmtErr("CheckSum (10) '%s' doesn't match the message's checksum '%03d'", last, expected)
+		}
+	}
+	root := idr.CreateNode(idr.DocumentNode, "")
+	b := &msgBuilder{fields: fields}
+	if err := b.addFields(root, r.decl.groups); err != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, r.fmtErr("%s", err.Error())
+	}
+	return root, nil
+}
+
+// msgBuilder builds the IDR of a message from its fields.
+type msgBuilder struct {
+	fields []field
+	pos    int // position of the next field to add.
+}
+
+// addFields adds an element for each of the fields of a message, named after its tag, e.g. `T35`, in
+// the order they appear, with the fields of the declared repeating groups nested in an element for
+// each of their instances.
+func (b *msgBuilder) addFields(parent *idr.Node, groups map[int]*GroupDecl) error {
+	for b.pos < len(b.fields) {
+		f := b.fields[b.pos]
+		b.pos++
+		if g := groups[f.tag]; g != nil {
+			if err := b.addGroup(parent, g, f.value); err != nil {
+				return err
+			}
+			continue
+		}
+		addText(parent, tagName(f.tag), f.value)
+	}
+	return nil
+}
+
+// addGroup adds an element, named after the group's count field, e.g. `T453`, for each of the count
+// instances of the group, starting from the current position.
+func (b *msgBuilder) addGroup(parent *idr.Node, g *GroupDecl, countValue string) error {
+	count, err := strconv.Atoi(countValue)
+	if err != nil || count < 0 {
+		return fmt.Errorf("invalid number of instances '%s' of group %d", countValue, g.CountTag)
+	}
+	first := g.Tags[0]
+	for i := 0; i < count; i++ {
+		if b.pos >= len(b.fields) || b.fields[b.pos].tag != first {
+			return fmt.Errorf("group %d has %d instance(s), expected %d", g.CountTag, i, count)
+		}
+		instance := idr.CreateNode(idr.ElementNode, g.elemName())
+		idr.AddChild(parent, instance)
+		for started := false; b.pos < len(b.fields); started = true {
+			f := b.fields[b.pos]
+			if started && f.tag == first {
+				// the next instance.
+				break
+			}
+			if sub := g.groups[f.tag]; sub != nil {
+				b.pos++
+				if err := b.addGroup(instance, sub, f.value); err != nil {
+					return err
+				}
+				continue
+			}
+			if !g.tags[f.tag] {
+				// the end of the group.
+				break
+			}
+			b.pos++
+			addText(instance, tagName(f.tag), f.value)
+		}
+	}
+	if b.pos < len(b.fields) && b.fields[b.pos].tag == first {
+		return fmt.Errorf("group %d has more than %d instance(s)", g.CountTag, count)
+	}
+	return nil
+}
+
+func addText(parent *idr.Node, name, value string) {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the number of the message
+// returned by the last successful Read call, as both begin and end.
+func (r *reader) RecordPosition() (int, int) {
+	return r.msgNum, r.msgNum
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidFIX(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return r.fmtErr(format, args...)
+}
+
+func (r *reader) fmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.msgNum, format, args...))
+}
+
+func (r *reader) fmtErrStr(msgNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' message %d: %s", r.inputName, msgNum, fmt.Sprintf(format, args...))
+}
+
+// invalidFIX creates an ErrInvalidFIX, wrapped in an errs.ErrInput.
+func (r *reader) invalidFIX(msgNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format:  fileFormatFIX,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: msgNum,
+		Reason:  reason,
+		Err:     ErrInvalidFIX(r.fmtErrStr(msgNum, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for FIX file format.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	s := bufio.NewScanner(src)
+	s.Buffer(nil, maxFieldSize)
+	s.Split(splitFields(decl.delimiter()))
+	return &reader{
+		inputName:   inputName,
+		s:           s,
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
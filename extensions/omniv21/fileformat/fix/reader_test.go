@@ -0,0 +1,237 @@
+package fix
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+func testDecl(t *testing.T, delim string, verifyChecksum bool) *FileDecl {
+	decl := &FileDecl{
+		VerifyChecksum: verifyChecksum,
+		Groups: []*GroupDecl{
+			{CountTag: 453, Tags: []int{448, 447, 452}, Groups: []*GroupDecl{{CountTag: 802, Tags: []int{523, 803}}}},
+		},
+	}
+	if delim != "" {
+		decl.Delimiter = strs.StrPtr(delim)
+	}
+	assert.NoError(t, decl.build())
+	return decl
+}
+
+const (
+	testOrder     = "8=FIX.4.4|9=75|35=D|11=ORD1|453=2|448=TRADER1|447=D|452=11|448=BROKER1|447=D|452=1|55=IBM|10=092|"
+	testOrderJSON = `{"T10":"092","T11":"ORD1","T35":"D","T453":[{"T447":"D","T448":"TRADER1","T452":"11"},` +
+		`{"T447":"D","T448":"BROKER1","T452":"1"}],"T55":"IBM","T8":"FIX.4.4","T9":"75"}`
+	testHeartbeat     = "8=FIX.4.4|9=5|35=0|10=163|"
+	testHeartbeatJSON = `{"T10":"163","T35":"0","T8":"FIX.4.4","T9":"5"}`
+)
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		delim    string
+		xpath    string
+		expected []string
+	}{
+		{
+			name:     "multiple messages",
+			input:    testOrder + testHeartbeat,
+			delim:    "|",
+			expected: []string{testOrderJSON, testHeartbeatJSON},
+		},
+		{
+			name:     "SOH delimited",
+			input:    strings.ReplaceAll(testOrder+testHeartbeat, "|", "\x01"),
+			expected: []string{testOrderJSON, testHeartbeatJSON},
+		},
+		{
+			name:     "log lines",
+			input:    "20201225-12:00:00.000 : " + testHeartbeat + "\r\n20201225-12:00:01.000 : " + testOrder + "\r\n",
+			delim:    "|",
+			expected: []string{testHeartbeatJSON, testOrderJSON},
+		},
+		{
+			name:     "with xpath",
+			input:    testOrder + testHeartbeat,
+			delim:    "|",
+			xpath:    ".[T35='0']",
+			expected: []string{testHeartbeatJSON},
+		},
+		{
+			name:  "nested groups",
+			input: "8=FIX.4.4|35=8|453=1|448=P1|447=D|452=3|802=2|523=S1|803=10|523=S2|803=11|55=IBM|10=000|",
+			delim: "|",
+			expected: []string{`{"T10":"000","T35":"8","T453":{"T447":"D","T448":"P1","T452":"3",` +
+				`"T802":[{"T523":"S1","T803":"10"},{"T523":"S2","T803":"11"}]},"T55":"IBM","T8":"FIX.4.4"}`},
+		},
+		{
+			name:     "group with no instances",
+			input:    "8=FIX.4.4|35=8|453=0|55=IBM|10=000|",
+			delim:    "|",
+			expected: []string{`{"T10":"000","T35":"8","T55":"IBM","T8":"FIX.4.4"}`},
+		},
+		{
+			name:     "no message",
+			input:    " \r\n",
+			delim:    "|",
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testDecl(t, test.delim, false), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_VerifyChecksum(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(testOrder+strings.Replace(testHeartbeat, "10=163", "10=164", 1)+testOrder),
+		testDecl(t, "|", true), "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, testOrderJSON, idr.JSONify2(n))
+	n, err = r.Read()
+	assert.Error(t, err)
+	assert.True(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' message 2: CheckSum (10) '164' doesn't match the message's checksum '163'", err.Error())
+	assert.Nil(t, n)
+	n, err = r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, testOrderJSON, idr.JSONify2(n))
+}
+
+func TestRead_MessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		msg    string
+		expErr string
+	}{
+		{
+			name:   "invalid field",
+			msg:    "8=FIX.4.4|35=0|hello|10=000|",
+			expErr: "input 'test-input' message 1: invalid field 'hello'",
+		},
+		{
+			name:   "invalid tag",
+			msg:    "8=FIX.4.4|35=0|X=1|10=000|",
+			expErr: "input 'test-input' message 1: invalid field 'X=1'",
+		},
+		{
+			name:   "no checksum before next message",
+			msg:    "8=FIX.4.4|35=0|",
+			expErr: "input 'test-input' message 1: message isn't terminated by a CheckSum (10) field",
+		},
+		{
+			name:   "invalid number of instances",
+			msg:    "8=FIX.4.4|35=8|453=X|10=000|",
+			expErr: "input 'test-input' message 1: invalid number of instances 'X' of group 453",
+		},
+		{
+			name:   "fewer instances",
+			msg:    "8=FIX.4.4|35=8|453=2|448=P1|447=D|55=IBM|10=000|",
+			expErr: "input 'test-input' message 1: group 453 has 1 instance(s), expected 2",
+		},
+		{
+			name:   "more instances",
+			msg:    "8=FIX.4.4|35=8|453=1|448=P1|448=P2|10=000|",
+			expErr: "input 'test-input' message 1: group 453 has more than 1 instance(s)",
+		},
+		{
+			name:   "invalid nested group",
+			msg:    "8=FIX.4.4|35=8|453=1|448=P1|802=1|803=10|10=000|",
+			expErr: "input 'test-input' message 1: group 802 has 0 instance(s), expected 1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.msg+testHeartbeat), testDecl(t, "|", false), "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+			// reader moves onto the next message.
+			n, err = r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, testHeartbeatJSON, idr.JSONify2(n))
+			assert.Equal(t, "input 'test-input' message 2: test", r.FmtErr("test").Error())
+			begin, end := r.RecordPosition()
+			assert.Equal(t, 2, begin)
+			assert.Equal(t, 2, end)
+		})
+	}
+}
+
+func TestRead_NotTerminatedAtEOF(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(testHeartbeat+"8=FIX.4.4|35=0|"), testDecl(t, "|", false), "")
+	assert.NoError(t, err)
+	records, err := readAll(t, r)
+	assert.Equal(t, []string{testHeartbeatJSON}, records)
+	assert.Error(t, err)
+	assert.True(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' message 2: message isn't terminated by a CheckSum (10) field", err.Error())
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", io.MultiReader(strings.NewReader(testHeartbeat), failingReader{}),
+		testDecl(t, "|", false), "")
+	assert.NoError(t, err)
+	records, err := readAll(t, r)
+	assert.Equal(t, []string{testHeartbeatJSON}, records)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidFIX(err))
+	assert.False(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' message 1: unable to read field: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testDecl(t, "", false), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestChecksum(t *testing.T) {
+	assert.Equal(t, 163, checksum([]string{"8=FIX.4.4", "9=5", "35=0"}))
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidFIX("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/jf-tech/go-corelib/caches"
 	"github.com/jf-tech/go-corelib/ios"
 
+	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -19,14 +20,11 @@ type ErrInvalidEnvelope string
 
 func (e ErrInvalidEnvelope) Error() string { return string(e) }
 
-// IsErrInvalidEnvelope checks if an error is of ErrInvalidEnvelope type.
+// IsErrInvalidEnvelope checks if an error is of ErrInvalidEnvelope type, or wraps one, e.g. in an
+// errs.ErrInput.
 func IsErrInvalidEnvelope(err error) bool {
-	switch err.(type) {
-	case ErrInvalidEnvelope:
-		return true
-	default:
-		return false
-	}
+	var e ErrInvalidEnvelope
+	return errors.As(err, &e)
 }
 
 type reader struct {
@@ -68,8 +66,8 @@ func (r *reader) readByRowsEnvelope() (*idr.Node, error) {
 			if err == io.EOF && i == 0 {
 				return nil, err
 			}
-			return nil, ErrInvalidEnvelope(
-				r.fmtErrStr("incomplete envelope, missing %d row(s)", envelopeDecl.byRows()-i))
+			return nil, r.invalidEnvelope(
+				"incomplete envelope, missing %d row(s)", envelopeDecl.byRows()-i)
 		}
 		for col := range envelopeDecl.Columns {
 			if columnsDone[col] {
@@ -95,7 +93,7 @@ func (r *reader) readByHeaderFooterEnvelope() (*idr.Node, error) {
 		if err == io.EOF {
 			return nil, err
 		}
-		return nil, ErrInvalidEnvelope(r.fmtErrStr("incomplete envelope: %s", err.Error()))
+		return nil, r.invalidEnvelope("incomplete envelope: %s", err.Error())
 	}
 	for ; r.envelopeIndex < len(r.decl.Envelopes); r.envelopeIndex++ {
 		// regex is already validated
@@ -132,7 +130,7 @@ func (r *reader) readByHeaderFooterEnvelope() (*idr.Node, error) {
 		line, err = r.readLine()
 		// Since the envelope has started, any reading error, including EOF, indicates incomplete envelope error.
 		if err != nil {
-			return nil, ErrInvalidEnvelope(r.fmtErrStr("incomplete envelope: %s", err.Error()))
+			return nil, r.invalidEnvelope("incomplete envelope: %s", err.Error())
 		}
 	}
 }
@@ -191,6 +189,19 @@ func (r *reader) fmtErrStr(format string, args ...interface{}) string {
 	return fmt.Sprintf("input '%s' line %d: %s", r.inputName, r.line, fmt.Sprintf(format, args...))
 }
 
+// invalidEnvelope creates an ErrInvalidEnvelope, wrapped in an errs.ErrInput.
+func (r *reader) invalidEnvelope(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatFixedLength,
+		Input:  r.inputName,
+		Line:   r.line,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidEnvelope(r.fmtErrStr("%s", reason)),
+	}
+}
+
 // NewReader creates an FormatReader for fixed-length file format.
 func NewReader(inputName string, r io.Reader, decl *FileDecl, xpathStr string) (*reader, error) {
 	var expr *xpath.Expr
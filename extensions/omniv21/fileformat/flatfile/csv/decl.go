@@ -3,11 +3,14 @@ package csv
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/jf-tech/go-corelib/maths"
 
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
 )
 
 // ColumnDecl describes a column of an csv record column.
@@ -16,8 +19,69 @@ type ColumnDecl struct {
 	Index       *int    `json:"index,omitempty"`        // 1-based. optional.
 	LineIndex   *int    `json:"line_index,omitempty"`   // 1-based. optional
 	LinePattern *string `json:"line_pattern,omitempty"` // optional
+	Trim        *string `json:"trim,omitempty"`         // optional. overrides file_declaration level `trim`.
+	// Validation, if not nil, declares the constraints the column values must satisfy, or the target
+	// record read along with them is rejected with an ErrInvalidColumns.
+	Validation *ColumnValidation `json:"validation,omitempty"`
 
 	linePatternRegexp *regexp.Regexp
+	skip              bool // not referenced by any transform; no need to create its IDR node.
+	trim              fileformat.TrimPolicy
+}
+
+// ColumnValidation declares the constraints on the values, after trimming, of a column. All the
+// constraints but Required apply to non-empty values only.
+type ColumnValidation struct {
+	// Required rejects empty values, including the ones of columns missing from the line.
+	Required bool `json:"required,omitempty"`
+	// Pattern is a regexp the values must match.
+	Pattern *string `json:"pattern,omitempty"`
+	// Enum is the list of the values allowed.
+	Enum []string `json:"enum,omitempty"`
+	// Min and Max are the inclusive range of the values, which must be numbers if either is specified.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+
+	patternRegexp *regexp.Regexp
+}
+
+// violation returns why a column value fails the validation, or "" if it doesn't.
+func (v *ColumnValidation) violation(value string) string {
+	if value == "" {
+		if v.Required {
+			return "is required"
+		}
+		return ""
+	}
+	if v.patternRegexp != nil && !v.patternRegexp.MatchString(value) {
+		return fmt.Sprintf("does not match pattern '%s'", *v.Pattern)
+	}
+	if len(v.Enum) > 0 {
+		found := false
+		for _, e := range v.Enum {
+			if value == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("is not one of ['%s']", strings.Join(v.Enum, "', '"))
+		}
+	}
+	if v.Min == nil && v.Max == nil {
+		return ""
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "is not a number"
+	}
+	if v.Min != nil && f < *v.Min {
+		return fmt.Sprintf("is less than min %s", strconv.FormatFloat(*v.Min, 'f', -1, 64))
+	}
+	if v.Max != nil && f > *v.Max {
+		return fmt.Sprintf("is greater than max %s", strconv.FormatFloat(*v.Max, 'f', -1, 64))
+	}
+	return ""
 }
 
 func (c *ColumnDecl) lineMatch(lineIndex int, line *line, records []string, delim string) bool {
@@ -58,6 +122,8 @@ type RecordDecl struct {
 	Max      *int          `json:"max,omitempty"`
 	Columns  []*ColumnDecl `json:"columns,omitempty"`
 	Children []*RecordDecl `json:"child_records,omitempty"`
+	// Totals are checked against the records read since the previous instance of this record.
+	Totals []*flatfile.TotalDecl `json:"totals,omitempty"`
 
 	fqdn          string // fully hierarchical name to the record.
 	childRecDecls []flatfile.RecDecl
@@ -103,6 +169,20 @@ func (r *RecordDecl) ChildDecls() []flatfile.RecDecl {
 	return r.childRecDecls
 }
 
+// TotalDecls implements flatfile.TotalsDecl.
+func (r *RecordDecl) TotalDecls() []*flatfile.TotalDecl {
+	return r.Totals
+}
+
+func (r *RecordDecl) hasColumn(name string) bool {
+	for _, c := range r.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *RecordDecl) rowsBased() bool {
 	if r.Group() {
 		panic("record_group is neither rows based nor header/footer based")
@@ -159,7 +239,49 @@ func toFlatFileRecDecls(rs []*RecordDecl) []flatfile.RecDecl {
 
 // FileDecl describes csv/delimited schema `file_declaration` setting.
 type FileDecl struct {
-	Delimiter           string        `json:"delimiter,omitempty"`
-	ReplaceDoubleQuotes bool          `json:"replace_double_quotes,omitempty"`
-	Records             []*RecordDecl `json:"records,omitempty"`
+	Delimiter               string        `json:"delimiter,omitempty"`
+	ReplaceDoubleQuotes     bool          `json:"replace_double_quotes,omitempty"`
+	LazyQuotes              bool          `json:"lazy_quotes,omitempty"`
+	QuoteEscape             *string       `json:"quote_escape,omitempty"`
+	SkipUnreferencedColumns bool          `json:"skip_unreferenced_columns,omitempty"`
+	Trim                    *string       `json:"trim,omitempty"`
+	LineEnding              *string       `json:"line_ending,omitempty"`
+	SkipLines               int           `json:"skip_lines,omitempty"`
+	Comment                 *string       `json:"comment,omitempty"`
+	SkipTrailingLines       int           `json:"skip_trailing_lines,omitempty"`
+	Records                 []*RecordDecl `json:"records,omitempty"`
+
+	skipping bool // true if any column is marked as skipped.
+}
+
+// markSkippedColumns marks the columns that are not referenced by any transform or totals as skipped,
+// unless the value of any of their enclosing records is read as a whole.
+func (f *FileDecl) markSkippedColumns(refs *transform.References) {
+	// columns used by totals must be read, referenced by transforms or not.
+	totalsColumns := map[string]bool{}
+	var markTotals func(records []*RecordDecl)
+	markTotals = func(records []*RecordDecl) {
+		for _, r := range records {
+			for _, t := range r.Totals {
+				totalsColumns[r.Name+"/"+t.Column] = true
+				if t.SumColumn != nil {
+					totalsColumns[t.Source()+"/"+*t.SumColumn] = true
+				}
+			}
+			markTotals(r.Children)
+		}
+	}
+	markTotals(f.Records)
+	var mark func(records []*RecordDecl, valueRead bool)
+	mark = func(records []*RecordDecl, valueRead bool) {
+		for _, r := range records {
+			valueRead := valueRead || refs.ValueRead(r.Name)
+			for _, c := range r.Columns {
+				c.skip = !valueRead && !refs.Referenced(c.Name) && !totalsColumns[r.Name+"/"+c.Name]
+				f.skipping = f.skipping || c.skip
+			}
+			mark(r.Children, valueRead)
+		}
+	}
+	mark(f.Records, false)
 }
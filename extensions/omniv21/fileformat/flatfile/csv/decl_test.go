@@ -35,6 +35,44 @@ func TestColumnDecl_LineToColumnValue(t *testing.T) {
 		[]string{"1", "2", "3", "4", "5", "6", "7", "8", "9"})) // in range
 }
 
+func TestColumnValidation_Violation(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		validation ColumnValidation
+		value      string
+		expected   string
+	}{
+		{name: "empty, not required", validation: ColumnValidation{Enum: []string{"A"}}, value: "", expected: ""},
+		{name: "empty, required", validation: ColumnValidation{Required: true}, value: "", expected: "is required"},
+		{
+			name:       "pattern mismatch",
+			validation: ColumnValidation{Pattern: strs.StrPtr("^[A-Z]+$"), patternRegexp: regexp.MustCompile("^[A-Z]+$")},
+			value:      "abc",
+			expected:   "does not match pattern '^[A-Z]+$'",
+		},
+		{
+			name:       "pattern match",
+			validation: ColumnValidation{Pattern: strs.StrPtr("^[A-Z]+$"), patternRegexp: regexp.MustCompile("^[A-Z]+$")},
+			value:      "ABC",
+			expected:   "",
+		},
+		{name: "not in enum", validation: ColumnValidation{Enum: []string{"A", "B"}}, value: "C", expected: "is not one of ['A', 'B']"},
+		{name: "in enum", validation: ColumnValidation{Enum: []string{"A", "B"}}, value: "B", expected: ""},
+		{name: "not a number", validation: ColumnValidation{Min: floatPtr(0)}, value: "x", expected: "is not a number"},
+		{name: "less than min", validation: ColumnValidation{Min: floatPtr(0.5)}, value: "0.25", expected: "is less than min 0.5"},
+		{name: "greater than max", validation: ColumnValidation{Max: floatPtr(100)}, value: "1e3", expected: "is greater than max 100"},
+		{name: "in range", validation: ColumnValidation{Min: floatPtr(-1), Max: floatPtr(1)}, value: "-1", expected: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.validation.violation(test.value))
+		})
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
 func TestRecordDecl(t *testing.T) {
 	// DeclName()
 	r := &RecordDecl{Name: "r1"}
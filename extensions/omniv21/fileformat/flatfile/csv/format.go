@@ -56,6 +56,11 @@ func (f *csvFormat) ValidateSchema(
 	if finalOutputDecl == nil {
 		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
 	}
+	if runtime.Decl.SkipUnreferencedColumns {
+		if refs := transform.AnalyzeReferences(finalOutputDecl); refs != nil {
+			runtime.Decl.markSkippedColumns(refs)
+		}
+	}
 	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
 	if runtime.XPath != "" {
 		_, err := caches.GetXPathExpr(runtime.XPath)
@@ -68,13 +73,35 @@ func (f *csvFormat) ValidateSchema(
 }
 
 func (f *csvFormat) validateFileDecl(decl *FileDecl) error {
-	err := (&validateCtx{}).validateFileDecl(decl)
+	err := decl.Validate()
 	if err != nil {
 		return f.FmtErr(err.Error())
 	}
 	return err
 }
 
+// ParseFileDecl parses and validates a csv2 `file_declaration`, in JSON, for creating Readers with
+// NewReader without an omni schema.
+func ParseFileDecl(declJSON []byte) (*FileDecl, error) {
+	content := []byte(`{"file_declaration":` + string(declJSON) + `}`)
+	err := validation.SchemaValidate("file_declaration", content, v21validation.JSONSchemaCSV2FileDeclaration)
+	if err != nil {
+		return nil, err
+	}
+	var runtime csvFormatRuntime
+	_ = json.Unmarshal(content, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if err = runtime.Decl.Validate(); err != nil {
+		return nil, err
+	}
+	return runtime.Decl, nil
+}
+
+// Validate validates a csv2 `file_declaration` built in code, compiling its record header regexps and
+// designating the first record as the target if none is.
+func (d *FileDecl) Validate() error {
+	return (&validateCtx{}).validateFileDecl(d)
+}
+
 func (f *csvFormat) CreateFormatReader(
 	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
 	rt := runtime.(*csvFormatRuntime)
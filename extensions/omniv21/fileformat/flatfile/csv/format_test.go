@@ -1,6 +1,8 @@
 package csv
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
 )
@@ -310,3 +313,511 @@ func TestCreateFormatReader(t *testing.T) {
 		err.Error())
 	assert.Nil(t, reader)
 }
+
+func TestCreateFormatReader_SkipUnreferencedColumns(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations([]byte(`
+		{
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "xpath": ".[c1 != 'x']", "object": { "c2": { "xpath": "c2" } } }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	for _, skip := range []bool{false, true} {
+		format := NewCSVFileFormat("test-schema")
+		runtime, err := format.ValidateSchema(
+			fileFormatCSV,
+			[]byte(fmt.Sprintf(`
+				{
+					"file_declaration": {
+						"delimiter": "|",
+						"skip_unreferenced_columns": %t,
+						"records" : [
+							{
+								"columns": [
+									{ "name": "c1", "index": 1 },
+									{ "name": "c2", "index": 2 },
+									{ "name": "c3", "index": 3 }
+								]
+							}
+						]
+					}
+				}`, skip)),
+			finalOutputDecl)
+		assert.NoError(t, err)
+		r, err := format.CreateFormatReader("test-input", strings.NewReader("a|b|c\nd|e|f\n"), runtime)
+		assert.NoError(t, err)
+		n, err := r.Read()
+		assert.NoError(t, err)
+		if skip {
+			assert.Equal(t, `{"c1":"a","c2":"b"}`, idr.JSONify2(n))
+			assert.Equal(t, "a|b|c\n", string(r.(*Reader).RecordBytes()))
+		} else {
+			assert.Equal(t, `{"c1":"a","c2":"b","c3":"c"}`, idr.JSONify2(n))
+			assert.Nil(t, r.(*Reader).RecordBytes())
+		}
+		r.Release(n)
+		n, err = r.Read()
+		assert.NoError(t, err)
+		if skip {
+			assert.Equal(t, "d|e|f\n", string(r.(*Reader).RecordBytes()))
+		}
+		r.Release(n)
+	}
+}
+
+func TestCreateFormatReader_Trim(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	format := NewCSVFileFormat("test-schema")
+	runtime, err := format.ValidateSchema(
+		fileFormatCSV,
+		[]byte(`
+			{
+				"file_declaration": {
+					"delimiter": "|",
+					"trim": "both",
+					"records" : [
+						{
+							"columns": [
+								{ "name": "c1", "index": 1 },
+								{ "name": "c2", "index": 2, "trim": "collapse" },
+								{ "name": "c3", "index": 3, "trim": "none" }
+							]
+						}
+					]
+				}
+			}`),
+		finalOutputDecl)
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader("test-input", strings.NewReader(" a | b  c | d \n"), runtime)
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c1":"a","c2":"b c","c3":" d "}`, idr.JSONify2(n))
+}
+
+func TestCreateFormatReader_LineEnding(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name     string
+		setting  string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "default: LF and CRLF",
+			setting:  ``,
+			input:    "a|b\r\nc|d\n",
+			expected: []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`},
+		},
+		{
+			name:     "cr",
+			setting:  `"line_ending": "cr",`,
+			input:    "a|b\rc|d\r",
+			expected: []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`},
+		},
+		{
+			name:     "mixed",
+			setting:  `"line_ending": "mixed",`,
+			input:    "a|b\rc|d\r\ne|f\ng|h",
+			expected: []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`, `{"c1":"e","c2":"f"}`, `{"c1":"g","c2":"h"}`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			format := NewCSVFileFormat("test-schema")
+			runtime, err := format.ValidateSchema(
+				fileFormatCSV,
+				[]byte(`
+					{
+						"file_declaration": {
+							"delimiter": "|",
+							`+test.setting+`
+							"records" : [
+								{ "columns": [ { "name": "c1", "index": 1 }, { "name": "c2", "index": 2 } ] }
+							]
+						}
+					}`),
+				finalOutputDecl)
+			assert.NoError(t, err)
+			r, err := format.CreateFormatReader("test-input", strings.NewReader(test.input), runtime)
+			assert.NoError(t, err)
+			var records []string
+			for {
+				n, err := r.Read()
+				if err != nil {
+					assert.Equal(t, io.EOF, err)
+					break
+				}
+				records = append(records, idr.JSONify2(n))
+				r.Release(n)
+			}
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestCreateFormatReader_Quotes(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name      string
+		setting   string
+		input     string
+		expected  []string
+		positions [][2]int
+		expectErr string
+	}{
+		{
+			name:      "RFC 4180: embedded newlines and escaped quotes",
+			setting:   ``,
+			input:     "a|\"b\nc\r\nd\"|e\n\"f\"\"g\"|h|i\n",
+			expected:  []string{`{"c1":"a","c2":"b\nc\nd","c3":"e"}`, `{"c1":"f\"g","c2":"h","c3":"i"}`},
+			positions: [][2]int{{1, 3}, {4, 4}},
+		},
+		{
+			name:      "bare quote in non-quoted field",
+			setting:   ``,
+			input:     "a|b\"c|d\n",
+			expectErr: "input 'test-input' line 1: parse error on line 1, column 4: bare \" in non-quoted-field",
+		},
+		{
+			name:      "lazy_quotes",
+			setting:   `"lazy_quotes": true,`,
+			input:     "a|b\"c|d\n",
+			expected:  []string{`{"c1":"a","c2":"b\"c","c3":"d"}`},
+			positions: [][2]int{{1, 1}},
+		},
+		{
+			name:      "quote_escape backslash",
+			setting:   `"quote_escape": "backslash",`,
+			input:     "\"a\\\"b\"|\"c\nd\"|e\n",
+			expected:  []string{`{"c1":"a\"b","c2":"c\nd","c3":"e"}`},
+			positions: [][2]int{{1, 2}},
+		},
+		{
+			name:      "quote_escape backslash: backslash ending a quoted value",
+			setting:   `"quote_escape": "backslash",`,
+			input:     "\"C:\\dir\\\"|\"a\\\"b\"|c\n\"d\\\"\"|e|\"f\\\"\n",
+			expected:  []string{`{"c1":"C:\\dir\\","c2":"a\"b","c3":"c"}`, `{"c1":"d\"","c2":"e","c3":"f\\"}`},
+			positions: [][2]int{{1, 1}, {2, 2}},
+		},
+		{
+			name:      "quote_escape backslash: outside quoted values",
+			setting:   `"quote_escape": "backslash", "lazy_quotes": true,`,
+			input:     "a\\\"b|\"c\\\"d\"|e\n",
+			expected:  []string{`{"c1":"a\\\"b","c2":"c\"d","c3":"e"}`},
+			positions: [][2]int{{1, 1}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			format := NewCSVFileFormat("test-schema")
+			runtime, err := format.ValidateSchema(
+				fileFormatCSV,
+				[]byte(`
+					{
+						"file_declaration": {
+							"delimiter": "|",
+							`+test.setting+`
+							"records" : [
+								{
+									"columns": [
+										{ "name": "c1", "index": 1 },
+										{ "name": "c2", "index": 2 },
+										{ "name": "c3", "index": 3 }
+									]
+								}
+							]
+						}
+					}`),
+				finalOutputDecl)
+			assert.NoError(t, err)
+			r, err := format.CreateFormatReader("test-input", strings.NewReader(test.input), runtime)
+			assert.NoError(t, err)
+			var records []string
+			var positions [][2]int
+			for {
+				n, err := r.Read()
+				if err != nil {
+					if test.expectErr != "" {
+						assert.Equal(t, test.expectErr, err.Error())
+					} else {
+						assert.Equal(t, io.EOF, err)
+					}
+					break
+				}
+				records = append(records, idr.JSONify2(n))
+				begin, end := r.(*Reader).RecordPosition()
+				positions = append(positions, [2]int{begin, end})
+				r.Release(n)
+			}
+			assert.Equal(t, test.expected, records)
+			assert.Equal(t, test.positions, positions)
+		})
+	}
+}
+
+func TestCreateFormatReader_SkipLinesCommentAndTrailingLines(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name      string
+		setting   string
+		input     string
+		expected  []string
+		positions [][2]int
+	}{
+		{
+			name:      "skip_lines, even if they are not valid csv",
+			setting:   `"skip_lines": 2,`,
+			input:     "Bank Statement \"2020\nAccount|\"123\na|b\nc|d\n",
+			expected:  []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`},
+			positions: [][2]int{{3, 3}, {4, 4}},
+		},
+		{
+			name:      "comment",
+			setting:   `"comment": "#",`,
+			input:     "# generated\na|b\n#c|d\ne|f\n",
+			expected:  []string{`{"c1":"a","c2":"b"}`, `{"c1":"e","c2":"f"}`},
+			positions: [][2]int{{1, 2}, {3, 4}},
+		},
+		{
+			name:      "skip_trailing_lines",
+			setting:   `"skip_trailing_lines": 1,`,
+			input:     "a|b\nc|d\nTOTAL|2\n",
+			expected:  []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`},
+			positions: [][2]int{{1, 1}, {2, 2}},
+		},
+		{
+			name:      "all combined",
+			setting:   `"skip_lines": 1, "comment": "#", "skip_trailing_lines": 2,`,
+			input:     "H1|H2\n#x\na|b\nc|d\n#y\nSUBTOTAL|2\nTOTAL|2\n",
+			expected:  []string{`{"c1":"a","c2":"b"}`, `{"c1":"c","c2":"d"}`},
+			positions: [][2]int{{2, 3}, {4, 5}},
+		},
+		{
+			name:    "fewer lines than skip_lines",
+			setting: `"skip_lines": 3,`,
+			input:   "a|b\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			format := NewCSVFileFormat("test-schema")
+			runtime, err := format.ValidateSchema(
+				fileFormatCSV,
+				[]byte(`
+					{
+						"file_declaration": {
+							"delimiter": "|",
+							`+test.setting+`
+							"records" : [
+								{ "columns": [ { "name": "c1", "index": 1 }, { "name": "c2", "index": 2 } ] }
+							]
+						}
+					}`),
+				finalOutputDecl)
+			assert.NoError(t, err)
+			r, err := format.CreateFormatReader("test-input", strings.NewReader(test.input), runtime)
+			assert.NoError(t, err)
+			var records []string
+			var positions [][2]int
+			for {
+				n, err := r.Read()
+				if err != nil {
+					assert.Equal(t, io.EOF, err)
+					break
+				}
+				records = append(records, idr.JSONify2(n))
+				begin, end := r.(*Reader).RecordPosition()
+				positions = append(positions, [2]int{begin, end})
+				r.Release(n)
+			}
+			assert.Equal(t, test.expected, records)
+			assert.Equal(t, test.positions, positions)
+		})
+	}
+}
+
+func TestCreateFormatReader_Totals(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations([]byte(`
+		{
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": { "id": { "xpath": "id" } } }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	format := NewCSVFileFormat("test-schema")
+	runtime, err := format.ValidateSchema(
+		fileFormatCSV,
+		[]byte(`
+			{
+				"file_declaration": {
+					"delimiter": "|",
+					"skip_unreferenced_columns": true,
+					"records" : [
+						{
+							"name": "batch", "type": "record_group",
+							"child_records": [
+								{
+									"name": "entry", "header": "^E", "is_target": true,
+									"columns": [
+										{ "name": "id", "index": 2 },
+										{ "name": "amount", "index": 3 }
+									]
+								},
+								{
+									"name": "control", "header": "^C", "min": 1, "max": 1,
+									"columns": [
+										{ "name": "count", "index": 2 },
+										{ "name": "total", "index": 3 }
+									],
+									"totals": [
+										{ "column": "count", "count": "entry" },
+										{ "column": "total", "sum": "entry", "sum_column": "amount" }
+									]
+								}
+							]
+						}
+					]
+				}
+			}`),
+		finalOutputDecl)
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader(
+		"test-input",
+		strings.NewReader("E|1|1.50\nE|2|2.25\nC|2|3.70\nE|3|10\nC|1|10.00\n"),
+		runtime)
+	assert.NoError(t, err)
+	for _, expected := range []string{"1", "2", "", "3"} {
+		n, err := r.Read()
+		if expected == "" {
+			assert.Nil(t, n)
+			assert.Error(t, err)
+			assert.True(t, flatfile.IsErrTotalsMismatch(err))
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t,
+				"input 'test-input' line 3: totals mismatch: 'control' column 'total' value '3.70' doesn't match the sum of 'entry' column 'amount': 3.75",
+				err.Error())
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, n.FirstChild.InnerText())
+		r.Release(n)
+	}
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCreateFormatReader_ColumnValidation(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations([]byte(`
+		{
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": { "id": { "xpath": "id" } } }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	format := NewCSVFileFormat("test-schema")
+	runtime, err := format.ValidateSchema(
+		fileFormatCSV,
+		[]byte(`
+			{
+				"file_declaration": {
+					"delimiter": "|",
+					"skip_unreferenced_columns": true,
+					"records" : [
+						{
+							"name": "batch", "type": "record_group",
+							"child_records": [
+								{
+									"name": "entry", "header": "^E", "is_target": true,
+									"columns": [
+										{ "name": "id", "index": 2, "validation": { "required": true, "pattern": "^[0-9]+$" } },
+										{ "name": "currency", "index": 3, "validation": { "enum": [ "USD", "EUR" ] } },
+										{ "name": "amount", "index": 4, "validation": { "min": 0, "max": 1000 } }
+									]
+								},
+								{
+									"name": "control", "header": "^C", "min": 1, "max": 1,
+									"columns": [ { "name": "count", "index": 2 } ],
+									"totals": [ { "column": "count", "count": "entry" } ]
+								}
+							]
+						}
+					]
+				}
+			}`),
+		finalOutputDecl)
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader(
+		"test-input",
+		strings.NewReader("E|1|USD|1.50\nE|x2|GBP|-1\nE|3|EUR|\nE||EUR|abc\nC|4\n"),
+		runtime)
+	assert.NoError(t, err)
+	for _, expected := range []string{
+		"1",
+		"input 'test-input' line 2: record 'batch/entry' column 'id' (index 2) value 'x2' does not match pattern '^[0-9]+$'; " +
+			"input 'test-input' line 2: record 'batch/entry' column 'currency' (index 3) value 'GBP' is not one of ['USD', 'EUR']; " +
+			"input 'test-input' line 2: record 'batch/entry' column 'amount' (index 4) value '-1' is less than min 0",
+		"3",
+		"input 'test-input' line 4: record 'batch/entry' column 'id' (index 2) value '' is required; " +
+			"input 'test-input' line 4: record 'batch/entry' column 'amount' (index 4) value 'abc' is not a number",
+	} {
+		n, err := r.Read()
+		if !strings.HasPrefix(expected, "input") {
+			assert.NoError(t, err)
+			assert.Equal(t, expected, n.FirstChild.InnerText())
+			// the validated columns not referenced by any transform are validated but not in the IDR.
+			assert.Nil(t, n.FirstChild.NextSibling)
+			r.Release(n)
+			continue
+		}
+		assert.Nil(t, n)
+		assert.True(t, IsErrInvalidColumns(err))
+		assert.True(t, r.IsContinuableError(err))
+		assert.Equal(t, expected, err.Error())
+	}
+	// the rejected records still count towards the totals.
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Equal(t, io.EOF, err)
+
+	r, err = format.CreateFormatReader("test-input", strings.NewReader("E|1|JPY|2000\nC|1\n"), runtime)
+	assert.NoError(t, err)
+	_, err = r.Read()
+	var e *ErrInvalidColumns
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, []ColumnViolation{
+		{Line: 1, Record: "batch/entry", Column: "currency", Index: 3, Value: "JPY", Reason: "is not one of ['USD', 'EUR']"},
+		{Line: 1, Record: "batch/entry", Column: "amount", Index: 4, Value: "2000", Reason: "is greater than max 1000"},
+	}, e.Violations)
+}
+
+func TestParseFileDecl(t *testing.T) {
+	decl, err := ParseFileDecl([]byte(`{
+		"delimiter": "|",
+		"records": [ { "columns": [ { "name": "a" }, { "name": "b" } ] } ]
+	}`))
+	assert.NoError(t, err)
+	reader := NewReader("test-input", strings.NewReader("1|2\n3|4\n"), decl, nil)
+	for _, expected := range []string{`{"a":"1","b":"2"}`, `{"a":"3","b":"4"}`} {
+		n, err := reader.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, idr.JSONify2(n))
+		reader.Release(n)
+	}
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = ParseFileDecl([]byte(`{ "records": [] }`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema 'file_declaration' validation failed")
+
+	_, err = ParseFileDecl([]byte(`{ "delimiter": ",", "comment": "," }`))
+	assert.Error(t, err)
+	assert.Equal(t, "'comment' cannot be the same as 'delimiter'", err.Error())
+}
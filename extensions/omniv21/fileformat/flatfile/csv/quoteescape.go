@@ -0,0 +1,105 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// backslashQuoteReader turns the backslash escaped double quotes (`\"`) inside double-quoted values
+// into RFC 4180 style (`""`), which csv.Reader understands. Outside double-quoted values, `\"` is left
+// as is. A backslash followed by a double quote that is itself followed by the delimiter or the end of
+// the line, or of the input, is a backslash ending the value, e.g. `"C:\dir\"`, rather than an escaped
+// double quote.
+type backslashQuoteReader struct {
+	r         *bufio.Reader
+	delim     []byte
+	comment   []byte // nil if there are no comment lines.
+	out       []byte // bytes processed but not yet read.
+	err       error  // error reading the input, returned once out is all read.
+	quoted    bool   // true if inside a double-quoted value.
+	lineStart bool   // true if at the start of a line.
+	valStart  bool   // true if at the start of a value.
+}
+
+func newBackslashQuoteReader(r io.Reader, delim, comment string) *backslashQuoteReader {
+	br := &backslashQuoteReader{
+		r: bufio.NewReader(r), delim: []byte(delim), lineStart: true, valStart: true}
+	if comment != "" {
+		br.comment = []byte(comment)
+	}
+	return br
+}
+
+func (r *backslashQuoteReader) Read(p []byte) (int, error) {
+	// processes what's buffered to fill p, but blocks on the input only if there is nothing to return.
+	for r.err == nil && (len(r.out) == 0 || (len(r.out) < len(p) && r.r.Buffered() > 0)) {
+		r.err = r.process()
+	}
+	if len(r.out) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.out)
+	r.out = append(r.out[:0], r.out[n:]...)
+	return n, nil
+}
+
+// process processes the next byte, or line if it's a comment line, of the input into r.out.
+func (r *backslashQuoteReader) process() error {
+	if !r.quoted && r.lineStart && r.comment != nil && r.next(r.comment) {
+		line, err := r.r.ReadBytes('\n')
+		r.out = append(r.out, line...)
+		return err
+	}
+	c, err := r.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	r.lineStart = false
+	switch {
+	case r.quoted && c == '\\' && r.next([]byte(`"`)):
+		_, _ = r.r.ReadByte()
+		if r.valueEnds() {
+			r.out = append(r.out, '\\', '"')
+			r.quoted = false
+		} else {
+			r.out = append(r.out, '"', '"')
+		}
+		return nil
+	case r.quoted && c == '"':
+		if r.next([]byte(`"`)) {
+			_, _ = r.r.ReadByte()
+			r.out = append(r.out, '"', '"')
+			return nil
+		}
+		r.quoted = false
+	case !r.quoted && c == '"':
+		r.quoted = r.valStart
+	case !r.quoted && c == '\n':
+		r.lineStart, r.valStart = true, true
+		r.out = append(r.out, c)
+		return nil
+	case !r.quoted && c == r.delim[0] && r.next(r.delim[1:]):
+		_, _ = r.r.Discard(len(r.delim) - 1)
+		r.valStart = true
+		r.out = append(r.out, r.delim...)
+		return nil
+	}
+	r.valStart = false
+	r.out = append(r.out, c)
+	return nil
+}
+
+// next tells whether the input continues with b.
+func (r *backslashQuoteReader) next(b []byte) bool {
+	peeked, _ := r.r.Peek(len(b))
+	return bytes.Equal(peeked, b)
+}
+
+// valueEnds tells whether the input continues with the delimiter or the end of the line or input.
+func (r *backslashQuoteReader) valueEnds() bool {
+	if peeked, err := r.r.Peek(1); err != nil || peeked[0] == '\n' {
+		return true
+	}
+	return r.next(r.delim) || r.next([]byte("\r\n"))
+}
@@ -0,0 +1,60 @@
+package csv
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackslashQuoteReader(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		delim    string
+		comment  string
+		input    string
+		expected string
+	}{
+		{
+			name:     "escaped quotes in quoted values only",
+			delim:    ",",
+			input:    `a\"b,"c\"d","e""f"` + "\n" + `"g\"h"`,
+			expected: `a\"b,"c""d","e""f"` + "\n" + `"g""h"`,
+		},
+		{
+			name:     "backslash ending quoted values",
+			delim:    "|",
+			input:    `"a\"|"b\"` + "\r\n" + `"c\"`,
+			expected: `"a\"|"b\"` + "\r\n" + `"c\"`,
+		},
+		{
+			name:     "quoted value spanning lines",
+			delim:    ",",
+			input:    "\"a\n\\\"b\\\" \n\",c\n",
+			expected: "\"a\n\"\"b\"\" \n\",c\n",
+		},
+		{
+			name:     "multi-byte delimiter",
+			delim:    "¦",
+			input:    `"a\"¦"b\"c"`,
+			expected: `"a\"¦"b""c"`,
+		},
+		{
+			name:     "comment lines",
+			delim:    ",",
+			comment:  "#",
+			input:    "# \"x\\\"\n\"a\\\"b\",c\n",
+			expected: "# \"x\\\"\n\"a\"\"b\",c\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := newBackslashQuoteReader(
+				iotest.OneByteReader(strings.NewReader(test.input)), test.delim, test.comment)
+			b, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
@@ -1,80 +1,234 @@
 package csv
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/antchfx/xpath"
 	"github.com/jf-tech/go-corelib/ios"
+	"github.com/jf-tech/go-corelib/strs"
 
+	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 	"github.com/logward/omniparser/idr"
 )
 
+const (
+	quoteEscapeDouble    = "double"
+	quoteEscapeBackslash = "backslash"
+)
+
 type line struct {
 	lineNum                int // 1-based
 	recordStart, recordNum int // positional references into reader.records[] slice.
+	rawStart, rawLen       int // positional references into reader.rawBuf[] slice.
 	raw                    string
 }
 
-type reader struct {
+// heldRecord is a csv record read ahead and held back, in case it turns out to be one of the
+// trailing lines to skip.
+type heldRecord struct {
+	lineNum int
+	record  []string
+	raw     []byte
+}
+
+// rawRecorder sits between the input and the csv.Reader, recording the bytes the csv.Reader reads, so
+// that the raw bytes of each csv record can be cut out by the csv.Reader's input offsets.
+type rawRecorder struct {
+	r      io.Reader
+	buf    []byte // bytes read, starting at the input offset 'off'.
+	off    int64
+	cutLen int // number of bytes at the front of buf already cut, to be dropped on the next read or cut.
+}
+
+func (rr *rawRecorder) Read(p []byte) (int, error) {
+	rr.drop()
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+// cut returns the bytes not yet cut, up to the input offset 'end'. The returned slice is only valid
+// until the next Read or cut call.
+func (rr *rawRecorder) cut(end int64) []byte {
+	rr.drop()
+	rr.cutLen = int(end - rr.off)
+	rr.off = end
+	return rr.buf[:rr.cutLen:rr.cutLen]
+}
+
+func (rr *rawRecorder) drop() {
+	if rr.cutLen > 0 {
+		rr.buf = rr.buf[:copy(rr.buf, rr.buf[rr.cutLen:])]
+		rr.cutLen = 0
+	}
+}
+
+// Reader is the csv2 FormatReader: it reads the records of a CSV (or any other delimited) input,
+// per a FileDecl, and returns the target ones as IDR nodes. It can be used on its own, without an omni
+// schema, see NewReader.
+type Reader struct {
 	inputName string
 	fileDecl  *FileDecl
+	br        *bufio.Reader
+	rr        *rawRecorder
 	r         *ios.LineNumReportingCsvReader
+	skipLines int  // number of leading lines to skip.
+	skipped   bool // true once the leading lines have been skipped.
+	skipTrail int  // number of trailing lines to skip.
+	held      []heldRecord
 	hr        *flatfile.HierarchyReader
 	linesBuf  []line // linesBuf contains all the unprocessed lines
+	posBegin  int    // first line consumed by the last Read call.
+	posEnd    int    // last line consumed by the last Read call.
 	records   []string
+	rawBuf    []byte // raw bytes of the lines in linesBuf.
+	recBytes  []byte // raw bytes of the lines turned into IDR nodes by the last Read call.
+	totals    *flatfile.Totals
+	totalsErr []string // totals discrepancies found by the current Read call, if any.
+	// column validation violations found by the current Read call, if any.
+	violations []ColumnViolation
+	deferred   []deferred // results of a Read call, deferred to report totals discrepancies first.
+}
+
+type deferred struct {
+	n   *idr.Node
+	err error
 }
 
-// NewReader creates an FormatReader for csv file format.
+// NewReader creates a Reader for csv file format. decl must have been validated, i.e. it's either from
+// ParseFileDecl or FileDecl.Validate has been called on it. If targetXPathExpr isn't nil, only the target
+// records matching it are returned.
 func NewReader(
-	inputName string, r io.Reader, decl *FileDecl, targetXPathExpr *xpath.Expr) *reader {
+	inputName string, r io.Reader, decl *FileDecl, targetXPathExpr *xpath.Expr) *Reader {
+	r = flatfile.NormalizeLineEndings(r, strs.StrPtrOrElse(decl.LineEnding, ""))
+	if strs.StrPtrOrElse(decl.QuoteEscape, quoteEscapeDouble) == quoteEscapeBackslash {
+		comment := ""
+		if decl.Comment != nil {
+			comment = string([]rune(*decl.Comment)[0])
+		}
+		r = newBackslashQuoteReader(r, string([]rune(decl.Delimiter)[0]), comment)
+	}
 	if decl.ReplaceDoubleQuotes {
 		r = ios.NewBytesReplacingReader(r, []byte(`"`), []byte(`'`))
 	}
-	csv := ios.NewLineNumReportingCsvReader(r)
+	br := bufio.NewReader(r)
+	// The leading lines to skip are read directly off br, thus never recorded.
+	rr := &rawRecorder{r: br}
+	csv := ios.NewLineNumReportingCsvReader(rr)
 	delim := []rune(decl.Delimiter)
 	csv.Comma = delim[0]
+	if decl.Comment != nil {
+		csv.Comment = []rune(*decl.Comment)[0]
+	}
 	csv.FieldsPerRecord = -1
+	csv.LazyQuotes = decl.LazyQuotes
 	// While csv.ReuseRecord = true minimize encoding/csv.Reader slice allocations,
 	// It does make our multi-line caching a bit trickier. Since the csv.Reader.Read()
 	// returned []string slice will be reused, we have to have our own slice to copy
 	// those record string references down: reader.records[].
 	csv.ReuseRecord = true
-	reader := &reader{
+	reader := &Reader{
 		inputName: inputName,
 		fileDecl:  decl,
+		br:        br,
+		rr:        rr,
 		r:         csv,
+		skipLines: decl.SkipLines,
+		skipTrail: decl.SkipTrailingLines,
 	}
 	reader.hr = flatfile.NewHierarchyReader(
 		toFlatFileRecDecls(decl.Records), reader, targetXPathExpr)
+	reader.totals = flatfile.NewTotals(toFlatFileRecDecls(decl.Records))
 	return reader
 }
 
 // Read implements fileformat.FormatReader interface, reading in data from input and returns
 // target IDR node.
-func (r *reader) Read() (*idr.Node, error) {
+func (r *Reader) Read() (*idr.Node, error) {
+	if len(r.deferred) > 0 {
+		d := r.deferred[0]
+		r.deferred = r.deferred[1:]
+		return d.n, d.err
+	}
+	n, err := r.read()
+	if len(r.totalsErr) > 0 {
+		// Totals discrepancies are found on trailer records read on the way to the next target
+		// record (or the end of input), so they are reported first, with the target deferred
+		// to the next Read call.
+		totalsErr := strings.Join(r.totalsErr, "; ")
+		r.totalsErr = r.totalsErr[:0]
+		r.deferred = append(r.deferred, deferred{err: flatfile.ErrTotalsMismatch(totalsErr)})
+	}
+	if len(r.violations) > 0 {
+		// The target record read along with the invalid column values, if any, is rejected.
+		if n != nil {
+			r.hr.Release(n)
+			n = nil
+		}
+		r.deferred = append(r.deferred, deferred{err: r.invalidColumns()})
+	}
+	if len(r.deferred) == 0 {
+		return n, err
+	}
+	if n != nil || err != nil {
+		r.deferred = append(r.deferred, deferred{n: n, err: err})
+	}
+	return r.Read()
+}
+
+func (r *Reader) read() (*idr.Node, error) {
+	begin := r.unprocessedLineNum()
+	r.recBytes = r.recBytes[:0]
 	n, err := r.hr.Read()
 	switch {
 	case err == nil:
+		r.posBegin, r.posEnd = begin, r.unprocessedLineNum()-1
 		return n, nil
 	case flatfile.IsErrFewerThanMinOccurs(err):
 		e := err.(flatfile.ErrFewerThanMinOccurs)
 		decl := e.RecDecl.(*RecordDecl)
-		return nil, ErrInvalidCSV(r.fmtErrStr(r.unprocessedLineNum(),
+		return nil, r.invalidCSV(r.unprocessedLineNum(),
 			"record/record_group '%s' needs min occur %d, but only got %d",
-			decl.fqdn, decl.MinOccurs(), e.ActualOcccurs))
+			decl.fqdn, decl.MinOccurs(), e.ActualOcccurs)
 	case flatfile.IsErrUnexpectedData(err):
-		return nil, ErrInvalidCSV(r.fmtErrStr(r.unprocessedLineNum(), "unexpected data"))
+		return nil, r.invalidCSV(r.unprocessedLineNum(), "unexpected data")
 	default:
 		return nil, err
 	}
 }
 
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of lines
+// consumed by the last successful Read call.
+func (r *Reader) RecordPosition() (int, int) {
+	return r.posBegin, r.posEnd
+}
+
+// RecordBytes implements fileformat.RecordBytesReporter, returning the raw bytes of the last record
+// read, if unreferenced columns are skipped; or nil otherwise.
+func (r *Reader) RecordBytes() []byte {
+	if !r.fileDecl.skipping {
+		return nil
+	}
+	return r.recBytes
+}
+
+// RawBytes implements fileformat.RawBytesReporter, returning the raw bytes of the lines consumed by the
+// last successful Read call, quoting and line terminators included, as the csv reader reads them, i.e.
+// after `line_ending`, `quote_escape` and `replace_double_quotes` rewriting, if any. Comment and empty
+// lines are excluded.
+func (r *Reader) RawBytes() []byte {
+	return r.recBytes
+}
+
 // MoreUnprocessedData implements flatfile.RecReader, telling whether there is still unprocessed
 // data or not.
-func (r *reader) MoreUnprocessedData() (bool, error) {
+func (r *Reader) MoreUnprocessedData() (bool, error) {
 	if len(r.linesBuf) > 0 {
 		return true, nil
 	}
@@ -87,7 +241,7 @@ func (r *reader) MoreUnprocessedData() (bool, error) {
 // ReadAndMatch implements flatfile.RecReader, reading unprocessed data (from buffer or from IO),
 // trying to match against the given non-group typed record decl, and converting the data into IDR
 // node if asked to.
-func (r *reader) ReadAndMatch(
+func (r *Reader) ReadAndMatch(
 	decl flatfile.RecDecl, createIDR bool) (matched bool, node *idr.Node, err error) {
 	recDecl := decl.(*RecordDecl)
 	if recDecl.rowsBased() {
@@ -96,7 +250,7 @@ func (r *reader) ReadAndMatch(
 	return r.readAndMatchHeaderFooterBasedRecord(recDecl, createIDR)
 }
 
-func (r *reader) readAndMatchRowsBasedRecord(
+func (r *Reader) readAndMatchRowsBasedRecord(
 	decl *RecordDecl, createNode bool) (bool, *idr.Node, error) {
 	for len(r.linesBuf) < decl.rows() {
 		if err := r.readLine(); err != nil {
@@ -123,7 +277,7 @@ func (r *reader) readAndMatchRowsBasedRecord(
 	return true, nil, nil
 }
 
-func (r *reader) readAndMatchHeaderFooterBasedRecord(
+func (r *Reader) readAndMatchHeaderFooterBasedRecord(
 	decl *RecordDecl, createNode bool) (bool, *idr.Node, error) {
 	if len(r.linesBuf) <= 0 {
 		if err := r.readLine(); err != nil {
@@ -161,49 +315,152 @@ func (r *reader) readAndMatchHeaderFooterBasedRecord(
 	}
 }
 
-func (r *reader) readLine() error {
-	lineStart := r.r.LineNum() + 1
-	record, err := r.r.Read()
-	switch {
-	case err == io.EOF:
-		return io.EOF
-	case err != nil:
-		return ErrInvalidCSV(r.fmtErrStr(lineStart, err.Error()))
+func (r *Reader) readLine() error {
+	lineStart, record, raw, err := r.readRecord()
+	if err != nil {
+		return err
 	}
 	start, num := len(r.records), len(record)
 	r.records = append(r.records, record...)
+	rawStart := len(r.rawBuf)
+	r.rawBuf = append(r.rawBuf, raw...)
 	r.linesBuf = append(r.linesBuf, line{
 		lineNum:     lineStart,
 		recordStart: start,
 		recordNum:   num,
+		rawStart:    rawStart,
+		rawLen:      len(raw),
 	})
 	return nil
 }
 
-func (r *reader) linesToNode(decl *RecordDecl, n int) *idr.Node {
+// readRecord reads in the next csv record, holding back the last `skip_trailing_lines` records of
+// the input so they are never returned.
+func (r *Reader) readRecord() (int, []string, []byte, error) {
+	if r.skipTrail <= 0 {
+		return r.readCSVRecord()
+	}
+	for len(r.held) <= r.skipTrail {
+		lineNum, record, raw, err := r.readCSVRecord()
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		// csv.Reader reuses the record slice, and the raw bytes are only valid until the next read,
+		// so we need our own copies.
+		r.held = append(r.held, heldRecord{
+			lineNum: lineNum,
+			record:  append([]string(nil), record...),
+			raw:     append([]byte(nil), raw...),
+		})
+	}
+	held := r.held[0]
+	copy(r.held, r.held[1:])
+	r.held = r.held[:len(r.held)-1]
+	return held.lineNum, held.record, held.raw, nil
+}
+
+// readCSVRecord reads in the next csv record, returning its starting line number, its fields and its
+// raw bytes, which are only valid until the next call.
+func (r *Reader) readCSVRecord() (int, []string, []byte, error) {
+	if !r.skipped {
+		r.skipped = true
+		for i := 0; i < r.skipLines; i++ {
+			_, err := ios.ByteReadLine(r.br)
+			switch {
+			case err == io.EOF:
+				return 0, nil, nil, io.EOF
+			case err != nil:
+				return 0, nil, nil, r.invalidCSV(i+1, err.Error())
+			}
+		}
+	}
+	prevLine := r.r.LineNum()
+	lineStart := prevLine + r.skipLines + 1
+	record, err := r.r.Read()
+	switch {
+	case err == io.EOF:
+		return 0, nil, nil, io.EOF
+	case err != nil:
+		return 0, nil, nil, r.invalidCSV(lineStart, err.Error())
+	}
+	raw := r.rr.cut(r.r.InputOffset())
+	// Comment and empty lines are skipped by csv.Reader, so the record may not start right after
+	// where the last record ended, and its raw bytes start after those of the skipped lines.
+	line, _ := r.r.FieldPos(0)
+	for i := prevLine + 1; i < line; i++ {
+		raw = raw[bytes.IndexByte(raw, '\n')+1:]
+	}
+	return line + r.skipLines, record, raw, nil
+}
+
+// csvLineNum returns the line number of the input the csv.Reader is at, taking the skipped leading
+// lines, which the csv.Reader never sees, into account.
+func (r *Reader) csvLineNum() int {
+	return r.r.LineNum() + r.skipLines
+}
+
+func (r *Reader) linesToNode(decl *RecordDecl, n int) *idr.Node {
 	if len(r.linesBuf) < n {
 		panic(fmt.Sprintf(
 			"linesBuf has %d lines but requested %d lines to convert", len(r.linesBuf), n))
 	}
 	node := idr.CreateNode(idr.ElementNode, decl.Name)
+	for i := 0; i < n; i++ {
+		l := &r.linesBuf[i]
+		r.recBytes = append(r.recBytes, r.rawBuf[l.rawStart:l.rawStart+l.rawLen]...)
+	}
 	for col := range decl.Columns {
 		colDecl := decl.Columns[col]
+		if colDecl.skip && colDecl.Validation == nil {
+			continue
+		}
+		found := false
 		for i := 0; i < n; i++ {
 			if !colDecl.lineMatch(i, &(r.linesBuf[i]), r.records, r.fileDecl.Delimiter) {
 				continue
 			}
+			found = true
+			value := colDecl.trim.Apply(colDecl.lineToColumnValue(&r.linesBuf[i], r.records))
+			r.validateColumn(decl, colDecl, r.linesBuf[i].lineNum, value)
+			if colDecl.skip {
+				break
+			}
 			colNode := idr.CreateNode(idr.ElementNode, colDecl.Name)
 			idr.AddChild(node, colNode)
-			colVal := idr.CreateNode(
-				idr.TextNode, colDecl.lineToColumnValue(&r.linesBuf[i], r.records))
+			colVal := idr.CreateNode(idr.TextNode, value)
 			idr.AddChild(colNode, colVal)
 			break
 		}
+		if !found {
+			// the column is missing from the record.
+			r.validateColumn(decl, colDecl, r.linesBuf[0].lineNum, "")
+		}
+	}
+	for _, d := range r.totals.Observe(decl, node) {
+		r.totalsErr = append(r.totalsErr, r.fmtErrStr(r.linesBuf[0].lineNum, "totals mismatch: %s", d))
 	}
 	return node
 }
 
-func (r *reader) popFrontLinesBuf(n int) {
+// validateColumn validates a column value, if the column has a validation, recording the violation, if
+// any, for the current Read call to report.
+func (r *Reader) validateColumn(decl *RecordDecl, colDecl *ColumnDecl, line int, value string) {
+	if colDecl.Validation == nil {
+		return
+	}
+	if reason := colDecl.Validation.violation(value); reason != "" {
+		r.violations = append(r.violations, ColumnViolation{
+			Line:   line,
+			Record: decl.fqdn,
+			Column: colDecl.Name,
+			Index:  *colDecl.Index,
+			Value:  value,
+			Reason: reason,
+		})
+	}
+}
+
+func (r *Reader) popFrontLinesBuf(n int) {
 	if n > len(r.linesBuf) {
 		panic(fmt.Sprintf(
 			"less lines (%d) in r.linesBuf than requested pop front count (%d)",
@@ -217,43 +474,106 @@ func (r *reader) popFrontLinesBuf(n int) {
 	copy(r.records, r.records[recordShift:])
 	r.records = r.records[:len(r.records)-recordShift]
 
+	rawShift := 0
+	for i := 0; i < n; i++ {
+		rawShift += r.linesBuf[i].rawLen
+	}
+	copy(r.rawBuf, r.rawBuf[rawShift:])
+	r.rawBuf = r.rawBuf[:len(r.rawBuf)-rawShift]
+
 	newLinesBufLen := len(r.linesBuf) - n
 	for i := 0; i < newLinesBufLen; i++ {
 		r.linesBuf[i] = r.linesBuf[i+n]
 		r.linesBuf[i].recordStart -= recordShift
+		r.linesBuf[i].rawStart -= rawShift
 	}
 	r.linesBuf = r.linesBuf[:newLinesBufLen]
 }
 
-func (r *reader) unprocessedLineNum() int {
+func (r *Reader) unprocessedLineNum() int {
 	if len(r.linesBuf) > 0 {
 		return r.linesBuf[0].lineNum
 	}
-	return r.r.LineNum() + 1
+	if len(r.held) > 0 {
+		return r.held[0].lineNum
+	}
+	return r.csvLineNum() + 1
 }
 
 // Release implements fileformat.FormatReader interface, releasing a finished IDR target node.
-func (r *reader) Release(n *idr.Node) {
+func (r *Reader) Release(n *idr.Node) {
 	r.hr.Release(n)
 }
 
 // IsContinuableError implements fileformat..FormatReader interface, checking if an error is
 // fatal or not.
-func (r *reader) IsContinuableError(err error) bool {
+func (r *Reader) IsContinuableError(err error) bool {
 	return !IsErrInvalidCSV(err) && err != io.EOF
 }
 
 // FmtErr implements errs.CtxAwareErr embedded in fileformat.FormatReader, formatting an error
 // with line info.
-func (r *reader) FmtErr(format string, args ...interface{}) error {
+func (r *Reader) FmtErr(format string, args ...interface{}) error {
 	return errors.New(r.fmtErrStr(r.unprocessedLineNum(), format, args...))
 }
 
-func (r *reader) fmtErrStr(line int, format string, args ...interface{}) string {
+func (r *Reader) fmtErrStr(line int, format string, args ...interface{}) string {
 	return fmt.Sprintf("input '%s' line %d: %s",
 		r.inputName, line, fmt.Sprintf(format, args...))
 }
 
+// invalidCSV creates an ErrInvalidCSV, wrapped in an errs.ErrInput.
+func (r *Reader) invalidCSV(line int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatCSV,
+		Input:  r.inputName,
+		Line:   line,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidCSV(r.fmtErrStr(line, "%s", reason)),
+	}
+}
+
+// invalidColumns creates an ErrInvalidColumns from the violations found by the current Read call.
+func (r *Reader) invalidColumns() error {
+	msgs := make([]string, len(r.violations))
+	for i, v := range r.violations {
+		msgs[i] = r.fmtErrStr(v.Line, "record '%s' column '%s' (index %d) value '%s' %s",
+			v.Record, v.Column, v.Index, v.Value, v.Reason)
+	}
+	err := &ErrInvalidColumns{Violations: r.violations, msg: strings.Join(msgs, "; ")}
+	r.violations = nil
+	return err
+}
+
+// ColumnViolation is a column value failing the `validation` of its column.
+type ColumnViolation struct {
+	Line   int    // 1-based line number of the value.
+	Record string // fully qualified name of the record, e.g. "HDR/DTL".
+	Column string // name of the column.
+	Index  int    // 1-based index of the column.
+	Value  string // the value, after trimming.
+	Reason string // why the value fails the validation, e.g. "is not a number".
+}
+
+// ErrInvalidColumns indicates column values failing the `validation` of their columns. This is a
+// continuable error: the target record read along with the values is rejected, and reading resumes
+// with the next one.
+type ErrInvalidColumns struct {
+	Violations []ColumnViolation
+	msg        string
+}
+
+// Error implements error interface.
+func (e *ErrInvalidColumns) Error() string { return e.msg }
+
+// IsErrInvalidColumns checks if the `err` is of ErrInvalidColumns type, or wraps one.
+func IsErrInvalidColumns(err error) bool {
+	var e *ErrInvalidColumns
+	return errors.As(err, &e)
+}
+
 // ErrInvalidCSV indicates the csv content is corrupted or IO failure.
 // This is a fatal, non-continuable error.
 type ErrInvalidCSV string
@@ -261,12 +581,8 @@ type ErrInvalidCSV string
 // Error implements error interface.
 func (e ErrInvalidCSV) Error() string { return string(e) }
 
-// IsErrInvalidCSV checks if the `err` is of ErrInvalidCSV type.
+// IsErrInvalidCSV checks if the `err` is of ErrInvalidCSV type, or wraps one, e.g. in an errs.ErrInput.
 func IsErrInvalidCSV(err error) bool {
-	switch err.(type) {
-	case ErrInvalidCSV:
-		return true
-	default:
-		return false
-	}
+	var e ErrInvalidCSV
+	return errors.As(err, &e)
 }
@@ -3,6 +3,7 @@ package csv
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"regexp"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/jf-tech/go-corelib/ios"
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
+	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/idr"
 	"github.com/stretchr/testify/assert"
 )
@@ -240,10 +242,12 @@ func TestReadAndMatchRowsBasedRecord(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{
+			rr := &rawRecorder{r: test.r}
+			r := &Reader{
 				inputName: "test-input",
 				fileDecl:  &FileDecl{Delimiter: ","},
-				r:         ios.NewLineNumReportingCsvReader(test.r),
+				rr:        rr,
+				r:         ios.NewLineNumReportingCsvReader(rr),
 				linesBuf:  test.linesBuf,
 				records:   test.records,
 			}
@@ -372,10 +376,12 @@ func TestReadAndMatchHeaderFooterBasedRecord(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{
+			rr := &rawRecorder{r: test.r}
+			r := &Reader{
 				inputName: "test-input",
 				fileDecl:  &FileDecl{Delimiter: ","},
-				r:         ios.NewLineNumReportingCsvReader(test.r),
+				rr:        rr,
+				r:         ios.NewLineNumReportingCsvReader(rr),
 				linesBuf:  test.linesBuf,
 				records:   test.records,
 			}
@@ -399,7 +405,7 @@ func TestReadAndMatchHeaderFooterBasedRecord(t *testing.T) {
 }
 
 func TestLinesToNode(t *testing.T) {
-	r := &reader{fileDecl: &FileDecl{Delimiter: ","}}
+	r := &Reader{fileDecl: &FileDecl{Delimiter: ","}}
 	assert.PanicsWithValue(t,
 		"linesBuf has 0 lines but requested 3 lines to convert",
 		func() { r.linesToNode(&RecordDecl{}, 3) })
@@ -430,7 +436,7 @@ func TestLinesToNode(t *testing.T) {
 }
 
 func TestPopFrontLinesBuf(t *testing.T) {
-	r := &reader{}
+	r := &Reader{}
 	r.linesBuf = make([]line, 0, 10)
 	r.linesBuf = append(r.linesBuf, []line{
 		{lineNum: 10, recordStart: 0, recordNum: 3},
@@ -457,7 +463,7 @@ func TestPopFrontLinesBuf(t *testing.T) {
 }
 
 func TestIsContinuableError(t *testing.T) {
-	r := &reader{
+	r := &Reader{
 		r: ios.NewLineNumReportingCsvReader(strings.NewReader("test")),
 	}
 	assert.True(t, r.IsContinuableError(r.FmtErr("some error")))
@@ -470,3 +476,96 @@ func TestIsErrInvalidCSV(t *testing.T) {
 	assert.Equal(t, "test", ErrInvalidCSV("test").Error())
 	assert.False(t, IsErrInvalidCSV(errors.New("test")))
 }
+
+func TestIsErrInvalidColumns(t *testing.T) {
+	err := &ErrInvalidColumns{msg: "test"}
+	assert.True(t, IsErrInvalidColumns(err))
+	assert.True(t, IsErrInvalidColumns(fmt.Errorf("wrapped: %w", err)))
+	assert.Equal(t, "test", err.Error())
+	assert.False(t, IsErrInvalidColumns(errors.New("test")))
+}
+
+func TestRecordPosition(t *testing.T) {
+	var fd FileDecl
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"delimiter": ",",
+		"records": [
+			{ "name": "h", "header": "^H", "min": 1, "max": 1 },
+			{ "name": "r", "rows": 2, "is_target": true }
+		]
+	}`), &fd))
+	assert.NoError(t, (&validateCtx{}).validateFileDecl(&fd))
+	r := NewReader("test-input", strings.NewReader(lf("H")+lf("a,1")+lf("a,2")+lf("b,1")+lf("b,2")), &fd, nil)
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 0, begin)
+	assert.Equal(t, 0, end)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 1, begin)
+	assert.Equal(t, 3, end)
+	assert.Equal(t, "H\na,1\na,2\n", string(r.RawBytes()))
+	n, err = r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 4, begin)
+	assert.Equal(t, 5, end)
+	assert.Equal(t, "b,1\nb,2\n", string(r.RawBytes()))
+}
+
+func TestRawBytes(t *testing.T) {
+	var fd FileDecl
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"delimiter": ",",
+		"comment": "#",
+		"skip_lines": 1,
+		"skip_trailing_lines": 1,
+		"records": [ { "name": "r", "columns": [ { "name": "c", "index": 2 } ] } ]
+	}`), &fd))
+	assert.NoError(t, (&validateCtx{}).validateFileDecl(&fd))
+	r := NewReader("test-input", strings.NewReader(
+		"skipped\r\n"+
+			"a ,\"b \"\"q\"\"\"\r\n"+
+			"# comment\r\n"+
+			"\r\n"+
+			"c,\"multi\r\nline\"\r\n"+
+			"trailer"), &fd, nil)
+	var raws []string
+	for {
+		n, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		raws = append(raws, string(r.RawBytes()))
+		r.Release(n)
+	}
+	assert.Equal(t, []string{"a ,\"b \"\"q\"\"\"\r\n", "c,\"multi\r\nline\"\r\n"}, raws)
+}
+
+func TestRead_ErrInput(t *testing.T) {
+	var fd FileDecl
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"delimiter": ",",
+		"records": [
+			{ "name": "h", "header": "^H", "min": 1, "max": 1 },
+			{ "name": "r", "header": "^R", "is_target": true }
+		]
+	}`), &fd))
+	assert.NoError(t, (&validateCtx{}).validateFileDecl(&fd))
+	r := NewReader("test-input", strings.NewReader(lf("H")+lf("R")+lf("X")), &fd, nil)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	_, err = r.Read()
+	assert.True(t, IsErrInvalidCSV(err))
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "csv2", inputErr.Format)
+	assert.Equal(t, 3, inputErr.Line)
+	assert.Equal(t, int64(-1), inputErr.Offset)
+	assert.Equal(t, "unexpected data", inputErr.Reason)
+	assert.Equal(t, "input 'test-input' line 3: unexpected data", err.Error())
+}
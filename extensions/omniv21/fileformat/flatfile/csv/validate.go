@@ -1,22 +1,36 @@
 package csv
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/jf-tech/go-corelib/caches"
 	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 )
 
 type validateCtx struct {
 	seenTarget bool
+	trim       *string
+	records    map[string][]*RecordDecl // all the records/record_groups, by name.
 }
 
 func (ctx *validateCtx) validateFileDecl(fileDecl *FileDecl) error {
+	ctx.trim = fileDecl.Trim
+	if fileDecl.Comment != nil && *fileDecl.Comment == fileDecl.Delimiter {
+		return fmt.Errorf("'comment' cannot be the same as 'delimiter'")
+	}
+	ctx.records = map[string][]*RecordDecl{}
 	for _, decl := range fileDecl.Records {
 		if err := ctx.validateRecordDecl(decl.Name, decl); err != nil {
 			return err
 		}
 	}
+	if err := ctx.validateTotalDecls(fileDecl.Records); err != nil {
+		return err
+	}
 	if !ctx.seenTarget && len(fileDecl.Records) > 0 {
 		// for easy of use and convenience, if no is_target=true record is specified, then
 		// the first one will be automatically designated as target record.
@@ -27,6 +41,7 @@ func (ctx *validateCtx) validateFileDecl(fileDecl *FileDecl) error {
 
 func (ctx *validateCtx) validateRecordDecl(fqdn string, decl *RecordDecl) (err error) {
 	decl.fqdn = fqdn
+	ctx.records[decl.Name] = append(ctx.records[decl.Name], decl)
 	if decl.Header != nil {
 		if decl.headerRegexp, err = caches.GetRegex(*decl.Header); err != nil {
 			return fmt.Errorf(
@@ -80,6 +95,41 @@ func (ctx *validateCtx) validateRecordDecl(fqdn string, decl *RecordDecl) (err e
 	return nil
 }
 
+// validateTotalDecls validates the totals declared on the records and their descendants. It must be
+// called after all the records have been validated, since totals can refer to any of them.
+func (ctx *validateCtx) validateTotalDecls(decls []*RecordDecl) error {
+	for _, decl := range decls {
+		for _, t := range decl.Totals {
+			if err := ctx.validateTotalDecl(decl, t); err != nil {
+				return fmt.Errorf("record '%s' totals column '%s' %s", decl.fqdn, t.Column, err.Error())
+			}
+		}
+		if err := ctx.validateTotalDecls(decl.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *validateCtx) validateTotalDecl(decl *RecordDecl, t *flatfile.TotalDecl) error {
+	if !decl.hasColumn(t.Column) {
+		return errors.New("is not a column of the record")
+	}
+	sources := ctx.records[t.Source()]
+	if len(sources) == 0 {
+		return fmt.Errorf("refers to non-existing record '%s'", t.Source())
+	}
+	for _, source := range sources {
+		if source.Group() {
+			return fmt.Errorf("refers to record_group '%s'", source.fqdn)
+		}
+		if t.SumColumn != nil && !source.hasColumn(*t.SumColumn) {
+			return fmt.Errorf("refers to non-existing column '%s' of record '%s'", *t.SumColumn, source.fqdn)
+		}
+	}
+	return nil
+}
+
 func intPtr(v int) *int {
 	return &v
 }
@@ -95,6 +145,7 @@ func (ctx *validateCtx) validateColumnDecl(fqdn string, prevDecl, decl *ColumnDe
 			decl.Index = intPtr(*prevDecl.Index + 1)
 		}
 	}
+	decl.trim = fileformat.ResolveTrimPolicy(decl.Trim, ctx.trim)
 	if decl.LineIndex != nil && decl.LinePattern != nil {
 		return fmt.Errorf(
 			"record '%s' column '%s' cannot have both `line_index` and `line_pattern` specified at the same time",
@@ -107,5 +158,18 @@ func (ctx *validateCtx) validateColumnDecl(fqdn string, prevDecl, decl *ColumnDe
 				fqdn, decl.Name, *decl.LinePattern, err.Error())
 		}
 	}
+	if v := decl.Validation; v != nil {
+		if v.Pattern != nil {
+			if v.patternRegexp, err = caches.GetRegex(*v.Pattern); err != nil {
+				return fmt.Errorf(
+					"record '%s' column '%s' has an invalid 'validation.pattern' regexp '%s': %s",
+					fqdn, decl.Name, *v.Pattern, err.Error())
+			}
+		}
+		if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+			return fmt.Errorf("record '%s' column '%s' has 'validation.min' value %v > 'validation.max' value %v",
+				fqdn, decl.Name, *v.Min, *v.Max)
+		}
+	}
 	return nil
 }
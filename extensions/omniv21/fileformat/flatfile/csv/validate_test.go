@@ -7,6 +7,8 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 )
 
 func TestValidateFileDecl_AutoTargetFirstRecord(t *testing.T) {
@@ -24,6 +26,12 @@ func TestValidateFileDecl_AutoTargetFirstRecord(t *testing.T) {
 	assert.False(t, decl.Records[1].Target())
 }
 
+func TestValidateFileDecl_CommentSameAsDelimiter(t *testing.T) {
+	err := (&validateCtx{}).validateFileDecl(&FileDecl{Delimiter: "#", Comment: strs.StrPtr("#")})
+	assert.Error(t, err)
+	assert.Equal(t, "'comment' cannot be the same as 'delimiter'", err.Error())
+}
+
 func TestValidateFileDecl_InvalidHeaderRegexp(t *testing.T) {
 	err := (&validateCtx{}).validateFileDecl(&FileDecl{
 		Records: []*RecordDecl{
@@ -126,6 +134,35 @@ func TestValidateFileDecl_InvalidColumnLinePattern(t *testing.T) {
 		err.Error())
 }
 
+func TestValidateFileDecl_InvalidColumnValidation(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		validation *ColumnValidation
+		err        string
+	}{
+		{
+			name:       "invalid pattern",
+			validation: &ColumnValidation{Pattern: strs.StrPtr("[invalid")},
+			err:        "record 'A' column 'c' has an invalid 'validation.pattern' regexp '[invalid': error parsing regexp: missing closing ]: `[invalid`",
+		},
+		{
+			name:       "min greater than max",
+			validation: &ColumnValidation{Min: floatPtr(2.5), Max: floatPtr(1)},
+			err:        "record 'A' column 'c' has 'validation.min' value 2.5 > 'validation.max' value 1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&validateCtx{}).validateFileDecl(&FileDecl{
+				Records: []*RecordDecl{
+					{Name: "A", Columns: []*ColumnDecl{{Name: "c", Validation: test.validation}}},
+				},
+			})
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
+
 func TestValidateFileDecl_Success(t *testing.T) {
 	col1 := &ColumnDecl{Name: "c1", LineIndex: testlib.IntPtr(1)}
 	col2 := &ColumnDecl{Name: "c2", Index: testlib.IntPtr(3)}
@@ -157,3 +194,62 @@ func TestValidateFileDecl_Success(t *testing.T) {
 	assert.Equal(t, "A/B", fd.Records[0].Children[0].fqdn)
 	cupaloy.SnapshotT(t, fd.Records[0].Children[0].Columns)
 }
+
+func TestValidateFileDecl_Totals(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		totals []*flatfile.TotalDecl
+		err    string
+	}{
+		{
+			name:   "column not of the record",
+			totals: []*flatfile.TotalDecl{{Column: "x", Count: strs.StrPtr("E")}},
+			err:    "record 'G/C' totals column 'x' is not a column of the record",
+		},
+		{
+			name:   "non-existing record",
+			totals: []*flatfile.TotalDecl{{Column: "count", Count: strs.StrPtr("X")}},
+			err:    "record 'G/C' totals column 'count' refers to non-existing record 'X'",
+		},
+		{
+			name:   "record_group",
+			totals: []*flatfile.TotalDecl{{Column: "count", Count: strs.StrPtr("G")}},
+			err:    "record 'G/C' totals column 'count' refers to record_group 'G'",
+		},
+		{
+			name: "non-existing sum column",
+			totals: []*flatfile.TotalDecl{
+				{Column: "count", Sum: strs.StrPtr("E"), SumColumn: strs.StrPtr("x")}},
+			err: "record 'G/C' totals column 'count' refers to non-existing column 'x' of record 'G/E'",
+		},
+		{
+			name: "success",
+			totals: []*flatfile.TotalDecl{
+				{Column: "count", Count: strs.StrPtr("E")},
+				{Column: "count", Sum: strs.StrPtr("E"), SumColumn: strs.StrPtr("amount")},
+			},
+			err: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&validateCtx{}).validateFileDecl(&FileDecl{
+				Records: []*RecordDecl{
+					{
+						Name: "G",
+						Type: strs.StrPtr(typeGroup),
+						Children: []*RecordDecl{
+							{Name: "E", Columns: []*ColumnDecl{{Name: "amount"}}},
+							{Name: "C", Columns: []*ColumnDecl{{Name: "count"}}, Totals: test.totals},
+						},
+					},
+				},
+			})
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package fixedlength
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/maths"
+)
+
+const (
+	binTypePackedDecimal = "packed_decimal" // IBM COMP-3: 2 digits per byte, sign in the last nibble.
+	binTypeBCD           = "bcd"            // unsigned packed BCD: 2 digits per byte, no sign nibble.
+	binTypeBits          = "bits"           // unsigned integer out of a bit range.
+	binTypeHex           = "hex"            // raw bytes in upper case hex.
+)
+
+type binIntType struct {
+	size      int
+	signed    bool
+	bigEndian bool
+}
+
+var binIntTypes = map[string]binIntType{
+	"int8":      {size: 1, signed: true},
+	"uint8":     {size: 1},
+	"int16_be":  {size: 2, signed: true, bigEndian: true},
+	"int16_le":  {size: 2, signed: true},
+	"uint16_be": {size: 2, bigEndian: true},
+	"uint16_le": {size: 2},
+	"int32_be":  {size: 4, signed: true, bigEndian: true},
+	"int32_le":  {size: 4, signed: true},
+	"uint32_be": {size: 4, bigEndian: true},
+	"uint32_le": {size: 4},
+	"int64_be":  {size: 8, signed: true, bigEndian: true},
+	"int64_le":  {size: 8, signed: true},
+	"uint64_be": {size: 8, bigEndian: true},
+	"uint64_le": {size: 8},
+}
+
+func (c *ColumnDecl) binary() bool {
+	return c.Type != nil
+}
+
+func (c *ColumnDecl) bitField() bool {
+	return c.Type != nil && *c.Type == binTypeBits
+}
+
+// validateBinary validates the binary-specific settings of a column, in addition to what JSON schema
+// validation has already covered.
+func (c *ColumnDecl) validateBinary() error {
+	if !c.bitField() && (c.BitOffset != nil || c.BitLength != nil) {
+		return fmt.Errorf("'bit_offset' and 'bit_length' are only allowed with type '%s'", binTypeBits)
+	}
+	if !c.binary() {
+		if c.DecimalPlaces != nil {
+			return fmt.Errorf("'decimal_places' is only allowed with a numeric binary type")
+		}
+		return nil
+	}
+	if intType, ok := binIntTypes[*c.Type]; ok {
+		if c.Length != intType.size {
+			return fmt.Errorf("type '%s' requires 'length' %d, but got %d", *c.Type, intType.size, c.Length)
+		}
+		return nil
+	}
+	switch *c.Type {
+	case binTypePackedDecimal, binTypeBCD:
+		return nil
+	case binTypeBits:
+		if c.BitOffset == nil || c.BitLength == nil {
+			return fmt.Errorf("type '%s' requires both 'bit_offset' and 'bit_length'", binTypeBits)
+		}
+		if *c.BitOffset+*c.BitLength > c.Length*8 {
+			return fmt.Errorf("'bit_offset' %d + 'bit_length' %d exceeds the column's %d bits",
+				*c.BitOffset, *c.BitLength, c.Length*8)
+		}
+	case binTypeHex:
+	default:
+		return fmt.Errorf("unknown type '%s'", *c.Type)
+	}
+	if c.DecimalPlaces != nil {
+		return fmt.Errorf("'decimal_places' is not allowed with type '%s'", *c.Type)
+	}
+	return nil
+}
+
+// lineToColumnBytes is the byte-based counterpart of lineToColumnValue, used by binary columns.
+func (c *ColumnDecl) lineToColumnBytes(line []byte) []byte {
+	start := maths.MinInt(c.StartPos-1, len(line))
+	end := maths.MinInt(start+c.Length, len(line))
+	return line[start:end]
+}
+
+// lineToBinaryColumnValue decodes a binary column into its string value: a decimal number for
+// integer, packed decimal, BCD and bits types, or upper case hex for the hex type.
+func (c *ColumnDecl) lineToBinaryColumnValue(line []byte) (string, error) {
+	b := c.lineToColumnBytes(line)
+	if len(b) < c.Length {
+		return "", fmt.Errorf("needs %d bytes, but only got %d", c.Length, len(b))
+	}
+	if intType, ok := binIntTypes[*c.Type]; ok {
+		return c.applyDecimalPlaces(decodeBinInt(intType, b)), nil
+	}
+	switch *c.Type {
+	case binTypePackedDecimal, binTypeBCD:
+		digits, err := decodePacked(b, *c.Type == binTypePackedDecimal)
+		if err != nil {
+			return "", err
+		}
+		return c.applyDecimalPlaces(digits), nil
+	case binTypeBits:
+		var v uint64
+		for i := *c.BitOffset; i < *c.BitOffset+*c.BitLength; i++ {
+			v = v<<1 | uint64(b[i/8]>>(7-i%8)&1)
+		}
+		return strconv.FormatUint(v, 10), nil
+	default:
+		return strings.ToUpper(hex.EncodeToString(b)), nil
+	}
+}
+
+func decodeBinInt(t binIntType, b []byte) string {
+	var u uint64
+	for i := 0; i < t.size; i++ {
+		if t.bigEndian {
+			u = u<<8 | uint64(b[i])
+		} else {
+			u = u<<8 | uint64(b[t.size-1-i])
+		}
+	}
+	if !t.signed {
+		return strconv.FormatUint(u, 10)
+	}
+	shift := uint(64 - 8*t.size)
+	return strconv.FormatInt(int64(u<<shift)>>shift, 10)
+}
+
+// decodePacked decodes packed BCD digits. If signed, the last nibble is the sign: 0xD and 0xB are
+// negative; 0xC, 0xF, 0xA and 0xE are positive.
+func decodePacked(b []byte, signed bool) (string, error) {
+	var sb strings.Builder
+	neg := false
+	for i, by := range b {
+		for j, nibble := range []byte{by >> 4, by & 0x0F} {
+			if signed && i == len(b)-1 && j == 1 {
+				switch nibble {
+				case 0x0B, 0x0D:
+					neg = true
+				case 0x0A, 0x0C, 0x0E, 0x0F:
+				default:
+					return "", fmt.Errorf("invalid packed decimal sign in 0x%X", b)
+				}
+				continue
+			}
+			if nibble > 9 {
+				return "", fmt.Errorf("invalid packed digit in 0x%X", b)
+			}
+			sb.WriteByte('0' + nibble)
+		}
+	}
+	digits := strings.TrimLeft(sb.String(), "0")
+	switch {
+	case digits == "":
+		return "0", nil
+	case neg:
+		return "-" + digits, nil
+	default:
+		return digits, nil
+	}
+}
+
+// applyDecimalPlaces inserts the implied decimal point into a decimal integer string.
+func (c *ColumnDecl) applyDecimalPlaces(v string) string {
+	if c.DecimalPlaces == nil || *c.DecimalPlaces == 0 {
+		return v
+	}
+	sign := ""
+	if strings.HasPrefix(v, "-") {
+		sign, v = "-", v[1:]
+	}
+	places := *c.DecimalPlaces
+	if len(v) <= places {
+		v = strings.Repeat("0", places-len(v)+1) + v
+	}
+	return sign + v[:len(v)-places] + "." + v[len(v)-places:]
+}
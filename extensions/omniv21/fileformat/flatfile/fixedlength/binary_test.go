@@ -0,0 +1,209 @@
+package fixedlength
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/jf-tech/go-corelib/testlib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnDecl_ValidateBinary(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		decl *ColumnDecl
+		err  string
+	}{
+		{
+			name: "text column",
+			decl: &ColumnDecl{Length: 3},
+		},
+		{
+			name: "text column with decimal_places",
+			decl: &ColumnDecl{Length: 3, DecimalPlaces: testlib.IntPtr(2)},
+			err:  "'decimal_places' is only allowed with a numeric binary type",
+		},
+		{
+			name: "text column with bit_offset",
+			decl: &ColumnDecl{Length: 3, BitOffset: testlib.IntPtr(2)},
+			err:  "'bit_offset' and 'bit_length' are only allowed with type 'bits'",
+		},
+		{
+			name: "int with decimal_places",
+			decl: &ColumnDecl{Length: 4, Type: strs.StrPtr("int32_be"), DecimalPlaces: testlib.IntPtr(2)},
+		},
+		{
+			name: "int with wrong length",
+			decl: &ColumnDecl{Length: 3, Type: strs.StrPtr("uint32_le")},
+			err:  "type 'uint32_le' requires 'length' 4, but got 3",
+		},
+		{
+			name: "packed decimal",
+			decl: &ColumnDecl{Length: 5, Type: strs.StrPtr("packed_decimal"), DecimalPlaces: testlib.IntPtr(2)},
+		},
+		{
+			name: "bits",
+			decl: &ColumnDecl{Length: 2, Type: strs.StrPtr("bits"),
+				BitOffset: testlib.IntPtr(4), BitLength: testlib.IntPtr(12)},
+		},
+		{
+			name: "bits without bit_length",
+			decl: &ColumnDecl{Length: 2, Type: strs.StrPtr("bits"), BitOffset: testlib.IntPtr(4)},
+			err:  "type 'bits' requires both 'bit_offset' and 'bit_length'",
+		},
+		{
+			name: "bits out of range",
+			decl: &ColumnDecl{Length: 2, Type: strs.StrPtr("bits"),
+				BitOffset: testlib.IntPtr(4), BitLength: testlib.IntPtr(13)},
+			err: "'bit_offset' 4 + 'bit_length' 13 exceeds the column's 16 bits",
+		},
+		{
+			name: "bits with decimal_places",
+			decl: &ColumnDecl{Length: 1, Type: strs.StrPtr("bits"),
+				BitOffset: testlib.IntPtr(0), BitLength: testlib.IntPtr(1), DecimalPlaces: testlib.IntPtr(1)},
+			err: "'decimal_places' is not allowed with type 'bits'",
+		},
+		{
+			name: "hex with decimal_places",
+			decl: &ColumnDecl{Length: 1, Type: strs.StrPtr("hex"), DecimalPlaces: testlib.IntPtr(1)},
+			err:  "'decimal_places' is not allowed with type 'hex'",
+		},
+		{
+			name: "unknown type",
+			decl: &ColumnDecl{Length: 1, Type: strs.StrPtr("float32")},
+			err:  "unknown type 'float32'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.decl.validateBinary()
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestColumnDecl_LineToBinaryColumnValue(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		decl     *ColumnDecl
+		line     []byte
+		expected string
+		err      string
+	}{
+		{
+			name:     "int8",
+			decl:     &ColumnDecl{StartPos: 2, Length: 1, Type: strs.StrPtr("int8")},
+			line:     []byte{0x00, 0x80},
+			expected: "-128",
+		},
+		{
+			name:     "uint8",
+			decl:     &ColumnDecl{StartPos: 2, Length: 1, Type: strs.StrPtr("uint8")},
+			line:     []byte{0x00, 0x80},
+			expected: "128",
+		},
+		{
+			name:     "int16_be",
+			decl:     &ColumnDecl{StartPos: 1, Length: 2, Type: strs.StrPtr("int16_be")},
+			line:     []byte{0xFF, 0xFE},
+			expected: "-2",
+		},
+		{
+			name:     "uint16_le",
+			decl:     &ColumnDecl{StartPos: 1, Length: 2, Type: strs.StrPtr("uint16_le")},
+			line:     []byte{0xFE, 0xFF},
+			expected: "65534",
+		},
+		{
+			name: "int32_le with decimal_places",
+			decl: &ColumnDecl{StartPos: 1, Length: 4, Type: strs.StrPtr("int32_le"),
+				DecimalPlaces: testlib.IntPtr(2)},
+			line:     []byte{0xCF, 0xC7, 0xFF, 0xFF}, // -14385
+			expected: "-143.85",
+		},
+		{
+			name: "uint32_be with more decimal_places than digits",
+			decl: &ColumnDecl{StartPos: 1, Length: 4, Type: strs.StrPtr("uint32_be"),
+				DecimalPlaces: testlib.IntPtr(3)},
+			line:     []byte{0x00, 0x00, 0x00, 0x05},
+			expected: "0.005",
+		},
+		{
+			name:     "int64_be",
+			decl:     &ColumnDecl{StartPos: 1, Length: 8, Type: strs.StrPtr("int64_be")},
+			line:     []byte{0x80, 0, 0, 0, 0, 0, 0, 0},
+			expected: "-9223372036854775808",
+		},
+		{
+			name:     "uint64_le",
+			decl:     &ColumnDecl{StartPos: 1, Length: 8, Type: strs.StrPtr("uint64_le")},
+			line:     []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			expected: "18446744073709551615",
+		},
+		{
+			name: "packed_decimal negative",
+			decl: &ColumnDecl{StartPos: 1, Length: 3, Type: strs.StrPtr("packed_decimal"),
+				DecimalPlaces: testlib.IntPtr(2)},
+			line:     []byte{0x01, 0x23, 0x4D},
+			expected: "-12.34",
+		},
+		{
+			name:     "packed_decimal unsigned positive",
+			decl:     &ColumnDecl{StartPos: 1, Length: 2, Type: strs.StrPtr("packed_decimal")},
+			line:     []byte{0x00, 0x0F},
+			expected: "0",
+		},
+		{
+			name:     "packed_decimal negative zero",
+			decl:     &ColumnDecl{StartPos: 1, Length: 1, Type: strs.StrPtr("packed_decimal")},
+			line:     []byte{0x0D},
+			expected: "0",
+		},
+		{
+			name: "packed_decimal invalid sign",
+			decl: &ColumnDecl{StartPos: 1, Length: 2, Type: strs.StrPtr("packed_decimal")},
+			line: []byte{0x12, 0x34},
+			err:  "invalid packed decimal sign in 0x1234",
+		},
+		{
+			name:     "bcd",
+			decl:     &ColumnDecl{StartPos: 1, Length: 4, Type: strs.StrPtr("bcd")},
+			line:     []byte{0x20, 0x24, 0x12, 0x31},
+			expected: "20241231",
+		},
+		{
+			name:     "bits",
+			decl:     &ColumnDecl{StartPos: 1, Length: 2, Type: strs.StrPtr("bits"), BitOffset: testlib.IntPtr(6), BitLength: testlib.IntPtr(4)},
+			line:     []byte{0x02, 0xC0}, // 0000_0010 1100_0000
+			expected: "11",
+		},
+		{
+			name:     "hex",
+			decl:     &ColumnDecl{StartPos: 2, Length: 2, Type: strs.StrPtr("hex")},
+			line:     []byte{0x00, 0xAB, 0x0C},
+			expected: "AB0C",
+		},
+		{
+			name: "line too short",
+			decl: &ColumnDecl{StartPos: 2, Length: 4, Type: strs.StrPtr("uint32_be")},
+			line: []byte{0x00, 0x01, 0x02},
+			err:  "needs 4 bytes, but only got 2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := test.decl.lineToBinaryColumnValue(test.line)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", v)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, v)
+			}
+		})
+	}
+}
@@ -7,18 +7,27 @@ import (
 
 	"github.com/jf-tech/go-corelib/maths"
 
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
 )
 
 // ColumnDecl describes a column of an envelope.
 type ColumnDecl struct {
-	Name        string  `json:"name,omitempty"`
-	StartPos    int     `json:"start_pos,omitempty"`  // 1-based. and rune-based.
-	Length      int     `json:"length,omitempty"`     // rune-based length.
-	LineIndex   *int    `json:"line_index,omitempty"` // 1-based.
-	LinePattern *string `json:"line_pattern,omitempty"`
+	Name          string  `json:"name,omitempty"`
+	StartPos      int     `json:"start_pos,omitempty"`  // 1-based. and rune-based, or byte-based if Type is set.
+	Length        int     `json:"length,omitempty"`     // rune-based length, or byte-based if Type is set.
+	LineIndex     *int    `json:"line_index,omitempty"` // 1-based.
+	LinePattern   *string `json:"line_pattern,omitempty"`
+	Trim          *string `json:"trim,omitempty"`           // overrides file_declaration level `trim`.
+	Type          *string `json:"type,omitempty"`           // binary type. nil means text.
+	DecimalPlaces *int    `json:"decimal_places,omitempty"` // implied decimal places of numeric binary types.
+	BitOffset     *int    `json:"bit_offset,omitempty"`     // 0-based, from the most significant bit.
+	BitLength     *int    `json:"bit_length,omitempty"`
 
 	linePatternRegexp *regexp.Regexp
+	skip              bool // not referenced by any transform; no need to create its IDR node.
+	trim              fileformat.TrimPolicy
 }
 
 func (c *ColumnDecl) lineMatch(lineIndex int, line []byte) bool {
@@ -75,6 +84,8 @@ type EnvelopeDecl struct {
 	Max      *int            `json:"max,omitempty"`
 	Columns  []*ColumnDecl   `json:"columns,omitempty"`
 	Children []*EnvelopeDecl `json:"child_envelopes,omitempty"`
+	// Totals are checked against the envelopes read since the previous instance of this envelope.
+	Totals []*flatfile.TotalDecl `json:"totals,omitempty"`
 
 	fqdn          string // fully hierarchical name to the envelope.
 	childRecDecls []flatfile.RecDecl
@@ -120,6 +131,20 @@ func (e *EnvelopeDecl) ChildDecls() []flatfile.RecDecl {
 	return e.childRecDecls
 }
 
+// TotalDecls implements flatfile.TotalsDecl.
+func (e *EnvelopeDecl) TotalDecls() []*flatfile.TotalDecl {
+	return e.Totals
+}
+
+func (e *EnvelopeDecl) hasColumn(name string) bool {
+	for _, c := range e.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *EnvelopeDecl) rowsBased() bool {
 	if e.Group() {
 		panic("envelope_group is neither rows based nor header/footer based")
@@ -167,7 +192,72 @@ func toFlatFileRecDecls(es []*EnvelopeDecl) []flatfile.RecDecl {
 	return ret
 }
 
+// Supported values of the `file_declaration` level `truncated_last_record` setting, which controls how
+// the trailing data of the input not making up a full record, i.e. a last record shorter than
+// `record_length`, or a last line not terminated by a line terminator, is handled.
+const (
+	// TruncatedLastRecordError fails the read. It's the default with `record_length`.
+	TruncatedLastRecordError = "error"
+	// TruncatedLastRecordSkip drops the last record, as if it weren't in the input.
+	TruncatedLastRecordSkip = "skip"
+	// TruncatedLastRecordPreserve keeps the last record as a line shorter than `record_length`, or as
+	// the last line. It's the default without `record_length`.
+	TruncatedLastRecordPreserve = "preserve"
+)
+
+// Supported values of the `file_declaration` level `raw_line_terminator` setting, which controls
+// whether the raw bytes of the records, see Reader.RawBytes, have each of their lines terminated by
+// '\n'.
+const (
+	// RawLineTerminatorKeep terminates each line of the raw bytes by '\n'. It's the default.
+	RawLineTerminatorKeep = "keep"
+	// RawLineTerminatorDrop leaves the lines of the raw bytes unterminated, e.g. for the raw bytes of
+	// a `record_length` input to be exactly the same as the input.
+	RawLineTerminatorDrop = "drop"
+)
+
 // FileDecl describes fixed-length schema `file_declaration` setting.
 type FileDecl struct {
-	Envelopes []*EnvelopeDecl `json:"envelopes,omitempty"`
+	SkipUnreferencedColumns bool            `json:"skip_unreferenced_columns,omitempty"`
+	Trim                    *string         `json:"trim,omitempty"`
+	LineEnding              *string         `json:"line_ending,omitempty"`
+	RecordLength            *int            `json:"record_length,omitempty"`
+	TruncatedLastRecord     *string         `json:"truncated_last_record,omitempty"`
+	RawLineTerminator       *string         `json:"raw_line_terminator,omitempty"`
+	Envelopes               []*EnvelopeDecl `json:"envelopes,omitempty"`
+
+	skipping     bool   // true if any column is marked as skipped.
+	binaryColumn string // the first binary column, if any, for error messages.
+}
+
+// markSkippedColumns marks the columns that are not referenced by any transform or totals as skipped,
+// unless the value of any of their enclosing envelopes is read as a whole.
+func (f *FileDecl) markSkippedColumns(refs *transform.References) {
+	// columns used by totals must be read, referenced by transforms or not.
+	totalsColumns := map[string]bool{}
+	var markTotals func(envelopes []*EnvelopeDecl)
+	markTotals = func(envelopes []*EnvelopeDecl) {
+		for _, e := range envelopes {
+			for _, t := range e.Totals {
+				totalsColumns[e.Name+"/"+t.Column] = true
+				if t.SumColumn != nil {
+					totalsColumns[t.Source()+"/"+*t.SumColumn] = true
+				}
+			}
+			markTotals(e.Children)
+		}
+	}
+	markTotals(f.Envelopes)
+	var mark func(envelopes []*EnvelopeDecl, valueRead bool)
+	mark = func(envelopes []*EnvelopeDecl, valueRead bool) {
+		for _, e := range envelopes {
+			valueRead := valueRead || refs.ValueRead(e.Name)
+			for _, c := range e.Columns {
+				c.skip = !valueRead && !refs.Referenced(c.Name) && !totalsColumns[e.Name+"/"+c.Name]
+				f.skipping = f.skipping || c.skip
+			}
+			mark(e.Children, valueRead)
+		}
+	}
+	mark(f.Envelopes, false)
 }
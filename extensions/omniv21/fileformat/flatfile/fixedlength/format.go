@@ -14,6 +14,7 @@ import (
 	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/header"
 	"github.com/logward/omniparser/validation"
 )
 
@@ -31,8 +32,9 @@ func NewFixedLengthFileFormat(schemaName string) fileformat.FileFormat {
 }
 
 type fixedLengthFormatRuntime struct {
-	Decl  *FileDecl `json:"file_declaration"`
-	XPath string
+	ParserSettings header.ParserSettings `json:"parser_settings"`
+	Decl           *FileDecl             `json:"file_declaration"`
+	XPath          string
 }
 
 func (f *fixedLengthFormat) ValidateSchema(
@@ -53,9 +55,20 @@ func (f *fixedLengthFormat) ValidateSchema(
 		// err is already context formatted.
 		return nil, err
 	}
+	if runtime.Decl.binaryColumn != "" && !runtime.ParserSettings.RawBytes() {
+		return nil, f.FmtErr(
+			"%s is binary, which doesn't allow 'parser_settings.encoding' other than 'utf-8' "+
+				"or 'parser_settings.invalid_utf8' of '%s' or '%s'",
+			runtime.Decl.binaryColumn, header.InvalidUTF8Replace, header.InvalidUTF8Error)
+	}
 	if finalOutputDecl == nil {
 		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
 	}
+	if runtime.Decl.SkipUnreferencedColumns {
+		if refs := transform.AnalyzeReferences(finalOutputDecl); refs != nil {
+			runtime.Decl.markSkippedColumns(refs)
+		}
+	}
 	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
 	if runtime.XPath != "" {
 		_, err := caches.GetXPathExpr(runtime.XPath)
@@ -68,13 +81,35 @@ func (f *fixedLengthFormat) ValidateSchema(
 }
 
 func (f *fixedLengthFormat) validateFileDecl(decl *FileDecl) error {
-	err := (&validateCtx{}).validateFileDecl(decl)
+	err := decl.Validate()
 	if err != nil {
 		return f.FmtErr(err.Error())
 	}
 	return err
 }
 
+// ParseFileDecl parses and validates a fixedlength2 `file_declaration`, in JSON, for creating Readers with
+// NewReader without an omni schema.
+func ParseFileDecl(declJSON []byte) (*FileDecl, error) {
+	content := []byte(`{"file_declaration":` + string(declJSON) + `}`)
+	err := validation.SchemaValidate("file_declaration", content, v21validation.JSONSchemaFixedLength2FileDeclaration)
+	if err != nil {
+		return nil, err
+	}
+	var runtime fixedLengthFormatRuntime
+	_ = json.Unmarshal(content, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if err = runtime.Decl.Validate(); err != nil {
+		return nil, err
+	}
+	return runtime.Decl, nil
+}
+
+// Validate validates a fixedlength2 `file_declaration` built in code, compiling its envelope header and
+// footer regexps and designating the first envelope as the target if none is.
+func (d *FileDecl) Validate() error {
+	return (&validateCtx{}).validateFileDecl(d)
+}
+
 func (f *fixedLengthFormat) CreateFormatReader(
 	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
 	rt := runtime.(*fixedLengthFormatRuntime)
@@ -185,6 +185,52 @@ func TestValidateSchema(t *testing.T) {
 			finalOutput: nil,
 			err:         "schema 'test': envelope 'e1' column 'c1' has an invalid 'line_pattern' regexp '[': error parsing regexp: missing closing ]: `[`",
 		},
+		{
+			name:   "binary column without record_length",
+			format: fileFormatFixedLength,
+			fileDecl: `
+				{
+					"file_declaration": {
+						"envelopes" : [
+							{ "name": "e1", "columns": [{ "name": "c1", "start_pos": 1, "length": 2, "type": "int16_be" }] }
+						]
+					}
+				}`,
+			finalOutput: nil,
+			err:         "schema 'test': envelope 'e1' column 'c1' is binary, which requires 'record_length'",
+		},
+		{
+			name:   "binary column with non utf-8 encoding",
+			format: fileFormatFixedLength,
+			fileDecl: `
+				{
+					"parser_settings": { "encoding": "windows-1252" },
+					"file_declaration": {
+						"record_length": 2,
+						"envelopes" : [
+							{ "name": "e1", "columns": [{ "name": "c1", "start_pos": 1, "length": 2, "type": "int16_be" }] }
+						]
+					}
+				}`,
+			finalOutput: &transform.Decl{},
+			err:         "schema 'test': envelope 'e1' column 'c1' is binary, which doesn't allow 'parser_settings.encoding' other than 'utf-8' or 'parser_settings.invalid_utf8' of 'replace' or 'error'",
+		},
+		{
+			name:   "binary column with invalid_utf8 replace",
+			format: fileFormatFixedLength,
+			fileDecl: `
+				{
+					"parser_settings": { "invalid_utf8": "replace" },
+					"file_declaration": {
+						"record_length": 2,
+						"envelopes" : [
+							{ "name": "e1", "columns": [{ "name": "c1", "start_pos": 1, "length": 2, "type": "hex" }] }
+						]
+					}
+				}`,
+			finalOutput: &transform.Decl{},
+			err:         "schema 'test': envelope 'e1' column 'c1' is binary, which doesn't allow 'parser_settings.encoding' other than 'utf-8' or 'parser_settings.invalid_utf8' of 'replace' or 'error'",
+		},
 		{
 			name:   "FINAL_OUTPUT decl is nil",
 			format: fileFormatFixedLength,
@@ -295,3 +341,238 @@ func TestCreateFormatReader(t *testing.T) {
 		err.Error())
 	assert.Nil(t, reader)
 }
+
+func TestCreateFormatReader_SkipUnreferencedColumns(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations([]byte(`
+		{
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": {
+					"c1": { "xpath": "c1" },
+					"e2": { "xpath": "e2", "object": { "all": { "xpath": "." } } }
+				}}
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	format := NewFixedLengthFileFormat("test-schema")
+	runtime, err := format.ValidateSchema(
+		fileFormatFixedLength,
+		[]byte(`
+			{
+				"file_declaration": {
+					"skip_unreferenced_columns": true,
+					"envelopes" : [
+						{
+							"rows": 2,
+							"columns": [
+								{ "name": "c1", "start_pos": 1, "length": 2, "line_index": 1 },
+								{ "name": "c2", "start_pos": 3, "length": 2, "line_index": 1 }
+							],
+							"child_envelopes": [
+								{
+									"name": "e2",
+									"columns": [ { "name": "c3", "start_pos": 1, "length": 4 } ]
+								}
+							]
+						}
+					]
+				}
+			}`),
+		finalOutputDecl)
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader("test-input", strings.NewReader("1234\n5678\nabcd\n"), runtime)
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	// c2 is skipped; c3 isn't, because the entire value of its envelope 'e2' is read.
+	assert.Equal(t, `{"c1":"12","e2":{"c3":"abcd"}}`, idr.JSONify2(n))
+	assert.Equal(t, "1234\n5678\nabcd\n", string(r.(*Reader).RecordBytes()))
+	r.Release(n)
+}
+
+func TestCreateFormatReader_Trim(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	format := NewFixedLengthFileFormat("test-schema")
+	runtime, err := format.ValidateSchema(
+		fileFormatFixedLength,
+		[]byte(`
+			{
+				"file_declaration": {
+					"trim": "right",
+					"envelopes" : [
+						{
+							"columns": [
+								{ "name": "c1", "start_pos": 1, "length": 4 },
+								{ "name": "c2", "start_pos": 5, "length": 4, "trim": "both" },
+								{ "name": "c3", "start_pos": 9, "length": 4, "trim": "none" }
+							]
+						}
+					]
+				}
+			}`),
+		finalOutputDecl)
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader("test-input", strings.NewReader(" a   b   c  \n"), runtime)
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c1":" a","c2":"b","c3":" c  "}`, idr.JSONify2(n))
+}
+
+func TestCreateFormatReader_LineEndingAndRecordLength(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": { "FINAL_OUTPUT": { "xpath": "." } } }`), nil, nil)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name      string
+		setting   string
+		input     string
+		expected  []string
+		expectErr string
+	}{
+		{
+			name:     "default: LF and CRLF",
+			setting:  ``,
+			input:    "1234\r\n5678\n",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":"78"}`},
+		},
+		{
+			name:     "cr",
+			setting:  `"line_ending": "cr",`,
+			input:    "1234\r5678\r",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":"78"}`},
+		},
+		{
+			name:     "mixed",
+			setting:  `"line_ending": "mixed",`,
+			input:    "1234\r5678\r\nabcd\nefgh",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":"78"}`, `{"c1":"ab","c2":"cd"}`, `{"c1":"ef","c2":"gh"}`},
+		},
+		{
+			name:     "record_length, trailing line terminator ignored",
+			setting:  `"record_length": 4,`,
+			input:    "12345678abcd\r\n",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":"78"}`, `{"c1":"ab","c2":"cd"}`},
+		},
+		{
+			name:     "record_length, CR and LF are data",
+			setting:  `"record_length": 4,`,
+			input:    "1\r\n45678",
+			expected: []string{`{"c1":"1\r","c2":"\n4"}`, `{"c1":"56","c2":"78"}`},
+		},
+		{
+			name:      "record_length with incomplete last record",
+			setting:   `"record_length": 4,`,
+			input:     "123456",
+			expected:  []string{`{"c1":"12","c2":"34"}`},
+			expectErr: "input 'test-input' line 2: incomplete record: expected 4 bytes, but only got 2",
+		},
+		{
+			name:     "record_length with incomplete last record skipped",
+			setting:  `"record_length": 4, "truncated_last_record": "skip",`,
+			input:    "123456",
+			expected: []string{`{"c1":"12","c2":"34"}`},
+		},
+		{
+			name:     "record_length with incomplete last record preserved",
+			setting:  `"record_length": 4, "truncated_last_record": "preserve",`,
+			input:    "12345\r\n",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"5","c2":""}`},
+		},
+		{
+			name:     "unterminated last line preserved by default",
+			setting:  ``,
+			input:    "1234\n56",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":""}`},
+		},
+		{
+			name:     "unterminated last line skipped",
+			setting:  `"line_ending": "cr", "truncated_last_record": "skip",`,
+			input:    "1234\r56",
+			expected: []string{`{"c1":"12","c2":"34"}`},
+		},
+		{
+			name:      "unterminated last line",
+			setting:   `"truncated_last_record": "error",`,
+			input:     "1234\r\n56",
+			expected:  []string{`{"c1":"12","c2":"34"}`},
+			expectErr: "input 'test-input' line 2: last line is not terminated by a line terminator, input might be truncated",
+		},
+		{
+			name:     "terminated last line",
+			setting:  `"truncated_last_record": "error",`,
+			input:    "1234\r\n5678\r\n",
+			expected: []string{`{"c1":"12","c2":"34"}`, `{"c1":"56","c2":"78"}`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			format := NewFixedLengthFileFormat("test-schema")
+			runtime, err := format.ValidateSchema(
+				fileFormatFixedLength,
+				[]byte(`
+					{
+						"file_declaration": {
+							`+test.setting+`
+							"envelopes" : [
+								{
+									"columns": [
+										{ "name": "c1", "start_pos": 1, "length": 2 },
+										{ "name": "c2", "start_pos": 3, "length": 2 }
+									]
+								}
+							]
+						}
+					}`),
+				finalOutputDecl)
+			assert.NoError(t, err)
+			r, err := format.CreateFormatReader("test-input", strings.NewReader(test.input), runtime)
+			assert.NoError(t, err)
+			var records []string
+			for {
+				n, err := r.Read()
+				if err != nil {
+					if test.expectErr != "" {
+						assert.Equal(t, test.expectErr, err.Error())
+					} else {
+						assert.Equal(t, io.EOF, err)
+					}
+					break
+				}
+				records = append(records, idr.JSONify2(n))
+				r.Release(n)
+			}
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestParseFileDecl(t *testing.T) {
+	decl, err := ParseFileDecl([]byte(`{
+		"envelopes" : [
+			{
+				"rows": 2,
+				"columns": [
+					{ "name": "letters", "start_pos": 1, "length": 3, "line_pattern": "^[a-z]" },
+					{ "name": "numerics", "start_pos": 1, "length": 3, "line_pattern": "^[0-9]" }
+				]
+			}
+		]
+	}`))
+	assert.NoError(t, err)
+	reader := NewReader("test-input", strings.NewReader("abcd\n1234\n"), decl, nil)
+	n, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"letters":"abc","numerics":"123"}`, idr.JSONify2(n))
+	reader.Release(n)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = ParseFileDecl([]byte(`{ "envelopes": [ { "rows": 0 } ] }`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema 'file_declaration' validation failed")
+
+	_, err = ParseFileDecl([]byte(`{ "envelopes": [ { "name": "e", "header": "[" } ] }`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid 'header' regexp '['")
+}
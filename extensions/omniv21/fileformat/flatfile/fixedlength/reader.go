@@ -2,66 +2,154 @@ package fixedlength
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/antchfx/xpath"
 	"github.com/jf-tech/go-corelib/ios"
+	"github.com/jf-tech/go-corelib/maths"
+	"github.com/jf-tech/go-corelib/strs"
 
+	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/input"
 )
 
 type line struct {
 	lineNum int    // 1-based
-	b       []byte // either a copy of a line content or a direct ref into bufio.Reader.
+	b       []byte // either a copy of a line content or a direct ref into bufio.Reader or r.mem.
 	copied  bool   // see notes in reader.readLine()
 }
 
-type reader struct {
+const defaultBufSize = 4096
+
+// Reader is the fixedlength2 FormatReader: it reads the envelopes of a fixed-length input, per a
+// FileDecl, and returns the target ones as IDR nodes. It can be used on its own, without an omni schema,
+// see NewReader.
+type Reader struct {
 	inputName string
 	r         *bufio.Reader
 	hr        *flatfile.HierarchyReader
 	linesRead int    // total number of lines read in so far
 	linesBuf  []line // linesBuf contains all the unprocessed lines
+	posBegin  int    // first line consumed by the last Read call.
+	posEnd    int    // last line consumed by the last Read call.
+	skipping  bool   // if true, unreferenced columns are skipped.
+	recBytes  []byte // raw bytes of the lines turned into IDR nodes by the last Read call.
+	recLen    int    // if > 0, input has no line terminators, and each line is exactly recLen bytes.
+	truncated string // how the trailing data not making up a full line is handled.
+	rawNoLF   bool   // if true, lines in recBytes aren't terminated by '\n'; see raw_line_terminator.
+	inMem     bool   // if true, input is in memory, and lines are sliced directly out of mem.
+	mem       []byte // unread content of an in-memory input.
+	totals    *flatfile.Totals
+	totalsErr []string  // totals discrepancies found by the current Read call, if any.
+	deferred  *deferred // result of a Read call, deferred to report totals discrepancies first.
+}
+
+type deferred struct {
+	n   *idr.Node
+	err error
 }
 
-// NewReader creates an FormatReader for fixed-length file format.
+// NewReader creates a Reader for fixed-length file format. decl must have been validated, i.e. it's
+// either from ParseFileDecl or FileDecl.Validate has been called on it. If targetXPathExpr isn't nil,
+// only the target envelopes matching it are returned.
 func NewReader(
-	inputName string, r io.Reader, decl *FileDecl, targetXPathExpr *xpath.Expr) *reader {
-	reader := &reader{
+	inputName string, r io.Reader, decl *FileDecl, targetXPathExpr *xpath.Expr) *Reader {
+	reader := &Reader{
 		inputName: inputName,
-		r:         bufio.NewReader(r),
+		skipping:  decl.skipping,
+	}
+	reader.rawNoLF =
+		strs.StrPtrOrElse(decl.RawLineTerminator, RawLineTerminatorKeep) == RawLineTerminatorDrop
+	if decl.RecordLength != nil {
+		reader.recLen = *decl.RecordLength
+		reader.truncated = strs.StrPtrOrElse(decl.TruncatedLastRecord, TruncatedLastRecordError)
+	} else {
+		reader.truncated = strs.StrPtrOrElse(decl.TruncatedLastRecord, TruncatedLastRecordPreserve)
+		r = flatfile.NormalizeLineEndings(r, strs.StrPtrOrElse(decl.LineEnding, ""))
+	}
+	if m, ok := r.(input.InMemory); ok && m.Bytes() != nil {
+		reader.inMem, reader.mem = true, m.Bytes()
+	} else {
+		// bufio.Reader.Peek can't peek more than its buffer size.
+		reader.r = bufio.NewReaderSize(r, maths.MaxInt(reader.recLen, defaultBufSize))
 	}
 	reader.hr = flatfile.NewHierarchyReader(
 		toFlatFileRecDecls(decl.Envelopes), reader, targetXPathExpr)
+	reader.totals = flatfile.NewTotals(toFlatFileRecDecls(decl.Envelopes))
 	return reader
 }
 
 // Read implements fileformat.FormatReader interface, reading in data from input and returns
 // target IDR node.
-func (r *reader) Read() (*idr.Node, error) {
+func (r *Reader) Read() (*idr.Node, error) {
+	if d := r.deferred; d != nil {
+		r.deferred = nil
+		return d.n, d.err
+	}
+	n, err := r.read()
+	if len(r.totalsErr) > 0 {
+		// Totals discrepancies are found on trailer envelopes read on the way to the next target
+		// envelope (or the end of input), so they are reported first, with the target deferred
+		// to the next Read call.
+		r.deferred = &deferred{n: n, err: err}
+		totalsErr := strings.Join(r.totalsErr, "; ")
+		r.totalsErr = r.totalsErr[:0]
+		return nil, flatfile.ErrTotalsMismatch(totalsErr)
+	}
+	return n, err
+}
+
+func (r *Reader) read() (*idr.Node, error) {
+	begin := r.unprocessedLineNum()
+	r.recBytes = r.recBytes[:0]
 	n, err := r.hr.Read()
 	switch {
 	case err == nil:
+		r.posBegin, r.posEnd = begin, r.unprocessedLineNum()-1
 		return n, nil
 	case flatfile.IsErrFewerThanMinOccurs(err):
 		e := err.(flatfile.ErrFewerThanMinOccurs)
 		envelopeDecl := e.RecDecl.(*EnvelopeDecl)
-		return nil, ErrInvalidFixedLength(r.fmtErrStr(r.unprocessedLineNum(),
+		return nil, r.invalidFixedLength(r.unprocessedLineNum(),
 			"envelope/envelope_group '%s' needs min occur %d, but only got %d",
-			envelopeDecl.fqdn, envelopeDecl.MinOccurs(), e.ActualOcccurs))
+			envelopeDecl.fqdn, envelopeDecl.MinOccurs(), e.ActualOcccurs)
 	case flatfile.IsErrUnexpectedData(err):
-		return nil, ErrInvalidFixedLength(r.fmtErrStr(r.unprocessedLineNum(), "unexpected data"))
+		return nil, r.invalidFixedLength(r.unprocessedLineNum(), "unexpected data")
 	default:
 		return nil, err
 	}
 }
 
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of lines
+// consumed by the last successful Read call.
+func (r *Reader) RecordPosition() (int, int) {
+	return r.posBegin, r.posEnd
+}
+
+// RecordBytes implements fileformat.RecordBytesReporter, returning the raw bytes of the last record
+// read, if unreferenced columns are skipped; or nil otherwise.
+func (r *Reader) RecordBytes() []byte {
+	if !r.skipping {
+		return nil
+	}
+	return r.recBytes
+}
+
+// RawBytes implements fileformat.RawBytesReporter, returning the raw bytes of the lines consumed by
+// the last successful Read call, each terminated by '\n', unless raw_line_terminator is `drop`.
+func (r *Reader) RawBytes() []byte {
+	return r.recBytes
+}
+
 // MoreUnprocessedData implements flatfile.RecReader, telling whether there is still unprocessed
 // data or not.
-func (r *reader) MoreUnprocessedData() (bool, error) {
+func (r *Reader) MoreUnprocessedData() (bool, error) {
 	if len(r.linesBuf) > 0 {
 		return true, nil
 	}
@@ -74,7 +162,7 @@ func (r *reader) MoreUnprocessedData() (bool, error) {
 // ReadAndMatch implements flatfile.RecReader, reading unprocessed data (from buffer or from IO),
 // trying to match against the given non-group typed record decl, and converting the data into IDR
 // node if asked to.
-func (r *reader) ReadAndMatch(
+func (r *Reader) ReadAndMatch(
 	decl flatfile.RecDecl, createIDR bool) (matched bool, node *idr.Node, err error) {
 	envelopeDecl := decl.(*EnvelopeDecl)
 	if envelopeDecl.rowsBased() {
@@ -83,7 +171,7 @@ func (r *reader) ReadAndMatch(
 	return r.readAndMatchHeaderFooterBasedEnvelope(envelopeDecl, createIDR)
 }
 
-func (r *reader) readAndMatchRowsBasedEnvelope(
+func (r *Reader) readAndMatchRowsBasedEnvelope(
 	decl *EnvelopeDecl, createNode bool) (bool, *idr.Node, error) {
 	for len(r.linesBuf) < decl.rows() {
 		if err := r.readLine(); err != nil {
@@ -101,16 +189,19 @@ func (r *reader) readAndMatchRowsBasedEnvelope(
 		}
 	}
 	if createNode {
-		n := r.linesToNode(decl, decl.rows())
+		n, err := r.linesToNode(decl, decl.rows())
 		// Once those rows have been converted into IDR node, we're done with them, and remove
 		// them from the unprocessed line buffer.
 		r.popFrontLinesBuf(decl.rows())
+		if err != nil {
+			return false, nil, err
+		}
 		return true, n, nil
 	}
 	return true, nil, nil
 }
 
-func (r *reader) readAndMatchHeaderFooterBasedEnvelope(
+func (r *Reader) readAndMatchHeaderFooterBasedEnvelope(
 	decl *EnvelopeDecl, createNode bool) (bool, *idr.Node, error) {
 	if len(r.linesBuf) <= 0 {
 		if err := r.readLine(); err != nil {
@@ -125,8 +216,11 @@ func (r *reader) readAndMatchHeaderFooterBasedEnvelope(
 	for {
 		if decl.matchFooter(r.linesBuf[i].b) {
 			if createNode {
-				n := r.linesToNode(decl, i+1)
+				n, err := r.linesToNode(decl, i+1)
 				r.popFrontLinesBuf(i + 1)
+				if err != nil {
+					return false, nil, err
+				}
 				return true, n, nil
 			}
 			return true, nil, nil
@@ -148,7 +242,7 @@ func (r *reader) readAndMatchHeaderFooterBasedEnvelope(
 	}
 }
 
-func (r *reader) readLine() error {
+func (r *Reader) readLine() error {
 	// https://github.com/logward/omniparser/issues/213
 	//
 	// If we're dealing with multi-lined envelope (either by rows or by header/footer), this
@@ -167,6 +261,9 @@ func (r *reader) readLine() error {
 	//
 	// This way, we optimize for the vast majority cases without
 	// needing allocations, and avoid any potential corruptions in the multi-lined envelope cases.
+	//
+	// Lines of an in-memory input are sliced directly out of r.mem, which never changes, so they're
+	// good as copies.
 	linesBufLen := len(r.linesBuf)
 	if linesBufLen > 0 && !r.linesBuf[linesBufLen-1].copied {
 		cp := make([]byte, len(r.linesBuf[linesBufLen-1].b))
@@ -180,45 +277,187 @@ func (r *reader) readLine() error {
 		// note3: ios.ByteReadLine's returned []byte is merely pointing into the bufio.Reader's
 		//        internal buffer, thus the content will be invalided if ios.ByteReadLine is called
 		//        again. Caution!
-		b, err := ios.ByteReadLine(r.r)
+		b, err := r.readRawLine()
 		switch {
 		case err == io.EOF:
 			return io.EOF
 		case err != nil:
-			return ErrInvalidFixedLength(r.fmtErrStr(r.linesRead+1, err.Error()))
+			return r.invalidFixedLength(r.linesRead+1, err.Error())
 		}
 		r.linesRead++
 		if len(b) > 0 {
-			r.linesBuf = append(r.linesBuf, line{lineNum: r.linesRead, b: b})
+			r.linesBuf = append(r.linesBuf, line{lineNum: r.linesRead, b: b, copied: r.inMem})
 			return nil
 		}
 	}
 }
 
-func (r *reader) linesToNode(decl *EnvelopeDecl, n int) *idr.Node {
+// readRawLine reads in a line, either delimited by line terminators, or of exactly r.recLen bytes if
+// the input has no line terminators. Same as ios.ByteReadLine, the returned []byte may point into the
+// bufio.Reader's internal buffer.
+func (r *Reader) readRawLine() ([]byte, error) {
+	if r.inMem {
+		return r.readMemLine()
+	}
+	if r.checkLastLine() {
+		return r.readTerminatedLine()
+	}
+	if r.recLen <= 0 {
+		return ios.ByteReadLine(r.r)
+	}
+	b, err := r.r.Peek(r.recLen)
+	switch {
+	case err == nil:
+		_, _ = r.r.Discard(r.recLen)
+		return b, nil
+	case err == io.EOF && strings.Trim(string(b), "\r\n") == "":
+		// Some tools append a line terminator at the end of an otherwise unterminated file.
+		_, _ = r.r.Discard(len(b))
+		return nil, io.EOF
+	case err == io.EOF:
+		_, _ = r.r.Discard(len(b))
+		return r.truncatedLastRecord(b)
+	default:
+		return nil, err
+	}
+}
+
+// checkLastLine tells if the input has line terminators, and its last line not terminated by one
+// isn't simply kept as a line, as per truncated_last_record.
+func (r *Reader) checkLastLine() bool {
+	return r.recLen <= 0 &&
+		(r.truncated == TruncatedLastRecordSkip || r.truncated == TruncatedLastRecordError)
+}
+
+// readTerminatedLine is the counterpart of ios.ByteReadLine that tells apart the last line not
+// terminated by '\n', which is handled by truncatedLastRecord. Same as ios.ByteReadLine, the
+// returned []byte may point into the bufio.Reader's internal buffer.
+func (r *Reader) readTerminatedLine() ([]byte, error) {
+	var line []byte
+	for {
+		b, err := r.r.ReadSlice('\n')
+		if line != nil || err == bufio.ErrBufferFull {
+			line = append(line, b...)
+			b = line
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == nil:
+			b = b[:len(b)-1]
+			if len(b) > 0 && b[len(b)-1] == '\r' {
+				b = b[:len(b)-1]
+			}
+			return b, nil
+		case err == io.EOF && len(b) > 0:
+			return r.truncatedLastRecord(b)
+		default:
+			return nil, err
+		}
+	}
+}
+
+// truncatedLastRecord handles the trailing data of the input not making up a full line, i.e.
+// shorter than r.recLen, or not terminated by a line terminator, as per truncated_last_record. The
+// trailing line terminators, if any, aren't part of the preserved record.
+func (r *Reader) truncatedLastRecord(b []byte) ([]byte, error) {
+	switch {
+	case len(bytes.Trim(b, "\r\n")) == 0 || r.truncated == TruncatedLastRecordSkip:
+		return nil, io.EOF
+	case r.truncated == TruncatedLastRecordPreserve:
+		return bytes.TrimRight(b, "\r\n"), nil
+	case r.recLen <= 0:
+		return nil, errors.New(
+			"last line is not terminated by a line terminator, input might be truncated")
+	default:
+		return nil, fmt.Errorf("incomplete record: expected %d bytes, but only got %d", r.recLen, len(b))
+	}
+}
+
+// readMemLine is the in-memory counterpart of readRawLine, slicing the line directly out of r.mem.
+func (r *Reader) readMemLine() ([]byte, error) {
+	if len(r.mem) == 0 {
+		return nil, io.EOF
+	}
+	if r.recLen > 0 {
+		if len(r.mem) >= r.recLen {
+			b := r.mem[:r.recLen]
+			r.mem = r.mem[r.recLen:]
+			return b, nil
+		}
+		b := r.mem
+		r.mem = nil
+		if len(bytes.Trim(b, "\r\n")) == 0 {
+			return nil, io.EOF
+		}
+		return r.truncatedLastRecord(b)
+	}
+	// same as ios.ByteReadLine, the trailing '\n' or "\r\n" is dropped.
+	i := bytes.IndexByte(r.mem, '\n')
+	if i < 0 {
+		b := r.mem
+		r.mem = nil
+		if r.checkLastLine() {
+			return r.truncatedLastRecord(b)
+		}
+		return b, nil
+	}
+	b := r.mem[:i]
+	r.mem = r.mem[i+1:]
+	if len(b) > 0 && b[len(b)-1] == '\r' {
+		b = b[:len(b)-1]
+	}
+	return b, nil
+}
+
+// linesToNode converts the first n lines in r.linesBuf into an IDR node of the envelope. A binary
+// column failing to decode means the content is corrupted, and an ErrInvalidFixedLength is returned.
+func (r *Reader) linesToNode(decl *EnvelopeDecl, n int) (*idr.Node, error) {
 	if len(r.linesBuf) < n {
 		panic(
 			fmt.Sprintf("linesBuf has %d lines but requested %d lines to convert",
 				len(r.linesBuf), n))
 	}
 	node := idr.CreateNode(idr.ElementNode, decl.Name)
+	for i := 0; i < n; i++ {
+		r.recBytes = append(r.recBytes, r.linesBuf[i].b...)
+		if !r.rawNoLF {
+			r.recBytes = append(r.recBytes, '\n')
+		}
+	}
 	for col := range decl.Columns {
 		colDecl := decl.Columns[col]
+		if colDecl.skip {
+			continue
+		}
 		for i := 0; i < n; i++ {
 			if !colDecl.lineMatch(i, r.linesBuf[i].b) {
 				continue
 			}
+			v := ""
+			if colDecl.binary() {
+				var err error
+				if v, err = colDecl.lineToBinaryColumnValue(r.linesBuf[i].b); err != nil {
+					idr.RemoveAndReleaseTree(node)
+					return nil, r.invalidFixedLength(r.linesBuf[i].lineNum,
+						"envelope '%s' column '%s': %s", decl.fqdn, colDecl.Name, err.Error())
+				}
+			} else {
+				v = colDecl.trim.Apply(colDecl.lineToColumnValue(r.linesBuf[i].b))
+			}
 			colNode := idr.CreateNode(idr.ElementNode, colDecl.Name)
 			idr.AddChild(node, colNode)
-			colVal := idr.CreateNode(idr.TextNode, colDecl.lineToColumnValue(r.linesBuf[i].b))
-			idr.AddChild(colNode, colVal)
+			idr.AddChild(colNode, idr.CreateNode(idr.TextNode, v))
 			break
 		}
 	}
-	return node
+	for _, d := range r.totals.Observe(decl, node) {
+		r.totalsErr = append(r.totalsErr, r.fmtErrStr(r.linesBuf[0].lineNum, "totals mismatch: %s", d))
+	}
+	return node, nil
 }
 
-func (r *reader) popFrontLinesBuf(n int) {
+func (r *Reader) popFrontLinesBuf(n int) {
 	if n > len(r.linesBuf) {
 		panic(fmt.Sprintf(
 			"less lines (%d) in r.linesBuf than requested pop front count (%d)",
@@ -231,7 +470,7 @@ func (r *reader) popFrontLinesBuf(n int) {
 	r.linesBuf = r.linesBuf[:newLen]
 }
 
-func (r *reader) unprocessedLineNum() int {
+func (r *Reader) unprocessedLineNum() int {
 	if len(r.linesBuf) > 0 {
 		return r.linesBuf[0].lineNum
 	}
@@ -239,27 +478,40 @@ func (r *reader) unprocessedLineNum() int {
 }
 
 // Release implements fileformat.FormatReader interface, releasing a finished IDR target node.
-func (r *reader) Release(n *idr.Node) {
+func (r *Reader) Release(n *idr.Node) {
 	r.hr.Release(n)
 }
 
 // IsContinuableError implements fileformat..FormatReader interface, checking if an error is
 // fatal or not.
-func (r *reader) IsContinuableError(err error) bool {
+func (r *Reader) IsContinuableError(err error) bool {
 	return !IsErrInvalidFixedLength(err) && err != io.EOF
 }
 
 // FmtErr implements errs.CtxAwareErr embedded in fileformat.FormatReader, formatting an error
 // with line info.
-func (r *reader) FmtErr(format string, args ...interface{}) error {
+func (r *Reader) FmtErr(format string, args ...interface{}) error {
 	return errors.New(r.fmtErrStr(r.unprocessedLineNum(), format, args...))
 }
 
-func (r *reader) fmtErrStr(line int, format string, args ...interface{}) string {
+func (r *Reader) fmtErrStr(line int, format string, args ...interface{}) string {
 	return fmt.Sprintf("input '%s' line %d: %s",
 		r.inputName, line, fmt.Sprintf(format, args...))
 }
 
+// invalidFixedLength creates an ErrInvalidFixedLength, wrapped in an errs.ErrInput.
+func (r *Reader) invalidFixedLength(line int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatFixedLength,
+		Input:  r.inputName,
+		Line:   line,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidFixedLength(r.fmtErrStr(line, "%s", reason)),
+	}
+}
+
 // ErrInvalidFixedLength indicates the fixed-length content is corrupted or IO failure.
 // This is a fatal, non-continuable error.
 type ErrInvalidFixedLength string
@@ -267,12 +519,8 @@ type ErrInvalidFixedLength string
 // Error implements error interface.
 func (e ErrInvalidFixedLength) Error() string { return string(e) }
 
-// IsErrInvalidFixedLength checks if the `err` is of ErrInvalidFixedLength type.
+// IsErrInvalidFixedLength checks if the `err` is of ErrInvalidFixedLength type, or wraps one, e.g. in an errs.ErrInput.
 func IsErrInvalidFixedLength(err error) bool {
-	switch err.(type) {
-	case ErrInvalidFixedLength:
-		return true
-	default:
-		return false
-	}
+	var e ErrInvalidFixedLength
+	return errors.As(err, &e)
 }
@@ -11,8 +11,10 @@ import (
 	"github.com/bradleyjkemp/cupaloy"
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/input"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -125,7 +127,7 @@ func TestMoreUnprocessedData(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{
+			r := &Reader{
 				inputName: "test-input",
 			}
 			if len(test.lines) > 0 {
@@ -228,7 +230,7 @@ func TestReadAndMatchRowsBasedEnvelope(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{
+			r := &Reader{
 				inputName: "test-input",
 				linesRead: len(test.linesBuf),
 				r:         bufio.NewReader(test.r),
@@ -356,7 +358,7 @@ func TestReadAndMatchHeaderFooterBasedEnvelope(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{
+			r := &Reader{
 				inputName: "test-input",
 				linesRead: len(test.linesBuf),
 				r:         bufio.NewReader(test.r),
@@ -385,7 +387,7 @@ func TestReadAndMatchHeaderFooterBasedEnvelope(t *testing.T) {
 }
 
 func TestReadLine(t *testing.T) {
-	r := &reader{
+	r := &Reader{
 		inputName: "test-input",
 		linesRead: 42,
 		linesBuf: []line{
@@ -414,7 +416,7 @@ func TestReadLine(t *testing.T) {
 
 func TestReadLineMultiCalls(t *testing.T) {
 	// See more details about the bug: https://github.com/jf-tech/omniparser/issues/213
-	r := &reader{inputName: "test-input"}
+	r := &Reader{inputName: "test-input"}
 	// construct a two-line input, and total length of the two line input is longer than
 	// the bufio.Reader's internal buffer. Note bufio.Reader has a minReadBufferSize at 16.
 	r.r = bufio.NewReaderSize(strings.NewReader("0123456789\nabcdefghijklmnopq\n!@#"), 16)
@@ -443,6 +445,67 @@ func TestReadLineMultiCalls(t *testing.T) {
 	assert.Equal(t, line{lineNum: 3, b: []byte("!@#"), copied: false}, r.linesBuf[2])
 }
 
+func TestReadMemLine(t *testing.T) {
+	// Lines sliced out of an in-memory input must be the same as read through bufio.Reader.
+	for _, test := range []struct {
+		name      string
+		input     string
+		recLen    int
+		truncated string
+	}{
+		{name: "empty", input: ""},
+		{name: "lf", input: "line 1\n\nline 3\n"},
+		{name: "crlf", input: "line 1\r\n\r\nline 3\r\n"},
+		{name: "no trailing line terminator", input: "line 1\nline 2\r"},
+		{name: "record length", input: "abcdefghi", recLen: 3},
+		{name: "record length with trailing line terminator", input: "abcdef\r\n", recLen: 3},
+		{name: "record length incomplete", input: "abcdefgh", recLen: 3},
+		{name: "unterminated last line skipped", input: "line 1\r\nline 2", truncated: "skip"},
+		{name: "unterminated last line error", input: "line 1\nline 2\r", truncated: "error"},
+		{name: "terminated last line", input: "line 1\r\n\nline 3\n", truncated: "error"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			readAll := func(r *Reader) []string {
+				var lines []string
+				for {
+					b, err := r.readRawLine()
+					if err != nil {
+						return append(lines, err.Error())
+					}
+					lines = append(lines, string(b))
+				}
+			}
+			expected := readAll(&Reader{
+				r:      bufio.NewReader(strings.NewReader(test.input)),
+				recLen: test.recLen, truncated: test.truncated})
+			mem := &Reader{
+				inMem: true, mem: []byte(test.input), recLen: test.recLen, truncated: test.truncated}
+			assert.Equal(t, expected, readAll(mem))
+			assert.Equal(t, 0, len(mem.mem))
+		})
+	}
+}
+
+func TestReadLine_InMem(t *testing.T) {
+	r := NewReader("test-input", input.NewBytesReader([]byte("0123456789\n\nabc\n")), &FileDecl{}, nil)
+	assert.True(t, r.inMem)
+	assert.Nil(t, r.r)
+	assert.NoError(t, r.readLine())
+	assert.NoError(t, r.readLine())
+	// in-memory lines are never copied, yet stay intact across reads.
+	assert.Equal(t, []line{
+		{lineNum: 1, b: []byte("0123456789"), copied: true},
+		{lineNum: 3, b: []byte("abc"), copied: true},
+	}, r.linesBuf)
+	assert.Equal(t, io.EOF, r.readLine())
+	// line endings requiring normalization, or a non in-memory input, go through bufio.Reader.
+	r = NewReader("test-input", input.NewBytesReader([]byte("a\rb")),
+		&FileDecl{LineEnding: strs.StrPtr(flatfile.LineEndingCR)}, nil)
+	assert.False(t, r.inMem)
+	r = NewReader("test-input", strings.NewReader("a"), &FileDecl{}, nil)
+	assert.False(t, r.inMem)
+}
+
 func TestLinesToNode(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -450,6 +513,7 @@ func TestLinesToNode(t *testing.T) {
 		n        int
 		cols     []*ColumnDecl
 		panicStr string
+		err      string
 	}{
 		{
 			name:     "n > len(lines)",
@@ -473,9 +537,31 @@ func TestLinesToNode(t *testing.T) {
 				{Name: "A", StartPos: 1, Length: 1},
 			},
 		},
+		{
+			name:  "binary columns",
+			lines: [][]byte{{0x01, 0x02, 0xFF, 0xFE, 0x12, 0x34, 0x5D, 0xA5}},
+			n:     1,
+			cols: []*ColumnDecl{
+				{Name: "U", StartPos: 1, Length: 2, Type: strs.StrPtr("uint16_be")},
+				{Name: "I", StartPos: 3, Length: 2, Type: strs.StrPtr("int16_le")},
+				{Name: "P", StartPos: 5, Length: 3, Type: strs.StrPtr("packed_decimal"),
+					DecimalPlaces: testlib.IntPtr(2)},
+				{Name: "B", StartPos: 8, Length: 1, Type: strs.StrPtr("bits"),
+					BitOffset: testlib.IntPtr(1), BitLength: testlib.IntPtr(3)},
+			},
+		},
+		{
+			name:  "binary column decoding failure",
+			lines: [][]byte{{0x12, 0x3F}},
+			n:     1,
+			cols: []*ColumnDecl{
+				{Name: "P", StartPos: 1, Length: 2, Type: strs.StrPtr("bcd")},
+			},
+			err: "input 'test' line 0: envelope 'test-envelope' column 'P': invalid packed digit in 0x123F",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			r := &reader{}
+			r := &Reader{inputName: "test"}
 			r.linesBuf = make([]line, len(test.lines))
 			for i := range test.lines {
 				r.linesBuf[i] = line{lineNum: i, b: test.lines[i]}
@@ -487,9 +573,17 @@ func TestLinesToNode(t *testing.T) {
 						test.n)
 				})
 			} else {
-				node := r.linesToNode(
-					&EnvelopeDecl{Name: "test-envelope", Columns: test.cols},
+				node, err := r.linesToNode(
+					&EnvelopeDecl{Name: "test-envelope", fqdn: "test-envelope", Columns: test.cols},
 					test.n)
+				if test.err != "" {
+					assert.Error(t, err)
+					assert.True(t, IsErrInvalidFixedLength(err))
+					assert.Equal(t, test.err, err.Error())
+					assert.Nil(t, node)
+					return
+				}
+				assert.NoError(t, err)
 				cupaloy.SnapshotT(t, idr.JSONify1(node))
 			}
 		})
@@ -497,7 +591,7 @@ func TestLinesToNode(t *testing.T) {
 }
 
 func TestPopFrontLinesBuf(t *testing.T) {
-	r := &reader{}
+	r := &Reader{}
 	r.linesBuf = make([]line, 3, 10)
 	r.linesBuf[0] = line{lineNum: 10, b: []byte("a")}
 	r.linesBuf[1] = line{lineNum: 11, b: []byte("b")}
@@ -514,14 +608,14 @@ func TestPopFrontLinesBuf(t *testing.T) {
 }
 
 func TestUnprocessedLineNum(t *testing.T) {
-	r := &reader{linesRead: 42}
+	r := &Reader{linesRead: 42}
 	assert.Equal(t, 42+1, r.unprocessedLineNum())
 	r.linesBuf = []line{{lineNum: 13}}
 	assert.Equal(t, 13, r.unprocessedLineNum())
 }
 
 func TestIsContinuableError(t *testing.T) {
-	r := &reader{}
+	r := &Reader{}
 	assert.True(t, r.IsContinuableError(r.FmtErr("some error")))
 	assert.False(t, r.IsContinuableError(ErrInvalidFixedLength("invalid envelope")))
 	assert.False(t, r.IsContinuableError(io.EOF))
@@ -532,3 +626,123 @@ func TestIsErrInvalidFixedLength(t *testing.T) {
 	assert.Equal(t, "test", ErrInvalidFixedLength("test").Error())
 	assert.False(t, IsErrInvalidFixedLength(errors.New("test")))
 }
+
+func TestRecordPosition(t *testing.T) {
+	format := NewFixedLengthFileFormat("test-schema")
+	rt, err := format.ValidateSchema(
+		fileFormatFixedLength,
+		[]byte(`
+			{
+				"file_declaration": {
+					"envelopes" : [
+						{ "name": "h", "header": "^H", "min": 1, "max": 1 },
+						{ "name": "r", "rows": 2, "is_target": true }
+					]
+				}
+			}
+		`),
+		&transform.Decl{})
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader("test-input", strings.NewReader("H\na1\na2\nb1\nb2\n"), rt)
+	assert.NoError(t, err)
+	pr := r.(*Reader)
+	begin, end := pr.RecordPosition()
+	assert.Equal(t, 0, begin)
+	assert.Equal(t, 0, end)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = pr.RecordPosition()
+	assert.Equal(t, 1, begin)
+	assert.Equal(t, 3, end)
+	assert.Equal(t, "H\na1\na2\n", string(pr.RawBytes()))
+	// no unreferenced columns skipped, so the IDR node contains all the data for the checksum.
+	assert.Nil(t, pr.RecordBytes())
+	n, err = r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = pr.RecordPosition()
+	assert.Equal(t, 4, begin)
+	assert.Equal(t, 5, end)
+	assert.Equal(t, "b1\nb2\n", string(pr.RawBytes()))
+}
+
+func TestRawBytes_RawLineTerminatorDrop(t *testing.T) {
+	r := NewReader("test-input", strings.NewReader("abcdefgh"), &FileDecl{
+		RecordLength:      testlib.IntPtr(4),
+		RawLineTerminator: strs.StrPtr(RawLineTerminatorDrop),
+		Envelopes:         []*EnvelopeDecl{{Name: "e", IsTarget: true}},
+	}, nil)
+	var raw []byte
+	for {
+		n, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		raw = append(raw, r.RawBytes()...)
+		r.Release(n)
+	}
+	// the raw bytes of all the records are exactly the input.
+	assert.Equal(t, "abcdefgh", string(raw))
+}
+
+func TestRead_Totals(t *testing.T) {
+	format := NewFixedLengthFileFormat("test-schema")
+	rt, err := format.ValidateSchema(
+		fileFormatFixedLength,
+		[]byte(`
+			{
+				"file_declaration": {
+					"envelopes" : [
+						{
+							"name": "batch", "type": "envelope_group",
+							"child_envelopes": [
+								{
+									"name": "entry", "header": "^E", "is_target": true,
+									"columns": [ { "name": "amount", "start_pos": 2, "length": 6 } ]
+								},
+								{
+									"name": "control", "header": "^C", "min": 1, "max": 1,
+									"columns": [
+										{ "name": "count", "start_pos": 2, "length": 2 },
+										{ "name": "amount", "start_pos": 4, "length": 6 }
+									],
+									"totals": [
+										{ "column": "count", "count": "entry" },
+										{ "column": "amount", "sum": "entry", "sum_column": "amount" }
+									]
+								}
+							]
+						}
+					]
+				}
+			}
+		`),
+		&transform.Decl{})
+	assert.NoError(t, err)
+	r, err := format.CreateFormatReader(
+		"test-input",
+		strings.NewReader("E001.50\nE002.25\nC03003.75\nE010.00\nC01010.00\n"),
+		rt)
+	assert.NoError(t, err)
+	for _, expected := range []string{"001.50", "002.25", "", "010.00"} {
+		n, err := r.Read()
+		if expected == "" {
+			assert.Nil(t, n)
+			assert.Error(t, err)
+			assert.True(t, flatfile.IsErrTotalsMismatch(err))
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t,
+				"input 'test-input' line 3: totals mismatch: 'control' column 'count' value '03' doesn't match the count of 'entry': 2",
+				err.Error())
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, n.FirstChild.InnerText())
+		r.Release(n)
+	}
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Equal(t, io.EOF, err)
+}
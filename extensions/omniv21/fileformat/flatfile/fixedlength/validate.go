@@ -1,22 +1,43 @@
 package fixedlength
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/jf-tech/go-corelib/caches"
 	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 )
 
 type validateCtx struct {
 	seenTarget bool
+	trim       *string
+	envelopes  map[string][]*EnvelopeDecl // all the envelopes/envelope_groups, by name.
+	fileDecl   *FileDecl
 }
 
 func (ctx *validateCtx) validateFileDecl(fileDecl *FileDecl) error {
+	ctx.trim = fileDecl.Trim
+	ctx.fileDecl = fileDecl
+	if fileDecl.RecordLength != nil && fileDecl.LineEnding != nil {
+		return fmt.Errorf("'record_length' and 'line_ending' cannot be both specified")
+	}
+	ctx.envelopes = map[string][]*EnvelopeDecl{}
 	for _, envelopeDecl := range fileDecl.Envelopes {
 		if err := ctx.validateEnvelopeDecl(envelopeDecl.Name, envelopeDecl); err != nil {
 			return err
 		}
 	}
+	if err := ctx.validateTotalDecls(fileDecl.Envelopes); err != nil {
+		return err
+	}
+	if fileDecl.binaryColumn != "" && fileDecl.RecordLength == nil {
+		// binary records can contain any bytes, line terminators included, thus they can only be
+		// delimited by a fixed length.
+		return fmt.Errorf("%s is binary, which requires 'record_length'", fileDecl.binaryColumn)
+	}
 	if !ctx.seenTarget && len(fileDecl.Envelopes) > 0 {
 		// for easy of use and convenience, if no is_target=true envelope is specified, then
 		// the first one will be automatically designated as target envelope.
@@ -27,6 +48,7 @@ func (ctx *validateCtx) validateFileDecl(fileDecl *FileDecl) error {
 
 func (ctx *validateCtx) validateEnvelopeDecl(fqdn string, envelopeDecl *EnvelopeDecl) (err error) {
 	envelopeDecl.fqdn = fqdn
+	ctx.envelopes[envelopeDecl.Name] = append(ctx.envelopes[envelopeDecl.Name], envelopeDecl)
 	if envelopeDecl.Header != nil {
 		if envelopeDecl.headerRegexp, err = caches.GetRegex(*envelopeDecl.Header); err != nil {
 			return fmt.Errorf(
@@ -76,7 +98,43 @@ func (ctx *validateCtx) validateEnvelopeDecl(fqdn string, envelopeDecl *Envelope
 	return nil
 }
 
+// validateTotalDecls validates the totals declared on the envelopes and their descendants. It must be
+// called after all the envelopes have been validated, since totals can refer to any of them.
+func (ctx *validateCtx) validateTotalDecls(envelopeDecls []*EnvelopeDecl) error {
+	for _, envelopeDecl := range envelopeDecls {
+		for _, t := range envelopeDecl.Totals {
+			if err := ctx.validateTotalDecl(envelopeDecl, t); err != nil {
+				return fmt.Errorf("envelope '%s' totals column '%s' %s", envelopeDecl.fqdn, t.Column, err.Error())
+			}
+		}
+		if err := ctx.validateTotalDecls(envelopeDecl.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *validateCtx) validateTotalDecl(envelopeDecl *EnvelopeDecl, t *flatfile.TotalDecl) error {
+	if !envelopeDecl.hasColumn(t.Column) {
+		return errors.New("is not a column of the envelope")
+	}
+	sources := ctx.envelopes[t.Source()]
+	if len(sources) == 0 {
+		return fmt.Errorf("refers to non-existing envelope '%s'", t.Source())
+	}
+	for _, source := range sources {
+		if source.Group() {
+			return fmt.Errorf("refers to envelope_group '%s'", source.fqdn)
+		}
+		if t.SumColumn != nil && !source.hasColumn(*t.SumColumn) {
+			return fmt.Errorf("refers to non-existing column '%s' of envelope '%s'", *t.SumColumn, source.fqdn)
+		}
+	}
+	return nil
+}
+
 func (ctx *validateCtx) validateColumnDecl(fqdn string, colDecl *ColumnDecl) (err error) {
+	colDecl.trim = fileformat.ResolveTrimPolicy(colDecl.Trim, ctx.trim)
 	if colDecl.LineIndex != nil && colDecl.LinePattern != nil {
 		return fmt.Errorf(
 			"envelope '%s' column '%s' cannot have both `line_index` and `line_pattern` specified at the same time",
@@ -89,5 +147,11 @@ func (ctx *validateCtx) validateColumnDecl(fqdn string, colDecl *ColumnDecl) (er
 				fqdn, colDecl.Name, *colDecl.LinePattern, err.Error())
 		}
 	}
+	if err = colDecl.validateBinary(); err != nil {
+		return fmt.Errorf("envelope '%s' column '%s': %s", fqdn, colDecl.Name, err.Error())
+	}
+	if colDecl.binary() && ctx.fileDecl.binaryColumn == "" {
+		ctx.fileDecl.binaryColumn = fmt.Sprintf("envelope '%s' column '%s'", fqdn, colDecl.Name)
+	}
 	return nil
 }
@@ -6,6 +6,8 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile"
 )
 
 func TestValidateFileDecl_AutoTargetFirstEnvelope(t *testing.T) {
@@ -122,6 +124,38 @@ func TestValidateFileDecl_InvalidColumnLinePattern(t *testing.T) {
 		err.Error())
 }
 
+func TestValidateFileDecl_InvalidBinaryColumn(t *testing.T) {
+	err := (&validateCtx{}).validateFileDecl(&FileDecl{
+		Envelopes: []*EnvelopeDecl{
+			{Name: "A", Columns: []*ColumnDecl{
+				{Name: "c", StartPos: 1, Length: 3, Type: strs.StrPtr("int16_be")}}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "envelope 'A' column 'c': type 'int16_be' requires 'length' 2, but got 3", err.Error())
+}
+
+func TestValidateFileDecl_BinaryColumnWithoutRecordLength(t *testing.T) {
+	err := (&validateCtx{}).validateFileDecl(&FileDecl{
+		Envelopes: []*EnvelopeDecl{
+			{Name: "A", Columns: []*ColumnDecl{
+				{Name: "t", StartPos: 1, Length: 3},
+				{Name: "c", StartPos: 4, Length: 2, Type: strs.StrPtr("int16_be")}}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "envelope 'A' column 'c' is binary, which requires 'record_length'", err.Error())
+}
+
+func TestValidateFileDecl_RecordLengthAndLineEndingSameTime(t *testing.T) {
+	err := (&validateCtx{}).validateFileDecl(&FileDecl{
+		LineEnding:   strs.StrPtr("cr"),
+		RecordLength: testlib.IntPtr(10),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, "'record_length' and 'line_ending' cannot be both specified", err.Error())
+}
+
 func TestValidateFileDecl_Success(t *testing.T) {
 	col1 := &ColumnDecl{Name: "c1", LineIndex: testlib.IntPtr(1)}
 	col2 := &ColumnDecl{Name: "c2"}
@@ -153,3 +187,63 @@ func TestValidateFileDecl_Success(t *testing.T) {
 	assert.True(t, fd.Envelopes[0].Children[0].Columns[0].lineMatch(0, []byte("C")))
 	assert.True(t, fd.Envelopes[0].Children[0].Columns[2].lineMatch(0, []byte("C")))
 }
+
+func TestValidateFileDecl_Totals(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		totals []*flatfile.TotalDecl
+		err    string
+	}{
+		{
+			name:   "column not of the envelope",
+			totals: []*flatfile.TotalDecl{{Column: "x", Count: strs.StrPtr("E")}},
+			err:    "envelope 'G/C' totals column 'x' is not a column of the envelope",
+		},
+		{
+			name:   "non-existing envelope",
+			totals: []*flatfile.TotalDecl{{Column: "count", Count: strs.StrPtr("X")}},
+			err:    "envelope 'G/C' totals column 'count' refers to non-existing envelope 'X'",
+		},
+		{
+			name:   "envelope_group",
+			totals: []*flatfile.TotalDecl{{Column: "count", Count: strs.StrPtr("G")}},
+			err:    "envelope 'G/C' totals column 'count' refers to envelope_group 'G'",
+		},
+		{
+			name: "non-existing sum column",
+			totals: []*flatfile.TotalDecl{
+				{Column: "count", Sum: strs.StrPtr("E"), SumColumn: strs.StrPtr("x")}},
+			err: "envelope 'G/C' totals column 'count' refers to non-existing column 'x' of envelope 'G/E'",
+		},
+		{
+			name: "success",
+			totals: []*flatfile.TotalDecl{
+				{Column: "count", Count: strs.StrPtr("E")},
+				{Column: "count", Sum: strs.StrPtr("E"), SumColumn: strs.StrPtr("amount")},
+				{Column: "count", Count: strs.StrPtr("C")},
+			},
+			err: "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&validateCtx{}).validateFileDecl(&FileDecl{
+				Envelopes: []*EnvelopeDecl{
+					{
+						Name: "G",
+						Type: strs.StrPtr(typeGroup),
+						Children: []*EnvelopeDecl{
+							{Name: "E", Columns: []*ColumnDecl{{Name: "amount"}}},
+							{Name: "C", Columns: []*ColumnDecl{{Name: "count"}}, Totals: test.totals},
+						},
+					},
+				},
+			})
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package flatfile
+
+import (
+	"io"
+
+	"github.com/jf-tech/go-corelib/ios"
+)
+
+// Supported values of the `file_declaration` level `line_ending` setting of flat file formats.
+const (
+	LineEndingLF    = "lf"
+	LineEndingCRLF  = "crlf"
+	LineEndingCR    = "cr"
+	LineEndingMixed = "mixed"
+)
+
+var (
+	cr   = []byte("\r")
+	lf   = []byte("\n")
+	crlf = []byte("\r\n")
+)
+
+// NormalizeLineEndings returns an io.Reader in which all the line terminators specified by lineEnding
+// are turned into LF, the line terminator that flat file readers natively split lines on. Note LF and
+// CRLF (with the CR dropped) are natively supported thus need no normalization.
+func NormalizeLineEndings(r io.Reader, lineEnding string) io.Reader {
+	switch lineEnding {
+	case LineEndingCR:
+		return ios.NewBytesReplacingReader(r, cr, lf)
+	case LineEndingMixed:
+		// CRLF must be replaced first, or it would become two line terminators.
+		return ios.NewBytesReplacingReader(ios.NewBytesReplacingReader(r, crlf, lf), cr, lf)
+	default:
+		return r
+	}
+}
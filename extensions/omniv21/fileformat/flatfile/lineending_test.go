@@ -0,0 +1,29 @@
+package flatfile
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	for _, test := range []struct {
+		lineEnding string
+		input      string
+		expected   string
+	}{
+		{lineEnding: "", input: "a\nb\r\nc\rd", expected: "a\nb\r\nc\rd"},
+		{lineEnding: LineEndingLF, input: "a\nb\r\nc\rd", expected: "a\nb\r\nc\rd"},
+		{lineEnding: LineEndingCRLF, input: "a\nb\r\nc\rd", expected: "a\nb\r\nc\rd"},
+		{lineEnding: LineEndingCR, input: "a\rb\rc", expected: "a\nb\nc"},
+		{lineEnding: LineEndingMixed, input: "a\nb\r\nc\rd\r\r\n", expected: "a\nb\nc\nd\n\n"},
+	} {
+		t.Run(test.lineEnding, func(t *testing.T) {
+			b, err := ioutil.ReadAll(NormalizeLineEndings(strings.NewReader(test.input), test.lineEnding))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
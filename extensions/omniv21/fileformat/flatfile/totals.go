@@ -0,0 +1,220 @@
+package flatfile
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// TotalDecl declares a check, on a trailer record such as the batch control or file trailer record of
+// a payment batch file, that one of its columns matches a total computed over the records read since
+// the previous instance of the trailer record, or since the beginning of the input.
+// Exactly one of Count and Sum must be specified. (JSON schema validation will ensure this.)
+type TotalDecl struct {
+	Column    string  `json:"column,omitempty"`     // the trailer record column holding the total.
+	Count     *string `json:"count,omitempty"`      // name of the records counted.
+	Sum       *string `json:"sum,omitempty"`        // name of the records whose SumColumn values are summed.
+	SumColumn *string `json:"sum_column,omitempty"` // required by Sum.
+	Digits    *int    `json:"digits,omitempty"`     // if set, only the rightmost digits of Sum are checked.
+}
+
+// Source returns the name of the records the total is computed over.
+func (d *TotalDecl) Source() string {
+	if d.Count != nil {
+		return *d.Count
+	}
+	return *d.Sum
+}
+
+func (d *TotalDecl) describe() string {
+	if d.Count != nil {
+		return fmt.Sprintf("count of '%s'", *d.Count)
+	}
+	return fmt.Sprintf("sum of '%s' column '%s'", *d.Sum, *d.SumColumn)
+}
+
+// TotalsDecl is implemented by RecDecls that can declare totals.
+type TotalsDecl interface {
+	RecDecl
+	TotalDecls() []*TotalDecl
+}
+
+type totalCheck struct {
+	decl  *TotalDecl
+	count int64
+	sum   big.Rat
+	bad   string // describes the first source value that isn't a number, if any.
+}
+
+func (c *totalCheck) reset() {
+	c.count = 0
+	c.sum.SetInt64(0)
+	c.bad = ""
+}
+
+// Totals computes and checks, while records are being read, the totals declared on trailer records,
+// e.g. record counts, hash totals and amount sums of payment batch files.
+type Totals struct {
+	checks  map[RecDecl][]*totalCheck // keyed by the trailer record decls declaring the totals.
+	sources map[string][]*totalCheck  // keyed by the name of the records the totals are computed over.
+}
+
+// NewTotals creates a Totals for the totals declared on the decls and their descendants. It returns
+// nil if there are none.
+func NewTotals(decls []RecDecl) *Totals {
+	t := &Totals{checks: map[RecDecl][]*totalCheck{}, sources: map[string][]*totalCheck{}}
+	var add func(decls []RecDecl)
+	add = func(decls []RecDecl) {
+		for _, decl := range decls {
+			if td, ok := decl.(TotalsDecl); ok {
+				for _, totalDecl := range td.TotalDecls() {
+					c := &totalCheck{decl: totalDecl}
+					t.checks[decl] = append(t.checks[decl], c)
+					t.sources[totalDecl.Source()] = append(t.sources[totalDecl.Source()], c)
+				}
+			}
+			add(decl.ChildDecls())
+		}
+	}
+	add(decls)
+	if len(t.checks) == 0 {
+		return nil
+	}
+	return t
+}
+
+// Observe accounts a record, just converted into the IDR node n, into the totals computed over it,
+// then checks the totals declared on it, if any, and restarts their computation. It returns a
+// description of each total that doesn't match. Observe is a no-op on a nil Totals.
+func (t *Totals) Observe(decl RecDecl, n *idr.Node) []string {
+	if t == nil {
+		return nil
+	}
+	for _, c := range t.sources[decl.DeclName()] {
+		c.count++
+		if c.decl.Sum == nil || c.bad != "" {
+			continue
+		}
+		v := strings.TrimSpace(columnValue(n, *c.decl.SumColumn))
+		if v == "" {
+			continue
+		}
+		r, ok := parseDecimal(v)
+		if !ok {
+			c.bad = fmt.Sprintf(
+				"'%s' column '%s' value '%s' is not a number", decl.DeclName(), *c.decl.SumColumn, v)
+			continue
+		}
+		c.sum.Add(&c.sum, r)
+	}
+	var discrepancies []string
+	for _, c := range t.checks[decl] {
+		if d := c.check(decl, n); d != "" {
+			discrepancies = append(discrepancies, d)
+		}
+		c.reset()
+	}
+	return discrepancies
+}
+
+func (c *totalCheck) check(decl RecDecl, n *idr.Node) string {
+	if c.bad != "" {
+		return fmt.Sprintf("'%s' column '%s' cannot be checked: %s", decl.DeclName(), c.decl.Column, c.bad)
+	}
+	v := strings.TrimSpace(columnValue(n, c.decl.Column))
+	expected, ok := parseDecimal(v)
+	if !ok {
+		return fmt.Sprintf("'%s' column '%s' value '%s' is not a number", decl.DeclName(), c.decl.Column, v)
+	}
+	var computed big.Rat
+	switch {
+	case c.decl.Count != nil:
+		computed.SetInt64(c.count)
+	case c.decl.Digits != nil:
+		if !c.sum.IsInt() {
+			return fmt.Sprintf("'%s' column '%s' cannot be checked: %s is %s, not an integer",
+				decl.DeclName(), c.decl.Column, c.decl.describe(), ratString(&c.sum))
+		}
+		modulus := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(*c.decl.Digits)), nil)
+		computed.SetInt(new(big.Int).Mod(c.sum.Num(), modulus))
+	default:
+		computed.Set(&c.sum)
+	}
+	if expected.Cmp(&computed) == 0 {
+		return ""
+	}
+	if c.decl.Digits != nil {
+		return fmt.Sprintf(
+			"'%s' column '%s' value '%s' doesn't match the rightmost %d digits of the %s: %s",
+			decl.DeclName(), c.decl.Column, v, *c.decl.Digits, c.decl.describe(), ratString(&computed))
+	}
+	return fmt.Sprintf("'%s' column '%s' value '%s' doesn't match the %s: %s",
+		decl.DeclName(), c.decl.Column, v, c.decl.describe(), ratString(&computed))
+}
+
+// parseDecimal parses a plain decimal number, such as "-00123.45", exactly.
+func parseDecimal(s string) (*big.Rat, bool) {
+	digits, dot := 0, false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.' && !dot:
+			dot = true
+		case (c == '+' || c == '-') && i == 0:
+		default:
+			return nil, false
+		}
+	}
+	if digits == 0 {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// columnValue returns the value of the column named 'name' of the record node n, or "" if not found.
+func columnValue(n *idr.Node, name string) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == idr.ElementNode && c.Data == name {
+			return c.InnerText()
+		}
+	}
+	return ""
+}
+
+// ratString formats r as a decimal number, with as few decimal places as needed.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// A sum of decimal numbers has a power-of-10 denominator, so this always terminates in practice;
+	// the cap is merely defensive.
+	prec := 1
+	for scaled := new(big.Rat); prec < 64; prec++ {
+		scaled.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(prec)), nil)))
+		if scaled.IsInt() {
+			break
+		}
+	}
+	return r.FloatString(prec)
+}
+
+// ErrTotalsMismatch indicates totals declared on a trailer record don't match the totals computed
+// over the records read before it. This is a continuable error: reading resumes with the records
+// following the trailer record.
+type ErrTotalsMismatch string
+
+// Error implements error interface.
+func (e ErrTotalsMismatch) Error() string { return string(e) }
+
+// IsErrTotalsMismatch checks if the `err` is of ErrTotalsMismatch type.
+func IsErrTotalsMismatch(err error) bool {
+	switch err.(type) {
+	case ErrTotalsMismatch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+package flatfile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+type testTotalsDecl struct {
+	testDecl
+	totals []*TotalDecl
+}
+
+func (d *testTotalsDecl) TotalDecls() []*TotalDecl { return d.totals }
+
+func testRecNode(name string, columns ...string) *idr.Node {
+	n := idr.CreateNode(idr.ElementNode, name)
+	for i := 0; i < len(columns); i += 2 {
+		c := idr.CreateNode(idr.ElementNode, columns[i])
+		idr.AddChild(n, c)
+		idr.AddChild(c, idr.CreateNode(idr.TextNode, columns[i+1]))
+	}
+	return n
+}
+
+func TestNewTotals_NoTotals(t *testing.T) {
+	totals := NewTotals(toDeclSlice([]testDecl{{name: "a", children: []testDecl{{name: "b"}}}}))
+	assert.Nil(t, totals)
+	assert.Nil(t, totals.Observe(testDecl{name: "a"}, testRecNode("a")))
+}
+
+func TestTotals_Observe(t *testing.T) {
+	entry := &testTotalsDecl{testDecl: testDecl{name: "entry"}}
+	control := &testTotalsDecl{
+		testDecl: testDecl{name: "control"},
+		totals: []*TotalDecl{
+			{Column: "count", Count: strs.StrPtr("entry")},
+			{Column: "amount", Sum: strs.StrPtr("entry"), SumColumn: strs.StrPtr("amount")},
+			{Column: "hash", Sum: strs.StrPtr("entry"), SumColumn: strs.StrPtr("routing"), Digits: testIntPtr(8)},
+		},
+	}
+	group := &testTotalsDecl{testDecl: testDecl{name: "batch", group: true}}
+	totals := NewTotals([]RecDecl{group, entry, control})
+	assert.NotNil(t, totals)
+
+	controlNode := func(count, amount, hash string) *idr.Node {
+		return testRecNode("control", "count", count, "amount", amount, "hash", hash)
+	}
+	for _, test := range []struct {
+		name     string
+		entries  []*idr.Node
+		control  *idr.Node
+		expected []string
+	}{
+		{
+			name: "all match",
+			entries: []*idr.Node{
+				testRecNode("entry", "amount", "100.10", "routing", "12345678"),
+				testRecNode("entry", "amount", " 200.05 ", "routing", "87654321"),
+			},
+			control:  controlNode("00002", "300.15", "99999999"),
+			expected: nil,
+		},
+		{
+			name: "hash total truncated; count mismatch",
+			entries: []*idr.Node{
+				testRecNode("entry", "amount", "1", "routing", "99999999"),
+				testRecNode("entry", "amount", "2", "routing", "00000002"),
+			},
+			control:  controlNode("3", "3.00", "00000001"),
+			expected: []string{"'control' column 'count' value '3' doesn't match the count of 'entry': 2"},
+		},
+		{
+			name: "amount and hash mismatch",
+			entries: []*idr.Node{
+				testRecNode("entry", "amount", "-0.25", "routing", "1"),
+				testRecNode("entry", "amount", "", "routing", "2"),
+			},
+			control: controlNode("2", "0.25", "4"),
+			expected: []string{
+				"'control' column 'amount' value '0.25' doesn't match the sum of 'entry' column 'amount': -0.25",
+				"'control' column 'hash' value '4' doesn't match the rightmost 8 digits of the sum of 'entry' column 'routing': 3",
+			},
+		},
+		{
+			name:    "no entries; non-number values",
+			entries: []*idr.Node{testRecNode("entry", "amount", "1/2", "routing", "1.5")},
+			control: controlNode("x", "0", "0"),
+			expected: []string{
+				"'control' column 'count' value 'x' is not a number",
+				"'control' column 'amount' cannot be checked: 'entry' column 'amount' value '1/2' is not a number",
+				"'control' column 'hash' cannot be checked: sum of 'entry' column 'routing' is 1.5, not an integer",
+			},
+		},
+		{
+			name:     "totals restart after each check",
+			control:  controlNode("0", "0", "0"),
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, e := range test.entries {
+				assert.Nil(t, totals.Observe(entry, e))
+			}
+			assert.Equal(t, test.expected, totals.Observe(control, test.control))
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	for _, s := range []string{"0", "-00123.45", "+1.", ".5"} {
+		_, ok := parseDecimal(s)
+		assert.True(t, ok, s)
+	}
+	for _, s := range []string{"", "-", ".", "1/2", "0x10", "1e3", "1.2.3", "1-"} {
+		_, ok := parseDecimal(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestIsErrTotalsMismatch(t *testing.T) {
+	assert.True(t, IsErrTotalsMismatch(ErrTotalsMismatch("test")))
+	assert.Equal(t, "test", ErrTotalsMismatch("test").Error())
+	assert.False(t, IsErrTotalsMismatch(errors.New("test")))
+}
+
+func testIntPtr(i int) *int { return &i }
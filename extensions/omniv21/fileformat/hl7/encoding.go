@@ -0,0 +1,114 @@
+package hl7
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// delimiters are the delimiters of a message, declared by the field separator (MSH-1) and the encoding
+// characters (MSH-2: the component separator, repetition separator, escape character and sub-component
+// separator, in that order) of its MSH segment. A delimiter not declared is 0, and isn't used.
+type delimiters struct {
+	field, component, repetition, escape, subComponent byte
+}
+
+// parseDelimiters parses the delimiters declared by a MSH, FHS or BHS segment, e.g. `MSH|^~\&|APP|...`,
+// returning them along with the encoding characters.
+func parseDelimiters(seg string) (delimiters, string, error) {
+	if len(seg) < 4 {
+		return delimiters{}, "", fmt.Errorf("segment '%s' is missing the field separator", seg)
+	}
+	d := delimiters{field: seg[3]}
+	if !isDelimiter(d.field) {
+		return delimiters{}, "", fmt.Errorf("invalid field separator '%c'", d.field)
+	}
+	encChars := seg[4:]
+	if i := strings.IndexByte(encChars, d.field); i >= 0 {
+		encChars = encChars[:i]
+	}
+	// the 5th encoding character, i.e. the truncation character of v2.7+, doesn't affect parsing.
+	if len(encChars) < 1 || len(encChars) > 5 {
+		return delimiters{}, "", fmt.Errorf(
+			"invalid encoding characters '%s', expected 1 to 5 characters, e.g. '^~\\&'", encChars)
+	}
+	seen := map[byte]bool{d.field: true}
+	for i := 0; i < len(encChars); i++ {
+		c := encChars[i]
+		if !isDelimiter(c) || seen[c] {
+			return delimiters{}, "", fmt.Errorf("invalid encoding characters '%s'", encChars)
+		}
+		seen[c] = true
+	}
+	for i, p := range []*byte{&d.component, &d.repetition, &d.escape, &d.subComponent} {
+		if i < len(encChars) {
+			*p = encChars[i]
+		}
+	}
+	return d, encChars, nil
+}
+
+func isDelimiter(c byte) bool {
+	return c > ' ' && c < 0x7f &&
+		!(c >= '0' && c <= '9') && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z')
+}
+
+// split splits s by sep, or returns s as is if sep isn't used.
+func split(s string, sep byte) []string {
+	if sep == 0 {
+		return []string{s}
+	}
+	return strings.Split(s, string(sep))
+}
+
+// unescape replaces the escape sequences of a value: `\F\`, `\S\`, `\T\`, `\R\` and `\E\` with the
+// field separator, the component separator, the sub-component separator, the repetition separator and
+// the escape character, respectively, and `\Xhh...\` with the bytes of the hex digits. Other escape
+// sequences, e.g. the formatting ones such as `\.br\`, and incomplete ones are kept as is.
+func unescape(s string, d delimiters) string {
+	if d.escape == 0 || strings.IndexByte(s, d.escape) < 0 {
+		return s
+	}
+	var sb strings.Builder
+	for {
+		begin := strings.IndexByte(s, d.escape)
+		if begin < 0 {
+			break
+		}
+		end := strings.IndexByte(s[begin+1:], d.escape)
+		if end < 0 {
+			break
+		}
+		end += begin + 1
+		sb.WriteString(s[:begin])
+		seq := s[begin+1 : end]
+		switch {
+		case seq == "F":
+			sb.WriteByte(d.field)
+		case seq == "S" && d.component != 0:
+			sb.WriteByte(d.component)
+		case seq == "T" && d.subComponent != 0:
+			sb.WriteByte(d.subComponent)
+		case seq == "R" && d.repetition != 0:
+			sb.WriteByte(d.repetition)
+		case seq == "E":
+			sb.WriteByte(d.escape)
+		case len(seq) > 1 && seq[0] == 'X' && isHex(seq[1:]):
+			b, _ := hex.DecodeString(seq[1:])
+			sb.Write(b)
+		default:
+			sb.WriteString(s[begin : end+1])
+		}
+		s = s[end+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+func isHex(s string) bool {
+	if len(s)%2 != 0 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,85 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDelimiters(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		seg      string
+		expected delimiters
+		encChars string
+		err      string
+	}{
+		{
+			name:     "standard",
+			seg:      `MSH|^~\&|APP`,
+			expected: delimiters{field: '|', component: '^', repetition: '~', escape: '\\', subComponent: '&'},
+			encChars: `^~\&`,
+		},
+		{
+			name:     "truncation character",
+			seg:      `MSH|^~\&#|APP`,
+			expected: delimiters{field: '|', component: '^', repetition: '~', escape: '\\', subComponent: '&'},
+			encChars: `^~\&#`,
+		},
+		{
+			name:     "non-standard, partial",
+			seg:      `MSH*:!`,
+			expected: delimiters{field: '*', component: ':', repetition: '!'},
+			encChars: `:!`,
+		},
+		{name: "no field separator", seg: "MSH", err: "segment 'MSH' is missing the field separator"},
+		{name: "invalid field separator", seg: "MSHA^~", err: "invalid field separator 'A'"},
+		{
+			name: "no encoding characters",
+			seg:  "MSH||APP",
+			err:  `invalid encoding characters '', expected 1 to 5 characters, e.g. '^~\&'`,
+		},
+		{
+			name: "too many encoding characters",
+			seg:  `MSH|^~\&#$|APP`,
+			err:  `invalid encoding characters '^~\&#$', expected 1 to 5 characters, e.g. '^~\&'`,
+		},
+		{name: "duplicate encoding characters", seg: `MSH|^~\^|APP`, err: `invalid encoding characters '^~\^'`},
+		{name: "alphanumeric encoding characters", seg: `MSH|^~\a|APP`, err: `invalid encoding characters '^~\a'`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			d, encChars, err := parseDelimiters(test.seg)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, d)
+			assert.Equal(t, test.encChars, encChars)
+		})
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	d := delimiters{field: '|', component: '^', repetition: '~', escape: '\\', subComponent: '&'}
+	for _, test := range []struct {
+		name     string
+		s        string
+		d        delimiters
+		expected string
+	}{
+		{name: "no escape", s: "abc", d: d, expected: "abc"},
+		{name: "delimiters", s: `a\F\b\S\c\T\d\R\e\E\f`, d: d, expected: `a|b^c&d~e\f`},
+		{name: "hex", s: `\X4142\C`, d: d, expected: "ABC"},
+		{name: "invalid hex", s: `\X414\`, d: d, expected: `\X414\`},
+		{name: "formatting kept", s: `line 1\.br\line 2`, d: d, expected: `line 1\.br\line 2`},
+		{name: "incomplete", s: `a\F`, d: d, expected: `a\F`},
+		{name: "escape not declared", s: `a\F\b`, d: delimiters{field: '|', component: '^'}, expected: `a\F\b`},
+		{name: "delimiter not declared", s: `a\T\b`, d: delimiters{field: '|', component: '^', escape: '\\'}, expected: `a\T\b`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, unescape(test.s, test.d))
+		})
+	}
+}
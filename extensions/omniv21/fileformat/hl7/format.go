@@ -0,0 +1,54 @@
+package hl7
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+const (
+	fileFormatHL7 = "hl7"
+)
+
+type hl7FileFormat struct {
+	schemaName string
+}
+
+// NewHL7FileFormat creates a FileFormat for HL7 v2.x messages.
+func NewHL7FileFormat(schemaName string) fileformat.FileFormat {
+	return &hl7FileFormat{schemaName: schemaName}
+}
+
+func (f *hl7FileFormat) ValidateSchema(
+	format string, _ []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatHL7 {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	xpath := strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if xpath != "" {
+		_, err := caches.GetXPathExpr(xpath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s", xpath, err.Error())
+		}
+	}
+	return xpath, nil
+}
+
+func (f *hl7FileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	return NewReader(name, r, runtime.(string))
+}
+
+func (f *hl7FileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
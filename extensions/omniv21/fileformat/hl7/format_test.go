@@ -0,0 +1,65 @@
+package hl7
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		format        string
+		decl          *transform.Decl
+		expectedXPath string
+		expectedErr   string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatHL7,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatHL7,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success, no xpath",
+			format: fileFormatHL7,
+			decl:   &transform.Decl{},
+		},
+		{
+			name:          "success",
+			format:        fileFormatHL7,
+			decl:          &transform.Decl{XPath: strs.StrPtr(" .[MSH/MSH.9/MSH.9.1='ADT'] ")},
+			expectedXPath: ".[MSH/MSH.9/MSH.9.1='ADT']",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewHL7FileFormat("test-schema").ValidateSchema(test.format, nil, test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedXPath, runtime)
+			r, err := NewHL7FileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
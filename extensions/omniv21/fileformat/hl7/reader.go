@@ -0,0 +1,280 @@
+package hl7
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidHL7 indicates the input can't be read. This is a fatal, non-continuable error.
+// Note errors in a message (e.g. an invalid MSH segment) are continuable: the reader simply moves
+// onto the next message.
+type ErrInvalidHL7 string
+
+func (e ErrInvalidHL7) Error() string { return string(e) }
+
+// IsErrInvalidHL7 checks if the `err` is of ErrInvalidHL7 type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidHL7(err error) bool {
+	var e ErrInvalidHL7
+	return errors.As(err, &e)
+}
+
+const (
+	mshSegName = "MSH"
+	// maxSegmentSize is the max size of a segment, e.g. an OBX carrying an encapsulated document.
+	maxSegmentSize = 64 * 1024 * 1024
+)
+
+var (
+	segNameRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]{2}$`)
+	// the batch envelope segments: file header/trailer and batch header/trailer.
+	batchSegNames = map[string]bool{"FHS": true, "FTS": true, "BHS": true, "BTS": true}
+)
+
+type reader struct {
+	inputName     string
+	s             *bufio.Scanner
+	targetXPath   *xpath.Expr
+	segNum        int    // 1-based number of the last segment read.
+	pending       string // a MSH segment read ahead, which starts the next message.
+	pendingSegNum int
+	msgSegNum     int // number of the current message's MSH segment.
+	msgEndSegNum  int // number of the current message's last segment.
+}
+
+// Read returns the next message as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		n, err := r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+// splitSegments is a bufio.SplitFunc returning the segments terminated by CR (as the HL7 standard
+// specifies), LF or CRLF. The empty segments in between, e.g. of CRLF, are skipped by readSegment.
+func splitSegments(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// readSegment returns the next non-empty segment, less the MLLP start block and end block characters,
+// if any.
+func (r *reader) readSegment() (string, error) {
+	for r.s.Scan() {
+		seg := strings.Trim(r.s.Text(), "\x0b\x1c")
+		if seg == "" {
+			continue
+		}
+		r.segNum++
+		return seg, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return "", r.invalidHL7(r.segNum+1, "unable to read segment: %s", err.Error())
+	}
+	return "", io.EOF
+}
+
+func segName(seg string) string {
+	if len(seg) < 3 {
+		return seg
+	}
+	return seg[:3]
+}
+
+func (r *reader) readMessage() (*idr.Node, error) {
+	for r.pending == "" {
+		seg, err := r.readSegment()
+		if err != nil {
+			return nil, err
+		}
+		switch name := segName(seg); {
+		case name == mshSegName:
+			r.pending, r.pendingSegNum = seg, r.segNum
+		case batchSegNames[name]:
+		default:
+			return nil, errors.New(r.fmtErrStr(r.segNum, "unexpected segment '%s' before 'MSH'", seg))
+		}
+	}
+	msh := r.pending
+	r.pending, r.msgSegNum, r.msgEndSegNum = "", r.pendingSegNum, r.pendingSegNum
+	root := idr.CreateNode(idr.DocumentNode, "")
+	d, encChars, err := parseDelimiters(msh)
+	var msgErr error
+	if err != nil {
+		msgErr = errors.New(r.fmtErrStr(r.msgSegNum, "invalid 'MSH' segment: %s", err.Error()))
+	} else {
+		addSegment(root, msh, d, encChars)
+	}
+	for {
+		seg, err := r.readSegment()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			idr.RemoveAndReleaseTree(root)
+			return nil, err
+		}
+		name := segName(seg)
+		if name == mshSegName {
+			r.pending, r.pendingSegNum = seg, r.segNum
+			break
+		}
+		if batchSegNames[name] {
+			// a batch trailer, or the header of the next batch, ends the message.
+			break
+		}
+		r.msgEndSegNum = r.segNum
+		if msgErr != nil {
+			// skip the rest of a broken message.
+			continue
+		}
+		if !segNameRegexp.MatchString(name) || (len(seg) > 3 && seg[3] != d.field) {
+			msgErr = errors.New(r.fmtErrStr(r.segNum, "invalid segment '%s'", seg))
+			continue
+		}
+		addSegment(root, seg, d, "")
+	}
+	if msgErr != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, msgErr
+	}
+	return root, nil
+}
+
+// addSegment adds an element for a segment, with an element for each of its non-empty fields, e.g.
+// `PID.5`, one per repetition, each with an element for each of its non-empty components, e.g.
+// `PID.5.1`, whose value is either the text or, if it has sub-components, an element for each of its
+// non-empty sub-components, e.g. `PID.3.4.2`. encChars, if not empty, are the encoding characters of a
+// MSH segment, whose fields 1 and 2 are then its field separator and encoding characters, as is.
+func addSegment(parent *idr.Node, seg string, d delimiters, encChars string) {
+	fields := split(seg, d.field)
+	name := fields[0]
+	segNode := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, segNode)
+	fieldNum := 1
+	if encChars != "" {
+		addText(segNode, name+".1", string(d.field))
+		addText(segNode, name+".2", encChars)
+		// fields[1] is the encoding characters, the field separator not being a field in between.
+		fields, fieldNum = fields[1:], 3
+	}
+	for _, field := range fields[1:] {
+		fieldName := name + "." + strconv.Itoa(fieldNum)
+		fieldNum++
+		if field == "" {
+			continue
+		}
+		for _, rep := range split(field, d.repetition) {
+			if rep == "" {
+				continue
+			}
+			fieldNode := idr.CreateNode(idr.ElementNode, fieldName)
+			idr.AddChild(segNode, fieldNode)
+			for i, comp := range split(rep, d.component) {
+				if comp == "" {
+					continue
+				}
+				compName := fieldName + "." + strconv.Itoa(i+1)
+				subs := split(comp, d.subComponent)
+				if len(subs) == 1 {
+					addText(fieldNode, compName, unescape(comp, d))
+					continue
+				}
+				compNode := idr.CreateNode(idr.ElementNode, compName)
+				idr.AddChild(fieldNode, compNode)
+				for j, sub := range subs {
+					if sub != "" {
+						addText(compNode, compName+"."+strconv.Itoa(j+1), unescape(sub, d))
+					}
+				}
+			}
+		}
+	}
+}
+
+func addText(parent *idr.Node, name, value string) {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of segments the
+// message returned by the last successful Read call spans, from its MSH segment to its last segment.
+func (r *reader) RecordPosition() (int, int) {
+	return r.msgSegNum, r.msgEndSegNum
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidHL7(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.msgSegNum, format, args...))
+}
+
+func (r *reader) fmtErrStr(segNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' segment %d: %s", r.inputName, segNum, fmt.Sprintf(format, args...))
+}
+
+// invalidHL7 creates an ErrInvalidHL7, wrapped in an errs.ErrInput.
+func (r *reader) invalidHL7(segNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format:  fileFormatHL7,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: segNum,
+		Reason:  reason,
+		Err:     ErrInvalidHL7(r.fmtErrStr(segNum, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for HL7 v2.x file format.
+func NewReader(inputName string, src io.Reader, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	s := bufio.NewScanner(src)
+	s.Buffer(nil, maxSegmentSize)
+	s.Split(splitSegments)
+	return &reader{
+		inputName:   inputName,
+		s:           s,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
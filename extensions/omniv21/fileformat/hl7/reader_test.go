@@ -0,0 +1,182 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+const (
+	testADT = "MSH|^~\\&|REG|HOSP|EHR|HOSP|20240102120000||ADT^A01^ADT_A01|MSG001|P|2.5\r" +
+		"PID|1||12345^^^HOSP&1.2.3&ISO^MR~67890^^^SSA^SS||SMITH^JOHN^\\T\\JR||19800101|M\r" +
+		"PV1|1|I|W1^101^A\r"
+	testORU = "MSH|^~\\&|LAB|HOSP|EHR|HOSP|20240102130000||ORU^R01|MSG002|P|2.5\r" +
+		"OBX|1|NM|GLU^Glucose||5.4|mmol/L\r"
+	// note idr.JSONify2 collapses an element with a single kind of children into an array.
+	testORUJSON = `{"MSH":{"MSH.1":"|","MSH.10":{"MSH.10.1":"MSG002"},"MSH.11":{"MSH.11.1":"P"},` +
+		`"MSH.12":{"MSH.12.1":"2.5"},"MSH.2":"^~\\\u0026","MSH.3":{"MSH.3.1":"LAB"},"MSH.4":{"MSH.4.1":"HOSP"},` +
+		`"MSH.5":{"MSH.5.1":"EHR"},"MSH.6":{"MSH.6.1":"HOSP"},"MSH.7":{"MSH.7.1":"20240102130000"},` +
+		`"MSH.9":{"MSH.9.1":"ORU","MSH.9.2":"R01"}},` +
+		`"OBX":{"OBX.1":{"OBX.1.1":"1"},"OBX.2":{"OBX.2.1":"NM"},"OBX.3":{"OBX.3.1":"GLU","OBX.3.2":"Glucose"},` +
+		`"OBX.5":{"OBX.5.1":"5.4"},"OBX.6":{"OBX.6.1":"mmol/L"}}}`
+)
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		xpath    string
+		expected []string
+	}{
+		{
+			name:  "multiple messages",
+			input: testADT + testORU,
+			expected: []string{
+				`{"MSH":{"MSH.1":"|","MSH.10":{"MSH.10.1":"MSG001"},"MSH.11":{"MSH.11.1":"P"},` +
+					`"MSH.12":{"MSH.12.1":"2.5"},"MSH.2":"^~\\\u0026","MSH.3":{"MSH.3.1":"REG"},"MSH.4":{"MSH.4.1":"HOSP"},` +
+					`"MSH.5":{"MSH.5.1":"EHR"},"MSH.6":{"MSH.6.1":"HOSP"},"MSH.7":{"MSH.7.1":"20240102120000"},` +
+					`"MSH.9":{"MSH.9.1":"ADT","MSH.9.2":"A01","MSH.9.3":"ADT_A01"}},` +
+					`"PID":{"PID.1":{"PID.1.1":"1"},` +
+					`"PID.3":[{"PID.3.1":"12345","PID.3.4":{"PID.3.4.1":"HOSP","PID.3.4.2":"1.2.3","PID.3.4.3":"ISO"},"PID.3.5":"MR"},` +
+					`{"PID.3.1":"67890","PID.3.4":"SSA","PID.3.5":"SS"}],` +
+					`"PID.5":{"PID.5.1":"SMITH","PID.5.2":"JOHN","PID.5.3":"\u0026JR"},` +
+					`"PID.7":{"PID.7.1":"19800101"},"PID.8":{"PID.8.1":"M"}},` +
+					`"PV1":{"PV1.1":{"PV1.1.1":"1"},"PV1.2":{"PV1.2.1":"I"},"PV1.3":{"PV1.3.1":"W1","PV1.3.2":"101","PV1.3.3":"A"}}}`,
+				testORUJSON,
+			},
+		},
+		{
+			name:     "with xpath",
+			input:    testADT + testORU,
+			xpath:    ".[MSH/MSH.9/MSH.9.1='ORU']",
+			expected: []string{testORUJSON},
+		},
+		{
+			name: "LF, CRLF, MLLP framing and batch envelope",
+			input: "FHS|^~\\&|LAB\nBHS|^~\\&|LAB\n\x0b" + strings.Replace(testORU, "\r", "\r\n", -1) + "\x1c\r\n" +
+				"BTS|1\nFTS|1\n",
+			expected: []string{testORUJSON},
+		},
+		{
+			name:  "non-standard delimiters",
+			input: "MSH*:!?#*APP*A:B!C?F??S?D*X#Y\r",
+			expected: []string{`{"MSH":{"MSH.1":"*","MSH.2":":!?#","MSH.3":{"MSH.3.1":"APP"},` +
+				`"MSH.4":[{"MSH.4.1":"A","MSH.4.2":"B"},{"MSH.4.1":"C*:D"}],` +
+				`"MSH.5":{"MSH.5.1":{"MSH.5.1.1":"X","MSH.5.1.2":"Y"}}}}`},
+		},
+		{
+			name:     "no message",
+			input:    "",
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_MessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		msg           string
+		expErr        string
+		nextMsgSegNum int
+	}{
+		{
+			name:          "segment before MSH",
+			msg:           "PID|1\r",
+			expErr:        "input 'test-input' segment 1: unexpected segment 'PID|1' before 'MSH'",
+			nextMsgSegNum: 2,
+		},
+		{
+			name:          "invalid MSH",
+			msg:           "MSH|^~\\^|APP\rPID|1\r",
+			expErr:        "input 'test-input' segment 1: invalid 'MSH' segment: invalid encoding characters '^~\\^'",
+			nextMsgSegNum: 3,
+		},
+		{
+			name:          "invalid segment",
+			msg:           "MSH|^~\\&|APP\rPID|1\rhello\rPV1|1\r",
+			expErr:        "input 'test-input' segment 3: invalid segment 'hello'",
+			nextMsgSegNum: 5,
+		},
+		{
+			name:          "segment not using field separator",
+			msg:           "MSH|^~\\&|APP\rPID*1\r",
+			expErr:        "input 'test-input' segment 2: invalid segment 'PID*1'",
+			nextMsgSegNum: 3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.msg+testORU), "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+			// reader moves onto the next message.
+			n, err = r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, testORUJSON, idr.JSONify2(n))
+			assert.Equal(t, fmt.Sprintf("input 'test-input' segment %d: test", test.nextMsgSegNum), r.FmtErr("test").Error())
+			begin, end := r.RecordPosition()
+			assert.Equal(t, test.nextMsgSegNum, begin)
+			assert.Equal(t, test.nextMsgSegNum+1, end)
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", io.MultiReader(strings.NewReader(testORU), failingReader{}), "")
+	assert.NoError(t, err)
+	_, err = readAll(t, r)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidHL7(err))
+	assert.False(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' segment 3: unable to read segment: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidHL7("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
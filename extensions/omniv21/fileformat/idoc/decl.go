@@ -0,0 +1,80 @@
+package idoc
+
+import (
+	"fmt"
+)
+
+// FieldDecl describes a field of the data (SDATA) of a segment.
+type FieldDecl struct {
+	Name string `json:"name"`
+	// Length is the length of the field, in characters. The fields of a segment are contiguous, in the
+	// order they're declared, the way SAP defines them.
+	Length int `json:"length"`
+}
+
+// SegmentDecl describes the fields of the segments of a segment type.
+type SegmentDecl struct {
+	// Name is the segment type, e.g. `E1EDK01`, or one of its definitions, e.g. `E2EDK01005`.
+	Name   string       `json:"name"`
+	Fields []*FieldDecl `json:"fields"`
+
+	fields []field
+}
+
+// FileDecl describes IDoc specific schema settings for omniparser reader. All settings are optional.
+type FileDecl struct {
+	// SegmentDecls declares the fields of the data of segments. The data of the segments of types not
+	// declared is read as a single SDATA field.
+	SegmentDecls []*SegmentDecl `json:"segments,omitempty"`
+
+	segments map[string]*SegmentDecl // keyed by segment type.
+}
+
+func (d *FileDecl) build() error {
+	d.segments = map[string]*SegmentDecl{}
+	for _, s := range d.SegmentDecls {
+		typ := segmentType(s.Name)
+		if d.segments[typ] != nil {
+			return fmt.Errorf("segment '%s' is declared more than once", typ)
+		}
+		d.segments[typ] = s
+		names := map[string]bool{}
+		s.fields = nil
+		for _, f := range s.Fields {
+			if names[f.Name] {
+				return fmt.Errorf("segment '%s' has more than one field '%s'", typ, f.Name)
+			}
+			names[f.Name] = true
+			s.fields = append(s.fields, field{name: f.Name, length: f.Length})
+		}
+		s.fields = contiguous(s.fields)
+		if last := s.fields[len(s.fields)-1]; last.start+last.length > sdataSize {
+			return fmt.Errorf("segment '%s' has fields of %d characters, more than the %d characters of segment data",
+				typ, last.start+last.length, sdataSize)
+		}
+	}
+	return nil
+}
+
+// segmentType returns the segment type of a segment name, which is either a segment type, e.g.
+// `E1EDK01` or `Z1ORDER`, or a segment definition, i.e. the segment type with its 2nd character being
+// `2` rather than `1` and followed by a 3-digit version, e.g. `E2EDK01005`.
+func segmentType(name string) string {
+	if len(name) < 2 || name[1] != '2' {
+		return name
+	}
+	typ := name[:1] + "1" + name[2:]
+	if n := len(typ); n > 5 && isDigits(typ[n-3:]) {
+		typ = typ[:n-3]
+	}
+	return typ
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package idoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	decl := &FileDecl{}
+	assert.NoError(t, decl.build())
+	assert.Empty(t, decl.segments)
+
+	decl = &FileDecl{
+		SegmentDecls: []*SegmentDecl{
+			{Name: "E2EDK01005", Fields: []*FieldDecl{{Name: "ACTION", Length: 3}, {Name: "KZABS", Length: 1}}},
+			{Name: "Z1ORDER", Fields: []*FieldDecl{{Name: "REF", Length: 1000}}},
+		},
+	}
+	assert.NoError(t, decl.build())
+	assert.Equal(t, 2, len(decl.segments))
+	assert.Equal(t, []field{{"ACTION", 0, 3}, {"KZABS", 3, 1}}, decl.segments["E1EDK01"].fields)
+	assert.Equal(t, []field{{"REF", 0, 1000}}, decl.segments["Z1ORDER"].fields)
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		decl   *FileDecl
+		expErr string
+	}{
+		{
+			name: "duplicate segment",
+			decl: &FileDecl{SegmentDecls: []*SegmentDecl{
+				{Name: "E1EDK01", Fields: []*FieldDecl{{Name: "ACTION", Length: 3}}},
+				{Name: "E2EDK01005", Fields: []*FieldDecl{{Name: "ACTION", Length: 3}}},
+			}},
+			expErr: "segment 'E1EDK01' is declared more than once",
+		},
+		{
+			name: "duplicate field",
+			decl: &FileDecl{SegmentDecls: []*SegmentDecl{
+				{Name: "E1EDK01", Fields: []*FieldDecl{{Name: "ACTION", Length: 3}, {Name: "ACTION", Length: 1}}},
+			}},
+			expErr: "segment 'E1EDK01' has more than one field 'ACTION'",
+		},
+		{
+			name: "fields too long",
+			decl: &FileDecl{SegmentDecls: []*SegmentDecl{
+				{Name: "E1EDK01", Fields: []*FieldDecl{{Name: "ACTION", Length: 3}, {Name: "TEXT", Length: 998}}},
+			}},
+			expErr: "segment 'E1EDK01' has fields of 1001 characters, more than the 1000 characters of segment data",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.decl.build()
+			assert.Error(t, err)
+			assert.Equal(t, test.expErr, err.Error())
+		})
+	}
+}
+
+func TestSegmentType(t *testing.T) {
+	for name, expected := range map[string]string{
+		"E1EDK01":    "E1EDK01",
+		"E2EDK01005": "E1EDK01",
+		"E2EDK14":    "E1EDK14",
+		"Z2ORDER000": "Z1ORDER",
+		"Z1ORDER":    "Z1ORDER",
+		"E2":         "E1",
+		"E":          "E",
+		"":           "",
+	} {
+		assert.Equal(t, expected, segmentType(name), name)
+	}
+}
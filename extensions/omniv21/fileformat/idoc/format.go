@@ -0,0 +1,79 @@
+package idoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/validation"
+)
+
+const (
+	fileFormatIDoc = "idoc"
+)
+
+type idocFileFormat struct {
+	schemaName string
+}
+
+// NewIDocFileFormat creates a FileFormat for SAP IDoc flat files.
+func NewIDocFileFormat(schemaName string) fileformat.FileFormat {
+	return &idocFileFormat{schemaName: schemaName}
+}
+
+type idocFormatRuntime struct {
+	Decl  *FileDecl `json:"file_declaration"`
+	XPath string
+}
+
+func (f *idocFileFormat) ValidateSchema(
+	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatIDoc {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	err := validation.SchemaValidate(f.schemaName, schemaContent, v21validation.JSONSchemaIDocFileDeclaration)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	var runtime idocFormatRuntime
+	_ = json.Unmarshal(schemaContent, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if runtime.Decl == nil {
+		// file_declaration is optional.
+		runtime.Decl = &FileDecl{}
+	}
+	err = runtime.Decl.build()
+	if err != nil {
+		return nil, f.FmtErr("%s", err.Error())
+	}
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	runtime.XPath = strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if runtime.XPath != "" {
+		_, err := caches.GetXPathExpr(runtime.XPath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s",
+				runtime.XPath, err.Error())
+		}
+	}
+	return &runtime, nil
+}
+
+func (f *idocFileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	rt := runtime.(*idocFormatRuntime)
+	return NewReader(name, r, rt.Decl, rt.XPath)
+}
+
+func (f *idocFileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
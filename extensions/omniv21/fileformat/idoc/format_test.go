@@ -0,0 +1,90 @@
+package idoc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatIDoc,
+			schema:      `{"file_declaration": { "segments": [ { "name": "E1EDK01", "fields": [] } ] }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.segments.0.fields: Array must have at least 1 items`,
+		},
+		{
+			name:   "file_declaration build fail",
+			format: fileFormatIDoc,
+			schema: `{"file_declaration": { "segments": [
+				{ "name": "E1EDK01", "fields": [ { "name": "ACTION", "length": 3 } ] },
+				{ "name": "E1EDK01", "fields": [ { "name": "CURCY", "length": 3 } ] }
+			]}}`,
+			expectedErr: `schema 'test-schema': segment 'E1EDK01' is declared more than once`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatIDoc,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatIDoc,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatIDoc,
+			schema: `{"file_declaration": { "segments": [
+				{ "name": "E1EDK01", "fields": [ { "name": "ACTION", "length": 3 }, { "name": "CURCY", "length": 3 } ] }
+			]}}`,
+			decl: &transform.Decl{XPath: strs.StrPtr(" .[EDI_DC40/MESTYP='ORDERS'] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewIDocFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*idocFormatRuntime)
+			assert.Equal(t, ".[EDI_DC40/MESTYP='ORDERS']", rt.XPath)
+			assert.Equal(t, []field{{name: "ACTION", start: 0, length: 3}, {name: "CURCY", start: 3, length: 3}},
+				rt.Decl.segments["E1EDK01"].fields)
+			r, err := NewIDocFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
+
+func TestValidateSchema_NoFileDeclaration(t *testing.T) {
+	runtime, err := NewIDocFileFormat("test-schema").ValidateSchema(fileFormatIDoc, []byte(`{}`), &transform.Decl{})
+	assert.NoError(t, err)
+	rt := runtime.(*idocFormatRuntime)
+	assert.Equal(t, "", rt.XPath)
+	assert.Empty(t, rt.Decl.segments)
+}
@@ -0,0 +1,78 @@
+package idoc
+
+import (
+	"strings"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// controlTabName is the TABNAM of the control records, i.e. of the EDI_DC40 structure, which each IDoc
+// starts with.
+const controlTabName = "EDI_DC40"
+
+// Sizes of the records, i.e. of the EDI_DC40 and EDI_DD40 structures, and of the segment data.
+const (
+	controlRecordSize = 524
+	dataRecordSize    = 1063
+	sdataSize         = 1000
+)
+
+// field is a field of a record, at the 0-based start position, in characters.
+type field struct {
+	name   string
+	start  int
+	length int
+}
+
+// controlFields are the fields of the EDI_DC40 control record.
+var controlFields = contiguous([]field{
+	{name: "TABNAM", length: 10}, {name: "MANDT", length: 3}, {name: "DOCNUM", length: 16},
+	{name: "DOCREL", length: 4}, {name: "STATUS", length: 2}, {name: "DIRECT", length: 1},
+	{name: "OUTMOD", length: 1}, {name: "EXPRSS", length: 1}, {name: "TEST", length: 1},
+	{name: "IDOCTYP", length: 30}, {name: "CIMTYP", length: 30}, {name: "MESTYP", length: 30},
+	{name: "MESCOD", length: 3}, {name: "MESFCT", length: 3}, {name: "STD", length: 1},
+	{name: "STDVRS", length: 6}, {name: "STDMES", length: 6}, {name: "SNDPOR", length: 10},
+	{name: "SNDPRT", length: 2}, {name: "SNDPFC", length: 2}, {name: "SNDPRN", length: 10},
+	{name: "SNDSAD", length: 21}, {name: "SNDLAD", length: 70}, {name: "RCVPOR", length: 10},
+	{name: "RCVPRT", length: 2}, {name: "RCVPFC", length: 2}, {name: "RCVPRN", length: 10},
+	{name: "RCVSAD", length: 21}, {name: "RCVLAD", length: 70}, {name: "CREDAT", length: 8},
+	{name: "CRETIM", length: 6}, {name: "REFINT", length: 14}, {name: "REFGRP", length: 14},
+	{name: "REFMES", length: 14}, {name: "ARCKEY", length: 70}, {name: "SERIAL", length: 20},
+})
+
+// The fields of the EDI_DD40 data records used by the reader.
+var (
+	segnamField = field{"SEGNAM", 0, 30}
+	segnumField = field{"SEGNUM", 49, 6}
+	hlevelField = field{"HLEVEL", 61, 2}
+	sdataField  = field{"SDATA", 63, sdataSize}
+)
+
+// contiguous sets the start positions of fields, one after the other.
+func contiguous(fields []field) []field {
+	start := 0
+	for i := range fields {
+		fields[i].start = start
+		start += fields[i].length
+	}
+	return fields
+}
+
+// value returns the value of a field of a record, with its trailing space padding trimmed. The record
+// may be shorter than its size, e.g. with its trailing spaces trimmed.
+func (f field) value(rec []rune) string {
+	if f.start >= len(rec) {
+		return ""
+	}
+	end := f.start + f.length
+	if end > len(rec) {
+		end = len(rec)
+	}
+	return strings.TrimRight(string(rec[f.start:end]), " ")
+}
+
+func addText(parent *idr.Node, name, value string) {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+}
@@ -0,0 +1,225 @@
+package idoc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidIDoc indicates the input can't be read, e.g. it doesn't start with a control record. This
+// is a fatal, non-continuable error. Note errors in an IDoc (e.g. a segment with an invalid HLEVEL) are
+// continuable: the reader simply moves onto the next IDoc.
+type ErrInvalidIDoc string
+
+func (e ErrInvalidIDoc) Error() string { return string(e) }
+
+// IsErrInvalidIDoc checks if the `err` is of ErrInvalidIDoc type, or wraps one, e.g. in an
+// errs.ErrInput.
+func IsErrInvalidIDoc(err error) bool {
+	var e ErrInvalidIDoc
+	return errors.As(err, &e)
+}
+
+type line struct {
+	num  int
+	text string
+	rec  []rune
+}
+
+func (l *line) isControl() bool {
+	return strings.HasPrefix(l.text, controlTabName)
+}
+
+type reader struct {
+	inputName   string
+	s           *bufio.Scanner
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	lineNum     int   // 1-based number of the last line read.
+	pending     *line // the control record read ahead, which starts the next IDoc.
+	idocBegin   int   // line number of the control record of the last IDoc read.
+	idocEnd     int   // line number of the last record of the last IDoc read.
+}
+
+// Read returns the next IDoc as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		n, err := r.readIDoc()
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+// nextLine returns the next line that isn't blank.
+func (r *reader) nextLine() (*line, error) {
+	for r.s.Scan() {
+		r.lineNum++
+		if text := r.s.Text(); strings.TrimSpace(text) != "" {
+			return &line{num: r.lineNum, text: text, rec: []rune(text)}, nil
+		}
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, r.invalidIDoc(r.lineNum+1, "unable to read line: %s", err.Error())
+	}
+	return nil, io.EOF
+}
+
+// readIDoc reads the next IDoc, i.e. a control record and the data records till the next control
+// record, and builds its segment hierarchy. Upon an error in an IDoc, the rest of it is skipped.
+func (r *reader) readIDoc() (*idr.Node, error) {
+	ctl := r.pending
+	r.pending = nil
+	if ctl == nil {
+		l, err := r.nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if !l.isControl() {
+			return nil, r.invalidIDoc(l.num, "input doesn't start with an %s control record", controlTabName)
+		}
+		ctl = l
+	}
+	r.idocBegin, r.idocEnd = ctl.num, ctl.num
+	var idocErr error
+	if len(ctl.rec) > controlRecordSize {
+		idocErr = r.fmtErr(ctl.num, "control record of %d characters is longer than %d",
+			len(ctl.rec), controlRecordSize)
+	}
+	root := idr.CreateNode(idr.DocumentNode, "")
+	control := idr.CreateNode(idr.ElementNode, controlTabName)
+	idr.AddChild(root, control)
+	for _, f := range controlFields {
+		addText(control, f.name, f.value(ctl.rec))
+	}
+	// parents[i] is the parent of the segments of HLEVEL i+1.
+	parents := []*idr.Node{root}
+	for {
+		l, err := r.nextLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			idr.RemoveAndReleaseTree(root)
+			return nil, err
+		}
+		if l.isControl() {
+			r.pending = l
+			break
+		}
+		r.idocEnd = l.num
+		if idocErr == nil {
+			parents, idocErr = r.addSegment(parents, l)
+		}
+	}
+	if idocErr != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, idocErr
+	}
+	return root, nil
+}
+
+// addSegment adds a data record as a segment, named after its segment type, under the last segment of
+// the level above its HLEVEL, with its data split into the declared fields of the segment type if any,
+// or as a single SDATA field.
+func (r *reader) addSegment(parents []*idr.Node, l *line) ([]*idr.Node, error) {
+	if len(l.rec) > dataRecordSize {
+		return nil, r.fmtErr(l.num, "data record of %d characters is longer than %d", len(l.rec), dataRecordSize)
+	}
+	segnam := segnamField.value(l.rec)
+	hlevel := hlevelField.value(l.rec)
+	level, err := strconv.Atoi(strings.TrimSpace(hlevel))
+	if err != nil || level < 1 || level > len(parents) {
+		return nil, r.fmtErr(l.num, "segment '%s' (SEGNUM '%s') has HLEVEL '%s', expected 1 to %d",
+			segnam, segnumField.value(l.rec), hlevel, len(parents))
+	}
+	typ := segmentType(segnam)
+	n := idr.CreateNode(idr.ElementNode, typ)
+	idr.AddChild(parents[level-1], n)
+	if decl := r.decl.segments[typ]; decl != nil {
+		var sdata []rune
+		if len(l.rec) > sdataField.start {
+			sdata = l.rec[sdataField.start:]
+		}
+		for _, f := range decl.fields {
+			addText(n, f.name, f.value(sdata))
+		}
+	} else {
+		addText(n, sdataField.name, sdataField.value(l.rec))
+	}
+	return append(parents[:level], n), nil
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the line numbers of the
+// control record and the last data record of the IDoc returned by the last successful Read call.
+func (r *reader) RecordPosition() (int, int) {
+	return r.idocBegin, r.idocEnd
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidIDoc(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return r.fmtErr(r.idocBegin, format, args...)
+}
+
+func (r *reader) fmtErr(lineNum int, format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(lineNum, format, args...))
+}
+
+func (r *reader) fmtErrStr(lineNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' line %d: %s", r.inputName, lineNum, fmt.Sprintf(format, args...))
+}
+
+// invalidIDoc creates an ErrInvalidIDoc, wrapped in an errs.ErrInput.
+func (r *reader) invalidIDoc(lineNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatIDoc,
+		Input:  r.inputName,
+		Line:   lineNum,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidIDoc(r.fmtErrStr(lineNum, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for SAP IDoc flat file format.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		s:           bufio.NewScanner(src),
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
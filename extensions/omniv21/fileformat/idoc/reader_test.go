@@ -0,0 +1,274 @@
+package idoc
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+func pad(s string, n int) string {
+	return s + strings.Repeat(" ", n-len(s))
+}
+
+// testControl returns a control record, with its trailing spaces trimmed.
+func testControl(docnum, mestyp string) string {
+	values := map[string]string{"TABNAM": controlTabName, "MANDT": "800", "DOCNUM": docnum, "MESTYP": mestyp}
+	var sb strings.Builder
+	for _, f := range controlFields {
+		sb.WriteString(pad(values[f.name], f.length))
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// testData returns a data record, with its trailing spaces trimmed.
+func testData(segnam, hlevel, sdata string) string {
+	return strings.TrimRight(pad(segnam, 30)+"800"+pad("", 16)+"000001000000"+hlevel+sdata, " ")
+}
+
+// summarize returns the DOCNUM of an IDoc followed by its segment hierarchy, e.g.
+// `1:E1EDK01,E1EDP01(E1EDP19)`.
+func summarize(n *idr.Node) string {
+	var segments func(n *idr.Node) string
+	segments = func(n *idr.Node) string {
+		var s []string
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != idr.ElementNode || c.Data == controlTabName || c.FirstChild.Type == idr.TextNode {
+				continue
+			}
+			if children := segments(c); children != "" {
+				s = append(s, c.Data+"("+children+")")
+			} else {
+				s = append(s, c.Data)
+			}
+		}
+		return strings.Join(s, ",")
+	}
+	docnum, _ := idr.MatchSingle(n, "EDI_DC40/DOCNUM")
+	return docnum.InnerText() + ":" + segments(n)
+}
+
+// readAll returns the summaries of the IDocs read, and the messages of the continuable errors, till
+// io.EOF or a fatal error.
+func readAll(r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		switch {
+		case err == io.EOF:
+			return records, nil
+		case err != nil && r.IsContinuableError(err):
+			records = append(records, "error: "+err.Error())
+			continue
+		case err != nil:
+			return records, err
+		}
+		records = append(records, summarize(n))
+		r.Release(n)
+	}
+}
+
+func testDecl(t *testing.T) *FileDecl {
+	decl := &FileDecl{SegmentDecls: []*SegmentDecl{
+		{Name: "E1EDP19", Fields: []*FieldDecl{{Name: "QUALF", Length: 3}, {Name: "IDTNR", Length: 35}, {Name: "KTEXT", Length: 70}}},
+	}}
+	assert.NoError(t, decl.build())
+	return decl
+}
+
+func testIDoc(docnum string) []string {
+	return []string{
+		testControl(docnum, "ORDERS"),
+		testData("E2EDK01005", "01", "   USD"),
+		testData("E2EDP01008", "01", "000010"),
+		testData("E2EDP19001", "02", "002MAT-1001"),
+		testData("E2EDP01008", "01", "000020"),
+		testData("E2EDP19001", "02", "002MAT-2002"),
+		testData("Z2EDP19EXT000", "03", "EXT"),
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		xpath    string
+		expected []string
+	}{
+		{
+			name:     "single IDoc",
+			input:    strings.Join(testIDoc("1"), "\n"),
+			expected: []string{"1:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))"},
+		},
+		{
+			name:  "multiple IDocs, CRLF and blank lines",
+			input: "\r\n" + strings.Join(append(testIDoc("1"), testIDoc("2")...), "\r\n\r\n") + "\r\n",
+			expected: []string{
+				"1:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+				"2:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+			},
+		},
+		{
+			name:     "IDoc without data records",
+			input:    testControl("1", "ORDERS") + "\n" + testControl("2", "ORDRSP"),
+			expected: []string{"1:", "2:"},
+		},
+		{
+			name:     "with xpath",
+			input:    strings.Join(append(testIDoc("1"), testControl("2", "ORDRSP")), "\n"),
+			xpath:    ".[EDI_DC40/MESTYP='ORDRSP']",
+			expected: []string{"2:"},
+		},
+		{
+			name:     "empty input",
+			input:    " \n\n",
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testDecl(t), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_Fields(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(strings.Join(testIDoc("0000000004711001"), "\n")),
+		testDecl(t), "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	for xpath, expected := range map[string]string{
+		"EDI_DC40/TABNAM":                     "EDI_DC40",
+		"EDI_DC40/DOCNUM":                     "0000000004711001",
+		"EDI_DC40/MESTYP":                     "ORDERS",
+		"EDI_DC40/SERIAL":                     "",
+		"E1EDK01/SDATA":                       "   USD",
+		"E1EDP01[1]/SDATA":                    "000010",
+		"E1EDP01[2]/E1EDP19/QUALF":            "002",
+		"E1EDP01[2]/E1EDP19/IDTNR":            "MAT-2002",
+		"E1EDP01[2]/E1EDP19/KTEXT":            "",
+		"E1EDP01[2]/E1EDP19/Z1EDP19EXT/SDATA": "EXT",
+	} {
+		v, err := idr.MatchSingle(n, xpath)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, v.InnerText(), xpath)
+	}
+	// the declared segments have their fields instead of SDATA.
+	_, err = idr.MatchSingle(n, "E1EDP01[2]/E1EDP19/SDATA")
+	assert.Equal(t, idr.ErrNoMatch, err)
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 1, begin)
+	assert.Equal(t, 7, end)
+	assert.Equal(t, "input 'test-input' line 1: test", r.FmtErr("test").Error())
+}
+
+func TestRead_Errors(t *testing.T) {
+	idoc := testIDoc("1")
+	for _, test := range []struct {
+		name     string
+		recs     []string
+		expected []string
+	}{
+		{
+			name: "HLEVEL skipping a level",
+			recs: append([]string{idoc[0], idoc[1], testData("E2EDP19001", "03", ""), idoc[2]}, testIDoc("2")...),
+			expected: []string{
+				"error: input 'test-input' line 3: segment 'E2EDP19001' (SEGNUM '000001') has HLEVEL '03', expected 1 to 2",
+				"2:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+			},
+		},
+		{
+			name: "invalid HLEVEL",
+			recs: append([]string{idoc[0], testData("E2EDK01005", "XX", "")}, testIDoc("2")...),
+			expected: []string{
+				"error: input 'test-input' line 2: segment 'E2EDK01005' (SEGNUM '000001') has HLEVEL 'XX', expected 1 to 1",
+				"2:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+			},
+		},
+		{
+			name: "HLEVEL missing",
+			recs: []string{idoc[0], "E2EDK01005"},
+			expected: []string{
+				"error: input 'test-input' line 2: segment 'E2EDK01005' (SEGNUM '') has HLEVEL '', expected 1 to 1",
+			},
+		},
+		{
+			name: "data record too long",
+			recs: append([]string{idoc[0], pad(idoc[1], dataRecordSize) + "X"}, testIDoc("2")...),
+			expected: []string{
+				"error: input 'test-input' line 2: data record of 1064 characters is longer than 1063",
+				"2:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+			},
+		},
+		{
+			name: "control record too long",
+			recs: append([]string{pad(idoc[0], controlRecordSize) + "X", idoc[1]}, testIDoc("2")...),
+			expected: []string{
+				"error: input 'test-input' line 1: control record of 525 characters is longer than 524",
+				"2:E1EDK01,E1EDP01(E1EDP19),E1EDP01(E1EDP19(Z1EDP19EXT))",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(strings.Join(test.recs, "\n")), testDecl(t), "")
+			assert.NoError(t, err)
+			records, err := readAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_NotStartingWithControlRecord(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader("\n"+testData("E2EDK01005", "01", "")), testDecl(t), "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidIDoc(err))
+	assert.False(t, r.IsContinuableError(err))
+	assert.Equal(t, "input 'test-input' line 2: input doesn't start with an EDI_DC40 control record", err.Error())
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, fileFormatIDoc, inputErr.Format)
+	assert.Equal(t, 2, inputErr.Line)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input",
+		io.MultiReader(strings.NewReader(strings.Join(testIDoc("1")[:2], "\n")+"\n"), failingReader{}),
+		testDecl(t), "")
+	assert.NoError(t, err)
+	records, err := readAll(r)
+	assert.Nil(t, records)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidIDoc(err))
+	assert.Equal(t, "input 'test-input' line 3: unable to read line: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testDecl(t), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidIDoc("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
@@ -0,0 +1,143 @@
+package iso8583
+
+import (
+	"fmt"
+)
+
+// Message framings, i.e. how messages are delimited in the input.
+const (
+	// FramingLength2B is a 2-byte big endian binary length header before each message, as commonly
+	// used over TCP.
+	FramingLength2B = "length_2b"
+	// FramingLength4A is a 4 ASCII digit length header before each message.
+	FramingLength4A = "length_4a"
+	// FramingLine is one message per line, typical of message logs/dumps. Since binary data may
+	// contain line breaks, it is meant to be used with hex bitmaps and no binary data elements.
+	FramingLine = "line"
+)
+
+// Bitmap encodings.
+const (
+	BitmapBinary = "binary"
+	BitmapHex    = "hex"
+)
+
+// Data element types.
+const (
+	typeFixed   = "fixed"
+	typeLLVar   = "llvar"
+	typeLLLVar  = "lllvar"
+	typeLLLLVar = "llllvar"
+)
+
+// DataElemDecl describes a data element (DE) of ISO 8583 messages.
+type DataElemDecl struct {
+	// Number is the data element number, 2 to 128. (DE1 is the secondary bitmap.)
+	Number int `json:"number"`
+	// Type is one of 'fixed', 'llvar', 'lllvar' and 'llllvar'. The variable length types are
+	// prefixed with 2, 3 or 4 ASCII digits of length, respectively.
+	Type string `json:"type"`
+	// Length is the exact length of 'fixed' data elements, or the max length of variable length
+	// data elements, in characters, or bytes if Binary.
+	Length int `json:"length"`
+	// Binary data elements (e.g. PIN data, MAC) are exposed in IDR as upper case hex strings.
+	Binary bool `json:"binary,omitempty"`
+}
+
+func (d *DataElemDecl) lenDigits() int {
+	switch d.Type {
+	case typeLLVar:
+		return 2
+	case typeLLLVar:
+		return 3
+	case typeLLLLVar:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func (d *DataElemDecl) elemName() string {
+	return fmt.Sprintf("DE%d", d.Number)
+}
+
+// FileDecl describes ISO 8583 specific schema settings for omniparser reader. All settings are
+// optional.
+type FileDecl struct {
+	// Framing defaults to FramingLength2B.
+	Framing *string `json:"framing,omitempty"`
+	// BitmapEncoding defaults to BitmapBinary.
+	BitmapEncoding *string `json:"bitmap_encoding,omitempty"`
+	// DataElemDecls overrides or extends the default data element dictionary, which is based on
+	// ISO 8583:1987.
+	DataElemDecls []*DataElemDecl `json:"data_elements,omitempty"`
+
+	dict [129]*DataElemDecl // indexed by data element number.
+}
+
+func (d *FileDecl) framing() string {
+	if d.Framing == nil {
+		return FramingLength2B
+	}
+	return *d.Framing
+}
+
+func (d *FileDecl) bitmapEncoding() string {
+	if d.BitmapEncoding == nil {
+		return BitmapBinary
+	}
+	return *d.BitmapEncoding
+}
+
+func fixed(n int) *DataElemDecl       { return &DataElemDecl{Type: typeFixed, Length: n} }
+func fixedBinary(n int) *DataElemDecl { return &DataElemDecl{Type: typeFixed, Length: n, Binary: true} }
+func llvar(n int) *DataElemDecl       { return &DataElemDecl{Type: typeLLVar, Length: n} }
+func lllvar(n int) *DataElemDecl      { return &DataElemDecl{Type: typeLLLVar, Length: n} }
+
+// defaultDict is the ISO 8583:1987 data element dictionary.
+var defaultDict = map[int]*DataElemDecl{
+	2: llvar(19), 3: fixed(6), 4: fixed(12), 5: fixed(12), 6: fixed(12), 7: fixed(10), 8: fixed(8),
+	9: fixed(8), 10: fixed(8), 11: fixed(6), 12: fixed(6), 13: fixed(4), 14: fixed(4), 15: fixed(4),
+	16: fixed(4), 17: fixed(4), 18: fixed(4), 19: fixed(3), 20: fixed(3), 21: fixed(3), 22: fixed(3),
+	23: fixed(3), 24: fixed(3), 25: fixed(2), 26: fixed(2), 27: fixed(1), 28: fixed(9), 29: fixed(9),
+	30: fixed(9), 31: fixed(9), 32: llvar(11), 33: llvar(11), 34: llvar(28), 35: llvar(37),
+	36: lllvar(104), 37: fixed(12), 38: fixed(6), 39: fixed(2), 40: fixed(3), 41: fixed(8),
+	42: fixed(15), 43: fixed(40), 44: llvar(25), 45: llvar(76), 46: lllvar(999), 47: lllvar(999),
+	48: lllvar(999), 49: fixed(3), 50: fixed(3), 51: fixed(3), 52: fixedBinary(8), 53: fixed(16),
+	54: lllvar(120), 55: lllvar(999), 56: lllvar(999), 57: lllvar(999), 58: lllvar(999),
+	59: lllvar(999), 60: lllvar(999), 61: lllvar(999), 62: lllvar(999), 63: lllvar(999),
+	64: fixedBinary(8), 65: fixedBinary(1), 66: fixed(1), 67: fixed(2), 68: fixed(3), 69: fixed(3),
+	70: fixed(3), 71: fixed(4), 72: fixed(4), 73: fixed(6), 74: fixed(10), 75: fixed(10),
+	76: fixed(10), 77: fixed(10), 78: fixed(10), 79: fixed(10), 80: fixed(10), 81: fixed(10),
+	82: fixed(12), 83: fixed(12), 84: fixed(12), 85: fixed(12), 86: fixed(16), 87: fixed(16),
+	88: fixed(16), 89: fixed(16), 90: fixed(42), 91: fixed(1), 92: fixed(2), 93: fixed(5),
+	94: fixed(7), 95: fixed(42), 96: fixedBinary(8), 97: fixed(17), 98: fixed(25), 99: llvar(11),
+	100: llvar(11), 101: llvar(17), 102: llvar(28), 103: llvar(28), 104: lllvar(100),
+	105: lllvar(999), 106: lllvar(999), 107: lllvar(999), 108: lllvar(999), 109: lllvar(999),
+	110: lllvar(999), 111: lllvar(999), 112: lllvar(999), 113: lllvar(999), 114: lllvar(999),
+	115: lllvar(999), 116: lllvar(999), 117: lllvar(999), 118: lllvar(999), 119: lllvar(999),
+	120: lllvar(999), 121: lllvar(999), 122: lllvar(999), 123: lllvar(999), 124: lllvar(999),
+	125: lllvar(999), 126: lllvar(999), 127: lllvar(999), 128: fixedBinary(8),
+}
+
+// buildDict merges the schema's data element declarations into the default dictionary.
+func (d *FileDecl) buildDict() error {
+	for num, decl := range defaultDict {
+		declCopy := *decl
+		declCopy.Number = num
+		d.dict[num] = &declCopy
+	}
+	seen := map[int]bool{}
+	for _, decl := range d.DataElemDecls {
+		if seen[decl.Number] {
+			return fmt.Errorf("data element %d is declared more than once", decl.Number)
+		}
+		seen[decl.Number] = true
+		if max := []int{0, 0, 99, 999, 9999}[decl.lenDigits()]; max > 0 && decl.Length > max {
+			return fmt.Errorf("data element %d of type '%s' has 'length' %d > max %d",
+				decl.Number, decl.Type, decl.Length, max)
+		}
+		d.dict[decl.Number] = decl
+	}
+	return nil
+}
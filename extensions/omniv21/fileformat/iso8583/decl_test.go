@@ -0,0 +1,31 @@
+package iso8583
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDict(t *testing.T) {
+	decl := &FileDecl{DataElemDecls: []*DataElemDecl{{Number: 48, Type: typeLLVar, Length: 10}}}
+	assert.NoError(t, decl.buildDict())
+	assert.Equal(t, &DataElemDecl{Number: 2, Type: typeLLVar, Length: 19}, decl.dict[2])
+	assert.Equal(t, &DataElemDecl{Number: 48, Type: typeLLVar, Length: 10}, decl.dict[48])
+	assert.Equal(t, &DataElemDecl{Number: 128, Type: typeFixed, Length: 8, Binary: true}, decl.dict[128])
+	assert.Nil(t, decl.dict[1])
+	// default dictionary must not be altered.
+	assert.Equal(t, lllvar(999), defaultDict[48])
+	assert.Equal(t, FramingLength2B, decl.framing())
+	assert.Equal(t, BitmapBinary, decl.bitmapEncoding())
+
+	decl = &FileDecl{DataElemDecls: []*DataElemDecl{
+		{Number: 48, Type: typeLLVar, Length: 10}, {Number: 48, Type: typeFixed, Length: 10}}}
+	err := decl.buildDict()
+	assert.Error(t, err)
+	assert.Equal(t, "data element 48 is declared more than once", err.Error())
+
+	decl = &FileDecl{DataElemDecls: []*DataElemDecl{{Number: 48, Type: typeLLVar, Length: 100}}}
+	err = decl.buildDict()
+	assert.Error(t, err)
+	assert.Equal(t, "data element 48 of type 'llvar' has 'length' 100 > max 99", err.Error())
+}
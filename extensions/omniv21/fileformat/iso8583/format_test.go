@@ -0,0 +1,77 @@
+package iso8583
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatISO8583,
+			schema:      `{"file_declaration": { "framing": "stx_etx" }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.framing: file_declaration.framing must be one of the following: "length_2b", "length_4a", "line"`,
+		},
+		{
+			name:   "file_declaration validation fail",
+			format: fileFormatISO8583,
+			schema: `{"file_declaration": { "data_elements": [
+				{ "number": 2, "type": "fixed", "length": 16 }, { "number": 2, "type": "llvar", "length": 19 } ] }}`,
+			expectedErr: `schema 'test-schema': data element 2 is declared more than once`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatISO8583,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatISO8583,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatISO8583,
+			schema: `{}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[MTI='0200'] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewISO8583FileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*iso8583FormatRuntime)
+			assert.Equal(t, ".[MTI='0200']", rt.XPath)
+			assert.NotNil(t, rt.Decl.dict[2])
+			r, err := NewISO8583FileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package iso8583
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/ios"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidFraming indicates a message can't be separated out of the input, e.g. the length header
+// is corrupted or the input is truncated. This is a fatal, non-continuable error. Note errors in
+// decoding a properly framed message are continuable: the reader simply moves onto the next message.
+type ErrInvalidFraming string
+
+func (e ErrInvalidFraming) Error() string { return string(e) }
+
+// IsErrInvalidFraming checks if the `err` is of ErrInvalidFraming type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidFraming(err error) bool {
+	var e ErrInvalidFraming
+	return errors.As(err, &e)
+}
+
+type reader struct {
+	inputName   string
+	r           *bufio.Reader
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	msgNum      int // 1-based index of the last message read; line number in FramingLine.
+}
+
+// Read decodes the next message into a record, with an 'MTI' child element and a 'DE<n>' child
+// element for each data element present.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		msg, err := r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		n, err := r.decodeMessage(msg)
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+func (r *reader) readMessage() ([]byte, error) {
+	var msg []byte
+	var length int
+	switch r.decl.framing() {
+	case FramingLine:
+		for {
+			line, err := ios.ByteReadLine(r.r)
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, r.invalidFraming(r.msgNum+1, "unable to read message: %s", err.Error())
+			}
+			r.msgNum++
+			if len(line) > 0 {
+				// ByteReadLine returns a slice into the bufio.Reader's buffer.
+				return append([]byte(nil), line...), nil
+			}
+		}
+	case FramingLength4A:
+		var header [4]byte
+		if err := r.readFull(header[:]); err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(string(header[:]))
+		if err != nil || n < 0 {
+			return nil, r.invalidFraming(r.msgNum+1, "invalid length header '%s'", string(header[:]))
+		}
+		length = n
+	default:
+		var header [2]byte
+		if err := r.readFull(header[:]); err != nil {
+			return nil, err
+		}
+		length = int(header[0])<<8 | int(header[1])
+	}
+	msg = make([]byte, length)
+	if _, err := io.ReadFull(r.r, msg); err != nil {
+		return nil, r.truncated(err)
+	}
+	r.msgNum++
+	return msg, nil
+}
+
+// readFull reads a length header. io.EOF is returned only if the input ends cleanly before the header.
+func (r *reader) readFull(b []byte) error {
+	_, err := io.ReadFull(r.r, b)
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return r.truncated(err)
+	}
+	return nil
+}
+
+func (r *reader) truncated(err error) error {
+	return r.invalidFraming(r.msgNum+1, "unable to read message: %s", err.Error())
+}
+
+// msgDecoder decodes fields out of a single message.
+type msgDecoder struct {
+	msg []byte
+	pos int
+}
+
+func (d *msgDecoder) next(n int) ([]byte, error) {
+	if d.pos+n > len(d.msg) {
+		return nil, fmt.Errorf("need %d bytes at position %d, but only %d left", n, d.pos, len(d.msg)-d.pos)
+	}
+	b := d.msg[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgDecoder) bitmap(encoding string) ([]byte, error) {
+	if encoding == BitmapHex {
+		b, err := d.next(16)
+		if err != nil {
+			return nil, err
+		}
+		bitmap, err := hex.DecodeString(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex bitmap '%s'", string(b))
+		}
+		return bitmap, nil
+	}
+	return d.next(8)
+}
+
+func (r *reader) decodeMessage(msg []byte) (*idr.Node, error) {
+	root := idr.CreateNode(idr.DocumentNode, "")
+	addChild := func(name, value string) {
+		n := idr.CreateNode(idr.ElementNode, name)
+		idr.AddChild(root, n)
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+	}
+	fail := func(format string, args ...interface{}) (*idr.Node, error) {
+		idr.RemoveAndReleaseTree(root)
+		return nil, r.FmtErr(format, args...)
+	}
+	d := &msgDecoder{msg: msg}
+	mti, err := d.next(4)
+	if err != nil {
+		return fail("unable to read MTI: %s", err.Error())
+	}
+	addChild("MTI", string(mti))
+	bitmap, err := d.bitmap(r.decl.bitmapEncoding())
+	if err != nil {
+		return fail("unable to read primary bitmap: %s", err.Error())
+	}
+	if bitmap[0]&0x80 != 0 {
+		secondary, err := d.bitmap(r.decl.bitmapEncoding())
+		if err != nil {
+			return fail("unable to read secondary bitmap: %s", err.Error())
+		}
+		bitmap = append(append([]byte(nil), bitmap...), secondary...)
+	}
+	for num := 2; num <= len(bitmap)*8; num++ {
+		if bitmap[(num-1)/8]&(0x80>>uint((num-1)%8)) == 0 {
+			continue
+		}
+		decl := r.decl.dict[num]
+		value, err := d.dataElem(decl)
+		if err != nil {
+			return fail("unable to read data element %d: %s", num, err.Error())
+		}
+		addChild(decl.elemName(), value)
+	}
+	if d.pos < len(msg) {
+		return fail("%d unexpected bytes at the end of the message", len(msg)-d.pos)
+	}
+	return root, nil
+}
+
+func (d *msgDecoder) dataElem(decl *DataElemDecl) (string, error) {
+	length := decl.Length
+	if digits := decl.lenDigits(); digits > 0 {
+		b, err := d.next(digits)
+		if err != nil {
+			return "", err
+		}
+		length, err = strconv.Atoi(string(b))
+		if err != nil || length < 0 {
+			return "", fmt.Errorf("invalid length prefix '%s'", string(b))
+		}
+		if length > decl.Length {
+			return "", fmt.Errorf("length %d exceeds max length %d", length, decl.Length)
+		}
+	}
+	b, err := d.next(length)
+	if err != nil {
+		return "", err
+	}
+	if decl.Binary {
+		return strings.ToUpper(hex.EncodeToString(b)), nil
+	}
+	return string(b), nil
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidFraming(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.msgNum, format, args...))
+}
+
+func (r *reader) fmtErrStr(msgNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' message %d: %s", r.inputName, msgNum, fmt.Sprintf(format, args...))
+}
+
+// invalidFraming creates an ErrInvalidFraming, wrapped in an errs.ErrInput.
+func (r *reader) invalidFraming(msgNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	err := &errs.ErrInput{
+		Format:  fileFormatISO8583,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: msgNum,
+		Reason:  reason,
+		Err:     ErrInvalidFraming(r.fmtErrStr(msgNum, "%s", reason)),
+	}
+	if r.decl.framing() == FramingLine {
+		err.Line = msgNum
+	}
+	return err
+}
+
+// NewReader creates an FormatReader for ISO 8583 file format. decl must have been validated.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		r:           bufio.NewReader(src),
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
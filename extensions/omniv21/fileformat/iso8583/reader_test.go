@@ -0,0 +1,262 @@
+package iso8583
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func bitmap(nums ...int) []byte {
+	b := make([]byte, 8)
+	for _, n := range nums {
+		if n > 64 && len(b) == 8 {
+			b = append(b, make([]byte, 8)...)
+			b[0] |= 0x80
+		}
+	}
+	for _, n := range nums {
+		b[(n-1)/8] |= 0x80 >> uint((n-1)%8)
+	}
+	return b
+}
+
+func frame2B(msg string) string {
+	return string([]byte{byte(len(msg) >> 8), byte(len(msg))}) + msg
+}
+
+func frame4A(msg string) string {
+	return fmt.Sprintf("%04d", len(msg)) + msg
+}
+
+// hexBitmap converts a message with binary primary bitmap into one with hex bitmap.
+func hexBitmap(msg string) string {
+	return msg[:4] + strings.ToUpper(hex.EncodeToString([]byte(msg[4:12]))) + msg[12:]
+}
+
+var (
+	testMsg1 = "0200" + string(bitmap(2, 3, 4, 11, 52, 70)) +
+		"164111111111111111" + "000000" + "000000010000" + "123456" + "\x01\x23\x45\x67\x89\xab\xcd\xef" + "301"
+	testMsg2 = "0210" + string(bitmap(3, 39, 48)) + "000000" + "00" + "05hello"
+)
+
+func testDecl(t *testing.T, framing, bitmapEncoding string) *FileDecl {
+	decl := &FileDecl{
+		Framing:        strs.StrPtr(framing),
+		BitmapEncoding: strs.StrPtr(bitmapEncoding),
+		DataElemDecls:  []*DataElemDecl{{Number: 48, Type: typeLLVar, Length: 10}},
+	}
+	assert.NoError(t, decl.buildDict())
+	return decl
+}
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		decl     *FileDecl
+		xpath    string
+		expected []string
+	}{
+		{
+			name:  "length_2b framing, binary bitmap",
+			input: frame2B(testMsg1) + frame2B(testMsg2),
+			decl:  testDecl(t, FramingLength2B, BitmapBinary),
+			expected: []string{
+				`{"DE11":"123456","DE2":"4111111111111111","DE3":"000000","DE4":"000000010000",` +
+					`"DE52":"0123456789ABCDEF","DE70":"301","MTI":"0200"}`,
+				`{"DE3":"000000","DE39":"00","DE48":"hello","MTI":"0210"}`,
+			},
+		},
+		{
+			name:     "length_4a framing, with xpath",
+			input:    frame4A(testMsg1) + frame4A(testMsg2),
+			decl:     testDecl(t, FramingLength4A, BitmapBinary),
+			xpath:    ".[DE39='00']",
+			expected: []string{`{"DE3":"000000","DE39":"00","DE48":"hello","MTI":"0210"}`},
+		},
+		{
+			name:  "line framing, hex bitmap",
+			input: hexBitmap(testMsg2) + "\r\n\n" + hexBitmap(testMsg2) + "\n",
+			decl:  testDecl(t, FramingLine, BitmapHex),
+			expected: []string{
+				`{"DE3":"000000","DE39":"00","DE48":"hello","MTI":"0210"}`,
+				`{"DE3":"000000","DE39":"00","DE48":"hello","MTI":"0210"}`,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), test.decl, test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_MessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		msg            string
+		bitmapEncoding string
+		expErr         string
+	}{
+		{
+			name:   "missing MTI",
+			msg:    "02",
+			expErr: "input 'test-input' message 1: unable to read MTI: need 4 bytes at position 0, but only 2 left",
+		},
+		{
+			name:   "missing primary bitmap",
+			msg:    "0200\x00",
+			expErr: "input 'test-input' message 1: unable to read primary bitmap: need 8 bytes at position 4, but only 1 left",
+		},
+		{
+			name:   "missing secondary bitmap",
+			msg:    "0200" + string(bitmap(70)[:8]),
+			expErr: "input 'test-input' message 1: unable to read secondary bitmap: need 8 bytes at position 12, but only 0 left",
+		},
+		{
+			name:           "invalid hex bitmap",
+			msg:            "0200ZZ00000000000000",
+			bitmapEncoding: BitmapHex,
+			expErr:         "input 'test-input' message 1: unable to read primary bitmap: invalid hex bitmap 'ZZ00000000000000'",
+		},
+		{
+			name:           "truncated hex bitmap",
+			msg:            "02000000",
+			bitmapEncoding: BitmapHex,
+			expErr:         "input 'test-input' message 1: unable to read primary bitmap: need 16 bytes at position 4, but only 4 left",
+		},
+		{
+			name:   "invalid length prefix",
+			msg:    "0200" + string(bitmap(2)) + "1x",
+			expErr: "input 'test-input' message 1: unable to read data element 2: invalid length prefix '1x'",
+		},
+		{
+			name:   "truncated length prefix",
+			msg:    "0200" + string(bitmap(2)) + "1",
+			expErr: "input 'test-input' message 1: unable to read data element 2: need 2 bytes at position 12, but only 1 left",
+		},
+		{
+			name:   "length exceeds max",
+			msg:    "0200" + string(bitmap(2)) + "20" + strings.Repeat("1", 20),
+			expErr: "input 'test-input' message 1: unable to read data element 2: length 20 exceeds max length 19",
+		},
+		{
+			name:   "truncated data element",
+			msg:    "0200" + string(bitmap(3)) + "000",
+			expErr: "input 'test-input' message 1: unable to read data element 3: need 6 bytes at position 12, but only 3 left",
+		},
+		{
+			name:   "trailing bytes",
+			msg:    "0200" + string(bitmap(39)) + "00xyz",
+			expErr: "input 'test-input' message 1: 3 unexpected bytes at the end of the message",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decl := testDecl(t, FramingLength2B, BitmapBinary)
+			next := testMsg2
+			if test.bitmapEncoding != "" {
+				decl.BitmapEncoding = strs.StrPtr(test.bitmapEncoding)
+				next = hexBitmap(testMsg2)
+			}
+			r, err := NewReader("test-input", strings.NewReader(frame2B(test.msg)+frame2B(next)), decl, "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+			// reader moves onto the next message.
+			n, err = r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, `{"DE3":"000000","DE39":"00","DE48":"hello","MTI":"0210"}`, idr.JSONify2(n))
+		})
+	}
+}
+
+func TestRead_FramingErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		input   io.Reader
+		framing string
+		expErr  string
+	}{
+		{
+			name:    "truncated 2b header",
+			input:   strings.NewReader(frame2B(testMsg2) + "\x00"),
+			framing: FramingLength2B,
+			expErr:  "input 'test-input' message 2: unable to read message: unexpected EOF",
+		},
+		{
+			name:    "truncated message",
+			input:   strings.NewReader(frame2B(testMsg2) + "\x00\x10" + "0200"),
+			framing: FramingLength2B,
+			expErr:  "input 'test-input' message 2: unable to read message: unexpected EOF",
+		},
+		{
+			name:    "invalid 4a header",
+			input:   strings.NewReader("00x1"),
+			framing: FramingLength4A,
+			expErr:  "input 'test-input' message 1: invalid length header '00x1'",
+		},
+		{
+			name:    "line read failure",
+			input:   &failingReader{},
+			framing: FramingLine,
+			expErr:  "input 'test-input' message 1: unable to read message: read failure",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", test.input, testDecl(t, test.framing, BitmapBinary), "")
+			assert.NoError(t, err)
+			_, err = readAll(t, r)
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidFraming(err))
+			assert.False(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testDecl(t, FramingLine, BitmapHex), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidFraming("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
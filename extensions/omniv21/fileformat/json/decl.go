@@ -0,0 +1,36 @@
+package json
+
+import (
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// Supported values of the `file_declaration` level `duplicate_keys` setting.
+const (
+	duplicateKeysKeepAll   = "keep_all"
+	duplicateKeysFirstWins = "first_wins"
+	duplicateKeysLastWins  = "last_wins"
+	duplicateKeysError     = "error"
+	duplicateKeysArray     = "array"
+)
+
+var duplicateKeysModes = map[string]idr.JSONDuplicateKeys{
+	duplicateKeysKeepAll:   idr.JSONDuplicateKeysKeepAll,
+	duplicateKeysFirstWins: idr.JSONDuplicateKeysFirstWins,
+	duplicateKeysLastWins:  idr.JSONDuplicateKeysLastWins,
+	duplicateKeysError:     idr.JSONDuplicateKeysError,
+	duplicateKeysArray:     idr.JSONDuplicateKeysArray,
+}
+
+// FileDecl describes JSON specific schema settings for omniparser reader. It's optional.
+type FileDecl struct {
+	// DuplicateKeys specifies how duplicate keys in a JSON object are handled. If not specified, all
+	// the duplicate keys are kept (keep_all). JSON schema validation guarantees it's one of the
+	// supported values.
+	DuplicateKeys *string `json:"duplicate_keys,omitempty"`
+}
+
+func (d *FileDecl) duplicateKeys() idr.JSONDuplicateKeys {
+	return duplicateKeysModes[strs.StrPtrOrElse(d.DuplicateKeys, duplicateKeysKeepAll)]
+}
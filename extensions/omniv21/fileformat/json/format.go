@@ -1,6 +1,7 @@
 package json
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
+	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
+	"github.com/logward/omniparser/validation"
 )
 
 const (
@@ -25,24 +28,42 @@ func NewJSONFileFormat(schemaName string) fileformat.FileFormat {
 	return &jsonFileFormat{schemaName: schemaName}
 }
 
-func (f *jsonFileFormat) ValidateSchema(format string, _ []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+type jsonFormatRuntime struct {
+	Decl  *FileDecl `json:"file_declaration"`
+	XPath string
+}
+
+func (f *jsonFileFormat) ValidateSchema(
+	format string, schemaContent []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
 	if format != fileFormatJSON {
 		return nil, errs.ErrSchemaNotSupported
 	}
+	err := validation.SchemaValidate(f.schemaName, schemaContent, v21validation.JSONSchemaJSONFileDeclaration)
+	if err != nil {
+		// err is already context formatted.
+		return nil, err
+	}
+	var runtime jsonFormatRuntime
+	_ = json.Unmarshal(schemaContent, &runtime) // JSON schema validation earlier guarantees Unmarshal success.
+	if runtime.Decl == nil {
+		// file_declaration is optional.
+		runtime.Decl = &FileDecl{}
+	}
 	if finalOutputDecl == nil {
 		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
 	}
-	xpath := strs.StrPtrOrElse(finalOutputDecl.XPath, ".")
-	_, err := caches.GetXPathExpr(xpath)
+	runtime.XPath = strs.StrPtrOrElse(finalOutputDecl.XPath, ".")
+	_, err = caches.GetXPathExpr(runtime.XPath)
 	if err != nil {
-		return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s", xpath, err.Error())
+		return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s", runtime.XPath, err.Error())
 	}
-	return xpath, nil
+	return &runtime, nil
 }
 
 func (f *jsonFileFormat) CreateFormatReader(
 	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
-	return NewReader(name, r, runtime.(string))
+	rt := runtime.(*jsonFormatRuntime)
+	return NewReader(name, r, rt.Decl, rt.XPath)
 }
 
 func (f *jsonFileFormat) FmtErr(format string, args ...interface{}) error {
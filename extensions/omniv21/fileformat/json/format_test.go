@@ -18,6 +18,7 @@ func TestValidateSchema(t *testing.T) {
 	for _, test := range []struct {
 		name        string
 		format      string
+		schema      string
 		decl        *transform.Decl
 		expected    interface{}
 		expectedErr string
@@ -29,6 +30,14 @@ func TestValidateSchema(t *testing.T) {
 			expected:    nil,
 			expectedErr: errs.ErrSchemaNotSupported.Error(),
 		},
+		{
+			name:        "invalid file_declaration",
+			format:      fileFormatJSON,
+			schema:      `{"file_declaration": {"duplicate_keys": "random"}}`,
+			decl:        &transform.Decl{},
+			expected:    nil,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.duplicate_keys: file_declaration.duplicate_keys must be one of the following: "keep_all", "first_wins", "last_wins", "error", "array"`,
+		},
 		{
 			name:        "FINAL_OUTPUT decl is nil",
 			format:      fileFormatJSON,
@@ -47,19 +56,31 @@ func TestValidateSchema(t *testing.T) {
 			name:        "success 1",
 			format:      fileFormatJSON,
 			decl:        &transform.Decl{XPath: strs.StrPtr("/A/B[.!='skip']")},
-			expected:    "/A/B[.!='skip']",
+			expected:    &jsonFormatRuntime{Decl: &FileDecl{}, XPath: "/A/B[.!='skip']"},
 			expectedErr: "",
 		},
 		{
 			name:        "success 2",
 			format:      fileFormatJSON,
 			decl:        &transform.Decl{},
-			expected:    ".",
+			expected:    &jsonFormatRuntime{Decl: &FileDecl{}, XPath: "."},
+			expectedErr: "",
+		},
+		{
+			name:        "success with file_declaration",
+			format:      fileFormatJSON,
+			schema:      `{"file_declaration": {"duplicate_keys": "error"}}`,
+			decl:        &transform.Decl{},
+			expected:    &jsonFormatRuntime{Decl: &FileDecl{DuplicateKeys: strs.StrPtr("error")}, XPath: "."},
 			expectedErr: "",
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			runtime, err := NewJSONFileFormat("test-schema").ValidateSchema(test.format, nil, test.decl)
+			schema := test.schema
+			if schema == "" {
+				schema = "{}"
+			}
+			runtime, err := NewJSONFileFormat("test-schema").ValidateSchema(test.format, []byte(schema), test.decl)
 			if test.expectedErr != "" {
 				assert.Error(t, err)
 				assert.Equal(t, test.expectedErr, err.Error())
@@ -76,7 +97,7 @@ func TestCreateFormatReader(t *testing.T) {
 	r, err := NewJSONFileFormat("test-schema").CreateFormatReader(
 		"test-input",
 		strings.NewReader(`["B1", "B2", "B3"]`),
-		"/*[.!='B2']")
+		&jsonFormatRuntime{Decl: &FileDecl{}, XPath: "/*[.!='B2']"})
 	assert.NoError(t, err)
 	assert.NotNil(t, r)
 	t.Run("B1", func(t *testing.T) {
@@ -96,7 +117,8 @@ func TestCreateFormatReader(t *testing.T) {
 		assert.Nil(t, n3)
 	})
 
-	r, err = NewJSONFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), "[invalid")
+	r, err = NewJSONFileFormat("test-schema").CreateFormatReader(
+		"test-input", strings.NewReader(""), &jsonFormatRuntime{Decl: &FileDecl{}, XPath: "[invalid"})
 	assert.Error(t, err)
 	assert.Equal(t, `invalid xpath '[invalid', err: expression must evaluate to a node-set`, err.Error())
 	assert.Nil(t, r)
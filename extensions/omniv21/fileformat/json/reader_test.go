@@ -6,9 +6,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -39,6 +41,7 @@ func TestReader_Read_Success(t *testing.T) {
 					"age": 20
 				}
 			]`),
+		&FileDecl{},
 		"/*[age>30]")
 	assert.NoError(t, err)
 
@@ -64,28 +67,52 @@ func TestReader_Read_Success(t *testing.T) {
 }
 
 func TestReader_Read_InvalidJSON(t *testing.T) {
-	r, err := NewReader("test-input", strings.NewReader("{\n}\n}"), "/A/B[. != 'c']")
+	r, err := NewReader("test-input", strings.NewReader("{\n}\n}"), &FileDecl{}, "/A/B[. != 'c']")
 	assert.NoError(t, err)
 
 	n, err := r.Read()
 	assert.Error(t, err)
 	assert.True(t, IsErrNodeReadingFailed(err))
 	assert.Equal(t,
-		`input 'test-input' before/near line 3: invalid character '}' looking for beginning of value`,
+		`input 'test-input' before/near line 3, col 1, byte offset 4: invalid character '}' looking for beginning of value`,
 		err.Error())
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, "json", inputErr.Format)
+	assert.Equal(t, 3, inputErr.Line)
+	assert.Equal(t, 1, inputErr.Column)
+	assert.Equal(t, int64(4), inputErr.Offset)
+	assert.Equal(t, "invalid character '}' looking for beginning of value", inputErr.Reason)
+	assert.Nil(t, n)
+}
+
+func TestReader_Read_DuplicateKeys(t *testing.T) {
+	input := "[\n{\"id\": 1, \"id\": 2}\n]"
+	r, err := NewReader("test-input", strings.NewReader(input), &FileDecl{DuplicateKeys: strs.StrPtr("last_wins")}, "/*")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":2}`, idr.JSONify2(n))
+
+	r, err = NewReader("test-input", strings.NewReader(input), &FileDecl{DuplicateKeys: strs.StrPtr("error")}, "/*")
+	assert.NoError(t, err)
+	n, err = r.Read()
+	assert.Error(t, err)
+	assert.True(t, IsErrNodeReadingFailed(err))
+	assert.Equal(t, `input 'test-input' before/near line 2, col 19, byte offset 20: duplicate key 'id'`, err.Error())
 	assert.Nil(t, n)
 }
 
 func TestReader_FmtErr(t *testing.T) {
-	r, err := NewReader("test-input", strings.NewReader(""), "/A/B")
+	r, err := NewReader("test-input", strings.NewReader(""), &FileDecl{}, "/A/B")
 	assert.NoError(t, err)
 	err = r.FmtErr("golang is %s", "fun")
 	assert.Error(t, err)
-	assert.Equal(t, `input 'test-input' before/near line 1: golang is fun`, err.Error())
+	assert.Equal(t, `input 'test-input' before/near line 1, col 1, byte offset 0: golang is fun`, err.Error())
 }
 
 func TestReader_IsContinuableError(t *testing.T) {
-	r, err := NewReader("test", strings.NewReader(""), "/A/B")
+	r, err := NewReader("test", strings.NewReader(""), &FileDecl{}, "/A/B")
 	assert.NoError(t, err)
 	assert.False(t, r.IsContinuableError(io.EOF))
 	assert.False(t, r.IsContinuableError(ErrNodeReadingFailed("failure")))
@@ -94,10 +121,51 @@ func TestReader_IsContinuableError(t *testing.T) {
 }
 
 func TestNewReader_InvalidXPath(t *testing.T) {
-	r, err := NewReader("test-input", strings.NewReader(""), "[not-valid")
+	r, err := NewReader("test-input", strings.NewReader(""), &FileDecl{}, "[not-valid")
 	assert.Error(t, err)
 	assert.Equal(t,
 		`invalid xpath '[not-valid', err: expression must evaluate to a node-set`,
 		err.Error())
 	assert.Nil(t, r)
 }
+
+func TestReader_RecordPosition(t *testing.T) {
+	r, err := NewReader(
+		"test-input",
+		strings.NewReader("[\n{\n\"id\": 1\n},\n{\n\"id\": 2\n}\n]"),
+		&FileDecl{},
+		"/*")
+	assert.NoError(t, err)
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 0, begin)
+	assert.Equal(t, 0, end)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 2, begin)
+	assert.Equal(t, 4, end)
+	n, err = r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 5, begin)
+	assert.Equal(t, 7, end)
+}
+
+func TestReader_SourcePosition(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader("[\n  {\"id\": 1},\n  {\"id\": 2}\n]"), &FileDecl{}, "/*")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end := r.SourcePosition()
+	assert.Equal(t, fileformat.Position{Offset: 4, Line: 2, Column: 3}, begin)
+	assert.Equal(t, fileformat.Position{Offset: 13, Line: 2, Column: 12}, end)
+	n, err = r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.SourcePosition()
+	assert.Equal(t, fileformat.Position{Offset: 17, Line: 3, Column: 3}, begin)
+	assert.Equal(t, fileformat.Position{Offset: 26, Line: 3, Column: 12}, end)
+}
@@ -0,0 +1,27 @@
+package nacha
+
+import (
+	"github.com/jf-tech/go-corelib/strs"
+)
+
+const (
+	// RecordBatch makes each batch, i.e. its batch header, entries with their addenda and batch
+	// control, a record. This is the default.
+	RecordBatch = "batch"
+	// RecordEntry makes each entry, with its addenda, a record, so that huge batches needn't be held in
+	// memory. The batch header is still reachable from an entry, as its parent's `batch_header`.
+	RecordEntry = "entry"
+)
+
+// FileDecl describes NACHA specific schema settings for omniparser reader. All settings are optional.
+type FileDecl struct {
+	// Record is the level of the records, RecordBatch or RecordEntry.
+	Record *string `json:"record,omitempty"`
+	// VerifyControls tells if the counts, entry hashes and totals of the batch control and file control
+	// records are verified.
+	VerifyControls bool `json:"verify_controls,omitempty"`
+}
+
+func (d *FileDecl) record() string {
+	return strs.StrPtrOrElse(d.Record, RecordBatch)
+}
@@ -0,0 +1,80 @@
+package nacha
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatNACHA,
+			schema:      `{"file_declaration": { "record": "file" }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.record: file_declaration.record must be one of the following: "batch", "entry"`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatNACHA,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatNACHA,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatNACHA,
+			schema: `{"file_declaration": { "record": "entry", "verify_controls": true }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[addenda] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewNACHAFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*nachaFormatRuntime)
+			assert.Equal(t, ".[addenda]", rt.XPath)
+			assert.Equal(t, RecordEntry, rt.Decl.record())
+			assert.True(t, rt.Decl.VerifyControls)
+			r, err := NewNACHAFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
+
+func TestValidateSchema_NoFileDeclaration(t *testing.T) {
+	runtime, err := NewNACHAFileFormat("test-schema").ValidateSchema(fileFormatNACHA, []byte(`{}`), &transform.Decl{})
+	assert.NoError(t, err)
+	rt := runtime.(*nachaFormatRuntime)
+	assert.Equal(t, "", rt.XPath)
+	assert.Equal(t, RecordBatch, rt.Decl.record())
+	assert.False(t, rt.Decl.VerifyControls)
+}
@@ -0,0 +1,171 @@
+package nacha
+
+import (
+	"strings"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// recordSize is the size of a NACHA record.
+const recordSize = 94
+
+const (
+	recordTypeFileHeader   = '1'
+	recordTypeBatchHeader  = '5'
+	recordTypeEntryDetail  = '6'
+	recordTypeAddenda      = '7'
+	recordTypeBatchControl = '8'
+	recordTypeFileControl  = '9'
+)
+
+const (
+	elemFile         = "file"
+	elemFileHeader   = "file_header"
+	elemBatch        = "batch"
+	elemBatchHeader  = "batch_header"
+	elemEntry        = "entry"
+	elemAddenda      = "addenda"
+	elemBatchControl = "batch_control"
+	elemFileControl  = "file_control"
+)
+
+// field is a field of a record, at the 1-based begin and end positions, inclusive, the NACHA rules
+// refer to fields by.
+type field struct {
+	name       string
+	begin, end int
+}
+
+var fileHeaderFields = []field{
+	{"priority_code", 2, 3},
+	{"immediate_destination", 4, 13},
+	{"immediate_origin", 14, 23},
+	{"file_creation_date", 24, 29},
+	{"file_creation_time", 30, 33},
+	{"file_id_modifier", 34, 34},
+	{"record_size", 35, 37},
+	{"blocking_factor", 38, 39},
+	{"format_code", 40, 40},
+	{"immediate_destination_name", 41, 63},
+	{"immediate_origin_name", 64, 86},
+	{"reference_code", 87, 94},
+}
+
+var batchHeaderFields = []field{
+	{"service_class_code", 2, 4},
+	{"company_name", 5, 20},
+	{"company_discretionary_data", 21, 40},
+	{"company_identification", 41, 50},
+	{"standard_entry_class_code", 51, 53},
+	{"company_entry_description", 54, 63},
+	{"company_descriptive_date", 64, 69},
+	{"effective_entry_date", 70, 75},
+	{"settlement_date", 76, 78},
+	{"originator_status_code", 79, 79},
+	{"originating_dfi_identification", 80, 87},
+	{"batch_number", 88, 94},
+}
+
+var entryDetailFields = []field{
+	{"transaction_code", 2, 3},
+	{"receiving_dfi_identification", 4, 11},
+	{"check_digit", 12, 12},
+	{"dfi_account_number", 13, 29},
+	{"amount", 30, 39},
+	{"individual_identification_number", 40, 54},
+	{"individual_name", 55, 76},
+	{"discretionary_data", 77, 78},
+	{"addenda_record_indicator", 79, 79},
+	{"trace_number", 80, 94},
+}
+
+// addendaFields are the fields of the addenda records, by addenda type code. The ones of other types
+// than the notification of change (98) and return (99) ones are read as of the 05 type.
+var addendaFields = map[string][]field{
+	"05": {
+		{"addenda_type_code", 2, 3},
+		{"payment_related_information", 4, 83},
+		{"addenda_sequence_number", 84, 87},
+		{"entry_detail_sequence_number", 88, 94},
+	},
+	"98": {
+		{"addenda_type_code", 2, 3},
+		{"change_code", 4, 6},
+		{"original_entry_trace_number", 7, 21},
+		{"original_receiving_dfi_identification", 28, 35},
+		{"corrected_data", 36, 64},
+		{"trace_number", 80, 94},
+	},
+	"99": {
+		{"addenda_type_code", 2, 3},
+		{"return_reason_code", 4, 6},
+		{"original_entry_trace_number", 7, 21},
+		{"date_of_death", 22, 27},
+		{"original_receiving_dfi_identification", 28, 35},
+		{"addenda_information", 36, 79},
+		{"trace_number", 80, 94},
+	},
+}
+
+var batchControlFields = []field{
+	{"service_class_code", 2, 4},
+	{"entry_addenda_count", 5, 10},
+	{"entry_hash", 11, 20},
+	{"total_debit_entry_dollar_amount", 21, 32},
+	{"total_credit_entry_dollar_amount", 33, 44},
+	{"company_identification", 45, 54},
+	{"message_authentication_code", 55, 73},
+	{"originating_dfi_identification", 80, 87},
+	{"batch_number", 88, 94},
+}
+
+var fileControlFields = []field{
+	{"batch_count", 2, 7},
+	{"block_count", 8, 13},
+	{"entry_addenda_count", 14, 21},
+	{"entry_hash", 22, 31},
+	{"total_debit_entry_dollar_amount", 32, 43},
+	{"total_credit_entry_dollar_amount", 44, 55},
+}
+
+// value returns the value of a field of a record, with its space padding trimmed.
+func (f field) value(rec string) string {
+	return strings.TrimSpace(rec[f.begin-1 : f.end])
+}
+
+func fieldValue(rec string, fields []field, name string) string {
+	for _, f := range fields {
+		if f.name == name {
+			return f.value(rec)
+		}
+	}
+	return ""
+}
+
+// addRecord adds an element, named name, for a record, with an element for each of its fields.
+func addRecord(parent *idr.Node, name, rec string, fields []field) *idr.Node {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	addFields(n, rec, fields)
+	return n
+}
+
+func addFields(n *idr.Node, rec string, fields []field) {
+	for _, f := range fields {
+		fn := idr.CreateNode(idr.ElementNode, f.name)
+		idr.AddChild(n, fn)
+		idr.AddChild(fn, idr.CreateNode(idr.TextNode, f.value(rec)))
+	}
+}
+
+func addendaFieldsOf(rec string) []field {
+	if fields, found := addendaFields[rec[1:3]]; found {
+		return fields
+	}
+	return addendaFields["05"]
+}
+
+// isPadding tells if a record is a padding record, i.e. all '9's, filling up the last block of a file.
+func isPadding(rec string) bool {
+	return strings.Trim(rec, "9") == ""
+}
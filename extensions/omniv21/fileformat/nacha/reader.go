@@ -0,0 +1,353 @@
+package nacha
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidNACHA indicates the input can't be read, e.g. it doesn't start with a file header record.
+// This is a fatal, non-continuable error. Note errors in a batch (e.g. an addenda record not following
+// an entry detail record) are continuable: the reader simply moves onto the next batch.
+type ErrInvalidNACHA string
+
+func (e ErrInvalidNACHA) Error() string { return string(e) }
+
+// IsErrInvalidNACHA checks if the `err` is of ErrInvalidNACHA type, or wraps one, e.g. in an
+// errs.ErrInput.
+func IsErrInvalidNACHA(err error) bool {
+	var e ErrInvalidNACHA
+	return errors.As(err, &e)
+}
+
+// maxLineSize is the max size of a line, for the inputs without line breaks between records.
+const maxLineSize = 64 * 1024 * 1024
+
+// record is a record read from the input, with its 1-based number.
+type record struct {
+	num  int
+	text string
+}
+
+func (rec *record) typ() byte {
+	return rec.text[0]
+}
+
+type reader struct {
+	inputName   string
+	s           *bufio.Scanner
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	root        *idr.Node
+	recNum      int      // number of the last record read from the input.
+	queue       []string // the records of the current line not read yet.
+	pending     *record  // a record read ahead, which is processed next.
+	started     bool     // a file header record has been read.
+	file        *idr.Node
+	fileBegin   int  // number of the file header record of the current file.
+	fileDone    bool // the file control record of the current file has been read.
+	fileTotals  totals
+	batch       *idr.Node // the current batch, if any.
+	batchBegin  int       // number of the batch header record of the current batch.
+	batchTotals totals
+	entry       *idr.Node // the current entry, if any, which its addenda records are added into.
+	entryBegin  int       // number of the entry detail record of the current entry.
+	entryEnd    int       // number of the last record of the current entry.
+	skipping    bool      // skipping the rest of a broken batch.
+	recBegin    int       // number of the first record of the record returned by the last Read.
+	recEnd      int       // number of the last record of the record returned by the last Read.
+}
+
+// Read returns the next batch, or entry if the `record` is RecordEntry, as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		n, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			continue
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+// readRecord returns the next record. Lines of a single record are padded with spaces, or have their
+// trailing spaces trimmed, to the size of a record, and lines of several records, e.g. an input without
+// line breaks, are split into records.
+func (r *reader) readRecord() (*record, error) {
+	if r.pending != nil {
+		rec := r.pending
+		r.pending = nil
+		return rec, nil
+	}
+	for len(r.queue) == 0 {
+		if !r.s.Scan() {
+			if err := r.s.Err(); err != nil {
+				return nil, r.invalidNACHA(r.recNum+1, "unable to read record: %s", err.Error())
+			}
+			return nil, io.EOF
+		}
+		line := r.s.Text()
+		trimmed := strings.TrimRight(line, " ")
+		switch {
+		case trimmed == "":
+			continue
+		case len(trimmed) <= recordSize:
+			r.queue = append(r.queue, trimmed+strings.Repeat(" ", recordSize-len(trimmed)))
+		case len(line)%recordSize == 0:
+			for i := 0; i < len(line); i += recordSize {
+				r.queue = append(r.queue, line[i:i+recordSize])
+			}
+		default:
+			return nil, r.invalidNACHA(r.recNum+1,
+				"line of %d characters isn't made of %d-character records", len(line), recordSize)
+		}
+	}
+	r.recNum++
+	rec := &record{num: r.recNum, text: r.queue[0]}
+	r.queue = r.queue[1:]
+	return rec, nil
+}
+
+// next processes the next record, and returns the batch, or entry, it completes, if any.
+func (r *reader) next() (*idr.Node, error) {
+	rec, err := r.readRecord()
+	if err == io.EOF {
+		return r.end()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.started && rec.typ() != recordTypeFileHeader {
+		return nil, r.invalidNACHA(rec.num, "input doesn't start with a file header record")
+	}
+	if r.entry != nil && rec.typ() != recordTypeAddenda {
+		// the current entry is complete.
+		entry := r.entry
+		r.entry = nil
+		if r.decl.record() == RecordEntry {
+			r.pending = rec
+			r.recBegin, r.recEnd = r.entryBegin, r.entryEnd
+			return entry, nil
+		}
+	}
+	if r.skipping {
+		switch rec.typ() {
+		case recordTypeFileHeader, recordTypeBatchHeader, recordTypeFileControl:
+			r.skipping = false
+		default:
+			return nil, nil
+		}
+	}
+	switch rec.typ() {
+	case recordTypeFileHeader:
+		if r.file != nil && !r.fileDone {
+			r.pending = rec
+			return nil, r.unterminated()
+		}
+		r.startFile(rec)
+	case recordTypeBatchHeader:
+		if r.file == nil || r.fileDone {
+			return nil, r.breakBatch(rec, "batch header record outside of a file")
+		}
+		if r.batch != nil {
+			r.pending = rec
+			return nil, r.unterminated()
+		}
+		r.batch = idr.CreateNode(idr.ElementNode, elemBatch)
+		idr.AddChild(r.file, r.batch)
+		addRecord(r.batch, elemBatchHeader, rec.text, batchHeaderFields)
+		r.batchBegin = rec.num
+		r.batchTotals = totals{}
+	case recordTypeEntryDetail:
+		if r.batch == nil {
+			return nil, r.breakBatch(rec, "entry detail record outside of a batch")
+		}
+		if r.decl.VerifyControls {
+			if err := r.batchTotals.addEntry(rec.text); err != nil {
+				return nil, r.breakBatch(rec, "%s", err.Error())
+			}
+		}
+		r.entry = addRecord(r.batch, elemEntry, rec.text, entryDetailFields)
+		r.entryBegin, r.entryEnd = rec.num, rec.num
+	case recordTypeAddenda:
+		if r.entry == nil {
+			return nil, r.breakBatch(rec, "addenda record not following an entry detail record")
+		}
+		r.batchTotals.addAddenda()
+		addRecord(r.entry, elemAddenda, rec.text, addendaFieldsOf(rec.text))
+		r.entryEnd = rec.num
+	case recordTypeBatchControl:
+		if r.batch == nil {
+			return nil, r.breakBatch(rec, "batch control record outside of a batch")
+		}
+		return r.endBatch(rec)
+	case recordTypeFileControl:
+		if r.file == nil || r.fileDone {
+			if isPadding(rec.text) {
+				return nil, nil
+			}
+			return nil, r.breakBatch(rec, "file control record outside of a file")
+		}
+		if r.batch != nil {
+			r.pending = rec
+			return nil, r.unterminated()
+		}
+		addRecord(r.file, elemFileControl, rec.text, fileControlFields)
+		r.fileDone = true
+		if r.decl.VerifyControls {
+			if err := r.fileTotals.verify(rec.text, fileControlFields, elemFile); err != nil {
+				return nil, errors.New(r.fmtErrStr(rec.num, "%s", err.Error()))
+			}
+		}
+	default:
+		return nil, r.breakBatch(rec, "unknown record type code '%c'", rec.typ())
+	}
+	return nil, nil
+}
+
+func (r *reader) startFile(rec *record) {
+	if r.file != nil {
+		idr.RemoveAndReleaseTree(r.file)
+	}
+	r.started = true
+	r.file = idr.CreateNode(idr.ElementNode, elemFile)
+	idr.AddChild(r.root, r.file)
+	addRecord(r.file, elemFileHeader, rec.text, fileHeaderFields)
+	r.fileBegin = rec.num
+	r.fileDone = false
+	r.fileTotals = totals{}
+}
+
+// endBatch ends the current batch with its batch control record, and returns it if it's the record.
+func (r *reader) endBatch(rec *record) (*idr.Node, error) {
+	batch := r.batch
+	r.batch = nil
+	addRecord(batch, elemBatchControl, rec.text, batchControlFields)
+	if r.decl.VerifyControls {
+		err := r.batchTotals.verify(rec.text, batchControlFields, elemBatch)
+		r.fileTotals.addBatch(&r.batchTotals)
+		if err != nil {
+			idr.RemoveAndReleaseTree(batch)
+			return nil, errors.New(r.fmtErrStr(rec.num, "%s", err.Error()))
+		}
+	}
+	if r.decl.record() == RecordEntry {
+		// its entries are already returned.
+		idr.RemoveAndReleaseTree(batch)
+		return nil, nil
+	}
+	r.recBegin, r.recEnd = r.batchBegin, rec.num
+	return batch, nil
+}
+
+// breakBatch drops the current batch, if any, and skips the rest of it, upon an error at a record.
+func (r *reader) breakBatch(rec *record, format string, args ...interface{}) error {
+	if r.batch != nil {
+		idr.RemoveAndReleaseTree(r.batch)
+		r.batch, r.entry = nil, nil
+	}
+	r.skipping = true
+	return errors.New(r.fmtErrStr(rec.num, format, args...))
+}
+
+// unterminated drops the current batch if any, or else ends the current file, as it isn't terminated
+// by its control record.
+func (r *reader) unterminated() error {
+	if r.batch != nil {
+		idr.RemoveAndReleaseTree(r.batch)
+		r.batch, r.entry = nil, nil
+		return errors.New(r.fmtErrStr(r.batchBegin, "batch isn't terminated by a batch control record"))
+	}
+	r.fileDone = true
+	return errors.New(r.fmtErrStr(r.fileBegin, "file isn't terminated by a file control record"))
+}
+
+// end returns, at the end of the input, the current entry if it's the record, and the errors of the
+// batch and file not terminated, if any, before io.EOF.
+func (r *reader) end() (*idr.Node, error) {
+	if r.entry != nil {
+		entry := r.entry
+		r.entry = nil
+		if r.decl.record() == RecordEntry {
+			r.recBegin, r.recEnd = r.entryBegin, r.entryEnd
+			return entry, nil
+		}
+	}
+	if r.batch != nil || (r.file != nil && !r.fileDone) {
+		return nil, r.unterminated()
+	}
+	return nil, io.EOF
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of the numbers of
+// the NACHA records the record returned by the last successful Read call is made of.
+func (r *reader) RecordPosition() (int, int) {
+	return r.recBegin, r.recEnd
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidNACHA(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.recBegin, format, args...))
+}
+
+func (r *reader) fmtErrStr(recNum int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' record %d: %s", r.inputName, recNum, fmt.Sprintf(format, args...))
+}
+
+// invalidNACHA creates an ErrInvalidNACHA, wrapped in an errs.ErrInput.
+func (r *reader) invalidNACHA(recNum int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format:  fileFormatNACHA,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: recNum,
+		Reason:  reason,
+		Err:     ErrInvalidNACHA(r.fmtErrStr(recNum, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for NACHA file format.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	s := bufio.NewScanner(src)
+	s.Buffer(nil, maxLineSize)
+	return &reader{
+		inputName:   inputName,
+		s:           s,
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+		root:        idr.CreateNode(idr.DocumentNode, ""),
+	}, nil
+}
@@ -0,0 +1,439 @@
+package nacha
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+const (
+	testFileHeader   = "101 091000019 1234567802610151200A094101WELLS FARGO BANK       ACME CORP"
+	testBatchHeader  = "5220ACME CORP                           1234567890PPDPAYROLL         261016   1091000010000001"
+	testEntry1       = "622071000013123456789        0000150000EMP001         JOHN DOE                0091000010000101"
+	testEntry2       = "632021000021987654321        0000210050EMP002         JANE ROE                1091000010000102"
+	testAddenda      = "705DIRECT DEPOSIT OCT 2026 PAY PERIOD 2                                            00010000102"
+	testBatchControl = "822000000300092000030000000000000000003600501234567890                         091000010000001"
+	testFileControl  = "9000001000001000000030009200003000000000000000000360050"
+	testPadding      = "9999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999"
+)
+
+// testFile returns the records of a file of the given batches, each a batch header, the given entry
+// records and a batch control.
+func testFile(batches ...[]string) []string {
+	recs := []string{testFileHeader}
+	for _, b := range batches {
+		recs = append(recs, testBatchHeader)
+		recs = append(recs, b...)
+		recs = append(recs, testBatchControl)
+	}
+	return append(recs, testFileControl)
+}
+
+// padAll pads the records with spaces, as the records of a file without line breaks.
+func padAll(recs []string) []string {
+	padded := make([]string, len(recs))
+	for i, rec := range recs {
+		padded[i] = rec + strings.Repeat(" ", recordSize-len(rec))
+	}
+	return padded
+}
+
+func testBatch() []string {
+	return []string{testEntry1, testEntry2, testAddenda}
+}
+
+// summarize returns the trace numbers of the entries of a record, each followed by the number of its
+// addenda records.
+func summarize(t *testing.T, n *idr.Node) string {
+	entries := []*idr.Node{n}
+	if n.Data == elemBatch {
+		var err error
+		entries, err = idr.MatchAll(n, elemEntry)
+		assert.NoError(t, err)
+	}
+	var s []string
+	for _, e := range entries {
+		trace, err := idr.MatchSingle(e, "trace_number")
+		assert.NoError(t, err)
+		addenda, err := idr.MatchAll(e, elemAddenda)
+		assert.NoError(t, err)
+		s = append(s, trace.InnerText()+"/"+strconv.Itoa(len(addenda)))
+	}
+	return n.Data + ":" + strings.Join(s, ",")
+}
+
+// readAll returns the summaries of the records read, and the messages of the continuable errors, till
+// io.EOF or a fatal error.
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		switch {
+		case err == io.EOF:
+			return records, nil
+		case err != nil && r.IsContinuableError(err):
+			records = append(records, "error: "+err.Error())
+			continue
+		case err != nil:
+			return records, err
+		}
+		records = append(records, summarize(t, n))
+		r.Release(n)
+	}
+}
+
+func testDecl(record string, verifyControls bool) *FileDecl {
+	decl := &FileDecl{VerifyControls: verifyControls}
+	if record != "" {
+		decl.Record = strs.StrPtr(record)
+	}
+	return decl
+}
+
+const (
+	testBatchSummary  = "batch:091000010000101/0,091000010000102/1"
+	testEntry1Summary = "entry:091000010000101/0"
+	testEntry2Summary = "entry:091000010000102/1"
+)
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		record   string
+		xpath    string
+		expected []string
+	}{
+		{
+			name:     "batches",
+			input:    strings.Join(testFile(testBatch(), testBatch()), "\n"),
+			expected: []string{testBatchSummary, testBatchSummary},
+		},
+		{
+			name:     "entries",
+			input:    strings.Join(testFile(testBatch(), testBatch()), "\n"),
+			record:   RecordEntry,
+			expected: []string{testEntry1Summary, testEntry2Summary, testEntry1Summary, testEntry2Summary},
+		},
+		{
+			name:     "entries with xpath",
+			input:    strings.Join(testFile(testBatch()), "\n"),
+			record:   RecordEntry,
+			xpath:    ".[addenda and ../batch_header/standard_entry_class_code='PPD']",
+			expected: []string{testEntry2Summary},
+		},
+		{
+			name:     "batches with xpath",
+			input:    strings.Join(testFile(testBatch()), "\n"),
+			xpath:    ".[batch_header/standard_entry_class_code='CCD']",
+			expected: nil,
+		},
+		{
+			name:     "entry ending the input",
+			input:    strings.Join(testFile(testBatch()), "\n"),
+			record:   RecordEntry,
+			xpath:    ".[not(addenda)]",
+			expected: []string{testEntry1Summary},
+		},
+		{
+			name:     "no line breaks, with padding records",
+			input:    strings.Join(padAll(append(testFile(testBatch()), testPadding, testPadding)), "") + "\r\n",
+			expected: []string{testBatchSummary},
+		},
+		{
+			name:     "blank lines, trailing spaces and CRLF",
+			input:    "\r\n" + strings.Join(testFile(testBatch()), "   \r\n\r\n") + "\r\n\r\n",
+			expected: []string{testBatchSummary},
+		},
+		{
+			name:     "multiple files",
+			input:    strings.Join(append(testFile(testBatch()), testFile(testBatch())...), "\n"),
+			expected: []string{testBatchSummary, testBatchSummary},
+		},
+		{
+			name:     "empty batch",
+			input:    strings.Join(testFile(nil), "\n"),
+			expected: []string{"batch:"},
+		},
+		{
+			name:     "empty input",
+			input:    " \n",
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testDecl(test.record, false), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_AncestorsOfEntries(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(strings.Join(testFile(testBatch()), "\n")),
+		testDecl(RecordEntry, false), "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	header, err := idr.MatchSingle(n, "../batch_header/batch_number")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000001", header.InnerText())
+	header, err = idr.MatchSingle(n, "../../file_header/immediate_origin_name")
+	assert.NoError(t, err)
+	assert.Equal(t, "ACME CORP", header.InnerText())
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 3, begin)
+	assert.Equal(t, 3, end)
+	r.Release(n)
+	n, err = r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, testEntry2Summary, summarize(t, n))
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 4, begin)
+	assert.Equal(t, 5, end)
+	assert.Equal(t, "input 'test-input' record 4: test", r.FmtErr("test").Error())
+}
+
+func TestRead_Batch(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(strings.Join(testFile(testBatch()), "\n")),
+		testDecl("", false), "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"batch_control":{"batch_number":"0000001","company_identification":"1234567890",`+
+			`"entry_addenda_count":"000003","entry_hash":"0009200003","message_authentication_code":"",`+
+			`"originating_dfi_identification":"09100001","service_class_code":"220",`+
+			`"total_credit_entry_dollar_amount":"000000360050","total_debit_entry_dollar_amount":"000000000000"},`+
+			`"batch_header":{"batch_number":"0000001","company_descriptive_date":"","company_discretionary_data":"",`+
+			`"company_entry_description":"PAYROLL","company_identification":"1234567890","company_name":"ACME CORP",`+
+			`"effective_entry_date":"261016","originating_dfi_identification":"09100001","originator_status_code":"1",`+
+			`"service_class_code":"220","settlement_date":"","standard_entry_class_code":"PPD"},`+
+			`"entry":[{"addenda_record_indicator":"0","amount":"0000150000","check_digit":"3",`+
+			`"dfi_account_number":"123456789","discretionary_data":"","individual_identification_number":"EMP001",`+
+			`"individual_name":"JOHN DOE","receiving_dfi_identification":"07100001","trace_number":"091000010000101",`+
+			`"transaction_code":"22"},{"addenda":{"addenda_sequence_number":"0001","addenda_type_code":"05",`+
+			`"entry_detail_sequence_number":"0000102","payment_related_information":"DIRECT DEPOSIT OCT 2026 PAY PERIOD 2"},`+
+			`"addenda_record_indicator":"1","amount":"0000210050","check_digit":"1","dfi_account_number":"987654321",`+
+			`"discretionary_data":"","individual_identification_number":"EMP002","individual_name":"JANE ROE",`+
+			`"receiving_dfi_identification":"02100002","trace_number":"091000010000102","transaction_code":"32"}]}`,
+		idr.JSONify2(n))
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 2, begin)
+	assert.Equal(t, 6, end)
+	header, err := idr.MatchSingle(n, "../file_header/immediate_destination")
+	assert.NoError(t, err)
+	assert.Equal(t, "091000019", header.InnerText())
+	r.Release(n)
+	n, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, n)
+}
+
+func TestRead_Errors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		recs     []string
+		record   string
+		verify   bool
+		expected []string
+	}{
+		{
+			name: "addenda not following an entry",
+			recs: testFile([]string{testAddenda, testEntry1}, testBatch()),
+			expected: []string{
+				"error: input 'test-input' record 3: addenda record not following an entry detail record",
+				testBatchSummary,
+			},
+		},
+		{
+			name:   "batch not terminated, of entries",
+			recs:   testFile([]string{testEntry1, testBatchHeader, testEntry2, testAddenda}),
+			record: RecordEntry,
+			expected: []string{
+				testEntry1Summary,
+				"error: input 'test-input' record 2: batch isn't terminated by a batch control record",
+				testEntry2Summary,
+			},
+		},
+		{
+			name: "entry outside of a batch",
+			recs: append([]string{testFileHeader, testEntry1}, testFile(testBatch())[1:]...),
+			expected: []string{
+				"error: input 'test-input' record 2: entry detail record outside of a batch",
+				testBatchSummary,
+			},
+		},
+		{
+			name: "batch control outside of a batch",
+			recs: append([]string{testFileHeader, testBatchControl}, testFile(testBatch())[1:]...),
+			expected: []string{
+				"error: input 'test-input' record 2: batch control record outside of a batch",
+				testBatchSummary,
+			},
+		},
+		{
+			name: "unknown record type",
+			recs: testFile([]string{testEntry1, "X"}, testBatch()),
+			expected: []string{
+				"error: input 'test-input' record 4: unknown record type code 'X'",
+				testBatchSummary,
+			},
+		},
+		{
+			name: "batch not terminated",
+			recs: testFile(testBatch())[:5],
+			expected: []string{
+				"error: input 'test-input' record 2: batch isn't terminated by a batch control record",
+				"error: input 'test-input' record 1: file isn't terminated by a file control record",
+			},
+		},
+		{
+			name: "batch not terminated by the next batch",
+			recs: append(testFile(testBatch())[:3], testFile(testBatch())[1:]...),
+			expected: []string{
+				"error: input 'test-input' record 2: batch isn't terminated by a batch control record",
+				testBatchSummary,
+			},
+		},
+		{
+			name: "batch not terminated by the file control",
+			recs: append(testFile(testBatch())[:3], testFileControl),
+			expected: []string{
+				"error: input 'test-input' record 2: batch isn't terminated by a batch control record",
+			},
+		},
+		{
+			name: "file not terminated by the next file",
+			recs: append(testFile(testBatch())[:6], testFile(testBatch())...),
+			expected: []string{
+				testBatchSummary,
+				"error: input 'test-input' record 1: file isn't terminated by a file control record",
+				testBatchSummary,
+			},
+		},
+		{
+			name: "records after file control",
+			recs: append(testFile(testBatch()), testPadding, testFileControl, testBatchHeader, testEntry1, testBatchControl),
+			expected: []string{
+				testBatchSummary,
+				"error: input 'test-input' record 9: file control record outside of a file",
+				"error: input 'test-input' record 10: batch header record outside of a file",
+			},
+		},
+		{
+			name:   "batch control mismatch",
+			recs:   testFile([]string{testEntry1, testEntry2}, testBatch()),
+			verify: true,
+			expected: []string{
+				"error: input 'test-input' record 5: entry_addenda_count '000003' doesn't match the batch's 2",
+				testBatchSummary,
+				"error: input 'test-input' record 11: entry_addenda_count '00000003' doesn't match the file's 5",
+			},
+		},
+		{
+			name:   "entry with invalid amount",
+			recs:   testFile([]string{strings.Replace(testEntry1, "0000150000", "00001500.0", 1)}, testBatch()),
+			verify: true,
+			expected: []string{
+				"error: input 'test-input' record 3: invalid amount '00001500.0'",
+				testBatchSummary,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(strings.Join(test.recs, "\n")),
+				testDecl(test.record, test.verify), "")
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_VerifyControls(t *testing.T) {
+	recs := testFile(testBatch())
+	for _, record := range []string{RecordBatch, RecordEntry} {
+		r, err := NewReader("test-input", strings.NewReader(strings.Join(recs, "\n")), testDecl(record, true), "")
+		assert.NoError(t, err)
+		records, err := readAll(t, r)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{RecordBatch: 1, RecordEntry: 2}[record], len(records))
+	}
+}
+
+func TestRead_FatalErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		expected []string
+		expErr   string
+	}{
+		{
+			name:   "not starting with a file header",
+			input:  testBatchHeader,
+			expErr: "input 'test-input' record 1: input doesn't start with a file header record",
+		},
+		{
+			name:     "invalid line length",
+			input:    strings.Join(testFile(testBatch())[:6], "\n") + "\n" + testFileControl + strings.Repeat(" ", 39) + "X\n",
+			expected: []string{testBatchSummary},
+			expErr:   "input 'test-input' record 7: line of 95 characters isn't made of 94-character records",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), testDecl("", false), "")
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.Equal(t, test.expected, records)
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidNACHA(err))
+			assert.False(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			var inputErr *errs.ErrInput
+			assert.True(t, errors.As(err, &inputErr))
+			assert.Equal(t, fileFormatNACHA, inputErr.Format)
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input",
+		io.MultiReader(strings.NewReader(strings.Join(testFile(testBatch())[:6], "\n")+"\n"), failingReader{}),
+		testDecl("", false), "")
+	assert.NoError(t, err)
+	records, err := readAll(t, r)
+	assert.Equal(t, []string{testBatchSummary}, records)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidNACHA(err))
+	assert.Equal(t, "input 'test-input' record 7: unable to read record: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), testDecl("", false), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidNACHA("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
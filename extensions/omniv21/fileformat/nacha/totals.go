@@ -0,0 +1,81 @@
+package nacha
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// entryHashModulo keeps the rightmost 10 digits of an entry hash.
+const entryHashModulo = 10000000000
+
+// totals are the counts, entry hash and dollar totals of a batch or a file, which its control record is
+// verified against.
+type totals struct {
+	batches      int64
+	entryAddenda int64
+	entryHash    int64
+	debit        int64
+	credit       int64
+}
+
+// addEntry adds an entry detail record into the totals. The entry hash is the sum of the receiving
+// DFI identifications, i.e. the routing numbers less their check digits, and a transaction code is a
+// credit if its 2nd digit is 0 to 4, or a debit otherwise, e.g. 22 and 27 for checking accounts.
+func (t *totals) addEntry(rec string) error {
+	rdfi, err := strconv.ParseInt(fieldValue(rec, entryDetailFields, "receiving_dfi_identification"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid receiving_dfi_identification '%s'", rec[3:11])
+	}
+	amount, err := strconv.ParseInt(fieldValue(rec, entryDetailFields, "amount"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount '%s'", rec[29:39])
+	}
+	t.entryAddenda++
+	t.entryHash += rdfi
+	if rec[2] <= '4' {
+		t.credit += amount
+	} else {
+		t.debit += amount
+	}
+	return nil
+}
+
+// addAddenda adds an addenda record into the totals.
+func (t *totals) addAddenda() {
+	t.entryAddenda++
+}
+
+// addBatch adds the totals of a batch into the totals of a file.
+func (t *totals) addBatch(batch *totals) {
+	t.batches++
+	t.entryAddenda += batch.entryAddenda
+	t.entryHash += batch.entryHash % entryHashModulo
+	t.debit += batch.debit
+	t.credit += batch.credit
+}
+
+type check struct {
+	name     string
+	expected int64
+}
+
+// verify verifies a control record, of the given fields, against the totals, e.g. of the batch it
+// ends. of is what the totals are of, e.g. "batch", for the error messages.
+func (t *totals) verify(rec string, fields []field, of string) error {
+	checks := []check{
+		{"entry_addenda_count", t.entryAddenda},
+		{"entry_hash", t.entryHash % entryHashModulo},
+		{"total_debit_entry_dollar_amount", t.debit},
+		{"total_credit_entry_dollar_amount", t.credit},
+	}
+	if of == elemFile {
+		checks = append(checks, check{"batch_count", t.batches})
+	}
+	for _, c := range checks {
+		v := fieldValue(rec, fields, c.name)
+		if n, err := strconv.ParseInt(v, 10, 64); err != nil || n != c.expected {
+			return fmt.Errorf("%s '%s' doesn't match the %s's %d", c.name, v, of, c.expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package nacha
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotals(t *testing.T) {
+	var batch totals
+	assert.NoError(t, batch.addEntry(testEntry1))
+	assert.NoError(t, batch.addEntry(testEntry2))
+	batch.addAddenda()
+	assert.Equal(t, totals{entryAddenda: 3, entryHash: 9200003, credit: 360050}, batch)
+	assert.NoError(t, batch.verify(testBatchControl, batchControlFields, elemBatch))
+
+	var file totals
+	file.addBatch(&batch)
+	assert.NoError(t, file.verify(padAll([]string{testFileControl})[0], fileControlFields, elemFile))
+	file.batches++
+	assert.Equal(t,
+		"batch_count '000001' doesn't match the file's 2",
+		file.verify(padAll([]string{testFileControl})[0], fileControlFields, elemFile).Error())
+}
+
+func TestTotals_Debit(t *testing.T) {
+	var batch totals
+	// 27: checking account debit.
+	assert.NoError(t, batch.addEntry("627"+testEntry1[3:]))
+	assert.Equal(t, totals{entryAddenda: 1, entryHash: 7100001, debit: 150000}, batch)
+}
+
+func TestTotals_EntryHashOverflow(t *testing.T) {
+	batch := totals{entryHash: 99999999999}
+	var file totals
+	file.addBatch(&batch)
+	assert.Equal(t, int64(9999999999), file.entryHash)
+}
+
+func TestTotals_InvalidEntry(t *testing.T) {
+	var batch totals
+	assert.Equal(t,
+		"invalid receiving_dfi_identification '0710000X'",
+		batch.addEntry(testEntry1[:3]+"0710000X"+testEntry1[11:]).Error())
+	assert.Equal(t,
+		"invalid amount '00001500.0'",
+		batch.addEntry(testEntry1[:29]+"00001500.0"+testEntry1[39:]).Error())
+	assert.Equal(t, totals{}, batch)
+}
+
+func TestLayout(t *testing.T) {
+	for _, fields := range append([][]field{
+		fileHeaderFields, batchHeaderFields, entryDetailFields, batchControlFields, fileControlFields},
+		addendaFields["05"], addendaFields["98"], addendaFields["99"]) {
+		end := 1
+		for _, f := range fields {
+			assert.True(t, f.begin > end && f.begin <= f.end && f.end <= recordSize, f.name)
+			end = f.end
+		}
+	}
+	assert.Equal(t, addendaFields["99"], addendaFieldsOf("799R01"))
+	assert.Equal(t, addendaFields["05"], addendaFieldsOf("702"))
+	assert.True(t, isPadding(testPadding))
+	assert.False(t, isPadding(testFileControl))
+	assert.Equal(t, "0000001", fieldValue(testBatchHeader, batchHeaderFields, "batch_number"))
+	assert.Equal(t, "", fieldValue(testBatchHeader, batchHeaderFields, "unknown"))
+}
@@ -0,0 +1,12 @@
+package parquet
+
+// FileDecl describes Parquet specific schema settings for omniparser reader. All settings are
+// optional.
+type FileDecl struct {
+	// Columns are the names of the columns to read, e.g. to skip the nested or repeated columns, which
+	// aren't supported, or for the columns not needed not to be decoded at all. Default: all columns.
+	Columns []string `json:"columns,omitempty"`
+	// BinaryAsString reads the BYTE_ARRAY and FIXED_LEN_BYTE_ARRAY columns with no logical type as
+	// strings rather than in hex, for the files written without annotating their string columns.
+	BinaryAsString bool `json:"binary_as_string,omitempty"`
+}
@@ -0,0 +1,188 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errValuesTruncated = errors.New("page values are truncated")
+
+// decodeHybrid decodes count values of bitWidth bits encoded with the RLE/bit-packing hybrid encoding,
+// used for the definition levels, the dictionary indices and the RLE encoded booleans.
+func decodeHybrid(b []byte, bitWidth, count int) ([]int, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid number of values %d", count)
+	}
+	values := make([]int, 0, count)
+	byteWidth := (bitWidth + 7) / 8
+	for pos := 0; len(values) < count; {
+		h, n := binary.Uvarint(b[pos:])
+		if n <= 0 {
+			return nil, errValuesTruncated
+		}
+		pos += n
+		if h&1 == 0 {
+			// RLE run: a value repeated h>>1 times, on byteWidth bytes, little endian.
+			if len(b)-pos < byteWidth {
+				return nil, errValuesTruncated
+			}
+			v := 0
+			for i := byteWidth - 1; i >= 0; i-- {
+				v = v<<8 | int(b[pos+i])
+			}
+			pos += byteWidth
+			for run := h >> 1; run > 0 && len(values) < count; run-- {
+				values = append(values, v)
+			}
+			continue
+		}
+		// bit-packed run: h>>1 groups of 8 values, least significant bits first.
+		groups := h >> 1
+		if bitWidth == 0 {
+			// no bytes: the values are all 0.
+			for i := uint64(0); i < groups*8 && len(values) < count; i++ {
+				values = append(values, 0)
+			}
+			continue
+		}
+		if groups > uint64(len(b)-pos) {
+			return nil, errValuesTruncated
+		}
+		end := pos + int(groups)*bitWidth
+		if end > len(b) {
+			return nil, errValuesTruncated
+		}
+		for bit := pos * 8; bit < end*8 && len(values) < count; bit += bitWidth {
+			v := 0
+			for i := 0; i < bitWidth; i++ {
+				if b[(bit+i)/8]&(1<<((bit+i)%8)) != 0 {
+					v |= 1 << i
+				}
+			}
+			values = append(values, v)
+		}
+		pos = end
+	}
+	return values, nil
+}
+
+// decodePlain decodes count values of the column with the PLAIN encoding.
+func (c *column) decodePlain(b []byte, count int) ([]string, error) {
+	// every value takes a bit at least.
+	if count < 0 || count > len(b)*8 {
+		return nil, errValuesTruncated
+	}
+	values := make([]string, 0, count)
+	fixed := func(size int) ([][]byte, error) {
+		if size <= 0 || len(b)/size < count {
+			return nil, errValuesTruncated
+		}
+		chunks := make([][]byte, count)
+		for i := range chunks {
+			chunks[i] = b[i*size : (i+1)*size]
+		}
+		return chunks, nil
+	}
+	switch c.typ {
+	case typeBoolean:
+		if len(b)*8 < count {
+			return nil, errValuesTruncated
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, formatBool(b[i/8]&(1<<(i%8)) != 0))
+		}
+		return values, nil
+	case typeByteArray:
+		for pos := 0; len(values) < count; {
+			if len(b)-pos < 4 {
+				return nil, errValuesTruncated
+			}
+			n := int64(binary.LittleEndian.Uint32(b[pos:]))
+			pos += 4
+			if n > int64(len(b)-pos) {
+				return nil, errValuesTruncated
+			}
+			values = append(values, c.formatBytes(b[pos:pos+int(n)]))
+			pos += int(n)
+		}
+		return values, nil
+	}
+	size := map[int64]int{
+		typeInt32: 4, typeInt64: 8, typeInt96: 12, typeFloat: 4, typeDouble: 8,
+		typeFixedLenByteArray: int(c.typeLength),
+	}[c.typ]
+	chunks, err := fixed(size)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range chunks {
+		switch c.typ {
+		case typeInt32:
+			values = append(values, c.formatInt(int64(int32(binary.LittleEndian.Uint32(v)))))
+		case typeInt64:
+			values = append(values, c.formatInt(int64(binary.LittleEndian.Uint64(v))))
+		case typeInt96:
+			values = append(values, formatInt96(v))
+		case typeFloat:
+			values = append(values, formatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(v))), 32))
+		case typeDouble:
+			values = append(values, formatFloat(math.Float64frombits(binary.LittleEndian.Uint64(v)), 64))
+		default:
+			values = append(values, c.formatBytes(v))
+		}
+	}
+	return values, nil
+}
+
+// decodeValues decodes count values of the column encoded with the given encoding, looking the
+// dictionary encoded ones up in dict.
+func (c *column) decodeValues(encoding int64, b []byte, count int, dict []string) ([]string, error) {
+	switch encoding {
+	case encodingPlain:
+		return c.decodePlain(b, count)
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if dict == nil {
+			return nil, errors.New("dictionary page is missing")
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		if len(b) == 0 {
+			return nil, errValuesTruncated
+		}
+		indices, err := decodeHybrid(b[1:], int(b[0]), count)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, count)
+		for i, index := range indices {
+			if index >= len(dict) {
+				return nil, fmt.Errorf("dictionary index %d out of range", index)
+			}
+			values[i] = dict[index]
+		}
+		return values, nil
+	case encodingRLE:
+		if c.typ != typeBoolean {
+			break
+		}
+		if len(b) < 4 || int64(binary.LittleEndian.Uint32(b)) > int64(len(b)-4) {
+			return nil, errValuesTruncated
+		}
+		bits, err := decodeHybrid(b[4:4+binary.LittleEndian.Uint32(b)], 1, count)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, count)
+		for i, bit := range bits {
+			values[i] = formatBool(bit != 0)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("encoding %s isn't supported", encodingName(encoding))
+}
@@ -0,0 +1,277 @@
+package parquet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeHybrid(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    []byte
+		bitWidth int
+		count    int
+		expected []int
+	}{
+		{
+			name:     "rle runs",
+			input:    []byte{3 << 1, 5, 2 << 1, 7},
+			bitWidth: 3,
+			count:    5,
+			expected: []int{5, 5, 5, 7, 7},
+		},
+		{
+			name:     "rle run of 2-byte values, longer than count",
+			input:    []byte{9 << 1, 0x34, 0x12},
+			bitWidth: 13,
+			count:    2,
+			expected: []int{0x1234, 0x1234},
+		},
+		{
+			name:     "bit-packed run of 0 to 7",
+			input:    []byte{1<<1 | 1, 0x88, 0xc6, 0xfa},
+			bitWidth: 3,
+			count:    8,
+			expected: []int{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:     "bit-packed run with padding, then rle run",
+			input:    []byte{1<<1 | 1, 0x0d, 2 << 1, 1},
+			bitWidth: 1,
+			count:    10,
+			expected: []int{1, 0, 1, 1, 0, 0, 0, 0, 1, 1},
+		},
+		{
+			name:     "bit width 0",
+			input:    []byte{1<<1 | 1, 4 << 1},
+			bitWidth: 0,
+			count:    10,
+			expected: []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:     "no values",
+			input:    nil,
+			bitWidth: 1,
+			count:    0,
+			expected: []int{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := decodeHybrid(test.input, test.bitWidth, test.count)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestDecodeHybrid_Errors(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		input       []byte
+		bitWidth    int
+		expectedErr string
+	}{
+		{name: "invalid bit width", input: nil, bitWidth: 33, expectedErr: "invalid bit width 33"},
+		{name: "no header", input: nil, bitWidth: 1, expectedErr: "page values are truncated"},
+		{name: "rle value truncated", input: []byte{4 << 1, 1}, bitWidth: 9, expectedErr: "page values are truncated"},
+		{name: "bit-packed groups truncated", input: []byte{4<<1 | 1, 0}, bitWidth: 1, expectedErr: "page values are truncated"},
+		{name: "bit-packed run truncated", input: []byte{1<<1 | 1, 0}, bitWidth: 2, expectedErr: "page values are truncated"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := decodeHybrid(test.input, test.bitWidth, 4)
+			assert.Error(t, err)
+			assert.Equal(t, test.expectedErr, err.Error())
+			assert.Nil(t, values)
+		})
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	boolColumn := &column{typ: typeBoolean}
+	intColumn := &column{typ: typeInt32}
+	dict := []string{"a", "b", "c"}
+	for _, test := range []struct {
+		name        string
+		c           *column
+		encoding    int64
+		input       []byte
+		count       int
+		dict        []string
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "rle booleans",
+			c:        boolColumn,
+			encoding: encodingRLE,
+			input:    []byte{3, 0, 0, 0, 2 << 1, 1, 0},
+			count:    2,
+			expected: []string{"true", "true"},
+		},
+		{
+			name:        "rle booleans length truncated",
+			c:           boolColumn,
+			encoding:    encodingRLE,
+			input:       []byte{3, 0},
+			count:       2,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "rle booleans truncated",
+			c:           boolColumn,
+			encoding:    encodingRLE,
+			input:       []byte{1, 0, 0, 0, 2 << 1},
+			count:       2,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "rle ints",
+			c:           intColumn,
+			encoding:    encodingRLE,
+			count:       2,
+			expectedErr: "encoding RLE isn't supported",
+		},
+		{
+			name:        "delta ints",
+			c:           intColumn,
+			encoding:    5,
+			count:       2,
+			expectedErr: "encoding DELTA_BINARY_PACKED isn't supported",
+		},
+		{
+			name:        "unknown encoding",
+			c:           intColumn,
+			encoding:    42,
+			count:       2,
+			expectedErr: "encoding #42 isn't supported",
+		},
+		{
+			name:     "dictionary",
+			c:        intColumn,
+			encoding: encodingPlainDictionary,
+			input:    []byte{2, 1<<1 | 1, 0x24, 0},
+			count:    3,
+			dict:     dict,
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "dictionary, no values",
+			c:        intColumn,
+			encoding: encodingRLEDictionary,
+			count:    0,
+			dict:     dict,
+			expected: nil,
+		},
+		{
+			name:        "dictionary missing",
+			c:           intColumn,
+			encoding:    encodingRLEDictionary,
+			input:       []byte{0, 2 << 1},
+			count:       2,
+			expectedErr: "dictionary page is missing",
+		},
+		{
+			name:        "dictionary bit width missing",
+			c:           intColumn,
+			encoding:    encodingRLEDictionary,
+			count:       2,
+			dict:        dict,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "dictionary indices truncated",
+			c:           intColumn,
+			encoding:    encodingRLEDictionary,
+			input:       []byte{2},
+			count:       2,
+			dict:        dict,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "dictionary index out of range",
+			c:           intColumn,
+			encoding:    encodingRLEDictionary,
+			input:       []byte{2, 2 << 1, 3},
+			count:       2,
+			dict:        dict,
+			expectedErr: "dictionary index 3 out of range",
+		},
+		{
+			name:        "plain booleans truncated",
+			c:           boolColumn,
+			encoding:    encodingPlain,
+			input:       []byte{1},
+			count:       9,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "plain byte arrays length truncated",
+			c:           &column{typ: typeByteArray},
+			encoding:    encodingPlain,
+			input:       []byte{1, 0, 0},
+			count:       1,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "plain byte arrays truncated",
+			c:           &column{typ: typeByteArray},
+			encoding:    encodingPlain,
+			input:       []byte{2, 0, 0, 0, 'a'},
+			count:       1,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "plain ints truncated",
+			c:           intColumn,
+			encoding:    encodingPlain,
+			input:       []byte{1, 0, 0, 0, 2, 0, 0},
+			count:       2,
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "plain fixed length byte arrays of length 0",
+			c:           &column{typ: typeFixedLenByteArray},
+			encoding:    encodingPlain,
+			count:       1,
+			expectedErr: "page values are truncated",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := test.c.decodeValues(test.encoding, test.input, test.count, test.dict)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, values)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	for _, test := range []struct {
+		unscaled int64
+		scale    int
+		expected string
+	}{
+		{unscaled: 12345, scale: 2, expected: "123.45"},
+		{unscaled: -12345, scale: 0, expected: "-12345"},
+		{unscaled: 5, scale: 3, expected: "0.005"},
+		{unscaled: -50, scale: 2, expected: "-0.50"},
+		{unscaled: 0, scale: 1, expected: "0.0"},
+	} {
+		t.Run(test.expected, func(t *testing.T) {
+			assert.Equal(t, test.expected, formatDecimal(big.NewInt(test.unscaled), test.scale))
+		})
+	}
+}
+
+func TestCodecName(t *testing.T) {
+	assert.Equal(t, "SNAPPY", codecName(codecSnappy))
+	assert.Equal(t, "#8", codecName(8))
+	assert.Equal(t, "#-1", codecName(-1))
+}
@@ -0,0 +1,81 @@
+package parquet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatParquet,
+			schema:      `{"file_declaration": { "columns": [] }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.columns: Array must have at least 1 items`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatParquet,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatParquet,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success",
+			format: fileFormatParquet,
+			schema: `{"file_declaration": { "columns": [ "id", "name" ], "binary_as_string": true }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[id > 2] ")},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewParquetFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			rt := runtime.(*parquetFormatRuntime)
+			assert.Equal(t, ".[id > 2]", rt.XPath)
+			assert.Equal(t, &FileDecl{Columns: []string{"id", "name"}, BinaryAsString: true}, rt.Decl)
+			r, err := NewParquetFileFormat("test-schema").CreateFormatReader(
+				"test-input", bytes.NewReader(testPeople().bytes()), runtime)
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, "id=3,name=bob", summarize(n))
+		})
+	}
+}
+
+func TestValidateSchema_NoFileDeclaration(t *testing.T) {
+	runtime, err := NewParquetFileFormat("test-schema").ValidateSchema(fileFormatParquet, []byte(`{}`), &transform.Decl{})
+	assert.NoError(t, err)
+	rt := runtime.(*parquetFormatRuntime)
+	assert.Equal(t, "", rt.XPath)
+	assert.Equal(t, &FileDecl{}, rt.Decl)
+}
@@ -0,0 +1,161 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const magic = "PAR1"
+
+// Physical types.
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt64             = 2
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDouble            = 5
+	typeByteArray         = 6
+	typeFixedLenByteArray = 7
+)
+
+// Field repetitions.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// Compression codecs.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+var codecNames = []string{"UNCOMPRESSED", "SNAPPY", "GZIP", "LZO", "BROTLI", "LZ4", "ZSTD", "LZ4_RAW"}
+
+func codecName(codec int64) string {
+	if codec >= 0 && codec < int64(len(codecNames)) {
+		return codecNames[codec]
+	}
+	return fmt.Sprintf("#%d", codec)
+}
+
+// Page types.
+const (
+	pageData       = 0
+	pageIndex      = 1
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// Encodings.
+const (
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingRLEDictionary   = 8
+)
+
+var encodingNames = []string{
+	"PLAIN", "GROUP_VAR_INT", "PLAIN_DICTIONARY", "RLE", "BIT_PACKED", "DELTA_BINARY_PACKED",
+	"DELTA_LENGTH_BYTE_ARRAY", "DELTA_BYTE_ARRAY", "RLE_DICTIONARY", "BYTE_STREAM_SPLIT",
+}
+
+func encodingName(encoding int64) string {
+	if encoding >= 0 && encoding < int64(len(encodingNames)) {
+		return encodingNames[encoding]
+	}
+	return fmt.Sprintf("#%d", encoding)
+}
+
+// schemaElement is a node of the schema tree of a Parquet file, flattened in depth-first order: a
+// group has children, a column doesn't, and has a physical type.
+type schemaElement struct {
+	name          string
+	typ           int64 // -1 for groups.
+	typeLength    int64
+	repetition    int64
+	numChildren   int64
+	convertedType int64 // -1 if none.
+	scale         int64
+	logical       tstruct // the LogicalType union, nil if none.
+}
+
+// columnChunk is the metadata of the values of a column in a row group.
+type columnChunk struct {
+	codec          int64
+	numValues      int64
+	dataPageOffset int64
+	dictPageOffset int64 // 0 if none.
+	compressedSize int64
+}
+
+type rowGroup struct {
+	numRows int64
+	columns []columnChunk
+}
+
+type fileMeta struct {
+	numRows   int64
+	schema    []schemaElement
+	rowGroups []rowGroup
+}
+
+// readFileMeta reads the metadata out of the footer of a Parquet file.
+func readFileMeta(data []byte) (*fileMeta, error) {
+	if len(data) < 2*len(magic)+4 || string(data[:len(magic)]) != magic {
+		if len(data) >= 4 && string(data[:4]) == "PARE" {
+			return nil, errors.New("encrypted Parquet files aren't supported")
+		}
+		return nil, errors.New("not a Parquet file")
+	}
+	if string(data[len(data)-len(magic):]) != magic {
+		return nil, errors.New("footer not found, the input may be truncated")
+	}
+	footerLen := int64(binary.LittleEndian.Uint32(data[len(data)-len(magic)-4:]))
+	footerEnd := int64(len(data) - len(magic) - 4)
+	if footerLen > footerEnd-int64(len(magic)) {
+		return nil, fmt.Errorf("invalid footer length %d", footerLen)
+	}
+	s, _, err := decodeStruct(data[footerEnd-footerLen : footerEnd])
+	if err != nil {
+		return nil, fmt.Errorf("invalid footer: %s", err.Error())
+	}
+	m := &fileMeta{numRows: s.intOr(3, 0)}
+	for _, v := range s.list(2) {
+		e, _ := v.(tstruct)
+		m.schema = append(m.schema, schemaElement{
+			name:          e.str(4),
+			typ:           e.intOr(1, -1),
+			typeLength:    e.intOr(2, 0),
+			repetition:    e.intOr(3, repetitionRequired),
+			numChildren:   e.intOr(5, 0),
+			convertedType: e.intOr(6, -1),
+			scale:         e.intOr(7, 0),
+			logical:       e.strct(10),
+		})
+	}
+	for _, v := range s.list(4) {
+		g, _ := v.(tstruct)
+		rg := rowGroup{numRows: g.intOr(3, 0)}
+		for _, v := range g.list(1) {
+			c, _ := v.(tstruct)
+			cm := c.strct(3)
+			rg.columns = append(rg.columns, columnChunk{
+				codec:          cm.intOr(4, codecUncompressed),
+				numValues:      cm.intOr(5, 0),
+				dataPageOffset: cm.intOr(9, 0),
+				dictPageOffset: cm.intOr(11, 0),
+				compressedSize: cm.intOr(7, 0),
+			})
+		}
+		m.rowGroups = append(m.rowGroups, rg)
+	}
+	if len(m.schema) == 0 {
+		return nil, errors.New("invalid footer: schema is missing")
+	}
+	return m, nil
+}
@@ -0,0 +1,204 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxPageSize is the max uncompressed size of a page, for corrupted metadata not to exhaust memory.
+const maxPageSize = 1 << 30
+
+// maxPageValues is the max number of values of a page, for corrupted metadata not to exhaust memory:
+// the values of an optional column can be all null, and take no bytes at all.
+const maxPageValues = 1 << 24
+
+// columnValues is the values of a column in a row group, as text, with defined[i] false for the
+// nulls.
+type columnValues struct {
+	values  []string
+	defined []bool
+}
+
+// readColumnChunk reads the values of a column in a row group, page after page, out of the data of a
+// Parquet file.
+func readColumnChunk(data []byte, c *column, chunk columnChunk) (*columnValues, error) {
+	start := chunk.dataPageOffset
+	if chunk.dictPageOffset > 0 && chunk.dictPageOffset < start {
+		start = chunk.dictPageOffset
+	}
+	end := start + chunk.compressedSize
+	if start < int64(len(magic)) || chunk.compressedSize < 0 || end > int64(len(data)) {
+		return nil, fmt.Errorf("invalid column chunk offsets %d to %d", start, end)
+	}
+	b := data[start:end]
+	cv := &columnValues{}
+	var dict []string
+	for int64(len(cv.defined)) < chunk.numValues {
+		if len(b) == 0 {
+			return nil, fmt.Errorf("%d values found, expected %d", len(cv.defined), chunk.numValues)
+		}
+		h, n, err := decodeStruct(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page header: %s", err.Error())
+		}
+		b = b[n:]
+		size := h.intOr(3, -1)
+		if size < 0 || size > int64(len(b)) {
+			return nil, fmt.Errorf("invalid page size %d", size)
+		}
+		page := b[:size]
+		b = b[size:]
+		uncompressedSize := h.intOr(2, -1)
+		if uncompressedSize < 0 || uncompressedSize > maxPageSize {
+			return nil, fmt.Errorf("invalid uncompressed page size %d", uncompressedSize)
+		}
+		switch h.intOr(1, -1) {
+		case pageDictionary:
+			dh := h.strct(7)
+			if page, err = decompress(chunk.codec, page, int(uncompressedSize)); err != nil {
+				return nil, err
+			}
+			count, err := pageValueCount(dh.intOr(1, 0))
+			if err != nil {
+				return nil, fmt.Errorf("dictionary page: %s", err.Error())
+			}
+			if dict, err = c.decodePlain(page, count); err != nil {
+				return nil, fmt.Errorf("dictionary page: %s", err.Error())
+			}
+		case pageData:
+			dh := h.strct(5)
+			if page, err = decompress(chunk.codec, page, int(uncompressedSize)); err != nil {
+				return nil, err
+			}
+			count, err := pageValueCount(dh.intOr(1, 0))
+			if err != nil {
+				return nil, err
+			}
+			defined, rest, err := c.readDefinitionLevels(page, count, true)
+			if err != nil {
+				return nil, err
+			}
+			if err = cv.add(c, dh.intOr(2, encodingPlain), rest, defined, dict); err != nil {
+				return nil, err
+			}
+		case pageDataV2:
+			dh := h.strct(8)
+			count, err := pageValueCount(dh.intOr(1, 0))
+			if err != nil {
+				return nil, err
+			}
+			levelsLen := dh.intOr(5, 0) + dh.intOr(6, 0)
+			if levelsLen < 0 || levelsLen > int64(len(page)) {
+				return nil, fmt.Errorf("invalid levels length %d", levelsLen)
+			}
+			defined, _, err := c.readDefinitionLevels(page[:levelsLen], count, false)
+			if err != nil {
+				return nil, err
+			}
+			values := page[levelsLen:]
+			if compressed, found := dh.bool(7); compressed || !found {
+				values, err = decompress(chunk.codec, values, int(uncompressedSize-levelsLen))
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err = cv.add(c, dh.intOr(4, encodingPlain), values, defined, dict); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if int64(len(cv.defined)) != chunk.numValues {
+		return nil, fmt.Errorf("%d values found, expected %d", len(cv.defined), chunk.numValues)
+	}
+	return cv, nil
+}
+
+// pageValueCount checks the number of values of a page, read from its header.
+func pageValueCount(count int64) (int, error) {
+	if count < 0 || count > maxPageValues {
+		return 0, fmt.Errorf("invalid number of values %d", count)
+	}
+	return int(count), nil
+}
+
+// readDefinitionLevels reads the definition levels of the count values of a data page, if the column
+// is optional, and returns which values are defined, along with the rest of the page. The levels of a
+// v1 data page are prefixed with their length.
+func (c *column) readDefinitionLevels(page []byte, count int, lengthPrefixed bool) ([]bool, []byte, error) {
+	defined := make([]bool, count)
+	if !c.optional {
+		for i := range defined {
+			defined[i] = true
+		}
+		return defined, page, nil
+	}
+	levels := page
+	if lengthPrefixed {
+		if len(page) < 4 || int64(binary.LittleEndian.Uint32(page)) > int64(len(page)-4) {
+			return nil, nil, errors.New("definition levels are truncated")
+		}
+		n := 4 + int(binary.LittleEndian.Uint32(page))
+		levels, page = page[4:n], page[n:]
+	}
+	values, err := decodeHybrid(levels, 1, count)
+	if err != nil {
+		return nil, nil, fmt.Errorf("definition levels: %s", err.Error())
+	}
+	for i, v := range values {
+		defined[i] = v == 1
+	}
+	return defined, page, nil
+}
+
+// add decodes the values of a data page, the defined ones only, and appends them.
+func (cv *columnValues) add(c *column, encoding int64, b []byte, defined []bool, dict []string) error {
+	count := 0
+	for _, d := range defined {
+		if d {
+			count++
+		}
+	}
+	values, err := c.decodeValues(encoding, b, count, dict)
+	if err != nil {
+		return err
+	}
+	for _, d := range defined {
+		v := ""
+		if d {
+			v, values = values[0], values[1:]
+		}
+		cv.values = append(cv.values, v)
+	}
+	cv.defined = append(cv.defined, defined...)
+	return nil
+}
+
+// decompress decompresses a page, whose uncompressed size is size.
+func decompress(codec int64, b []byte, size int) ([]byte, error) {
+	var out []byte
+	var err error
+	switch codec {
+	case codecUncompressed:
+		out = b
+	case codecSnappy:
+		out, err = snappyDecode(b, size)
+	case codecGzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(b)); err == nil {
+			out, err = io.ReadAll(io.LimitReader(r, int64(size)+1))
+		}
+	default:
+		return nil, fmt.Errorf("compression codec %s isn't supported", codecName(codec))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress page: %s", err.Error())
+	}
+	if len(out) != size {
+		return nil, fmt.Errorf("page of %d bytes once decompressed, expected %d", len(out), size)
+	}
+	return out, nil
+}
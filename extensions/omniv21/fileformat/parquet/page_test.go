@@ -0,0 +1,225 @@
+package parquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPage(typ int32, uncompressedSize int, body []byte, fields ...tfield) []byte {
+	h := append(tst{{1, typ}, {2, int32(uncompressedSize)}, {3, int32(len(body))}}, fields...)
+	return append(encodeThrift(nil, h), body...)
+}
+
+func testDataPage(count int, body []byte) []byte {
+	return testPage(pageData, len(body), body, tfield{5, tst{{1, int32(count)}, {2, int32(encodingPlain)}}})
+}
+
+func testDataPageV2(count int, levelsLen int, compressed bool, uncompressedSize int, body []byte) []byte {
+	return testPage(pageDataV2, uncompressedSize, body, tfield{8, tst{
+		{1, int32(count)}, {2, int32(0)}, {3, int32(count)}, {4, int32(encodingPlain)},
+		{5, int32(levelsLen)}, {6, int32(0)}, {7, compressed},
+	}})
+}
+
+func testChunk(codec int64, numValues int64, pages ...[]byte) ([]byte, columnChunk) {
+	data := []byte(magic)
+	for _, p := range pages {
+		data = append(data, p...)
+	}
+	return data, columnChunk{
+		codec:          codec,
+		numValues:      numValues,
+		dataPageOffset: int64(len(magic)),
+		compressedSize: int64(len(data) - len(magic)),
+	}
+}
+
+func TestReadColumnChunk(t *testing.T) {
+	data, chunk := testChunk(codecSnappy, 3,
+		testPage(pageIndex, 2, []byte{1, 2}),
+		// v2 data page, optional, values not compressed despite the codec.
+		testDataPageV2(3, 2, false, 10, []byte{1<<1 | 1, 0x05, 7, 0, 0, 0, 8, 0, 0, 0}))
+	cv, err := readColumnChunk(data, &column{typ: typeInt32, optional: true}, chunk)
+	assert.NoError(t, err)
+	assert.Equal(t, &columnValues{values: []string{"7", "", "8"}, defined: []bool{true, false, true}}, cv)
+}
+
+func TestReadColumnChunk_Errors(t *testing.T) {
+	required := &column{typ: typeInt32}
+	optional := &column{typ: typeInt32, optional: true}
+	int32s := []byte{1, 0, 0, 0, 2, 0, 0, 0}
+	for _, test := range []struct {
+		name        string
+		c           *column
+		data        []byte
+		chunk       columnChunk
+		expectedErr string
+	}{
+		{
+			name:        "invalid offsets",
+			c:           required,
+			data:        []byte(magic),
+			chunk:       columnChunk{numValues: 1, dataPageOffset: 4, compressedSize: 1},
+			expectedErr: "invalid column chunk offsets 4 to 5",
+		},
+		{
+			name:        "invalid dictionary page offset",
+			c:           required,
+			data:        []byte(magic),
+			chunk:       columnChunk{numValues: 1, dataPageOffset: 4, dictPageOffset: 2},
+			expectedErr: "invalid column chunk offsets 2 to 2",
+		},
+		{
+			name:        "no pages",
+			c:           required,
+			data:        []byte(magic),
+			chunk:       columnChunk{numValues: 1, dataPageOffset: 4},
+			expectedErr: "0 values found, expected 1",
+		},
+		{
+			name:        "invalid page header",
+			c:           required,
+			data:        []byte(magic + "\x15"),
+			chunk:       columnChunk{numValues: 1, dataPageOffset: 4, compressedSize: 1},
+			expectedErr: "invalid page header: thrift: unexpected end of data",
+		},
+		{
+			name:        "invalid page size",
+			c:           required,
+			data:        encodeThrift(nil, tst{{1, int32(pageData)}, {2, int32(4)}, {3, int32(10)}}),
+			expectedErr: "invalid page size 10",
+		},
+		{
+			name:        "invalid uncompressed page size",
+			c:           required,
+			data:        testPage(pageData, -1, nil),
+			expectedErr: "invalid uncompressed page size -1",
+		},
+		{
+			name:        "dictionary page not decompressed",
+			c:           required,
+			data:        testPage(pageDictionary, 4, []byte{1, 2}, tfield{7, tst{{1, int32(1)}}}),
+			expectedErr: "page of 2 bytes once decompressed, expected 4",
+		},
+		{
+			name:        "dictionary page truncated",
+			c:           required,
+			data:        testPage(pageDictionary, 2, []byte{1, 2}, tfield{7, tst{{1, int32(1)}}}),
+			expectedErr: "dictionary page: page values are truncated",
+		},
+		{
+			name:        "dictionary page invalid number of values",
+			c:           required,
+			data:        testPage(pageDictionary, 2, []byte{1, 2}, tfield{7, tst{{1, int32(-1)}}}),
+			expectedErr: "dictionary page: invalid number of values -1",
+		},
+		{
+			name:        "dictionary page more values than bytes",
+			c:           required,
+			data:        testPage(pageDictionary, 2, []byte{1, 2}, tfield{7, tst{{1, int32(1 << 30)}}}),
+			expectedErr: "dictionary page: invalid number of values 1073741824",
+		},
+		{
+			name:        "dictionary page values beyond the page size",
+			c:           &column{typ: typeByteArray},
+			data:        testPage(pageDictionary, 2, []byte{1, 2}, tfield{7, tst{{1, int32(17)}}}),
+			expectedErr: "dictionary page: page values are truncated",
+		},
+		{
+			name:        "data page not decompressed",
+			c:           required,
+			data:        testPage(pageData, 10, int32s, tfield{5, tst{{1, int32(2)}}}),
+			expectedErr: "page of 8 bytes once decompressed, expected 10",
+		},
+		{
+			name:        "data page definition levels length truncated",
+			c:           optional,
+			data:        testDataPage(2, []byte{1, 0}),
+			expectedErr: "definition levels are truncated",
+		},
+		{
+			name:        "data page definition levels truncated",
+			c:           optional,
+			data:        testDataPage(2, []byte{0, 0, 0, 0}),
+			expectedErr: "definition levels: page values are truncated",
+		},
+		{
+			name:        "data page values truncated",
+			c:           required,
+			data:        testDataPage(3, int32s),
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "data page invalid number of values",
+			c:           optional,
+			data:        testDataPage(-1, int32s),
+			expectedErr: "invalid number of values -1",
+		},
+		{
+			name:        "data page v2 too many values",
+			c:           optional,
+			data:        testDataPageV2(maxPageValues+1, 0, false, 8, int32s),
+			expectedErr: "invalid number of values 16777217",
+		},
+		{
+			name:        "data page v2 invalid levels length",
+			c:           optional,
+			data:        testDataPageV2(2, 9, false, 8, int32s),
+			expectedErr: "invalid levels length 9",
+		},
+		{
+			name:        "data page v2 definition levels truncated",
+			c:           optional,
+			data:        testDataPageV2(2, 0, false, 8, int32s),
+			expectedErr: "definition levels: page values are truncated",
+		},
+		{
+			name:        "data page v2 not decompressed",
+			c:           required,
+			data:        testDataPageV2(2, 0, true, 9, int32s),
+			expectedErr: "page of 8 bytes once decompressed, expected 9",
+		},
+		{
+			name:        "data page v2 values truncated",
+			c:           required,
+			data:        testDataPageV2(3, 0, true, 8, int32s),
+			expectedErr: "page values are truncated",
+		},
+		{
+			name:        "too many values",
+			c:           required,
+			data:        append(testDataPage(1, int32s[:4]), testDataPage(2, int32s)...),
+			expectedErr: "3 values found, expected 2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, chunk := test.data, test.chunk
+			if chunk == (columnChunk{}) {
+				data, chunk = testChunk(codecUncompressed, 2, test.data)
+			}
+			cv, err := readColumnChunk(data, test.c, chunk)
+			assert.Error(t, err)
+			assert.Equal(t, test.expectedErr, err.Error())
+			assert.Nil(t, cv)
+		})
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	b, err := decompress(codecGzip, compress(codecGzip, []byte("abc")), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(b))
+	b, err = decompress(codecGzip, []byte("not a gzip stream"), 3)
+	assert.Error(t, err)
+	assert.Equal(t, "unable to decompress page: gzip: invalid header", err.Error())
+	assert.Nil(t, b)
+	b, err = decompress(codecGzip, compress(codecGzip, []byte("abcd")), 3)
+	assert.Error(t, err)
+	assert.Equal(t, "page of 4 bytes once decompressed, expected 3", err.Error())
+	assert.Nil(t, b)
+	b, err = decompress(5, []byte("abc"), 3)
+	assert.Error(t, err)
+	assert.Equal(t, "compression codec LZ4 isn't supported", err.Error())
+	assert.Nil(t, b)
+}
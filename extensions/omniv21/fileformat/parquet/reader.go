@@ -0,0 +1,293 @@
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/input"
+)
+
+// ErrInvalidParquet indicates the input can't be read, e.g. it isn't a Parquet file, or its schema has
+// nested columns to be read. This is a fatal, non-continuable error. Note errors in a row group (e.g.
+// a corrupted page, or an unsupported compression codec) are continuable: the reader simply moves onto
+// the next row group.
+type ErrInvalidParquet string
+
+func (e ErrInvalidParquet) Error() string { return string(e) }
+
+// IsErrInvalidParquet checks if the `err` is of ErrInvalidParquet type, or wraps one, e.g. in an
+// errs.ErrInput.
+func IsErrInvalidParquet(err error) bool {
+	var e ErrInvalidParquet
+	return errors.As(err, &e)
+}
+
+type reader struct {
+	inputName   string
+	src         io.Reader
+	decl        *FileDecl
+	targetXPath *xpath.Expr
+	err         error // the fatal error, if any.
+	data        []byte
+	meta        *fileMeta
+	leaves      int       // number of columns, or leaves of the schema tree, in the input.
+	columns     []*column // the columns read, in the order of the schema.
+	rowGroup    int       // 1-based number of the row group the rows are from.
+	values      []*columnValues
+	rows        int   // number of rows of the row group, 0 if it failed to be read.
+	rowIndex    int   // 0-based index of the next row of the row group to read.
+	rowBase     int64 // number of rows of the row groups before the current one.
+	nextRowBase int64 // number of rows of the row groups till the current one.
+	row         int64 // 1-based number, in the input, of the last row read.
+}
+
+// Read returns the next row as a record. Parquet files have their metadata in their footer, so the
+// entire input is read into memory on the first Read, unless it's already in memory, e.g. a
+// memory-mapped input.MappedFile. The rows are then decoded one row group at a time.
+func (r *reader) Read() (*idr.Node, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.meta == nil {
+		if r.err = r.load(); r.err != nil {
+			return nil, r.err
+		}
+	}
+	for {
+		for r.rowIndex >= r.rows {
+			if r.rowGroup >= len(r.meta.rowGroups) {
+				return nil, io.EOF
+			}
+			rg := r.meta.rowGroups[r.rowGroup]
+			// the rows of a row group failing to be read still count.
+			r.rowBase, r.nextRowBase = r.nextRowBase, r.nextRowBase+rg.numRows
+			r.rowGroup++
+			r.values, r.rows, r.rowIndex = nil, 0, 0
+			if err := r.readRowGroup(rg); err != nil {
+				return nil, err
+			}
+		}
+		n := r.rowToNode(r.rowIndex)
+		r.rowIndex++
+		r.row = r.rowBase + int64(r.rowIndex)
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+// load reads the input and its metadata, and works out the columns to read.
+func (r *reader) load() error {
+	if m, ok := r.src.(input.InMemory); ok && m.Bytes() != nil {
+		r.data = m.Bytes()
+	} else {
+		data, err := io.ReadAll(r.src)
+		if err != nil {
+			return r.invalidParquet("unable to read input: %s", err.Error())
+		}
+		r.data = data
+	}
+	meta, err := readFileMeta(r.data)
+	if err != nil {
+		return r.invalidParquet("%s", err.Error())
+	}
+	// the columns supported are the leaves of the schema tree right under its root, i.e. not nested,
+	// and not repeated.
+	schema := meta.schema
+	var supported []*column
+	var unsupported []string
+	pos := 1
+	for i := int64(0); i < schema[0].numChildren; i++ {
+		if pos >= len(schema) {
+			return r.invalidParquet("invalid schema: %d elements, more expected", len(schema))
+		}
+		e := schema[pos]
+		leaves, next := countLeaves(schema, pos)
+		if next < 0 {
+			return r.invalidParquet("invalid schema: %d elements, more expected", len(schema))
+		}
+		if e.numChildren == 0 && e.typ >= 0 && e.repetition != repetitionRepeated {
+			supported = append(supported, newColumn(e, r.leaves, r.decl.BinaryAsString))
+		} else {
+			unsupported = append(unsupported, e.name)
+		}
+		r.leaves += leaves
+		pos = next
+	}
+	if len(r.decl.Columns) == 0 {
+		if len(unsupported) > 0 {
+			return r.invalidParquet(
+				"column '%s' is nested or repeated, which isn't supported, 'columns' can be used to skip it",
+				unsupported[0])
+		}
+		r.columns = supported
+	} else {
+		for _, name := range r.decl.Columns {
+			if contains(unsupported, name) {
+				return r.invalidParquet("column '%s' is nested or repeated, which isn't supported", name)
+			}
+		}
+		for _, c := range supported {
+			if contains(r.decl.Columns, c.name) {
+				r.columns = append(r.columns, c)
+			}
+		}
+		if len(r.columns) < len(r.decl.Columns) {
+			for _, name := range r.decl.Columns {
+				if !containsColumn(r.columns, name) {
+					return r.invalidParquet("column '%s' isn't in the input", name)
+				}
+			}
+		}
+	}
+	r.meta = meta
+	return nil
+}
+
+// countLeaves returns the number of leaves of the subtree of the schema element at pos, and the
+// position of the element following the subtree, or -1 if the schema is truncated.
+func countLeaves(schema []schemaElement, pos int) (int, int) {
+	if pos >= len(schema) {
+		return 0, -1
+	}
+	if schema[pos].numChildren == 0 {
+		return 1, pos + 1
+	}
+	leaves, next := 0, pos+1
+	for i := int64(0); i < schema[pos].numChildren; i++ {
+		n, nxt := countLeaves(schema, next)
+		if nxt < 0 {
+			return 0, -1
+		}
+		leaves, next = leaves+n, nxt
+	}
+	return leaves, next
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsColumn(columns []*column, name string) bool {
+	for _, c := range columns {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readRowGroup decodes the values of the columns read in a row group.
+func (r *reader) readRowGroup(rg rowGroup) error {
+	if rg.numRows < 0 {
+		return r.fmtErr("invalid number of rows %d", rg.numRows)
+	}
+	if len(rg.columns) != r.leaves {
+		return r.fmtErr("%d column chunks, expected %d", len(rg.columns), r.leaves)
+	}
+	values := make([]*columnValues, len(r.columns))
+	for i, c := range r.columns {
+		cv, err := readColumnChunk(r.data, c, rg.columns[c.index])
+		if err != nil {
+			return r.fmtErr("column '%s': %s", c.name, err.Error())
+		}
+		if int64(len(cv.values)) != rg.numRows {
+			return r.fmtErr("column '%s' has %d values, expected %d", c.name, len(cv.values), rg.numRows)
+		}
+		values[i] = cv
+	}
+	r.values, r.rows = values, int(rg.numRows)
+	return nil
+}
+
+// rowToNode creates the record of a row of the row group, with an element for each column read, but
+// the nulls.
+func (r *reader) rowToNode(i int) *idr.Node {
+	root := idr.CreateNode(idr.DocumentNode, "")
+	for j, c := range r.columns {
+		if !r.values[j].defined[i] {
+			continue
+		}
+		n := idr.CreateNode(idr.ElementNode, c.name)
+		idr.AddChild(root, n)
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, r.values[j].values[i]))
+	}
+	return root
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the 1-based number of the row
+// returned by the last successful Read call, in the input.
+func (r *reader) RecordPosition() (int, int) {
+	return int(r.row), int(r.row)
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidParquet(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("input '%s' row %d: %s", r.inputName, r.row, fmt.Sprintf(format, args...))
+}
+
+// fmtErr creates an error of the current row group, wrapped in an errs.ErrInput.
+func (r *reader) fmtErr(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format:  fileFormatParquet,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: r.rowGroup,
+		Reason:  reason,
+		Err:     fmt.Errorf("input '%s' row group %d: %s", r.inputName, r.rowGroup, reason),
+	}
+}
+
+// invalidParquet creates an ErrInvalidParquet, wrapped in an errs.ErrInput.
+func (r *reader) invalidParquet(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatParquet,
+		Input:  r.inputName,
+		Offset: -1,
+		Reason: reason,
+		Err:    ErrInvalidParquet(fmt.Sprintf("input '%s': %s", r.inputName, reason)),
+	}
+}
+
+// NewReader creates an FormatReader for Parquet file format.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		src:         src,
+		decl:        decl,
+		targetXPath: targetXPathExpr,
+	}, nil
+}
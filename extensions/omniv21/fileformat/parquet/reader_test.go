@@ -0,0 +1,573 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/input"
+)
+
+// summarize returns the columns of a row and their values, e.g. `id=1,name=a`.
+func summarize(n *idr.Node) string {
+	var s []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		s = append(s, c.Data+"="+c.InnerText())
+	}
+	return strings.Join(s, ",")
+}
+
+// readAll returns the summaries of the rows read, and the messages of the continuable errors, till
+// io.EOF or a fatal error.
+func readAll(r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		switch {
+		case err == io.EOF:
+			return records, nil
+		case err != nil && r.IsContinuableError(err):
+			records = append(records, "error: "+err.Error())
+			continue
+		case err != nil:
+			return records, err
+		}
+		records = append(records, summarize(n))
+		r.Release(n)
+	}
+}
+
+func idColumn(values ...interface{}) testColumn {
+	return testColumn{name: "id", typ: typeInt64, converted: -1, values: values}
+}
+
+func nameColumn(values ...interface{}) testColumn {
+	return testColumn{
+		name: "name", typ: typeByteArray, repetition: repetitionOptional, converted: convertedUTF8,
+		logical: tst{{logicalString, tst{}}}, values: values,
+	}
+}
+
+func testPeople() testFile {
+	return testFile{columns: []testColumn{
+		idColumn(int64(1), int64(2), int64(3), int64(4), int64(5)),
+		nameColumn("ann", nil, "bob", "ann", nil),
+	}}
+}
+
+var testPeopleRows = []string{"id=1,name=ann", "id=2", "id=3,name=bob", "id=4,name=ann", "id=5"}
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		file     func(f testFile) testFile
+		decl     *FileDecl
+		xpath    string
+		expected []string
+	}{
+		{
+			name:     "plain, uncompressed, v1 pages",
+			expected: testPeopleRows,
+		},
+		{
+			name:     "dictionary, snappy, v2 pages",
+			file:     func(f testFile) testFile { f.dict, f.codec, f.v2 = true, codecSnappy, true; return f },
+			expected: testPeopleRows,
+		},
+		{
+			name:     "dictionary, gzip, v1 pages",
+			file:     func(f testFile) testFile { f.dict, f.codec = true, codecGzip; return f },
+			expected: testPeopleRows,
+		},
+		{
+			name:     "plain, uncompressed, v2 pages",
+			file:     func(f testFile) testFile { f.v2 = true; return f },
+			expected: testPeopleRows,
+		},
+		{
+			name: "row groups and pages",
+			file: func(f testFile) testFile {
+				f.rowGroupRows, f.pageRows, f.dict, f.codec = 3, 2, true, codecSnappy
+				return f
+			},
+			expected: testPeopleRows,
+		},
+		{
+			name:     "columns",
+			decl:     &FileDecl{Columns: []string{"name"}},
+			expected: []string{"name=ann", "", "name=bob", "name=ann", ""},
+		},
+		{
+			name:     "xpath",
+			xpath:    ".[name='ann']",
+			expected: []string{"id=1,name=ann", "id=4,name=ann"},
+		},
+		{
+			name:     "no rows",
+			file:     func(testFile) testFile { return testFile{columns: []testColumn{idColumn()}} },
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := testPeople()
+			if test.file != nil {
+				f = test.file(f)
+			}
+			decl := test.decl
+			if decl == nil {
+				decl = &FileDecl{}
+			}
+			r, err := NewReader("test-input", bytes.NewReader(f.bytes()), decl, test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_Types(t *testing.T) {
+	column := func(name string, typ int32, converted int32, logical tst, v interface{}) testColumn {
+		return testColumn{name: name, typ: typ, converted: converted, logical: logical, values: []interface{}{v}}
+	}
+	timestamp := func(utc bool, unit int16) tst {
+		return tst{{logicalTimestamp, tst{{1, utc}, {2, tst{{unit, tst{}}}}}}}
+	}
+	f := testFile{columns: []testColumn{
+		column("bool", typeBoolean, -1, nil, true),
+		column("int32", typeInt32, -1, nil, int32(-7)),
+		column("int64", typeInt64, -1, nil, int64(-1)<<40),
+		column("uint32", typeInt32, convertedUint8+2, nil, int32(-1)),
+		column("uint64", typeInt64, -1, tst{{logicalInteger, tst{{1, int8(64)}, {2, false}}}}, int64(-1)),
+		column("int8", typeInt32, -1, tst{{logicalInteger, tst{{1, int8(8)}, {2, true}}}}, int32(-8)),
+		column("float", typeFloat, -1, nil, float32(1.5)),
+		column("double", typeDouble, -1, nil, 1234567.125),
+		column("string", typeByteArray, convertedUTF8, nil, "héllo"),
+		column("enum", typeByteArray, -1, tst{{logicalEnum, tst{}}}, "RED"),
+		column("json", typeByteArray, convertedJSON, nil, `{"a":1}`),
+		column("binary", typeByteArray, -1, nil, []byte{0xca, 0xfe}),
+		column("date", typeInt32, convertedDate, nil, int32(19000)),
+		column("date_logical", typeInt32, -1, tst{{logicalDate, tst{}}}, int32(-1)),
+		column("time_millis", typeInt32, convertedTimeMillis, nil, int32(45296789)),
+		column("time_micros_converted", typeInt64, convertedTimeMicros, nil, int64(1)),
+		column("time_micros", typeInt64, -1, tst{{logicalTime, tst{{1, true}, {2, tst{{2, tst{}}}}}}}, int64(45296789012)),
+		column("ts_millis", typeInt64, convertedTimestampMillis, nil, int64(1700000000123)),
+		column("ts_micros", typeInt64, convertedTimestampMicros, nil, int64(-1)),
+		column("ts_nanos", typeInt64, -1, timestamp(true, 3), int64(1700000000123456789)),
+		column("ts_local", typeInt64, -1, timestamp(false, 1), int64(1700000000000)),
+		column("int96", typeInt96, -1, nil, func() [12]byte {
+			var b [12]byte
+			binary.LittleEndian.PutUint64(b[:], uint64(3661)*1e9+5)
+			binary.LittleEndian.PutUint32(b[8:], julianUnixEpoch+19000)
+			return b
+		}()),
+		{name: "decimal32", typ: typeInt32, converted: convertedDecimal, scale: 2, values: []interface{}{int32(-5)}},
+		{
+			name: "decimal_fixed", typ: typeFixedLenByteArray, typeLength: 4, converted: -1,
+			logical: tst{{logicalDecimal, tst{{1, int32(3)}, {2, int32(9)}}}},
+			values:  []interface{}{[]byte{0xff, 0xfe, 0x1d, 0xc0}},
+		},
+		{
+			name: "uuid", typ: typeFixedLenByteArray, typeLength: 16, converted: -1,
+			logical: tst{{logicalUUID, tst{}}},
+			values: []interface{}{[]byte{
+				0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}},
+		},
+		{name: "fixed", typ: typeFixedLenByteArray, typeLength: 2, converted: -1, values: []interface{}{[]byte("ab")}},
+	}}
+	for _, test := range []struct {
+		name     string
+		decl     *FileDecl
+		expected map[string]string
+	}{
+		{
+			name: "default",
+			decl: &FileDecl{},
+			expected: map[string]string{
+				"bool":                  "true",
+				"int32":                 "-7",
+				"int64":                 "-1099511627776",
+				"uint32":                "4294967295",
+				"uint64":                "18446744073709551615",
+				"int8":                  "-8",
+				"float":                 "1.5",
+				"double":                "1234567.125",
+				"string":                "héllo",
+				"enum":                  "RED",
+				"json":                  `{"a":1}`,
+				"binary":                "cafe",
+				"date":                  "2022-01-08",
+				"date_logical":          "1969-12-31",
+				"time_millis":           "12:34:56.789",
+				"time_micros":           "12:34:56.789012",
+				"time_micros_converted": "00:00:00.000001",
+				"ts_millis":             "2023-11-14T22:13:20.123Z",
+				"ts_micros":             "1969-12-31T23:59:59.999999Z",
+				"ts_nanos":              "2023-11-14T22:13:20.123456789Z",
+				"ts_local":              "2023-11-14T22:13:20",
+				"int96":                 "2022-01-08T01:01:01.000000005Z",
+				"decimal32":             "-0.05",
+				"decimal_fixed":         "-123.456",
+				"uuid":                  "12345678-9abc-def0-1234-56789abcdef0",
+				"fixed":                 "6162",
+			},
+		},
+		{
+			name:     "binary as string",
+			decl:     &FileDecl{Columns: []string{"binary", "fixed", "uuid"}, BinaryAsString: true},
+			expected: map[string]string{"binary": "\xca\xfe", "fixed": "ab", "uuid": "12345678-9abc-def0-1234-56789abcdef0"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", bytes.NewReader(f.bytes()), test.decl, "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.NoError(t, err)
+			values := map[string]string{}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				values[c.Data] = c.InnerText()
+			}
+			assert.Equal(t, test.expected, values)
+			_, err = r.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestRead_RowGroupErrors(t *testing.T) {
+	f := testPeople()
+	f.rowGroupRows, f.codec = 2, codecSnappy
+	valid := f.bytes()
+	for _, test := range []struct {
+		name     string
+		corrupt  func(b []byte) []byte
+		expected []string
+	}{
+		{
+			name: "corrupted page",
+			corrupt: func(b []byte) []byte {
+				// the snappy literal tag of the values of the 2nd row group's id column chunk.
+				i := bytes.Index(b, []byte{16, 15 << 2, 3, 0, 0, 0, 0, 0, 0, 0})
+				b[i+1] = 0xff
+				return b
+			},
+			expected: []string{
+				"id=1,name=ann", "id=2",
+				"error: input 'test-input' row group 2: column 'id': unable to decompress page: snappy: corrupt input",
+				"id=5",
+			},
+		},
+		{
+			name: "unsupported codec",
+			corrupt: func(b []byte) []byte {
+				return replaceFooter(b, func(m tstruct) {
+					cm := m.list(4)[0].(tstruct).list(1)[1].(tstruct).strct(3)
+					cm[4] = int64(6)
+				})
+			},
+			expected: []string{
+				"error: input 'test-input' row group 1: column 'name': compression codec ZSTD isn't supported",
+				"id=3,name=bob", "id=4,name=ann", "id=5",
+			},
+		},
+		{
+			name: "missing column chunk",
+			corrupt: func(b []byte) []byte {
+				return replaceFooter(b, func(m tstruct) {
+					g := m.list(4)[2].(tstruct)
+					g[1] = g.list(1)[:1]
+				})
+			},
+			expected: []string{
+				"id=1,name=ann", "id=2", "id=3,name=bob", "id=4,name=ann",
+				"error: input 'test-input' row group 3: 1 column chunks, expected 2",
+			},
+		},
+		{
+			name: "wrong number of rows",
+			corrupt: func(b []byte) []byte {
+				return replaceFooter(b, func(m tstruct) { m.list(4)[0].(tstruct)[3] = int64(3) })
+			},
+			expected: []string{
+				"error: input 'test-input' row group 1: column 'id' has 2 values, expected 3",
+				"id=3,name=bob", "id=4,name=ann", "id=5",
+			},
+		},
+		{
+			name: "negative number of rows",
+			corrupt: func(b []byte) []byte {
+				return replaceFooter(b, func(m tstruct) { m.list(4)[0].(tstruct)[3] = int64(-1) })
+			},
+			expected: []string{
+				"error: input 'test-input' row group 1: invalid number of rows -1",
+				"id=3,name=bob", "id=4,name=ann", "id=5",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b := test.corrupt(append([]byte(nil), valid...))
+			r, err := NewReader("test-input", bytes.NewReader(b), &FileDecl{}, "")
+			assert.NoError(t, err)
+			records, err := readAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_CorruptedPageHeader(t *testing.T) {
+	f := testPeople()
+	f.dict = true
+	b := f.bytes()
+	// the dictionary page header of the name column, with 2 values.
+	dictHeader := encodeThrift(nil, tst{{1, int32(2)}, {2, int32(encodingPlainDictionary)}})
+	i := bytes.Index(b, dictHeader)
+	copy(b[i:], encodeThrift(nil, tst{{1, int32(-1)}, {2, int32(encodingPlainDictionary)}}))
+	r, err := NewReader("test-input", bytes.NewReader(b), &FileDecl{}, "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Error(t, err)
+	assert.True(t, r.IsContinuableError(err))
+	assert.Equal(t,
+		"input 'test-input' row group 1: column 'name': dictionary page: invalid number of values -1",
+		err.Error())
+	var inputErr *errs.ErrInput
+	assert.True(t, errors.As(err, &inputErr))
+	assert.Equal(t, fileFormatParquet, inputErr.Format)
+	assert.Equal(t, 1, inputErr.Segment)
+	assert.Equal(t, "column 'name': dictionary page: invalid number of values -1", inputErr.Reason)
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+// replaceFooter decodes the footer of a Parquet file, has it modified, and re-encodes it.
+func replaceFooter(b []byte, modify func(m tstruct)) []byte {
+	footerLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	footerStart := len(b) - 8 - footerLen
+	m, _, err := decodeStruct(b[footerStart : len(b)-8])
+	if err != nil {
+		panic(err)
+	}
+	modify(m)
+	footer := encodeThrift(nil, toTst(m))
+	out := append(append([]byte(nil), b[:footerStart]...), footer...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(footer)))
+	return append(out, magic...)
+}
+
+// toTst converts a decoded thrift struct back into one to encode.
+func toTst(s tstruct) tst {
+	var ids []int16
+	for id := range s {
+		ids = append(ids, id)
+	}
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			if ids[j] < ids[i] {
+				ids[i], ids[j] = ids[j], ids[i]
+			}
+		}
+	}
+	var t tst
+	for _, id := range ids {
+		t = append(t, tfield{id, toThrift(s[id])})
+	}
+	return t
+}
+
+func toThrift(v interface{}) interface{} {
+	switch v := v.(type) {
+	case tstruct:
+		return toTst(v)
+	case []interface{}:
+		l := tlist{typ: thriftStruct}
+		for _, e := range v {
+			e = toThrift(e)
+			l.typ = thriftType(e)
+			l.elems = append(l.elems, e)
+		}
+		return l
+	default:
+		return v
+	}
+}
+
+func TestRead_InvalidInput(t *testing.T) {
+	nested := testFile{columns: []testColumn{
+		idColumn(int64(1)),
+		{name: "address", repetition: repetitionOptional, converted: -1, children: []testColumn{
+			{name: "city", typ: typeByteArray, repetition: repetitionOptional, converted: convertedUTF8, values: []interface{}{"x"}},
+		}},
+		{name: "tags", typ: typeByteArray, repetition: repetitionRepeated, converted: convertedUTF8, values: []interface{}{"y"}},
+		nameColumn("ann"),
+	}}
+	for _, test := range []struct {
+		name        string
+		input       []byte
+		decl        *FileDecl
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:        "empty",
+			input:       nil,
+			expectedErr: "not a Parquet file",
+		},
+		{
+			name:        "not parquet",
+			input:       []byte("id,name\n1,ann\n"),
+			expectedErr: "not a Parquet file",
+		},
+		{
+			name:        "encrypted",
+			input:       []byte("PARE-encrypted-PARE"),
+			expectedErr: "encrypted Parquet files aren't supported",
+		},
+		{
+			name:        "truncated",
+			input:       testPeople().bytes()[:40],
+			expectedErr: "footer not found, the input may be truncated",
+		},
+		{
+			name: "invalid footer length",
+			input: func() []byte {
+				b := testPeople().bytes()
+				binary.LittleEndian.PutUint32(b[len(b)-8:], 1<<20)
+				return b
+			}(),
+			expectedErr: "invalid footer length 1048576",
+		},
+		{
+			name:        "corrupted footer",
+			input:       []byte("PAR1\x15\x01\x00\x00\x00PAR1"),
+			expectedErr: "invalid footer: thrift: unexpected end of data",
+		},
+		{
+			name: "no schema",
+			input: func() []byte {
+				return replaceFooter(testPeople().bytes(), func(m tstruct) { delete(m, 2) })
+			}(),
+			expectedErr: "invalid footer: schema is missing",
+		},
+		{
+			name: "truncated schema",
+			input: func() []byte {
+				return replaceFooter(testPeople().bytes(), func(m tstruct) { m[2] = m.list(2)[:2] })
+			}(),
+			expectedErr: "invalid schema: 2 elements, more expected",
+		},
+		{
+			name: "truncated group",
+			input: func() []byte {
+				return replaceFooter(nested.bytes(), func(m tstruct) { m[2] = m.list(2)[:3] })
+			}(),
+			expectedErr: "invalid schema: 3 elements, more expected",
+		},
+		{
+			name:        "nested column",
+			input:       nested.bytes(),
+			expectedErr: "column 'address' is nested or repeated, which isn't supported, 'columns' can be used to skip it",
+		},
+		{
+			name:        "nested column selected",
+			input:       nested.bytes(),
+			decl:        &FileDecl{Columns: []string{"id", "tags"}},
+			expectedErr: "column 'tags' is nested or repeated, which isn't supported",
+		},
+		{
+			name:        "unknown column",
+			input:       nested.bytes(),
+			decl:        &FileDecl{Columns: []string{"id", "city"}},
+			expectedErr: "column 'city' isn't in the input",
+		},
+		{
+			name:     "nested columns skipped",
+			input:    nested.bytes(),
+			decl:     &FileDecl{Columns: []string{"name", "id"}},
+			expected: []string{"id=1,name=ann"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decl := test.decl
+			if decl == nil {
+				decl = &FileDecl{}
+			}
+			r, err := NewReader("test-input", bytes.NewReader(test.input), decl, "")
+			assert.NoError(t, err)
+			records, err := readAll(r)
+			assert.Equal(t, test.expected, records)
+			if test.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidParquet(err))
+			assert.False(t, r.IsContinuableError(err))
+			assert.Equal(t, "input 'test-input': "+test.expectedErr, err.Error())
+			var inputErr *errs.ErrInput
+			assert.True(t, errors.As(err, &inputErr))
+			assert.Equal(t, fileFormatParquet, inputErr.Format)
+			assert.Equal(t, test.expectedErr, inputErr.Reason)
+			// the fatal error sticks.
+			_, err2 := r.Read()
+			assert.Equal(t, err, err2)
+		})
+	}
+}
+
+func TestRead_InMemory(t *testing.T) {
+	f := testPeople()
+	f.rowGroupRows = 2
+	r, err := NewReader("test-input", input.NewBytesReader(f.bytes()), &FileDecl{}, "")
+	assert.NoError(t, err)
+	var positions []int
+	for {
+		n, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		begin, end := r.RecordPosition()
+		assert.Equal(t, begin, end)
+		positions = append(positions, begin)
+		r.Release(n)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, positions)
+	assert.Equal(t, "input 'test-input' row 5: test", r.FmtErr("test").Error())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", failingReader{}, &FileDecl{}, "")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.Nil(t, n)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidParquet(err))
+	assert.Equal(t, "input 'test-input': unable to read input: read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", bytes.NewReader(nil), &FileDecl{}, "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidParquet("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
@@ -0,0 +1,78 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errSnappyCorrupt = errors.New("snappy: corrupt input")
+
+// snappyDecode decodes a snappy block, i.e. the raw snappy format, without the framing of the snappy
+// streaming format, as used by Parquet. maxLen is the max length of the decoded data, for corrupted
+// input not to exhaust memory.
+func snappyDecode(src []byte, maxLen int) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || n > uint64(maxLen) {
+		return nil, errSnappyCorrupt
+	}
+	dst := make([]byte, 0, n)
+	for s := k; s < len(src); {
+		tag := src[s]
+		s++
+		var length, offset int
+		switch tag & 0x03 {
+		case 0x00: // literal
+			length = int(tag >> 2)
+			if length >= 60 {
+				extra := length - 59
+				if len(src)-s < extra {
+					return nil, errSnappyCorrupt
+				}
+				length = 0
+				for i := extra - 1; i >= 0; i-- {
+					length = length<<8 | int(src[s+i])
+				}
+				s += extra
+			}
+			length++
+			if length <= 0 || len(src)-s < length || len(dst)+length > int(n) {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[s:s+length]...)
+			s += length
+			continue
+		case 0x01: // copy with a 1-byte offset
+			if s >= len(src) {
+				return nil, errSnappyCorrupt
+			}
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[s])
+			s++
+		case 0x02: // copy with a 2-byte offset
+			if len(src)-s < 2 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[s:]))
+			s += 2
+		case 0x03: // copy with a 4-byte offset
+			if len(src)-s < 4 {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[s:]))
+			s += 4
+		}
+		if offset <= 0 || offset > len(dst) || len(dst)+length > int(n) {
+			return nil, errSnappyCorrupt
+		}
+		// byte by byte, as the copy may overlap what it appends, e.g. a run of a repeated byte.
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if len(dst) != int(n) {
+		return nil, errSnappyCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,70 @@
+package parquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnappyDecode(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{name: "empty", input: []byte{0}, expected: ""},
+		{name: "literal", input: []byte{3, 2 << 2, 'a', 'b', 'c'}, expected: "abc"},
+		{
+			name:     "long literal",
+			input:    append([]byte{65, 60 << 2, 64}, []byte("0123456789012345678901234567890123456789012345678901234567890123X")...),
+			expected: "0123456789012345678901234567890123456789012345678901234567890123X",
+		},
+		{
+			name:     "copy with a 1-byte offset, overlapping",
+			input:    []byte{7, 1 << 2, 'a', 'b', 1 | 1<<2, 2},
+			expected: "abababa",
+		},
+		{
+			name:     "copy with a 2-byte offset",
+			input:    []byte{6, 2 << 2, 'a', 'b', 'c', 2 | 2<<2, 3, 0},
+			expected: "abcabc",
+		},
+		{
+			name:     "copy with a 4-byte offset",
+			input:    []byte{5, 1 << 2, 'x', 'y', 3 | 2<<2, 1, 0, 0, 0},
+			expected: "xyyyy",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := snappyDecode(test.input, 100)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
+
+func TestSnappyDecode_Corrupt(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input []byte
+	}{
+		{name: "no length", input: nil},
+		{name: "too long", input: []byte{101}},
+		{name: "literal length truncated", input: []byte{3, 61 << 2, 2}},
+		{name: "literal truncated", input: []byte{3, 2 << 2, 'a'}},
+		{name: "literal too long", input: []byte{1, 1 << 2, 'a', 'b'}},
+		{name: "1-byte offset truncated", input: []byte{5, 0, 'a', 1}},
+		{name: "2-byte offset truncated", input: []byte{5, 0, 'a', 2, 1}},
+		{name: "4-byte offset truncated", input: []byte{5, 0, 'a', 3, 1, 0, 0}},
+		{name: "zero offset", input: []byte{5, 0, 'a', 2 | 3<<2, 0, 0}},
+		{name: "offset out of range", input: []byte{5, 0, 'a', 2 | 3<<2, 2, 0}},
+		{name: "copy too long", input: []byte{3, 0, 'a', 2 | 3<<2, 1, 0}},
+		{name: "too short", input: []byte{3, 0, 'a'}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := snappyDecode(test.input, 100)
+			assert.Equal(t, errSnappyCorrupt, err)
+			assert.Nil(t, b)
+		})
+	}
+}
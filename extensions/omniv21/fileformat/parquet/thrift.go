@@ -0,0 +1,259 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Thrift compact protocol types, as found in field headers and list headers.
+const (
+	thriftStop   = 0
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// maxThriftDepth is the max nesting of structs and lists, for corrupted metadata not to exhaust the
+// stack.
+const maxThriftDepth = 32
+
+var errThriftTruncated = errors.New("thrift: unexpected end of data")
+
+// tstruct is a thrift struct decoded without its IDL, keyed by field id. Integers and enums are
+// int64, booleans bool, doubles float64, strings and binaries []byte, lists and sets []interface{},
+// structs and unions tstruct. Maps, which the Parquet metadata read here has none of, are skipped.
+type tstruct map[int16]interface{}
+
+func (s tstruct) int(id int16) (int64, bool) {
+	v, ok := s[id].(int64)
+	return v, ok
+}
+
+func (s tstruct) intOr(id int16, dflt int64) int64 {
+	if v, ok := s.int(id); ok {
+		return v
+	}
+	return dflt
+}
+
+func (s tstruct) bool(id int16) (bool, bool) {
+	v, ok := s[id].(bool)
+	return v, ok
+}
+
+func (s tstruct) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s tstruct) strct(id int16) tstruct {
+	v, _ := s[id].(tstruct)
+	return v
+}
+
+func (s tstruct) list(id int16) []interface{} {
+	v, _ := s[id].([]interface{})
+	return v
+}
+
+// thriftDecoder decodes thrift compact protocol data.
+type thriftDecoder struct {
+	b     []byte
+	pos   int
+	depth int
+}
+
+// decodeStruct decodes the thrift struct at the beginning of b, and returns it along with its length
+// in bytes.
+func decodeStruct(b []byte) (tstruct, int, error) {
+	d := &thriftDecoder{b: b}
+	s, err := d.readStruct()
+	if err != nil {
+		return nil, 0, err
+	}
+	return s, d.pos, nil
+}
+
+func (d *thriftDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.b) {
+		return 0, errThriftTruncated
+	}
+	c := d.b[d.pos]
+	d.pos++
+	return c, nil
+}
+
+func (d *thriftDecoder) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.b[d.pos:])
+	if n <= 0 {
+		if n == 0 {
+			return 0, errThriftTruncated
+		}
+		return 0, errors.New("thrift: varint overflow")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *thriftDecoder) readZigzag() (int64, error) {
+	v, err := d.readUvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (d *thriftDecoder) readStruct() (tstruct, error) {
+	if d.depth++; d.depth > maxThriftDepth {
+		return nil, errors.New("thrift: nesting too deep")
+	}
+	defer func() { d.depth-- }()
+	s := tstruct{}
+	var id int16
+	for {
+		h, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		typ := h & 0x0f
+		if typ == thriftStop {
+			return s, nil
+		}
+		if delta := h >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			v, err := d.readZigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		v, err := d.readValue(typ)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			s[id] = v
+		}
+	}
+}
+
+// readValue reads a value of the given type. Booleans, whose value is in the type of the field header,
+// are only read that way as struct fields: in lists, they're a byte each, read as thriftByte.
+func (d *thriftDecoder) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	case thriftByte:
+		c, err := d.readByte()
+		return int64(int8(c)), err
+	case thriftI16, thriftI32, thriftI64:
+		return d.readZigzag()
+	case thriftDouble:
+		if len(d.b)-d.pos < 8 {
+			return nil, errThriftTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.b[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := d.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.b)-d.pos) {
+			return nil, errThriftTruncated
+		}
+		v := d.b[d.pos : d.pos+int(n)]
+		d.pos += int(n)
+		return v, nil
+	case thriftList, thriftSet:
+		return d.readList()
+	case thriftMap:
+		return nil, d.skipMap()
+	case thriftStruct:
+		return d.readStruct()
+	default:
+		return nil, fmt.Errorf("thrift: unknown type %d", typ)
+	}
+}
+
+func (d *thriftDecoder) readListHeader() (int, byte, error) {
+	h, err := d.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := uint64(h >> 4)
+	if size == 15 {
+		if size, err = d.readUvarint(); err != nil {
+			return 0, 0, err
+		}
+	}
+	// each element takes at least a byte, which bounds the size of corrupted lists.
+	if size > uint64(len(d.b)-d.pos) {
+		return 0, 0, errThriftTruncated
+	}
+	return int(size), h & 0x0f, nil
+}
+
+func (d *thriftDecoder) readList() ([]interface{}, error) {
+	if d.depth++; d.depth > maxThriftDepth {
+		return nil, errors.New("thrift: nesting too deep")
+	}
+	defer func() { d.depth-- }()
+	size, typ, err := d.readListHeader()
+	if err != nil {
+		return nil, err
+	}
+	if typ == thriftTrue || typ == thriftFalse {
+		typ = thriftByte
+	}
+	list := make([]interface{}, 0, size)
+	for i := 0; i < size; i++ {
+		v, err := d.readValue(typ)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (d *thriftDecoder) skipMap() error {
+	size, err := d.readUvarint()
+	if err != nil || size == 0 {
+		return err
+	}
+	if size > uint64(len(d.b)-d.pos) {
+		return errThriftTruncated
+	}
+	types, err := d.readByte()
+	if err != nil {
+		return err
+	}
+	keyType, valueType := types>>4, types&0x0f
+	for _, typ := range []*byte{&keyType, &valueType} {
+		if *typ == thriftTrue || *typ == thriftFalse {
+			*typ = thriftByte
+		}
+	}
+	for i := uint64(0); i < size; i++ {
+		if _, err := d.readValue(keyType); err != nil {
+			return err
+		}
+		if _, err := d.readValue(valueType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package parquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeStruct(t *testing.T) {
+	b := encodeThrift(nil, tst{
+		{1, int8(-3)},
+		{2, int16(-300)},
+		{3, int32(70000)},
+		{4, int64(-1) << 40},
+		{5, true},
+		{6, false},
+		{7, 1.25},
+		{8, "text"},
+		{9, tlist{thriftI32, []interface{}{int32(1), int32(-2)}}},
+		{10, tlist{thriftTrue, []interface{}{true, false}}},
+		// a long id delta, and a long list.
+		{100, tlist{thriftByte, []interface{}{
+			int8(0), int8(1), int8(2), int8(3), int8(4), int8(5), int8(6), int8(7),
+			int8(8), int8(9), int8(10), int8(11), int8(12), int8(13), int8(14), int8(15)}}},
+		{101, tmap{"k": "v"}},
+		{102, tmap{}},
+		{103, tst{{1, tst{{1, "nested"}}}}},
+	})
+	b = append(b, "trailing"...)
+	s, n, err := decodeStruct(b)
+	assert.NoError(t, err)
+	assert.Equal(t, len(b)-len("trailing"), n)
+	assert.Equal(t, tstruct{
+		1:  int64(-3),
+		2:  int64(-300),
+		3:  int64(70000),
+		4:  int64(-1) << 40,
+		5:  true,
+		6:  false,
+		7:  1.25,
+		8:  []byte("text"),
+		9:  []interface{}{int64(1), int64(-2)},
+		10: []interface{}{int64(1), int64(2)},
+		100: []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4), int64(5), int64(6), int64(7),
+			int64(8), int64(9), int64(10), int64(11), int64(12), int64(13), int64(14), int64(15)},
+		103: tstruct{1: tstruct{1: []byte("nested")}},
+	}, s)
+	assert.Equal(t, int64(70000), s.intOr(3, 0))
+	assert.Equal(t, int64(42), s.intOr(8, 42))
+	v, found := s.bool(6)
+	assert.False(t, v)
+	assert.True(t, found)
+	assert.Equal(t, "text", s.str(8))
+	assert.Equal(t, "", s.str(1))
+	assert.Equal(t, "nested", s.strct(103).strct(1).str(1))
+	assert.Nil(t, s.strct(1))
+	assert.Len(t, s.list(9), 2)
+	assert.Nil(t, s.list(1))
+}
+
+func TestDecodeStruct_MapOfBools(t *testing.T) {
+	// a map of 1 bool key to a bool value, whose values are a byte each.
+	s, n, err := decodeStruct([]byte{0x1b, 0x01, 0x12, 0x01, 0x02, 0x00})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, n)
+	assert.Equal(t, tstruct{}, s)
+}
+
+func TestDecodeStruct_Errors(t *testing.T) {
+	nested := tst{{1, "x"}}
+	for i := 0; i < maxThriftDepth; i++ {
+		nested = tst{{1, nested}}
+	}
+	nestedList := tlist{thriftI32, []interface{}{int32(1)}}
+	for i := 0; i < maxThriftDepth; i++ {
+		nestedList = tlist{thriftList, []interface{}{nestedList}}
+	}
+	for _, test := range []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{name: "empty", input: nil, expectedErr: "thrift: unexpected end of data"},
+		{name: "no stop", input: []byte{0x15, 0x02}, expectedErr: "thrift: unexpected end of data"},
+		{name: "long id truncated", input: []byte{0x05}, expectedErr: "thrift: unexpected end of data"},
+		{name: "varint overflow", input: []byte{0x15, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+			expectedErr: "thrift: varint overflow"},
+		{name: "byte truncated", input: []byte{0x13}, expectedErr: "thrift: unexpected end of data"},
+		{name: "double truncated", input: []byte{0x17, 0, 0}, expectedErr: "thrift: unexpected end of data"},
+		{name: "binary length truncated", input: []byte{0x18}, expectedErr: "thrift: unexpected end of data"},
+		{name: "binary truncated", input: []byte{0x18, 0x05, 'a'}, expectedErr: "thrift: unexpected end of data"},
+		{name: "unknown type", input: []byte{0x1d}, expectedErr: "thrift: unknown type 13"},
+		{name: "list header truncated", input: []byte{0x19}, expectedErr: "thrift: unexpected end of data"},
+		{name: "list size truncated", input: []byte{0x19, 0xf5}, expectedErr: "thrift: unexpected end of data"},
+		{name: "list too long", input: []byte{0x19, 0xf5, 0x7f, 0x02}, expectedErr: "thrift: unexpected end of data"},
+		{name: "list element truncated", input: []byte{0x19, 0x28, 0x01, 'a', 0x05}, expectedErr: "thrift: unexpected end of data"},
+		{name: "map size truncated", input: []byte{0x1b}, expectedErr: "thrift: unexpected end of data"},
+		{name: "map too long", input: []byte{0x1b, 0x7f, 0x88}, expectedErr: "thrift: unexpected end of data"},
+		{name: "map truncated", input: []byte{0x1b, 0x01}, expectedErr: "thrift: unexpected end of data"},
+		{name: "map key truncated", input: []byte{0x1b, 0x01, 0x88, 0x05}, expectedErr: "thrift: unexpected end of data"},
+		{name: "map value truncated", input: []byte{0x1b, 0x01, 0x81, 0x01, 'k', 0x05}, expectedErr: "thrift: unexpected end of data"},
+		{name: "struct too deep", input: encodeThrift(nil, nested), expectedErr: "thrift: nesting too deep"},
+		{name: "list too deep", input: encodeThrift(nil, tst{{1, nestedList}}), expectedErr: "thrift: nesting too deep"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, n, err := decodeStruct(test.input)
+			assert.Error(t, err)
+			assert.Equal(t, test.expectedErr, err.Error())
+			assert.Nil(t, s)
+			assert.Equal(t, 0, n)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Converted types, the legacy way of annotating physical types, still written along with the logical
+// types by most writers.
+const (
+	convertedUTF8            = 0
+	convertedEnum            = 4
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimeMillis      = 7
+	convertedTimeMicros      = 8
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+	convertedUint8           = 11
+	convertedUint64          = 14
+	convertedJSON            = 19
+)
+
+// Logical types, i.e. the ids of the LogicalType union members.
+const (
+	logicalString    = 1
+	logicalEnum      = 4
+	logicalDecimal   = 5
+	logicalDate      = 6
+	logicalTime      = 7
+	logicalTimestamp = 8
+	logicalInteger   = 10
+	logicalJSON      = 12
+	logicalUUID      = 14
+)
+
+// Kinds of values, i.e. how values are converted into text.
+const (
+	kindPlain = iota // numbers as is, binaries in hex.
+	kindString
+	kindDecimal
+	kindDate
+	kindTime
+	kindTimestamp
+	kindUnsigned
+	kindUUID
+)
+
+// julianUnixEpoch is the Julian day of the Unix epoch, for INT96 timestamps.
+const julianUnixEpoch = 2440588
+
+// column is a column of a flat Parquet schema.
+type column struct {
+	name       string
+	index      int // 0-based index of the column in the schema, and of its chunk in the row groups.
+	typ        int64
+	typeLength int64
+	optional   bool
+	kind       int
+	scale      int   // of kindDecimal.
+	unit       int64 // of kindTime and kindTimestamp: nanoseconds per unit.
+	utc        bool  // of kindTimestamp: whether it's adjusted to UTC, i.e. an instant.
+}
+
+func newColumn(e schemaElement, index int, binaryAsString bool) *column {
+	c := &column{
+		name:       e.name,
+		index:      index,
+		typ:        e.typ,
+		typeLength: e.typeLength,
+		optional:   e.repetition == repetitionOptional,
+		scale:      int(e.scale),
+		unit:       int64(time.Millisecond),
+		utc:        true,
+	}
+	switch {
+	case e.logical != nil:
+		c.kindOfLogical(e.logical)
+	case e.convertedType == convertedUTF8 || e.convertedType == convertedEnum || e.convertedType == convertedJSON:
+		c.kind = kindString
+	case e.convertedType == convertedDecimal:
+		c.kind = kindDecimal
+	case e.convertedType == convertedDate:
+		c.kind = kindDate
+	case e.convertedType == convertedTimeMillis || e.convertedType == convertedTimeMicros:
+		c.kind = kindTime
+		if e.convertedType == convertedTimeMicros {
+			c.unit = int64(time.Microsecond)
+		}
+	case e.convertedType == convertedTimestampMillis || e.convertedType == convertedTimestampMicros:
+		c.kind = kindTimestamp
+		if e.convertedType == convertedTimestampMicros {
+			c.unit = int64(time.Microsecond)
+		}
+	case e.convertedType >= convertedUint8 && e.convertedType <= convertedUint64:
+		c.kind = kindUnsigned
+	}
+	if c.kind == kindPlain && binaryAsString && (c.typ == typeByteArray || c.typ == typeFixedLenByteArray) {
+		c.kind = kindString
+	}
+	return c
+}
+
+func (c *column) kindOfLogical(l tstruct) {
+	timeUnit := func(t tstruct) {
+		switch unit := t.strct(2); {
+		case unit.strct(2) != nil:
+			c.unit = int64(time.Microsecond)
+		case unit.strct(3) != nil:
+			c.unit = int64(time.Nanosecond)
+		}
+	}
+	switch {
+	case l.strct(logicalString) != nil, l.strct(logicalEnum) != nil, l.strct(logicalJSON) != nil:
+		c.kind = kindString
+	case l.strct(logicalDecimal) != nil:
+		c.kind = kindDecimal
+		c.scale = int(l.strct(logicalDecimal).intOr(1, 0))
+	case l.strct(logicalDate) != nil:
+		c.kind = kindDate
+	case l.strct(logicalTime) != nil:
+		c.kind = kindTime
+		timeUnit(l.strct(logicalTime))
+	case l.strct(logicalTimestamp) != nil:
+		c.kind = kindTimestamp
+		timeUnit(l.strct(logicalTimestamp))
+		c.utc, _ = l.strct(logicalTimestamp).bool(1)
+	case l.strct(logicalInteger) != nil:
+		if signed, _ := l.strct(logicalInteger).bool(2); !signed {
+			c.kind = kindUnsigned
+		}
+	case l.strct(logicalUUID) != nil:
+		c.kind = kindUUID
+	}
+}
+
+func formatBool(v bool) string {
+	return strconv.FormatBool(v)
+}
+
+func formatFloat(v float64, bitSize int) string {
+	return strconv.FormatFloat(v, 'f', -1, bitSize)
+}
+
+// formatInt formats the value of an INT32 or INT64 column.
+func (c *column) formatInt(v int64) string {
+	switch c.kind {
+	case kindDecimal:
+		return formatDecimal(big.NewInt(v), c.scale)
+	case kindDate:
+		return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+	case kindTime:
+		return time.Unix(0, v*c.unit).UTC().Format("15:04:05.999999999")
+	case kindTimestamp:
+		perSecond := int64(time.Second) / c.unit
+		t := time.Unix(v/perSecond, v%perSecond*c.unit).UTC()
+		if c.utc {
+			return t.Format(time.RFC3339Nano)
+		}
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case kindUnsigned:
+		if c.typ == typeInt32 {
+			return strconv.FormatUint(uint64(uint32(v)), 10)
+		}
+		return strconv.FormatUint(uint64(v), 10)
+	default:
+		return strconv.FormatInt(v, 10)
+	}
+}
+
+// formatBytes formats the value of a BYTE_ARRAY or FIXED_LEN_BYTE_ARRAY column.
+func (c *column) formatBytes(b []byte) string {
+	switch c.kind {
+	case kindString:
+		return string(b)
+	case kindDecimal:
+		// two's complement, big endian.
+		n := new(big.Int).SetBytes(b)
+		if len(b) > 0 && b[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+		}
+		return formatDecimal(n, c.scale)
+	case kindUUID:
+		if len(b) == 16 {
+			s := hex.EncodeToString(b)
+			return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+// formatInt96 formats the value of an INT96 column, the legacy timestamp type made of the nanoseconds
+// of the day and the Julian day, both little endian.
+func formatInt96(b []byte) string {
+	nanos := int64(binary.LittleEndian.Uint64(b))
+	day := int64(binary.LittleEndian.Uint32(b[8:]))
+	return time.Unix((day-julianUnixEpoch)*86400, nanos).UTC().Format(time.RFC3339Nano)
+}
+
+// formatDecimal formats an unscaled decimal value, e.g. 12345 with scale 2 is `123.45`.
+func formatDecimal(unscaled *big.Int, scale int) string {
+	if scale <= 0 {
+		return unscaled.String()
+	}
+	digits := new(big.Int).Abs(unscaled).String()
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	s := digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	if unscaled.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
@@ -0,0 +1,458 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// This file has a minimal Parquet writer, for the tests to build their inputs.
+
+// tfield is a field of a thrift struct to encode.
+type tfield struct {
+	id int16
+	v  interface{}
+}
+
+// tst is a thrift struct to encode. Field values are int8, int16, int32, int64, bool, float64, string,
+// []byte, tst, tlist or tmap.
+type tst []tfield
+
+// tlist is a thrift list to encode.
+type tlist struct {
+	typ   byte
+	elems []interface{}
+}
+
+// tmap is a thrift map of strings to encode.
+type tmap map[string]string
+
+func thriftType(v interface{}) byte {
+	switch v := v.(type) {
+	case int8:
+		return thriftByte
+	case int16:
+		return thriftI16
+	case int32:
+		return thriftI32
+	case int64:
+		return thriftI64
+	case bool:
+		if v {
+			return thriftTrue
+		}
+		return thriftFalse
+	case float64:
+		return thriftDouble
+	case string, []byte:
+		return thriftBinary
+	case tst:
+		return thriftStruct
+	case tlist:
+		return thriftList
+	case tmap:
+		return thriftMap
+	}
+	panic(fmt.Sprintf("unsupported thrift value %T", v))
+}
+
+func appendZigzag(b []byte, v int64) []byte {
+	return binary.AppendUvarint(b, uint64(v<<1)^uint64(v>>63))
+}
+
+// encodeThrift encodes v with the thrift compact protocol.
+func encodeThrift(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case int8:
+		return append(b, byte(v))
+	case int16:
+		return appendZigzag(b, int64(v))
+	case int32:
+		return appendZigzag(b, int64(v))
+	case int64:
+		return appendZigzag(b, v)
+	case bool:
+		// only as a list element: struct fields have their value in their type.
+		if v {
+			return append(b, 1)
+		}
+		return append(b, 2)
+	case float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	case string:
+		return append(binary.AppendUvarint(b, uint64(len(v))), v...)
+	case []byte:
+		return append(binary.AppendUvarint(b, uint64(len(v))), v...)
+	case tst:
+		var last int16
+		for _, f := range v {
+			typ := thriftType(f.v)
+			if delta := f.id - last; delta > 0 && delta <= 15 {
+				b = append(b, byte(delta)<<4|typ)
+			} else {
+				b = appendZigzag(append(b, typ), int64(f.id))
+			}
+			last = f.id
+			if typ != thriftTrue && typ != thriftFalse {
+				b = encodeThrift(b, f.v)
+			}
+		}
+		return append(b, thriftStop)
+	case tlist:
+		if len(v.elems) < 15 {
+			b = append(b, byte(len(v.elems))<<4|v.typ)
+		} else {
+			b = binary.AppendUvarint(append(b, 0xf0|v.typ), uint64(len(v.elems)))
+		}
+		for _, e := range v.elems {
+			b = encodeThrift(b, e)
+		}
+		return b
+	case tmap:
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		if len(v) > 0 {
+			b = append(b, thriftBinary<<4|thriftBinary)
+		}
+		for k, e := range v {
+			b = encodeThrift(encodeThrift(b, k), e)
+		}
+		return b
+	}
+	panic(fmt.Sprintf("unsupported thrift value %T", v))
+}
+
+// testColumn is a column of a Parquet file to write.
+type testColumn struct {
+	name       string
+	typ        int32
+	typeLength int32
+	repetition int32
+	converted  int32 // -1 if none.
+	scale      int32
+	logical    tst
+	// values are the values of the column: nil for nulls, or bool, int32, int64, [12]byte, float32,
+	// float64, string or []byte, per typ.
+	values   []interface{}
+	children []testColumn // for a group.
+}
+
+// testFile is a Parquet file to write.
+type testFile struct {
+	columns      []testColumn
+	rowGroupRows int // rows per row group, 0 for a single row group.
+	pageRows     int // rows per data page, 0 for a single data page per column chunk.
+	codec        int64
+	v2           bool // data pages v2 rather than v1.
+	dict         bool // dictionary encoding, rather than plain encoding.
+}
+
+func (f testFile) leaves() []testColumn {
+	var leaves []testColumn
+	var walk func(cs []testColumn)
+	walk = func(cs []testColumn) {
+		for _, c := range cs {
+			if len(c.children) > 0 {
+				walk(c.children)
+			} else {
+				leaves = append(leaves, c)
+			}
+		}
+	}
+	walk(f.columns)
+	return leaves
+}
+
+func (f testFile) rows() int {
+	if leaves := f.leaves(); len(leaves) > 0 {
+		return len(leaves[0].values)
+	}
+	return 0
+}
+
+func schemaElements(cs []testColumn) []interface{} {
+	var elems []interface{}
+	for _, c := range cs {
+		var e tst
+		if len(c.children) == 0 {
+			e = append(e, tfield{1, c.typ})
+			if c.typ == typeFixedLenByteArray {
+				e = append(e, tfield{2, c.typeLength})
+			}
+		}
+		e = append(e, tfield{3, c.repetition}, tfield{4, c.name})
+		if len(c.children) > 0 {
+			e = append(e, tfield{5, int32(len(c.children))})
+		}
+		if c.converted >= 0 {
+			e = append(e, tfield{6, c.converted})
+		}
+		if c.scale > 0 {
+			e = append(e, tfield{7, c.scale}, tfield{8, int32(18)})
+		}
+		if c.logical != nil {
+			e = append(e, tfield{10, c.logical})
+		}
+		elems = append(elems, e)
+		elems = append(elems, schemaElements(c.children)...)
+	}
+	return elems
+}
+
+func (f testFile) bytes() []byte {
+	out := []byte(magic)
+	rows := f.rows()
+	groupRows := f.rowGroupRows
+	if groupRows <= 0 {
+		groupRows = rows
+	}
+	var rowGroups []interface{}
+	for start := 0; start < rows; start += groupRows {
+		end := start + groupRows
+		if end > rows {
+			end = rows
+		}
+		var chunks []interface{}
+		for _, c := range f.leaves() {
+			var chunk tst
+			out, chunk = f.writeColumnChunk(out, c, c.values[start:end])
+			chunks = append(chunks, chunk)
+		}
+		rowGroups = append(rowGroups, tst{
+			{1, tlist{thriftStruct, chunks}},
+			{2, int64(0)},
+			{3, int64(end - start)},
+		})
+	}
+	meta := tst{
+		{1, int32(1)},
+		{2, tlist{thriftStruct, append([]interface{}{
+			tst{{4, "schema"}, {5, int32(len(f.columns))}},
+		}, schemaElements(f.columns)...)}},
+		{3, int64(rows)},
+		{4, tlist{thriftStruct, rowGroups}},
+		{5, tlist{thriftStruct, []interface{}{tst{{1, "writer"}, {2, "test"}}}}},
+		{6, "omniparser test writer"},
+	}
+	footer := encodeThrift(nil, meta)
+	out = append(out, footer...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(footer)))
+	return append(out, magic...)
+}
+
+func (f testFile) writeColumnChunk(out []byte, c testColumn, values []interface{}) ([]byte, tst) {
+	chunkStart := len(out)
+	var dictOffset int64
+	encoding := int32(encodingPlain)
+	var dict []interface{}
+	indices := map[string]int{}
+	if f.dict {
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if _, found := indices[fmt.Sprint(v)]; !found {
+				indices[fmt.Sprint(v)] = len(dict)
+				dict = append(dict, v)
+			}
+		}
+		dictOffset = int64(len(out))
+		body := plainValues(c, dict)
+		compressed := compress(f.codec, body)
+		out = append(out, encodeThrift(nil, tst{
+			{1, int32(pageDictionary)},
+			{2, int32(len(body))},
+			{3, int32(len(compressed))},
+			{7, tst{{1, int32(len(dict))}, {2, int32(encodingPlainDictionary)}}},
+		})...)
+		out = append(out, compressed...)
+		encoding = encodingRLEDictionary
+	}
+	dataOffset := int64(len(out))
+	pageRows := f.pageRows
+	if pageRows <= 0 || pageRows > len(values) {
+		pageRows = len(values)
+	}
+	for start := 0; start < len(values) || start == 0; start += pageRows {
+		end := start + pageRows
+		if end > len(values) {
+			end = len(values)
+		}
+		page := values[start:end]
+		var levels []byte
+		var defined []interface{}
+		nulls := 0
+		if c.repetition == repetitionOptional {
+			flags := make([]int, len(page))
+			for i, v := range page {
+				if v != nil {
+					flags[i] = 1
+				}
+			}
+			levels = bitPacked(flags, 1)
+		}
+		for _, v := range page {
+			if v != nil {
+				defined = append(defined, v)
+			} else {
+				nulls++
+			}
+		}
+		var body []byte
+		if f.dict {
+			idx := make([]int, len(defined))
+			for i, v := range defined {
+				idx[i] = indices[fmt.Sprint(v)]
+			}
+			width := 0
+			if len(dict) > 1 {
+				width = bits.Len(uint(len(dict) - 1))
+			}
+			body = append([]byte{byte(width)}, bitPacked(idx, width)...)
+		} else {
+			body = plainValues(c, defined)
+		}
+		if f.v2 {
+			compressed := compress(f.codec, body)
+			out = append(out, encodeThrift(nil, tst{
+				{1, int32(pageDataV2)},
+				{2, int32(len(levels) + len(body))},
+				{3, int32(len(levels) + len(compressed))},
+				{8, tst{
+					{1, int32(len(page))},
+					{2, int32(nulls)},
+					{3, int32(len(page))},
+					{4, encoding},
+					{5, int32(len(levels))},
+					{6, int32(0)},
+					{7, f.codec != codecUncompressed},
+				}},
+			})...)
+			out = append(append(out, levels...), compressed...)
+		} else {
+			if levels != nil {
+				levels = append(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))), levels...)
+			}
+			body = append(levels, body...)
+			compressed := compress(f.codec, body)
+			out = append(out, encodeThrift(nil, tst{
+				{1, int32(pageData)},
+				{2, int32(len(body))},
+				{3, int32(len(compressed))},
+				{5, tst{
+					{1, int32(len(page))},
+					{2, encoding},
+					{3, int32(encodingRLE)},
+					{4, int32(encodingRLE)},
+				}},
+			})...)
+			out = append(out, compressed...)
+		}
+		if len(values) == 0 {
+			break
+		}
+	}
+	meta := tst{
+		{1, c.typ},
+		{2, tlist{thriftI32, []interface{}{int32(encoding), int32(encodingRLE)}}},
+		{3, tlist{thriftBinary, []interface{}{c.name}}},
+		{4, int32(f.codec)},
+		{5, int64(len(values))},
+		{6, int64(len(out) - chunkStart)},
+		{7, int64(len(out) - chunkStart)},
+		{9, dataOffset},
+	}
+	if f.dict {
+		meta = append(meta, tfield{11, dictOffset})
+	}
+	return out, tst{{2, int64(chunkStart)}, {3, meta}}
+}
+
+// bitPacked encodes values with the RLE/bit-packing hybrid encoding, as a single bit-packed run.
+func bitPacked(values []int, width int) []byte {
+	groups := (len(values) + 7) / 8
+	b := binary.AppendUvarint(nil, uint64(groups<<1|1))
+	packed := make([]byte, groups*width)
+	for i, v := range values {
+		for j := 0; j < width; j++ {
+			if v&(1<<j) != 0 {
+				bit := i*width + j
+				packed[bit/8] |= 1 << (bit % 8)
+			}
+		}
+	}
+	return append(b, packed...)
+}
+
+func plainValues(c testColumn, values []interface{}) []byte {
+	var b []byte
+	if c.typ == typeBoolean {
+		b = make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v.(bool) {
+				b[i/8] |= 1 << (i % 8)
+			}
+		}
+		return b
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case int32:
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		case int64:
+			b = binary.LittleEndian.AppendUint64(b, uint64(v))
+		case [12]byte:
+			b = append(b, v[:]...)
+		case float32:
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+		case float64:
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+		case string:
+			b = append(binary.LittleEndian.AppendUint32(b, uint32(len(v))), v...)
+		case []byte:
+			if c.typ == typeByteArray {
+				b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+			}
+			b = append(b, v...)
+		default:
+			panic(fmt.Sprintf("unsupported value %T", v))
+		}
+	}
+	return b
+}
+
+func compress(codec int64, b []byte) []byte {
+	switch codec {
+	case codecSnappy:
+		return snappyLiterals(b)
+	case codecGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write(b)
+		_ = w.Close()
+		return buf.Bytes()
+	default:
+		return b
+	}
+}
+
+// snappyLiterals encodes b as a snappy block made of literals only, which is valid, if not compressed.
+func snappyLiterals(b []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(len(b)))
+	for len(b) > 0 {
+		n := len(b)
+		if n > 256 {
+			n = 256
+		}
+		if n <= 60 {
+			out = append(out, byte(n-1)<<2)
+		} else {
+			out = append(out, 60<<2, byte(n-1))
+		}
+		out = append(out, b[:n]...)
+		b = b[n:]
+	}
+	return out
+}
@@ -0,0 +1,419 @@
+package pdf
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// textFragment is a piece of text shown by a single text showing operator (or a single string
+// inside a 'TJ' array), along with its estimated position and width in text space units.
+type textFragment struct {
+	x, y, width, fontSize float64
+	text                  string
+}
+
+// line is a line of text on a page, with its text split into cells by horizontal gaps.
+type line struct {
+	text  string
+	cells []string
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenName
+	tokenArrayBegin
+	tokenArrayEnd
+	tokenDictBegin
+	tokenDictEnd
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	num  float64
+	str  []byte // string content for tokenString, name for tokenName, keyword for tokenOperator.
+}
+
+// lexer tokenizes a PDF content stream.
+type lexer struct {
+	b   []byte
+	pos int
+}
+
+func isPDFWhiteSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.b) {
+		c := l.b[l.pos]
+		if isPDFWhiteSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.b) && l.b[l.pos] != '\n' && l.b[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.b) {
+		return token{kind: tokenEOF}
+	}
+	c := l.b[l.pos]
+	switch {
+	case c == '(':
+		return token{kind: tokenString, str: l.literalString()}
+	case c == '<' && l.peekAt(1) == '<':
+		l.pos += 2
+		return token{kind: tokenDictBegin}
+	case c == '>' && l.peekAt(1) == '>':
+		l.pos += 2
+		return token{kind: tokenDictEnd}
+	case c == '<':
+		return token{kind: tokenString, str: l.hexString()}
+	case c == '[':
+		l.pos++
+		return token{kind: tokenArrayBegin}
+	case c == ']':
+		l.pos++
+		return token{kind: tokenArrayEnd}
+	case c == '/':
+		l.pos++
+		return token{kind: tokenName, str: l.regular()}
+	case isPDFDelimiter(c):
+		// stray '{', '}', '>' or ')', which aren't valid in a content stream. Skip.
+		l.pos++
+		return l.next()
+	}
+	word := l.regular()
+	if (word[0] >= '0' && word[0] <= '9') || word[0] == '-' || word[0] == '+' || word[0] == '.' {
+		if f, err := strconv.ParseFloat(string(word), 64); err == nil {
+			return token{kind: tokenNumber, num: f}
+		}
+	}
+	return token{kind: tokenOperator, str: word}
+}
+
+func (l *lexer) peekAt(offset int) byte {
+	if l.pos+offset < len(l.b) {
+		return l.b[l.pos+offset]
+	}
+	return 0
+}
+
+func (l *lexer) regular() []byte {
+	start := l.pos
+	for l.pos < len(l.b) && !isPDFWhiteSpace(l.b[l.pos]) && !isPDFDelimiter(l.b[l.pos]) {
+		l.pos++
+	}
+	return l.b[start:l.pos]
+}
+
+func (l *lexer) literalString() []byte {
+	var s []byte
+	depth := 0
+	for l.pos++; l.pos < len(l.b); l.pos++ {
+		c := l.b[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				l.pos++
+				return s
+			}
+			depth--
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.b) {
+				return s
+			}
+			c = l.b[l.pos]
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// backslash followed by EOL is a line continuation.
+				if l.peekAt(1) == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					oct := int(c - '0')
+					for i := 0; i < 2 && l.peekAt(1) >= '0' && l.peekAt(1) <= '7'; i++ {
+						l.pos++
+						oct = oct*8 + int(l.b[l.pos]-'0')
+					}
+					c = byte(oct)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return s
+}
+
+func (l *lexer) hexString() []byte {
+	var digits []byte
+	for l.pos++; l.pos < len(l.b) && l.b[l.pos] != '>'; l.pos++ {
+		if c := l.b[l.pos]; !isPDFWhiteSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		s = append(s, byte(v))
+	}
+	return s
+}
+
+// skipInlineImage skips the binary data of an inline image, i.e. everything between 'ID' and 'EI'.
+func (l *lexer) skipInlineImage() {
+	if end := bytes.Index(l.b[l.pos:], []byte("EI")); end >= 0 {
+		l.pos += end + 2
+		return
+	}
+	l.pos = len(l.b)
+}
+
+// decodeText converts a PDF string into UTF-8. Strings starting with a UTF-16BE byte order mark are
+// decoded as UTF-16BE, all other strings are treated as single byte encoded (approximately
+// PDFDocEncoding/WinAnsiEncoding). Font specific encodings (e.g. CID fonts or '/ToUnicode' cmaps)
+// aren't supported.
+func decodeText(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		u := make([]uint16, 0, (len(s)-2)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, len(s))
+	for i, b := range s {
+		r[i] = rune(b)
+	}
+	return string(r)
+}
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+func (m matrix) translate(tx, ty float64) matrix {
+	m[4], m[5] = tx*m[0]+ty*m[2]+m[4], tx*m[1]+ty*m[3]+m[5]
+	return m
+}
+
+// textState tracks the text related graphics state while interpreting a content stream.
+type textState struct {
+	tm, tlm     matrix
+	fontSize    float64
+	leading     float64
+	charSpacing float64
+	wordSpacing float64
+	fragments   []textFragment
+}
+
+func (ts *textState) nextLine(tx, ty float64) {
+	ts.tlm = ts.tlm.translate(tx, ty)
+	ts.tm = ts.tlm
+}
+
+// show records a text fragment at the current text position and advances the text position. Since
+// font metrics aren't available, the width of each glyph is estimated as half of the font size.
+func (ts *textState) show(s []byte) {
+	text := decodeText(s)
+	scale := math.Hypot(ts.tm[0], ts.tm[1])
+	if scale == 0 {
+		scale = 1
+	}
+	n := float64(len([]rune(text)))
+	advance := n*(ts.fontSize*0.5+ts.charSpacing) + float64(strings.Count(text, " "))*ts.wordSpacing
+	if strings.TrimSpace(text) != "" {
+		ts.fragments = append(ts.fragments, textFragment{
+			x:        ts.tm[4],
+			y:        ts.tm[5],
+			width:    advance * scale,
+			fontSize: ts.fontSize * scale,
+			text:     text,
+		})
+	}
+	ts.tm = ts.tm.translate(advance, 0)
+}
+
+// interpret runs a content stream and returns all the text fragments it shows.
+func interpret(content []byte) []textFragment {
+	ts := &textState{tm: identity, tlm: identity}
+	l := &lexer{b: content}
+	var operands []token
+	var array []token
+	inArray := false
+	dictDepth := 0
+	num := func(i int) float64 {
+		if i < len(operands) && operands[i].kind == tokenNumber {
+			return operands[i].num
+		}
+		return 0
+	}
+	lastString := func() []byte {
+		if len(operands) > 0 && operands[len(operands)-1].kind == tokenString {
+			return operands[len(operands)-1].str
+		}
+		return nil
+	}
+	for {
+		t := l.next()
+		switch t.kind {
+		case tokenEOF:
+			return ts.fragments
+		case tokenDictBegin:
+			dictDepth++
+			continue
+		case tokenDictEnd:
+			dictDepth--
+			continue
+		}
+		if dictDepth > 0 {
+			// dictionaries (e.g. marked content properties) carry no text. Skip.
+			continue
+		}
+		switch t.kind {
+		case tokenArrayBegin:
+			inArray, array = true, nil
+			continue
+		case tokenArrayEnd:
+			inArray = false
+			operands = append(operands, token{kind: tokenArrayBegin})
+			continue
+		}
+		if inArray {
+			array = append(array, t)
+			continue
+		}
+		if t.kind != tokenOperator {
+			operands = append(operands, t)
+			continue
+		}
+		switch string(t.str) {
+		case "BT":
+			ts.tm, ts.tlm = identity, identity
+		case "Tf":
+			ts.fontSize = num(1)
+		case "TL":
+			ts.leading = num(0)
+		case "Tc":
+			ts.charSpacing = num(0)
+		case "Tw":
+			ts.wordSpacing = num(0)
+		case "Td":
+			ts.nextLine(num(0), num(1))
+		case "TD":
+			ts.leading = -num(1)
+			ts.nextLine(num(0), num(1))
+		case "Tm":
+			ts.tm = matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+			ts.tlm = ts.tm
+		case "T*":
+			ts.nextLine(0, -ts.leading)
+		case "Tj":
+			ts.show(lastString())
+		case "'":
+			ts.nextLine(0, -ts.leading)
+			ts.show(lastString())
+		case "\"":
+			ts.wordSpacing, ts.charSpacing = num(0), num(1)
+			ts.nextLine(0, -ts.leading)
+			ts.show(lastString())
+		case "TJ":
+			for _, e := range array {
+				switch e.kind {
+				case tokenString:
+					ts.show(e.str)
+				case tokenNumber:
+					ts.tm = ts.tm.translate(-e.num/1000*ts.fontSize, 0)
+				}
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// assembleLines groups text fragments of a page into lines, top to bottom, and splits each line into
+// cells, left to right, wherever the horizontal gap between two fragments is at least columnGap.
+func assembleLines(fragments []textFragment, columnGap, lineTolerance float64) []line {
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].y > fragments[j].y
+	})
+	var lines []line
+	for i := 0; i < len(fragments); {
+		j := i + 1
+		for j < len(fragments) && fragments[i].y-fragments[j].y <= lineTolerance {
+			j++
+		}
+		lines = append(lines, assembleLine(fragments[i:j], columnGap))
+		i = j
+	}
+	return lines
+}
+
+func assembleLine(fragments []textFragment, columnGap float64) line {
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].x < fragments[j].x
+	})
+	var cells []string
+	var cell strings.Builder
+	end := 0.0
+	for i, f := range fragments {
+		gap := f.x - end
+		switch {
+		case i == 0:
+		case gap >= columnGap:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		case gap > f.fontSize*0.15:
+			// gap is wider than a typical inter-glyph space: treat it as a word break.
+			cell.WriteByte(' ')
+		}
+		cell.WriteString(f.text)
+		end = math.Max(end, f.x+f.width)
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+	return line{text: strings.Join(cells, " "), cells: cells}
+}
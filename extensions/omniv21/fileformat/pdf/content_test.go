@@ -0,0 +1,65 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexer(t *testing.T) {
+	l := &lexer{b: []byte(`% comment
+/F1 -1.5 +.5 (a\(b\)\n\101\0531(c)\
+d) <48 6 > <</K [1]>> } T* 1.2.3`)}
+	var tokens []token
+	for {
+		tok := l.next()
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			break
+		}
+	}
+	assert.Equal(t, []token{
+		{kind: tokenName, str: []byte("F1")},
+		{kind: tokenNumber, num: -1.5},
+		{kind: tokenNumber, num: 0.5},
+		{kind: tokenString, str: []byte("a(b)\nA+1(c)d")},
+		{kind: tokenString, str: []byte{0x48, 0x60}},
+		{kind: tokenDictBegin},
+		{kind: tokenName, str: []byte("K")},
+		{kind: tokenArrayBegin},
+		{kind: tokenNumber, num: 1},
+		{kind: tokenArrayEnd},
+		{kind: tokenDictEnd},
+		{kind: tokenOperator, str: []byte("T*")},
+		{kind: tokenOperator, str: []byte("1.2.3")},
+		{kind: tokenEOF},
+	}, tokens)
+}
+
+func TestDecodeText(t *testing.T) {
+	assert.Equal(t, "café", decodeText([]byte("caf\xe9")))
+	assert.Equal(t, "中文", decodeText([]byte("\xfe\xff\x4e\x2d\x65\x87")))
+}
+
+func TestInterpret(t *testing.T) {
+	assert.Equal(t, []textFragment{
+		{x: 10, y: 20, width: 10, fontSize: 10, text: "ab"},
+		{x: 10, y: 8, width: 5, fontSize: 10, text: "c"},
+		{x: 40, y: -4, width: 10, fontSize: 20, text: "d"},
+	}, interpret([]byte(`BT /F1 5 Tf 10 20 Td 2 0 0 2 10 20 Tm (ab) Tj (  ) Tj 0 -6 TD (c) Tj
+2 0 0 2 0 0 Tm /F2 10 Tf 1 Tc 20 4 Td 0 0 (d) " ET`)))
+}
+
+func TestAssembleLines(t *testing.T) {
+	lines := assembleLines([]textFragment{
+		{x: 50, y: 99, width: 10, fontSize: 10, text: "b"},
+		{x: 0, y: 100, width: 10, fontSize: 10, text: "a"},
+		{x: 10.5, y: 100, width: 10, fontSize: 10, text: "a"},
+		{x: 23, y: 100, width: 10, fontSize: 10, text: "a"},
+		{x: 0, y: 80, width: 10, fontSize: 10, text: "c"},
+	}, 10, 2)
+	assert.Equal(t, []line{
+		{text: "aa a b", cells: []string{"aa a", "b"}},
+		{text: "c", cells: []string{"c"}},
+	}, lines)
+}
@@ -0,0 +1,58 @@
+package pdf
+
+const (
+	// ModeLines emits every text line of a PDF as a record.
+	ModeLines = "lines"
+	// ModeTables emits only the text lines that look like table rows, i.e. lines with at least
+	// 'min_columns' cells.
+	ModeTables = "tables"
+)
+
+const (
+	defaultColumnGap     = 10.0
+	defaultLineTolerance = 2.0
+	defaultMinColumns    = 2
+)
+
+// FileDecl describes PDF specific schema settings for omniparser reader. All settings are optional.
+type FileDecl struct {
+	// Mode is either ModeLines (default) or ModeTables.
+	Mode *string `json:"mode,omitempty"`
+	// ColumnGap is the minimum horizontal gap, in PDF text space units (1/72 inch), between two
+	// pieces of text on the same line for them to be considered in different cells. Default 10.
+	ColumnGap *float64 `json:"column_gap,omitempty"`
+	// LineTolerance is the maximum vertical distance, in PDF text space units, between two pieces
+	// of text for them to be considered on the same line. Default 2.
+	LineTolerance *float64 `json:"line_tolerance,omitempty"`
+	// MinColumns is the minimum number of cells a line must have to be considered a table row in
+	// ModeTables. Default 2.
+	MinColumns *int `json:"min_columns,omitempty"`
+}
+
+func (d *FileDecl) mode() string {
+	if d == nil || d.Mode == nil {
+		return ModeLines
+	}
+	return *d.Mode
+}
+
+func (d *FileDecl) columnGap() float64 {
+	if d == nil || d.ColumnGap == nil {
+		return defaultColumnGap
+	}
+	return *d.ColumnGap
+}
+
+func (d *FileDecl) lineTolerance() float64 {
+	if d == nil || d.LineTolerance == nil {
+		return defaultLineTolerance
+	}
+	return *d.LineTolerance
+}
+
+func (d *FileDecl) minColumns() int {
+	if d == nil || d.MinColumns == nil {
+		return defaultMinColumns
+	}
+	return *d.MinColumns
+}
@@ -0,0 +1,93 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		format      string
+		schema      string
+		decl        *transform.Decl
+		expected    interface{}
+		expectedErr string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "json schema validation fail",
+			format:      fileFormatPDF,
+			schema:      `{"file_declaration": { "mode": "pages" }}`,
+			expectedErr: `schema 'test-schema' validation failed: file_declaration.mode: file_declaration.mode must be one of the following: "lines", "tables"`,
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatPDF,
+			schema:      `{}`,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatPDF,
+			schema:      `{}`,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:     "success without file_declaration",
+			format:   fileFormatPDF,
+			schema:   `{}`,
+			decl:     &transform.Decl{},
+			expected: &pdfFormatRuntime{},
+		},
+		{
+			name:   "success with file_declaration",
+			format: fileFormatPDF,
+			schema: `{"file_declaration": { "mode": "tables", "column_gap": 20.5, "min_columns": 3 }}`,
+			decl:   &transform.Decl{XPath: strs.StrPtr(" .[page='1'] ")},
+			expected: &pdfFormatRuntime{
+				Decl: &FileDecl{
+					Mode:       strs.StrPtr(ModeTables),
+					ColumnGap:  func() *float64 { f := 20.5; return &f }(),
+					MinColumns: func() *int { i := 3; return &i }(),
+				},
+				XPath: ".[page='1']",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewPDFFileFormat("test-schema").ValidateSchema(test.format, []byte(test.schema), test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, runtime)
+			}
+		})
+	}
+}
+
+func TestCreateFormatReader(t *testing.T) {
+	r, err := NewPDFFileFormat("test-schema").CreateFormatReader(
+		"test-input", strings.NewReader(""), &pdfFormatRuntime{XPath: ".[page='2']"})
+	assert.NoError(t, err)
+	assert.NotNil(t, r.(*reader).xpath)
+
+	r, err = NewPDFFileFormat("test-schema").CreateFormatReader(
+		"test-input", strings.NewReader(""), &pdfFormatRuntime{XPath: "."})
+	assert.NoError(t, err)
+	assert.Nil(t, r.(*reader).xpath)
+}
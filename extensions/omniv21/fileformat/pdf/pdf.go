@@ -0,0 +1,128 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+)
+
+var (
+	objHeaderRegexp  = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pageTypeRegexp   = regexp.MustCompile(`/Type\s*/Page\b`)
+	contentsRegexp   = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	objRefRegexp     = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	directLenRegexp  = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	filterRegexp     = regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/[A-Za-z0-9]+)`)
+	nonContentRegexp = regexp.MustCompile(
+		`/Type\s*/(XObject|ObjStm|XRef|Metadata|EmbeddedFile)\b|/Subtype\s*/Image\b|/Length[123]\b`)
+)
+
+type pdfObject struct {
+	dict   []byte
+	stream []byte // nil if the object isn't a stream, or the stream can't be decoded.
+}
+
+// extractContentStreams returns the decoded page content streams of a PDF, in page order. Pages are
+// located by their page objects and '/Contents' references. If no page object can be found (e.g. they
+// are stored in compressed object streams), all the decodable streams that contain text objects are
+// returned, in the order they appear in the file, each as a separate page.
+func extractContentStreams(data []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, errors.New("missing '%PDF-' header")
+	}
+	var order []int
+	objs := map[int]*pdfObject{}
+	matches := objHeaderRegexp.FindAllSubmatchIndex(data, -1)
+	for i, m := range matches {
+		end := len(data)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		objs[num] = parseObject(data[m[1]:end])
+		order = append(order, num)
+	}
+	var pages [][]byte
+	for _, num := range order {
+		obj := objs[num]
+		if obj.stream != nil || !pageTypeRegexp.Match(obj.dict) {
+			continue
+		}
+		var page []byte
+		contents := contentsRegexp.FindSubmatch(obj.dict)
+		if contents == nil {
+			// A page without '/Contents' is a blank page.
+			pages = append(pages, page)
+			continue
+		}
+		for _, ref := range objRefRegexp.FindAllSubmatch(contents[1], -1) {
+			refNum, _ := strconv.Atoi(string(ref[1]))
+			if refObj, found := objs[refNum]; found && refObj.stream != nil {
+				// Per PDF spec, multiple content streams of a page are concatenated as if they
+				// were one, with white space in between.
+				page = append(append(page, refObj.stream...), '\n')
+			}
+		}
+		pages = append(pages, page)
+	}
+	if len(pages) > 0 {
+		return pages, nil
+	}
+	for _, num := range order {
+		obj := objs[num]
+		if obj.stream != nil && !nonContentRegexp.Match(obj.dict) && bytes.Contains(obj.stream, []byte("BT")) {
+			pages = append(pages, obj.stream)
+		}
+	}
+	return pages, nil
+}
+
+func parseObject(body []byte) *pdfObject {
+	if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+		body = body[:end]
+	}
+	streamBegin := bytes.Index(body, []byte("stream"))
+	if streamBegin < 0 {
+		return &pdfObject{dict: body}
+	}
+	obj := &pdfObject{dict: body[:streamBegin]}
+	raw := body[streamBegin+len("stream"):]
+	// 'stream' keyword is followed by either CRLF or LF.
+	raw = bytes.TrimPrefix(raw, []byte("\r"))
+	raw = bytes.TrimPrefix(raw, []byte("\n"))
+	if m := directLenRegexp.FindSubmatch(obj.dict); m != nil && len(m[2]) == 0 {
+		if n, err := strconv.Atoi(string(m[1])); err == nil && n <= len(raw) {
+			raw = raw[:n]
+		}
+	} else if end := bytes.LastIndex(raw, []byte("endstream")); end >= 0 {
+		raw = bytes.TrimSuffix(bytes.TrimSuffix(raw[:end], []byte("\n")), []byte("\r"))
+	}
+	obj.stream = decodeStream(obj.dict, raw)
+	return obj
+}
+
+// decodeStream decodes a stream with no filter or /FlateDecode filter. For all other filters (mostly
+// for images and fonts), nil is returned.
+func decodeStream(dict, raw []byte) []byte {
+	filter := filterRegexp.FindSubmatch(dict)
+	if filter == nil {
+		return raw
+	}
+	f := bytes.Trim(filter[1], "[] \t\r\n")
+	if !bytes.Equal(f, []byte("/FlateDecode")) {
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	decoded, err := ioutil.ReadAll(zr)
+	if err != nil && len(decoded) == 0 {
+		return nil
+	}
+	return decoded
+}
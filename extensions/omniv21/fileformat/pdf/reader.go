@@ -0,0 +1,128 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/antchfx/xpath"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidPDF indicates the PDF input is corrupted or unreadable. This is a fatal, non-continuable
+// error.
+type ErrInvalidPDF string
+
+func (e ErrInvalidPDF) Error() string { return string(e) }
+
+// IsErrInvalidPDF checks if the `err` is of ErrInvalidPDF type, or wraps one, e.g. in an errs.ErrInput.
+func IsErrInvalidPDF(err error) bool {
+	var e ErrInvalidPDF
+	return errors.As(err, &e)
+}
+
+type reader struct {
+	inputName string
+	src       io.Reader
+	decl      *FileDecl
+	xpath     *xpath.Expr
+	loaded    bool
+	pages     [][]byte
+	page      int // 1-based index of the page the current lines are from.
+	lines     []line
+	lineIndex int // 0-based index of the next line to read in lines.
+}
+
+// Read returns the next text line (or table row, in ModeTables) as a record. A PDF has no random
+// access friendly layout for streaming, so the entire input is read into memory on the first Read.
+func (r *reader) Read() (*idr.Node, error) {
+	if !r.loaded {
+		r.loaded = true
+		data, err := ioutil.ReadAll(r.src)
+		if err != nil {
+			return nil, r.invalidPDF("unable to read input: %s", err.Error())
+		}
+		r.pages, err = extractContentStreams(data)
+		if err != nil {
+			return nil, r.invalidPDF("unable to read input: %s", err.Error())
+		}
+	}
+	for {
+		for r.lineIndex >= len(r.lines) {
+			if r.page >= len(r.pages) {
+				return nil, io.EOF
+			}
+			r.lines = assembleLines(interpret(r.pages[r.page]), r.decl.columnGap(), r.decl.lineTolerance())
+			r.lineIndex = 0
+			r.page++
+		}
+		l := r.lines[r.lineIndex]
+		r.lineIndex++
+		if r.decl.mode() == ModeTables && len(l.cells) < r.decl.minColumns() {
+			continue
+		}
+		n := r.lineToNode(l)
+		if r.xpath != nil && !idr.MatchAny(n, r.xpath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+func (r *reader) lineToNode(l line) *idr.Node {
+	root := idr.CreateNode(idr.DocumentNode, "")
+	addChild := func(name, value string) {
+		n := idr.CreateNode(idr.ElementNode, name)
+		idr.AddChild(root, n)
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, value))
+	}
+	addChild("page", strconv.Itoa(r.page))
+	addChild("line", strconv.Itoa(r.lineIndex))
+	addChild("text", l.text)
+	for _, c := range l.cells {
+		addChild("cell", c)
+	}
+	return root
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidPDF(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(format, args...))
+}
+
+func (r *reader) fmtErrStr(format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' page %d: %s", r.inputName, r.page, fmt.Sprintf(format, args...))
+}
+
+// invalidPDF creates an ErrInvalidPDF, wrapped in an errs.ErrInput.
+func (r *reader) invalidPDF(format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format:  fileFormatPDF,
+		Input:   r.inputName,
+		Offset:  -1,
+		Segment: r.page,
+		Reason:  reason,
+		Err:     ErrInvalidPDF(r.fmtErrStr("%s", reason)),
+	}
+}
+
+// NewReader creates an experimental FormatReader for PDF file format. Each record is a text line of
+// the PDF, with child elements 'page', 'line' (both 1-based), 'text' and repeated 'cell'.
+func NewReader(inputName string, src io.Reader, decl *FileDecl, xpath *xpath.Expr) *reader {
+	return &reader{inputName: inputName, src: src, decl: decl, xpath: xpath}
+}
@@ -0,0 +1,154 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func flate(s string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return b.String()
+}
+
+// testPDF builds a minimal PDF with the given objects, numbered from 1. No xref table is generated as
+// the reader doesn't need it.
+func testPDF(objs ...string) string {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	for i, o := range objs {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.String()
+}
+
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+const page1Content = `BT /F1 12 Tf 72 720 Td (Invoice Report) Tj ET
+BT /F1 10 Tf 14 TL
+1 0 0 1 72 700 Tm (Item) Tj 1 0 0 1 200 700 Tm (Qty) Tj 1 0 0 1 300 700 Tm (Price) Tj
+1 0 0 1 72 686 Tm [(Wid) -20 (get)] TJ 1 0 0 1 200 686.5 Tm (2) Tj 1 0 0 1 300 686 Tm <31302E3030> Tj
+ET`
+
+const page2Content = `q BI /W 1 /H 1 ID xEIx EI Q
+/P << /MCID 0 >> BDC BT /F1 10 Tf 12 TL 72 700 Td (Total) Tj (: ) ' [(see) -300 (page 1)] TJ
+(\(final\)) ' ET EMC`
+
+var testInput = testPDF(
+	"<< /Type /Catalog /Pages 2 0 R >>",
+	"<< /Type /Pages /Kids [3 0 R 5 0 R 7 0 R] /Count 3 >>",
+	"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+	stream("/Filter /FlateDecode", flate(page1Content)),
+	"<< /Type /Page /Parent 2 0 R /Contents [6 0 R 8 0 R] >>",
+	stream("", page2Content),
+	"<< /Type /Page /Parent 2 0 R >>",
+	stream("/Filter [/FlateDecode]", flate("BT 72 500 Td (after) Tj ET")),
+	stream("/Type /XObject /Subtype /Image /Filter /DCTDecode", "\xff\xd8\xff"),
+)
+
+func readAll(t *testing.T, r *reader) []string {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err == io.EOF {
+			return records
+		}
+		assert.NoError(t, err)
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		decl     *FileDecl
+		xpath    string
+		expected []string
+	}{
+		{
+			name:  "lines",
+			input: testInput,
+			expected: []string{
+				`{"cell":"Invoice Report","line":"1","page":"1","text":"Invoice Report"}`,
+				`{"cell":["Item","Qty","Price"],"line":"2","page":"1","text":"Item Qty Price"}`,
+				`{"cell":["Widget","2","10.00"],"line":"3","page":"1","text":"Widget 2 10.00"}`,
+				`{"cell":"Total","line":"1","page":"2","text":"Total"}`,
+				`{"cell":": see page 1","line":"2","page":"2","text":": see page 1"}`,
+				`{"cell":"(final)","line":"3","page":"2","text":"(final)"}`,
+				`{"cell":"after","line":"4","page":"2","text":"after"}`,
+			},
+		},
+		{
+			name:  "tables with xpath",
+			input: testInput,
+			decl:  &FileDecl{Mode: strs.StrPtr(ModeTables)},
+			xpath: ".[cell[1] != 'Item']",
+			expected: []string{
+				`{"cell":["Widget","2","10.00"],"line":"3","page":"1","text":"Widget 2 10.00"}`,
+			},
+		},
+		{
+			name:  "no page objects",
+			input: testPDF(stream("", "BT 1 0 0 1 0 0 Tm (a) Tj ET"), stream("/Length1 5", "BT (font) Tj ET")),
+			expected: []string{
+				`{"cell":"a","line":"1","page":"1","text":"a"}`,
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewReader("test-input", strings.NewReader(test.input), test.decl, nil)
+			if test.xpath != "" {
+				r.xpath, _ = caches.GetXPathExpr(test.xpath)
+			}
+			assert.Equal(t, test.expected, readAll(t, r))
+		})
+	}
+}
+
+func TestRead_Failures(t *testing.T) {
+	r := NewReader("test-input", strings.NewReader("not a pdf"), nil, nil)
+	n, err := r.Read()
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidPDF(err))
+	assert.False(t, r.IsContinuableError(err))
+	assert.Equal(t, `input 'test-input' page 0: unable to read input: missing '%PDF-' header`, err.Error())
+	assert.Nil(t, n)
+
+	r = NewReader("test-input", &failingReader{}, nil, nil)
+	_, err = r.Read()
+	assert.Equal(t, `input 'test-input' page 0: unable to read input: read failure`, err.Error())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidPDF("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
+
+func TestFmtErr(t *testing.T) {
+	r := &reader{inputName: "test-input", page: 3}
+	assert.Equal(t, "input 'test-input' page 3: test 1", r.FmtErr("test %d", 1).Error())
+}
@@ -0,0 +1,71 @@
+package fileformat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Position is a location in an input. Note if the input isn't in UTF-8, the position is in the input
+// as converted to UTF-8.
+type Position struct {
+	Offset int64 // 0-based byte offset.
+	Line   int   // 1-based line number.
+	Column int   // 1-based column number, in bytes.
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, col %d, byte offset %d", p.Line, p.Column, p.Offset)
+}
+
+// SourcePositionReporter is an optional interface a FormatReader can implement to report where exactly
+// in the input the record returned by the most recent successful Read call is: begin is the position of
+// its first byte and end is the position right after its last byte.
+type SourcePositionReporter interface {
+	SourcePosition() (begin, end Position)
+}
+
+// PositionReader is an io.Reader wrapper that keeps track of where the lines of the input read through
+// it start, so that byte offsets into the input can be turned into Positions.
+type PositionReader struct {
+	r          io.Reader
+	offset     int64   // number of bytes read so far.
+	lineStarts []int64 // offsets where lines start, lineStarts[0] being the start of line lineBase.
+	lineBase   int
+}
+
+func (pr *PositionReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	for i, c := range p[:n] {
+		if c == '\n' {
+			pr.lineStarts = append(pr.lineStarts, pr.offset+int64(i)+1)
+		}
+	}
+	pr.offset += int64(n)
+	return n, err
+}
+
+// Position returns the Position of a byte offset into the input. To keep the memory usage bounded,
+// the start offsets of the lines before the offset are discarded, thus offsets passed in must never
+// decrease, or the positions returned are approximate.
+func (pr *PositionReader) Position(offset int64) Position {
+	i := sort.Search(len(pr.lineStarts), func(i int) bool { return pr.lineStarts[i] > offset }) - 1
+	if i < 0 {
+		// the offset is on a line already discarded; the best we can do is the earliest line known.
+		return Position{Offset: offset, Line: pr.lineBase, Column: 1}
+	}
+	if i > 0 {
+		pr.lineStarts = pr.lineStarts[i:]
+		pr.lineBase += i
+	}
+	return Position{
+		Offset: offset,
+		Line:   pr.lineBase,
+		Column: int(offset-pr.lineStarts[0]) + 1,
+	}
+}
+
+// NewPositionReader creates a new PositionReader.
+func NewPositionReader(r io.Reader) *PositionReader {
+	return &PositionReader{r: r, lineStarts: []int64{0}, lineBase: 1}
+}
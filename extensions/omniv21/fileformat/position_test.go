@@ -0,0 +1,37 @@
+package fileformat
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosition_String(t *testing.T) {
+	assert.Equal(t, "line 2, col 3, byte offset 7", Position{Offset: 7, Line: 2, Column: 3}.String())
+}
+
+func TestPositionReader(t *testing.T) {
+	pr := NewPositionReader(strings.NewReader("ab\ncd\r\n\nefg"))
+	assert.Equal(t, Position{Offset: 0, Line: 1, Column: 1}, pr.Position(0))
+	b, err := ioutil.ReadAll(pr)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab\ncd\r\n\nefg", string(b))
+	for _, test := range []struct {
+		offset   int64
+		expected Position
+	}{
+		{offset: 1, expected: Position{Offset: 1, Line: 1, Column: 2}},
+		{offset: 2, expected: Position{Offset: 2, Line: 1, Column: 3}},
+		{offset: 3, expected: Position{Offset: 3, Line: 2, Column: 1}},
+		{offset: 5, expected: Position{Offset: 5, Line: 2, Column: 3}},
+		{offset: 7, expected: Position{Offset: 7, Line: 3, Column: 1}},
+		{offset: 10, expected: Position{Offset: 10, Line: 4, Column: 3}},
+		{offset: 11, expected: Position{Offset: 11, Line: 4, Column: 4}},
+		// offsets on lines already discarded are approximate.
+		{offset: 4, expected: Position{Offset: 4, Line: 4, Column: 1}},
+	} {
+		assert.Equal(t, test.expected, pr.Position(test.offset))
+	}
+}
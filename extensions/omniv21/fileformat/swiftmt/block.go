@@ -0,0 +1,259 @@
+package swiftmt
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/logward/omniparser/idr"
+)
+
+const (
+	basicHeaderBlockID       = "1"
+	applicationHeaderBlockID = "2"
+	textBlockID              = "4"
+	// sequenceStartTag and sequenceEndTag are the tags of the fields starting and ending a sequence, e.g.
+	// `:16R:GENL` and `:16S:GENL`, in the text block of e.g. MT5xx messages.
+	sequenceStartTag = "16R"
+	sequenceEndTag   = "16S"
+)
+
+var (
+	blockIDRegexp  = regexp.MustCompile(`^[A-Za-z0-9]{1,3}$`)
+	subTagRegexp   = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	fieldTagRegexp = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+	// a generic field, e.g. `:98A::TRAD//20201225` or `:22F::SETR/ABCD/TRAD`: qualifier, data source
+	// scheme (issuer) and value.
+	genericFieldRegexp = regexp.MustCompile(`(?s)^:([A-Z0-9]{4})/([A-Z0-9]{0,8})/(.*)$`)
+	sequenceNameRegexp = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+)
+
+// blockName returns the element name of a block, e.g. `block4`.
+func blockName(id string) string {
+	return "block" + id
+}
+
+// tagName returns the element name of a field or sub-block tag, e.g. `F20C` for `20C`, `F108` for `108`
+// and `CHK` for `CHK`, as element names can't start with a digit.
+func tagName(tag string) string {
+	if tag[0] >= '0' && tag[0] <= '9' {
+		return "F" + tag
+	}
+	return tag
+}
+
+// addBasicHeader adds the element of a basic header block, e.g. `{1:F01BANKDEFFAXXX0000000000}`.
+func addBasicHeader(parent *idr.Node, content string) error {
+	if len(content) != 25 {
+		return fmt.Errorf("invalid basic header block '%s', expected 25 characters", content)
+	}
+	n := addElement(parent, blockName(basicHeaderBlockID))
+	addText(n, "application_id", content[:1])
+	addText(n, "service_id", content[1:3])
+	addText(n, "lt_address", content[3:15])
+	addText(n, "session_number", content[15:19])
+	addText(n, "sequence_number", content[19:25])
+	return nil
+}
+
+// addApplicationHeader adds the element of an application header block, either of an input message,
+// e.g. `{2:I103BANKDEFFXXXXN}`, or of an output message, e.g.
+// `{2:O9401200201225BANKDEFFAXXX00000000002012251200N}`.
+func addApplicationHeader(parent *idr.Node, content string) error {
+	invalid := func() error {
+		return fmt.Errorf("invalid application header block '%s'", content)
+	}
+	if len(content) < 4 {
+		return invalid()
+	}
+	var fields [][2]string
+	switch direction := content[:1]; {
+	case direction == "I" && len(content) >= 16 && len(content) <= 21:
+		fields = [][2]string{
+			{"direction", direction},
+			{"message_type", content[1:4]},
+			{"receiver_address", content[4:16]},
+			{"priority", substr(content, 16, 17)},
+			{"delivery_monitoring", substr(content, 17, 18)},
+			{"obsolescence_period", substr(content, 18, 21)},
+		}
+	case direction == "O" && (len(content) == 46 || len(content) == 47):
+		fields = [][2]string{
+			{"direction", direction},
+			{"message_type", content[1:4]},
+			{"input_time", content[4:8]},
+			{"mir", ""},
+			{"output_date", content[36:42]},
+			{"output_time", content[42:46]},
+			{"priority", substr(content, 46, 47)},
+		}
+	default:
+		return invalid()
+	}
+	n := addElement(parent, blockName(applicationHeaderBlockID))
+	for _, f := range fields {
+		if f[0] == "mir" {
+			// the message input reference: input date, sender's LT address, session and sequence numbers.
+			mir := addElement(n, f[0])
+			addText(mir, "date", content[8:14])
+			addText(mir, "sender_address", content[14:26])
+			addText(mir, "session_number", content[26:30])
+			addText(mir, "sequence_number", content[30:36])
+			continue
+		}
+		if f[1] != "" {
+			addText(n, f[0], f[1])
+		}
+	}
+	return nil
+}
+
+func substr(s string, begin, end int) string {
+	if begin >= len(s) {
+		return ""
+	}
+	if end > len(s) {
+		end = len(s)
+	}
+	return s[begin:end]
+}
+
+// addSubBlocks adds an element for each of the sub-blocks, e.g. `{108:REF}{121:...}` of a user header
+// block or `{CHK:123456789ABC}{PDE:}` of a trailer block, named after their tags, with the ones of any
+// nested sub-blocks, e.g. `{S:{SAC:}{COP:P}}`, as their children.
+func addSubBlocks(parent *idr.Node, content string) error {
+	for content != "" {
+		if content[0] != '{' {
+			return fmt.Errorf("invalid sub-block '%s'", content)
+		}
+		end := matchingBrace(content)
+		colon := strings.IndexByte(content, ':')
+		if end < 0 || colon < 0 || colon > end {
+			return fmt.Errorf("invalid sub-block '%s'", content)
+		}
+		tag, value := content[1:colon], content[colon+1:end]
+		if !subTagRegexp.MatchString(tag) {
+			return fmt.Errorf("invalid sub-block tag '%s'", tag)
+		}
+		if strings.HasPrefix(value, "{") {
+			if err := addSubBlocks(addElement(parent, tagName(tag)), value); err != nil {
+				return err
+			}
+		} else {
+			addText(parent, tagName(tag), value)
+		}
+		content = content[end+1:]
+	}
+	return nil
+}
+
+// matchingBrace returns the index of the '}' matching the '{' s starts with, or -1 if there is none.
+func matchingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// field is a field of a text block, e.g. `:20:STMT20201225`, with its continuation lines, if any.
+type field struct {
+	tag   string
+	value string
+	line  int
+}
+
+// parseFields parses the lines of a text block into fields. firstLine is the line number of lines[0].
+// Lines not starting with a field tag are the continuation lines of the previous field. The block end
+// line, i.e. `-`, must have been removed.
+func parseFields(lines []string, firstLine int) ([]field, error) {
+	var fields []field
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if m := fieldTagRegexp.FindStringSubmatch(line); m != nil {
+			fields = append(fields, field{tag: m[1], value: line[len(m[0]):], line: firstLine + i})
+			continue
+		}
+		if len(fields) == 0 {
+			if line == "" {
+				continue
+			}
+			return nil, &lineErr{
+				line: firstLine + i,
+				msg:  fmt.Sprintf("unexpected line '%s' before the first field", line),
+			}
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+	return fields, nil
+}
+
+// lineErr is an error at a line of a text block.
+type lineErr struct {
+	line int
+	msg  string
+}
+
+func (e *lineErr) Error() string { return e.msg }
+
+// addTextBlock adds the element of a text block, with an element for each field, named after its tag,
+// e.g. `F20` or `F60F`, in the order they appear, nested in an element for each sequence, e.g. `GENL`,
+// started and ended by a `:16R:` and a `:16S:` field, respectively. The text of a field is its value,
+// with its continuation lines, if any, separated by '\n', unless it's a generic field, e.g.
+// `:98A::TRAD//20201225`, in which case it has a `qualifier`, an `issuer` (i.e. data source scheme), if
+// any, and a `value` element.
+func addTextBlock(parent *idr.Node, fields []field) error {
+	stack := []*idr.Node{addElement(parent, blockName(textBlockID))}
+	for _, f := range fields {
+		top := stack[len(stack)-1]
+		switch f.tag {
+		case sequenceStartTag:
+			if !sequenceNameRegexp.MatchString(f.value) {
+				return &lineErr{line: f.line, msg: fmt.Sprintf("invalid sequence name '%s'", f.value)}
+			}
+			stack = append(stack, addElement(top, f.value))
+			continue
+		case sequenceEndTag:
+			if len(stack) == 1 || top.Data != f.value {
+				return &lineErr{
+					line: f.line,
+					msg:  fmt.Sprintf("':16S:%s' doesn't end the current sequence", f.value),
+				}
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if m := genericFieldRegexp.FindStringSubmatch(f.value); m != nil {
+			n := addElement(top, tagName(f.tag))
+			addText(n, "qualifier", m[1])
+			if m[2] != "" {
+				addText(n, "issuer", m[2])
+			}
+			addText(n, "value", m[3])
+			continue
+		}
+		addText(top, tagName(f.tag), f.value)
+	}
+	if len(stack) > 1 {
+		return fmt.Errorf("sequence '%s' isn't ended", stack[len(stack)-1].Data)
+	}
+	return nil
+}
+
+func addElement(parent *idr.Node, name string) *idr.Node {
+	n := idr.CreateNode(idr.ElementNode, name)
+	idr.AddChild(parent, n)
+	return n
+}
+
+func addText(parent *idr.Node, name, value string) {
+	idr.AddChild(addElement(parent, name), idr.CreateNode(idr.TextNode, value))
+}
@@ -0,0 +1,84 @@
+package swiftmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func TestAddApplicationHeader(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		content  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "input, minimal",
+			content:  "I940BANKDEFFXXXX",
+			expected: `{"block2":{"direction":"I","message_type":"940","receiver_address":"BANKDEFFXXXX"}}`,
+		},
+		{
+			name:    "input, full",
+			content: "I103BANKDEFFXXXXU3003",
+			expected: `{"block2":{"delivery_monitoring":"3","direction":"I","message_type":"103",` +
+				`"obsolescence_period":"003","priority":"U","receiver_address":"BANKDEFFXXXX"}}`,
+		},
+		{
+			name:    "output, no priority",
+			content: "O9401200201225BANKDEFFAXXX00000000002012251200",
+			expected: `{"block2":{"direction":"O","input_time":"1200","message_type":"940",` +
+				`"mir":{"date":"201225","sender_address":"BANKDEFFAXXX","sequence_number":"000000",` +
+				`"session_number":"0000"},"output_date":"201225","output_time":"1200"}}`,
+		},
+		{name: "too short", content: "I94", err: "invalid application header block 'I94'"},
+		{name: "input too long", content: "I103BANKDEFFXXXXU30031", err: "invalid application header block 'I103BANKDEFFXXXXU30031'"},
+		{name: "output too short", content: "O9401200", err: "invalid application header block 'O9401200'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := idr.CreateNode(idr.DocumentNode, "")
+			err := addApplicationHeader(root, test.content)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, root.FirstChild)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, idr.JSONify2(root))
+		})
+	}
+}
+
+func TestAddSubBlocks(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		content  string
+		expected string
+		err      string
+	}{
+		{
+			name:     "nested",
+			content:  "{MAC:12345678}{S:{SAC:}{COP:P}}",
+			expected: `{"MAC":"12345678","S":{"COP":"P","SAC":""}}`,
+		},
+		{name: "not terminated", content: "{CHK:ABC", err: "invalid sub-block '{CHK:ABC'"},
+		{name: "no tag", content: "{CHK}", err: "invalid sub-block '{CHK}'"},
+		{name: "invalid tag", content: "{C-K:ABC}", err: "invalid sub-block tag 'C-K'"},
+		{name: "nested error", content: "{S:{SAC}}", err: "invalid sub-block '{SAC}'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := idr.CreateNode(idr.DocumentNode, "")
+			err := addSubBlocks(root, test.content)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, idr.JSONify2(root))
+		})
+	}
+}
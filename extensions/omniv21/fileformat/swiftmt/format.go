@@ -0,0 +1,54 @@
+package swiftmt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/caches"
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+const (
+	fileFormatSwiftMT = "swiftmt"
+)
+
+type swiftMTFileFormat struct {
+	schemaName string
+}
+
+// NewSwiftMTFileFormat creates a FileFormat for SWIFT MT messages, e.g. MT103 or MT940.
+func NewSwiftMTFileFormat(schemaName string) fileformat.FileFormat {
+	return &swiftMTFileFormat{schemaName: schemaName}
+}
+
+func (f *swiftMTFileFormat) ValidateSchema(
+	format string, _ []byte, finalOutputDecl *transform.Decl) (interface{}, error) {
+	if format != fileFormatSwiftMT {
+		return nil, errs.ErrSchemaNotSupported
+	}
+	if finalOutputDecl == nil {
+		return nil, f.FmtErr("'FINAL_OUTPUT' is missing")
+	}
+	xpath := strings.TrimSpace(strs.StrPtrOrElse(finalOutputDecl.XPath, ""))
+	if xpath != "" {
+		_, err := caches.GetXPathExpr(xpath)
+		if err != nil {
+			return nil, f.FmtErr("'FINAL_OUTPUT.xpath' (value: '%s') is invalid, err: %s", xpath, err.Error())
+		}
+	}
+	return xpath, nil
+}
+
+func (f *swiftMTFileFormat) CreateFormatReader(
+	name string, r io.Reader, runtime interface{}) (fileformat.FormatReader, error) {
+	return NewReader(name, r, runtime.(string))
+}
+
+func (f *swiftMTFileFormat) FmtErr(format string, args ...interface{}) error {
+	return fmt.Errorf("schema '%s': %s", f.schemaName, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,65 @@
+package swiftmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+func TestValidateSchema(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		format        string
+		decl          *transform.Decl
+		expectedXPath string
+		expectedErr   string
+	}{
+		{
+			name:        "not supported format",
+			format:      "exe",
+			expectedErr: errs.ErrSchemaNotSupported.Error(),
+		},
+		{
+			name:        "FINAL_OUTPUT decl is nil",
+			format:      fileFormatSwiftMT,
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT' is missing`,
+		},
+		{
+			name:        "FINAL_OUTPUT 'xpath' is invalid",
+			format:      fileFormatSwiftMT,
+			decl:        &transform.Decl{XPath: strs.StrPtr("[invalid")},
+			expectedErr: `schema 'test-schema': 'FINAL_OUTPUT.xpath' (value: '[invalid') is invalid, err: expression must evaluate to a node-set`,
+		},
+		{
+			name:   "success, no xpath",
+			format: fileFormatSwiftMT,
+			decl:   &transform.Decl{},
+		},
+		{
+			name:          "success",
+			format:        fileFormatSwiftMT,
+			decl:          &transform.Decl{XPath: strs.StrPtr(" .[block2/message_type='940'] ")},
+			expectedXPath: ".[block2/message_type='940']",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, err := NewSwiftMTFileFormat("test-schema").ValidateSchema(test.format, nil, test.decl)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+				assert.Nil(t, runtime)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedXPath, runtime)
+			r, err := NewSwiftMTFileFormat("test-schema").CreateFormatReader("test-input", strings.NewReader(""), runtime)
+			assert.NoError(t, err)
+			assert.NotNil(t, r)
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package swiftmt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antchfx/xpath"
+	"github.com/jf-tech/go-corelib/caches"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/idr"
+)
+
+// ErrInvalidSwiftMT indicates the input can't be read, e.g. a block isn't terminated. This is a fatal,
+// non-continuable error. Note errors in a message (e.g. an invalid basic header block) are continuable:
+// the reader simply moves onto the next message.
+type ErrInvalidSwiftMT string
+
+func (e ErrInvalidSwiftMT) Error() string { return string(e) }
+
+// IsErrInvalidSwiftMT checks if the `err` is of ErrInvalidSwiftMT type, or wraps one, e.g. in an
+// errs.ErrInput.
+func IsErrInvalidSwiftMT(err error) bool {
+	var e ErrInvalidSwiftMT
+	return errors.As(err, &e)
+}
+
+// maxBlockSize is the max size of a block, or of a text block without the block structure.
+const maxBlockSize = 64 * 1024 * 1024
+
+type reader struct {
+	inputName   string
+	r           *bufio.Reader
+	targetXPath *xpath.Expr
+	line        int // 1-based number of the line being read.
+	msgBegin    int // number of the first line of the current message.
+	msgEnd      int // number of the last line of the current message.
+}
+
+// Read returns the next message as a record.
+func (r *reader) Read() (*idr.Node, error) {
+	for {
+		n, err := r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if r.targetXPath != nil && !idr.MatchAny(n, r.targetXPath) {
+			idr.RemoveAndReleaseTree(n)
+			continue
+		}
+		return n, nil
+	}
+}
+
+func (r *reader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil && b == '\n' {
+		r.line++
+	}
+	return b, err
+}
+
+func (r *reader) peek() (byte, error) {
+	p, err := r.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return p[0], nil
+}
+
+// skipSeparators skips whatever separates messages: whitespace, the `$` of RJE files and the SOH and
+// ETX characters of messages captured from a network connection.
+func (r *reader) skipSeparators() error {
+	for {
+		b, err := r.peek()
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			return r.invalidSwiftMT(r.line, "unable to read message: %s", err.Error())
+		}
+		if !strings.ContainsRune(" \t\r\n$\x01\x03", rune(b)) {
+			return nil
+		}
+		_, _ = r.readByte()
+	}
+}
+
+func (r *reader) readMessage() (*idr.Node, error) {
+	if err := r.skipSeparators(); err != nil {
+		return nil, err
+	}
+	r.msgBegin, r.msgEnd = r.line, r.line
+	b, _ := r.peek()
+	switch b {
+	case '{':
+		return r.readBlocks()
+	case ':':
+		return r.readTextBlockOnly()
+	default:
+		return nil, r.invalidSwiftMT(r.line, "unexpected character '%c', expected '{' or ':'", b)
+	}
+}
+
+// readBlocks reads the blocks of a message, e.g. `{1:...}{2:...}{4:...-}`, till the next message, i.e.
+// the next basic header block, or anything but a block.
+func (r *reader) readBlocks() (*idr.Node, error) {
+	root := idr.CreateNode(idr.DocumentNode, "")
+	seen := map[string]bool{}
+	var msgErr error
+	for {
+		if b, err := r.peek(); err != nil || b != '{' {
+			break
+		}
+		if p, _ := r.r.Peek(3); len(seen) > 0 && string(p) == "{"+basicHeaderBlockID+":" {
+			// the next message.
+			break
+		}
+		line := r.line
+		id, content, err := r.readBlock()
+		if err != nil {
+			idr.RemoveAndReleaseTree(root)
+			return nil, err
+		}
+		r.msgEnd = r.line
+		if msgErr != nil {
+			// skip the rest of a broken message.
+			continue
+		}
+		if seen[id] {
+			msgErr = errors.New(r.fmtErrStr(line, "duplicate block '%s'", id))
+			continue
+		}
+		seen[id] = true
+		msgErr = r.addBlock(root, id, content, line)
+	}
+	if msgErr != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, msgErr
+	}
+	return root, nil
+}
+
+// readBlock reads a block, e.g. `{4:...-}`, and returns its ID and content.
+func (r *reader) readBlock() (string, string, error) {
+	line := r.line
+	_, _ = r.readByte() // the '{'.
+	var id strings.Builder
+	for {
+		b, err := r.readByte()
+		if err != nil || id.Len() > 3 {
+			return "", "", r.invalidSwiftMT(line, "invalid block '{%s'", id.String())
+		}
+		if b == ':' {
+			break
+		}
+		id.WriteByte(b)
+	}
+	if !blockIDRegexp.MatchString(id.String()) {
+		return "", "", r.invalidSwiftMT(line, "invalid block ID '%s'", id.String())
+	}
+	var content strings.Builder
+	for depth := 1; ; {
+		b, err := r.readByte()
+		if err == io.EOF {
+			return "", "", r.invalidSwiftMT(line, "block '%s' isn't terminated", id.String())
+		}
+		if err != nil {
+			return "", "", r.invalidSwiftMT(r.line, "unable to read block '%s': %s", id.String(), err.Error())
+		}
+		switch b {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			return id.String(), content.String(), nil
+		}
+		if content.Len() >= maxBlockSize {
+			return "", "", r.invalidSwiftMT(line, "block '%s' exceeds %d bytes", id.String(), maxBlockSize)
+		}
+		content.WriteByte(b)
+	}
+}
+
+// addBlock adds the element of a block. line is the line number the block starts at.
+func (r *reader) addBlock(root *idr.Node, id, content string, line int) error {
+	var err error
+	switch {
+	case id == basicHeaderBlockID:
+		err = addBasicHeader(root, content)
+	case id == applicationHeaderBlockID:
+		err = addApplicationHeader(root, content)
+	case strings.HasPrefix(content, "{"):
+		err = addSubBlocks(addElement(root, blockName(id)), content)
+	case id == textBlockID:
+		lines := strings.Split(content, "\n")
+		// the text block ends with a line of '-'.
+		if strings.TrimSuffix(lines[len(lines)-1], "\r") != "-" {
+			err = errors.New("text block doesn't end with '-'")
+			break
+		}
+		err = r.addTextBlock(root, lines[:len(lines)-1], line)
+	default:
+		addText(root, blockName(id), content)
+	}
+	if err != nil {
+		return r.msgErr(err, line)
+	}
+	return nil
+}
+
+// msgErr formats an error in a message, at the line of the error if known, else at the given line.
+func (r *reader) msgErr(err error, line int) error {
+	var le *lineErr
+	if errors.As(err, &le) {
+		line = le.line
+	}
+	return errors.New(r.fmtErrStr(line, "%s", err.Error()))
+}
+
+func (r *reader) addTextBlock(root *idr.Node, lines []string, firstLine int) error {
+	fields, err := parseFields(lines, firstLine)
+	if err != nil {
+		return err
+	}
+	return addTextBlock(root, fields)
+}
+
+// readTextBlockOnly reads a message without the block structure, i.e. just the fields of its text
+// block, as some banks deliver MT940 statements, till a line of '-' or the end of the input. The
+// message is read as if it only had a text block.
+func (r *reader) readTextBlockOnly() (*idr.Node, error) {
+	var lines []string
+	size := 0
+	for {
+		s, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, r.invalidSwiftMT(r.line, "unable to read line: %s", err.Error())
+		}
+		if s == "" {
+			break
+		}
+		r.msgEnd = r.line
+		if strings.HasSuffix(s, "\n") {
+			r.line++
+		}
+		line := strings.TrimRight(s, "\r\n")
+		if line == "-" {
+			break
+		}
+		if size += len(s); size > maxBlockSize {
+			return nil, r.invalidSwiftMT(r.msgBegin, "text block exceeds %d bytes", maxBlockSize)
+		}
+		lines = append(lines, line)
+		if err == io.EOF {
+			break
+		}
+	}
+	root := idr.CreateNode(idr.DocumentNode, "")
+	if err := r.addTextBlock(root, lines, r.msgBegin); err != nil {
+		idr.RemoveAndReleaseTree(root)
+		return nil, r.msgErr(err, r.msgBegin)
+	}
+	return root, nil
+}
+
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of lines the message
+// returned by the last successful Read call spans.
+func (r *reader) RecordPosition() (int, int) {
+	return r.msgBegin, r.msgEnd
+}
+
+func (r *reader) Release(n *idr.Node) {
+	if n != nil {
+		idr.RemoveAndReleaseTree(n)
+	}
+}
+
+func (r *reader) IsContinuableError(err error) bool {
+	return !IsErrInvalidSwiftMT(err) && err != io.EOF
+}
+
+func (r *reader) FmtErr(format string, args ...interface{}) error {
+	return errors.New(r.fmtErrStr(r.msgBegin, format, args...))
+}
+
+func (r *reader) fmtErrStr(line int, format string, args ...interface{}) string {
+	return fmt.Sprintf("input '%s' line %d: %s", r.inputName, line, fmt.Sprintf(format, args...))
+}
+
+// invalidSwiftMT creates an ErrInvalidSwiftMT, wrapped in an errs.ErrInput.
+func (r *reader) invalidSwiftMT(line int, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	return &errs.ErrInput{
+		Format: fileFormatSwiftMT,
+		Input:  r.inputName,
+		Offset: -1,
+		Line:   line,
+		Reason: reason,
+		Err:    ErrInvalidSwiftMT(r.fmtErrStr(line, "%s", reason)),
+	}
+}
+
+// NewReader creates an FormatReader for SWIFT MT file format.
+func NewReader(inputName string, src io.Reader, targetXPath string) (*reader, error) {
+	targetXPathExpr, err := func() (*xpath.Expr, error) {
+		if targetXPath == "" || targetXPath == "." {
+			return nil, nil
+		}
+		return caches.GetXPathExpr(targetXPath)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("invalid target xpath '%s', err: %s", targetXPath, err.Error())
+	}
+	return &reader{
+		inputName:   inputName,
+		r:           bufio.NewReader(src),
+		targetXPath: targetXPathExpr,
+		line:        1,
+	}, nil
+}
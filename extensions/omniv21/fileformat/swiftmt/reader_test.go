@@ -0,0 +1,246 @@
+package swiftmt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func readAll(t *testing.T, r *reader) ([]string, error) {
+	var records []string
+	for {
+		n, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return records, err
+		}
+		records = append(records, idr.JSONify2(n))
+		r.Release(n)
+	}
+}
+
+const (
+	testMT103 = "{1:F01BANKDEFFAXXX0000000000}{2:I103BANKUS33XXXXN}" +
+		"{3:{108:REF1}{121:e5f3b0a2-1c4d-4b8e-9f6a-0b1c2d3e4f50}}" +
+		"{4:\r\n:20:REF1\r\n:32A:201225EUR1000,\r\n:70:LINE1\r\nLINE2\r\n-}{5:{CHK:ABC}{PDE:}}"
+	testMT103JSON = `{"block1":{"application_id":"F","lt_address":"BANKDEFFAXXX","sequence_number":"000000",` +
+		`"service_id":"01","session_number":"0000"},` +
+		`"block2":{"direction":"I","message_type":"103","priority":"N","receiver_address":"BANKUS33XXXX"},` +
+		`"block3":{"F108":"REF1","F121":"e5f3b0a2-1c4d-4b8e-9f6a-0b1c2d3e4f50"},` +
+		`"block4":{"F20":"REF1","F32A":"201225EUR1000,","F70":"LINE1\nLINE2"},` +
+		`"block5":{"CHK":"ABC","PDE":""}}`
+	testMT502 = "{1:F01BANKDEFFAXXX0000000000}{2:O5021200201225BANKDEFFAXXX00000000002012251200N}" +
+		"{4:\r\n:16R:GENL\r\n:20C::SEME//REF2\r\n:22F::SETR/ABCD/TRAD\r\n:16S:GENL\r\n-}{S:{SAC:}{COP:P}}"
+	testMT502JSON = `{"block1":{"application_id":"F","lt_address":"BANKDEFFAXXX","sequence_number":"000000",` +
+		`"service_id":"01","session_number":"0000"},` +
+		`"block2":{"direction":"O","input_time":"1200","message_type":"502",` +
+		`"mir":{"date":"201225","sender_address":"BANKDEFFAXXX","sequence_number":"000000","session_number":"0000"},` +
+		`"output_date":"201225","output_time":"1200","priority":"N"},` +
+		`"block4":{"GENL":{"F20C":{"qualifier":"SEME","value":"REF2"},` +
+		`"F22F":{"issuer":"ABCD","qualifier":"SETR","value":"TRAD"}}},` +
+		`"blockS":{"COP":"P","SAC":""}}`
+)
+
+func TestRead(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    string
+		xpath    string
+		expected []string
+	}{
+		{
+			name:     "multiple messages",
+			input:    testMT103 + "\r\n" + testMT502,
+			expected: []string{testMT103JSON, testMT502JSON},
+		},
+		{
+			name:     "with xpath",
+			input:    testMT103 + testMT502,
+			xpath:    ".[block2/message_type='502']",
+			expected: []string{testMT502JSON},
+		},
+		{
+			name:     "RJE and network framing",
+			input:    "\x01" + testMT103 + "\x03$" + testMT502 + "$\n",
+			expected: []string{testMT103JSON, testMT502JSON},
+		},
+		{
+			name:  "text block only",
+			input: ":20:STMT1\n:25:ACC\n-\n:20:STMT2\n:86:INFO\n",
+			expected: []string{
+				`{"block4":{"F20":"STMT1","F25":"ACC"}}`,
+				`{"block4":{"F20":"STMT2","F86":"INFO"}}`,
+			},
+		},
+		{
+			name:  "system message with sub-blocks as text block",
+			input: "{1:F21BANKDEFFAXXX0000000000}{4:{177:2012251200}{451:0}}",
+			expected: []string{`{"block1":{"application_id":"F","lt_address":"BANKDEFFAXXX","sequence_number":"000000",` +
+				`"service_id":"21","session_number":"0000"},"block4":{"F177":"2012251200","F451":"0"}}`},
+		},
+		{
+			name:     "no message",
+			input:    " \r\n",
+			expected: nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), test.xpath)
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestRead_MessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		msg         string
+		expErr      string
+		nextMsgLine int
+	}{
+		{
+			name:        "invalid basic header",
+			msg:         "{1:F01BANK}{4:\n:20:X\n-}\n",
+			expErr:      "input 'test-input' line 1: invalid basic header block 'F01BANK', expected 25 characters",
+			nextMsgLine: 4,
+		},
+		{
+			name:        "invalid application header",
+			msg:         "{1:F01BANKDEFFAXXX0000000000}{2:X103}\n",
+			expErr:      "input 'test-input' line 1: invalid application header block 'X103'",
+			nextMsgLine: 2,
+		},
+		{
+			name:        "duplicate block",
+			msg:         "{1:F01BANKDEFFAXXX0000000000}{4:\n:20:X\n-}{4:\n:20:Y\n-}\n",
+			expErr:      "input 'test-input' line 3: duplicate block '4'",
+			nextMsgLine: 6,
+		},
+		{
+			name:        "text block not ending with '-'",
+			msg:         "{1:F01BANKDEFFAXXX0000000000}{4:\n:20:X\n}\n",
+			expErr:      "input 'test-input' line 1: text block doesn't end with '-'",
+			nextMsgLine: 4,
+		},
+		{
+			name:        "line before first field",
+			msg:         "{1:F01BANKDEFFAXXX0000000000}{4:\nhello\n:20:X\n-}\n",
+			expErr:      "input 'test-input' line 2: unexpected line 'hello' before the first field",
+			nextMsgLine: 5,
+		},
+		{
+			name:        "sequence not ended",
+			msg:         ":16R:GENL\n:20C::SEME//X\n-\n",
+			expErr:      "input 'test-input' line 1: sequence 'GENL' isn't ended",
+			nextMsgLine: 4,
+		},
+		{
+			name:        "sequence end mismatch",
+			msg:         ":16R:GENL\n:16S:LINK\n-\n",
+			expErr:      "input 'test-input' line 2: ':16S:LINK' doesn't end the current sequence",
+			nextMsgLine: 4,
+		},
+		{
+			name:        "invalid sub-block",
+			msg:         "{1:F01BANKDEFFAXXX0000000000}{5:{CHK:ABC}x}\n",
+			expErr:      "input 'test-input' line 1: invalid sub-block 'x'",
+			nextMsgLine: 2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.msg+testMT103), "")
+			assert.NoError(t, err)
+			n, err := r.Read()
+			assert.Error(t, err)
+			assert.True(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, n)
+			// reader moves onto the next message.
+			n, err = r.Read()
+			assert.NoError(t, err)
+			assert.Equal(t, testMT103JSON, idr.JSONify2(n))
+			assert.Equal(t, fmt.Sprintf("input 'test-input' line %d: test", test.nextMsgLine), r.FmtErr("test").Error())
+			begin, end := r.RecordPosition()
+			assert.Equal(t, test.nextMsgLine, begin)
+			assert.Equal(t, test.nextMsgLine+5, end)
+		})
+	}
+}
+
+func TestRead_InvalidSwiftMT(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		input  string
+		expErr string
+	}{
+		{
+			name:   "unexpected character",
+			input:  testMT103 + "\nhello",
+			expErr: "input 'test-input' line 7: unexpected character 'h', expected '{' or ':'",
+		},
+		{
+			name:   "block not terminated",
+			input:  testMT103 + "\n{1:F01",
+			expErr: "input 'test-input' line 7: block '1' isn't terminated",
+		},
+		{
+			name:   "invalid block ID",
+			input:  testMT103 + "\n{1234:X}",
+			expErr: "input 'test-input' line 7: invalid block '{1234'",
+		},
+		{
+			name:   "invalid block ID character",
+			input:  testMT103 + "\n{#:X}",
+			expErr: "input 'test-input' line 7: invalid block ID '#'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := NewReader("test-input", strings.NewReader(test.input), "")
+			assert.NoError(t, err)
+			records, err := readAll(t, r)
+			assert.Equal(t, []string{testMT103JSON}, records)
+			assert.Error(t, err)
+			assert.True(t, IsErrInvalidSwiftMT(err))
+			assert.False(t, r.IsContinuableError(err))
+			assert.Equal(t, test.expErr, err.Error())
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestRead_ReadFailure(t *testing.T) {
+	r, err := NewReader("test-input", io.MultiReader(strings.NewReader("{1:F01"), failingReader{}), "")
+	assert.NoError(t, err)
+	_, err = readAll(t, r)
+	assert.Error(t, err)
+	assert.True(t, IsErrInvalidSwiftMT(err))
+	assert.Equal(t, "input 'test-input' line 1: unable to read block '1': read failure", err.Error())
+}
+
+func TestNewReader_InvalidXPath(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader(""), "[invalid")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid target xpath '[invalid', err: expression must evaluate to a node-set", err.Error())
+	assert.Nil(t, r)
+}
+
+func TestIsContinuableError(t *testing.T) {
+	r := &reader{}
+	assert.False(t, r.IsContinuableError(ErrInvalidSwiftMT("test")))
+	assert.False(t, r.IsContinuableError(io.EOF))
+	assert.True(t, r.IsContinuableError(errors.New("test")))
+}
@@ -0,0 +1,47 @@
+package fileformat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TrimPolicy specifies how whitespaces in a value ingested from input are treated before the value is
+// placed into the IDR.
+type TrimPolicy string
+
+const (
+	// TrimNone leaves values as is. This is the default.
+	TrimNone TrimPolicy = "none"
+	// TrimRight removes trailing whitespaces, e.g. the space padding of fixed-length values.
+	TrimRight TrimPolicy = "right"
+	// TrimBoth removes leading and trailing whitespaces.
+	TrimBoth TrimPolicy = "both"
+	// TrimCollapse removes leading and trailing whitespaces, and collapses each run of internal
+	// whitespaces into a single space.
+	TrimCollapse TrimPolicy = "collapse"
+)
+
+// ResolveTrimPolicy returns the first specified policy, from the most specific (e.g. column or element
+// level) to the least specific (e.g. file_declaration level). TrimNone is returned if none is specified.
+func ResolveTrimPolicy(policies ...*string) TrimPolicy {
+	for _, p := range policies {
+		if p != nil {
+			return TrimPolicy(*p)
+		}
+	}
+	return TrimNone
+}
+
+// Apply applies the trim policy to a value.
+func (p TrimPolicy) Apply(s string) string {
+	switch p {
+	case TrimRight:
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	case TrimBoth:
+		return strings.TrimSpace(s)
+	case TrimCollapse:
+		return strings.Join(strings.Fields(s), " ")
+	default:
+		return s
+	}
+}
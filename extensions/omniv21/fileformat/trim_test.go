@@ -0,0 +1,24 @@
+package fileformat
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveTrimPolicy(t *testing.T) {
+	assert.Equal(t, TrimNone, ResolveTrimPolicy())
+	assert.Equal(t, TrimNone, ResolveTrimPolicy(nil, nil))
+	assert.Equal(t, TrimBoth, ResolveTrimPolicy(nil, strs.StrPtr("both")))
+	assert.Equal(t, TrimRight, ResolveTrimPolicy(strs.StrPtr("right"), strs.StrPtr("both")))
+}
+
+func TestTrimPolicy_Apply(t *testing.T) {
+	s := " \t a  b \t c  "
+	assert.Equal(t, s, TrimNone.Apply(s))
+	assert.Equal(t, " \t a  b \t c", TrimRight.Apply(s))
+	assert.Equal(t, "a  b \t c", TrimBoth.Apply(s))
+	assert.Equal(t, "a b c", TrimCollapse.Apply(s))
+	assert.Equal(t, s, TrimPolicy("unknown").Apply(s))
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/idr"
 )
 
@@ -14,19 +16,18 @@ type ErrNodeReadingFailed string
 
 func (e ErrNodeReadingFailed) Error() string { return string(e) }
 
-// IsErrNodeReadingFailed checks if the `err` is of ErrNodeReadingFailed type.
+// IsErrNodeReadingFailed checks if the `err` is of ErrNodeReadingFailed type, or wraps one, e.g. in an errs.ErrInput.
 func IsErrNodeReadingFailed(err error) bool {
-	switch err.(type) {
-	case ErrNodeReadingFailed:
-		return true
-	default:
-		return false
-	}
+	var e ErrNodeReadingFailed
+	return errors.As(err, &e)
 }
 
 type reader struct {
 	inputName string
+	pr        *fileformat.PositionReader
 	r         *idr.XMLStreamReader
+	posBegin  fileformat.Position // source position of the record returned by the last Read call.
+	posEnd    fileformat.Position
 }
 
 func (r *reader) Read() (*idr.Node, error) {
@@ -35,11 +36,23 @@ func (r *reader) Read() (*idr.Node, error) {
 		return nil, io.EOF
 	}
 	if err != nil {
-		return nil, ErrNodeReadingFailed(r.fmtErrStr(err.Error()))
+		return nil, r.readingFailed(err.Error())
 	}
+	r.posBegin, r.posEnd = r.pr.Position(r.r.StreamStartOffset()), r.pr.Position(r.r.InputOffset())
 	return n, nil
 }
 
+// RecordPosition implements fileformat.RecordPositionReporter, returning the range of lines the
+// record returned by the last successful Read call spans, from its start element to its end element.
+func (r *reader) RecordPosition() (int, int) {
+	return r.posBegin.Line, r.posEnd.Line
+}
+
+// SourcePosition implements fileformat.SourcePositionReporter.
+func (r *reader) SourcePosition() (fileformat.Position, fileformat.Position) {
+	return r.posBegin, r.posEnd
+}
+
 func (r *reader) Release(n *idr.Node) {
 	if n != nil {
 		r.r.Release(n)
@@ -55,14 +68,33 @@ func (r *reader) FmtErr(format string, args ...interface{}) error {
 }
 
 func (r *reader) fmtErrStr(format string, args ...interface{}) string {
-	return fmt.Sprintf("input '%s' near line %d: %s", r.inputName, r.r.AtLine(), fmt.Sprintf(format, args...))
+	return errStr(r.inputName, r.pr.Position(r.r.InputOffset()), fmt.Sprintf(format, args...))
+}
+
+func errStr(inputName string, pos fileformat.Position, reason string) string {
+	return fmt.Sprintf("input '%s' near %s: %s", inputName, pos, reason)
+}
+
+// readingFailed creates an ErrNodeReadingFailed, wrapped in an errs.ErrInput, at the current position.
+func (r *reader) readingFailed(reason string) error {
+	pos := r.pr.Position(r.r.InputOffset())
+	return &errs.ErrInput{
+		Format: fileFormatXML,
+		Input:  r.inputName,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Offset: pos.Offset,
+		Reason: reason,
+		Err:    ErrNodeReadingFailed(errStr(r.inputName, pos, reason)),
+	}
 }
 
 // NewReader creates an FormatReader for XML file format.
 func NewReader(inputName string, src io.Reader, xpath string) (*reader, error) {
-	sp, err := idr.NewXMLStreamReader(src, xpath)
+	pr := fileformat.NewPositionReader(src)
+	sp, err := idr.NewXMLStreamReader(pr, xpath)
 	if err != nil {
 		return nil, err
 	}
-	return &reader{inputName: inputName, r: sp}, nil
+	return &reader{inputName: inputName, pr: pr, r: sp}, nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 )
 
 func TestIsErrNodeReadingFailed(t *testing.T) {
@@ -66,7 +67,7 @@ func TestReader_Read_InvalidXML(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, IsErrNodeReadingFailed(err))
 	assert.Equal(t,
-		`input 'test-input' near line 5: XML syntax error on line 5: element <Node> closed by </Root>`,
+		`input 'test-input' near line 5, col 11, byte offset 58: XML syntax error on line 5: element <Node> closed by </Root>`,
 		err.Error())
 	assert.Nil(t, n)
 }
@@ -76,7 +77,7 @@ func TestReader_FmtErr(t *testing.T) {
 	assert.NoError(t, err)
 	err = r.FmtErr("golang is %s", "fun")
 	assert.Error(t, err)
-	assert.Equal(t, `input 'test-input' near line 1: golang is fun`, err.Error())
+	assert.Equal(t, `input 'test-input' near line 1, col 1, byte offset 0: golang is fun`, err.Error())
 }
 
 func TestReader_IsContinuableError(t *testing.T) {
@@ -96,3 +97,38 @@ func TestNewReader_InvalidXPath(t *testing.T) {
 		err.Error())
 	assert.Nil(t, r)
 }
+
+func TestReader_RecordPosition(t *testing.T) {
+	r, err := NewReader(
+		"test-input",
+		strings.NewReader("<Root>\n<Node>\n1\n</Node>\n<Node>2</Node>\n</Root>"),
+		"Root/Node")
+	assert.NoError(t, err)
+	begin, end := r.RecordPosition()
+	assert.Equal(t, 0, begin)
+	assert.Equal(t, 0, end)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 2, begin)
+	assert.Equal(t, 4, end)
+	// Ranges of consecutive records must not overlap.
+	n, err = r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end = r.RecordPosition()
+	assert.Equal(t, 5, begin)
+	assert.Equal(t, 5, end)
+}
+
+func TestReader_SourcePosition(t *testing.T) {
+	r, err := NewReader("test-input", strings.NewReader("<Root>\n  <Node>1</Node>\n</Root>"), "Root/Node")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	r.Release(n)
+	begin, end := r.SourcePosition()
+	assert.Equal(t, fileformat.Position{Offset: 9, Line: 2, Column: 3}, begin)
+	assert.Equal(t, fileformat.Position{Offset: 23, Line: 2, Column: 17}, end)
+}
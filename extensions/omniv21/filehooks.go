@@ -0,0 +1,92 @@
+package omniv21
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// fileHooker emits the `file_header` record right after the first record of the input is read, ahead
+// of it, and the `file_trailer` record upon io.EOF, both evaluated on the root of the input's IDR
+// tree, or, if the records have no parent (e.g. csv) or there is no record at all, on an empty
+// document node. Neither is emitted after a fatal error.
+type fileHooker struct {
+	hooks       *transform.FileHooks
+	headerDone  bool
+	trailerDone bool
+	// the outcome of the read the header is emitted ahead of, returned upon the next read.
+	pending       bool
+	pendingRecord schemahandler.RawRecord
+	pendingOutput []byte
+	pendingErr    error
+	hookRecord    rawRecord
+}
+
+func newFileHooker(hooks *transform.FileHooks) *fileHooker {
+	return &fileHooker{hooks: hooks}
+}
+
+func (h *fileHooker) read(g *ingester) (schemahandler.RawRecord, []byte, error) {
+	var record schemahandler.RawRecord
+	var output []byte
+	var err error
+	if h.pending {
+		h.pending = false
+		record, output, err = h.pendingRecord, h.pendingOutput, h.pendingErr
+	} else {
+		record, output, err = g.read()
+		if err != nil && err != io.EOF && !g.IsContinuableError(err) {
+			return nil, nil, err
+		}
+	}
+	if !h.headerDone && (g.rawRecord.ordinal > 0 || err == io.EOF) {
+		h.headerDone = true
+		if h.hooks.Header != nil {
+			h.pending, h.pendingRecord, h.pendingOutput, h.pendingErr = true, record, output, err
+			return h.eval(g, h.hooks.Header, transformctx.RecordCounters{})
+		}
+	}
+	if err == io.EOF && !h.trailerDone {
+		h.trailerDone = true
+		if h.hooks.Trailer != nil {
+			// the counters of the last record read are the ones of the whole input.
+			counters := g.counters
+			counters.NumberInGroup = 0
+			return h.eval(g, h.hooks.Trailer, counters)
+		}
+	}
+	return record, output, err
+}
+
+// eval evaluates a hook decl on the root of the input's IDR tree, with the given record counters.
+func (h *fileHooker) eval(
+	g *ingester, decl *transform.Decl, counters transformctx.RecordCounters) (schemahandler.RawRecord, []byte, error) {
+
+	root := g.root
+	if root == nil {
+		root = idr.CreateNode(idr.DocumentNode, "")
+	}
+	h.hookRecord = rawRecord{node: root}
+	ctx := transformctx.Ctx{}
+	if g.ctx != nil {
+		ctx = *g.ctx
+	}
+	ctx.RecordID = h.hookRecord.RecordID
+	ctx.Counters = counters
+	parseCtx := transform.NewParseCtx(&ctx, g.customFuncs, g.customParseFuncs).
+		WithStats(g.stats).WithVars(g.contextVars)
+	result, err := parseCtx.ParseNode(root, decl)
+	if err != nil {
+		return nil, nil, errs.ErrTransformFailed(g.fmtErrStr("fail to transform. err: %s", err.Error()))
+	}
+	output, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &h.hookRecord, output, nil
+}
@@ -0,0 +1,221 @@
+package omniv21
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/customfuncs"
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func createFileHooksSchemaHandler(hooks string) (schemahandler.SchemaHandler, error) {
+	return CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "json"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "xpath": "/batch/items/*", "object": { "n": { "xpath": "n", "type": "int" } } }
+			}` + hooks + `
+		}`),
+		CustomFuncs: customfuncs.Merge(customfuncs.CommonCustomFuncs, v21.OmniV21CustomFuncs),
+	})
+}
+
+const fileHooksTestHooks = `,
+	"file_header": { "xpath": "batch", "object": {
+		"type": { "const": "header" },
+		"batch_id": { "xpath": "id" },
+		"input": { "external": "input" }
+	} },
+	"file_trailer": { "object": {
+		"type": { "const": "trailer" },
+		"batch_id": { "xpath": "batch/id" },
+		"declared_total": { "xpath": "batch/total", "type": "int" },
+		"records": { "custom_func": { "name": "record_number", "ignore_error": true }, "type": "int" },
+		"emitted": { "custom_func": { "name": "emitted_count", "ignore_error": true }, "type": "int" }
+	} }`
+
+// readAll reads all the records, with continuable errors as "error", until io.EOF or a fatal error.
+func readAll(t *testing.T, g schemahandler.Ingester) ([]string, error) {
+	var records []string
+	for {
+		raw, transformed, err := g.Read()
+		switch {
+		case err == io.EOF:
+			// io.EOF is returned repeatedly.
+			_, _, err = g.Read()
+			assert.Equal(t, io.EOF, err)
+			return records, nil
+		case err != nil && g.IsContinuableError(err):
+			records = append(records, "error")
+		case err != nil:
+			return records, err
+		default:
+			assert.NotNil(t, raw)
+			records = append(records, string(transformed))
+		}
+	}
+}
+
+func TestIngester_Read_FileHooks(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		hooks    string
+		input    string
+		expected []string
+		err      string
+	}{
+		{
+			name:  "header and trailer",
+			hooks: fileHooksTestHooks,
+			input: `{"batch":{"id":"B1","items":[{"n":"1"},{"n":"x"},{"n":"3"}],"total":"3"}}`,
+			expected: []string{
+				`{"batch_id":"B1","input":"test-input","type":"header"}`,
+				`{"n":1}`,
+				"error",
+				`{"n":3}`,
+				`{"batch_id":"B1","declared_total":3,"emitted":2,"records":3,"type":"trailer"}`,
+			},
+		},
+		{
+			name:  "first record fails",
+			hooks: fileHooksTestHooks,
+			input: `{"batch":{"id":"B1","items":[{"n":"x"}]}}`,
+			expected: []string{
+				`{"batch_id":"B1","input":"test-input","type":"header"}`,
+				"error",
+				`{"batch_id":"B1","emitted":0,"records":1,"type":"trailer"}`,
+			},
+		},
+		{
+			name:  "no records",
+			hooks: fileHooksTestHooks,
+			input: `{"batch":{"id":"B1","items":[]}}`,
+			// with no record, the hooks are evaluated on an empty document node, and the counters
+			// aren't available.
+			expected: []string{"null", `{"type":"trailer"}`},
+		},
+		{
+			name:     "header only",
+			hooks:    `, "file_header": { "const": "header" }`,
+			input:    `{"batch":{"items":[{"n":"1"}]}}`,
+			expected: []string{`"header"`, `{"n":1}`},
+		},
+		{
+			name:     "trailer only",
+			hooks:    `, "file_trailer": { "const": "trailer" }`,
+			input:    `{"batch":{"items":[{"n":"1"}]}}`,
+			expected: []string{`{"n":1}`, `"trailer"`},
+		},
+		{
+			name:     "hook failure is continuable",
+			hooks:    `, "file_header": { "xpath": "batch/id", "type": "int" }`,
+			input:    `{"batch":{"id":"B1","items":[{"n":"1"}]}}`,
+			expected: []string{"error", `{"n":1}`},
+		},
+		{
+			name:     "no hooks after a fatal error",
+			hooks:    fileHooksTestHooks,
+			input:    `{"batch":{"id":"B1","items":[{"n":"1"},{"n"]}}`,
+			expected: []string{`{"batch_id":"B1","input":"test-input","type":"header"}`, `{"n":1}`},
+			err:      "input 'test-input' before/near line 1, col 44, byte offset 43: invalid character ']' after object key",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := createFileHooksSchemaHandler(test.hooks)
+			assert.NoError(t, err)
+			g, err := h.NewIngester(&transformctx.Ctx{
+				InputName:          "test-input",
+				ExternalProperties: map[string]string{"input": "test-input"},
+			}, strings.NewReader(test.input))
+			assert.NoError(t, err)
+			if !assert.NoError(t, err) {
+				return
+			}
+			records, err := readAll(t, g)
+			assert.Equal(t, test.expected, records)
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.err, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestIngester_Read_FileHooksAndRecordOrder(t *testing.T) {
+	h, err := createFileHooksSchemaHandler(fileHooksTestHooks + `,
+		"record_order": { "key": { "xpath": "n", "type": "int" }, "window": 10 }`)
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{
+		InputName:          "test-input",
+		ExternalProperties: map[string]string{"input": "test-input"},
+	}, strings.NewReader(`{"batch":{"id":"B1","items":[{"n":"3"},{"n":"1"},{"n":"2"}],"total":"3"}}`))
+	assert.NoError(t, err)
+	records, err := readAll(t, g)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"batch_id":"B1","input":"test-input","type":"header"}`,
+		`{"n":1}`,
+		`{"n":2}`,
+		`{"n":3}`,
+		`{"batch_id":"B1","declared_total":3,"emitted":3,"records":3,"type":"trailer"}`,
+	}, records)
+}
+
+func TestIngester_Read_FileHooksSkipUnreferencedColumns(t *testing.T) {
+	h, err := CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "csv2"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+			"file_declaration": {
+				"delimiter": ",",
+				"skip_unreferenced_columns": true,
+				"records": [
+					{ "name": "head", "header": "^H", "min": 1, "max": 1,
+						"columns": [ { "name": "batch_id", "index": 2 } ] },
+					{ "name": "item", "header": "^I", "is_target": true,
+						"columns": [ { "name": "n", "index": 2 } ] },
+					{ "name": "tail", "header": "^T", "min": 1, "max": 1,
+						"columns": [ { "name": "total", "index": 2 } ] }
+				]
+			},
+			"transform_declarations": { "FINAL_OUTPUT": { "object": { "n": { "xpath": "n" } } } },
+			"file_header": { "object": { "batch_id": { "xpath": "head/batch_id" } } },
+			"file_trailer": { "object": { "total": { "xpath": "tail/total" } } }
+		}`),
+		CustomFuncs: customfuncs.CommonCustomFuncs,
+	})
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader("H,B1\nI,1\nI,2\nT,2\n"))
+	assert.NoError(t, err)
+	records, err := readAll(t, g)
+	assert.NoError(t, err)
+	// the columns only referenced by the hooks aren't skipped.
+	assert.Equal(t, []string{`{"batch_id":"B1"}`, `{"n":"1"}`, `{"n":"2"}`, `{"total":"2"}`}, records)
+}
+
+func TestCreateSchemaHandler_FileHooksFailure(t *testing.T) {
+	_, err := createFileHooksSchemaHandler(`, "file_trailer": { "custom_func": { "name": "no_such_func" } }`)
+	assert.Error(t, err)
+	assert.Equal(t,
+		"schema 'test-schema' 'file_header'/'file_trailer' validation failed: unknown custom_func 'no_such_func' on 'file_trailer'",
+		err.Error())
+	_, err = createFileHooksSchemaHandler(`, "file_header": { "array": [] }`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "file_header")
+}
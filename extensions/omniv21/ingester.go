@@ -3,6 +3,7 @@ package omniv21
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/errs"
@@ -14,7 +15,15 @@ import (
 )
 
 type rawRecord struct {
-	node *idr.Node
+	node               *idr.Node
+	ordinal            int                 // 1-based ordinal of the record in the input stream.
+	posBegin, posEnd   int                 // source position range, if reported by the FormatReader.
+	srcBegin, srcEnd   fileformat.Position // source byte/line/column range, if hasSrcPos.
+	hasSrcPos          bool
+	bytes              []byte // raw bytes, if the FormatReader's IDR node doesn't contain all the data.
+	rawBytes           []byte // raw bytes as read from the input, if reported by the FormatReader.
+	ownRawBytes        bool   // if true, RawBytes returns a copy owned by the caller.
+	checksum, recordID string
 }
 
 func (rr *rawRecord) Raw() interface{} {
@@ -23,8 +32,82 @@ func (rr *rawRecord) Raw() interface{} {
 
 // Checksum returns a stable MD5(v3) hash of the rawRecord.
 func (rr *rawRecord) Checksum() string {
-	hash, _ := customfuncs.UUIDv3(nil, idr.JSONify2(rr.node))
-	return hash
+	if rr.checksum == "" && rr.bytes != nil {
+		rr.checksum, _ = customfuncs.UUIDv3(nil, string(rr.bytes))
+	}
+	if rr.checksum == "" {
+		rr.checksum, _ = customfuncs.UUIDv3(nil, idr.JSONify2(rr.node))
+	}
+	return rr.checksum
+}
+
+// RecordID returns a deterministic MD5(v3) ID of the rawRecord composed of its checksum, ordinal
+// and source position range.
+func (rr *rawRecord) RecordID() string {
+	if rr.recordID == "" {
+		rr.recordID, _ = customfuncs.UUIDv3(nil,
+			fmt.Sprintf("%s/%d/%d-%d", rr.Checksum(), rr.ordinal, rr.posBegin, rr.posEnd))
+	}
+	return rr.recordID
+}
+
+// RecordPosition implements schemahandler.RecordPositioner.
+func (rr *rawRecord) RecordPosition() (ordinal, begin, end int) {
+	return rr.ordinal, rr.posBegin, rr.posEnd
+}
+
+// RecordOffsets implements schemahandler.RecordOffsetter.
+func (rr *rawRecord) RecordOffsets() (begin, end int64, ok bool) {
+	return rr.srcBegin.Offset, rr.srcEnd.Offset, rr.hasSrcPos
+}
+
+// RawBytes returns the raw bytes of the rawRecord as read from the input, or nil if the FormatReader
+// doesn't report them. See schemahandler.RawBytesRecord for the lifetime of the returned slice.
+func (rr *rawRecord) RawBytes() []byte {
+	if rr.rawBytes == nil || !rr.ownRawBytes {
+		return rr.rawBytes
+	}
+	return append([]byte(nil), rr.rawBytes...)
+}
+
+// SourceJSON implements schemahandler.SourcePreviewer.
+func (rr *rawRecord) SourceJSON() string {
+	return idr.JSONify2(rr.node)
+}
+
+// ContextJSON implements schemahandler.SourcePreviewer. The context of a record is the IDR tree the
+// reader keeps around it, e.g. its envelopes, without the record.
+func (rr *rawRecord) ContextJSON() string {
+	if rr.node.Parent == nil {
+		return ""
+	}
+	root := rr.node.Parent
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return idr.JSONify2(copyTreeWithout(root, rr.node))
+}
+
+// copyTreeWithout returns a copy of a node and its subtree, like idr.CopyTree, less the subtree of
+// the node excluded.
+func copyTreeWithout(n, excluded *idr.Node) *idr.Node {
+	c := idr.CreateNode(n.Type, n.Data)
+	c.FormatSpecific = n.FormatSpecific
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child != excluded {
+			idr.AddChild(c, copyTreeWithout(child, excluded))
+		}
+	}
+	return c
+}
+
+func (rr *rawRecord) reset() {
+	rr.node = nil
+	// ordinal is deliberately kept: it counts records across the whole input stream.
+	rr.posBegin, rr.posEnd = 0, 0
+	rr.hasSrcPos = false
+	rr.bytes, rr.rawBytes = nil, nil
+	rr.checksum, rr.recordID = "", ""
 }
 
 type ingester struct {
@@ -34,35 +117,247 @@ type ingester struct {
 	ctx              *transformctx.Ctx
 	reader           fileformat.FormatReader
 	rawRecord        rawRecord
+	recordCtx        transformctx.Ctx // per-record copy of ctx, so caller's ctx is never mutated.
+	indexRecords     bool
+	rawRecordOnly    bool // if true, records are only ingested, not transformed.
+	counters         transformctx.RecordCounters
+	groupID          int64                    // ID of the parent node of the previous record, for counters.NumberInGroup.
+	resequencer      *resequencer             // nil if the schema has no `record_order`.
+	fileHooker       *fileHooker              // nil if the schema has neither `file_header` nor `file_trailer`.
+	root             *idr.Node                // root of the IDR tree of the records, nil if they have no parent.
+	recordContext    *transform.RecordContext // nil if the schema has no `record_context`.
+	contextVars      map[string]interface{}   // values of the `record_context`, nil until captured.
+	warnings         []error                  // warnings raised by the reader during the current Read call.
+	sortStats        schemahandler.SpillStats // spilled by the `input_sort`, if any.
+	inputSorted      bool                     // the readers read the input sorted by the `input_sort`.
+	stats            *transform.Stats         // nil unless transformctx.Ctx.CollectStats is on.
+	sampler          *sampler                 // nil unless transformctx.Ctx.Sampling is set.
 }
 
 // Read ingests a raw record from the input stream, transforms it according the given schema and return
 // the raw record, transformed JSON bytes.
 func (g *ingester) Read() (schemahandler.RawRecord, []byte, error) {
+	g.warnings = nil
+	if g.rawRecordOnly {
+		if _, err := g.next(); err != nil {
+			return nil, nil, err
+		}
+		g.counters.Emitted++
+		return &g.rawRecord, nil, nil
+	}
+	if g.fileHooker != nil {
+		return g.fileHooker.read(g)
+	}
+	return g.read()
+}
+
+func (g *ingester) read() (schemahandler.RawRecord, []byte, error) {
+	if g.resequencer != nil {
+		return g.resequencer.read(g)
+	}
+	transformed, _, err := g.ingest()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &g.rawRecord, transformed, nil
+}
+
+// ingest ingests a raw record from the input stream, transforms it, and evaluates the extra decls,
+// if any, on it. It returns the transformed JSON bytes and the values of the extra decls.
+func (g *ingester) ingest(decls ...*transform.Decl) ([]byte, []interface{}, error) {
+	n, err := g.next()
+	if err != nil {
+		// next() supposed to have already done CtxAwareErr error wrapping. So directly return.
+		return nil, nil, err
+	}
+	parseCtx := transform.NewParseCtx(&g.recordCtx, g.customFuncs, g.customParseFuncs).
+		WithStats(g.stats).WithVars(g.contextVars)
+	if g.indexRecords {
+		parseCtx.WithIndex(idr.NewIndex(n))
+	}
+	result, err := parseCtx.ParseNode(n, g.finalOutputDecl)
+	if err != nil {
+		// ParseNode() error not CtxAwareErr wrapped, so wrap it.
+		// Note errs.ErrorTransformFailed is a continuable error.
+		return nil, nil, errs.ErrTransformFailed(g.fmtErrStr("fail to transform. err: %s", err.Error()))
+	}
+	values := make([]interface{}, len(decls))
+	for i, decl := range decls {
+		values[i], err = parseCtx.ParseNode(n, decl)
+		if err != nil {
+			return nil, nil, errs.ErrTransformFailed(g.fmtErrStr("fail to transform. err: %s", err.Error()))
+		}
+	}
+	transformed, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	g.counters.Emitted++
+	return transformed, values, nil
+}
+
+// next ingests the next raw record sampled, if sampling, from the input stream, and sets up the
+// per-record ctx for it.
+func (g *ingester) next() (*idr.Node, error) {
+	for {
+		n, err := g.nextRecord()
+		if err != nil || g.sampler == nil || g.sampler.sampled(g.counters.Number) {
+			return n, err
+		}
+	}
+}
+
+// nextRecord ingests the next raw record from the input stream, and sets up the per-record ctx for it.
+func (g *ingester) nextRecord() (*idr.Node, error) {
 	if g.rawRecord.node != nil {
 		g.reader.Release(g.rawRecord.node)
-		g.rawRecord.node = nil
 	}
+	g.rawRecord.reset()
 	n, err := g.reader.Read()
 	if n != nil {
 		g.rawRecord.node = n
 	}
+	if wr, ok := g.reader.(fileformat.WarningReporter); ok {
+		g.warnings = append(g.warnings, wr.Warnings()...)
+	}
 	if err != nil {
 		// Read() supposed to have already done CtxAwareErr error wrapping. So directly return.
-		return nil, nil, err
+		return nil, err
+	}
+	g.rawRecord.ordinal++
+	if g.root == nil && n.Parent != nil {
+		// readers keep the ancestors of the records, e.g. the envelopes, in the tree till the end.
+		g.root = n.Parent
+		for g.root.Parent != nil {
+			g.root = g.root.Parent
+		}
+	}
+	if pr, ok := g.reader.(fileformat.RecordPositionReporter); ok {
+		g.rawRecord.posBegin, g.rawRecord.posEnd = pr.RecordPosition()
+	}
+	if sr, ok := g.reader.(fileformat.SourcePositionReporter); ok {
+		g.rawRecord.srcBegin, g.rawRecord.srcEnd = sr.SourcePosition()
+		g.rawRecord.hasSrcPos = true
+	}
+	if br, ok := g.reader.(fileformat.RecordBytesReporter); ok {
+		g.rawRecord.bytes = br.RecordBytes()
 	}
-	result, err := transform.NewParseCtx(g.ctx, g.customFuncs, g.customParseFuncs).ParseNode(n, g.finalOutputDecl)
+	if rr, ok := g.reader.(fileformat.RawBytesReporter); ok {
+		g.rawRecord.rawBytes = rr.RawBytes()
+	}
+	g.recordCtx = transformctx.Ctx{}
+	if g.ctx != nil {
+		g.recordCtx = *g.ctx
+	}
+	g.recordCtx.RecordID = g.rawRecord.RecordID
+	g.count(n)
+	g.recordCtx.Counters = g.counters
+	if g.recordContext != nil && g.contextVars == nil {
+		if err := g.captureRecordContext(); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// captureRecordContext captures the values of the `record_context` on the root of the input's IDR
+// tree, or, if the records have no parent (e.g. csv), on an empty document node. It's done upon the
+// first record, thus has access to the envelope data read so far, e.g. the ISA/GS segments of an EDI
+// input. A failure fails the record, and the capture is re-attempted upon the next record.
+func (g *ingester) captureRecordContext() error {
+	root := g.root
+	if root == nil {
+		root = idr.CreateNode(idr.DocumentNode, "")
+	}
+	parseCtx := transform.NewParseCtx(&g.recordCtx, g.customFuncs, g.customParseFuncs).WithStats(g.stats)
+	values, err := parseCtx.Capture(root, g.recordContext)
 	if err != nil {
-		// ParseNode() error not CtxAwareErr wrapped, so wrap it.
-		// Note errs.ErrorTransformFailed is a continuable error.
-		return nil, nil, errs.ErrTransformFailed(g.fmtErrStr("fail to transform. err: %s", err.Error()))
+		return errs.ErrTransformFailed(g.fmtErrStr("fail to capture 'record_context'. err: %s", err.Error()))
 	}
-	transformed, err := json.Marshal(result)
-	return &g.rawRecord, transformed, err
+	g.contextVars = values
+	return nil
+}
+
+// InvocationStats implements schemahandler.StatsCollector.
+func (g *ingester) InvocationStats() (templates, customFuncs map[string]schemahandler.InvocationStats) {
+	if g.stats == nil {
+		return nil, nil
+	}
+	copyStats := func(m map[string]schemahandler.InvocationStats) map[string]schemahandler.InvocationStats {
+		c := make(map[string]schemahandler.InvocationStats, len(m))
+		for name, stats := range m {
+			c[name] = stats
+		}
+		return c
+	}
+	return copyStats(g.stats.Templates), copyStats(g.stats.CustomFuncs)
+}
+
+// InputRewritten implements schemahandler.InputRewriter.
+func (g *ingester) InputRewritten() bool {
+	return g.inputSorted
+}
+
+// SpillStats implements schemahandler.Spiller.
+func (g *ingester) SpillStats() schemahandler.SpillStats {
+	stats := g.sortStats
+	if g.resequencer != nil {
+		stats.Records += g.resequencer.stats.Records
+		stats.Bytes += g.resequencer.stats.Bytes
+		stats.Files += g.resequencer.stats.Files
+	}
+	return stats
+}
+
+// Warnings implements schemahandler.Warner.
+func (g *ingester) Warnings() []error {
+	return g.warnings
+}
+
+// debugTrace is the JSON trace returned by DebugRecord.
+type debugTrace struct {
+	Record interface{}      `json:"record"` // the IDR of the record.
+	Trace  *transform.Trace `json:"trace"`
+}
+
+// debug ingests the next raw record from the input stream, and traces the evaluation of decl, or, if
+// decl is nil, of the xpath, on it.
+func (g *ingester) debug(decl *transform.Decl, xpath string) ([]byte, error) {
+	n, err := g.next()
+	if err != nil {
+		return nil, err
+	}
+	parseCtx := transform.NewParseCtx(&g.recordCtx, g.customFuncs, g.customParseFuncs).
+		WithVars(g.contextVars).WithTrace()
+	if g.indexRecords {
+		parseCtx.WithIndex(idr.NewIndex(n))
+	}
+	if decl != nil {
+		// the error, if any, is recorded in the trace.
+		_, _ = parseCtx.ParseNode(n, decl)
+	} else {
+		parseCtx.TraceXPath(n, xpath)
+	}
+	return json.Marshal(debugTrace{Record: idr.J2NodeToInterface(n, true), Trace: parseCtx.Trace()})
+}
+
+// count updates the counters for the record n just read. Node IDs, unlike pointers, are unique even
+// for recycled nodes, so a new group with a recycled parent node is never mistaken for the old one.
+func (g *ingester) count(n *idr.Node) {
+	g.counters.Number = g.rawRecord.ordinal
+	groupID := int64(-1)
+	if n.Parent != nil {
+		groupID = n.Parent.ID
+	}
+	if g.counters.NumberInGroup == 0 || groupID != g.groupID {
+		g.counters.NumberInGroup = 0
+		g.groupID = groupID
+	}
+	g.counters.NumberInGroup++
 }
 
 func (g *ingester) IsContinuableError(err error) bool {
-	return errs.IsErrTransformFailed(err) || g.reader.IsContinuableError(err)
+	return errs.IsErrTransformFailed(err) || (!IsErrSpillFailed(err) && g.reader.IsContinuableError(err))
 }
 
 func (g *ingester) FmtErr(format string, args ...interface{}) error {
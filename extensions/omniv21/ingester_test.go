@@ -8,9 +8,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/errs"
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
 )
 
 var errContinuableInTest = errors.New("continuable error")
@@ -102,6 +107,230 @@ func TestIngester_Read_Success(t *testing.T) {
 	assert.Equal(t, 1, g.reader.(*testReader).releaseCalled)
 }
 
+type testPositionReader struct {
+	testReader
+}
+
+func (r *testPositionReader) RecordPosition() (int, int) { return 3, 5 }
+
+type testSourcePositionReader struct {
+	testReader
+}
+
+func (r *testSourcePositionReader) SourcePosition() (fileformat.Position, fileformat.Position) {
+	return fileformat.Position{Offset: 10, Line: 2, Column: 1}, fileformat.Position{Offset: 25, Line: 3, Column: 6}
+}
+
+type testBytesReader struct {
+	testReader
+}
+
+func (r *testBytesReader) RecordBytes() []byte { return []byte("raw bytes") }
+
+func TestIngester_Read_RecordBytesChecksum(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader: &testBytesReader{testReader{
+			result: []*idr.Node{ingesterTestNode},
+			err:    []error{nil},
+		}},
+	}
+	raw, _, err := g.Read()
+	assert.NoError(t, err)
+	checksum, _ := customfuncs.UUIDv3(nil, "raw bytes")
+	assert.Equal(t, checksum, raw.Checksum())
+}
+
+type testRawBytesReader struct {
+	testReader
+	buf []byte
+}
+
+func (r *testRawBytesReader) RawBytes() []byte { return r.buf }
+
+func TestIngester_Read_RawBytes(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	for _, own := range []bool{false, true} {
+		t.Run(fmt.Sprintf("own=%t", own), func(t *testing.T) {
+			reader := &testRawBytesReader{
+				testReader: testReader{
+					result: []*idr.Node{ingesterTestNode},
+					err:    []error{nil},
+				},
+				buf: []byte("raw bytes"),
+			}
+			g := &ingester{
+				finalOutputDecl: finalOutputDecl,
+				reader:          reader,
+				rawRecord:       rawRecord{ownRawBytes: own},
+			}
+			raw, _, err := g.Read()
+			assert.NoError(t, err)
+			b := raw.(schemahandler.RawBytesRecord).RawBytes()
+			assert.Equal(t, "raw bytes", string(b))
+			// the reader reuses its internal buffer.
+			copy(reader.buf, "XXX")
+			if own {
+				assert.Equal(t, "raw bytes", string(b))
+			} else {
+				assert.Equal(t, "XXX bytes", string(b))
+			}
+		})
+	}
+	// readers not reporting raw bytes.
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader:          &testReader{result: []*idr.Node{ingesterTestNode}, err: []error{nil}},
+		rawRecord:       rawRecord{ownRawBytes: true},
+	}
+	raw, _, err := g.Read()
+	assert.NoError(t, err)
+	assert.Nil(t, raw.(schemahandler.RawBytesRecord).RawBytes())
+}
+
+func TestIngester_Read_RecordID(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "custom_func": { "name": "record_id" } }
+			}
+		}`), v21.OmniV21CustomFuncs, nil)
+	assert.NoError(t, err)
+	ctx := &transformctx.Ctx{}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		customFuncs:     v21.OmniV21CustomFuncs,
+		ctx:             ctx,
+		reader: &testPositionReader{testReader{
+			result: []*idr.Node{ingesterTestNode, ingesterTestNode},
+			err:    []error{nil, nil},
+		}},
+	}
+	raw1, b, err := g.Read()
+	assert.NoError(t, err)
+	id1, checksum1 := raw1.(schemahandler.RecordIDer).RecordID(), raw1.Checksum()
+	assert.Equal(t, "464ddf81-18e1-3192-a0db-1d42aec48cce", id1)
+	assert.Equal(t, `"`+id1+`"`, string(b))
+	// Caller's ctx must not be mutated.
+	assert.Nil(t, ctx.RecordID)
+	// Same content and same position range, but different ordinal, yields a different ID.
+	raw2, _, err := g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, checksum1, raw2.Checksum())
+	assert.NotEqual(t, id1, raw2.(schemahandler.RecordIDer).RecordID())
+	ordinal, begin, end := raw2.(schemahandler.RecordPositioner).RecordPosition()
+	assert.Equal(t, []int{2, 3, 5}, []int{ordinal, begin, end})
+}
+
+func TestIngester_Read_RecordOffsets(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader: &testSourcePositionReader{testReader{
+			result: []*idr.Node{ingesterTestNode}, err: []error{nil}}},
+	}
+	raw, _, err := g.Read()
+	assert.NoError(t, err)
+	begin, end, ok := raw.(schemahandler.RecordOffsetter).RecordOffsets()
+	assert.True(t, ok)
+	assert.Equal(t, []int64{10, 25}, []int64{begin, end})
+
+	g = &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader:          &testReader{result: []*idr.Node{ingesterTestNode}, err: []error{nil}},
+	}
+	raw, _, err = g.Read()
+	assert.NoError(t, err)
+	_, _, ok = raw.(schemahandler.RecordOffsetter).RecordOffsets()
+	assert.False(t, ok)
+}
+
+type testWarningReader struct {
+	testReader
+	warnings [][]error
+}
+
+func (r *testWarningReader) Warnings() []error {
+	warnings := r.warnings[0]
+	r.warnings = r.warnings[1:]
+	return warnings
+}
+
+func TestIngester_Read_Warnings(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "const": "123", "type": "int" }
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		reader: &testWarningReader{
+			testReader: testReader{result: []*idr.Node{ingesterTestNode}, err: []error{nil}},
+			warnings:   [][]error{{errors.New("warning 1")}, {errors.New("warning 2")}},
+		},
+	}
+	var warner schemahandler.Warner = g
+	_, _, err = g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []error{errors.New("warning 1")}, warner.Warnings())
+	// warnings are reported even if the Read call reaches the end of the input.
+	_, _, err = g.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []error{errors.New("warning 2")}, warner.Warnings())
+}
+
+func TestIngester_Read_IndexRecords(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": {
+					"names": { "array": [ { "xpath": ".//name" } ] },
+					"second": { "xpath": ".//name[. = 'b']" }
+				}}
+			}
+		}`), nil, nil)
+	assert.NoError(t, err)
+	n := idr.CreateNode(idr.ElementNode, "root")
+	for _, name := range []string{"a", "b"} {
+		child := idr.CreateNode(idr.ElementNode, "child")
+		idr.AddChild(n, child)
+		nameNode := idr.CreateNode(idr.ElementNode, "name")
+		idr.AddChild(child, nameNode)
+		idr.AddChild(nameNode, idr.CreateNode(idr.TextNode, name))
+	}
+	for _, indexRecords := range []bool{false, true} {
+		g := &ingester{
+			finalOutputDecl: finalOutputDecl,
+			reader:          &testReader{result: []*idr.Node{n}, err: []error{nil}},
+			indexRecords:    indexRecords,
+		}
+		_, b, err := g.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, `{"names":["a","b"],"second":"b"}`, string(b))
+	}
+}
+
 func TestIsContinuableError(t *testing.T) {
 	g := &ingester{reader: &testReader{}}
 	assert.False(t, g.IsContinuableError(errors.New("test failure")))
@@ -112,3 +341,72 @@ func TestFmtErr(t *testing.T) {
 	g := &ingester{reader: &testReader{}}
 	assert.Equal(t, "ctx: some 1 fruit", g.FmtErr("some %d %s", 1, "fruit").Error())
 }
+
+func TestIngester_Read_Counters(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": {
+					"number": { "custom_func": { "name": "record_number" }, "type": "int" },
+					"number_in_group": { "custom_func": { "name": "record_number_in_group" }, "type": "int" },
+					"emitted": { "custom_func": { "name": "emitted_count" }, "type": "int" },
+					"value": { "xpath": ".", "type": "int" }
+				}}
+			}
+		}`), v21.OmniV21CustomFuncs, nil)
+	assert.NoError(t, err)
+	group1, group2 := idr.CreateNode(idr.ElementNode, "g"), idr.CreateNode(idr.ElementNode, "g")
+	var nodes []*idr.Node
+	for _, r := range []struct {
+		group *idr.Node
+		value string
+	}{{group1, "1"}, {group1, "x"}, {group1, "3"}, {group2, "4"}, {group2, "5"}} {
+		n := idr.CreateNode(idr.ElementNode, "r")
+		idr.AddChild(n, idr.CreateNode(idr.TextNode, r.value))
+		idr.AddChild(r.group, n)
+		nodes = append(nodes, n)
+	}
+	ctx := &transformctx.Ctx{}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		customFuncs:     v21.OmniV21CustomFuncs,
+		ctx:             ctx,
+		reader:          &testReader{result: nodes, err: make([]error, len(nodes))},
+	}
+	for _, expected := range []string{
+		`{"emitted":0,"number":1,"number_in_group":1,"value":1}`,
+		"", // transform fails on value 'x'.
+		`{"emitted":1,"number":3,"number_in_group":3,"value":3}`,
+		`{"emitted":2,"number":4,"number_in_group":1,"value":4}`,
+		`{"emitted":3,"number":5,"number_in_group":2,"value":5}`,
+	} {
+		_, b, err := g.Read()
+		if expected == "" {
+			assert.Error(t, err)
+			assert.True(t, g.IsContinuableError(err))
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+	// Caller's ctx must not be mutated.
+	assert.Equal(t, transformctx.RecordCounters{}, ctx.Counters)
+}
+
+func TestRawRecord_SourcePreviewer(t *testing.T) {
+	record := idr.CreateNode(idr.ElementNode, "r")
+	idr.AddChild(record, idr.CreateNode(idr.TextNode, "v"))
+	rr := &rawRecord{node: record}
+	assert.Equal(t, `"v"`, rr.SourceJSON())
+	assert.Equal(t, "", rr.ContextJSON())
+
+	root, envelope, header := idr.CreateNode(idr.DocumentNode, ""),
+		idr.CreateNode(idr.ElementNode, "env"), idr.CreateNode(idr.ElementNode, "hdr")
+	idr.AddChild(header, idr.CreateNode(idr.TextNode, "h"))
+	idr.AddChild(root, envelope)
+	idr.AddChild(envelope, header)
+	idr.AddChild(envelope, record)
+	assert.Equal(t, `{"env":{"hdr":"h"}}`, rr.ContextJSON())
+	// the tree is left intact.
+	assert.Equal(t, `{"hdr":"h","r":"v"}`, idr.JSONify2(envelope))
+}
@@ -0,0 +1,438 @@
+package omniv21
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/schemahandler"
+)
+
+const inputSort = "input_sort"
+
+// defaultInputSortMemoryBytes is the default `input_sort.memory_bytes`.
+const defaultInputSortMemoryBytes = 64 << 20
+
+// lineOverhead approximates the memory used by a line buffered for sorting, besides its data.
+const lineOverhead = 64
+
+// InputSort is the `input_sort` section of an omni schema, declaring how the lines of a flat file input
+// are sorted before being ingested, e.g. so that the lines of the same group are consecutive.
+type InputSort struct {
+	// HeaderLines is the number of leading lines kept in place, e.g. the CSV header line.
+	HeaderLines int `json:"header_lines,omitempty"`
+	// Delimiter is the delimiter of the columns of the `column` keys. Default to the `delimiter` of the
+	// `file_declaration`, if any, or ",".
+	Delimiter string `json:"delimiter,omitempty"`
+	// Keys are extracted from each line, and the lines are sorted in the ascending order of them, the
+	// first key being the most significant. Lines of the same keys keep their input order.
+	Keys []*InputSortKey `json:"keys,omitempty"`
+	// MemoryBytes is the approximate size of the lines buffered in memory, beyond which they're sorted
+	// and spilled into a temp file. Default to defaultInputSortMemoryBytes.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+	// MaxDiskBytes, if positive, caps the total size of the temp files.
+	MaxDiskBytes int64 `json:"max_disk_bytes,omitempty"`
+}
+
+// InputSortKey is a key of the `input_sort` section, extracted either from a delimited column, or from
+// a fixed position, of a line.
+type InputSortKey struct {
+	Column   int    `json:"column,omitempty"`    // 1-based.
+	StartPos int    `json:"start_pos,omitempty"` // 1-based and rune-based.
+	Length   int    `json:"length,omitempty"`    // rune-based.
+	Type     string `json:"type,omitempty"`      // "string" (default) or "number".
+}
+
+// inputSortFileFormatTypes are the file format types `input_sort` is supported for, i.e. the line based
+// ones.
+var inputSortFileFormatTypes = map[string]bool{
+	"csv": true, "csv2": true, "fixed-length": true, "fixedlength2": true,
+}
+
+// validateInputSort validates the `input_sort` section of an omni schema and returns it, or nil if there
+// is none. The schema must have passed the json schema validation.
+func validateInputSort(schemaContent []byte, fileFormatType string) (*InputSort, error) {
+	var schema struct {
+		InputSort       *InputSort `json:"input_sort"`
+		FileDeclaration struct {
+			Delimiter string `json:"delimiter"`
+		} `json:"file_declaration"`
+	}
+	_ = json.Unmarshal(schemaContent, &schema)
+	s := schema.InputSort
+	if s == nil {
+		return nil, nil
+	}
+	if !inputSortFileFormatTypes[fileFormatType] {
+		return nil, fmt.Errorf("not supported for file_format_type '%s'", fileFormatType)
+	}
+	if s.Delimiter == "" {
+		s.Delimiter = schema.FileDeclaration.Delimiter
+	}
+	if s.Delimiter == "" {
+		s.Delimiter = ","
+	}
+	if s.MemoryBytes <= 0 {
+		s.MemoryBytes = defaultInputSortMemoryBytes
+	}
+	return s, nil
+}
+
+// sortLine is a line of the input being sorted, including its line ending, and its keys.
+type sortLine struct {
+	line []byte
+	keys []interface{}
+}
+
+func (s *InputSort) newSortLine(line []byte) *sortLine {
+	l := &sortLine{line: line, keys: make([]interface{}, len(s.Keys))}
+	text := strings.TrimRight(string(line), "\r\n")
+	for i, key := range s.Keys {
+		var v string
+		if key.Column > 0 {
+			v = delimitedField(text, s.Delimiter, key.Column-1)
+		} else {
+			v = runeRange(text, key.StartPos-1, key.Length)
+		}
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "":
+			// missing keys come first, as nil comes first in compareKeys.
+		case key.Type == "number" && isNumber(v):
+			l.keys[i] = json.Number(v)
+		default:
+			l.keys[i] = v
+		}
+	}
+	return l
+}
+
+func (l *sortLine) size() int64 {
+	size := int64(lineOverhead + len(l.line))
+	for _, k := range l.keys {
+		if s, ok := k.(string); ok {
+			size += int64(len(s))
+		}
+	}
+	return size
+}
+
+func lessLine(a, b *sortLine) bool {
+	for i := range a.keys {
+		if c := compareKeys(a.keys[i], b.keys[i]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// delimitedField returns the i-th (0-based) field of a delimited line, unquoted if double quoted, or an
+// empty string if the line has fewer fields.
+func delimitedField(line, delim string, i int) string {
+	for field := 0; ; field++ {
+		var value string
+		more := true
+		if strings.HasPrefix(line, `"`) {
+			var sb strings.Builder
+			pos := 1
+			for pos < len(line) {
+				if line[pos] == '"' {
+					if pos+1 < len(line) && line[pos+1] == '"' {
+						sb.WriteByte('"')
+						pos += 2
+						continue
+					}
+					pos++
+					break
+				}
+				sb.WriteByte(line[pos])
+				pos++
+			}
+			value, line = sb.String(), line[pos:]
+			// anything between the closing quote and the next delimiter is ignored.
+			if idx := strings.Index(line, delim); idx >= 0 {
+				line = line[idx+len(delim):]
+			} else {
+				more = false
+			}
+		} else if idx := strings.Index(line, delim); idx >= 0 {
+			value, line = line[:idx], line[idx+len(delim):]
+		} else {
+			value, more = line, false
+		}
+		if field == i {
+			return value
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// runeRange returns the runes of s from the 0-based start, of the given length, or fewer if s is
+// shorter.
+func runeRange(s string, start, length int) string {
+	begin := -1
+	runes := 0
+	for pos := range s {
+		if runes == start {
+			begin = pos
+		}
+		if runes == start+length {
+			return s[begin:pos]
+		}
+		runes++
+	}
+	if begin < 0 {
+		return ""
+	}
+	return s[begin:]
+}
+
+// sortSource is a sorted sequence of lines, either in memory or in a spill file.
+type sortSource struct {
+	head  *sortLine                 // next line of the source, nil if exhausted.
+	next  func() (*sortLine, error) // returns the next line, or nil if exhausted.
+	close func()                    // releases the resources of the source, if any.
+}
+
+func (src *sortSource) advance() error {
+	line, err := src.next()
+	if err != nil {
+		return err
+	}
+	if src.head = line; line == nil {
+		src.close()
+	}
+	return nil
+}
+
+func memorySource(lines []*sortLine) *sortSource {
+	return &sortSource{
+		next: func() (*sortLine, error) {
+			if len(lines) == 0 {
+				return nil, nil
+			}
+			line := lines[0]
+			lines = lines[1:]
+			return line, nil
+		},
+		close: func() {},
+	}
+}
+
+// mergeNext returns the smallest head line of the sources, and advances its source. Ties are broken by
+// the order of the sources, so that the sort is stable if the sources are in the input order. It
+// returns nil once all the sources are exhausted.
+func mergeNext(sources []*sortSource) (*sortLine, error) {
+	var min *sortSource
+	for _, src := range sources {
+		if src.head != nil && (min == nil || lessLine(src.head, min.head)) {
+			min = src
+		}
+	}
+	if min == nil {
+		return nil, nil
+	}
+	line := min.head
+	return line, min.advance()
+}
+
+// inputSorter sorts the lines of an input, spilling them into temp files if they exceed the memory
+// budget.
+type inputSorter struct {
+	s         *InputSort
+	runs      []*sortSource // spill files, in the input order.
+	diskBytes int64         // size of the files of runs.
+	stats     schemahandler.SpillStats
+}
+
+var errInputSortTooLarge = errors.New("input_sort spill files too large")
+
+// spill writes the lines, got from next, into a new temp file, and reads back the first one.
+func (is *inputSorter) spill(next func() (*sortLine, error)) (*sortSource, error) {
+	f, err := os.CreateTemp("", "omniparser-sort-*")
+	if err != nil {
+		return nil, err
+	}
+	// see newSpillRun.
+	_ = os.Remove(f.Name())
+	closeFile := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	w := bufio.NewWriter(f)
+	var size int64
+	var count int
+	var lenBuf [binary.MaxVarintLen64]byte
+	for {
+		line, err := next()
+		if err != nil {
+			closeFile()
+			return nil, err
+		}
+		if line == nil {
+			break
+		}
+		count++
+		n := binary.PutUvarint(lenBuf[:], uint64(len(line.line)))
+		_, _ = w.Write(lenBuf[:n])
+		_, _ = w.Write(line.line)
+		size += int64(n + len(line.line))
+		if is.s.MaxDiskBytes > 0 && is.diskBytes+size > is.s.MaxDiskBytes {
+			closeFile()
+			return nil, errInputSortTooLarge
+		}
+	}
+	if err = w.Flush(); err != nil {
+		closeFile()
+		return nil, err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		closeFile()
+		return nil, err
+	}
+	is.diskBytes += size
+	is.stats.Records += count
+	is.stats.Bytes += size
+	is.stats.Files++
+	r := bufio.NewReader(f)
+	src := &sortSource{
+		next: func() (*sortLine, error) {
+			n, err := binary.ReadUvarint(r)
+			if err == io.EOF {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unable to read sort file '%s': %s", f.Name(), err.Error())
+			}
+			line := make([]byte, n)
+			if _, err = io.ReadFull(r, line); err != nil {
+				return nil, fmt.Errorf("unable to read sort file '%s': %s", f.Name(), err.Error())
+			}
+			return is.s.newSortLine(line), nil
+		},
+		close: func() {
+			closeFile()
+			is.diskBytes -= size
+		},
+	}
+	if err = src.advance(); err != nil {
+		closeFile()
+		return nil, err
+	}
+	return src, nil
+}
+
+// spillLines sorts the lines buffered in memory and spills them into a new temp file. If there are too
+// many temp files already, they're all merged into the new one, along with the lines.
+func (is *inputSorter) spillLines(lines []*sortLine) error {
+	sort.SliceStable(lines, func(i, j int) bool { return lessLine(lines[i], lines[j]) })
+	mem := memorySource(lines)
+	next := mem.next
+	merged := len(is.runs) >= maxSpillRuns
+	if merged {
+		if err := mem.advance(); err != nil {
+			return err
+		}
+		sources := append(is.runs[:len(is.runs):len(is.runs)], mem)
+		next = func() (*sortLine, error) { return mergeNext(sources) }
+	}
+	run, err := is.spill(next)
+	if err != nil {
+		return err
+	}
+	if merged {
+		is.runs = nil
+	}
+	is.runs = append(is.runs, run)
+	return nil
+}
+
+// sortInput reads the whole input, and returns a reader of its header lines followed by the rest of its
+// lines sorted, merged from the temp files, if any, as they're read. The last line is given a line
+// ending if it has none.
+func sortInput(s *InputSort, input io.Reader) (io.Reader, schemahandler.SpillStats, error) {
+	is := &inputSorter{s: s}
+	fail := func(err error) (io.Reader, schemahandler.SpillStats, error) {
+		for _, run := range is.runs {
+			run.close()
+		}
+		if err == errInputSortTooLarge {
+			return nil, is.stats, fmt.Errorf("'%s' spill files exceed 'max_disk_bytes' %d", inputSort, s.MaxDiskBytes)
+		}
+		return nil, is.stats, fmt.Errorf("unable to sort input: %s", err.Error())
+	}
+	r := bufio.NewReader(input)
+	var header bytes.Buffer
+	var lines []*sortLine
+	var memBytes int64
+	for i := 0; ; i++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fail(err)
+		}
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			if i < s.HeaderLines {
+				header.Write(line)
+			} else {
+				l := s.newSortLine(line)
+				lines = append(lines, l)
+				if memBytes += l.size(); memBytes > s.MemoryBytes {
+					if err := is.spillLines(lines); err != nil {
+						return fail(err)
+					}
+					lines, memBytes = nil, 0
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lessLine(lines[i], lines[j]) })
+	mem := memorySource(lines)
+	if err := mem.advance(); err != nil {
+		return fail(err)
+	}
+	sources := append(is.runs, mem)
+	return io.MultiReader(&header, &sortedReader{sources: sources}), is.stats, nil
+}
+
+// sortedReader is an io.Reader of the lines of the sources, merged.
+type sortedReader struct {
+	sources []*sortSource
+	line    []byte // rest of the current line not yet read.
+}
+
+func (r *sortedReader) Read(p []byte) (int, error) {
+	for len(r.line) == 0 {
+		line, err := mergeNext(r.sources)
+		if err != nil {
+			return 0, err
+		}
+		if line == nil {
+			return 0, io.EOF
+		}
+		r.line = line.line
+	}
+	n := copy(p, r.line)
+	r.line = r.line[n:]
+	return n, nil
+}
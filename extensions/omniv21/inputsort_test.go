@@ -0,0 +1,174 @@
+package omniv21
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/schemahandler"
+)
+
+func TestDelimitedField(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		line     string
+		delim    string
+		i        int
+		expected string
+	}{
+		{"first", "a,b,c", ",", 0, "a"},
+		{"last", "a,b,c", ",", 2, "c"},
+		{"out of range", "a,b,c", ",", 3, ""},
+		{"empty field", "a,,c", ",", 1, ""},
+		{"multi-char delimiter", "a||b||c", "||", 1, "b"},
+		{"quoted with delimiter", `a,"b,1",c`, ",", 1, "b,1"},
+		{"quoted with escaped quote", `"a""1",b`, ",", 0, `a"1`},
+		{"field after quoted", `"a,1",b`, ",", 1, "b"},
+		{"quoted last", `a,"b"`, ",", 1, "b"},
+		{"out of range after quoted", `a,"b"`, ",", 2, ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, delimitedField(test.line, test.delim, test.i))
+		})
+	}
+}
+
+func TestRuneRange(t *testing.T) {
+	assert.Equal(t, "bc", runeRange("abcd", 1, 2))
+	assert.Equal(t, "cd", runeRange("abcd", 2, 5))
+	assert.Equal(t, "", runeRange("abcd", 4, 1))
+	assert.Equal(t, "日本", runeRange("a日本語", 1, 2))
+}
+
+func TestSortInput(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		inputSort *InputSort
+		input     string
+		expected  string
+	}{
+		{
+			name:      "column keys",
+			inputSort: &InputSort{Keys: []*InputSortKey{{Column: 2}, {Column: 1, Type: "number"}}},
+			input:     "10,b\n9,b\r\n3,a\nx,b\n1,\n",
+			// numbers come before strings.
+			expected: "1,\n3,a\n9,b\r\n10,b\nx,b\n",
+		},
+		{
+			name: "fixed position keys, header lines and no last line ending",
+			inputSort: &InputSort{
+				HeaderLines: 2, Keys: []*InputSortKey{{StartPos: 3, Length: 2, Type: "number"}},
+			},
+			input:    "HDR1\nHDR2\nA 20\nB 10\nC  5\nD 10",
+			expected: "HDR1\nHDR2\nC  5\nB 10\nD 10\nA 20\n",
+		},
+		{
+			name:      "header lines only",
+			inputSort: &InputSort{HeaderLines: 3, Keys: []*InputSortKey{{Column: 1}}},
+			input:     "b\na",
+			expected:  "b\na\n",
+		},
+		{
+			name:      "empty input",
+			inputSort: &InputSort{Keys: []*InputSortKey{{Column: 1}}},
+			input:     "",
+			expected:  "",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.inputSort.Delimiter = ","
+			test.inputSort.MemoryBytes = defaultInputSortMemoryBytes
+			r, stats, err := sortInput(test.inputSort, strings.NewReader(test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, schemahandler.SpillStats{}, stats)
+			b, err := ioutil.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
+
+func TestSortInput_Spill(t *testing.T) {
+	// lines of descending keys, with every other line of the same key, to check the sort is stable.
+	var input, expected []string
+	for i := 100; i > 0; i-- {
+		input = append(input, fmt.Sprintf("%d,%d", i/2, i))
+	}
+	for i := 0; i <= 50; i++ {
+		for j := 100; j > 0; j-- {
+			if j/2 == i {
+				expected = append(expected, fmt.Sprintf("%d,%d", i, j))
+			}
+		}
+	}
+	s := &InputSort{
+		Delimiter:   ",",
+		Keys:        []*InputSortKey{{Column: 1, Type: "number"}},
+		MemoryBytes: 3 * lineOverhead,
+	}
+	r, stats, err := sortInput(s, strings.NewReader(strings.Join(input, "\n")))
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join(expected, "\n")+"\n", string(b))
+	// spill files are merged beyond maxSpillRuns, thus their lines are spilled more than once.
+	assert.True(t, stats.Files > maxSpillRuns)
+	assert.True(t, stats.Records > 100)
+	assert.True(t, stats.Bytes > 0)
+}
+
+func TestSortInput_MaxDiskBytes(t *testing.T) {
+	s := &InputSort{
+		Delimiter:    ",",
+		Keys:         []*InputSortKey{{Column: 1}},
+		MemoryBytes:  1,
+		MaxDiskBytes: 5,
+	}
+	_, _, err := sortInput(s, strings.NewReader("c\nb\na\n"))
+	assert.Error(t, err)
+	assert.Equal(t, "'input_sort' spill files exceed 'max_disk_bytes' 5", err.Error())
+}
+
+func TestValidateInputSort(t *testing.T) {
+	s, err := validateInputSort([]byte(`{}`), "csv2")
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	s, err = validateInputSort(
+		[]byte(`{"file_declaration": {"delimiter": "|"}, "input_sort": {"keys": [{"column": 1}]}}`), "csv2")
+	assert.NoError(t, err)
+	assert.Equal(t, "|", s.Delimiter)
+	assert.Equal(t, int64(defaultInputSortMemoryBytes), s.MemoryBytes)
+
+	s, err = validateInputSort([]byte(`{"input_sort": {"keys": [{"start_pos": 1, "length": 2}]}}`), "fixedlength2")
+	assert.NoError(t, err)
+	assert.Equal(t, ",", s.Delimiter)
+
+	_, err = validateInputSort([]byte(`{"input_sort": {"keys": [{"column": 1}]}}`), "json")
+	assert.Error(t, err)
+	assert.Equal(t, "not supported for file_format_type 'json'", err.Error())
+}
+
+func TestCreateSchemaHandler_InputSortFailure(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		inputSort string
+		expected  string
+	}{
+		{"no keys", `{ "keys": [] }`, "input_sort.keys"},
+		{"both column and start_pos", `{ "keys": [ { "column": 1, "start_pos": 1, "length": 1 } ] }`, "input_sort.keys.0"},
+		{"start_pos without length", `{ "keys": [ { "start_pos": 1 } ] }`, "input_sort.keys.0"},
+		{"file format not supported", `{ "keys": [ { "column": 1 } ] }`,
+			"schema 'test-schema' 'input_sort' validation failed: not supported for file_format_type 'json'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := createRecordOrderSchemaHandler(`{ "key": { "xpath": "id" }, "window": 1 }, "input_sort": ` +
+				test.inputSort)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
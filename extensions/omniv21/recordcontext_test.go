@@ -0,0 +1,128 @@
+package omniv21
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/customfuncs"
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func createRecordContextSchemaHandler(recordContext string) (schemahandler.SchemaHandler, error) {
+	return CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "json"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "xpath": "/batch/items/*", "object": {
+					"n": { "xpath": "n", "type": "int" },
+					"line": { "template": "line" }
+				}},
+				"line": { "object": { "batch_id": { "var": "batch_id" } } }
+			},
+			"file_trailer": { "object": { "batch_id": { "var": "batch_id" } } },
+			"record_context": ` + recordContext + `
+		}`),
+		CustomFuncs: customfuncs.Merge(customfuncs.CommonCustomFuncs, v21.OmniV21CustomFuncs),
+	})
+}
+
+func TestIngester_Read_RecordContext(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		recordContext string
+		input         string
+		expected      []string
+	}{
+		{
+			name:          "captured once",
+			recordContext: `{ "batch_id": { "xpath": "batch/id" }, "input": { "external": "input" } }`,
+			input:         `{"batch":{"id":"B1","items":[{"n":"1"},{"n":"2"}]}}`,
+			expected: []string{
+				`{"line":{"batch_id":"B1"},"n":1}`,
+				`{"line":{"batch_id":"B1"},"n":2}`,
+				`{"batch_id":"B1"}`,
+			},
+		},
+		{
+			name:          "capture failure is continuable and re-attempted",
+			recordContext: `{ "batch_id": { "xpath": "batch/items/*[1]/n", "type": "int" } }`,
+			input:         `{"batch":{"items":[{"n":"x"},{"n":"2"}]}}`,
+			// the first item is released from the tree after the first record, so the re-attempt upon
+			// the second record captures the second item's.
+			expected: []string{"error", `{"line":{"batch_id":2},"n":2}`, `{"batch_id":2}`},
+		},
+		{
+			name:          "var missing",
+			recordContext: `{ "other": { "const": "x" } }`,
+			input:         `{"batch":{"items":[{"n":"1"}]}}`,
+			expected:      []string{"error", "error"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := createRecordContextSchemaHandler(test.recordContext)
+			assert.NoError(t, err)
+			g, err := h.NewIngester(&transformctx.Ctx{
+				InputName:          "test-input",
+				ExternalProperties: map[string]string{"input": "test-input"},
+			}, strings.NewReader(test.input))
+			assert.NoError(t, err)
+			records, err := readAll(t, g)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, records)
+		})
+	}
+}
+
+func TestIngester_Read_RecordContextSkipUnreferencedColumns(t *testing.T) {
+	h, err := CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "csv2"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+			"file_declaration": {
+				"delimiter": ",",
+				"skip_unreferenced_columns": true,
+				"records": [
+					{ "name": "head", "header": "^H", "min": 1, "max": 1,
+						"columns": [ { "name": "batch_id", "index": 2 } ] },
+					{ "name": "item", "header": "^I", "is_target": true,
+						"columns": [ { "name": "n", "index": 2 } ] }
+				]
+			},
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": { "n": { "xpath": "n" }, "batch_id": { "var": "batch_id" } } }
+			},
+			"record_context": { "batch_id": { "xpath": "head/batch_id" } }
+		}`),
+		CustomFuncs: customfuncs.CommonCustomFuncs,
+	})
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader("H,B1\nI,1\nI,2\n"))
+	assert.NoError(t, err)
+	records, err := readAll(t, g)
+	assert.NoError(t, err)
+	// the column only referenced by the record context isn't skipped.
+	assert.Equal(t, []string{`{"batch_id":"B1","n":"1"}`, `{"batch_id":"B1","n":"2"}`}, records)
+}
+
+func TestCreateSchemaHandler_RecordContextFailure(t *testing.T) {
+	_, err := createRecordContextSchemaHandler(`{ "batch_id": { "custom_func": { "name": "no_such_func" } } }`)
+	assert.Error(t, err)
+	assert.Equal(t,
+		"schema 'test-schema' 'record_context' validation failed: unknown custom_func 'no_such_func' on 'record_context.batch_id'",
+		err.Error())
+	_, err = createRecordContextSchemaHandler(`{ "batch-id": { "const": "x" } }`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "record_context")
+}
@@ -0,0 +1,325 @@
+package omniv21
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+type orderedRecord struct {
+	rawRecord   rawRecord
+	transformed []byte
+	key, group  interface{}
+}
+
+// orderedRecords is a min-heap of records by their keys, then by their ordinals, so that records of
+// the same key are emitted in the order they're ingested.
+type orderedRecords []*orderedRecord
+
+func (rs orderedRecords) Len() int { return len(rs) }
+
+func (rs orderedRecords) Less(i, j int) bool { return lessRecord(rs[i], rs[j]) }
+
+func lessRecord(a, b *orderedRecord) bool {
+	if c := compareKeys(a.key, b.key); c != 0 {
+		return c < 0
+	}
+	return a.rawRecord.ordinal < b.rawRecord.ordinal
+}
+
+func (rs orderedRecords) Swap(i, j int) { rs[i], rs[j] = rs[j], rs[i] }
+
+func (rs *orderedRecords) Push(x interface{}) { *rs = append(*rs, x.(*orderedRecord)) }
+
+func (rs *orderedRecords) Pop() interface{} {
+	old := *rs
+	r := old[len(old)-1]
+	old[len(old)-1] = nil
+	*rs = old[:len(old)-1]
+	return r
+}
+
+// resequencer re-sequences the records ingested according to the `record_order` of a schema: it
+// buffers up to `window` records, and emits the one of the smallest key each time the buffer is full,
+// or, when a record of a new group arrives, all the records buffered of the current group. Continuable
+// errors are returned right away; io.EOF and fatal errors are returned once the buffer is drained.
+//
+// If the `record_order` has `spill` set, once the records buffered in memory exceed its `memory_bytes`,
+// they're spilled into a temp file, in the ascending order, and read back one at a time as they're
+// emitted; the buffer then consists of the records in memory and the ones in the temp files.
+type resequencer struct {
+	order   *transform.RecordOrder
+	buf     orderedRecords
+	group   interface{}    // group key of the records buffered.
+	held    *orderedRecord // first record of the next group, held until the buffer is drained.
+	err     error          // io.EOF or fatal error to return once the buffer is drained.
+	emitted *orderedRecord // last record emitted, whose IDR node copy is released upon the next read.
+
+	memBytes  int64       // approximate size of the records buffered in memory, if spilling is on.
+	runs      []*spillRun // spill files of records not yet emitted.
+	spilled   int         // number of records in runs.
+	diskBytes int64       // size of the files of runs.
+	stats     schemahandler.SpillStats
+}
+
+func newResequencer(order *transform.RecordOrder) *resequencer {
+	return &resequencer{order: order}
+}
+
+func (r *resequencer) read(g *ingester) (schemahandler.RawRecord, []byte, error) {
+	if r.emitted != nil {
+		idr.RemoveAndReleaseTree(r.emitted.rawRecord.node)
+		r.emitted = nil
+	}
+	if r.size() == 0 && r.held != nil {
+		r.group = r.held.group
+		r.push(r.held)
+		r.held = nil
+	}
+	for r.err == nil && r.held == nil && r.size() < r.order.Window {
+		rec, err := r.ingest(g)
+		if err != nil && g.IsContinuableError(err) {
+			return nil, nil, err
+		}
+		if err != nil {
+			r.err = err
+			break
+		}
+		if r.size() > 0 && r.order.GroupKey != nil && compareKeys(rec.group, r.group) != 0 {
+			r.held = rec
+			break
+		}
+		r.group = rec.group
+		r.push(rec)
+		if err := r.spillIfNeeded(); err != nil {
+			r.fail(err)
+			return nil, nil, err
+		}
+	}
+	if r.size() == 0 {
+		return nil, nil, r.err
+	}
+	var err error
+	if r.emitted, err = r.pop(); err != nil {
+		r.fail(err)
+		return nil, nil, err
+	}
+	return &r.emitted.rawRecord, r.emitted.transformed, nil
+}
+
+// size returns the number of records buffered, in memory or spilled.
+func (r *resequencer) size() int {
+	return len(r.buf) + r.spilled
+}
+
+func (r *resequencer) push(rec *orderedRecord) {
+	heap.Push(&r.buf, rec)
+	if r.order.Spill != nil {
+		r.memBytes += recordSize(rec)
+	}
+}
+
+func (r *resequencer) popMemory() *orderedRecord {
+	rec := heap.Pop(&r.buf).(*orderedRecord)
+	if r.order.Spill != nil {
+		r.memBytes -= recordSize(rec)
+	}
+	return rec
+}
+
+// pop removes and returns the record of the smallest key buffered, in memory or spilled.
+func (r *resequencer) pop() (*orderedRecord, error) {
+	var min *spillRun
+	for _, run := range r.runs {
+		if min == nil || lessRecord(run.head, min.head) {
+			min = run
+		}
+	}
+	if min == nil || (len(r.buf) > 0 && lessRecord(r.buf[0], min.head)) {
+		return r.popMemory(), nil
+	}
+	rec := min.head
+	r.spilled--
+	if err := min.next(); err != nil {
+		return nil, err
+	}
+	if min.head == nil {
+		r.diskBytes -= min.size
+		min.close()
+		for i, run := range r.runs {
+			if run == min {
+				r.runs = append(r.runs[:i], r.runs[i+1:]...)
+				break
+			}
+		}
+	}
+	return rec, nil
+}
+
+// maxSpillRuns bounds the number of spill files open at once: beyond it, they're merged into one.
+const maxSpillRuns = 32
+
+// spillIfNeeded spills the records buffered in memory into a new spill file, if they exceed the
+// `memory_bytes` of the `record_order`. If there are too many spill files already, all the records
+// buffered, in memory and in the spill files, are merged into the new one instead.
+func (r *resequencer) spillIfNeeded() error {
+	spill := r.order.Spill
+	if spill == nil || r.memBytes <= spill.MemoryBytes {
+		return nil
+	}
+	count, next := len(r.buf), func() (*orderedRecord, error) { return r.popMemory(), nil }
+	if len(r.runs) >= maxSpillRuns {
+		count, next = r.size(), r.pop
+	}
+	run, err := newSpillRun(count, next, func(written int64) bool {
+		return spill.MaxDiskBytes > 0 && r.diskBytes+written > spill.MaxDiskBytes
+	})
+	switch {
+	case err == errSpillTooLarge:
+		return ErrSpillFailed(fmt.Sprintf("'record_order' spill files exceed 'max_disk_bytes' %d", spill.MaxDiskBytes))
+	case IsErrSpillFailed(err):
+		return err
+	case err != nil:
+		return ErrSpillFailed(fmt.Sprintf("unable to spill 'record_order' records: %s", err.Error()))
+	}
+	r.runs = append(r.runs, run)
+	r.spilled += count
+	r.diskBytes += run.size
+	r.stats.Records += count
+	r.stats.Bytes += run.size
+	r.stats.Files++
+	return nil
+}
+
+// fail drops all the records buffered, and removes the spill files, upon a fatal error.
+func (r *resequencer) fail(err error) {
+	for _, run := range r.runs {
+		run.close()
+	}
+	r.runs, r.spilled, r.diskBytes = nil, 0, 0
+	r.buf, r.memBytes, r.held = nil, 0, nil
+	r.err = err
+}
+
+// ingest ingests and transforms the next record, and copies it so it can be buffered: readers release
+// or reuse the IDR node and the bytes of a record upon their next Read calls.
+func (r *resequencer) ingest(g *ingester) (*orderedRecord, error) {
+	decls := []*transform.Decl{r.order.Key}
+	if r.order.GroupKey != nil {
+		decls = append(decls, r.order.GroupKey)
+	}
+	transformed, values, err := g.ingest(decls...)
+	if err != nil {
+		return nil, err
+	}
+	rec := &orderedRecord{rawRecord: g.rawRecord, transformed: transformed, key: values[0]}
+	rec.rawRecord.node = idr.CopyTree(g.rawRecord.node)
+	if r.order.GroupKey != nil {
+		rec.group = values[1]
+	}
+	if rec.rawRecord.bytes != nil {
+		rec.rawRecord.bytes = append([]byte(nil), rec.rawRecord.bytes...)
+	}
+	if rec.rawRecord.rawBytes != nil {
+		rec.rawRecord.rawBytes = append([]byte(nil), rec.rawRecord.rawBytes...)
+	}
+	return rec, nil
+}
+
+// compareKeys compares two record order keys, returning -1, 0 or 1. Keys are ordered by type first:
+// nil (e.g. the key xpath matched nothing), booleans, numbers, strings, then anything else; numbers
+// are compared numerically, and other non-strings by their JSON encodings.
+func compareKeys(a, b interface{}) int {
+	ra, rb := keyRank(a), keyRank(b)
+	switch {
+	case ra != rb:
+		return compareInts(ra, rb)
+	case ra == rankNil:
+		return 0
+	case ra == rankBool:
+		return compareInts(boolInt(a.(bool)), boolInt(b.(bool)))
+	case ra == rankNumber:
+		fa, fb := keyFloat(a), keyFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case ra == rankString:
+		return strings.Compare(reflect.ValueOf(a).String(), reflect.ValueOf(b).String())
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return strings.Compare(string(ja), string(jb))
+}
+
+const (
+	rankNil = iota
+	rankBool
+	rankNumber
+	rankString
+	rankOther
+)
+
+func keyRank(v interface{}) int {
+	if v == nil {
+		return rankNil
+	}
+	if n, ok := v.(json.Number); ok {
+		if _, err := n.Float64(); err == nil {
+			return rankNumber
+		}
+		return rankString
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool:
+		return rankBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return rankNumber
+	case reflect.String:
+		return rankString
+	}
+	return rankOther
+}
+
+func keyFloat(v interface{}) float64 {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		return f
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	}
+	return rv.Float()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
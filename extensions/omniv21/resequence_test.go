@@ -0,0 +1,313 @@
+package omniv21
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func createRecordOrderSchemaHandler(recordOrder string) (schemahandler.SchemaHandler, error) {
+	return CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "json"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "xpath": "/*", "object": { "id": { "xpath": "id" } } }
+			},
+			"record_order": ` + recordOrder + `
+		}`),
+		CustomFuncs: customfuncs.CommonCustomFuncs,
+	})
+}
+
+func TestIngester_Read_RecordOrder(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		recordOrder string
+		input       string
+		expected    []string
+	}{
+		{
+			name:        "window",
+			recordOrder: `{ "key": { "xpath": "seq", "type": "int" }, "window": 3 }`,
+			input: `[{"id":"a","seq":"2"},{"id":"b","seq":"1"},{"id":"c","seq":"3"},{"id":"d","seq":"5"},
+				{"id":"e","seq":"4"},{"id":"f","seq":"7"},{"id":"g","seq":"6"}]`,
+			expected: []string{"b", "a", "c", "e", "d", "g", "f"},
+		},
+		{
+			name:        "window too small to fully re-sequence",
+			recordOrder: `{ "key": { "xpath": "seq", "type": "int" }, "window": 2 }`,
+			input:       `[{"id":"a","seq":"3"},{"id":"b","seq":"2"},{"id":"c","seq":"1"}]`,
+			expected:    []string{"b", "c", "a"},
+		},
+		{
+			name:        "window of 1 keeps the input order",
+			recordOrder: `{ "key": { "xpath": "seq" }, "window": 1 }`,
+			input:       `[{"id":"a","seq":"2"},{"id":"b","seq":"1"}]`,
+			expected:    []string{"a", "b"},
+		},
+		{
+			name:        "equal and missing keys",
+			recordOrder: `{ "key": { "xpath": "seq" }, "window": 10 }`,
+			input:       `[{"id":"a","seq":"x"},{"id":"b"},{"id":"c","seq":"x"},{"id":"d","seq":"w"},{"id":"e"}]`,
+			expected:    []string{"b", "e", "d", "a", "c"},
+		},
+		{
+			name: "group key",
+			recordOrder: `{
+				"key": { "xpath": "seq", "type": "int" },
+				"group_key": { "custom_func": { "name": "upper", "args": [ { "xpath": "grp" } ] } },
+				"window": 10
+			}`,
+			input: `[{"id":"a","grp":"x","seq":"2"},{"id":"b","grp":"X","seq":"1"},{"id":"c","grp":"y","seq":"3"},
+				{"id":"d","grp":"y","seq":"2"},{"id":"e","grp":"x","seq":"1"}]`,
+			expected: []string{"b", "a", "d", "c", "e"},
+		},
+		{
+			name: "group key and window",
+			recordOrder: `{
+				"key": { "xpath": "seq", "type": "int" }, "group_key": { "xpath": "grp" }, "window": 2
+			}`,
+			input: `[{"id":"a","grp":"x","seq":"3"},{"id":"b","grp":"x","seq":"2"},{"id":"c","grp":"x","seq":"1"},
+				{"id":"d","grp":"y","seq":"2"},{"id":"e","grp":"y","seq":"1"}]`,
+			expected: []string{"b", "c", "a", "e", "d"},
+		},
+		{
+			name: "spill",
+			recordOrder: `{
+				"key": { "xpath": "seq", "type": "int" }, "window": 10, "spill": { "memory_bytes": 500 }
+			}`,
+			input: `[{"id":"a","seq":"5"},{"id":"b","seq":"3"},{"id":"c","seq":"4"},{"id":"d","seq":"1"},
+				{"id":"e","seq":"3"},{"id":"f","seq":"2"},{"id":"g","seq":"6"}]`,
+			expected: []string{"d", "f", "b", "e", "c", "a", "g"},
+		},
+		{
+			name: "spill with group key",
+			recordOrder: `{
+				"key": { "xpath": "seq" }, "group_key": { "xpath": "grp" }, "window": 10,
+				"spill": { "memory_bytes": 1 }
+			}`,
+			input: `[{"id":"a","grp":"x","seq":"b"},{"id":"b","grp":"x","seq":"a"},{"id":"c","grp":"y","seq":"b"},
+				{"id":"d","grp":"y","seq":"a"}]`,
+			expected: []string{"b", "a", "d", "c"},
+		},
+		{
+			name: "spill files merged",
+			recordOrder: `{
+				"key": { "xpath": "seq", "type": "int" }, "window": 100, "spill": { "memory_bytes": 1 }
+			}`,
+			input:    spillTestInput(70),
+			expected: spillTestExpected(70),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := createRecordOrderSchemaHandler(test.recordOrder)
+			assert.NoError(t, err)
+			g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader(test.input))
+			assert.NoError(t, err)
+			var ids []string
+			for {
+				raw, transformed, err := g.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.NoError(t, err)
+				var record struct{ ID string }
+				assert.NoError(t, json.Unmarshal(transformed, &record))
+				ids = append(ids, record.ID)
+				// the raw record emitted is the one of the transformed record, and hasn't been released.
+				assert.Contains(t, idr.JSONify2(raw.Raw().(*idr.Node)), `"id":"`+record.ID+`"`)
+			}
+			assert.Equal(t, test.expected, ids)
+			// io.EOF is returned repeatedly.
+			_, _, err = g.Read()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestIngester_Read_RecordOrderSkipUnreferencedColumns(t *testing.T) {
+	h, err := CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "csv2"},
+		},
+		Content: []byte(`{
+			"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+			"file_declaration": {
+				"delimiter": ",",
+				"skip_unreferenced_columns": true,
+				"records": [ { "columns": [ { "name": "A", "index": 1 }, { "name": "B", "index": 2 } ] } ]
+			},
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": { "id": { "xpath": "A" } } }
+			},
+			"record_order": { "key": { "xpath": "B", "type": "int" }, "window": 10 }
+		}`),
+		CustomFuncs: customfuncs.CommonCustomFuncs,
+	})
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader("x,3\ny,1\nz,2\n"))
+	assert.NoError(t, err)
+	var ids []string
+	for {
+		_, transformed, err := g.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		var record struct{ ID string }
+		assert.NoError(t, json.Unmarshal(transformed, &record))
+		ids = append(ids, record.ID)
+	}
+	// column B, only referenced by the key, isn't skipped.
+	assert.Equal(t, []string{"y", "z", "x"}, ids)
+}
+
+// spillTestInput returns n records of descending seqs.
+func spillTestInput(n int) string {
+	var records []string
+	for i := n; i > 0; i-- {
+		records = append(records, fmt.Sprintf(`{"id":"%d","seq":"%d"}`, i, i))
+	}
+	return "[" + strings.Join(records, ",") + "]"
+}
+
+func spillTestExpected(n int) []string {
+	var ids []string
+	for i := 1; i <= n; i++ {
+		ids = append(ids, strconv.Itoa(i))
+	}
+	return ids
+}
+
+func TestIngester_Read_RecordOrder_SpillStats(t *testing.T) {
+	h, err := createRecordOrderSchemaHandler(
+		`{ "key": { "xpath": "seq", "type": "int" }, "window": 10, "spill": { "memory_bytes": 1 } }`)
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader(spillTestInput(3)))
+	assert.NoError(t, err)
+	assert.Equal(t, schemahandler.SpillStats{}, g.(schemahandler.Spiller).SpillStats())
+	for {
+		if _, _, err := g.Read(); err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+	}
+	stats := g.(schemahandler.Spiller).SpillStats()
+	assert.Equal(t, 3, stats.Records)
+	assert.Equal(t, 3, stats.Files)
+	assert.True(t, stats.Bytes > 0)
+}
+
+func TestIngester_Read_RecordOrder_SpillMaxDiskBytes(t *testing.T) {
+	h, err := createRecordOrderSchemaHandler(`{
+		"key": { "xpath": "seq", "type": "int" }, "window": 10,
+		"spill": { "memory_bytes": 1, "max_disk_bytes": 1 }
+	}`)
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader(spillTestInput(3)))
+	assert.NoError(t, err)
+	_, _, err = g.Read()
+	assert.Error(t, err)
+	assert.False(t, g.IsContinuableError(err))
+	assert.Equal(t, "'record_order' spill files exceed 'max_disk_bytes' 1", err.Error())
+	// the fatal error is returned repeatedly.
+	_, _, err2 := g.Read()
+	assert.Equal(t, err, err2)
+}
+
+func TestIngester_Read_RecordOrder_KeyFailure(t *testing.T) {
+	h, err := createRecordOrderSchemaHandler(`{ "key": { "xpath": "seq", "type": "int" }, "window": 2 }`)
+	assert.NoError(t, err)
+	g, err := h.NewIngester(&transformctx.Ctx{InputName: "test-input"},
+		strings.NewReader(`[{"id":"a","seq":"2"},{"id":"b","seq":"x"},{"id":"c","seq":"1"}]`))
+	assert.NoError(t, err)
+	// the failure to evaluate the key of a record is a continuable error, returned right away.
+	_, transformed, err := g.Read()
+	assert.Error(t, err)
+	assert.True(t, errs.IsErrTransformFailed(err))
+	assert.Contains(t, err.Error(), "unable to convert value 'x' to type 'int' on 'record_order.key'")
+	assert.Nil(t, transformed)
+	_, transformed, err = g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"c"}`, string(transformed))
+	_, transformed, err = g.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"a"}`, string(transformed))
+	_, _, err = g.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCreateSchemaHandler_RecordOrderFailure(t *testing.T) {
+	_, err := createRecordOrderSchemaHandler(`{ "key": { "custom_func": { "name": "no_such_func" } }, "window": 2 }`)
+	assert.Error(t, err)
+	assert.Equal(t,
+		"schema 'test-schema' 'record_order' validation failed: unknown custom_func 'no_such_func' on 'record_order.key'",
+		err.Error())
+	_, err = createRecordOrderSchemaHandler(`{ "key": { "xpath": "seq" }, "window": 0 }`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "record_order.window")
+	_, err = createRecordOrderSchemaHandler(`{ "key": { "xpath": "seq" }, "window": 2, "spill": {} }`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "memory_bytes")
+}
+
+func TestCompareKeys(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		a, b     interface{}
+		expected int
+	}{
+		{"nils", nil, nil, 0},
+		{"nil before bool", nil, false, -1},
+		{"bools", true, false, 1},
+		{"bool before number", true, int64(0), -1},
+		{"ints", int64(2), int64(10), -1},
+		{"int and float", int64(2), 1.5, 1},
+		{"json.Number and int", json.Number("2.0"), int64(2), 0},
+		{"number before string", 3.0, "1", -1},
+		{"strings", "10", "9", -1},
+		{"invalid json.Number as string", json.Number("x"), "x", 0},
+		{"string before others", "z", map[string]interface{}{}, -1},
+		{"others by json", []interface{}{"b"}, []interface{}{"a"}, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, compareKeys(test.a, test.b))
+			assert.Equal(t, -test.expected, compareKeys(test.b, test.a))
+		})
+	}
+}
+
+func TestIngester_Read_RawRecordOnly(t *testing.T) {
+	h, err := createRecordOrderSchemaHandler(`{ "key": { "xpath": "seq", "type": "int" }, "window": 3 }`)
+	assert.NoError(t, err)
+	g, err := h.NewIngester(
+		&transformctx.Ctx{RawRecordOnly: true},
+		strings.NewReader(`[{"id":"a","seq":"2"},{"id":"b","seq":"x"}]`))
+	assert.NoError(t, err)
+	// records are neither transformed nor re-sequenced, and a bad key doesn't fail the ingestion.
+	for _, expected := range []string{`{"id":"a","seq":"2"}`, `{"id":"b","seq":"x"}`} {
+		raw, output, err := g.Read()
+		assert.NoError(t, err)
+		assert.Nil(t, output)
+		assert.Equal(t, expected, idr.JSONify2(raw.Raw().(*idr.Node)))
+	}
+	_, _, err = g.Read()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,20 @@
+package bankstatement
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_MT940(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_mt940.schema.json", "./1_mt940.input.txt")))
+}
+
+func Test2_CAMT053(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./2_camt053.schema.json", "./2_camt053.input.xml")))
+}
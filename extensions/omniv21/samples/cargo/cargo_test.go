@@ -0,0 +1,25 @@
+package cargo
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_FWB(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_fwb.schema.json", "./1_fwb.input.txt")))
+}
+
+func Test2_FHL(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./2_fhl.schema.json", "./2_fhl.input.txt")))
+}
+
+func Test3_CargoXML_Waybill(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./3_cargoxml_waybill.schema.json", "./3_cargoxml_waybill.input.xml")))
+}
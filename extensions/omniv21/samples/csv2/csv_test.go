@@ -26,6 +26,7 @@ const (
 	test2_Fixed_Multi_Row
 	test3_Multi_Row_HeaderFooter
 	test4_Nested
+	test5_Input_Sort
 )
 
 var tests = []testCase{
@@ -49,6 +50,11 @@ var tests = []testCase{
 		schemaFile: "./4_nested.schema.json",
 		inputFile:  "./4_nested.input.csv",
 	},
+	{
+		// test5_Input_Sort
+		schemaFile: "./5_input_sort.schema.json",
+		inputFile:  "./5_input_sort.input.csv",
+	},
 }
 
 func init() {
@@ -107,6 +113,10 @@ func Test4_Nested(t *testing.T) {
 	tests[test4_Nested].doTest(t)
 }
 
+func Test5_Input_Sort(t *testing.T) {
+	tests[test5_Input_Sort].doTest(t)
+}
+
 // Benchmark1_Single_Row-8   	       11401	    105326 ns/op	   80756 B/op	    1424 allocs/op
 func Benchmark1_Single_Row(b *testing.B) {
 	tests[test1_Single_Row].doBenchmark(b)
@@ -126,3 +136,8 @@ func Benchmark3_Multi_Row_HeaderFooter(b *testing.B) {
 func Benchmark4_Nested(b *testing.B) {
 	tests[test4_Nested].doBenchmark(b)
 }
+
+// Benchmark5_Input_Sort-8   	   12543	    110572 ns/op	   31906 B/op	     726 allocs/op
+func Benchmark5_Input_Sort(b *testing.B) {
+	tests[test5_Input_Sort].doBenchmark(b)
+}
@@ -29,6 +29,11 @@ const (
 	test1_CanadaPost_EDI_214 = iota
 	test2_UPS_EDI_210
 	test3_X12_834
+	test4_X12_810
+	test5_X12_850
+	test6_X12_856
+	test7_X12_214
+	test8_EDIFACT_ORDERS
 )
 
 var tests = []testCase{
@@ -47,6 +52,31 @@ var tests = []testCase{
 		schemaFile: "./3_x12_834.schema.json",
 		inputFile:  "./3_x12_834.input.txt",
 	},
+	{
+		// test4_X12_810
+		schemaFile: "./4_x12_810.schema.json",
+		inputFile:  "./4_x12_810.input.txt",
+	},
+	{
+		// test5_X12_850
+		schemaFile: "./5_x12_850.schema.json",
+		inputFile:  "./5_x12_850.input.txt",
+	},
+	{
+		// test6_X12_856
+		schemaFile: "./6_x12_856.schema.json",
+		inputFile:  "./6_x12_856.input.txt",
+	},
+	{
+		// test7_X12_214
+		schemaFile: "./7_x12_214.schema.json",
+		inputFile:  "./7_x12_214.input.txt",
+	},
+	{
+		// test8_EDIFACT_ORDERS
+		schemaFile: "./8_edifact_orders.schema.json",
+		inputFile:  "./8_edifact_orders.input.txt",
+	},
 }
 
 func init() {
@@ -101,6 +131,26 @@ func Test3_X12_834(t *testing.T) {
 	tests[test3_X12_834].doTest(t)
 }
 
+func Test4_X12_810(t *testing.T) {
+	tests[test4_X12_810].doTest(t)
+}
+
+func Test5_X12_850(t *testing.T) {
+	tests[test5_X12_850].doTest(t)
+}
+
+func Test6_X12_856(t *testing.T) {
+	tests[test6_X12_856].doTest(t)
+}
+
+func Test7_X12_214(t *testing.T) {
+	tests[test7_X12_214].doTest(t)
+}
+
+func Test8_EDIFACT_ORDERS(t *testing.T) {
+	tests[test8_EDIFACT_ORDERS].doTest(t)
+}
+
 func Test3_NonValidatingReader(t *testing.T) {
 	schemaFileReader, err := os.Open("./2_ups_edi_210.schema.json")
 	assert.NoError(t, err)
@@ -171,3 +221,28 @@ func Benchmark2_UPS_EDI_210(b *testing.B) {
 func Benchmark3_X12_834(b *testing.B) {
 	tests[test3_X12_834].doBenchmark(b)
 }
+
+// Benchmark4_X12_810-8 	    1558	    784906 ns/op	  130067 B/op	    3840 allocs/op
+func Benchmark4_X12_810(b *testing.B) {
+	tests[test4_X12_810].doBenchmark(b)
+}
+
+// Benchmark5_X12_850-8 	    2698	    423337 ns/op	   99325 B/op	    2832 allocs/op
+func Benchmark5_X12_850(b *testing.B) {
+	tests[test5_X12_850].doBenchmark(b)
+}
+
+// Benchmark6_X12_856-8 	    2718	    492900 ns/op	  109114 B/op	    3129 allocs/op
+func Benchmark6_X12_856(b *testing.B) {
+	tests[test6_X12_856].doBenchmark(b)
+}
+
+// Benchmark7_X12_214-8 	    1838	    638334 ns/op	  126453 B/op	    3668 allocs/op
+func Benchmark7_X12_214(b *testing.B) {
+	tests[test7_X12_214].doBenchmark(b)
+}
+
+// Benchmark8_EDIFACT_ORDERS-8 	    2742	    453298 ns/op	   93997 B/op	    2702 allocs/op
+func Benchmark8_EDIFACT_ORDERS(b *testing.B) {
+	tests[test8_EDIFACT_ORDERS].doBenchmark(b)
+}
@@ -0,0 +1,15 @@
+package fix
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_DropCopy(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_drop_copy.schema.json", "./1_drop_copy.input.log")))
+}
@@ -26,6 +26,7 @@ const (
 	test2_Multi_Rows
 	test3_Header_Footer
 	test4_Nested
+	test5_Binary
 )
 
 var tests = []testCase{
@@ -49,6 +50,11 @@ var tests = []testCase{
 		schemaFile: "./4_nested.schema.json",
 		inputFile:  "./4_nested.input.txt",
 	},
+	{
+		// test5_Binary
+		schemaFile: "./5_binary.schema.json",
+		inputFile:  "./5_binary.input.bin",
+	},
 }
 
 func init() {
@@ -107,6 +113,10 @@ func Test4_Nested(t *testing.T) {
 	tests[test4_Nested].doTest(t)
 }
 
+func Test5_Binary(t *testing.T) {
+	tests[test5_Binary].doTest(t)
+}
+
 // Benchmark1_Single_Row-8      	   25951	     45776 ns/op	   28213 B/op	     645 allocs/op
 func Benchmark1_Single_Row(b *testing.B) {
 	tests[test1_Single_Row].doBenchmark(b)
@@ -126,3 +136,8 @@ func Benchmark3_Header_Footer(b *testing.B) {
 func Benchmark4_Nested(b *testing.B) {
 	tests[test4_Nested].doBenchmark(b)
 }
+
+// Benchmark5_Binary-8          	   14635	     76950 ns/op	   30044 B/op	     690 allocs/op
+func Benchmark5_Binary(b *testing.B) {
+	tests[test5_Binary].doBenchmark(b)
+}
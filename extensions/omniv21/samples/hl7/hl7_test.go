@@ -0,0 +1,20 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_ADT(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_adt.schema.json", "./1_adt.input.txt")))
+}
+
+func Test2_ORU(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./2_oru.schema.json", "./2_oru.input.txt")))
+}
@@ -0,0 +1,15 @@
+package idoc
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_Orders(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_orders.schema.json", "./1_orders.input.txt")))
+}
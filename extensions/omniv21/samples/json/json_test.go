@@ -29,6 +29,16 @@ func Test3_XPathDynamic(t *testing.T) {
 		"./3_xpathdynamic.schema.json", "./3_xpathdynamic.input.json")))
 }
 
+func Test4_X12_999_Ack(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(t,
+		"./4_x12_999_ack.schema.json", "./4_x12_999_ack.input.json")))
+}
+
+func Test5_X12_824_Ack(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(t,
+		"./5_x12_824_ack.schema.json", "./5_x12_824_ack.input.json")))
+}
+
 var benchSchemaFile = "./2_multiple_objects.schema.json"
 var benchInputFile = "./2_multiple_objects.input.json"
 var benchSchema omniparser.Schema
@@ -0,0 +1,20 @@
+package nacha
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+
+	"github.com/logward/omniparser/extensions/omniv21/samples"
+)
+
+func Test1_PPD_CCD(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./1_ppd_ccd.schema.json", "./1_ppd_ccd.input.txt")))
+}
+
+func Test2_Returns(t *testing.T) {
+	cupaloy.SnapshotT(t, jsons.BPJ(samples.SampleTestCommon(
+		t, "./2_returns.schema.json", "./2_returns.input.txt")))
+}
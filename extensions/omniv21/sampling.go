@@ -0,0 +1,33 @@
+package omniv21
+
+import (
+	"math/rand"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+// sampler tells which records are sampled per transformctx.Sampling.
+type sampler struct {
+	every int
+	rate  float64
+	rand  *rand.Rand
+}
+
+func newSampler(s *transformctx.Sampling) *sampler {
+	if s == nil || (s.Every <= 0 && s.Rate <= 0) {
+		return nil
+	}
+	if s.Every > 0 {
+		return &sampler{every: s.Every}
+	}
+	return &sampler{rate: s.Rate, rand: rand.New(rand.NewSource(s.Seed))}
+}
+
+// sampled tells if the record of the given 1-based number is sampled. It must be called once per
+// record, in the order of the records.
+func (s *sampler) sampled(number int) bool {
+	if s.every > 0 {
+		return (number-1)%s.every == 0
+	}
+	return s.rand.Float64() < s.rate
+}
@@ -0,0 +1,87 @@
+package omniv21
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestNewSampler(t *testing.T) {
+	assert.Nil(t, newSampler(nil))
+	assert.Nil(t, newSampler(&transformctx.Sampling{}))
+	assert.Nil(t, newSampler(&transformctx.Sampling{Every: -1, Rate: -0.5}))
+	assert.Equal(t, &sampler{every: 3}, newSampler(&transformctx.Sampling{Every: 3, Rate: 0.5}))
+	assert.Equal(t, 0.5, newSampler(&transformctx.Sampling{Rate: 0.5}).rate)
+}
+
+func TestSampler_Sampled(t *testing.T) {
+	sampled := func(s *sampler, records int) []int {
+		var numbers []int
+		for number := 1; number <= records; number++ {
+			if s.sampled(number) {
+				numbers = append(numbers, number)
+			}
+		}
+		return numbers
+	}
+	assert.Equal(t, []int{1, 4, 7, 10}, sampled(newSampler(&transformctx.Sampling{Every: 3}), 10))
+	assert.Equal(t, []int{1, 2, 3}, sampled(newSampler(&transformctx.Sampling{Every: 1}), 3))
+
+	random := sampled(newSampler(&transformctx.Sampling{Rate: 0.1, Seed: 42}), 10000)
+	assert.InDelta(t, 1000, len(random), 100)
+	assert.Equal(t, random, sampled(newSampler(&transformctx.Sampling{Rate: 0.1, Seed: 42}), 10000))
+	assert.NotEqual(t, random, sampled(newSampler(&transformctx.Sampling{Rate: 0.1, Seed: 43}), 10000))
+	assert.Equal(t, 10, len(sampled(newSampler(&transformctx.Sampling{Rate: 1}), 10)))
+}
+
+func TestIngester_Read_Sampling(t *testing.T) {
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(
+		[]byte(` {
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": {
+					"number": { "custom_func": { "name": "record_number" }, "type": "int" },
+					"envelope": { "xpath": "../@id" },
+					"value": { "xpath": "." }
+				}}
+			}
+		}`), v21.OmniV21CustomFuncs, nil)
+	assert.NoError(t, err)
+	var nodes []*idr.Node
+	for _, envelopeID := range []string{"e1", "e2"} {
+		envelope := idr.CreateNode(idr.ElementNode, "env")
+		id := idr.CreateNode(idr.AttributeNode, "id")
+		idr.AddChild(id, idr.CreateNode(idr.TextNode, envelopeID))
+		idr.AddChild(envelope, id)
+		for _, value := range []string{"a", "b", "c"} {
+			n := idr.CreateNode(idr.ElementNode, "r")
+			idr.AddChild(n, idr.CreateNode(idr.TextNode, envelopeID+value))
+			idr.AddChild(envelope, n)
+			nodes = append(nodes, n)
+		}
+	}
+	reader := &testReader{result: nodes, err: make([]error, len(nodes))}
+	g := &ingester{
+		finalOutputDecl: finalOutputDecl,
+		customFuncs:     v21.OmniV21CustomFuncs,
+		reader:          reader,
+		sampler:         newSampler(&transformctx.Sampling{Every: 4}),
+	}
+	for _, expected := range []string{
+		`{"envelope":"e1","number":1,"value":"e1a"}`,
+		`{"envelope":"e2","number":5,"value":"e2b"}`,
+	} {
+		_, b, err := g.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(b))
+	}
+	_, _, err = g.Read()
+	assert.Equal(t, io.EOF, err)
+	// all the records are read, and released, even the ones not sampled.
+	assert.Equal(t, 6, reader.releaseCalled)
+}
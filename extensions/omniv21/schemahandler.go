@@ -6,12 +6,22 @@ import (
 
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/asn1"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/cargoimp"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/csv"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/edi"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/fix"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/fixedlength"
 	csv2 "github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile/csv"
 	fixedlength2 "github.com/logward/omniparser/extensions/omniv21/fileformat/flatfile/fixedlength"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/hl7"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/idoc"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/iso8583"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/json"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/nacha"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/parquet"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/pdf"
+	"github.com/logward/omniparser/extensions/omniv21/fileformat/swiftmt"
 	"github.com/logward/omniparser/extensions/omniv21/fileformat/xml"
 	"github.com/logward/omniparser/extensions/omniv21/transform"
 	v21validation "github.com/logward/omniparser/extensions/omniv21/validation"
@@ -49,6 +59,31 @@ func CreateSchemaHandler(ctx *schemahandler.CreateCtx) (schemahandler.SchemaHand
 			"schema '%s' 'transform_declarations' validation failed: %s",
 			ctx.Name, err.Error())
 	}
+	recordOrder, err := transform.ValidateRecordOrder(ctx.Content, ctx.CustomFuncs, customParseFuncs(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("schema '%s' 'record_order' validation failed: %s", ctx.Name, err.Error())
+	}
+	if recordOrder != nil {
+		recordOrder.Relate(finalOutputDecl)
+	}
+	fileHooks, err := transform.ValidateFileHooks(ctx.Content, ctx.CustomFuncs, customParseFuncs(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("schema '%s' 'file_header'/'file_trailer' validation failed: %s", ctx.Name, err.Error())
+	}
+	if fileHooks != nil {
+		fileHooks.Relate(finalOutputDecl)
+	}
+	recordContext, err := transform.ValidateRecordContext(ctx.Content, ctx.CustomFuncs, customParseFuncs(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("schema '%s' 'record_context' validation failed: %s", ctx.Name, err.Error())
+	}
+	if recordContext != nil {
+		recordContext.Relate(finalOutputDecl)
+	}
+	inputSort, err := validateInputSort(ctx.Content, ctx.Header.ParserSettings.FileFormatType)
+	if err != nil {
+		return nil, fmt.Errorf("schema '%s' 'input_sort' validation failed: %s", ctx.Name, err.Error())
+	}
 	for _, fileFormat := range fileFormats(ctx) {
 		formatRuntime, err := fileFormat.ValidateSchema(
 			ctx.Header.ParserSettings.FileFormatType,
@@ -66,6 +101,10 @@ func CreateSchemaHandler(ctx *schemahandler.CreateCtx) (schemahandler.SchemaHand
 			fileFormat:      fileFormat,
 			formatRuntime:   formatRuntime,
 			finalOutputDecl: finalOutputDecl,
+			recordOrder:     recordOrder,
+			fileHooks:       fileHooks,
+			recordContext:   recordContext,
+			inputSort:       inputSort,
 		}, nil
 	}
 	return nil, errs.ErrSchemaNotSupported
@@ -85,14 +124,61 @@ func customParseFuncs(ctx *schemahandler.CreateCtx) transform.CustomParseFuncs {
 	return params.CustomParseFuncs
 }
 
+// FileFormatType describes a `parser_settings.file_format_type` supported by a builtin file format of
+// this schema handler.
+type FileFormatType struct {
+	Name       string
+	Deprecated bool // superseded by another file format type, e.g. 'csv' by 'csv2'.
+}
+
+// FileFormatTypes returns the file format types supported by the builtin file formats, in the order
+// the file formats are tried.
+func FileFormatTypes() []FileFormatType {
+	// keep in the same order as fileFormats.
+	return []FileFormatType{
+		{Name: "asn1"},
+		{Name: "cargoimp"},
+		{Name: "csv", Deprecated: true},
+		{Name: "csv2"},
+		{Name: "edi"},
+		{Name: "fix"},
+		{Name: "fixed-length", Deprecated: true},
+		{Name: "fixedlength2"},
+		{Name: "hl7"},
+		{Name: "idoc"},
+		{Name: "iso8583"},
+		{Name: "json"},
+		{Name: "nacha"},
+		{Name: "parquet"},
+		{Name: "pdf"},
+		{Name: "swiftmt"},
+		{Name: "xml"},
+	}
+}
+
+// Version returns the schema version, i.e. `parser_settings.version`, supported by this schema handler.
+func Version() string {
+	return version
+}
+
 func fileFormats(ctx *schemahandler.CreateCtx) []fileformat.FileFormat {
 	formats := []fileformat.FileFormat{
+		asn1.NewASN1FileFormat(ctx.Name),
+		cargoimp.NewCargoIMPFileFormat(ctx.Name),
 		csv.NewCSVFileFormat(ctx.Name),
 		csv2.NewCSVFileFormat(ctx.Name),
 		edi.NewEDIFileFormat(ctx.Name),
+		fix.NewFIXFileFormat(ctx.Name),
 		fixedlength.NewFixedLengthFileFormat(ctx.Name),
 		fixedlength2.NewFixedLengthFileFormat(ctx.Name),
+		hl7.NewHL7FileFormat(ctx.Name),
+		idoc.NewIDocFileFormat(ctx.Name),
+		iso8583.NewISO8583FileFormat(ctx.Name),
 		json.NewJSONFileFormat(ctx.Name),
+		nacha.NewNACHAFileFormat(ctx.Name),
+		parquet.NewParquetFileFormat(ctx.Name),
+		pdf.NewPDFFileFormat(ctx.Name),
+		swiftmt.NewSwiftMTFileFormat(ctx.Name),
 		xml.NewXMLFileFormat(ctx.Name),
 	}
 	if ctx.CreateParams == nil {
@@ -112,18 +198,79 @@ type schemaHandler struct {
 	fileFormat      fileformat.FileFormat
 	formatRuntime   interface{}
 	finalOutputDecl *transform.Decl
+	recordOrder     *transform.RecordOrder   // nil if the schema has no `record_order`.
+	fileHooks       *transform.FileHooks     // nil if the schema has neither `file_header` nor `file_trailer`.
+	recordContext   *transform.RecordContext // nil if the schema has no `record_context`.
+	inputSort       *InputSort               // nil if the schema has no `input_sort`.
 }
 
 func (h *schemaHandler) NewIngester(ctx *transformctx.Ctx, input io.Reader) (schemahandler.Ingester, error) {
-	reader, err := h.fileFormat.CreateFormatReader(ctx.InputName, input, h.formatRuntime)
+	runtime := h.formatRuntime
+	if applier, ok := h.fileFormat.(fileformat.ProfileApplier); ok && ctx.Profile != nil {
+		var err error
+		if runtime, err = applier.ApplyProfile(runtime, ctx.Profile); err != nil {
+			return nil, fmt.Errorf("profile '%s': %s", ctx.Profile.Name, err.Error())
+		}
+	}
+	var sortStats schemahandler.SpillStats
+	if h.inputSort != nil {
+		var err error
+		if input, sortStats, err = sortInput(h.inputSort, input); err != nil {
+			return nil, err
+		}
+	}
+	reader, err := h.fileFormat.CreateFormatReader(ctx.InputName, input, runtime)
 	if err != nil {
 		return nil, err
 	}
-	return &ingester{
+	g := &ingester{
 		finalOutputDecl:  h.finalOutputDecl,
 		customFuncs:      h.ctx.CustomFuncs,
 		customParseFuncs: customParseFuncs(h.ctx),
 		ctx:              ctx,
 		reader:           reader,
-	}, nil
+		indexRecords:     h.ctx.Header.ParserSettings.IndexRecords,
+		rawRecord:        rawRecord{ownRawBytes: ctx != nil && ctx.OwnRawBytes},
+		rawRecordOnly:    ctx != nil && ctx.RawRecordOnly,
+		sortStats:        sortStats,
+		inputSorted:      h.inputSort != nil,
+	}
+	if ctx != nil && ctx.CollectStats && !g.rawRecordOnly {
+		g.stats = transform.NewStats()
+	}
+	if ctx != nil {
+		g.sampler = newSampler(ctx.Sampling)
+	}
+	if h.recordOrder != nil && !g.rawRecordOnly {
+		g.resequencer = newResequencer(h.recordOrder)
+	}
+	if h.fileHooks != nil && !g.rawRecordOnly {
+		g.fileHooker = newFileHooker(h.fileHooks)
+	}
+	if !g.rawRecordOnly {
+		g.recordContext = h.recordContext
+	}
+	return g, nil
+}
+
+// DebugRecord implements schemahandler.RecordDebugger.
+func (h *schemaHandler) DebugRecord(
+	ctx *transformctx.Ctx, input io.Reader, target string) ([]byte, error) {
+
+	decl := h.finalOutputDecl
+	if target != "" {
+		// target is either a `transform_declarations` name, or, if decl turns out nil, an xpath.
+		var err error
+		decl, err = transform.ValidateNamedDeclaration(
+			h.ctx.Content, target, h.ctx.CustomFuncs, customParseFuncs(h.ctx))
+		if err != nil {
+			return nil, fmt.Errorf(
+				"schema '%s' 'transform_declarations' '%s' validation failed: %s", h.ctx.Name, target, err.Error())
+		}
+	}
+	g, err := h.NewIngester(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return g.(*ingester).debug(decl, target)
 }
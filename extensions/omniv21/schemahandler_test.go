@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jf-tech/go-corelib/jsons"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/customfuncs"
@@ -143,7 +144,7 @@ func TestCreateHandler_HandlerParamsTypeNotRight_Fallback(t *testing.T) {
 		})
 	assert.NoError(t, err)
 	assert.IsType(t, json.NewJSONFileFormat(""), p.(*schemaHandler).fileFormat)
-	assert.Equal(t, ".", p.(*schemaHandler).formatRuntime.(string))
+	assert.JSONEq(t, `{"file_declaration": {}, "XPath": "."}`, jsons.BPM(p.(*schemaHandler).formatRuntime))
 }
 
 func TestCreateHandler_CustomFileFormat_FormatNotSupported_Fallback(t *testing.T) {
@@ -166,7 +167,7 @@ func TestCreateHandler_CustomFileFormat_FormatNotSupported_Fallback(t *testing.T
 		})
 	assert.NoError(t, err)
 	assert.IsType(t, json.NewJSONFileFormat(""), p.(*schemaHandler).fileFormat)
-	assert.Equal(t, ".", p.(*schemaHandler).formatRuntime.(string))
+	assert.JSONEq(t, `{"file_declaration": {}, "XPath": "."}`, jsons.BPM(p.(*schemaHandler).formatRuntime))
 }
 
 func TestCreateHandler_CustomFileFormat_ValidationFailure(t *testing.T) {
@@ -267,3 +268,148 @@ func TestNewIngester_CustomFileFormat_Success(t *testing.T) {
 	assert.Equal(t, "test input", string(data))
 	assert.Equal(t, "test runtime", r.runtime.(string))
 }
+
+type testProfileFileFormat struct {
+	testFileFormat
+	applyProfileErr error
+}
+
+func (f testProfileFileFormat) ApplyProfile(
+	runtime interface{}, profile *transformctx.Profile) (interface{}, error) {
+	if f.applyProfileErr != nil {
+		return nil, f.applyProfileErr
+	}
+	return runtime.(string) + " with profile " + profile.Name, nil
+}
+
+func TestNewIngester_Profile(t *testing.T) {
+	handler := &schemaHandler{
+		ctx:           &schemahandler.CreateCtx{},
+		fileFormat:    testProfileFileFormat{},
+		formatRuntime: "test runtime",
+	}
+	ip, err := handler.NewIngester(&transformctx.Ctx{InputName: "test-input"}, strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "test runtime", ip.(*ingester).reader.(testFormatReader).runtime.(string))
+
+	ctx := &transformctx.Ctx{InputName: "test-input", Profile: &transformctx.Profile{Name: "acme"}}
+	ip, err = handler.NewIngester(ctx, strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "test runtime with profile acme", ip.(*ingester).reader.(testFormatReader).runtime.(string))
+	// the original runtime is intact for other input streams.
+	assert.Equal(t, "test runtime", handler.formatRuntime)
+
+	handler.fileFormat = testProfileFileFormat{applyProfileErr: errors.New("bad delimiters")}
+	ip, err = handler.NewIngester(ctx, strings.NewReader(""))
+	assert.Error(t, err)
+	assert.Equal(t, "profile 'acme': bad delimiters", err.Error())
+	assert.Nil(t, ip)
+}
+
+func TestFileFormatTypes(t *testing.T) {
+	formats := fileFormats(&schemahandler.CreateCtx{Name: "test-schema"})
+	types := FileFormatTypes()
+	assert.Equal(t, len(formats), len(types))
+	for _, fileFormatType := range types {
+		for i, format := range formats {
+			_, err := format.ValidateSchema(fileFormatType.Name, []byte(`{}`), nil)
+			// only the file format of the same index supports the file format type.
+			assert.Equal(t, i != indexOfFileFormatType(types, fileFormatType.Name),
+				err == errs.ErrSchemaNotSupported, fileFormatType.Name)
+		}
+	}
+	assert.Equal(t, "omni.2.1", Version())
+}
+
+func indexOfFileFormatType(types []FileFormatType, name string) int {
+	for i, t := range types {
+		if t.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSchemaHandler_DebugRecord(t *testing.T) {
+	content := []byte(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "json" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/*", "object": { "id": { "xpath": "id" }, "name": { "template": "upper_name" } } },
+			"upper_name": { "custom_func": { "name": "upper", "args": [ { "xpath": "name" } ] } },
+			"unused": { "custom_func": { "name": "no_such_func" } }
+		}
+	}`)
+	h, err := CreateSchemaHandler(&schemahandler.CreateCtx{
+		Name: "test-schema",
+		Header: header.Header{
+			ParserSettings: header.ParserSettings{Version: version, FileFormatType: "json"},
+		},
+		Content:     content,
+		CustomFuncs: customfuncs.CommonCustomFuncs,
+	})
+	assert.NoError(t, err)
+	debugger := h.(schemahandler.RecordDebugger)
+	input := `[{"id": "1", "name": "a"}, {"id": "2", "name": "b"}]`
+	for _, test := range []struct {
+		name     string
+		target   string
+		input    string
+		expected string
+		err      string
+	}{
+		{
+			name:   "FINAL_OUTPUT by default",
+			target: "",
+			input:  input,
+			expected: `{"record":{"id":"1","name":"a"},"trace":{"fqdn":"FINAL_OUTPUT","kind":"object","node":"/*",` +
+				`"value":{"id":"1","name":"A"},"children":[` +
+				`{"fqdn":"FINAL_OUTPUT.id","kind":"field","node":"/*","queries":[{"xpath":"id","matched":["/*/id"]}],"value":"1"},` +
+				`{"fqdn":"FINAL_OUTPUT.name","kind":"custom_func","node":"/*",` +
+				`"custom_func":{"name":"upper","args":["a"],"result":"A"},"value":"A","children":[` +
+				`{"fqdn":"FINAL_OUTPUT.name.custom_func(upper).arg[1]","kind":"field","node":"/*",` +
+				`"queries":[{"xpath":"name","matched":["/*/name"]}],"value":"a"}]}]}}`,
+		},
+		{
+			name:   "template",
+			target: "upper_name",
+			input:  input,
+			expected: `{"record":{"id":"1","name":"a"},"trace":{"fqdn":"upper_name","kind":"custom_func","node":"/*",` +
+				`"custom_func":{"name":"upper","args":["a"],"result":"A"},"value":"A","children":[` +
+				`{"fqdn":"upper_name.custom_func(upper).arg[1]","kind":"field","node":"/*",` +
+				`"queries":[{"xpath":"name","matched":["/*/name"]}],"value":"a"}]}}`,
+		},
+		{
+			name:   "xpath",
+			target: "*",
+			input:  input,
+			expected: `{"record":{"id":"1","name":"a"},"trace":{"fqdn":"*","kind":"xpath","node":"/*",` +
+				`"queries":[{"xpath":"*","matched":["/*/id","/*/name"]}],"value":["1","a"]}}`,
+		},
+		{
+			name:   "invalid template",
+			target: "unused",
+			input:  input,
+			err: "schema 'test-schema' 'transform_declarations' 'unused' validation failed: " +
+				"unknown custom_func 'no_such_func' on 'unused'",
+		},
+		{
+			name:   "no record",
+			target: "",
+			input:  `[]`,
+			err:    "EOF",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trace, err := debugger.DebugRecord(
+				&transformctx.Ctx{InputName: "test-input"}, strings.NewReader(test.input), test.target)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, trace)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(trace))
+		})
+	}
+}
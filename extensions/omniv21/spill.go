@@ -0,0 +1,258 @@
+package omniv21
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/logward/omniparser/extensions/omniv21/fileformat"
+	"github.com/logward/omniparser/idr"
+)
+
+func init() {
+	// The FormatSpecific values of the IDR nodes of the built-in file formats.
+	gob.Register(idr.XMLSpecific{})
+	gob.Register(idr.JSONType(0))
+}
+
+// nodeOverhead approximates the memory used by an IDR node, besides its data.
+const nodeOverhead = 128
+
+// recordSize approximates the memory used by a buffered record.
+func recordSize(rec *orderedRecord) int64 {
+	size := int64(len(rec.transformed) + len(rec.rawRecord.bytes) + len(rec.rawRecord.rawBytes))
+	var nodeSize func(n *idr.Node)
+	nodeSize = func(n *idr.Node) {
+		size += int64(nodeOverhead + len(n.Data))
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			nodeSize(c)
+		}
+	}
+	nodeSize(rec.rawRecord.node)
+	return size
+}
+
+// spilledNode is the encoding of an IDR node, and its subtree, in a spill file.
+type spilledNode struct {
+	Type           idr.NodeType
+	Data           string
+	FormatSpecific interface{}
+	Children       []*spilledNode
+}
+
+func toSpilledNode(n *idr.Node) *spilledNode {
+	sn := &spilledNode{Type: n.Type, Data: n.Data, FormatSpecific: n.FormatSpecific}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sn.Children = append(sn.Children, toSpilledNode(c))
+	}
+	return sn
+}
+
+func (sn *spilledNode) toNode() *idr.Node {
+	n := idr.CreateNode(sn.Type, sn.Data)
+	n.FormatSpecific = sn.FormatSpecific
+	for _, c := range sn.Children {
+		idr.AddChild(n, c.toNode())
+	}
+	return n
+}
+
+// spilledRecord is the encoding of a buffered record in a spill file. Keys are encoded in JSON, which
+// keeps their order as per compareKeys, as numbers are decoded into json.Number.
+type spilledRecord struct {
+	Node               *spilledNode
+	Ordinal            int
+	PosBegin, PosEnd   int
+	SrcBegin, SrcEnd   fileformat.Position
+	HasSrcPos          bool
+	Bytes, RawBytes    []byte
+	OwnRawBytes        bool
+	Checksum, RecordID string
+	Transformed        []byte
+	Key, Group         []byte
+}
+
+func toSpilledRecord(rec *orderedRecord) (*spilledRecord, error) {
+	key, err := json.Marshal(rec.key)
+	if err != nil {
+		return nil, err
+	}
+	group, err := json.Marshal(rec.group)
+	if err != nil {
+		return nil, err
+	}
+	rr := &rec.rawRecord
+	return &spilledRecord{
+		Node:        toSpilledNode(rr.node),
+		Ordinal:     rr.ordinal,
+		PosBegin:    rr.posBegin,
+		PosEnd:      rr.posEnd,
+		SrcBegin:    rr.srcBegin,
+		SrcEnd:      rr.srcEnd,
+		HasSrcPos:   rr.hasSrcPos,
+		Bytes:       rr.bytes,
+		RawBytes:    rr.rawBytes,
+		OwnRawBytes: rr.ownRawBytes,
+		Checksum:    rr.checksum,
+		RecordID:    rr.recordID,
+		Transformed: rec.transformed,
+		Key:         key,
+		Group:       group,
+	}, nil
+}
+
+func decodeKey(b []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	err := d.Decode(&v)
+	return v, err
+}
+
+func (sr *spilledRecord) toOrderedRecord() (*orderedRecord, error) {
+	key, err := decodeKey(sr.Key)
+	if err != nil {
+		return nil, err
+	}
+	group, err := decodeKey(sr.Group)
+	if err != nil {
+		return nil, err
+	}
+	return &orderedRecord{
+		rawRecord: rawRecord{
+			node:        sr.Node.toNode(),
+			ordinal:     sr.Ordinal,
+			posBegin:    sr.PosBegin,
+			posEnd:      sr.PosEnd,
+			srcBegin:    sr.SrcBegin,
+			srcEnd:      sr.SrcEnd,
+			hasSrcPos:   sr.HasSrcPos,
+			bytes:       sr.Bytes,
+			rawBytes:    sr.RawBytes,
+			ownRawBytes: sr.OwnRawBytes,
+			checksum:    sr.Checksum,
+			recordID:    sr.RecordID,
+		},
+		transformed: sr.Transformed,
+		key:         key,
+		group:       group,
+	}, nil
+}
+
+// spillRun is a temp file of records in the ascending order, spilled from the buffer of a resequencer,
+// read back one record at a time.
+type spillRun struct {
+	f     *os.File
+	dec   *gob.Decoder
+	head  *orderedRecord // next record of the run.
+	count int            // number of records of the run not yet read.
+	size  int64          // size of the file.
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// newSpillRun writes count records, got from next in the ascending order, into a new temp file, and
+// reads back the first one. The IDR nodes of the records written are released. It fails with
+// errSpillTooLarge if tooLarge tells the bytes written so far are too many.
+func newSpillRun(
+	count int, next func() (*orderedRecord, error), tooLarge func(written int64) bool) (run *spillRun, err error) {
+
+	f, err := os.CreateTemp("", "omniparser-spill-*")
+	if err != nil {
+		return nil, err
+	}
+	// Where supported, e.g. on Unix, the file is removed right away, while still being usable, so it
+	// doesn't outlive the process even if the transform is abandoned before the run is exhausted.
+	_ = os.Remove(f.Name())
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+	w := bufio.NewWriter(f)
+	cw := &countingWriter{w: w}
+	enc := gob.NewEncoder(cw)
+	for i := 0; i < count; i++ {
+		rec, err := next()
+		if err != nil {
+			return nil, err
+		}
+		sr, err := toSpilledRecord(rec)
+		idr.RemoveAndReleaseTree(rec.rawRecord.node)
+		if err != nil {
+			return nil, err
+		}
+		if err = enc.Encode(sr); err != nil {
+			return nil, err
+		}
+		if tooLarge(cw.n) {
+			return nil, errSpillTooLarge
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return nil, err
+	}
+	run = &spillRun{f: f, count: count, size: cw.n}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	run.dec = gob.NewDecoder(bufio.NewReader(f))
+	if err = run.next(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+var errSpillTooLarge = errors.New("spill files too large")
+
+// ErrSpillFailed indicates the records buffered by the `record_order` of a schema can't be spilled to, or
+// read back from, disk. This is a fatal, non-continuable error.
+type ErrSpillFailed string
+
+func (e ErrSpillFailed) Error() string { return string(e) }
+
+// IsErrSpillFailed checks if the `err` is of ErrSpillFailed type.
+func IsErrSpillFailed(err error) bool {
+	_, ok := err.(ErrSpillFailed)
+	return ok
+}
+
+// next reads the next record of the run into head, or sets head to nil if the run is exhausted.
+func (run *spillRun) next() error {
+	if run.count == 0 {
+		run.head = nil
+		return nil
+	}
+	run.count--
+	var sr spilledRecord
+	if err := run.dec.Decode(&sr); err != nil {
+		return ErrSpillFailed(fmt.Sprintf("unable to read spill file '%s': %s", run.f.Name(), err.Error()))
+	}
+	rec, err := sr.toOrderedRecord()
+	if err != nil {
+		return ErrSpillFailed(fmt.Sprintf("unable to read spill file '%s': %s", run.f.Name(), err.Error()))
+	}
+	run.head = rec
+	return nil
+}
+
+func (run *spillRun) close() {
+	_ = run.f.Close()
+	_ = os.Remove(run.f.Name())
+}
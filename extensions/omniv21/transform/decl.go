@@ -14,6 +14,8 @@ type kind string
 const (
 	kindConst       kind = "const"
 	kindExternal    kind = "external"
+	kindConstant    kind = "constant"
+	kindVar         kind = "var"
 	kindField       kind = "field"
 	kindObject      kind = "object"
 	kindArray       kind = "array"
@@ -31,6 +33,9 @@ const (
 	resultTypeFloat   resultType = "float"
 	resultTypeBoolean resultType = "boolean"
 	resultTypeString  resultType = "string"
+	// resultTypeNumber outputs a JSON number verbatim, without going through int64 or float64,
+	// so large integers and high-precision decimals are preserved exactly.
+	resultTypeNumber resultType = "number"
 )
 
 const (
@@ -39,12 +44,31 @@ const (
 	finalOutput = "FINAL_OUTPUT"
 )
 
+// constantValue is a schema-level constant declared in the `constants` section: either a single string
+// value or a mapping table from string keys to string values.
+type constantValue struct {
+	value *string
+	table map[string]string
+}
+
+func (c *constantValue) UnmarshalJSON(b []byte) error {
+	var value string
+	if err := json.Unmarshal(b, &value); err == nil {
+		c.value = &value
+		return nil
+	}
+	return json.Unmarshal(b, &c.table)
+}
+
 // CustomFuncDecl is the decl for a "custom_func".
 type CustomFuncDecl struct {
 	Name        string  `json:"name,omitempty"`
 	Args        []*Decl `json:"args,omitempty"`
 	IgnoreError bool    `json:"ignore_error,omitempty"`
+	Memoize     bool    `json:"memoize,omitempty"`
 	fqdn        string  // internal; never unmarshaled from a schema.
+	nodeArg     bool    // internal; whether the custom func takes *idr.Node as its secondary default arg.
+	nodesResult bool    // internal; whether the custom func returns *idr.Node or []*idr.Node.
 }
 
 // MarshalJSON is the custom JSON marshaler for CustomFuncDecl.
@@ -68,6 +92,7 @@ func (d *CustomFuncDecl) deepCopy() *CustomFuncDecl {
 		dest.Args = append(dest.Args, argDecl.deepCopy())
 	}
 	dest.IgnoreError = d.IgnoreError
+	dest.Memoize = d.Memoize
 	return dest
 }
 
@@ -77,6 +102,12 @@ type Decl struct {
 	Const *string `json:"const,omitempty"`
 	// External indicates the input element is from an external property.
 	External *string `json:"external,omitempty"`
+	// Constant indicates the input element is from a schema-level constant in the `constants` section.
+	Constant *string `json:"constant,omitempty"`
+	// Key specifies the key to look up, if Constant references a mapping table.
+	Key *Decl `json:"key,omitempty"`
+	// Var indicates the input element is the value of a var declared by an enclosing object.
+	Var *string `json:"var,omitempty"`
 	// XPath specifies an xpath for an input element.
 	XPath *string `json:"xpath,omitempty"`
 	// XPathDynamic specifies a dynamically constructed xpath for an input element.
@@ -89,10 +120,15 @@ type Decl struct {
 	Template *string `json:"template,omitempty"`
 	// Object specifies the input element is an object.
 	Object map[string]*Decl `json:"object,omitempty"`
+	// Vars specifies the vars an object declares, computed on the object's node, for the decls under
+	// the object, including the ones of the templates it references, to reference by name.
+	Vars map[string]*Decl `json:"vars,omitempty"`
 	// Array specifies the input element is an array.
 	Array []*Decl `json:"array,omitempty"`
 	// ResultType specifies the desired output type of element.
 	ResultType *resultType `json:"type,omitempty"`
+	// NumberFormat specifies how a numeric value is formatted, before being converted to ResultType.
+	NumberFormat *NumberFormat `json:"number_format,omitempty"`
 	// NoTrim specifies space trimming in string value of the output element.
 	NoTrim bool `json:"no_trim,omitempty"`
 	// KeepEmptyOrNull specifies whether to keep an empty/null output or not.
@@ -104,6 +140,10 @@ type Decl struct {
 	hash     string
 	children []*Decl
 	parent   *Decl
+	constant *constantValue // resolved from the `constants` section if kind is kindConstant.
+	template string         // name of the template the decl is expanded from, if any.
+	varNames []string       // names of the Vars, sorted.
+	related  []relatedDecl  // decls evaluated along with the `FINAL_OUTPUT` one, see AnalyzeReferences.
 }
 
 // MarshalJSON is the custom JSON marshaler for Decl.
@@ -145,6 +185,10 @@ func (d *Decl) resolveKind() {
 		d.kind = kindConst
 	case d.External != nil:
 		d.kind = kindExternal
+	case d.Constant != nil:
+		d.kind = kindConstant
+	case d.Var != nil:
+		d.kind = kindVar
 	case d.CustomFunc != nil:
 		d.kind = kindCustomFunc
 	case d.CustomParse != nil:
@@ -170,6 +214,11 @@ func (d *Decl) deepCopy() *Decl {
 	dest := &Decl{}
 	dest.Const = strs.CopyStrPtr(d.Const)
 	dest.External = strs.CopyStrPtr(d.External)
+	dest.Constant = strs.CopyStrPtr(d.Constant)
+	if d.Key != nil {
+		dest.Key = d.Key.deepCopy()
+	}
+	dest.Var = strs.CopyStrPtr(d.Var)
 	dest.XPath = strs.CopyStrPtr(d.XPath)
 	if d.XPathDynamic != nil {
 		dest.XPathDynamic = d.XPathDynamic.deepCopy()
@@ -185,6 +234,12 @@ func (d *Decl) deepCopy() *Decl {
 			dest.Object[childName] = childDecl.deepCopy()
 		}
 	}
+	if len(d.Vars) > 0 {
+		dest.Vars = map[string]*Decl{}
+		for name, varDecl := range d.Vars {
+			dest.Vars[name] = varDecl.deepCopy()
+		}
+	}
 	for _, childDecl := range d.Array {
 		dest.Array = append(dest.Array, childDecl.deepCopy())
 	}
@@ -192,6 +247,14 @@ func (d *Decl) deepCopy() *Decl {
 		rt := *d.ResultType
 		dest.ResultType = &rt
 	}
+	if d.NumberFormat != nil {
+		nf := *d.NumberFormat
+		if d.NumberFormat.DecimalPlaces != nil {
+			dp := *d.NumberFormat.DecimalPlaces
+			nf.DecimalPlaces = &dp
+		}
+		dest.NumberFormat = &nf
+	}
 	dest.NoTrim = d.NoTrim
 	dest.KeepEmptyOrNull = d.KeepEmptyOrNull
 	return dest
@@ -202,13 +202,18 @@ func verifyDeclDeepCopy(t *testing.T, d1, d2 *Decl) {
 	}
 
 	verifyPtrsInDeepCopy(d1.ResultType, d2.ResultType)
+
+	verifyPtrsInDeepCopy(d1.NumberFormat, d2.NumberFormat)
+	if d1.NumberFormat != nil {
+		verifyPtrsInDeepCopy(d1.NumberFormat.DecimalPlaces, d2.NumberFormat.DecimalPlaces)
+	}
 }
 
 func TestDeclDeepCopy(t *testing.T) {
 	declJson := `{ "xpath": "value0", "object": {
         "field1": { "const": "value1", "type": "boolean" },
         "field2": { "external": "value2" },
-        "field3": { "xpath": "value3" },
+        "field3": { "xpath": "value3", "number_format": { "decimal_places": 2, "rounding": "half_even" } },
         "field4": { "xpath_dynamic": { "const": "value4" } },
         "field5": { "custom_func": {
             "name": "func5",
@@ -0,0 +1,63 @@
+package transform
+
+import (
+	"encoding/json"
+
+	"github.com/logward/omniparser/customfuncs"
+)
+
+const (
+	fileHeader  = "file_header"
+	fileTrailer = "file_trailer"
+)
+
+// FileHooks contains the `file_header` and `file_trailer` sections of an omni schema, each evaluated
+// once per input, on the root of the input's IDR tree, and emitted as an extra record before the first
+// record and after the last record, respectively.
+type FileHooks struct {
+	// Header, if not nil, is evaluated upon the first record, thus has access to the envelope data read
+	// so far, e.g. the ISA/GS segments of an EDI input.
+	Header *Decl
+	// Trailer, if not nil, is evaluated at the end of the input, thus has access to all the envelope
+	// data, e.g. the IEA/GE segments of an EDI input, and the record counters of the whole input.
+	Trailer *Decl
+}
+
+// ValidateFileHooks validates the `file_header` and `file_trailer` sections of an omni schema and
+// returns them, or nil if there is neither. ValidateTransformDeclarations must have succeeded on the
+// schema.
+func ValidateFileHooks(
+	schemaContent []byte, customFuncs customfuncs.CustomFuncs, customParseFuncs CustomParseFuncs) (*FileHooks, error) {
+
+	var ctx validateCtx
+	_ = json.Unmarshal(schemaContent, &ctx)
+	if ctx.FileHeader == nil && ctx.FileTrailer == nil {
+		return nil, nil
+	}
+	ctx.customFuncs = customFuncs
+	ctx.customParseFuncs = customParseFuncs
+	ctx.declHashes = map[string]string{}
+
+	hooks := &FileHooks{}
+	var err error
+	if ctx.FileHeader != nil {
+		if hooks.Header, err = ctx.validateDecl(fileHeader, ctx.FileHeader); err != nil {
+			return nil, err
+		}
+		linkParent(hooks.Header)
+	}
+	if ctx.FileTrailer != nil {
+		if hooks.Trailer, err = ctx.validateDecl(fileTrailer, ctx.FileTrailer); err != nil {
+			return nil, err
+		}
+		linkParent(hooks.Trailer)
+	}
+	return hooks, nil
+}
+
+// Relate makes AnalyzeReferences on the `FINAL_OUTPUT` decl take the hooks into account, for file format
+// readers not to skip the elements only the hooks reference.
+func (h *FileHooks) Relate(finalOutputDecl *Decl) {
+	relate(finalOutputDecl, h.Header, refCtxRoot)
+	relate(finalOutputDecl, h.Trailer, refCtxRoot)
+}
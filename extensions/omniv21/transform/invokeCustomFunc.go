@@ -3,6 +3,9 @@ package transform
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/logward/omniparser/idr"
 )
@@ -15,10 +18,33 @@ func (p *parseCtx) invokeCustomFunc(n *idr.Node, customFuncDecl *CustomFuncDecl)
 	if err != nil {
 		return nil, err
 	}
+	// Like the transform cache, memoization is off when tracing, so that repeated invocations are
+	// recorded as well.
+	memoize := customFuncDecl.Memoize && !p.disableTransformCache
+	var key string
+	if memoize {
+		key = memoKey(customFuncDecl.Name, argValues)
+		if v, found := p.memo[key]; found {
+			return v, nil
+		}
+	}
+	var start time.Time
+	if p.stats != nil {
+		start = time.Now()
+	}
 	result := reflect.ValueOf(fn).Call(argValues)
+	if p.stats != nil {
+		addInvocation(p.stats.CustomFuncs, customFuncDecl.Name, start)
+	}
+	if p.trace != nil {
+		p.traceCustomFunc(customFuncDecl, argValues, result)
+	}
 	// result[0] - result from custom function
 	// result[1] - error from custom function
 	if result[1].Interface() == nil {
+		if memoize {
+			p.memo[key] = result[0].Interface()
+		}
 		return result[0].Interface(), nil
 	}
 	if customFuncDecl.IgnoreError {
@@ -27,6 +53,22 @@ func (p *parseCtx) invokeCustomFunc(n *idr.Node, customFuncDecl *CustomFuncDecl)
 	return nil, fmt.Errorf("'%s' failed: %s", customFuncDecl.fqdn, result[1].Interface().(error).Error())
 }
 
+// memoKey returns the key of a custom func invocation in the memo: the custom func name and the values
+// of its args, except the ctx, with the contextual node, if any, identified by its ID.
+func memoKey(name string, argValues []reflect.Value) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, arg := range argValues[1:] {
+		sb.WriteByte(0)
+		if n, ok := arg.Interface().(*idr.Node); ok && n != nil {
+			sb.WriteString("node:" + strconv.FormatInt(n.ID, 16))
+			continue
+		}
+		fmt.Fprintf(&sb, "%T:%v", arg.Interface(), arg.Interface())
+	}
+	return sb.String()
+}
+
 func (p *parseCtx) prepArgValues(
 	n *idr.Node, customFuncDecl *CustomFuncDecl, fnType reflect.Type) ([]reflect.Value, error) {
 
@@ -1,12 +1,16 @@
 package transform
 
 import (
+	"errors"
+	"strconv"
 	"testing"
 
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
 )
 
 func TestInvokeCustomFunc(t *testing.T) {
@@ -112,3 +116,58 @@ func TestInvokeCustomFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestInvokeCustomFunc_Memoize(t *testing.T) {
+	calls := 0
+	p := NewParseCtx(nil, customfuncs.CustomFuncs{
+		"count": func(_ *transformctx.Ctx, n *idr.Node, s string) (string, error) {
+			calls++
+			if s == "fail" {
+				return "", errors.New("fail")
+			}
+			return n.Data + s + strconv.Itoa(calls), nil
+		},
+	}, nil)
+	decl := func(memoize bool, arg string) *CustomFuncDecl {
+		return &CustomFuncDecl{
+			Name:    "count",
+			Args:    []*Decl{{Const: strs.StrPtr(arg), kind: kindConst, fqdn: "arg", hash: arg}},
+			Memoize: memoize,
+			fqdn:    "count",
+		}
+	}
+	n := testNode()
+	for _, test := range []struct {
+		name     string
+		n        *idr.Node
+		decl     *CustomFuncDecl
+		expected interface{}
+		calls    int
+	}{
+		{name: "first invocation", n: n, decl: decl(true, "x"), expected: "Ax1", calls: 1},
+		{name: "memoized", n: n, decl: decl(true, "x"), expected: "Ax1", calls: 1},
+		{name: "not memoized if off", n: n, decl: decl(false, "x"), expected: "Ax2", calls: 2},
+		{name: "different arg", n: n, decl: decl(true, "y"), expected: "Ay3", calls: 3},
+		{name: "different node", n: n.FirstChild, decl: decl(true, "x"), expected: "Bx4", calls: 4},
+		{name: "memoized again", n: n.FirstChild, decl: decl(true, "x"), expected: "Bx4", calls: 4},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := p.invokeCustomFunc(test.n, test.decl)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, v)
+			assert.Equal(t, test.calls, calls)
+		})
+	}
+	// errors aren't memoized.
+	for i := 0; i < 2; i++ {
+		_, err := p.invokeCustomFunc(n, decl(true, "fail"))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 6, calls)
+	// nor anything when tracing.
+	p.WithTrace()
+	p.trace = &Trace{}
+	v, err := p.invokeCustomFunc(n, decl(true, "x"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Ax7", v)
+}
@@ -0,0 +1,137 @@
+package transform
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Rounding modes of NumberFormat.
+const (
+	// RoundingHalfUp rounds to the nearest, and ties away from zero. The default.
+	RoundingHalfUp = "half_up"
+	// RoundingHalfEven rounds to the nearest, and ties to the even neighbor (aka banker's rounding).
+	RoundingHalfEven = "half_even"
+	// RoundingUp rounds away from zero.
+	RoundingUp = "up"
+	// RoundingDown rounds towards zero, i.e. truncates.
+	RoundingDown = "down"
+	// RoundingCeiling rounds towards positive infinity.
+	RoundingCeiling = "ceiling"
+	// RoundingFloor rounds towards negative infinity.
+	RoundingFloor = "floor"
+)
+
+// NumberFormat is the decl for formatting a numeric value, e.g. a monetary amount, in a
+// `transform_declarations` value decl. The value is formatted as a plain decimal string, which is
+// then converted to the decl's `type`, if any: `"type": "number"` keeps the formatted decimal places
+// in the output, e.g. `12.50`.
+type NumberFormat struct {
+	// DecimalPlaces, if set, is the number of decimal places the value is rounded to and formatted
+	// with. Otherwise, the decimal places of the value are kept as is.
+	DecimalPlaces *int `json:"decimal_places,omitempty"`
+	// Rounding is the rounding mode used if DecimalPlaces is set. Default to RoundingHalfUp.
+	Rounding string `json:"rounding,omitempty"`
+	// ThousandsSeparator, if set, is removed from the value before it's formatted, e.g. "," for
+	// "1,234.50".
+	ThousandsSeparator string `json:"thousands_separator,omitempty"`
+	// StripLeadingZeros specifies whether the leading zeros of the integer part are stripped, e.g.
+	// "000123.40" into "123.40". Otherwise, they're kept, e.g. for fixed-width output.
+	StripLeadingZeros bool `json:"strip_leading_zeros,omitempty"`
+}
+
+var (
+	decimalRegexp     = regexp.MustCompile(`^([+-]?)([0-9]*)(?:\.([0-9]*))?$`)
+	errNotPlainNumber = errors.New("not a valid decimal number")
+)
+
+// formatNumber formats a numeric value as specified by f. The value is either a number (int, uint or
+// float kinds) or a string (including json.Number) of a plain decimal number. All arithmetic is done on
+// the decimal digits, thus no precision is lost.
+func formatNumber(v interface{}, f *NumberFormat) (string, error) {
+	var s string
+	vv := reflect.ValueOf(v)
+	switch vv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(vv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(vv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(vv.Float(), 'f', -1, 64)
+	case reflect.String:
+		s = strings.TrimSpace(vv.String())
+	default:
+		return "", errTypeConversionNotSupported
+	}
+	if f.ThousandsSeparator != "" {
+		s = strings.ReplaceAll(s, f.ThousandsSeparator, "")
+	}
+	m := decimalRegexp.FindStringSubmatch(s)
+	if m == nil || m[2]+m[3] == "" {
+		return "", errNotPlainNumber
+	}
+	neg, intPart, fracPart := m[1] == "-", m[2], m[3]
+	intWidth := len(intPart)
+	if f.DecimalPlaces != nil {
+		intPart, fracPart = roundDecimal(neg, intPart, fracPart, *f.DecimalPlaces, f.Rounding)
+	}
+	if f.StripLeadingZeros || intPart == "" {
+		intPart = strings.TrimLeft(intPart, "0")
+		if intPart == "" {
+			intPart = "0"
+		}
+	} else if len(intPart) < intWidth {
+		intPart = strings.Repeat("0", intWidth-len(intPart)) + intPart
+	}
+	var sb strings.Builder
+	if neg && strings.Trim(intPart+fracPart, "0") != "" {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(intPart)
+	if fracPart != "" {
+		sb.WriteByte('.')
+		sb.WriteString(fracPart)
+	}
+	return sb.String(), nil
+}
+
+// roundDecimal rounds the absolute value of a decimal number, given as its integer and fractional
+// digits, to the given decimal places, and returns the integer and fractional digits of the result.
+func roundDecimal(neg bool, intPart, fracPart string, places int, rounding string) (string, string) {
+	if len(fracPart) <= places {
+		return intPart, fracPart + strings.Repeat("0", places-len(fracPart))
+	}
+	digits, _ := new(big.Int).SetString("0"+intPart+fracPart, 10)
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(fracPart)-places)), nil)
+	q, r := new(big.Int).QuoRem(digits, divisor, new(big.Int))
+	if r.Sign() != 0 {
+		// cmp is the comparison of the remainder with half of the divisor.
+		cmp := new(big.Int).Lsh(r, 1).Cmp(divisor)
+		var up bool
+		switch rounding {
+		case RoundingHalfEven:
+			up = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+		case RoundingUp:
+			up = true
+		case RoundingDown:
+			up = false
+		case RoundingCeiling:
+			up = !neg
+		case RoundingFloor:
+			up = neg
+		default:
+			up = cmp >= 0
+		}
+		if up {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	s := q.String()
+	if len(s) <= places {
+		s = strings.Repeat("0", places-len(s)+1) + s
+	}
+	return s[:len(s)-places], s[len(s)-places:]
+}
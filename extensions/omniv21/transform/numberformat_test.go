@@ -0,0 +1,92 @@
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testIntPtr(i int) *int {
+	return &i
+}
+
+func TestFormatNumber(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		v        interface{}
+		f        NumberFormat
+		expected string
+		err      string
+	}{
+		{name: "no-op", v: "123.40", expected: "123.40"},
+		{name: "keeps leading zeros", v: "000123.40", expected: "000123.40"},
+		{name: "strips leading zeros", v: "000123.40", f: NumberFormat{StripLeadingZeros: true}, expected: "123.40"},
+		{name: "all zeros", v: "000", f: NumberFormat{StripLeadingZeros: true}, expected: "0"},
+		{name: "no int part", v: ".5", expected: "0.5"},
+		{name: "plus sign dropped", v: "+1.5", expected: "1.5"},
+		{name: "thousands separator", v: "1,234,567.891", f: NumberFormat{ThousandsSeparator: ","}, expected: "1234567.891"},
+		{
+			name: "european thousands separator", v: "1.234.567", f: NumberFormat{ThousandsSeparator: "."},
+			expected: "1234567",
+		},
+		{name: "pad decimal places", v: "12", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "12.00"},
+		{name: "half_up default", v: "2.345", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "2.35"},
+		{name: "half_up negative", v: "-2.345", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "-2.35"},
+		{name: "half_up below half", v: "2.3449", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "2.34"},
+		{
+			name: "half_even tie to even", v: "2.345", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingHalfEven},
+			expected: "2.34",
+		},
+		{
+			name: "half_even tie to odd", v: "2.355", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingHalfEven},
+			expected: "2.36",
+		},
+		{
+			name: "half_even above half", v: "2.3451", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingHalfEven},
+			expected: "2.35",
+		},
+		{name: "up", v: "2.341", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingUp}, expected: "2.35"},
+		{name: "up negative", v: "-2.341", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingUp}, expected: "-2.35"},
+		{name: "down", v: "2.349", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingDown}, expected: "2.34"},
+		{name: "ceiling", v: "2.341", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingCeiling}, expected: "2.35"},
+		{
+			name: "ceiling negative", v: "-2.349", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingCeiling},
+			expected: "-2.34",
+		},
+		{name: "floor", v: "2.349", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingFloor}, expected: "2.34"},
+		{
+			name: "floor negative", v: "-2.341", f: NumberFormat{DecimalPlaces: testIntPtr(2), Rounding: RoundingFloor},
+			expected: "-2.35",
+		},
+		{name: "zero decimal places", v: "2.5", f: NumberFormat{DecimalPlaces: testIntPtr(0)}, expected: "3"},
+		{name: "carry keeps width", v: "0999.996", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "1000.00"},
+		{name: "rounding keeps leading zeros", v: "0012.345", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "0012.35"},
+		{name: "rounds to less than 1", v: "0.004", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "0.00"},
+		{name: "no negative zero", v: "-0.004", f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "0.00"},
+		{
+			name: "exact beyond float64", v: "12345678901234567890.125", f: NumberFormat{DecimalPlaces: testIntPtr(2)},
+			expected: "12345678901234567890.13",
+		},
+		{name: "json.Number", v: json.Number("1.005"), f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "1.01"},
+		{name: "int", v: -42, f: NumberFormat{DecimalPlaces: testIntPtr(1)}, expected: "-42.0"},
+		{name: "uint", v: uint8(7), expected: "7"},
+		{name: "float", v: 1234.5, f: NumberFormat{DecimalPlaces: testIntPtr(2)}, expected: "1234.50"},
+		{name: "invalid number", v: "12a", err: errNotPlainNumber.Error()},
+		{name: "exponent not supported", v: "1e5", err: errNotPlainNumber.Error()},
+		{name: "no digits", v: "-.", err: errNotPlainNumber.Error()},
+		{name: "unsupported type", v: true, err: errTypeConversionNotSupported.Error()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := formatNumber(test.v, &test.f)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Equal(t, "", s)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OutputField describes a field of the output of a validated `FINAL_OUTPUT` Decl tree, as known
+// statically from the schema, e.g. for comparing the outputs of two versions of a schema.
+type OutputField struct {
+	// Path is the path of the field in the output: the object keys separated by '.', with "[]"
+	// appended for the elements of an array, or "[0]", "[1]", etc, if the array has more than one
+	// element decl, e.g. "items[].sku". The path of `FINAL_OUTPUT` itself is "".
+	Path string
+	// Type is the type of the field: "object", "array", its `type` if any, "string" for the fields
+	// whose values are strings by default, i.e. of `const`, `external`, `constant` and `xpath`, or ""
+	// if it isn't known statically, e.g. for a `custom_func` without `type`.
+	Type string
+	// XPath is the `xpath` of the field, or its `xpath_dynamic` as described in Mapping, or "" if none.
+	XPath string
+	// Mapping describes how the value of the field is computed, other than its XPath, e.g.
+	// `custom_func upper(xpath "A")`, or, for objects and arrays, the options of the field, if any.
+	Mapping string
+}
+
+// OutputFields returns the output fields of a validated `FINAL_OUTPUT` Decl tree, with templates
+// expanded, parents before their children, and object keys in alphabetical order.
+func OutputFields(finalOutputDecl *Decl) []OutputField {
+	var fields []OutputField
+	collectOutputFields(finalOutputDecl, "", &fields)
+	return fields
+}
+
+func collectOutputFields(decl *Decl, path string, fields *[]OutputField) {
+	*fields = append(*fields, OutputField{
+		Path:    path,
+		Type:    outputType(decl),
+		XPath:   describeXPath(decl),
+		Mapping: describeMapping(decl),
+	})
+	switch decl.kind {
+	case kindObject:
+		var names []string
+		for name := range decl.Object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			childPath := name
+			if path != "" {
+				childPath = path + "." + name
+			}
+			collectOutputFields(decl.Object[name], childPath, fields)
+		}
+	case kindArray:
+		for i, elemDecl := range decl.Array {
+			elemPath := path + "[]"
+			if len(decl.Array) > 1 {
+				elemPath = fmt.Sprintf("%s[%d]", path, i)
+			}
+			collectOutputFields(elemDecl, elemPath, fields)
+		}
+	}
+}
+
+func outputType(decl *Decl) string {
+	switch {
+	case decl.kind == kindObject:
+		return string(kindObject)
+	case decl.kind == kindArray:
+		return string(kindArray)
+	case decl.ResultType != nil:
+		return string(*decl.ResultType)
+	case decl.kind == kindConst, decl.kind == kindExternal, decl.kind == kindConstant, decl.kind == kindField:
+		return string(resultTypeString)
+	default:
+		return ""
+	}
+}
+
+func describeXPath(decl *Decl) string {
+	switch {
+	case decl.XPath != nil:
+		return *decl.XPath
+	case decl.XPathDynamic != nil:
+		return "xpath_dynamic(" + describeDecl(decl.XPathDynamic) + ")"
+	default:
+		return ""
+	}
+}
+
+// describeDecl describes a decl in full, i.e. both its xpath and its mapping, e.g. for custom_func args.
+func describeDecl(decl *Decl) string {
+	var parts []string
+	if xpath := describeXPath(decl); xpath != "" {
+		if decl.XPath != nil {
+			xpath = fmt.Sprintf("xpath %q", xpath)
+		}
+		parts = append(parts, xpath)
+	}
+	if mapping := describeMapping(decl); mapping != "" {
+		parts = append(parts, mapping)
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeMapping(decl *Decl) string {
+	var parts []string
+	switch decl.kind {
+	case kindConst:
+		parts = append(parts, fmt.Sprintf("const %q", *decl.Const))
+	case kindExternal:
+		parts = append(parts, fmt.Sprintf("external %q", *decl.External))
+	case kindVar:
+		parts = append(parts, fmt.Sprintf("var %q", *decl.Var))
+	case kindConstant:
+		parts = append(parts, fmt.Sprintf("constant %q", *decl.Constant))
+		if decl.Key != nil {
+			parts = append(parts, "key("+describeDecl(decl.Key)+")")
+		}
+	case kindCustomFunc:
+		args := make([]string, len(decl.CustomFunc.Args))
+		for i, arg := range decl.CustomFunc.Args {
+			args[i] = describeDecl(arg)
+		}
+		parts = append(parts, fmt.Sprintf("custom_func %s(%s)", decl.CustomFunc.Name, strings.Join(args, ", ")))
+		if decl.CustomFunc.IgnoreError {
+			parts = append(parts, "ignore_error")
+		}
+	case kindCustomParse:
+		parts = append(parts, fmt.Sprintf("custom_parse %q", *decl.CustomParse))
+	}
+	if decl.NumberFormat != nil {
+		b, _ := json.Marshal(decl.NumberFormat)
+		parts = append(parts, "number_format "+string(b))
+	}
+	if decl.NoTrim {
+		parts = append(parts, "no_trim")
+	}
+	if decl.KeepEmptyOrNull {
+		parts = append(parts, "keep_empty_or_null")
+	}
+	return strings.Join(parts, " ")
+}
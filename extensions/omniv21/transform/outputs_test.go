@@ -0,0 +1,39 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputFields(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/A", "object": {
+				"b": { "xpath": "B", "type": "int", "keep_empty_or_null": true },
+				"c": { "const": "c" },
+				"d": { "xpath_dynamic": { "external": "d" }, "no_trim": true },
+				"e": { "custom_func": { "name": "upper", "args": [ { "xpath": "C" } ], "ignore_error": true } },
+				"items": { "array": [ { "xpath": "*", "template": "t" } ] },
+				"mixed": { "array": [ { "const": "1", "type": "int" }, { "object": {} } ] }
+			}},
+			"t": { "object": { "name": { "custom_func": { "name": "concat", "args": [
+				{ "xpath": "." }, { "const": "!" }
+			]}}}}
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []OutputField{
+		{Path: "", Type: "object", XPath: "/A"},
+		{Path: "b", Type: "int", XPath: "B", Mapping: "keep_empty_or_null"},
+		{Path: "c", Type: "string", Mapping: `const "c"`},
+		{Path: "d", Type: "string", XPath: `xpath_dynamic(external "d")`, Mapping: "no_trim"},
+		{Path: "e", Mapping: `custom_func upper(xpath "C") ignore_error`},
+		{Path: "items", Type: "array"},
+		{Path: "items[]", Type: "object", XPath: "*"},
+		{Path: "items[].name", Mapping: `custom_func concat(xpath ".", const "!")`},
+		{Path: "mixed", Type: "array"},
+		{Path: "mixed[0]", Type: "int", Mapping: `const "1"`},
+		{Path: "mixed[1]", Type: "object"},
+	}, OutputFields(finalOutputDecl))
+}
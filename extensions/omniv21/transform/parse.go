@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/jf-tech/go-corelib/strs"
 
@@ -18,6 +19,20 @@ type parseCtx struct {
 	customParseFuncs      CustomParseFuncs // Deprecated.
 	disableTransformCache bool             // by default, we have caching on. only in some tests we turn caching off.
 	transformCache        map[string]interface{}
+	memo                  map[string]interface{} // results of the custom funcs with 'memoize' on.
+	index                 *idr.Index             // optional; speeds up descendant xpath queries on large records.
+	tracing               bool
+	trace                 *Trace     // trace of the ParseNode call in progress, if tracing.
+	lastTrace             *Trace     // trace of the last top-level ParseNode call, if tracing.
+	stats                 *Stats     // optional; collects the invocation stats of templates and custom funcs.
+	vars                  []varScope // the vars declared by the objects being parsed, innermost last.
+	varScopes             int        // the number of var scopes created so far, for their ids.
+}
+
+// varScope is the values of the vars declared by an object being parsed.
+type varScope struct {
+	id     int
+	values map[string]interface{}
 }
 
 // NewParseCtx creates new context for parsing and transforming a *Node (and its sub-tree) into an output record.
@@ -31,13 +46,31 @@ func NewParseCtx(
 		customParseFuncs:      customParseFuncs,
 		disableTransformCache: false,
 		transformCache:        map[string]interface{}{},
+		memo:                  map[string]interface{}{},
 	}
 }
 
+// WithIndex makes the parseCtx answer xpath queries with the given idr.Index whenever possible.
+func (p *parseCtx) WithIndex(index *idr.Index) *parseCtx {
+	p.index = index
+	return p
+}
+
 func (p *parseCtx) ParseNode(n *idr.Node, decl *Decl) (interface{}, error) {
+	if p.tracing {
+		return p.traceParseNode(n, decl)
+	}
+	return p.parseNode(n, decl)
+}
+
+func (p *parseCtx) parseNode(n *idr.Node, decl *Decl) (interface{}, error) {
 	var cacheKey string
 	if !p.disableTransformCache {
 		cacheKey = strconv.FormatInt(n.ID, 16) + "/" + decl.hash
+		if len(p.vars) > 0 {
+			// the same decl on the same node may yield different values with different vars.
+			cacheKey += "/" + strconv.Itoa(p.vars[len(p.vars)-1].id)
+		}
 		if cacheValue, found := p.transformCache[cacheKey]; found {
 			return cacheValue, nil
 		}
@@ -51,11 +84,18 @@ func (p *parseCtx) ParseNode(n *idr.Node, decl *Decl) (interface{}, error) {
 		}
 		return value, err
 	}
+	if p.stats != nil && decl.template != "" {
+		defer addInvocation(p.stats.Templates, decl.template, time.Now())
+	}
 	switch decl.kind {
 	case kindConst:
 		return saveIntoCache(p.parseConst(decl))
 	case kindExternal:
 		return saveIntoCache(p.parseExternal(decl))
+	case kindConstant:
+		return saveIntoCache(p.parseConstant(n, decl))
+	case kindVar:
+		return saveIntoCache(p.parseVar(decl))
 	case kindField:
 		return saveIntoCache(p.parseField(n, decl))
 	case kindObject:
@@ -82,6 +122,36 @@ func (p *parseCtx) parseExternal(decl *Decl) (interface{}, error) {
 	return nil, fmt.Errorf("cannot find external property '%s' on '%s'", *decl.External, decl.fqdn)
 }
 
+func (p *parseCtx) parseConstant(n *idr.Node, decl *Decl) (interface{}, error) {
+	if decl.Key == nil {
+		return normalizeAndReturnValue(decl, *decl.constant.value)
+	}
+	key, err := p.ParseNode(n, decl.Key)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return normalizeAndReturnValue(decl, nil)
+	}
+	if reflect.ValueOf(key).Kind() != reflect.String {
+		return nil, fmt.Errorf("key on '%s' yields a non-string value '%v'", decl.fqdn, key)
+	}
+	if v, found := decl.constant.table[reflect.ValueOf(key).String()]; found {
+		return normalizeAndReturnValue(decl, v)
+	}
+	// Like a `field` whose xpath matches nothing, a key not in the table yields no value.
+	return normalizeAndReturnValue(decl, nil)
+}
+
+func (p *parseCtx) parseVar(decl *Decl) (interface{}, error) {
+	for i := len(p.vars) - 1; i >= 0; i-- {
+		if v, found := p.vars[i].values[*decl.Var]; found {
+			return normalizeAndReturnValue(decl, v)
+		}
+	}
+	return nil, fmt.Errorf("cannot find var '%s' on '%s'", *decl.Var, decl.fqdn)
+}
+
 func xpathQueryNeeded(decl *Decl) bool {
 	// For a given transform, we only do xpath query, if
 	// - it has "xpath" or "xpath_dynamic" defined in its decl AND
@@ -126,6 +196,30 @@ func (p *parseCtx) computeXPathDynamic(n *idr.Node, xpathDynamicDecl *Decl) (str
 	return xpathDynamic, nil
 }
 
+// xpathDynamicNodes tells if decl's `xpath_dynamic` is a custom_func returning IDR nodes, which are then
+// the source nodes of decl, instead of being matched by an xpath.
+func xpathDynamicNodes(decl *Decl) bool {
+	return decl.XPathDynamic != nil &&
+		decl.XPathDynamic.kind == kindCustomFunc &&
+		decl.XPathDynamic.CustomFunc.nodesResult
+}
+
+func (p *parseCtx) computeXPathDynamicNodes(n *idr.Node, xpathDynamicDecl *Decl) ([]*idr.Node, error) {
+	v, err := p.ParseNode(n, xpathDynamicDecl)
+	if err != nil {
+		return nil, err
+	}
+	switch nodes := v.(type) {
+	case *idr.Node:
+		if nodes != nil {
+			return []*idr.Node{nodes}, nil
+		}
+	case []*idr.Node:
+		return nodes, nil
+	}
+	return nil, nil
+}
+
 func xpathMatchFlags(dynamic bool) uint {
 	if dynamic {
 		return idr.DisableXPathCache
@@ -137,11 +231,26 @@ func (p *parseCtx) querySingleNodeFromXPath(n *idr.Node, decl *Decl) (*idr.Node,
 	if !xpathQueryNeeded(decl) {
 		return n, nil
 	}
+	if xpathDynamicNodes(decl) {
+		nodes, err := p.computeXPathDynamicNodes(n, decl.XPathDynamic)
+		switch {
+		case err != nil || len(nodes) == 0:
+			return nil, nil
+		case len(nodes) > 1:
+			return nil, fmt.Errorf("xpath_dynamic on '%s' yielded more than one node", decl.fqdn)
+		}
+		return nodes[0], nil
+	}
 	xpath, dynamic, err := p.computeXPath(n, decl)
 	if err != nil {
 		return nil, nil
 	}
-	resultNode, err := idr.MatchSingle(n, xpath, xpathMatchFlags(dynamic))
+	resultNode, err := p.index.MatchSingle(n, xpath, xpathMatchFlags(dynamic))
+	if resultNode != nil {
+		p.traceQuery(xpath, resultNode)
+	} else if err == idr.ErrNoMatch {
+		p.traceQuery(xpath)
+	}
 	switch {
 	case err == idr.ErrNoMatch:
 		return nil, nil
@@ -202,6 +311,22 @@ func (p *parseCtx) parseObject(n *idr.Node, decl *Decl) (interface{}, error) {
 	if n == nil {
 		return nil, nil
 	}
+	if len(decl.varNames) > 0 {
+		// the vars are computed in the enclosing objects' var scope, thus can't reference each other.
+		scope := varScope{values: map[string]interface{}{}}
+		for _, name := range decl.varNames {
+			v, err := p.ParseNode(n, decl.Vars[name])
+			if err != nil {
+				return nil, err
+			}
+			scope.values[name] = v
+		}
+		// ids are unique, even among the scopes created by the vars themselves, e.g. objects with vars.
+		p.varScopes++
+		scope.id = p.varScopes
+		p.vars = append(p.vars, scope)
+		defer func() { p.vars = p.vars[:len(p.vars)-1] }()
+	}
 	obj := map[string]interface{}{}
 	for _, childDecl := range decl.children {
 		childValue, err := p.ParseNode(n, childDecl)
@@ -217,6 +342,25 @@ func (p *parseCtx) parseObject(n *idr.Node, decl *Decl) (interface{}, error) {
 	return normalizeAndReturnValue(decl, obj)
 }
 
+// queryChildNodes returns the nodes an array's child decl transforms into the array's elements. Like a
+// failed xpath_dynamic computation of other decls, a failed one here yields no nodes, not an error.
+func (p *parseCtx) queryChildNodes(n *idr.Node, childDecl *Decl) ([]*idr.Node, error) {
+	if xpathDynamicNodes(childDecl) {
+		nodes, _ := p.computeXPathDynamicNodes(n, childDecl.XPathDynamic)
+		return nodes, nil
+	}
+	xpath, dynamic, err := p.computeXPath(n, childDecl)
+	if err != nil {
+		return nil, nil
+	}
+	childNodes, err := p.index.MatchAll(n, xpath, xpathMatchFlags(dynamic))
+	if err != nil {
+		return nil, fmt.Errorf("xpath query '%s' on '%s' failed: %s", xpath, childDecl.fqdn, err.Error())
+	}
+	p.traceQuery(xpath, childNodes...)
+	return childNodes, nil
+}
+
 func (p *parseCtx) parseArray(n *idr.Node, decl *Decl) (interface{}, error) {
 	var array []interface{}
 	for _, childDecl := range decl.children {
@@ -227,13 +371,9 @@ func (p *parseCtx) parseArray(n *idr.Node, decl *Decl) (interface{}, error) {
 		// Note computeXPath() already does this for us: if xpath/xpath_dynamic both null, it
 		// returns xpath "." which gives us the current node when we use it to query the current
 		// node.
-		xpath, dynamic, err := p.computeXPath(n, childDecl)
+		childNodes, err := p.queryChildNodes(n, childDecl)
 		if err != nil {
-			continue
-		}
-		childNodes, err := idr.MatchAll(n, xpath, xpathMatchFlags(dynamic))
-		if err != nil {
-			return nil, fmt.Errorf("xpath query '%s' on '%s' failed: %s", xpath, childDecl.fqdn, err.Error())
+			return nil, err
 		}
 		for _, childNode := range childNodes {
 			childValue, err := p.ParseNode(childNode, childDecl)
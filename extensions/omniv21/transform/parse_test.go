@@ -40,6 +40,19 @@ func testParseCtx() *parseCtx {
 				"test_func": func(_ *transformctx.Ctx, args ...string) (string, error) {
 					return "test", nil
 				},
+				// returns the element children of the contextual node in reverse order.
+				"test_reversed_children": func(_ *transformctx.Ctx, n *idr.Node) ([]*idr.Node, error) {
+					var nodes []*idr.Node
+					for c := n.LastChild; c != nil; c = c.PrevSibling {
+						if c.Type == idr.ElementNode {
+							nodes = append(nodes, c)
+						}
+					}
+					return nodes, nil
+				},
+				"test_last_child": func(_ *transformctx.Ctx, n *idr.Node) (*idr.Node, error) {
+					return n.LastChild, nil
+				},
 			},
 			customfuncs.CommonCustomFuncs,
 			v21.OmniV21CustomFuncs),
@@ -64,6 +77,14 @@ func testResultType(typ resultType) *resultType {
 	return &typ
 }
 
+func testNodesFuncDecl(name string) *Decl {
+	return &Decl{
+		fqdn:       "test_fqdn.xpath_dynamic",
+		kind:       kindCustomFunc,
+		CustomFunc: &CustomFuncDecl{Name: name, nodeArg: true, nodesResult: true},
+	}
+}
+
 func TestComputeXPath(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -339,6 +360,82 @@ func TestParseExternal(t *testing.T) {
 	}
 }
 
+func TestParseCtx_ParseConstant(t *testing.T) {
+	table := &constantValue{table: map[string]string{"b": "bee", "c": "sea"}}
+	for _, test := range []struct {
+		name          string
+		decl          *Decl
+		expectedValue interface{}
+		expectedErr   string
+	}{
+		{
+			name: "value",
+			decl: &Decl{
+				Constant: strs.StrPtr("v"), constant: &constantValue{value: strs.StrPtr(" 123 ")},
+				ResultType: testResultType(resultTypeInt)},
+			expectedValue: int64(123),
+			expectedErr:   "",
+		},
+		{
+			name: "table key found",
+			decl: &Decl{
+				Constant: strs.StrPtr("t"), constant: table,
+				Key: &Decl{XPath: strs.StrPtr("B"), kind: kindField}},
+			expectedValue: "bee",
+			expectedErr:   "",
+		},
+		{
+			name: "table key not found",
+			decl: &Decl{
+				Constant: strs.StrPtr("t"), constant: table,
+				Key: &Decl{Const: strs.StrPtr("d"), kind: kindConst}},
+			expectedValue: nil,
+			expectedErr:   "",
+		},
+		{
+			name: "key yields no value",
+			decl: &Decl{
+				Constant: strs.StrPtr("t"), constant: table,
+				Key: &Decl{XPath: strs.StrPtr("D"), kind: kindField}},
+			expectedValue: nil,
+			expectedErr:   "",
+		},
+		{
+			name: "key yields non-string value",
+			decl: &Decl{
+				Constant: strs.StrPtr("t"), constant: table, fqdn: "test_fqdn",
+				Key: &Decl{Const: strs.StrPtr("1"), kind: kindConst, ResultType: testResultType(resultTypeInt)}},
+			expectedValue: nil,
+			expectedErr:   "key on 'test_fqdn' yields a non-string value '1'",
+		},
+		{
+			name: "key failed",
+			decl: &Decl{
+				Constant: strs.StrPtr("t"), constant: table,
+				Key: &Decl{XPath: strs.StrPtr("*"), kind: kindField, fqdn: "test_fqdn.key"}},
+			expectedValue: nil,
+			expectedErr:   "xpath query '*' on 'test_fqdn.key' yielded more than one result",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.decl.Key != nil {
+				test.decl.kind = kindConstant
+				test.decl.children = []*Decl{test.decl.Key}
+			}
+			linkParent(test.decl)
+			value, err := testParseCtx().parseConstant(testNode(), test.decl)
+			switch test.expectedErr {
+			case "":
+				assert.NoError(t, err)
+			default:
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+			}
+			assert.Equal(t, test.expectedValue, value)
+		})
+	}
+}
+
 func TestParseCtx_ParseField(t *testing.T) {
 	for _, test := range []struct {
 		name          string
@@ -376,6 +473,19 @@ func TestParseCtx_ParseField(t *testing.T) {
 			expectedValue: nil,
 			expectedErr:   "xpath query '*' on 'test_fqdn' yielded more than one result",
 		},
+		{
+			name:          "node from custom_func",
+			decl:          &Decl{XPathDynamic: testNodesFuncDecl("test_last_child"), kind: kindField, fqdn: "test_fqdn"},
+			expectedValue: "c",
+			expectedErr:   "",
+		},
+		{
+			name: "more than one node from custom_func",
+			decl: &Decl{
+				XPathDynamic: testNodesFuncDecl("test_reversed_children"), kind: kindField, fqdn: "test_fqdn"},
+			expectedValue: nil,
+			expectedErr:   "xpath_dynamic on 'test_fqdn' yielded more than one node",
+		},
 		{
 			name:          "invalid xpath",
 			decl:          &Decl{XPath: strs.StrPtr("<"), kind: kindField, fqdn: "test_fqdn"},
@@ -743,6 +853,38 @@ func TestParseCtx_ParseArray(t *testing.T) {
 			expectedValue: nil,
 			expectedErr:   "", // no error when nothing matched
 		},
+		{
+			name: "nodes from custom_func for child",
+			decl: &Decl{
+				fqdn: "test_fqdn",
+				kind: kindArray,
+				children: []*Decl{
+					{
+						fqdn:         "test_fqdn.test_key",
+						kind:         kindField,
+						XPathDynamic: testNodesFuncDecl("test_reversed_children"),
+					},
+				},
+			},
+			expectedValue: []interface{}{"c", "b"},
+			expectedErr:   "",
+		},
+		{
+			name: "single node from custom_func for child",
+			decl: &Decl{
+				fqdn: "test_fqdn",
+				kind: kindArray,
+				children: []*Decl{
+					{
+						fqdn:         "test_fqdn.test_key",
+						kind:         kindField,
+						XPathDynamic: testNodesFuncDecl("test_last_child"),
+					},
+				},
+			},
+			expectedValue: []interface{}{"c"},
+			expectedErr:   "",
+		},
 		{
 			name: "failed parsing child",
 			decl: &Decl{
@@ -803,3 +945,51 @@ func TestParseCtx_ParseArray(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCtx_ParseVar(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": {
+				"vars": { "currency": { "xpath": "B" }, "code": { "const": " 12 ", "type": "int" } },
+				"object": {
+					"line": { "xpath": "C", "template": "line" },
+					"eur": {
+						"vars": { "currency": { "const": "eur" } },
+						"object": { "line": { "xpath": "C", "template": "line" } }
+					},
+					"all": { "array": [ { "xpath": "*", "object": {
+						"v": { "custom_func": { "name": "concat", "args": [ { "var": "currency" }, { "xpath": "." } ] } }
+					}}]}
+				}
+			},
+			"line": { "object": {
+				"value": { "xpath": "." },
+				"currency": { "var": "currency", "type": "string" },
+				"code": { "var": "code" }
+			}}
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	p := testParseCtx()
+	// the same template on the same node yields different values with different vars.
+	p.disableTransformCache = false
+	v, err := p.ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		map[string]interface{}{
+			"line": map[string]interface{}{"value": "c", "currency": "b", "code": int64(12)},
+			"eur":  map[string]interface{}{"line": map[string]interface{}{"value": "c", "currency": "eur", "code": int64(12)}},
+			"all":  []interface{}{map[string]interface{}{"v": "bb"}, map[string]interface{}{"v": "bc"}},
+		},
+		v)
+
+	finalOutputDecl, err = ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": { "currency": { "var": "currency" } } }
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	_, err = testParseCtx().ParseNode(testNode(), finalOutputDecl)
+	assert.Error(t, err)
+	assert.Equal(t, "cannot find var 'currency' on 'FINAL_OUTPUT.currency'", err.Error())
+}
@@ -0,0 +1,82 @@
+package transform
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/jf-tech/go-corelib/strs"
+
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/idr"
+)
+
+const recordContext = "record_context"
+
+// RecordContext is the `record_context` section of an omni schema: the values, e.g. of the envelope of
+// the records, captured once per input, on the root of the input's IDR tree, and made available to the
+// transforms of every record as vars.
+type RecordContext struct {
+	// Decls are the decls of the values, keyed by their var names.
+	Decls map[string]*Decl
+	names []string // names of the Decls, sorted.
+}
+
+// ValidateRecordContext validates the `record_context` section of an omni schema and returns it, or nil
+// if there is none. ValidateTransformDeclarations must have succeeded on the schema.
+func ValidateRecordContext(
+	schemaContent []byte, customFuncs customfuncs.CustomFuncs,
+	customParseFuncs CustomParseFuncs) (*RecordContext, error) {
+
+	var ctx validateCtx
+	_ = json.Unmarshal(schemaContent, &ctx)
+	if len(ctx.RecordContext) == 0 {
+		return nil, nil
+	}
+	ctx.customFuncs = customFuncs
+	ctx.customParseFuncs = customParseFuncs
+	ctx.declHashes = map[string]string{}
+
+	rc := &RecordContext{Decls: map[string]*Decl{}}
+	for name, decl := range ctx.RecordContext {
+		decl, err := ctx.validateDecl(strs.BuildFQDN(recordContext, name), decl)
+		if err != nil {
+			return nil, err
+		}
+		linkParent(decl)
+		rc.Decls[name] = decl
+		rc.names = append(rc.names, name)
+	}
+	sort.Strings(rc.names)
+	return rc, nil
+}
+
+// Relate makes AnalyzeReferences on the `FINAL_OUTPUT` decl take the values into account, for file
+// format readers not to skip the elements only the values reference.
+func (rc *RecordContext) Relate(finalOutputDecl *Decl) {
+	for _, name := range rc.names {
+		relate(finalOutputDecl, rc.Decls[name], refCtxRoot)
+	}
+}
+
+// Capture evaluates the values of the record context on the given node, normally the root of the
+// input's IDR tree, in the order of their names. The values can't reference each other.
+func (p *parseCtx) Capture(n *idr.Node, rc *RecordContext) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(rc.names))
+	for _, name := range rc.names {
+		v, err := p.ParseNode(n, rc.Decls[name])
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// WithVars makes the given values, e.g. the ones captured from a RecordContext, available as vars to all
+// the decls parsed, as if declared by an object enclosing them all.
+func (p *parseCtx) WithVars(values map[string]interface{}) *parseCtx {
+	if values != nil {
+		p.vars = append(p.vars[:0], varScope{values: values})
+	}
+	return p
+}
@@ -0,0 +1,72 @@
+package transform
+
+import (
+	"encoding/json"
+
+	"github.com/logward/omniparser/customfuncs"
+)
+
+const recordOrder = "record_order"
+
+// RecordOrder is the `record_order` section of an omni schema, declaring how the transformed records
+// are re-sequenced before being emitted.
+type RecordOrder struct {
+	// Key is evaluated on each record, and the records are emitted in the ascending order of it.
+	Key *Decl `json:"key,omitempty"`
+	// GroupKey, if not nil, is evaluated on each record, and the records are only re-sequenced among
+	// the consecutive records of the same group key: all the records of a group are emitted before
+	// any of the next group.
+	GroupKey *Decl `json:"group_key,omitempty"`
+	// Window is the maximum number of records buffered for re-sequencing.
+	Window int `json:"window,omitempty"`
+	// Spill, if not nil, allows the records buffered to be spilled to disk, so that large windows don't
+	// need to fit in memory.
+	Spill *RecordOrderSpill `json:"spill,omitempty"`
+}
+
+// RecordOrderSpill is the `record_order.spill` section of an omni schema.
+type RecordOrderSpill struct {
+	// MemoryBytes is the approximate size of the records buffered in memory, beyond which they're
+	// spilled into a temp file.
+	MemoryBytes int64 `json:"memory_bytes,omitempty"`
+	// MaxDiskBytes, if positive, caps the total size of the temp files: the transform fails with a
+	// fatal error if it's exceeded.
+	MaxDiskBytes int64 `json:"max_disk_bytes,omitempty"`
+}
+
+// ValidateRecordOrder validates the `record_order` section of an omni schema and returns it, or nil
+// if there is none. ValidateTransformDeclarations must have succeeded on the schema.
+func ValidateRecordOrder(
+	schemaContent []byte, customFuncs customfuncs.CustomFuncs, customParseFuncs CustomParseFuncs) (*RecordOrder, error) {
+
+	var ctx validateCtx
+	_ = json.Unmarshal(schemaContent, &ctx)
+	if ctx.RecordOrder == nil {
+		return nil, nil
+	}
+	ctx.customFuncs = customFuncs
+	ctx.customParseFuncs = customParseFuncs
+	ctx.declHashes = map[string]string{}
+
+	order := ctx.RecordOrder
+	var err error
+	// json schema validation guarantees the presence of key.
+	if order.Key, err = ctx.validateDecl(recordOrder+".key", order.Key); err != nil {
+		return nil, err
+	}
+	linkParent(order.Key)
+	if order.GroupKey != nil {
+		if order.GroupKey, err = ctx.validateDecl(recordOrder+".group_key", order.GroupKey); err != nil {
+			return nil, err
+		}
+		linkParent(order.GroupKey)
+	}
+	return order, nil
+}
+
+// Relate makes AnalyzeReferences on the `FINAL_OUTPUT` decl take the keys into account, for file format
+// readers not to skip the elements only the keys reference.
+func (o *RecordOrder) Relate(finalOutputDecl *Decl) {
+	relate(finalOutputDecl, o.Key, refCtxRecord)
+	relate(finalOutputDecl, o.GroupKey, refCtxRecord)
+}
@@ -0,0 +1,237 @@
+package transform
+
+import (
+	"strings"
+	"unicode"
+)
+
+// References is the result of statically analyzing all the xpaths used in a `FINAL_OUTPUT` Decl tree.
+// It allows file format readers to skip materializing IDR nodes that no transform will ever look at.
+type References struct {
+	// Names contains all the element and attribute names that appear in any of the xpaths.
+	Names map[string]bool
+	// ValueNames contains the names of the elements whose values (i.e. the text of their entire
+	// sub-trees) are read by `field` transforms.
+	ValueNames map[string]bool
+}
+
+// Referenced tells whether an element named 'name' is referenced by any of the xpaths.
+func (r *References) Referenced(name string) bool {
+	return r.Names[name]
+}
+
+// ValueRead tells whether the value of an element named 'name', i.e. the text of its entire sub-tree,
+// is read by any `field` transform.
+func (r *References) ValueRead(name string) bool {
+	return r.ValueNames[name]
+}
+
+const (
+	// refCtxRecord indicates the context node is the record node supplied by the reader.
+	refCtxRecord = "\x00record"
+	// refCtxRoot indicates the context node is the root of the input's IDR tree.
+	refCtxRoot = "\x00root"
+	// refCtxUnknown indicates the context node cannot be statically determined.
+	refCtxUnknown = "\x00unknown"
+)
+
+// relatedDecl is a decl, outside of the `FINAL_OUTPUT` Decl tree, evaluated on the same input, e.g. the
+// `record_order` key.
+type relatedDecl struct {
+	decl    *Decl
+	ctxName string // the context node the decl is evaluated on.
+}
+
+// relate makes AnalyzeReferences on finalOutputDecl analyze decl too, evaluated on the context node named
+// 'ctxName'.
+func relate(finalOutputDecl, decl *Decl, ctxName string) {
+	if finalOutputDecl != nil && decl != nil {
+		finalOutputDecl.related = append(finalOutputDecl.related, relatedDecl{decl: decl, ctxName: ctxName})
+	}
+}
+
+// AnalyzeReferences analyzes all the xpaths in a validated `FINAL_OUTPUT` Decl tree, and in the decls
+// related to it (e.g. by RecordOrder.Relate), and returns the names they reference. It returns nil if the
+// analysis is inconclusive, in which case callers must assume every element is referenced. The analysis
+// is inconclusive if any xpath is dynamic or uses wildcards, if any `custom_func` or `custom_parse` has
+// access to the IDR node directly, or if a `field` reads the value of the record node itself or a node
+// that cannot be statically determined.
+func AnalyzeReferences(finalOutputDecl *Decl) *References {
+	refs := &References{Names: map[string]bool{}, ValueNames: map[string]bool{}}
+	if finalOutputDecl == nil || !refs.analyzeDecl(finalOutputDecl, refCtxRecord) {
+		return nil
+	}
+	for _, related := range finalOutputDecl.related {
+		if !refs.analyzeDecl(related.decl, related.ctxName) {
+			return nil
+		}
+	}
+	return refs
+}
+
+func (r *References) analyzeDecl(decl *Decl, ctxName string) bool {
+	if decl.XPathDynamic != nil || decl.kind == kindCustomParse {
+		return false
+	}
+	if decl.XPath != nil {
+		if !r.addXPathNames(*decl.XPath) {
+			return false
+		}
+		// FINAL_OUTPUT's xpath is for the reader to select records; the transform itself
+		// always starts with the record node.
+		if decl.fqdn != finalOutput {
+			ctxName = xpathContextName(*decl.XPath, ctxName)
+		}
+	}
+	switch decl.kind {
+	case kindField:
+		if ctxName == refCtxRecord || ctxName == refCtxRoot || ctxName == refCtxUnknown {
+			return false
+		}
+		r.ValueNames[ctxName] = true
+	case kindCustomFunc:
+		if decl.CustomFunc.nodeArg {
+			return false
+		}
+	}
+	for _, child := range decl.children {
+		if !r.analyzeDecl(child, ctxName) {
+			return false
+		}
+	}
+	for _, varDecl := range decl.Vars {
+		if !r.analyzeDecl(varDecl, ctxName) {
+			return false
+		}
+	}
+	return true
+}
+
+// addXPathNames adds all the names used in an xpath into r.Names. It returns false if the xpath uses
+// constructs that can match elements without naming them.
+func (r *References) addXPathNames(xpath string) bool {
+	runes := []rune(xpath)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case c == '"' || c == '\'':
+			for i++; i < len(runes) && runes[i] != c; i++ {
+			}
+			i++
+		case c == '*':
+			return false
+		case isXPathNameRune(c, true):
+			start := i
+			for i < len(runes) && isXPathNameRune(runes[i], false) {
+				i++
+			}
+			name := string(runes[start:i])
+			rest := strings.TrimLeftFunc(string(runes[i:]), unicode.IsSpace)
+			switch {
+			case strings.HasPrefix(rest, "::"):
+				// axis name.
+			case strings.HasPrefix(rest, "("):
+				if name == "node" {
+					return false
+				}
+			default:
+				r.Names[name] = true
+			}
+		default:
+			i++
+		}
+	}
+	return true
+}
+
+func isXPathNameRune(c rune, first bool) bool {
+	if c == '_' || unicode.IsLetter(c) {
+		return true
+	}
+	return !first && (c == '-' || c == '.' || unicode.IsDigit(c))
+}
+
+// xpathContextName returns the name of the node an xpath selects, relative to the current context node
+// named 'ctxName', by looking at the xpath's last location step.
+func xpathContextName(xpath, ctxName string) string {
+	if strings.Contains(xpath, "|") {
+		return refCtxUnknown
+	}
+	steps := splitXPathSteps(xpath)
+	// Steps like "." and "text()" stay on the node selected by the previous step.
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := strings.TrimSpace(stripXPathPredicates(steps[i]))
+		if idx := strings.Index(step, "::"); idx >= 0 {
+			switch axis := strings.TrimSpace(step[:idx]); axis {
+			case "child", "descendant", "descendant-or-self", "self", "attribute":
+				step = strings.TrimSpace(step[idx+2:])
+			default:
+				return refCtxUnknown
+			}
+		}
+		switch {
+		case step == "." || step == "text()":
+			continue
+		case strings.HasPrefix(step, "@"):
+			return step[1:]
+		case step != "" && !strings.ContainsAny(step, "()."):
+			return step
+		}
+		return refCtxUnknown
+	}
+	return ctxName
+}
+
+// splitXPathSteps splits an xpath by '/', ignoring those inside predicates and quotes.
+func splitXPathSteps(xpath string) []string {
+	var steps []string
+	depth, start := 0, 0
+	var quote rune
+	for i, c := range xpath {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			if i > start {
+				steps = append(steps, xpath[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(xpath) {
+		steps = append(steps, xpath[start:])
+	}
+	return steps
+}
+
+// stripXPathPredicates removes all the predicates from a single xpath location step.
+func stripXPathPredicates(step string) string {
+	var b strings.Builder
+	depth := 0
+	var quote rune
+	for _, c := range step {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,247 @@
+package transform
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/idr"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestAnalyzeReferences(t *testing.T) {
+	funcs := customfuncs.CustomFuncs{
+		"concat": func(_ *transformctx.Ctx, args ...string) (string, error) { return "", nil },
+		"copy":   func(_ *transformctx.Ctx, n *idr.Node) (interface{}, error) { return nil, nil },
+	}
+	keys := func(m map[string]bool) []string {
+		var ret []string
+		for k := range m {
+			ret = append(ret, k)
+		}
+		sort.Strings(ret)
+		return ret
+	}
+	for _, test := range []struct {
+		name          string
+		declJSON      string
+		expNames      []string
+		expValueNames []string
+		inconclusive  bool
+	}{
+		{
+			name: "fields, objects, arrays and custom_func args",
+			declJSON: `{
+				"transform_declarations": {
+					"FINAL_OUTPUT": { "xpath": ".[type != 'x']", "object": {
+						"id": { "xpath": "id" },
+						"name": { "custom_func": { "name": "concat", "args": [
+							{ "xpath": "first_name" }, { "const": " " }, { "xpath": "last_name[. != 'n/a']" }
+						]}},
+						"addr": { "xpath": "address", "object": {
+							"city": { "xpath": "child::city" },
+							"zip": { "xpath": "@zip" }
+						}},
+						"tags": { "array": [ { "xpath": "tags/tag", "template": "tag_template" } ] },
+						"amount": { "xpath": "amount/text()", "type": "float" }
+					}},
+					"tag_template": { "custom_func": { "name": "concat", "args": [ { "xpath": "." } ] } }
+				}
+			}`,
+			expNames: []string{
+				"address", "amount", "city", "first_name", "id", "last_name", "tag", "tags", "type", "zip"},
+			expValueNames: []string{"amount", "city", "first_name", "id", "last_name", "tag", "zip"},
+		},
+		{
+			name: "wildcard",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "*[1]" } } } }
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "node()",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "node()" } } } }
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "xpath_dynamic",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": {
+					"a": { "xpath_dynamic": { "const": "b" } }
+				}}}
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "custom_func taking node",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": {
+					"a": { "xpath": "b", "custom_func": { "name": "copy" } }
+				}}}
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "field reading record value",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "." } } } }
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "field reading parent value",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": {
+					"a": { "xpath": "b", "object": { "c": { "xpath": ".." } } }
+				}}}
+			}`,
+			inconclusive: true,
+		},
+		{
+			name: "quoted literals and functions are not names",
+			declJSON: `{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": {
+					"a": { "xpath": "b[contains(., 'c d') and string-length(e) > 1]" }
+				}}}
+			}`,
+			expNames:      []string{"and", "b", "e"},
+			expValueNames: []string{"b"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decl, err := ValidateTransformDeclarations([]byte(test.declJSON), funcs, nil)
+			assert.NoError(t, err)
+			refs := AnalyzeReferences(decl)
+			if test.inconclusive {
+				assert.Nil(t, refs)
+				return
+			}
+			assert.NotNil(t, refs)
+			assert.Equal(t, test.expNames, keys(refs.Names))
+			assert.Equal(t, test.expValueNames, keys(refs.ValueNames))
+			for _, name := range test.expNames {
+				assert.True(t, refs.Referenced(name))
+			}
+			for _, name := range test.expValueNames {
+				assert.True(t, refs.ValueRead(name))
+			}
+		})
+	}
+	assert.Nil(t, AnalyzeReferences(nil))
+}
+
+func TestAnalyzeReferences_RecordOrder(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		recordOrder  string
+		inconclusive bool
+	}{
+		{
+			name:        "key and group key",
+			recordOrder: `{ "key": { "xpath": "b" }, "group_key": { "xpath": "c" } }`,
+		},
+		{
+			name:         "key reading the record node's value",
+			recordOrder:  `{ "key": { "xpath": "." } }`,
+			inconclusive: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			schema := []byte(`{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "a" } } } },
+				"record_order": ` + test.recordOrder + `
+			}`)
+			decl, err := ValidateTransformDeclarations(schema, nil, nil)
+			assert.NoError(t, err)
+			order, err := ValidateRecordOrder(schema, nil, nil)
+			assert.NoError(t, err)
+			order.Relate(decl)
+			refs := AnalyzeReferences(decl)
+			if test.inconclusive {
+				assert.Nil(t, refs)
+				return
+			}
+			assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, refs.Names)
+			assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, refs.ValueNames)
+		})
+	}
+}
+
+func TestAnalyzeReferences_FileHooks(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		hooks        string
+		inconclusive bool
+	}{
+		{
+			name:  "header and trailer",
+			hooks: `"file_header": { "xpath": "b", "object": { "c": { "xpath": "c" } } }, "file_trailer": { "xpath": "d" }`,
+		},
+		{
+			name:         "hook reading the root's value",
+			hooks:        `"file_trailer": { "xpath": "." }`,
+			inconclusive: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			schema := []byte(`{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "a" } } } },
+				` + test.hooks + `
+			}`)
+			decl, err := ValidateTransformDeclarations(schema, nil, nil)
+			assert.NoError(t, err)
+			hooks, err := ValidateFileHooks(schema, nil, nil)
+			assert.NoError(t, err)
+			hooks.Relate(decl)
+			refs := AnalyzeReferences(decl)
+			if test.inconclusive {
+				assert.Nil(t, refs)
+				return
+			}
+			assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true, "d": true}, refs.Names)
+			assert.Equal(t, map[string]bool{"a": true, "c": true, "d": true}, refs.ValueNames)
+		})
+	}
+}
+
+func TestAnalyzeReferences_RecordContext(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		recordContext string
+		inconclusive  bool
+	}{
+		{
+			name:          "values",
+			recordContext: `{ "x": { "xpath": "b/c" }, "y": { "xpath": "d" } }`,
+		},
+		{
+			name:          "value of the root",
+			recordContext: `{ "x": { "xpath": "b/c" }, "y": { "xpath": "." } }`,
+			inconclusive:  true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			schema := []byte(`{
+				"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "a" } } } },
+				"record_context": ` + test.recordContext + `
+			}`)
+			decl, err := ValidateTransformDeclarations(schema, nil, nil)
+			assert.NoError(t, err)
+			rc, err := ValidateRecordContext(schema, nil, nil)
+			assert.NoError(t, err)
+			rc.Relate(decl)
+			refs := AnalyzeReferences(decl)
+			if test.inconclusive {
+				assert.Nil(t, refs)
+				return
+			}
+			assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true, "d": true}, refs.Names)
+			assert.Equal(t, map[string]bool{"a": true, "c": true, "d": true}, refs.ValueNames)
+		})
+	}
+}
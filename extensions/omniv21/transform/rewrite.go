@@ -0,0 +1,54 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RewriteXPaths rewrites the `xpath`s of a validated Decl tree and all the decls under it, e.g. the args
+// of its custom funcs, with templates expanded, for file formats to compile their own XPath notations
+// into XPath. rewrite returns the xpath as is if there is nothing to rewrite. The `xpath_dynamic`s,
+// computed at transform time, aren't rewritten.
+func RewriteXPaths(decl *Decl, rewrite func(xpath string) (string, error)) error {
+	if decl == nil {
+		return nil
+	}
+	if decl.XPath != nil {
+		xpath, err := rewrite(*decl.XPath)
+		if err != nil {
+			return fmt.Errorf("'%s': %s", decl.fqdn, err.Error())
+		}
+		decl.XPath = &xpath
+	}
+	for _, child := range []*Decl{decl.Key, decl.XPathDynamic} {
+		if err := RewriteXPaths(child, rewrite); err != nil {
+			return err
+		}
+	}
+	if decl.CustomFunc != nil {
+		for _, arg := range decl.CustomFunc.Args {
+			if err := RewriteXPaths(arg, rewrite); err != nil {
+				return err
+			}
+		}
+	}
+	for _, decls := range []map[string]*Decl{decl.Vars, decl.Object} {
+		var names []string
+		for name := range decls {
+			names = append(names, name)
+		}
+		// rewrites in a deterministic order, so the same schema fails with the same error.
+		sort.Strings(names)
+		for _, name := range names {
+			if err := RewriteXPaths(decls[name], rewrite); err != nil {
+				return err
+			}
+		}
+	}
+	for _, elem := range decl.Array {
+		if err := RewriteXPaths(elem, rewrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package transform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteXPaths(t *testing.T) {
+	content := []byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a", "object": {
+				"b": { "xpath": "b" },
+				"c": { "xpath_dynamic": { "custom_func": { "name": "concat", "args": [ { "xpath": "c" } ] } } },
+				"items": { "array": [ { "xpath": "d", "template": "t" } ] }
+			}},
+			"t": { "object": { "e": { "custom_func": { "name": "upper", "args": [ { "xpath": "e" } ] } } } }
+		}
+	}`)
+	finalOutputDecl, err := ValidateTransformDeclarations(content, testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	var rewritten []string
+	err = RewriteXPaths(finalOutputDecl, func(xpath string) (string, error) {
+		rewritten = append(rewritten, xpath)
+		return strings.ToUpper(xpath), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/a", "b", "c", "d", "e"}, rewritten)
+	var xpaths []string
+	for _, f := range OutputFields(finalOutputDecl) {
+		if f.XPath != "" {
+			xpaths = append(xpaths, f.XPath)
+		}
+	}
+	assert.Equal(t, []string{"/A", "B", `xpath_dynamic(custom_func concat(xpath "C"))`, "D"}, xpaths)
+
+	err = RewriteXPaths(finalOutputDecl, func(xpath string) (string, error) {
+		if xpath == "D" {
+			return "", errors.New("bad xpath")
+		}
+		return xpath, nil
+	})
+	assert.EqualError(t, err, "'FINAL_OUTPUT.items.elem[1]': bad xpath")
+}
@@ -0,0 +1,37 @@
+package transform
+
+import (
+	"time"
+
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// Stats collects the invocation stats of the templates and custom funcs, keyed by their names, of the
+// ParseNode calls of the parseCtx's it's given to. The latency of a template includes the latencies of
+// all the templates and custom funcs it invokes, while the latency of a custom func excludes the
+// evaluation of its args. Invocations answered from the transform cache or the memo aren't counted.
+type Stats struct {
+	Templates   map[string]schemahandler.InvocationStats
+	CustomFuncs map[string]schemahandler.InvocationStats
+}
+
+// NewStats creates an empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		Templates:   map[string]schemahandler.InvocationStats{},
+		CustomFuncs: map[string]schemahandler.InvocationStats{},
+	}
+}
+
+func addInvocation(m map[string]schemahandler.InvocationStats, name string, start time.Time) {
+	stats := m[name]
+	stats.Count++
+	stats.Duration += time.Since(start)
+	m[name] = stats
+}
+
+// WithStats makes the parseCtx collect the invocation stats into stats.
+func (p *parseCtx) WithStats(stats *Stats) *parseCtx {
+	p.stats = stats
+	return p
+}
@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/schemahandler"
+)
+
+func TestParseCtx_WithStats(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"upper_c": { "custom_func": { "name": "upper", "args": [ { "xpath": "C" } ] } },
+				"all": { "array": [ { "xpath": "*", "template": "t" } ] },
+				"again": { "xpath": "B", "template": "t" },
+				"indirect": { "xpath": "C", "template": "indirect" }
+			}},
+			"t": { "custom_func": { "name": "concat", "args": [
+				{ "xpath": "." }, { "custom_func": { "name": "upper", "args": [ { "const": "!" } ] } }
+			]}},
+			"indirect": { "template": "t" }
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	stats := NewStats()
+	p := testParseCtx().WithStats(stats)
+	v, err := p.ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		map[string]interface{}{
+			"upper_c": "C", "all": []interface{}{"b!", "c!"}, "again": "b!", "indirect": "c!"},
+		v)
+	// "indirect" is expanded into the template "t" it references.
+	assert.Equal(t, []string{"t"}, keys(stats.Templates))
+	assert.Equal(t, 4, stats.Templates["t"].Count)
+	assert.True(t, stats.Templates["t"].Duration > 0)
+	assert.Equal(t, []string{"concat", "upper"}, keys(stats.CustomFuncs))
+	assert.Equal(t, 4, stats.CustomFuncs["concat"].Count)
+	assert.Equal(t, 5, stats.CustomFuncs["upper"].Count)
+	// the latency of a template includes the ones of the custom funcs it invokes.
+	assert.True(t, stats.Templates["t"].Duration >= stats.CustomFuncs["concat"].Duration)
+
+	// nothing is collected without stats.
+	_, err = testParseCtx().ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Templates["t"].Count)
+}
+
+func keys(m map[string]schemahandler.InvocationStats) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
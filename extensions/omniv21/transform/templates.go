@@ -0,0 +1,231 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/strs"
+)
+
+const (
+	// maxTemplateRefDepth limits how deeply template references can nest, starting from 'FINAL_OUTPUT'.
+	maxTemplateRefDepth = 64
+	// maxExpandedDecls limits the number of decls 'FINAL_OUTPUT' expands into once all of its template
+	// references are expanded. Each template reference is expanded into a copy of the template, so a
+	// few templates each referencing the next one several times can expand exponentially.
+	maxExpandedDecls = 1 << 20
+)
+
+// templateInfo describes a `transform_declarations` decl as a template.
+type templateInfo struct {
+	refs  []string // names of the templates it references, in a deterministic order, dups included.
+	decls int      // number of decls it contains, not counting those of the templates it references.
+	line  int      // 1-based line number in the schema; 0 if unknown.
+}
+
+// validateTemplateRefs analyzes the template references among all the `transform_declarations`
+// decls, before any of them is expanded, and reports any circular reference, excessively deep
+// nesting, or exponential expansion of the template references, which would otherwise cause the
+// expansion to never finish. Non-existing template references are left for validateTemplate to
+// report, as it knows the full fqdn of the referencing site.
+func (ctx *validateCtx) validateTemplateRefs(schemaContent []byte) error {
+	lines := declLines(schemaContent)
+	var names []string
+	templates := map[string]*templateInfo{}
+	for name, decl := range ctx.Decls {
+		info := &templateInfo{line: lines[name]}
+		info.decls = collectTemplateRefs(decl, &info.refs)
+		templates[name] = info
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	describe := func(chain []string) string {
+		return strings.Join(
+			strs.NoErrMapSlice(chain, func(name string) string {
+				if line := templates[name].line; line > 0 {
+					return fmt.Sprintf("'%s' (line %d)", name, line)
+				}
+				return fmt.Sprintf("'%s'", name)
+			}),
+			" -> ")
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range templates[name].refs {
+			if _, found := templates[ref]; !found {
+				continue
+			}
+			switch state[ref] {
+			case visiting:
+				for i := range path {
+					if path[i] == ref {
+						return fmt.Errorf("template circular reference detected: %s",
+							describe(append(append([]string(nil), path[i:]...), ref)))
+					}
+				}
+			case unvisited:
+				if err := visit(ref); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			if err := visit(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Now that there is no cycle, the depth and the expanded size of each template can be computed
+	// recursively.
+	depths := map[string][]string{} // the deepest template reference chain starting from each template.
+	var deepest func(name string) []string
+	deepest = func(name string) []string {
+		if chain, found := depths[name]; found {
+			return chain
+		}
+		var longest []string
+		for _, ref := range templates[name].refs {
+			if _, found := templates[ref]; found {
+				if chain := deepest(ref); len(chain) > len(longest) {
+					longest = chain
+				}
+			}
+		}
+		chain := append([]string{name}, longest...)
+		depths[name] = chain
+		return chain
+	}
+	// FINAL_OUTPUT is guaranteed to exist by the json schema validation done earlier.
+	if chain := deepest(finalOutput); len(chain) > maxTemplateRefDepth {
+		return fmt.Errorf("template references nest more than %d levels deep: %s",
+			maxTemplateRefDepth, describe(chain))
+	}
+	sizes := map[string]int{}
+	var size func(name string) int
+	size = func(name string) int {
+		if s, found := sizes[name]; found {
+			return s
+		}
+		s := templates[name].decls
+		for _, ref := range templates[name].refs {
+			if _, found := templates[ref]; found {
+				// Stop adding once over the limit, so the sizes can't overflow.
+				if s += size(ref); s > maxExpandedDecls {
+					break
+				}
+			}
+		}
+		sizes[name] = s
+		return s
+	}
+	if size(finalOutput) > maxExpandedDecls {
+		return fmt.Errorf(
+			"template references expand '%s' into more than %d transforms; "+
+				"check for templates referencing other templates many times over",
+			finalOutput, maxExpandedDecls)
+	}
+	return nil
+}
+
+// collectTemplateRefs appends the names of the templates referenced by the decl and its descendants to
+// refs, and returns the number of decls in it, not counting the templates referenced.
+func collectTemplateRefs(decl *Decl, refs *[]string) int {
+	if decl == nil {
+		return 0
+	}
+	count := 1
+	if decl.Template != nil {
+		*refs = append(*refs, *decl.Template)
+	}
+	count += collectTemplateRefs(decl.XPathDynamic, refs)
+	count += collectTemplateRefs(decl.Key, refs)
+	if decl.CustomFunc != nil {
+		for _, arg := range decl.CustomFunc.Args {
+			count += collectTemplateRefs(arg, refs)
+		}
+	}
+	for _, decls := range []map[string]*Decl{decl.Vars, decl.Object} {
+		var childNames []string
+		for childName := range decls {
+			childNames = append(childNames, childName)
+		}
+		sort.Strings(childNames)
+		for _, childName := range childNames {
+			count += collectTemplateRefs(decls[childName], refs)
+		}
+	}
+	for _, child := range decl.Array {
+		count += collectTemplateRefs(child, refs)
+	}
+	return count
+}
+
+// declLines returns the 1-based line numbers, in the schema, of the `transform_declarations` decls,
+// keyed by their names.
+func declLines(schemaContent []byte) map[string]int {
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+	lines := map[string]int{}
+	d := json.NewDecoder(bytes.NewReader(schemaContent))
+	var stack []*frame
+	line, lineOffset := 1, 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			// We did json schema validation earlier, so the only error expected is io.EOF.
+			return lines
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		// the closing '}' of an object also arrives when a key is expected.
+		if key, ok := token.(string); ok && top != nil && top.object && top.expectKey {
+			top.key, top.expectKey = key, false
+			if len(stack) == 2 && stack[0].key == "transform_declarations" {
+				offset := int(d.InputOffset())
+				line += bytes.Count(schemaContent[lineOffset:offset], []byte("\n"))
+				lineOffset = offset
+				lines[top.key] = line
+			}
+			continue
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			stack = append(stack, &frame{object: token == json.Delim('{'), expectKey: token == json.Delim('{')})
+			continue
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return lines
+			}
+			top = stack[len(stack)-1]
+		}
+		// a value is complete: the next token in an object is a key.
+		if top != nil && top.object {
+			top.expectKey = true
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/logward/omniparser/idr"
+)
+
+// Trace records the evaluation of a transform on a node, including the evaluations of all the
+// transforms it depends on, for debugging.
+type Trace struct {
+	FQDN       string       `json:"fqdn"`
+	Kind       string       `json:"kind"`
+	Node       string       `json:"node"`              // path of the node the transform is evaluated on.
+	Queries    []QueryTrace `json:"queries,omitempty"` // xpath queries done, in order.
+	CustomFunc *FuncTrace   `json:"custom_func,omitempty"`
+	Value      interface{}  `json:"value"`
+	Error      string       `json:"error,omitempty"`
+	Children   []*Trace     `json:"children,omitempty"`
+}
+
+// QueryTrace records an xpath query and the paths of the nodes it matched.
+type QueryTrace struct {
+	XPath   string   `json:"xpath"`
+	Matched []string `json:"matched"`
+}
+
+// FuncTrace records a custom_func invocation.
+type FuncTrace struct {
+	Name   string        `json:"name"`
+	Args   []interface{} `json:"args"` // excluding the ctx and the contextual node, if any.
+	Result interface{}   `json:"result"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// WithTrace makes the parseCtx record the evaluation of each ParseNode call. Tracing disables the
+// transform cache, so that repeated evaluations are recorded as well.
+func (p *parseCtx) WithTrace() *parseCtx {
+	p.tracing = true
+	p.disableTransformCache = true
+	return p
+}
+
+// Trace returns the trace of the last ParseNode or TraceXPath call, or nil if tracing isn't on.
+func (p *parseCtx) Trace() *Trace {
+	return p.lastTrace
+}
+
+// TraceXPath queries the xpath on n, and records the nodes matched into the trace. The value traced
+// is the text of each node matched. It's a no-op if tracing isn't on.
+func (p *parseCtx) TraceXPath(n *idr.Node, xpath string) {
+	if !p.tracing {
+		return
+	}
+	t := &Trace{FQDN: xpath, Kind: "xpath", Node: nodePath(n)}
+	p.lastTrace = t
+	nodes, err := p.index.MatchAll(n, xpath, idr.DisableXPathCache)
+	if err != nil {
+		t.Error = fmt.Sprintf("xpath query '%s' failed: %s", xpath, err.Error())
+		return
+	}
+	p.trace = t
+	p.traceQuery(xpath, nodes...)
+	p.trace = nil
+	values := []interface{}{}
+	for _, node := range nodes {
+		values = append(values, node.InnerText())
+	}
+	t.Value = values
+}
+
+func (p *parseCtx) traceParseNode(n *idr.Node, decl *Decl) (interface{}, error) {
+	t := &Trace{FQDN: decl.fqdn, Kind: string(decl.kind), Node: nodePath(n)}
+	parent := p.trace
+	if parent == nil {
+		p.lastTrace = t
+	} else {
+		parent.Children = append(parent.Children, t)
+	}
+	p.trace = t
+	v, err := p.parseNode(n, decl)
+	p.trace = parent
+	t.Value = traceValue(v)
+	if err != nil {
+		t.Error = err.Error()
+	}
+	return v, err
+}
+
+func (p *parseCtx) traceQuery(xpath string, nodes ...*idr.Node) {
+	if p.trace == nil {
+		return
+	}
+	q := QueryTrace{XPath: xpath, Matched: []string{}}
+	for _, n := range nodes {
+		q.Matched = append(q.Matched, nodePath(n))
+	}
+	p.trace.Queries = append(p.trace.Queries, q)
+}
+
+func (p *parseCtx) traceCustomFunc(customFuncDecl *CustomFuncDecl, argValues, result []reflect.Value) {
+	f := &FuncTrace{Name: customFuncDecl.Name, Args: []interface{}{}, Result: traceValue(result[0].Interface())}
+	// skip the ctx and the contextual node, if any.
+	first := len(argValues) - len(customFuncDecl.Args)
+	for _, arg := range argValues[first:] {
+		f.Args = append(f.Args, arg.Interface())
+	}
+	if err := result[1].Interface(); err != nil {
+		f.Error = err.(error).Error()
+	}
+	p.trace.CustomFunc = f
+}
+
+// traceValue returns the value to be traced for v: the IDR nodes returned by a custom_func, which can't
+// be marshaled into JSON, are traced as their paths.
+func traceValue(v interface{}) interface{} {
+	switch nodes := v.(type) {
+	case *idr.Node:
+		if nodes != nil {
+			return nodePath(nodes)
+		}
+		return nil
+	case []*idr.Node:
+		paths := []string{}
+		for _, n := range nodes {
+			paths = append(paths, nodePath(n))
+		}
+		return paths
+	}
+	return v
+}
+
+// nodePath returns an xpath-like path of n from the root of its tree, e.g. "/Order/Item[2]/SKU",
+// with the 1-based position of a node among its siblings of the same name only if it has any.
+func nodePath(n *idr.Node) string {
+	var segs []string
+	for ; n != nil && n.Type != idr.DocumentNode; n = n.Parent {
+		name := n.Data
+		switch {
+		case n.Type == idr.TextNode:
+			name = "text()"
+		case n.Type == idr.AttributeNode:
+			name = "@" + name
+		case name == "":
+			name = "*"
+		}
+		if n.Parent != nil {
+			pos, count := 0, 0
+			for s := n.Parent.FirstChild; s != nil; s = s.NextSibling {
+				if s.Type == n.Type && s.Data == n.Data {
+					count++
+					if s == n {
+						pos = count
+					}
+				}
+			}
+			if count > 1 {
+				name = fmt.Sprintf("%s[%d]", name, pos)
+			}
+		}
+		segs = append([]string{name}, segs...)
+	}
+	return "/" + strings.Join(segs, "/")
+}
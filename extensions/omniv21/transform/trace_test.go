@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/jf-tech/go-corelib/jsons"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/idr"
+)
+
+func TestParseCtx_Trace(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"b": { "xpath": "B" },
+				"upper_c": { "custom_func": { "name": "upper", "args": [ { "xpath": "C" } ] } },
+				"all": { "array": [ { "xpath": "*", "template": "t" } ] },
+				"none": { "xpath": "D" }
+			}},
+			"t": { "custom_func": { "name": "concat", "args": [ { "xpath": "." }, { "const": "!" } ] } }
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	p := testParseCtx()
+	assert.Nil(t, p.Trace())
+	p.WithTrace()
+	v, err := p.ParseNode(testNode(), finalOutputDecl)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		map[string]interface{}{"b": "b", "upper_c": "C", "all": []interface{}{"b!", "c!"}},
+		v)
+	cupaloy.SnapshotT(t, jsons.BPM(p.Trace()))
+}
+
+func TestParseCtx_Trace_Error(t *testing.T) {
+	finalOutputDecl, err := ValidateTransformDeclarations([]byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"d": { "custom_func": { "name": "dateTimeToRFC3339", "args": [
+					{ "xpath": "B" }, { "const": "" }, { "const": "" }
+				]}}
+			}}
+		}
+	}`), testParseCtx().customFuncs, nil)
+	assert.NoError(t, err)
+	p := testParseCtx().WithTrace()
+	_, err = p.ParseNode(testNode(), finalOutputDecl)
+	assert.Error(t, err)
+	trace := p.Trace()
+	assert.Equal(t, err.Error(), trace.Error)
+	assert.Equal(t, err.Error(), trace.Children[0].Error)
+	assert.Equal(t, "dateTimeToRFC3339", trace.Children[0].CustomFunc.Name)
+	assert.Equal(t, []interface{}{"b", "", ""}, trace.Children[0].CustomFunc.Args)
+	assert.NotEmpty(t, trace.Children[0].CustomFunc.Error)
+}
+
+func TestParseCtx_TraceXPath(t *testing.T) {
+	p := testParseCtx()
+	p.TraceXPath(testNode(), "*")
+	assert.Nil(t, p.Trace())
+
+	p.WithTrace()
+	p.TraceXPath(testNode(), "*")
+	assert.Equal(t, &Trace{
+		FQDN:    "*",
+		Kind:    "xpath",
+		Node:    "/A",
+		Queries: []QueryTrace{{XPath: "*", Matched: []string{"/A/B", "/A/C"}}},
+		Value:   []interface{}{"b", "c"},
+	}, p.Trace())
+
+	p.TraceXPath(testNode(), "[")
+	assert.Equal(t, "[", p.Trace().FQDN)
+	assert.Contains(t, p.Trace().Error, "xpath query '[' failed")
+}
+
+func TestNodePath(t *testing.T) {
+	root := idr.CreateNode(idr.DocumentNode, "")
+	a := idr.CreateNode(idr.ElementNode, "A")
+	b1 := idr.CreateNode(idr.ElementNode, "B")
+	b2 := idr.CreateNode(idr.ElementNode, "B")
+	attr := idr.CreateNode(idr.AttributeNode, "id")
+	text := idr.CreateNode(idr.TextNode, "x")
+	anon := idr.CreateNode(idr.ElementNode, "")
+	idr.AddChild(root, a)
+	idr.AddChild(a, b1)
+	idr.AddChild(a, b2)
+	idr.AddChild(b2, attr)
+	idr.AddChild(b2, text)
+	idr.AddChild(b1, anon)
+	assert.Equal(t, "/A", nodePath(a))
+	assert.Equal(t, "/A/B[1]/*", nodePath(anon))
+	assert.Equal(t, "/A/B[2]/@id", nodePath(attr))
+	assert.Equal(t, "/A/B[2]/text()", nodePath(text))
+	assert.Equal(t, "/", nodePath(root))
+}
+
+func TestTraceValue(t *testing.T) {
+	n := testNode()
+	assert.Equal(t, "/A/B", traceValue(n.FirstChild))
+	assert.Nil(t, traceValue((*idr.Node)(nil)))
+	assert.Equal(t, []string{"/A/C", "/A/B"}, traceValue([]*idr.Node{n.LastChild, n.FirstChild}))
+	assert.Equal(t, []string{}, traceValue([]*idr.Node(nil)))
+	assert.Equal(t, "abc", traceValue("abc"))
+}
@@ -5,19 +5,27 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
-	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jf-tech/go-corelib/strs"
 
 	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/idr"
 )
 
 type validateCtx struct {
-	Decls            map[string]*Decl `json:"transform_declarations"`
+	Decls            map[string]*Decl          `json:"transform_declarations"`
+	Constants        map[string]*constantValue `json:"constants"`
+	RecordOrder      *RecordOrder              `json:"record_order"`
+	FileHeader       *Decl                     `json:"file_header"`
+	FileTrailer      *Decl                     `json:"file_trailer"`
+	RecordContext    map[string]*Decl          `json:"record_context"`
 	customFuncs      customfuncs.CustomFuncs
 	customParseFuncs CustomParseFuncs // Deprecated.
 	declHashes       map[string]string
+	// xpathDynamicFQDN is the fqdn of the `xpath_dynamic` decl being validated, the only place where a
+	// custom func returning IDR nodes can be used.
+	xpathDynamicFQDN string
 }
 
 // ValidateTransformDeclarations validates `transform_declarations` section of an omni schema and returns
@@ -32,8 +40,11 @@ func ValidateTransformDeclarations(
 	ctx.customParseFuncs = customParseFuncs
 	ctx.declHashes = map[string]string{}
 
+	if err := ctx.validateTemplateRefs(schemaContent); err != nil {
+		return nil, err
+	}
 	// We did json schema validation earlier, so "FINAL_OUTPUT" must exist.
-	finalOutputDecl, err := ctx.validateDecl(finalOutput, ctx.Decls[finalOutput], []string{finalOutput})
+	finalOutputDecl, err := ctx.validateDecl(finalOutput, ctx.Decls[finalOutput])
 	if err != nil {
 		return nil, err
 	}
@@ -41,12 +52,36 @@ func ValidateTransformDeclarations(
 	return finalOutputDecl, nil
 }
 
-// In order to detect circular template references (e.g. template A has a reference to template B which
-// has a reference to C and C has one back to A), we need to keep a template reference stack, starting
-// from the root template 'FINAL_OUTPUT'. Everytime we see a template, we push its name onto the stack.
-// and check if it has appeared before or not.
-func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack []string) (*Decl, error) {
-	err := ctx.validateXPath(fqdn, decl, templateRefStack)
+// ValidateNamedDeclaration validates the `transform_declarations` decl of the given name, e.g. a
+// template, of an omni schema, as if it were `FINAL_OUTPUT`, and returns its Decl, or nil if there is
+// no decl of the name. ValidateTransformDeclarations must have succeeded on the schema.
+func ValidateNamedDeclaration(
+	schemaContent []byte, name string, customFuncs customfuncs.CustomFuncs,
+	customParseFuncs CustomParseFuncs) (*Decl, error) {
+
+	var ctx validateCtx
+	_ = json.Unmarshal(schemaContent, &ctx)
+	ctx.customFuncs = customFuncs
+	ctx.customParseFuncs = customParseFuncs
+	ctx.declHashes = map[string]string{}
+
+	decl, found := ctx.Decls[name]
+	if !found {
+		return nil, nil
+	}
+	// ValidateTransformDeclarations has ruled out circular template references among all the decls.
+	decl, err := ctx.validateDecl(name, decl)
+	if err != nil {
+		return nil, err
+	}
+	linkParent(decl)
+	return decl, nil
+}
+
+// Note validateDecl expands template references recursively, so validateTemplateRefs must have been
+// called to rule out circular template references.
+func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl) (*Decl, error) {
+	err := ctx.validateXPath(fqdn, decl)
 	if err != nil {
 		return nil, err
 	}
@@ -54,17 +89,17 @@ func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack [
 	decl.resolveKind()
 	switch decl.kind {
 	case kindObject:
-		err := ctx.validateObject(fqdn, decl, templateRefStack)
+		err := ctx.validateObject(fqdn, decl)
 		if err != nil {
 			return nil, err
 		}
 	case kindArray:
-		err := ctx.validateArray(fqdn, decl, templateRefStack)
+		err := ctx.validateArray(fqdn, decl)
 		if err != nil {
 			return nil, err
 		}
 	case kindCustomFunc:
-		err := ctx.validateCustomFunc(fqdn, decl, templateRefStack)
+		err := ctx.validateCustomFunc(fqdn, decl)
 		if err != nil {
 			return nil, err
 		}
@@ -73,8 +108,13 @@ func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack [
 		if err != nil {
 			return nil, err
 		}
+	case kindConstant:
+		err := ctx.validateConstant(fqdn, decl)
+		if err != nil {
+			return nil, err
+		}
 	case kindTemplate:
-		decl, err = ctx.validateTemplate(fqdn, decl, templateRefStack)
+		decl, err = ctx.validateTemplate(fqdn, decl)
 		if err != nil {
 			return nil, err
 		}
@@ -83,7 +123,7 @@ func (ctx *validateCtx) validateDecl(fqdn string, decl *Decl, templateRefStack [
 	return decl, nil
 }
 
-func (ctx *validateCtx) validateXPath(fqdn string, decl *Decl, templateRefStack []string) error {
+func (ctx *validateCtx) validateXPath(fqdn string, decl *Decl) error {
 	if decl.XPath != nil && decl.XPathDynamic != nil {
 		return fmt.Errorf("'%s' cannot set both 'xpath' and 'xpath_dynamic' at the same time", fqdn)
 	}
@@ -92,8 +132,10 @@ func (ctx *validateCtx) validateXPath(fqdn string, decl *Decl, templateRefStack
 	// and validate the decl as well.
 	if decl.XPathDynamic != nil {
 		var err error
-		decl.XPathDynamic, err = ctx.validateDecl(
-			strs.BuildFQDN(fqdn, "xpath_dynamic"), decl.XPathDynamic, templateRefStack)
+		xpathDynamicFQDN := ctx.xpathDynamicFQDN
+		ctx.xpathDynamicFQDN = strs.BuildFQDN(fqdn, "xpath_dynamic")
+		decl.XPathDynamic, err = ctx.validateDecl(ctx.xpathDynamicFQDN, decl.XPathDynamic)
+		ctx.xpathDynamicFQDN = xpathDynamicFQDN
 		if err != nil {
 			return err
 		}
@@ -101,11 +143,20 @@ func (ctx *validateCtx) validateXPath(fqdn string, decl *Decl, templateRefStack
 	return nil
 }
 
-func (ctx *validateCtx) validateObject(fqdn string, decl *Decl, templateRefStack []string) error {
+func (ctx *validateCtx) validateObject(fqdn string, decl *Decl) error {
+	for name, varDecl := range decl.Vars {
+		varDecl, err := ctx.validateDecl(strs.BuildFQDN(fqdn, fmt.Sprintf("var(%s)", name)), varDecl)
+		if err != nil {
+			return err
+		}
+		decl.Vars[name] = varDecl
+		decl.varNames = append(decl.varNames, name)
+	}
+	sort.Strings(decl.varNames)
 	for childName, childDecl := range decl.Object {
 		childDecl, err := ctx.validateDecl(
 			// childName can contain '.' or '%', it needs to be escaped.
-			strs.BuildFQDN(fqdn, strs.BuildFQDNWithEsc(childName)), childDecl, templateRefStack)
+			strs.BuildFQDN(fqdn, strs.BuildFQDNWithEsc(childName)), childDecl)
 		if err != nil {
 			return err
 		}
@@ -121,10 +172,10 @@ func (ctx *validateCtx) validateObject(fqdn string, decl *Decl, templateRefStack
 	return nil
 }
 
-func (ctx *validateCtx) validateArray(fqdn string, decl *Decl, templateRefStack []string) error {
+func (ctx *validateCtx) validateArray(fqdn string, decl *Decl) error {
 	for i, childDecl := range decl.Array {
 		childDecl, err := ctx.validateDecl(
-			strs.BuildFQDN(fqdn, fmt.Sprintf("elem[%d]", i+1)), childDecl, templateRefStack)
+			strs.BuildFQDN(fqdn, fmt.Sprintf("elem[%d]", i+1)), childDecl)
 		if err != nil {
 			return err
 		}
@@ -138,7 +189,7 @@ func (ctx *validateCtx) validateArray(fqdn string, decl *Decl, templateRefStack
 	return nil
 }
 
-func (ctx *validateCtx) validateCustomFunc(fqdn string, decl *Decl, templateRefStack []string) error {
+func (ctx *validateCtx) validateCustomFunc(fqdn string, decl *Decl) error {
 	fn, found := ctx.customFuncs[decl.CustomFunc.Name]
 	if !found {
 		return fmt.Errorf("unknown custom_func '%s' on '%s'", decl.CustomFunc.Name, fqdn)
@@ -159,11 +210,19 @@ func (ctx *validateCtx) validateCustomFunc(fqdn string, decl *Decl, templateRefS
 			decl.CustomFunc.Name, fnType.Out(1))
 	}
 	decl.CustomFunc.fqdn = strs.BuildFQDN(fqdn, fmt.Sprintf("custom_func(%s)", decl.CustomFunc.Name))
+	decl.CustomFunc.nodeArg = fnType.NumIn() >= 2 && fnType.In(1) == reflect.TypeOf((*idr.Node)(nil))
+	// A custom func returning IDR nodes can only be used as an `xpath_dynamic`, for the nodes to become the
+	// source nodes of the decl, e.g. the elements of an array.
+	decl.CustomFunc.nodesResult = fnType.Out(0) == reflect.TypeOf((*idr.Node)(nil)) ||
+		fnType.Out(0) == reflect.TypeOf([]*idr.Node(nil))
+	if decl.CustomFunc.nodesResult && fqdn != ctx.xpathDynamicFQDN {
+		return fmt.Errorf("custom_func '%s' on '%s' returns IDR nodes, thus can only be used as 'xpath_dynamic'",
+			decl.CustomFunc.Name, fqdn)
+	}
 	for i := 0; i < len(decl.CustomFunc.Args); i++ {
 		argDecl, err := ctx.validateDecl(
 			strs.BuildFQDN(decl.CustomFunc.fqdn, fmt.Sprintf("arg[%d]", i+1)),
-			decl.CustomFunc.Args[i],
-			templateRefStack)
+			decl.CustomFunc.Args[i])
 		if err != nil {
 			return err
 		}
@@ -180,7 +239,32 @@ func (ctx *validateCtx) validateCustomParse(fqdn string, decl *Decl) error {
 	return nil
 }
 
-func (ctx *validateCtx) validateTemplate(fqdn string, decl *Decl, templateRefStack []string) (*Decl, error) {
+func (ctx *validateCtx) validateConstant(fqdn string, decl *Decl) error {
+	constant, found := ctx.Constants[*decl.Constant]
+	if !found {
+		return fmt.Errorf("'%s' contains non-existing constant reference '%s'", fqdn, *decl.Constant)
+	}
+	decl.constant = constant
+	if constant.value != nil {
+		if decl.Key != nil {
+			return fmt.Errorf(
+				"'%s' cannot specify 'key' as constant '%s' is not a mapping table", fqdn, *decl.Constant)
+		}
+		return nil
+	}
+	if decl.Key == nil {
+		return fmt.Errorf("'%s' must specify 'key' as constant '%s' is a mapping table", fqdn, *decl.Constant)
+	}
+	keyDecl, err := ctx.validateDecl(strs.BuildFQDN(fqdn, "key"), decl.Key)
+	if err != nil {
+		return err
+	}
+	decl.Key = keyDecl
+	decl.children = append(decl.children, keyDecl)
+	return nil
+}
+
+func (ctx *validateCtx) validateTemplate(fqdn string, decl *Decl) (*Decl, error) {
 	templateName := *decl.Template
 	templateDecl, found := ctx.Decls[templateName]
 	if !found {
@@ -188,16 +272,6 @@ func (ctx *validateCtx) validateTemplate(fqdn string, decl *Decl, templateRefSta
 			"'%s' contains non-existing template reference '%s'", fqdn, templateName)
 	}
 
-	// need to make a copy otherwise slice is passed by reference and append might alter
-	// the slice in place.
-	templateRefStack = append(strs.CopySlice(templateRefStack), templateName)
-	if strs.HasDup(templateRefStack) {
-		return nil, fmt.Errorf("template circular dependency detected on '%s': %s",
-			fqdn, strings.Join(
-				strs.NoErrMapSlice(templateRefStack, func(s string) string { return "'" + s + "'" }),
-				"->"))
-	}
-
 	// Make a copy in case the template is referenced in multiple places.
 	declNew := templateDecl.deepCopy()
 	// between the template site and the template itself, there can only be one decl with xpath/xpath_dynamic set.
@@ -211,7 +285,15 @@ func (ctx *validateCtx) validateTemplate(fqdn string, decl *Decl, templateRefSta
 		declNew.XPathDynamic = decl.XPathDynamic
 	}
 
-	return ctx.validateDecl(fqdn, declNew, templateRefStack)
+	declNew, err := ctx.validateDecl(fqdn, declNew)
+	if err != nil {
+		return nil, err
+	}
+	// a template referencing another template right away is expanded into the latter.
+	if declNew.template == "" {
+		declNew.template = templateName
+	}
+	return declNew, nil
 }
 
 func computeDeclHash(decl *Decl, declHashes map[string]string) string {
@@ -263,4 +345,8 @@ func linkParent(decl *Decl) {
 		child.parent = decl
 		linkParent(child)
 	}
+	for _, varDecl := range decl.Vars {
+		varDecl.parent = decl
+		linkParent(varDecl)
+	}
 }
@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/bradleyjkemp/cupaloy"
@@ -117,6 +119,46 @@ func TestValidateTransformDeclarations(t *testing.T) {
             }`,
 			err: "'FINAL_OUTPUT.field4.elem[1]' contains non-existing template reference 'template12'",
 		},
+		{
+			name: "success - custom_func returning nodes as xpath_dynamic",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "items": { "array": [
+                            { "xpath_dynamic": { "custom_func": { "name": "test_nodes_func" } }, "object": {
+                                "id": { "xpath": "ID" }
+                            }}
+                        ]},
+                        "first_item": { "xpath_dynamic": { "template": "nodes_template" }, "object": {
+                            "id": { "xpath": "ID" }
+                        }}
+                    }},
+                    "nodes_template": { "custom_func": { "name": "test_nodes_func" } }
+                }
+            }`,
+		},
+		{
+			name: "failure - custom_func returning nodes not as xpath_dynamic",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "items": { "custom_func": { "name": "test_nodes_func" } }
+                    }}
+                }
+            }`,
+			err: "custom_func 'test_nodes_func' on 'FINAL_OUTPUT.items' returns IDR nodes, thus can only be used as 'xpath_dynamic'",
+		},
+		{
+			name: "failure - custom_func returning nodes as arg of xpath_dynamic",
+			declJSON: `{
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "xpath_dynamic": { "custom_func": { "name": "test_func", "args": [
+                        { "custom_func": { "name": "test_nodes_func" } }
+                    ]}}}
+                }
+            }`,
+			err: "custom_func 'test_nodes_func' on 'FINAL_OUTPUT.xpath_dynamic.custom_func(test_func).arg[1]' returns IDR nodes, thus can only be used as 'xpath_dynamic'",
+		},
 		{
 			name: "failure - custom_func arg decl validation failure",
 			declJSON: `{
@@ -195,7 +237,29 @@ func TestValidateTransformDeclarations(t *testing.T) {
                     }}
                 }
             }`,
-			err: "template circular dependency detected on 'FINAL_OUTPUT.field_1.field_2.field_3.field_circular': 'FINAL_OUTPUT'->'template1'->'template2'->'template3'->'template1'",
+			err: "template circular reference detected: 'template1' (line 6) -> 'template2' (line 9) -> 'template3' (line 12) -> 'template1' (line 6)",
+		},
+		{
+			name: "failure - circular template ref among templates not referenced by FINAL_OUTPUT",
+			declJSON: ` {
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "const": "abc" },
+                    "template_a": { "custom_func": { "name": "test_func", "args": [
+                        { "template": "template_b" }
+                    ]}},
+                    "template_b": { "xpath_dynamic": { "template": "template_a" } }
+                }
+            }`,
+			err: "template circular reference detected: 'template_a' (line 4) -> 'template_b' (line 7) -> 'template_a' (line 4)",
+		},
+		{
+			name: "failure - template referencing itself",
+			declJSON: ` {
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "array": [ { "const": "abc" }, { "template": "FINAL_OUTPUT" } ] }
+                }
+            }`,
+			err: "template circular reference detected: 'FINAL_OUTPUT' (line 3) -> 'FINAL_OUTPUT' (line 3)",
 		},
 		{
 			name: "failure - xpath conflict for template reference",
@@ -211,6 +275,74 @@ func TestValidateTransformDeclarations(t *testing.T) {
             }`,
 			err: "cannot specify 'xpath' or 'xpath_dynamic' on both 'FINAL_OUTPUT.field_1' and the template 'template1' it references",
 		},
+		{
+			name: "success - constants",
+			declJSON: ` {
+                "constants": {
+                    "PARTNER": "ACME",
+                    "STATES": { "CA": "California", "WA": "Washington" }
+                },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "partner": { "constant": "PARTNER" },
+                        "state": { "constant": "STATES", "key": { "xpath": "state" } },
+                        "states": { "array": [ { "template": "state_template" } ] }
+                    }},
+                    "state_template": { "constant": "STATES", "key": { "custom_func": {
+                        "name": "test_func"
+                    }}}
+                }
+            }`,
+			err: "",
+		},
+		{
+			name: "failure - non-existing constant",
+			declJSON: ` {
+                "constants": { "PARTNER": "ACME" },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "field_1": { "constant": "PARTNR" }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.field_1' contains non-existing constant reference 'PARTNR'",
+		},
+		{
+			name: "failure - key on non-table constant",
+			declJSON: ` {
+                "constants": { "PARTNER": "ACME" },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "field_1": { "constant": "PARTNER", "key": { "const": "x" } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.field_1' cannot specify 'key' as constant 'PARTNER' is not a mapping table",
+		},
+		{
+			name: "failure - no key on table constant",
+			declJSON: ` {
+                "constants": { "STATES": { "CA": "California" } },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "field_1": { "constant": "STATES" }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.field_1' must specify 'key' as constant 'STATES' is a mapping table",
+		},
+		{
+			name: "failure - key invalid",
+			declJSON: ` {
+                "constants": { "STATES": { "CA": "California" } },
+                "transform_declarations": {
+                    "FINAL_OUTPUT": { "object": {
+                        "field_1": { "constant": "STATES", "key": { "template": "non-existing" } }
+                    }}
+                }
+            }`,
+			err: "'FINAL_OUTPUT.field_1.key' contains non-existing template reference 'non-existing'",
+		},
 		{
 			name: "failure - unknown custom_parse",
 			declJSON: ` {
@@ -232,6 +364,7 @@ func TestValidateTransformDeclarations(t *testing.T) {
 					"invalid_func_missing_ctx":    func() {},
 					"invalid_func_missing_return": func(*transformctx.Ctx) {},
 					"invalid_func_no_err_return":  func(*transformctx.Ctx) (int, int) { return 0, 0 },
+					"test_nodes_func":             func(*transformctx.Ctx, *idr.Node) ([]*idr.Node, error) { return nil, nil },
 				},
 				CustomParseFuncs{
 					"test_custom_parse": func(_ *transformctx.Ctx, _ *idr.Node) (interface{}, error) {
@@ -251,6 +384,54 @@ func TestValidateTransformDeclarations(t *testing.T) {
 	}
 }
 
+func TestValidateTransformDeclarations_TemplateRefsTooDeep(t *testing.T) {
+	decls := []string{`"FINAL_OUTPUT": { "template": "t1" }`}
+	for i := 1; i < maxTemplateRefDepth; i++ {
+		decls = append(decls, fmt.Sprintf(`"t%d": { "object": { "f": { "template": "t%d" } } }`, i, i+1))
+	}
+	decls = append(decls, fmt.Sprintf(`"t%d": { "const": "abc" }`, maxTemplateRefDepth))
+	_, err := ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": {`+strings.Join(decls, ",\n")+`} }`), nil, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(),
+		"template references nest more than 64 levels deep: 'FINAL_OUTPUT' (line 1) -> 't1' (line 2) -> 't2' (line 3) -> "))
+	assert.True(t, strings.HasSuffix(err.Error(), " -> 't64' (line 65)"))
+}
+
+func TestValidateTransformDeclarations_TemplateRefsExpandExponentially(t *testing.T) {
+	decls := []string{`"FINAL_OUTPUT": { "template": "t1" }`}
+	for i := 1; i < 30; i++ {
+		decls = append(decls, fmt.Sprintf(
+			`"t%d": { "array": [ { "template": "t%d" }, { "template": "t%d" } ] }`, i, i+1, i+1))
+	}
+	decls = append(decls, `"t30": { "const": "abc" }`)
+	_, err := ValidateTransformDeclarations(
+		[]byte(`{ "transform_declarations": {`+strings.Join(decls, ",\n")+`} }`), nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t,
+		"template references expand 'FINAL_OUTPUT' into more than 1048576 transforms; "+
+			"check for templates referencing other templates many times over",
+		err.Error())
+}
+
+func TestDeclLines(t *testing.T) {
+	assert.Equal(t,
+		map[string]int{"FINAL_OUTPUT": 4, "t1": 7, "x": 7, "t2": 8},
+		declLines([]byte(`{
+			"parser_settings": { "version": "omni.2.1", "transform_declarations": "not me" },
+			"transform_declarations": {
+				"FINAL_OUTPUT": { "object": { "a": { "array": [ { "template": "t1" }, {} ] },
+					"b": { "const": "x" } }
+				},
+				"t1": { "template": "t2" }, "x": [],
+				"t2":
+					{ "const": "y" }
+			},
+			"t3": {}
+		}`)))
+	assert.Equal(t, map[string]int{}, declLines([]byte(`{ "transform_declarations": `)))
+}
+
 func TestComputeDeclHash(t *testing.T) {
 	decl1 := &Decl{
 		Object: map[string]*Decl{
@@ -282,3 +463,93 @@ func TestComputeDeclHash(t *testing.T) {
 
 	assert.NotEqual(t, jsons.BPM(decl1), jsons.BPM(decl1Copy))
 }
+
+func TestValidateNamedDeclaration(t *testing.T) {
+	schema := []byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "template": "t1" },
+			"t1": { "xpath": "A", "object": { "b": { "template": "t2" } } },
+			"t2": { "custom_func": { "name": "test_func" } }
+		}
+	}`)
+	customFuncs := customfuncs.CustomFuncs{"test_func": func(_ *transformctx.Ctx) (string, error) { return "", nil }}
+	decl, err := ValidateNamedDeclaration(schema, "t1", customFuncs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", decl.fqdn)
+	assert.Equal(t, kindObject, decl.kind)
+	assert.Equal(t, "t1.b", decl.children[0].fqdn)
+	assert.Equal(t, kindCustomFunc, decl.children[0].kind)
+	assert.Equal(t, decl, decl.children[0].parent)
+
+	decl, err = ValidateNamedDeclaration(schema, "t3", customFuncs, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, decl)
+
+	decl, err = ValidateNamedDeclaration(schema, "t2", nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "unknown custom_func 'test_func' on 't2'", err.Error())
+	assert.Nil(t, decl)
+}
+
+func TestValidateRecordOrder(t *testing.T) {
+	schema := []byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "A" },
+			"t": { "custom_func": { "name": "test_func" } }
+		},
+		"record_order": { "key": { "xpath": "seq", "type": "int" }, "group_key": { "template": "t" }, "window": 5 }
+	}`)
+	customFuncs := customfuncs.CustomFuncs{"test_func": func(_ *transformctx.Ctx) (string, error) { return "", nil }}
+	order, err := ValidateRecordOrder(schema, customFuncs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, order.Window)
+	assert.Equal(t, "record_order.key", order.Key.fqdn)
+	assert.Equal(t, kindField, order.Key.kind)
+	assert.Equal(t, "record_order.group_key", order.GroupKey.fqdn)
+	assert.Equal(t, kindCustomFunc, order.GroupKey.kind)
+
+	order, err = ValidateRecordOrder(schema, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "unknown custom_func 'test_func' on 'record_order.group_key'", err.Error())
+	assert.Nil(t, order)
+
+	order, err = ValidateRecordOrder([]byte(`{"transform_declarations": {"FINAL_OUTPUT": {}}}`), nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, order)
+}
+
+func TestValidateFileHooks(t *testing.T) {
+	schema := []byte(`{
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "A" },
+			"t": { "custom_func": { "name": "test_func" } }
+		},
+		"file_header": { "xpath": "ISA", "object": { "sender": { "xpath": "ISA06" } } },
+		"file_trailer": { "template": "t" }
+	}`)
+	customFuncs := customfuncs.CustomFuncs{"test_func": func(_ *transformctx.Ctx) (string, error) { return "", nil }}
+	hooks, err := ValidateFileHooks(schema, customFuncs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "file_header", hooks.Header.fqdn)
+	assert.Equal(t, kindObject, hooks.Header.kind)
+	assert.Equal(t, "file_header.sender", hooks.Header.Object["sender"].fqdn)
+	assert.Equal(t, "file_trailer", hooks.Trailer.fqdn)
+	assert.Equal(t, kindCustomFunc, hooks.Trailer.kind)
+
+	hooks, err = ValidateFileHooks(schema, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "unknown custom_func 'test_func' on 'file_trailer'", err.Error())
+	assert.Nil(t, hooks)
+
+	hooks, err = ValidateFileHooks([]byte(`{
+		"transform_declarations": { "FINAL_OUTPUT": {} },
+		"file_trailer": { "const": "done" }
+	}`), nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, hooks.Header)
+	assert.Equal(t, kindConst, hooks.Trailer.kind)
+
+	hooks, err = ValidateFileHooks([]byte(`{"transform_declarations": {"FINAL_OUTPUT": {}}}`), nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, hooks)
+}
@@ -1,8 +1,10 @@
 package transform
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
@@ -20,15 +22,43 @@ func isEmpty(v interface{}) bool {
 
 type convFunc func(v interface{}) (interface{}, error)
 
-var convStrToInt convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseInt(v.(string), 10, 64) }
-var convStrToFloat convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseFloat(v.(string), 64) }
-var convStrToBool convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseBool(v.(string)) }
+var convStrToInt convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseInt(reflect.ValueOf(v).String(), 10, 64) }
+var convStrToFloat convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseFloat(reflect.ValueOf(v).String(), 64) }
+var convStrToBool convFunc = func(v interface{}) (interface{}, error) { return strconv.ParseBool(reflect.ValueOf(v).String()) }
 var convIntToFloat convFunc = func(v interface{}) (interface{}, error) { return float64(reflect.ValueOf(v).Int()), nil }
 var convUintToFloat convFunc = func(v interface{}) (interface{}, error) { return float64(reflect.ValueOf(v).Uint()), nil }
 var convFloatToInt convFunc = func(v interface{}) (interface{}, error) { return int64(reflect.ValueOf(v).Float()), nil }
 var convToStr convFunc = func(v interface{}) (interface{}, error) { return fmt.Sprintf("%v", v), nil }
+var convIntToNumber convFunc = func(v interface{}) (interface{}, error) {
+	return json.Number(strconv.FormatInt(reflect.ValueOf(v).Int(), 10)), nil
+}
+var convUintToNumber convFunc = func(v interface{}) (interface{}, error) {
+	return json.Number(strconv.FormatUint(reflect.ValueOf(v).Uint(), 10)), nil
+}
+var convFloatToNumber convFunc = func(v interface{}) (interface{}, error) {
+	f := reflect.ValueOf(v).Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errNotJSONNumber
+	}
+	return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+}
+
+// convStrToNumber converts a string to a json.Number as is, i.e. without any loss of precision, if
+// it's a valid JSON number literal.
+var convStrToNumber convFunc = func(v interface{}) (interface{}, error) {
+	s := reflect.ValueOf(v).String()
+	// json.Valid alone would also accept other JSON literals (such as `true` or `"1"`) and
+	// surrounding whitespaces, thus the extra first/last char checks.
+	if s == "" || !isDigit(s[len(s)-1]) || (s[0] != '-' && !isDigit(s[0])) || !json.Valid([]byte(s)) {
+		return nil, errNotJSONNumber
+	}
+	return json.Number(s), nil
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
 
 var errTypeConversionNotSupported = errors.New("type conversion not supported")
+var errNotJSONNumber = errors.New("not a valid number")
 
 func resultTypeConversion(v interface{}, resultType resultType) (interface{}, error) {
 	switch reflect.ValueOf(v).Kind() {
@@ -38,6 +68,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return v, nil
 		case resultTypeFloat:
 			return convIntToFloat(v)
+		case resultTypeNumber:
+			return convIntToNumber(v)
 		case resultTypeString:
 			return convToStr(v)
 		}
@@ -47,6 +79,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return v, nil
 		case resultTypeFloat:
 			return convUintToFloat(v)
+		case resultTypeNumber:
+			return convUintToNumber(v)
 		case resultTypeString:
 			return convToStr(v)
 		}
@@ -56,6 +90,8 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return convFloatToInt(v)
 		case resultTypeFloat:
 			return v, nil
+		case resultTypeNumber:
+			return convFloatToNumber(v)
 		case resultTypeString:
 			return convToStr(v)
 		}
@@ -74,7 +110,13 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 			return convStrToFloat(v)
 		case resultTypeBoolean:
 			return convStrToBool(v)
+		case resultTypeNumber:
+			return convStrToNumber(v)
 		case resultTypeString:
+			if n, ok := v.(json.Number); ok {
+				// json.Number is a string kind, but it'd be output as a JSON number.
+				return n.String(), nil
+			}
 			return v, nil
 		}
 	}
@@ -83,8 +125,9 @@ func resultTypeConversion(v interface{}, resultType resultType) (interface{}, er
 
 func normalizeAndSaveValue(decl *Decl, v interface{}, save func(interface{})) error {
 	vv := reflect.ValueOf(v)
-	if vv.Kind() == reflect.String && !decl.NoTrim {
-		v = strings.TrimSpace(v.(string))
+	// Only trims plain strings: other string kinds, such as json.Number, must keep their types.
+	if s, ok := v.(string); ok && !decl.NoTrim {
+		v = strings.TrimSpace(s)
 		vv = reflect.ValueOf(v)
 	}
 	checkToSave := func(v interface{}) {
@@ -102,6 +145,13 @@ func normalizeAndSaveValue(decl *Decl, v interface{}, save func(interface{})) er
 		save(v)
 		return
 	}
+	if v != nil && decl.NumberFormat != nil && !isEmpty(v) {
+		formatted, err := formatNumber(v, decl.NumberFormat)
+		if err != nil {
+			return fmt.Errorf("unable to format value '%v' as number on '%s', err: %s", v, decl.fqdn, err.Error())
+		}
+		v = formatted
+	}
 	if v == nil || decl.ResultType == nil {
 		checkToSave(v)
 		return nil
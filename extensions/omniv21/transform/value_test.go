@@ -1,7 +1,9 @@
 package transform
 
 import (
+	"encoding/json"
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -236,6 +238,76 @@ func TestResultTypeConversion(t *testing.T) {
 			err:      ``,
 			expected: "1234567890",
 		},
+		{
+			name:     "int64 -> number",
+			v:        int64(9007199254740993),
+			typ:      resultTypeNumber,
+			err:      "",
+			expected: json.Number("9007199254740993"),
+		},
+		{
+			name:     "uint64 -> number",
+			v:        uint64(18446744073709551615),
+			typ:      resultTypeNumber,
+			err:      "",
+			expected: json.Number("18446744073709551615"),
+		},
+		{
+			name:     "float64 -> number",
+			v:        float64(3.25),
+			typ:      resultTypeNumber,
+			err:      "",
+			expected: json.Number("3.25"),
+		},
+		{
+			name:     "float64 -> number, failure",
+			v:        math.Inf(1),
+			typ:      resultTypeNumber,
+			err:      errNotJSONNumber.Error(),
+			expected: nil,
+		},
+		{
+			name:     "string -> number, success",
+			v:        "-12345678901234567890.123456789012345e-3",
+			typ:      resultTypeNumber,
+			err:      "",
+			expected: json.Number("-12345678901234567890.123456789012345e-3"),
+		},
+		{
+			name:     "string -> number, failure",
+			v:        "0x1f",
+			typ:      resultTypeNumber,
+			err:      errNotJSONNumber.Error(),
+			expected: nil,
+		},
+		{
+			name:     "string -> number, non-number literal",
+			v:        "true",
+			typ:      resultTypeNumber,
+			err:      errNotJSONNumber.Error(),
+			expected: nil,
+		},
+		{
+			name:     "string -> number, surrounding spaces",
+			v:        " 1 ",
+			typ:      resultTypeNumber,
+			err:      errNotJSONNumber.Error(),
+			expected: nil,
+		},
+		{
+			name:     "number -> string",
+			v:        json.Number("12345678901234567890"),
+			typ:      resultTypeString,
+			err:      "",
+			expected: "12345678901234567890",
+		},
+		{
+			name:     "number -> float",
+			v:        json.Number("1.5"),
+			typ:      resultTypeFloat,
+			err:      "",
+			expected: 1.5,
+		},
 		{
 			name:     "map -> string, failure",
 			v:        map[string]string{},
@@ -323,6 +395,33 @@ func TestNormalizeAndSaveValue(t *testing.T) {
 			expectedSaveCalled: true,
 			expectedErr:        "",
 		},
+		{
+			name:               "value is json.Number and result type is number",
+			decl:               &Decl{ResultType: testResultType(resultTypeNumber)},
+			value:              json.Number("12345678901234567890.50"),
+			expectedValue:      json.Number("12345678901234567890.50"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name: "value is number formatted and result type is number",
+			decl: &Decl{
+				ResultType:   testResultType(resultTypeNumber),
+				NumberFormat: &NumberFormat{DecimalPlaces: testIntPtr(2), ThousandsSeparator: ","},
+			},
+			value:              " 1,234.5 ",
+			expectedValue:      json.Number("1234.50"),
+			expectedSaveCalled: true,
+			expectedErr:        "",
+		},
+		{
+			name:               "value can't be number formatted",
+			decl:               &Decl{NumberFormat: &NumberFormat{}, fqdn: "test_fqdn"},
+			value:              "1.2.3",
+			expectedValue:      nil,
+			expectedSaveCalled: false,
+			expectedErr:        `unable to format value '1.2.3' as number on 'test_fqdn', err: not a valid decimal number`,
+		},
 		{
 			name: "value is string but can't convert to result type",
 			decl: &Decl{
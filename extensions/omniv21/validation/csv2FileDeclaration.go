@@ -16,6 +16,14 @@ const (
             "properties": {
                 "delimiter": { "type": "string", "minLength": 1, "maxLength": 1 },
                 "replace_double_quotes": { "type": "boolean" },
+                "lazy_quotes": { "type": "boolean" },
+                "quote_escape": { "type": "string", "enum": [ "double", "backslash" ] },
+                "skip_unreferenced_columns": { "type": "boolean" },
+                "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
+                "line_ending": { "type": "string", "enum": [ "lf", "crlf", "cr", "mixed" ] },
+                "skip_lines": { "type": "integer", "minimum": 0 },
+                "comment": { "type": "string", "minLength": 1, "maxLength": 1 },
+                "skip_trailing_lines": { "type": "integer", "minimum": 0 },
                 "records": { "$ref": "#/definitions/child_records_type" }
             },
             "required": [ "delimiter" ],
@@ -58,7 +66,8 @@ const (
                 "min": { "type": "integer", "minimum": 0 },
                 "max": { "type": "integer", "minimum": -1 },
                 "columns": { "$ref": "#/definitions/columns_type" },
-                "child_records": { "$ref": "#/definitions/child_records_type" }
+                "child_records": { "$ref": "#/definitions/child_records_type" },
+                "totals": { "$ref": "#/definitions/totals_type" }
             },
             "required": [], "$comment": "yes, 'name' is actually optional",
             "additionalProperties": false
@@ -74,11 +83,40 @@ const (
                 "min": { "type": "integer", "minimum": 0 },
                 "max": { "type": "integer", "minimum": -1 },
                 "columns": { "$ref": "#/definitions/columns_type" },
-                "child_records": { "$ref": "#/definitions/child_records_type" }
+                "child_records": { "$ref": "#/definitions/child_records_type" },
+                "totals": { "$ref": "#/definitions/totals_type" }
             },
             "required": [ "header" ], "$comment": "yes, 'name' is actually optional",
             "additionalProperties": false
         },
+        "totals_type": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "column": { "type": "string", "minLength": 1 },
+                    "count": { "type": "string", "minLength": 1 },
+                    "sum": { "type": "string", "minLength": 1 },
+                    "sum_column": { "type": "string", "minLength": 1 },
+                    "digits": { "type": "integer", "minimum": 1, "maximum": 64 }
+                },
+                "oneOf": [
+                    {
+                        "required": [ "count" ],
+                        "not": { "anyOf": [
+                            { "required": [ "sum" ] }, { "required": [ "sum_column" ] }, { "required": [ "digits" ] }
+                        ]}
+                    },
+                    {
+                        "required": [ "sum", "sum_column" ],
+                        "not": { "required": [ "count" ] }
+                    }
+                ],
+                "required": [ "column" ],
+                "additionalProperties": false
+            },
+            "$comment": "each total is either a 'count' of records, or a 'sum' of a column of records"
+        },
         "columns_type": {
             "type": "array",
             "items": {
@@ -87,7 +125,19 @@ const (
                     "name": { "type": "string", "minLength": 1 },
                     "index": { "type": "integer", "minimum": 1 },
                     "line_index": { "type": "integer", "minimum": 1 },
-                    "line_pattern": { "type": "string", "minLength": 1 }
+                    "line_pattern": { "type": "string", "minLength": 1 },
+                    "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
+                    "validation": {
+                        "type": "object",
+                        "properties": {
+                            "required": { "type": "boolean" },
+                            "pattern": { "type": "string", "minLength": 1 },
+                            "enum": { "type": "array", "items": { "type": "string" }, "minItems": 1 },
+                            "min": { "type": "number" },
+                            "max": { "type": "number" }
+                        },
+                        "additionalProperties": false
+                    }
                 },
                 "required": [ "name" ],
                 "additionalProperties": false
@@ -20,6 +20,29 @@ const (
                 "repetition_delimiter": { "type": "string", "minLength": 1 },
                 "release_character": { "type": "string", "minLength": 1 },
                 "ignore_crlf": { "type": "boolean" },
+                "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
+                "empty_segments": { "type": "string", "enum": [ "error", "skip", "preserve" ] },
+                "inter_segment_whitespace": { "type": "string", "enum": [ "error", "skip", "preserve" ] },
+                "truncated_last_segment": { "type": "string", "enum": [ "error", "warn", "skip", "preserve" ] },
+                "raw_segment_delimiter": { "type": "string", "enum": [ "keep", "drop" ] },
+                "strict_isa": { "type": "boolean" },
+                "dictionary": {
+                    "type": "object",
+                    "properties": {
+                        "standard": { "type": "string", "enum": [ "x12", "edifact" ] },
+                        "segments": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "object",
+                                "additionalProperties": { "type": "string" }
+                            }
+                        },
+                        "_comment": { "$ref": "#/definitions/value_comment" }
+                    },
+                    "additionalProperties": false
+                },
+                "positional_elements": { "type": "boolean" },
+                "skip_unreferenced_elements": { "type": "boolean" },
                 "segment_declarations": {
                     "type": "array",
                     "items": {
@@ -41,6 +64,7 @@ const (
                 "is_target": { "type": "boolean" },
                 "min": { "type": "integer", "minimum": 0 },
                 "max": { "type": "integer", "minimum": -1 },
+                "exceeds_max": { "type": "string", "enum": [ "error", "truncate", "accept" ] },
                 "elements": {
                     "type": "array",
                     "items": {
@@ -52,6 +76,7 @@ const (
                             "empty_if_missing": { "type": "boolean","$comment": "deprecated, use 'default'" },
                             "default": { "type": "string" },
                             "default_element": { "type": "string" },
+                            "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
                             "_comment": { "$ref": "#/definitions/value_comment" }
                         },
                         "required": [ "name", "index" ],
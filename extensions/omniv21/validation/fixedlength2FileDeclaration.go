@@ -14,6 +14,12 @@ const (
         "file_declaration": {
             "type": "object",
             "properties": {
+                "skip_unreferenced_columns": { "type": "boolean" },
+                "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
+                "line_ending": { "type": "string", "enum": [ "lf", "crlf", "cr", "mixed" ] },
+                "record_length": { "type": "integer", "minimum": 1 },
+                "truncated_last_record": { "type": "string", "enum": [ "error", "skip", "preserve" ] },
+                "raw_line_terminator": { "type": "string", "enum": [ "keep", "drop" ] },
                 "envelopes": { "$ref": "#/definitions/child_envelopes_type" }
             },
             "required": [ "envelopes" ],
@@ -56,7 +62,8 @@ const (
                 "min": { "type": "integer", "minimum": 0 },
                 "max": { "type": "integer", "minimum": -1 },
                 "columns": { "$ref": "#/definitions/columns_type" },
-                "child_envelopes": { "$ref": "#/definitions/child_envelopes_type" }
+                "child_envelopes": { "$ref": "#/definitions/child_envelopes_type" },
+                "totals": { "$ref": "#/definitions/totals_type" }
             },
             "required": [], "$comment": "yes, 'name' is actually optional",
             "additionalProperties": false
@@ -72,11 +79,40 @@ const (
                 "min": { "type": "integer", "minimum": 0 },
                 "max": { "type": "integer", "minimum": -1 },
                 "columns": { "$ref": "#/definitions/columns_type" },
-                "child_envelopes": { "$ref": "#/definitions/child_envelopes_type" }
+                "child_envelopes": { "$ref": "#/definitions/child_envelopes_type" },
+                "totals": { "$ref": "#/definitions/totals_type" }
             },
             "required": [ "header" ], "$comment": "yes, 'name' is actually optional",
             "additionalProperties": false
         },
+        "totals_type": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "column": { "type": "string", "minLength": 1 },
+                    "count": { "type": "string", "minLength": 1 },
+                    "sum": { "type": "string", "minLength": 1 },
+                    "sum_column": { "type": "string", "minLength": 1 },
+                    "digits": { "type": "integer", "minimum": 1, "maximum": 64 }
+                },
+                "oneOf": [
+                    {
+                        "required": [ "count" ],
+                        "not": { "anyOf": [
+                            { "required": [ "sum" ] }, { "required": [ "sum_column" ] }, { "required": [ "digits" ] }
+                        ]}
+                    },
+                    {
+                        "required": [ "sum", "sum_column" ],
+                        "not": { "required": [ "count" ] }
+                    }
+                ],
+                "required": [ "column" ],
+                "additionalProperties": false
+            },
+            "$comment": "each total is either a 'count' of records, or a 'sum' of a column of records"
+        },
         "columns_type": {
             "type": "array",
             "items": {
@@ -86,7 +122,21 @@ const (
                     "start_pos": { "type": "integer", "minimum": 1 },
                     "length": { "type": "integer", "minimum": 1 },
                     "line_index": { "type": "integer", "minimum": 1 },
-                    "line_pattern": { "type": "string", "minLength": 1 }
+                    "line_pattern": { "type": "string", "minLength": 1 },
+                    "trim": { "type": "string", "enum": [ "none", "right", "both", "collapse" ] },
+                    "type": {
+                        "type": "string",
+                        "enum": [
+                            "int8", "uint8",
+                            "int16_be", "int16_le", "uint16_be", "uint16_le",
+                            "int32_be", "int32_le", "uint32_be", "uint32_le",
+                            "int64_be", "int64_le", "uint64_be", "uint64_le",
+                            "packed_decimal", "bcd", "bits", "hex"
+                        ]
+                    },
+                    "decimal_places": { "type": "integer", "minimum": 0 },
+                    "bit_offset": { "type": "integer", "minimum": 0 },
+                    "bit_length": { "type": "integer", "minimum": 1, "maximum": 64 }
                 },
                 "required": [ "name", "start_pos", "length" ],
                 "additionalProperties": false
@@ -6,3 +6,12 @@ package validation
 //go:generate sh -c "go run ../../../validation/gen/gen.go -json ediFileDeclaration.json -varname JSONSchemaEDIFileDeclaration > ./ediFileDeclaration.go"
 //go:generate sh -c "go run ../../../validation/gen/gen.go -json fixedlengthFileDeclaration.json -varname JSONSchemaFixedLengthFileDeclaration > ./fixedlengthFileDeclaration.go"
 //go:generate sh -c "go run ../../../validation/gen/gen.go -json fixedlength2FileDeclaration.json -varname JSONSchemaFixedLength2FileDeclaration > ./fixedlength2FileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json pdfFileDeclaration.json -varname JSONSchemaPDFFileDeclaration > ./pdfFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json asn1FileDeclaration.json -varname JSONSchemaASN1FileDeclaration > ./asn1FileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json fixFileDeclaration.json -varname JSONSchemaFIXFileDeclaration > ./fixFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json iso8583FileDeclaration.json -varname JSONSchemaISO8583FileDeclaration > ./iso8583FileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json cargoimpFileDeclaration.json -varname JSONSchemaCargoIMPFileDeclaration > ./cargoimpFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json jsonFileDeclaration.json -varname JSONSchemaJSONFileDeclaration > ./jsonFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json nachaFileDeclaration.json -varname JSONSchemaNACHAFileDeclaration > ./nachaFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json idocFileDeclaration.json -varname JSONSchemaIDocFileDeclaration > ./idocFileDeclaration.go"
+//go:generate sh -c "go run ../../../validation/gen/gen.go -json parquetFileDeclaration.json -varname JSONSchemaParquetFileDeclaration > ./parquetFileDeclaration.go"
@@ -18,6 +18,7 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/constant" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
                         { "$ref": "#/definitions/custom_func" },
@@ -32,6 +33,8 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/constant" },
+                        { "$ref": "#/definitions/var" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
                         { "$ref": "#/definitions/custom_func" },
@@ -43,6 +46,67 @@ const (
             },
             "required": [ "FINAL_OUTPUT" ],
             "additionalProperties": false
+        },
+        "constants": {
+            "type": "object",
+            "patternProperties": {
+                "^[_a-zA-Z0-9]+$": {
+                    "oneOf": [
+                        { "type": "string" },
+                        { "type": "object", "additionalProperties": { "type": "string" } }
+                    ],
+                    "$comment": "a constant is either a string value or a mapping table of string keys to string values"
+                }
+            },
+            "additionalProperties": false
+        },
+        "record_order": {
+            "type": "object",
+            "properties": {
+                "key": { "$ref": "#/definitions/value_record_order_key" },
+                "group_key": { "$ref": "#/definitions/value_record_order_key" },
+                "window": { "type": "integer", "minimum": 1 },
+                "spill": {
+                    "type": "object",
+                    "properties": {
+                        "memory_bytes": { "type": "integer", "minimum": 1 },
+                        "max_disk_bytes": { "type": "integer", "minimum": 1 },
+                        "_comment": { "$ref": "#/definitions/value_comment" }
+                    },
+                    "required": [ "memory_bytes" ],
+                    "additionalProperties": false
+                },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "key", "window" ],
+            "additionalProperties": false,
+            "$comment": "records are re-sequenced by key within a window of records, and within a group of consecutive records of the same group_key, if any"
+        },
+        "input_sort": {
+            "type": "object",
+            "properties": {
+                "header_lines": { "type": "integer", "minimum": 0 },
+                "delimiter": { "type": "string", "minLength": 1 },
+                "keys": {
+                    "type": "array",
+                    "items": { "$ref": "#/definitions/value_input_sort_key" },
+                    "minItems": 1
+                },
+                "memory_bytes": { "type": "integer", "minimum": 1 },
+                "max_disk_bytes": { "type": "integer", "minimum": 1 },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "keys" ],
+            "additionalProperties": false,
+            "$comment": "lines of flat file inputs are sorted by keys before being ingested"
+        },
+        "file_header": { "$ref": "#/definitions/value_file_hook" },
+        "file_trailer": { "$ref": "#/definitions/value_file_hook" },
+        "record_context": { "$ref": "#/definitions/value_vars" },
+        "sensitive_fields": {
+            "type": "array",
+            "items": { "type": "string", "minLength": 1 },
+            "$comment": "xpaths, relative to the records, of the input fields to anonymize in samples"
         }
     },
     "required": [ "transform_declarations" ],
@@ -51,6 +115,7 @@ const (
         "value_no_trim": { "type": "boolean" },
         "value_ignore_error": { "type": "boolean" },
         "value_keep_empty_or_null": { "type": "boolean" },
+        "value_memoize": { "type": "boolean" },
         "value_name": {
             "type": "string",
             "minLength": 1,
@@ -65,6 +130,59 @@ const (
             "minLength": 1,
             "$comment": "external can not be empty string"
         },
+        "value_constant": {
+            "type": "string",
+            "minLength": 1,
+            "$comment": "constant can not be empty string"
+        },
+        "value_constant_key": {
+            "oneOf": [
+                { "$ref": "#/definitions/const" },
+                { "$ref": "#/definitions/external" },
+                { "$ref": "#/definitions/var" },
+                { "$ref": "#/definitions/field" },
+                { "$ref": "#/definitions/custom_func" },
+                { "$ref": "#/definitions/template" }
+            ]
+        },
+        "value_file_hook": {
+            "oneOf": [
+                { "$ref": "#/definitions/const" },
+                { "$ref": "#/definitions/external" },
+                { "$ref": "#/definitions/constant" },
+                { "$ref": "#/definitions/field" },
+                { "$ref": "#/definitions/object" },
+                { "$ref": "#/definitions/custom_func" },
+                { "$ref": "#/definitions/template" }
+            ],
+            "$comment": "evaluated once per input, on the root of the input's IDR tree, and emitted as an extra record before the first record (file_header) or after the last record (file_trailer)"
+        },
+        "value_record_order_key": {
+            "oneOf": [
+                { "$ref": "#/definitions/const" },
+                { "$ref": "#/definitions/external" },
+                { "$ref": "#/definitions/constant" },
+                { "$ref": "#/definitions/field" },
+                { "$ref": "#/definitions/custom_func" },
+                { "$ref": "#/definitions/template" }
+            ]
+        },
+        "value_input_sort_key": {
+            "type": "object",
+            "properties": {
+                "column": { "type": "integer", "minimum": 1 },
+                "start_pos": { "type": "integer", "minimum": 1 },
+                "length": { "type": "integer", "minimum": 1 },
+                "type": { "type": "string", "enum": [ "string", "number" ] },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "oneOf": [
+                { "required": [ "column" ], "not": { "anyOf": [ { "required": [ "start_pos" ] }, { "required": [ "length" ] } ] } },
+                { "required": [ "start_pos", "length" ], "not": { "required": [ "column" ] } }
+            ],
+            "additionalProperties": false,
+            "$comment": "a key is either a 1-based delimited column, or a 1-based rune start position and length"
+        },
         "value_xpath": {
             "type": "string",
             "minLength": 1,
@@ -76,6 +194,8 @@ const (
                 "oneOf": [
                     { "$ref": "#/definitions/const" },
                     { "$ref": "#/definitions/external" },
+                    { "$ref": "#/definitions/constant" },
+                    { "$ref": "#/definitions/var" },
                     { "$ref": "#/definitions/field" },
                     { "$ref": "#/definitions/custom_func" },
                     { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
@@ -83,6 +203,31 @@ const (
                 ]
             }
         },
+        "value_var": {
+            "type": "string",
+            "minLength": 1,
+            "$comment": "var can not be empty string"
+        },
+        "value_vars": {
+            "type": "object",
+            "patternProperties": {
+                "^[_a-zA-Z][_a-zA-Z0-9]*$": {
+                    "oneOf": [
+                        { "$ref": "#/definitions/const" },
+                        { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/constant" },
+                        { "$ref": "#/definitions/var" },
+                        { "$ref": "#/definitions/field" },
+                        { "$ref": "#/definitions/object" },
+                        { "$ref": "#/definitions/custom_func" },
+                        { "$ref": "#/definitions/array" },
+                        { "$ref": "#/definitions/template" }
+                    ]
+                }
+            },
+            "additionalProperties": false,
+            "$comment": "vars are computed on an object's node, for the decls under the object to reference"
+        },
         "value_template": {
             "type": "string",
             "minLength": 1,
@@ -95,6 +240,8 @@ const (
                     "oneOf": [
                         { "$ref": "#/definitions/const" },
                         { "$ref": "#/definitions/external" },
+                        { "$ref": "#/definitions/constant" },
+                        { "$ref": "#/definitions/var" },
                         { "$ref": "#/definitions/field" },
                         { "$ref": "#/definitions/object" },
                         { "$ref": "#/definitions/custom_func" },
@@ -117,6 +264,8 @@ const (
                         "oneOf": [
                             { "$ref": "#/definitions/const" },
                             { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/constant" },
+                            { "$ref": "#/definitions/var" },
                             { "$ref": "#/definitions/field" },
                             { "$ref": "#/definitions/custom_func" },
                             { "$ref": "#/definitions/custom_parse", "$comment": "Deprecated. Use custom_func." },
@@ -126,7 +275,8 @@ const (
                     },
                     "$comment": "args length can be 0"
                 },
-                "ignore_error": { "$ref": "#/definitions/value_ignore_error" }
+                "ignore_error": { "$ref": "#/definitions/value_ignore_error" },
+                "memoize": { "$ref": "#/definitions/value_memoize" }
             },
             "required": [ "name" ],
             "additionalProperties": false
@@ -142,14 +292,38 @@ const (
                 "boolean",
                 "float",
                 "int",
+                "number",
                 "string"
             ]
         },
+        "value_number_format": {
+            "type": "object",
+            "properties": {
+                "decimal_places": { "type": "integer", "minimum": 0 },
+                "rounding": {
+                    "type": "string",
+                    "enum": [
+                        "ceiling",
+                        "down",
+                        "floor",
+                        "half_even",
+                        "half_up",
+                        "up"
+                    ]
+                },
+                "thousands_separator": { "type": "string", "minLength": 1 },
+                "strip_leading_zeros": { "type": "boolean" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "additionalProperties": false,
+            "$comment": "rounding only applies if decimal_places is set"
+        },
         "const": {
             "type": "object",
             "properties": {
                 "const": { "$ref": "#/definitions/value_const" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
@@ -162,6 +336,7 @@ const (
             "properties": {
                 "external": { "$ref": "#/definitions/value_external" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
@@ -169,12 +344,40 @@ const (
             "required": [ "external" ],
             "additionalProperties": false
         },
+        "constant": {
+            "type": "object",
+            "properties": {
+                "constant": { "$ref": "#/definitions/value_constant" },
+                "key": { "$ref": "#/definitions/value_constant_key" },
+                "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
+                "no_trim": { "$ref": "#/definitions/value_no_trim" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "constant" ],
+            "additionalProperties": false
+        },
+        "var": {
+            "type": "object",
+            "properties": {
+                "var": { "$ref": "#/definitions/value_var" },
+                "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
+                "no_trim": { "$ref": "#/definitions/value_no_trim" },
+                "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
+                "_comment": { "$ref": "#/definitions/value_comment" }
+            },
+            "required": [ "var" ],
+            "additionalProperties": false
+        },
         "field": {
             "type": "object",
             "properties": {
                 "xpath": { "$ref": "#/definitions/value_xpath" },
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
@@ -187,6 +390,7 @@ const (
                 "xpath": { "$ref": "#/definitions/value_xpath" },
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "object": { "$ref": "#/definitions/value_object" },
+                "vars": { "$ref": "#/definitions/value_vars" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
             },
@@ -202,6 +406,8 @@ const (
                         "oneOf": [
                             { "$ref": "#/definitions/const" },
                             { "$ref": "#/definitions/external" },
+                            { "$ref": "#/definitions/constant" },
+                            { "$ref": "#/definitions/var" },
                             { "$ref": "#/definitions/field" },
                             { "$ref": "#/definitions/object" },
                             { "$ref": "#/definitions/custom_func" },
@@ -235,6 +441,7 @@ const (
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "custom_func": { "$ref": "#/definitions/value_custom_func" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
@@ -249,6 +456,7 @@ const (
                 "xpath_dynamic": { "$ref": "#/definitions/value_xpath_dynamic" },
                 "custom_parse": { "$ref": "#/definitions/value_custom_parse" },
                 "type": { "$ref": "#/definitions/value_type" },
+                "number_format": { "$ref": "#/definitions/value_number_format" },
                 "no_trim": { "$ref": "#/definitions/value_no_trim" },
                 "keep_empty_or_null": { "$ref": "#/definitions/value_keep_empty_or_null" },
                 "_comment": { "$ref": "#/definitions/value_comment" }
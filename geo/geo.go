@@ -0,0 +1,183 @@
+// Package geo contains UN/LOCODE locations and IATA/ICAO airports, looked up by transport status
+// mappings (e.g. EDIFACT IFTSTA or X12 214) to turn location codes into names and countries.
+package geo
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+//go:embed data/*.json
+var builtinData embed.FS
+
+// Location is a UN/LOCODE location.
+type Location struct {
+	// Code is the 5-character UN/LOCODE, e.g. "NLRTM": the ISO 3166-1 alpha-2 country code followed by
+	// the 3-character location code.
+	Code string `json:"code"`
+	// Name is the name of the location, without diacritics, e.g. "Rotterdam".
+	Name string `json:"name"`
+	// Subdivision is the ISO 3166-2 subdivision code of the location, without the country code prefix,
+	// e.g. "ZH". Optional.
+	Subdivision string `json:"subdivision,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 country code of the location. If empty, it's the first 2
+	// characters of Code.
+	Country string `json:"country,omitempty"`
+}
+
+// Airport is an airport known by its IATA and/or ICAO codes.
+type Airport struct {
+	// IATA is the 3-letter IATA airport code, e.g. "LHR". Optional if ICAO isn't empty.
+	IATA string `json:"iata,omitempty"`
+	// ICAO is the 4-letter ICAO airport code, e.g. "EGLL". Optional if IATA isn't empty.
+	ICAO string `json:"icao,omitempty"`
+	// Name is the name of the airport, e.g. "Heathrow Airport".
+	Name string `json:"name"`
+	// City is the name of the city the airport serves, e.g. "London". Optional.
+	City string `json:"city,omitempty"`
+	// Country is the ISO 3166-1 alpha-2 country code of the airport.
+	Country string `json:"country"`
+}
+
+// Registry contains the UN/LOCODE locations and airports to look codes up against. Locations and
+// airports added later override the ones of the same codes added earlier, thus a Registry created by
+// NewRegistry, pre-loaded with the built-in datasets, can be amended or corrected, e.g. with
+// partner-specific codes or with a complete, licensed UN/LOCODE dataset.
+type Registry struct {
+	locations map[string]*Location
+	iata      map[string]*Airport
+	icao      map[string]*Airport
+}
+
+// NewEmptyRegistry creates an empty Registry, without the built-in datasets.
+func NewEmptyRegistry() *Registry {
+	return &Registry{
+		locations: map[string]*Location{},
+		iata:      map[string]*Airport{},
+		icao:      map[string]*Airport{},
+	}
+}
+
+// NewRegistry creates a Registry pre-loaded with the built-in datasets: the UN/LOCODEs of major
+// seaports and logistics hubs, and major passenger and cargo airports.
+func NewRegistry() *Registry {
+	r := NewEmptyRegistry()
+	for file, load := range map[string]func(io.Reader) error{
+		"data/locations.json": r.LoadLocations,
+		"data/airports.json":  r.LoadAirports,
+	} {
+		b, _ := builtinData.ReadFile(file)
+		if err := load(bytes.NewReader(b)); err != nil {
+			panic(fmt.Sprintf("invalid built-in dataset '%s': %s", file, err.Error()))
+		}
+	}
+	return r
+}
+
+var (
+	builtin     *Registry
+	builtinOnce sync.Once
+)
+
+// Builtin returns the shared Registry of the built-in datasets. It must not be modified; use
+// NewRegistry to create one that can be.
+func Builtin() *Registry {
+	builtinOnce.Do(func() { builtin = NewRegistry() })
+	return builtin
+}
+
+// normalizeCode upper-cases a code and removes the spaces in it, as UN/LOCODEs are often written with
+// a space between the country and the location codes, e.g. "NL RTM".
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
+
+// AddLocation validates and adds a Location into the registry.
+func (r *Registry) AddLocation(l Location) error {
+	l.Code = normalizeCode(l.Code)
+	if len(l.Code) != 5 {
+		return fmt.Errorf("location '%s': 'code' must be 5 characters", l.Code)
+	}
+	if l.Name == "" {
+		return fmt.Errorf("location '%s': 'name' must not be empty", l.Code)
+	}
+	if l.Country == "" {
+		l.Country = l.Code[:2]
+	}
+	r.locations[l.Code] = &l
+	return nil
+}
+
+// AddAirport validates and adds an Airport into the registry.
+func (r *Registry) AddAirport(a Airport) error {
+	a.IATA, a.ICAO = normalizeCode(a.IATA), normalizeCode(a.ICAO)
+	switch {
+	case a.IATA == "" && a.ICAO == "":
+		return errors.New("airport: 'iata' and 'icao' must not be both empty")
+	case a.IATA != "" && len(a.IATA) != 3:
+		return fmt.Errorf("airport '%s': 'iata' must be 3 characters", a.IATA)
+	case a.ICAO != "" && len(a.ICAO) != 4:
+		return fmt.Errorf("airport '%s': 'icao' must be 4 characters", a.ICAO)
+	case a.Name == "":
+		return fmt.Errorf("airport '%s%s': 'name' must not be empty", a.IATA, a.ICAO)
+	}
+	if a.IATA != "" {
+		r.iata[a.IATA] = &a
+	}
+	if a.ICAO != "" {
+		r.icao[a.ICAO] = &a
+	}
+	return nil
+}
+
+// LoadLocations reads in and validates a JSON array of Location, and adds them into the registry.
+func (r *Registry) LoadLocations(reader io.Reader) error {
+	var locations []Location
+	if err := json.NewDecoder(reader).Decode(&locations); err != nil {
+		return err
+	}
+	for i, l := range locations {
+		if err := r.AddLocation(l); err != nil {
+			return fmt.Errorf("location[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// LoadAirports reads in and validates a JSON array of Airport, and adds them into the registry.
+func (r *Registry) LoadAirports(reader io.Reader) error {
+	var airports []Airport
+	if err := json.NewDecoder(reader).Decode(&airports); err != nil {
+		return err
+	}
+	for i, a := range airports {
+		if err := r.AddAirport(a); err != nil {
+			return fmt.Errorf("airport[%d]: %s", i, err.Error())
+		}
+	}
+	return nil
+}
+
+// Location returns the Location of a UN/LOCODE. The code is case insensitive and can contain spaces.
+func (r *Registry) Location(code string) (*Location, bool) {
+	l, found := r.locations[normalizeCode(code)]
+	return l, found
+}
+
+// AirportByIATA returns the Airport of an IATA airport code. The code is case insensitive.
+func (r *Registry) AirportByIATA(code string) (*Airport, bool) {
+	a, found := r.iata[normalizeCode(code)]
+	return a, found
+}
+
+// AirportByICAO returns the Airport of an ICAO airport code. The code is case insensitive.
+func (r *Registry) AirportByICAO(code string) (*Airport, bool) {
+	a, found := r.icao[normalizeCode(code)]
+	return a, found
+}
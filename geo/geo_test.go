@@ -0,0 +1,91 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltin(t *testing.T) {
+	r := Builtin()
+	assert.Same(t, r, Builtin())
+	l, found := r.Location("nl rtm")
+	assert.True(t, found)
+	assert.Equal(t, Location{Code: "NLRTM", Name: "Rotterdam", Subdivision: "ZH", Country: "NL"}, *l)
+	a, found := r.AirportByIATA("lhr")
+	assert.True(t, found)
+	assert.Equal(t, "EGLL", a.ICAO)
+	a, found = r.AirportByICAO("EGLL")
+	assert.True(t, found)
+	assert.Equal(t, "Heathrow Airport", a.Name)
+	_, found = r.Location("XXXXX")
+	assert.False(t, found)
+}
+
+func TestNewRegistry_Overrides(t *testing.T) {
+	r := NewRegistry()
+	assert.NoError(t, r.LoadLocations(strings.NewReader(`[
+		{ "code": "NLRTM", "name": "Rotterdam Maasvlakte" },
+		{ "code": "XXABC", "name": "Partner Depot", "country": "NL" }
+	]`)))
+	assert.NoError(t, r.LoadAirports(strings.NewReader(`[{ "icao": "ZZZZ", "name": "Private Strip", "country": "US" }]`)))
+	l, _ := r.Location("NLRTM")
+	assert.Equal(t, "Rotterdam Maasvlakte", l.Name)
+	l, _ = r.Location("XXABC")
+	assert.Equal(t, "NL", l.Country)
+	a, found := r.AirportByICAO("ZZZZ")
+	assert.True(t, found)
+	assert.Equal(t, "", a.IATA)
+	// the shared built-in registry is unaffected.
+	l, _ = Builtin().Location("NLRTM")
+	assert.Equal(t, "Rotterdam", l.Name)
+	_, found = NewEmptyRegistry().Location("NLRTM")
+	assert.False(t, found)
+}
+
+func TestRegistry_Load_Failure(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		location bool
+		input    string
+		err      string
+	}{
+		{name: "invalid json", location: true, input: `{`, err: "unexpected EOF"},
+		{
+			name: "invalid code", location: true, input: `[{ "code": "NLRT", "name": "x" }]`,
+			err: "location[0]: location 'NLRT': 'code' must be 5 characters",
+		},
+		{
+			name: "empty name", location: true, input: `[{ "code": "NLRTM" }]`,
+			err: "location[0]: location 'NLRTM': 'name' must not be empty",
+		},
+		{
+			name: "no codes", input: `[{ "name": "x" }]`,
+			err: "airport[0]: airport: 'iata' and 'icao' must not be both empty",
+		},
+		{
+			name: "invalid iata", input: `[{ "iata": "LHRX", "name": "x" }]`,
+			err: "airport[0]: airport 'LHRX': 'iata' must be 3 characters",
+		},
+		{
+			name: "invalid icao", input: `[{ "icao": "EGL", "name": "x" }]`,
+			err: "airport[0]: airport 'EGL': 'icao' must be 4 characters",
+		},
+		{
+			name: "empty airport name", input: `[{ "iata": "LHR" }]`,
+			err: "airport[0]: airport 'LHR': 'name' must not be empty",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewEmptyRegistry()
+			load := r.LoadAirports
+			if test.location {
+				load = r.LoadLocations
+			}
+			err := load(strings.NewReader(test.input))
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+		})
+	}
+}
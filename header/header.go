@@ -1,10 +1,17 @@
 package header
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"unicode/utf8"
 
 	"github.com/jf-tech/go-corelib/strs"
 	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/logward/omniparser/transformctx"
 )
 
 // ParserSettings defines the common header (and its JSON format) for all schemas across all schema handlers.
@@ -15,12 +22,31 @@ type ParserSettings struct {
 	Version        string  `json:"version,omitempty"`
 	FileFormatType string  `json:"file_format_type,omitempty"`
 	Encoding       *string `json:"encoding,omitempty"`
+	// IndexRecords hints the schema handler to build a per-record index (by element/segment name)
+	// so that descendant xpath queries on large records don't need to scan the entire record.
+	IndexRecords bool `json:"index_records,omitempty"`
+	// InvalidUTF8 is the policy for invalid UTF-8 byte sequences in an input in UTF-8, one of
+	// InvalidUTF8Replace, InvalidUTF8Error and InvalidUTF8Bytes. If not set, invalid bytes are passed
+	// on to the readers as is.
+	InvalidUTF8 *string `json:"invalid_utf8,omitempty"`
+	// ErrorDumpBytes, if positive, makes the input errors (errs.ErrInput) carry a Dump of about that many
+	// input bytes around the error, for diagnosing malformed inputs.
+	ErrorDumpBytes int `json:"error_dump_bytes,omitempty"`
+	// ValidateFirst makes the Transforms read the whole input before returning any record, and return
+	// none at all if any record fails, for inputs where partial output is unacceptable. The transformed
+	// records are kept in memory in the meantime, so it's meant for small to medium inputs.
+	ValidateFirst bool `json:"validate_first,omitempty"`
 }
 
 const (
 	encodingUTF8        = "utf-8"
 	encodingISO8859_1   = "iso-8859-1"
 	encodingWindows1252 = "windows-1252"
+	encodingUTF16LE     = "utf-16le"
+	encodingUTF16BE     = "utf-16be"
+	// encodingAuto detects UTF-16 LE/BE inputs without BOM by looking at the first 2 bytes, and
+	// falls back to UTF-8.
+	encodingAuto = "auto"
 )
 
 type encodingMappingFunc func(reader io.Reader) io.Reader
@@ -29,19 +55,131 @@ var supportedEncodingMappings = map[string]encodingMappingFunc{
 	encodingUTF8:        func(r io.Reader) io.Reader { return r },
 	encodingISO8859_1:   func(r io.Reader) io.Reader { return charmap.ISO8859_1.NewDecoder().Reader(r) },
 	encodingWindows1252: func(r io.Reader) io.Reader { return charmap.Windows1252.NewDecoder().Reader(r) },
+	encodingUTF16LE: func(r io.Reader) io.Reader {
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder().Reader(r)
+	},
+	encodingUTF16BE: func(r io.Reader) io.Reader {
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder().Reader(r)
+	},
+}
+
+var boms = []struct {
+	bom      []byte
+	encoding string
+}{
+	{bom: []byte{0xEF, 0xBB, 0xBF}, encoding: encodingUTF8},
+	{bom: []byte{0xFF, 0xFE}, encoding: encodingUTF16LE},
+	{bom: []byte{0xFE, 0xFF}, encoding: encodingUTF16BE},
+}
+
+// sniffBOM returns the encoding and the length of the BOM (byte order marker) at the beginning of
+// the input, if any.
+func sniffBOM(b []byte) (string, int) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(b, bom.bom) {
+			return bom.encoding, len(bom.bom)
+		}
+	}
+	return "", 0
+}
+
+// sniffUTF16 guesses the encoding of a BOM-less input by its first 2 bytes: the first character of
+// pretty much all the text inputs we deal with (digits, letters, '<', '{', etc) is in ASCII range, whose
+// UTF-16 encoding has one zero byte, either the 2nd one (LE) or the 1st one (BE).
+func sniffUTF16(b []byte) string {
+	switch {
+	case len(b) < 2:
+		return encodingUTF8
+	case b[0] != 0 && b[1] == 0:
+		return encodingUTF16LE
+	case b[0] == 0 && b[1] != 0:
+		return encodingUTF16BE
+	default:
+		return encodingUTF8
+	}
 }
 
 // WrapEncoding returns an io.Reader that ensures the encoding scheme matches what's specified
-// in 'parser_settings.encoding' setting.
+// in 'parser_settings.encoding' setting. If the input starts with a UTF-8 or UTF-16 BOM, the BOM
+// is stripped and the encoding it indicates takes precedence over 'parser_settings.encoding'. An
+// input in UTF-8 is subject to the 'parser_settings.invalid_utf8' policy.
 func (p ParserSettings) WrapEncoding(input io.Reader) io.Reader {
-	f, found := supportedEncodingMappings[strs.StrPtrOrElse(p.Encoding, encodingUTF8)]
-	if !found {
-		f = supportedEncodingMappings[encodingUTF8]
+	br := bufio.NewReader(input)
+	peek, _ := br.Peek(3)
+	encoding, bomLen := p.sniffEncoding(peek)
+	_, _ = br.Discard(bomLen)
+	f, found := supportedEncodingMappings[encoding]
+	if !found || encoding == encodingUTF8 {
+		return wrapInvalidUTF8(br, strs.StrPtrOrElse(p.InvalidUTF8, ""), bomLen)
+	}
+	return f(br)
+}
+
+// UTF8Content returns the content of an in-memory input, less the BOM if any, if it needs no
+// decoding, i.e. it's in UTF-8 as determined the same way as WrapEncoding does, and it's either valid
+// UTF-8 or the 'parser_settings.invalid_utf8' policy leaves invalid bytes as is. Otherwise, it returns
+// false, and the input needs to go through WrapEncoding.
+func (p ParserSettings) UTF8Content(b []byte) ([]byte, bool) {
+	encoding, bomLen := p.sniffEncoding(b)
+	if _, found := supportedEncodingMappings[encoding]; found && encoding != encodingUTF8 {
+		return nil, false
 	}
-	return f(input)
+	switch strs.StrPtrOrElse(p.InvalidUTF8, "") {
+	case InvalidUTF8Replace, InvalidUTF8Error:
+		if !utf8.Valid(b[bomLen:]) {
+			// needs replacing or failing per the policy, by WrapEncoding.
+			return nil, false
+		}
+	}
+	return b[bomLen:], true
+}
+
+// RawBytes returns true if the input bytes reach the readers as is (less the BOM if any), i.e. the
+// input is declared in UTF-8 and the 'parser_settings.invalid_utf8' policy leaves invalid bytes as is.
+// Binary inputs, which are rarely valid UTF-8, require it.
+func (p ParserSettings) RawBytes() bool {
+	if strs.StrPtrOrElse(p.Encoding, encodingUTF8) != encodingUTF8 {
+		return false
+	}
+	switch strs.StrPtrOrElse(p.InvalidUTF8, "") {
+	case InvalidUTF8Replace, InvalidUTF8Error:
+		return false
+	}
+	return true
+}
+
+// sniffEncoding returns the encoding of the input, given its first few bytes, and the length of its
+// BOM if any.
+func (p ParserSettings) sniffEncoding(b []byte) (string, int) {
+	if bomEncoding, bomLen := sniffBOM(b); bomLen > 0 {
+		return bomEncoding, bomLen
+	}
+	encoding := strs.StrPtrOrElse(p.Encoding, encodingUTF8)
+	if encoding == encodingAuto {
+		encoding = sniffUTF16(b)
+	}
+	return encoding, 0
 }
 
-// Header contains the common ParserSettings for all schemas.
+// Header contains the common ParserSettings for all schemas, and the external properties the schema
+// depends on, if declared.
 type Header struct {
-	ParserSettings ParserSettings `json:"parser_settings,omitempty"`
+	ParserSettings     ParserSettings                      `json:"parser_settings,omitempty"`
+	ExternalProperties []transformctx.ExternalPropertyDecl `json:"external_properties,omitempty"`
+}
+
+// Validate validates the parts of the header that can't be validated by its JSON schema, i.e. that the
+// external properties are declared once, with defaults of their declared types.
+func (h Header) Validate() error {
+	seen := map[string]bool{}
+	for _, decl := range h.ExternalProperties {
+		if seen[decl.Name] {
+			return fmt.Errorf("external property '%s' is declared more than once", decl.Name)
+		}
+		seen[decl.Name] = true
+		if err := decl.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
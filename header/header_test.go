@@ -13,6 +13,7 @@ import (
 	"github.com/jf-tech/go-corelib/strs"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
 )
 
 func TestSupportedEncodingMappingsDump(t *testing.T) {
@@ -24,10 +25,24 @@ func TestSupportedEncodingMappingsDump(t *testing.T) {
 	cupaloy.SnapshotT(t, jsons.BPM(supported))
 }
 
+func utf16Bytes(t *testing.T, e unicode.Endianness, s string) []byte {
+	b, err := unicode.UTF16(e, unicode.IgnoreBOM).NewEncoder().Bytes([]byte(s))
+	assert.NoError(t, err)
+	return b
+}
+
 func TestSupportedEncodingMappings(t *testing.T) {
+	encoders := map[string]func(string) []byte{
+		encodingUTF16LE: func(s string) []byte { return utf16Bytes(t, unicode.LittleEndian, s) },
+		encodingUTF16BE: func(s string) []byte { return utf16Bytes(t, unicode.BigEndian, s) },
+	}
 	for encoding, mappingFn := range supportedEncodingMappings {
 		t.Run(encoding, func(t *testing.T) {
-			actual, err := ioutil.ReadAll(mappingFn(strings.NewReader("test")))
+			input := []byte("test")
+			if encoder, found := encoders[encoding]; found {
+				input = encoder("test")
+			}
+			actual, err := ioutil.ReadAll(mappingFn(bytes.NewReader(input)))
 			assert.NoError(t, err)
 			assert.Equal(t, []byte("test"), actual)
 		})
@@ -59,3 +74,215 @@ func TestWrapEncoding(t *testing.T) {
 	assert.Equal(t, "test", readAll(
 		ParserSettings{Encoding: strs.StrPtr(encodingWindows1252)}.WrapEncoding(bytes.NewReader(windows1252bytes))))
 }
+
+func TestWrapEncoding_BOM(t *testing.T) {
+	readAll := func(r io.Reader) string {
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	for _, test := range []struct {
+		name     string
+		encoding *string
+		input    []byte
+	}{
+		{
+			name:     "UTF-8 BOM, no encoding",
+			encoding: nil,
+			input:    append([]byte{0xEF, 0xBB, 0xBF}, "a,b"...),
+		},
+		{
+			name:     "UTF-8 BOM overrides windows-1252",
+			encoding: strs.StrPtr(encodingWindows1252),
+			input:    append([]byte{0xEF, 0xBB, 0xBF}, "a,b"...),
+		},
+		{
+			name:     "UTF-16LE BOM overrides utf-8",
+			encoding: strs.StrPtr(encodingUTF8),
+			input:    append([]byte{0xFF, 0xFE}, utf16Bytes(t, unicode.LittleEndian, "a,b")...),
+		},
+		{
+			name:     "UTF-16BE BOM, no encoding",
+			encoding: nil,
+			input:    append([]byte{0xFE, 0xFF}, utf16Bytes(t, unicode.BigEndian, "a,b")...),
+		},
+		{
+			name:     "no BOM, auto detects UTF-16LE",
+			encoding: strs.StrPtr(encodingAuto),
+			input:    utf16Bytes(t, unicode.LittleEndian, "a,b"),
+		},
+		{
+			name:     "no BOM, auto detects UTF-16BE",
+			encoding: strs.StrPtr(encodingAuto),
+			input:    utf16Bytes(t, unicode.BigEndian, "a,b"),
+		},
+		{
+			name:     "no BOM, auto falls back to UTF-8",
+			encoding: strs.StrPtr(encodingAuto),
+			input:    []byte("a,b"),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, "a,b", readAll(ParserSettings{Encoding: test.encoding}.WrapEncoding(bytes.NewReader(test.input))))
+		})
+	}
+	assert.Equal(t, "", readAll(ParserSettings{Encoding: strs.StrPtr(encodingAuto)}.WrapEncoding(strings.NewReader(""))))
+	assert.Equal(t, "a", readAll(ParserSettings{Encoding: strs.StrPtr(encodingAuto)}.WrapEncoding(strings.NewReader("a"))))
+}
+
+func TestUTF8Content(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		encoding *string
+		input    []byte
+		expected []byte
+		ok       bool
+	}{
+		{name: "no encoding", input: []byte("a,b"), expected: []byte("a,b"), ok: true},
+		{name: "empty", input: []byte{}, expected: []byte{}, ok: true},
+		{name: "unknown encoding", encoding: strs.StrPtr("unknown"), input: []byte("a"), expected: []byte("a"), ok: true},
+		{name: "windows-1252", encoding: strs.StrPtr(encodingWindows1252), input: []byte("a")},
+		{
+			name:     "UTF-8 BOM overrides windows-1252",
+			encoding: strs.StrPtr(encodingWindows1252),
+			input:    append([]byte{0xEF, 0xBB, 0xBF}, "a,b"...),
+			expected: []byte("a,b"),
+			ok:       true,
+		},
+		{name: "UTF-16LE BOM", input: []byte{0xFF, 0xFE, 'a', 0}},
+		{name: "auto, UTF-16BE", encoding: strs.StrPtr(encodingAuto), input: []byte{0, 'a'}},
+		{name: "auto, UTF-8", encoding: strs.StrPtr(encodingAuto), input: []byte("ab"), expected: []byte("ab"), ok: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, ok := ParserSettings{Encoding: test.encoding}.UTF8Content(test.input)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, b)
+		})
+	}
+}
+
+type oneByteReader struct {
+	b []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	p[0] = r.b[0]
+	r.b = r.b[1:]
+	return 1, nil
+}
+
+func TestWrapEncoding_InvalidUTF8(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		encoding *string
+		policy   *string
+		input    []byte
+		expected string
+		err      string
+	}{
+		{name: "not set", input: []byte("a\xffb"), expected: "a\xffb"},
+		{name: "bytes", policy: strs.StrPtr(InvalidUTF8Bytes), input: []byte("a\xffb"), expected: "a\xffb"},
+		{name: "replace", policy: strs.StrPtr(InvalidUTF8Replace), input: []byte("a\xff\xfeb"), expected: "a��b"},
+		{
+			name:     "replace, unknown encoding",
+			encoding: strs.StrPtr("unknown"),
+			policy:   strs.StrPtr(InvalidUTF8Replace),
+			input:    []byte("a\xff"),
+			expected: "a�",
+		},
+		{name: "error, valid", policy: strs.StrPtr(InvalidUTF8Error), input: []byte("aé€😀"), expected: "aé€😀"},
+		{
+			name:   "error",
+			policy: strs.StrPtr(InvalidUTF8Error),
+			input:  []byte("aé\xffb"),
+			err:    "invalid UTF-8 byte 0xFF at byte offset 3",
+		},
+		{
+			name:   "error, offset includes BOM",
+			policy: strs.StrPtr(InvalidUTF8Error),
+			input:  append([]byte{0xEF, 0xBB, 0xBF}, "ab\xc3"...),
+			err:    "invalid UTF-8 byte 0xC3 at byte offset 5",
+		},
+		{
+			name:     "error, ignored for non UTF-8 encoding",
+			encoding: strs.StrPtr(encodingISO8859_1),
+			policy:   strs.StrPtr(InvalidUTF8Error),
+			input:    []byte("a\xe9"),
+			expected: "aé",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := ioutil.ReadAll(
+				ParserSettings{Encoding: test.encoding, InvalidUTF8: test.policy}.WrapEncoding(bytes.NewReader(test.input)))
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.True(t, IsErrInvalidUTF8(err))
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
+
+func TestWrapEncoding_InvalidUTF8Error_RuneSplitAcrossReads(t *testing.T) {
+	b, err := ioutil.ReadAll(ParserSettings{InvalidUTF8: strs.StrPtr(InvalidUTF8Error)}.WrapEncoding(
+		&oneByteReader{b: []byte("a😀é")}))
+	assert.NoError(t, err)
+	assert.Equal(t, "a😀é", string(b))
+
+	_, err = ioutil.ReadAll(ParserSettings{InvalidUTF8: strs.StrPtr(InvalidUTF8Error)}.WrapEncoding(
+		&oneByteReader{b: []byte("a😀\xf0\x9f")}))
+	assert.Error(t, err)
+	assert.Equal(t, ErrInvalidUTF8{Offset: 5, Byte: 0xF0}, err)
+	assert.False(t, IsErrInvalidUTF8(io.EOF))
+}
+
+func TestUTF8Content_InvalidUTF8(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		policy   *string
+		input    []byte
+		expected []byte
+		ok       bool
+	}{
+		{name: "not set", input: []byte("a\xff"), expected: []byte("a\xff"), ok: true},
+		{name: "bytes", policy: strs.StrPtr(InvalidUTF8Bytes), input: []byte("a\xff"), expected: []byte("a\xff"), ok: true},
+		{name: "replace", policy: strs.StrPtr(InvalidUTF8Replace), input: []byte("a\xff")},
+		{name: "error", policy: strs.StrPtr(InvalidUTF8Error), input: []byte("a\xff")},
+		{name: "error, valid", policy: strs.StrPtr(InvalidUTF8Error), input: []byte("aé"), expected: []byte("aé"), ok: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, ok := ParserSettings{InvalidUTF8: test.policy}.UTF8Content(test.input)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, b)
+		})
+	}
+}
+
+func TestRawBytes(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		ps       ParserSettings
+		expected bool
+	}{
+		{name: "defaults", ps: ParserSettings{}, expected: true},
+		{name: "utf-8, bytes", ps: ParserSettings{
+			Encoding: strs.StrPtr(encodingUTF8), InvalidUTF8: strs.StrPtr(InvalidUTF8Bytes)}, expected: true},
+		{name: "iso-8859-1", ps: ParserSettings{Encoding: strs.StrPtr(encodingISO8859_1)}, expected: false},
+		{name: "auto", ps: ParserSettings{Encoding: strs.StrPtr(encodingAuto)}, expected: false},
+		{name: "replace", ps: ParserSettings{InvalidUTF8: strs.StrPtr(InvalidUTF8Replace)}, expected: false},
+		{name: "error", ps: ParserSettings{InvalidUTF8: strs.StrPtr(InvalidUTF8Error)}, expected: false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.ps.RawBytes())
+		})
+	}
+}
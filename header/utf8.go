@@ -0,0 +1,83 @@
+package header
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Policies of 'parser_settings.invalid_utf8', for how invalid UTF-8 byte sequences in an input in
+// UTF-8 are handled. If not set, invalid bytes are passed on to the readers as is.
+const (
+	// InvalidUTF8Replace replaces each invalid byte with U+FFFD (the Unicode replacement character).
+	InvalidUTF8Replace = "replace"
+	// InvalidUTF8Error fails the input with ErrInvalidUTF8 at the first invalid byte.
+	InvalidUTF8Error = "error"
+	// InvalidUTF8Bytes passes invalid bytes on to the readers as is, and makes the readers that report
+	// character positions (e.g. EDI) report byte positions instead, which are accurate no matter
+	// what the input contains.
+	InvalidUTF8Bytes = "bytes"
+)
+
+// ErrInvalidUTF8 indicates an input contains an invalid UTF-8 byte sequence, under the
+// InvalidUTF8Error policy. This is a fatal, non-continuable error.
+type ErrInvalidUTF8 struct {
+	Offset int64 // 0-based byte offset of the invalid byte in the input, BOM included.
+	Byte   byte
+}
+
+func (e ErrInvalidUTF8) Error() string {
+	return fmt.Sprintf("invalid UTF-8 byte 0x%02X at byte offset %d", e.Byte, e.Offset)
+}
+
+// IsErrInvalidUTF8 checks if the `err` is of ErrInvalidUTF8 type.
+func IsErrInvalidUTF8(err error) bool {
+	_, ok := err.(ErrInvalidUTF8)
+	return ok
+}
+
+// utf8Validator is a transform.Transformer that passes valid UTF-8 through, and fails with
+// ErrInvalidUTF8 at the first invalid byte.
+type utf8Validator struct {
+	offset int64 // byte offset in the input of the next source byte.
+}
+
+func (v *utf8Validator) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { v.offset += int64(nSrc) }()
+	for nSrc < len(src) {
+		size := 1
+		if src[nSrc] >= utf8.RuneSelf {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			var r rune
+			if r, size = utf8.DecodeRune(src[nSrc:]); r == utf8.RuneError && size == 1 {
+				return nDst, nSrc, ErrInvalidUTF8{Offset: v.offset + int64(nSrc), Byte: src[nSrc]}
+			}
+		}
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+func (v *utf8Validator) Reset() {}
+
+// wrapInvalidUTF8 applies the 'parser_settings.invalid_utf8' policy to an input in UTF-8, bomLen
+// bytes into the input.
+func wrapInvalidUTF8(r io.Reader, policy string, bomLen int) io.Reader {
+	switch policy {
+	case InvalidUTF8Replace:
+		return unicode.UTF8.NewDecoder().Reader(r)
+	case InvalidUTF8Error:
+		return transform.NewReader(r, &utf8Validator{offset: int64(bomLen)})
+	default:
+		return r
+	}
+}
@@ -0,0 +1,146 @@
+package idr
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type indexSpan struct {
+	begin, end int // pre-order positions of a node and of the last node in its subtree.
+}
+
+// Index is an optional lookup structure built over an IDR tree (typically a single record) that
+// speeds up descendant queries by element name. Without an index, an xpath query such as ".//CLM"
+// walks the entire subtree of the context node on each evaluation, which for large records (e.g.
+// an EDI 837 with thousands of segments) turns every field lookup into an O(n) scan. With an index,
+// such queries are answered by a binary search over the pre-order positions of all the elements
+// with the given name.
+//
+// An Index is a snapshot: it must not be used after the IDR tree it was built upon is modified.
+type Index struct {
+	spans  map[*Node]indexSpan
+	byName map[string][]*Node // elements with the same name, in document order.
+}
+
+// NewIndex builds an Index over the IDR tree rooted at 'root'.
+func NewIndex(root *Node) *Index {
+	idx := &Index{
+		spans:  map[*Node]indexSpan{},
+		byName: map[string][]*Node{},
+	}
+	pos := 0
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		begin := pos
+		pos++
+		if n.Type == ElementNode {
+			idx.byName[n.Data] = append(idx.byName[n.Data], n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		idx.spans[n] = indexSpan{begin: begin, end: pos - 1}
+	}
+	walk(root)
+	return idx
+}
+
+// Descendants returns all the element descendants of 'n' whose name is 'name', in document order.
+// 'n' must be part of the IDR tree the Index was built upon; if not, nil is returned.
+func (idx *Index) Descendants(n *Node, name string) []*Node {
+	span, found := idx.spans[n]
+	if !found {
+		return nil
+	}
+	nodes := idx.byName[name]
+	lo := sort.Search(len(nodes), func(i int) bool { return idx.spans[nodes[i]].begin > span.begin })
+	hi := sort.Search(len(nodes), func(i int) bool { return idx.spans[nodes[i]].begin > span.end })
+	if lo >= hi {
+		return nil
+	}
+	return nodes[lo:hi]
+}
+
+var indexableXPathRegexp = regexp.MustCompile(`^\.//([A-Za-z_][A-Za-z0-9_.\-]*)$`)
+
+// indexablePredicate tells whether a predicate can be evaluated on each candidate node alone. Positional
+// predicates (e.g. "[1]", "[last()]") depend on a candidate's position among its siblings, thus not.
+func indexablePredicate(pred string) bool {
+	inner := strings.TrimSpace(pred[1 : len(pred)-1])
+	if inner == "" || strings.Contains(inner, "position(") || strings.Contains(inner, "last(") {
+		return false
+	}
+	_, err := strconv.ParseFloat(inner, 64)
+	return err != nil
+}
+
+// lookup answers an xpath query using the Index, if the query is one of the supported shapes:
+// ".//NAME" or ".//NAME[predicate]" where predicate isn't positional. The second return value
+// indicates whether the Index was able to answer the query.
+func (idx *Index) lookup(n *Node, exprStr string, flags []uint) ([]*Node, bool, error) {
+	if idx == nil {
+		return nil, false, nil
+	}
+	base := removeLastFilterInXPath(exprStr)
+	m := indexableXPathRegexp.FindStringSubmatch(base)
+	if m == nil {
+		return nil, false, nil
+	}
+	if _, found := idx.spans[n]; !found {
+		return nil, false, nil
+	}
+	candidates := idx.Descendants(n, m[1])
+	pred := exprStr[len(base):]
+	if pred == "" {
+		return candidates, true, nil
+	}
+	if !indexablePredicate(pred) {
+		return nil, false, nil
+	}
+	expr, err := loadXPathExpr("self::"+m[1]+pred, flags)
+	if err != nil {
+		return nil, true, err
+	}
+	var ret []*Node
+	for _, c := range candidates {
+		// Keep the query's context node as the navigation root, so absolute paths in the
+		// predicate are evaluated the same way as without the Index.
+		if expr.Select(&navigator{root: n, cur: c}).MoveNext() {
+			ret = append(ret, c)
+		}
+	}
+	return ret, true, nil
+}
+
+// MatchAll is the same as the package level MatchAll, except it uses the Index to answer the query
+// whenever possible. It is safe to call on a nil *Index.
+func (idx *Index) MatchAll(n *Node, exprStr string, flags ...uint) ([]*Node, error) {
+	ret, ok, err := idx.lookup(n, exprStr, flags)
+	if !ok {
+		return MatchAll(n, exprStr, flags...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// MatchSingle is the same as the package level MatchSingle, except it uses the Index to answer the
+// query whenever possible. It is safe to call on a nil *Index.
+func (idx *Index) MatchSingle(n *Node, exprStr string, flags ...uint) (*Node, error) {
+	ret, ok, err := idx.lookup(n, exprStr, flags)
+	if !ok {
+		return MatchSingle(n, exprStr, flags...)
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case len(ret) == 0:
+		return nil, ErrNoMatch
+	case len(ret) > 1:
+		return nil, ErrMoreThanExpected
+	}
+	return ret[0], nil
+}
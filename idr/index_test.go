@@ -0,0 +1,103 @@
+package idr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func indexTestTree(t *testing.T) *Node {
+	r, err := NewXMLStreamReader(strings.NewReader(`
+		<ISA>
+			<GS>
+				<ST><CLM amt="1">a</CLM><NM1>x</NM1></ST>
+				<ST><CLM amt="2">b</CLM><LX><CLM amt="3">c</CLM></LX></ST>
+			</GS>
+			<CLM amt="4">d</CLM>
+		</ISA>`), "/ISA")
+	assert.NoError(t, err)
+	n, err := r.Read()
+	assert.NoError(t, err)
+	return n
+}
+
+func TestIndex_Descendants(t *testing.T) {
+	root := indexTestTree(t)
+	idx := NewIndex(root)
+	clms := idx.Descendants(root, "CLM")
+	assert.Equal(t, 4, len(clms))
+	for i, exp := range []string{"a", "b", "c", "d"} {
+		assert.Equal(t, exp, clms[i].InnerText())
+	}
+	st2, err := MatchAll(root, "GS/ST[2]")
+	assert.NoError(t, err)
+	clms = idx.Descendants(st2[0], "CLM")
+	assert.Equal(t, 2, len(clms))
+	assert.Equal(t, "b", clms[0].InnerText())
+	assert.Equal(t, "c", clms[1].InnerText())
+	assert.Nil(t, idx.Descendants(st2[0], "NM1"))
+	assert.Nil(t, idx.Descendants(CreateNode(ElementNode, "not-indexed"), "CLM"))
+}
+
+func TestIndex_MatchAll_SameAsWithoutIndex(t *testing.T) {
+	root := indexTestTree(t)
+	idx := NewIndex(root)
+	for _, xpath := range []string{
+		".//CLM",
+		".//CLM[@amt > 1]",
+		".//CLM[. = 'c']",
+		".//CLM[1]",
+		".//CLM[last()]",
+		".//NOT_EXIST",
+		"GS/ST/CLM",
+		"//CLM",
+	} {
+		t.Run(xpath, func(t *testing.T) {
+			exp, err := MatchAll(root, xpath)
+			assert.NoError(t, err)
+			nodes, err := idx.MatchAll(root, xpath)
+			assert.NoError(t, err)
+			assert.Equal(t, exp, nodes)
+		})
+	}
+}
+
+func TestIndex_MatchAll_InvalidPredicate(t *testing.T) {
+	root := indexTestTree(t)
+	nodes, err := NewIndex(root).MatchAll(root, ".//CLM[@amt = ]")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "compilation failed")
+	assert.Nil(t, nodes)
+}
+
+func TestIndex_MatchSingle(t *testing.T) {
+	root := indexTestTree(t)
+	idx := NewIndex(root)
+	n, err := idx.MatchSingle(root, ".//CLM[@amt = 3]")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", n.InnerText())
+	n, err = idx.MatchSingle(root, ".//NM1")
+	assert.NoError(t, err)
+	assert.Equal(t, "x", n.InnerText())
+	n, err = idx.MatchSingle(root, ".//CLM")
+	assert.Equal(t, ErrMoreThanExpected, err)
+	assert.Nil(t, n)
+	n, err = idx.MatchSingle(root, ".//NOT_EXIST")
+	assert.Equal(t, ErrNoMatch, err)
+	assert.Nil(t, n)
+	n, err = idx.MatchSingle(root, ".//CLM[@amt = ]")
+	assert.Error(t, err)
+	assert.Nil(t, n)
+}
+
+func TestIndex_Nil(t *testing.T) {
+	root := indexTestTree(t)
+	var idx *Index
+	nodes, err := idx.MatchAll(root, ".//CLM")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(nodes))
+	n, err := idx.MatchSingle(root, ".//NM1")
+	assert.NoError(t, err)
+	assert.Equal(t, "x", n.InnerText())
+}
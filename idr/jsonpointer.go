@@ -0,0 +1,128 @@
+package idr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("json pointer '%s' is invalid: must be empty or start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 >= len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf(
+					"json pointer '%s' is invalid: '~' must be followed by '0' or '1'", pointer)
+			}
+		}
+		// Per RFC 6901, '~1' must be unescaped before '~0', so that '~01' becomes '~1', not '/'.
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// MatchJSONPointer returns the node referenced by an RFC 6901 JSON Pointer 'pointer' (e.g. "/items/0/sku")
+// evaluated against a JSON IDR tree rooted at 'n'. The pointer is relative to 'n', so "" references 'n'
+// itself. A referenced property or array element is returned as its element node, whose value is the
+// node's InnerText() if it's a value. If nothing is referenced, e.g. a property doesn't exist or an array
+// index is out of range, ErrNoMatch is returned; if an object has duplicate properties of the referenced
+// name, ErrMoreThanExpected is returned.
+func MatchJSONPointer(n *Node, pointer string) (*Node, error) {
+	if !IsJSON(n) {
+		return nil, fmt.Errorf("json pointer '%s' can only be matched against a JSON IDR tree", pointer)
+	}
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch {
+		case IsJSONObj(n):
+			n, err = jsonObjProp(n, token)
+		case IsJSONArr(n):
+			n, err = jsonArrElem(n, token)
+		default:
+			// values can't be referenced into.
+			err = ErrNoMatch
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func jsonObjProp(obj *Node, name string) (*Node, error) {
+	var ret *Node
+	for c := obj.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != ElementNode || c.Data != name {
+			continue
+		}
+		if ret != nil {
+			return nil, ErrMoreThanExpected
+		}
+		ret = c
+	}
+	if ret == nil {
+		return nil, ErrNoMatch
+	}
+	return ret, nil
+}
+
+func jsonArrElem(arr *Node, token string) (*Node, error) {
+	// Per RFC 6901, an array index is either "0" or a decimal without leading zeros; and "-", which
+	// references the (nonexistent) element after the last one, never matches.
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return nil, ErrNoMatch
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || token[0] == '+' {
+		return nil, ErrNoMatch
+	}
+	for c := arr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != ElementNode {
+			continue
+		}
+		if index == 0 {
+			return c, nil
+		}
+		index--
+	}
+	return nil, ErrNoMatch
+}
+
+// JSONPointerOf returns the RFC 6901 JSON Pointer of a node in a JSON IDR tree, relative to the root of the
+// tree, e.g. "/items/0/sku". If 'n' is a value (text) node, the pointer of its property or array element is
+// returned.
+func JSONPointerOf(n *Node) string {
+	if n.Type == TextNode && n.Parent != nil {
+		n = n.Parent
+	}
+	var tokens []string
+	for ; n != nil && n.Parent != nil; n = n.Parent {
+		if IsJSONArr(n.Parent) {
+			index := 0
+			for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+				if s.Type == ElementNode {
+					index++
+				}
+			}
+			tokens = append(tokens, strconv.Itoa(index))
+			continue
+		}
+		tokens = append(tokens, strings.ReplaceAll(strings.ReplaceAll(n.Data, "~", "~0"), "/", "~1"))
+	}
+	var sb strings.Builder
+	for i := len(tokens) - 1; i >= 0; i-- {
+		sb.WriteByte('/')
+		sb.WriteString(tokens[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package idr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readJSONTree(t *testing.T, js string) *Node {
+	sp, err := NewJSONStreamReader(strings.NewReader(js), "/")
+	assert.NoError(t, err)
+	n, err := sp.Read()
+	assert.NoError(t, err)
+	return n
+}
+
+func TestMatchJSONPointer(t *testing.T) {
+	js := `{
+		"order_id": "1234567",
+		"items": [
+			{ "sku": "A1", "qty": 5 },
+			{ "sku": "B2", "qty": 1, "tags": ["x", "y"] }
+		],
+		"a/b": 1,
+		"m~n": 2,
+		"": 3,
+		"refund_id": null
+	}`
+	for _, test := range []struct {
+		name     string
+		pointer  string
+		err      string
+		expected string
+	}{
+		{name: "whole doc", pointer: "", expected: JSONify2(readJSONTree(t, js))},
+		{name: "str prop", pointer: "/order_id", expected: `"1234567"`},
+		{name: "arr prop", pointer: "/items/1/tags", expected: `["x","y"]`},
+		{name: "arr elem obj", pointer: "/items/0", expected: `{"qty":5,"sku":"A1"}`},
+		{name: "arr elem value", pointer: "/items/1/tags/1", expected: `"y"`},
+		{name: "null prop", pointer: "/refund_id", expected: `null`},
+		{name: "escaped slash", pointer: "/a~1b", expected: `1`},
+		{name: "escaped tilde", pointer: "/m~0n", expected: `2`},
+		{name: "empty name", pointer: "/", expected: `3`},
+		{name: "prop not found", pointer: "/items/0/price", err: ErrNoMatch.Error()},
+		{name: "index out of range", pointer: "/items/2", err: ErrNoMatch.Error()},
+		{name: "index past last", pointer: "/items/-", err: ErrNoMatch.Error()},
+		{name: "index leading zero", pointer: "/items/01", err: ErrNoMatch.Error()},
+		{name: "index not a number", pointer: "/items/x", err: ErrNoMatch.Error()},
+		{name: "into a value", pointer: "/order_id/0", err: ErrNoMatch.Error()},
+		{
+			name:    "no leading slash",
+			pointer: "items",
+			err:     "json pointer 'items' is invalid: must be empty or start with '/'",
+		},
+		{
+			name:    "invalid escape",
+			pointer: "/m~2n",
+			err:     "json pointer '/m~2n' is invalid: '~' must be followed by '0' or '1'",
+		},
+		{
+			name:    "dangling escape",
+			pointer: "/m~",
+			err:     "json pointer '/m~' is invalid: '~' must be followed by '0' or '1'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := MatchJSONPointer(readJSONTree(t, js), test.pointer)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, n)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, JSONify2(n))
+			assert.Equal(t, test.pointer, JSONPointerOf(n))
+		})
+	}
+}
+
+func TestMatchJSONPointer_Relative(t *testing.T) {
+	root := readJSONTree(t, `{"items": [{"sku": "A1"}, {"sku": "B2"}]}`)
+	item, err := MatchJSONPointer(root, "/items/1")
+	assert.NoError(t, err)
+	sku, err := MatchJSONPointer(item, "/sku")
+	assert.NoError(t, err)
+	assert.Equal(t, "B2", sku.InnerText())
+	assert.Equal(t, "/items/1/sku", JSONPointerOf(sku))
+	assert.Equal(t, "/items/1/sku", JSONPointerOf(sku.FirstChild))
+}
+
+func TestMatchJSONPointer_DuplicateProps(t *testing.T) {
+	root := readJSONTree(t, `{"a": 1}`)
+	AddChild(root, CreateJSONNode(ElementNode, "a", JSONProp))
+	_, err := MatchJSONPointer(root, "/a")
+	assert.Equal(t, ErrMoreThanExpected, err)
+}
+
+func TestMatchJSONPointer_NotJSON(t *testing.T) {
+	_, err := MatchJSONPointer(CreateNode(DocumentNode, ""), "/a")
+	assert.Error(t, err)
+	assert.Equal(t, "json pointer '/a' can only be matched against a JSON IDR tree", err.Error())
+}
@@ -12,12 +12,88 @@ import (
 	"github.com/jf-tech/go-corelib/ios"
 )
 
+// JSONDuplicateKeys specifies how JSONStreamReader handles duplicate keys in a JSON object.
+type JSONDuplicateKeys int
+
+const (
+	// JSONDuplicateKeysKeepAll keeps all the duplicate keys as sibling nodes. This is the default.
+	JSONDuplicateKeysKeepAll JSONDuplicateKeys = iota
+	// JSONDuplicateKeysFirstWins keeps the first of the duplicate keys and discards the rest.
+	JSONDuplicateKeysFirstWins
+	// JSONDuplicateKeysLastWins keeps the last of the duplicate keys and discards the rest.
+	JSONDuplicateKeysLastWins
+	// JSONDuplicateKeysError fails the reading on duplicate keys.
+	JSONDuplicateKeysError
+	// JSONDuplicateKeysArray turns the duplicate keys into a single key whose value is an array of
+	// all the duplicate keys' values, in their original order.
+	JSONDuplicateKeysArray
+)
+
 // JSONStreamReader is a streaming JSON to *Node reader.
 type JSONStreamReader struct {
 	r                          *ios.LineCountingReader
 	d                          *json.Decoder
 	xpathExpr, xpathFilterExpr *xpath.Expr
 	root, cur, stream          *Node
+	streamLine                 int   // line where the current stream candidate started.
+	tokOffset                  int64 // offset where the token being processed started.
+	streamOffset               int64 // offset where the current stream candidate started.
+	dupKeys                    JSONDuplicateKeys
+}
+
+// WithDuplicateKeys sets how duplicate keys in a JSON object are handled. Duplicate keys are
+// handled once the object is completely read; thus keys of an object enclosing stream nodes aren't
+// checked against the stream nodes, which are released once read.
+func (sp *JSONStreamReader) WithDuplicateKeys(dupKeys JSONDuplicateKeys) *JSONStreamReader {
+	sp.dupKeys = dupKeys
+	return sp
+}
+
+// handleDuplicateKeys handles duplicate keys among the children of a completed object node.
+func (sp *JSONStreamReader) handleDuplicateKeys(obj *Node) error {
+	if sp.dupKeys == JSONDuplicateKeysKeepAll || obj.FirstChild == obj.LastChild {
+		return nil
+	}
+	seen := make(map[string]*Node)
+	var arrayified map[*Node]bool
+	for child := obj.FirstChild; child != nil; {
+		next := child.NextSibling
+		first, dup := seen[child.Data]
+		switch {
+		case !dup:
+			seen[child.Data] = child
+		case sp.dupKeys == JSONDuplicateKeysError:
+			return fmt.Errorf("duplicate key '%s'", child.Data)
+		case sp.dupKeys == JSONDuplicateKeysFirstWins:
+			RemoveAndReleaseTree(child)
+		case sp.dupKeys == JSONDuplicateKeysLastWins:
+			RemoveAndReleaseTree(first)
+			seen[child.Data] = child
+		case sp.dupKeys == JSONDuplicateKeysArray:
+			if !arrayified[first] {
+				// turn the first key into an array, with its original value as the first element.
+				elem := CreateJSONNode(ElementNode, "", toJSONArrElemType(JSONTypeOf(first)))
+				for c := first.FirstChild; c != nil; {
+					cnext := c.NextSibling
+					removeFromTree(c)
+					AddChild(elem, c)
+					c = cnext
+				}
+				first.FormatSpecific = JSONProp | JSONArr
+				AddChild(first, elem)
+				if arrayified == nil {
+					arrayified = make(map[*Node]bool)
+				}
+				arrayified[first] = true
+			}
+			removeFromTree(child)
+			child.Data = ""
+			child.FormatSpecific = toJSONArrElemType(JSONTypeOf(child))
+			AddChild(first, child)
+		}
+		child = next
+	}
+	return nil
 }
 
 // streamCandidateCheck checks if sp.cur is a potential stream candidate.
@@ -42,6 +118,8 @@ type JSONStreamReader struct {
 func (sp *JSONStreamReader) streamCandidateCheck() {
 	if sp.xpathExpr != nil && sp.stream == nil && MatchAny(sp.root, sp.xpathExpr) {
 		sp.stream = sp.cur
+		sp.streamLine = sp.AtLine()
+		sp.streamOffset = sp.tokOffset
 	}
 }
 
@@ -81,8 +159,10 @@ func (sp *JSONStreamReader) addTextChild(tok interface{}) {
 	var data string
 	var jtype JSONType
 	switch v := tok.(type) {
-	case float64:
-		data = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		// Numbers are kept verbatim, rather than round-tripped through float64, so that large
+		// integers (e.g. 64-bit IDs) and high-precision decimals don't lose any digits.
+		data = v.String()
 		jtype = JSONValueNum
 	case bool:
 		data = strconv.FormatBool(v)
@@ -100,7 +180,17 @@ func (sp *JSONStreamReader) addTextChild(tok interface{}) {
 	// added below it, so no need to advance sp.cur to child.
 }
 
-func (sp *JSONStreamReader) parseDelim(tok json.Delim) *Node {
+// toJSONArrElemType returns the type of the anonymous array element node holding the value of a
+// property node of the given type, the same as if the value were directly inside an array.
+func toJSONArrElemType(propType JSONType) JSONType {
+	if t := propType &^ JSONProp; t != 0 {
+		// object or array value.
+		return t
+	}
+	return JSONProp
+}
+
+func (sp *JSONStreamReader) parseDelim(tok json.Delim) (*Node, error) {
 	switch tok {
 	case '{':
 		switch {
@@ -141,12 +231,17 @@ func (sp *JSONStreamReader) parseDelim(tok json.Delim) *Node {
 			sp.streamCandidateCheck()
 		}
 	case '}', ']':
+		if tok == '}' {
+			if err := sp.handleDuplicateKeys(sp.cur); err != nil {
+				return nil, err
+			}
+		}
 		ret := sp.wrapUpCurAndTargetCheck()
 		if ret != nil {
-			return ret
+			return ret, nil
 		}
 	}
-	return nil
+	return nil, nil
 }
 
 func (sp *JSONStreamReader) parseVal(tok json.Token) *Node {
@@ -189,17 +284,26 @@ func (sp *JSONStreamReader) parseVal(tok json.Token) *Node {
 
 func (sp *JSONStreamReader) parse() (*Node, error) {
 	for {
+		sp.tokOffset = sp.d.InputOffset()
 		tok, err := sp.d.Token()
 		if err != nil {
 			// including io.EOF
 			return nil, err
 		}
+		if _, ok := tok.(json.Delim); ok {
+			// delimiters are single bytes, thus exactly where they start is known.
+			sp.tokOffset = sp.d.InputOffset() - 1
+		}
 		switch tok := tok.(type) {
 		case json.Delim:
-			if ret := sp.parseDelim(tok); ret != nil {
+			ret, err := sp.parseDelim(tok)
+			if err != nil {
+				return nil, err
+			}
+			if ret != nil {
 				return ret, nil
 			}
-		case string, float64, bool, nil:
+		case string, json.Number, bool, nil:
 			if ret := sp.parseVal(tok); ret != nil {
 				return ret, nil
 			}
@@ -235,6 +339,25 @@ func (sp *JSONStreamReader) AtLine() int {
 	return sp.r.AtLine()
 }
 
+// StreamStartLine returns the **rough** line number where the stream node most recently returned
+// by Read started. It shares the same inaccuracy as AtLine due to the JSON decoder's read-ahead.
+func (sp *JSONStreamReader) StreamStartLine() int {
+	return sp.streamLine
+}
+
+// InputOffset returns the byte offset of the current JSON decoder position in the input, i.e. the end
+// of the last token read.
+func (sp *JSONStreamReader) InputOffset() int64 {
+	return sp.d.InputOffset()
+}
+
+// StreamStartOffset returns the byte offset where the stream node most recently returned by Read
+// started. The offset is exact for objects and arrays; for other values, and for properties, it's where
+// the previous token ended, so it may include the whitespace and separators (',' or ':') before them.
+func (sp *JSONStreamReader) StreamStartOffset() int64 {
+	return sp.streamOffset
+}
+
 // NewJSONStreamReader creates a new instance of JSON streaming reader.
 func NewJSONStreamReader(r io.Reader, xpathStr string) (*JSONStreamReader, error) {
 	xpathStr = strings.TrimSpace(xpathStr)
@@ -245,9 +368,11 @@ func NewJSONStreamReader(r io.Reader, xpathStr string) (*JSONStreamReader, error
 	}
 	xpathNoFilterExpr, _ := caches.GetXPathExpr(xpathNoFilterStr)
 	lineCountingReader := ios.NewLineCountingReader(r)
+	d := json.NewDecoder(lineCountingReader)
+	d.UseNumber()
 	reader := &JSONStreamReader{
 		r:         lineCountingReader,
-		d:         json.NewDecoder(lineCountingReader),
+		d:         d,
 		xpathExpr: xpathNoFilterExpr,
 		xpathFilterExpr: func() *xpath.Expr {
 			if xpathStr == xpathNoFilterStr {
@@ -127,3 +127,101 @@ func TestJSONStreamReader(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONStreamReader_DuplicateKeys(t *testing.T) {
+	js := `{"a":1, "b":{"x":1, "x":2}, "a":{"y":"2"}, "c":[], "a":[3, 4]}`
+	for _, test := range []struct {
+		name     string
+		dupKeys  JSONDuplicateKeys
+		expected []string // the root object's children, each in the form of key=JSONify2(value).
+		err      string
+	}{
+		{
+			name:    "keep all",
+			dupKeys: JSONDuplicateKeysKeepAll,
+			// note JSONify2 renders the duplicate "x"s as if they're array elements.
+			expected: []string{`a=1`, `b=[1,2]`, `a={"y":"2"}`, `c=[]`, `a=[3,4]`},
+		},
+		{
+			name:     "first wins",
+			dupKeys:  JSONDuplicateKeysFirstWins,
+			expected: []string{`a=1`, `b={"x":1}`, `c=[]`},
+		},
+		{
+			name:     "last wins",
+			dupKeys:  JSONDuplicateKeysLastWins,
+			expected: []string{`b={"x":2}`, `c=[]`, `a=[3,4]`},
+		},
+		{
+			name:    "error",
+			dupKeys: JSONDuplicateKeysError,
+			err:     "duplicate key 'x'",
+		},
+		{
+			name:     "array",
+			dupKeys:  JSONDuplicateKeysArray,
+			expected: []string{`a=[1,{"y":"2"},[3,4]]`, `b={"x":[1,2]}`, `c=[]`},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			sp, err := NewJSONStreamReader(strings.NewReader(js), "/")
+			assert.NoError(t, err)
+			n, err := sp.WithDuplicateKeys(test.dupKeys).Read()
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				assert.Nil(t, n)
+				return
+			}
+			assert.NoError(t, err)
+			var children []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				children = append(children, c.Data+"="+JSONify2(c))
+			}
+			assert.Equal(t, test.expected, children)
+			// xpath queries work the same as if the input had no duplicate keys.
+			if test.dupKeys == JSONDuplicateKeysArray {
+				nodes, err := MatchAll(n, "a/*")
+				assert.NoError(t, err)
+				assert.Equal(t, 3, len(nodes))
+			}
+		})
+	}
+}
+
+func TestJSONStreamReader_NumbersVerbatim(t *testing.T) {
+	sp, err := NewJSONStreamReader(
+		strings.NewReader(`{"id": 12345678901234567890, "amount": 0.10000000000000000555, "exp": -1.5E+300, "one": 1.0}`),
+		"/")
+	assert.NoError(t, err)
+	n, err := sp.Read()
+	assert.NoError(t, err)
+	var values []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		assert.Equal(t, JSONValueNum, JSONTypeOf(c.FirstChild))
+		values = append(values, c.Data+"="+c.FirstChild.Data)
+	}
+	assert.Equal(t,
+		[]string{`id=12345678901234567890`, `amount=0.10000000000000000555`, `exp=-1.5E+300`, `one=1.0`},
+		values)
+	assert.Equal(t,
+		`{"amount":0.10000000000000000555,"exp":-1.5E+300,"id":12345678901234567890,"one":1.0}`,
+		JSONify2(n))
+}
+
+func TestJSONStreamReader_Offsets(t *testing.T) {
+	input := "[\n  {\"id\": 1},\n  \"two\",\n  [3]\n]"
+	sp, err := NewJSONStreamReader(strings.NewReader(input), "/*")
+	assert.NoError(t, err)
+	var ranges []string
+	for {
+		n, err := sp.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		ranges = append(ranges, input[sp.StreamStartOffset():sp.InputOffset()])
+		sp.Release(n)
+	}
+	assert.Equal(t, []string{`{"id": 1}`, ",\n  \"two\"", `[3]`}, ranges)
+}
@@ -122,8 +122,8 @@ func (ctx *ctx) getChildData(n *Node) interface{} {
 	n = n.FirstChild
 	switch {
 	case IsJSONValueNum(n):
-		f, _ := strconv.ParseFloat(n.Data, 64)
-		return f
+		// json.Number, rather than float64, so numbers are marshaled back out exactly as they're read.
+		return json.Number(n.Data)
 	case IsJSONValueBool(n):
 		b, _ := strconv.ParseBool(n.Data)
 		return b
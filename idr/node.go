@@ -146,11 +146,29 @@ func AddChild(parent, n *Node) {
 	parent.LastChild = n
 }
 
+// CopyTree returns a copy of a node and its subtree, detached from the IDR tree the node is in, if
+// any. FormatSpecific values are copied shallowly. The copy is independent of the original, so it
+// stays valid after the original is released, e.g. by a reader's next Read call.
+func CopyTree(n *Node) *Node {
+	c := CreateNode(n.Type, n.Data)
+	c.FormatSpecific = n.FormatSpecific
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		AddChild(c, CopyTree(child))
+	}
+	return c
+}
+
 // RemoveAndReleaseTree removes a node and its subtree from an IDR tree it is in and
 // release the resources (Node allocation) associated with the node and its subtree.
 func RemoveAndReleaseTree(n *Node) {
+	removeFromTree(n)
+	recycle(n)
+}
+
+// removeFromTree detaches a node (and its subtree) from the IDR tree it is in, if any.
+func removeFromTree(n *Node) {
 	if n.Parent == nil {
-		goto recycle
+		return
 	}
 	if n.Parent.FirstChild == n {
 		if n.Parent.LastChild == n {
@@ -169,8 +187,7 @@ func RemoveAndReleaseTree(n *Node) {
 			n.NextSibling.PrevSibling = n.PrevSibling
 		}
 	}
-recycle:
-	recycle(n)
+	n.Parent, n.PrevSibling, n.NextSibling = nil, nil, nil
 }
 
 func recycle(n *Node) {
@@ -219,3 +219,16 @@ func BenchmarkCreateAndDestroyTree_WithCache(b *testing.B) {
 		RemoveAndReleaseTree(tt.root)
 	}
 }
+
+func TestCopyTree(t *testing.T) {
+	setupTestNodeCaching(testNodeCachingOn)
+	tt := newTestTree(t, testTreeXML)
+	expected := JSONify2(tt.elemC)
+	c := CopyTree(tt.elemC)
+	assert.Nil(t, c.Parent)
+	assert.NotEqual(t, tt.elemC.ID, c.ID)
+	checkPointersInTree(t, c)
+	// the copy stays intact after the original is released.
+	RemoveAndReleaseTree(tt.elemC)
+	assert.Equal(t, expected, JSONify2(c))
+}
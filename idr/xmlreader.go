@@ -18,6 +18,9 @@ type XMLStreamReader struct {
 	space2prefix               map[string]string
 	xpathExpr, xpathFilterExpr *xpath.Expr
 	root, cur, stream          *Node
+	streamLine                 int   // line where the current stream candidate started.
+	tokOffset                  int64 // offset where the token being processed started.
+	streamOffset               int64 // offset where the current stream candidate started.
 	err                        error
 }
 
@@ -26,6 +29,8 @@ type XMLStreamReader struct {
 func (sp *XMLStreamReader) streamCandidateCheck() {
 	if sp.xpathExpr != nil && sp.stream == nil && MatchAny(sp.root, sp.xpathExpr) {
 		sp.stream = sp.cur
+		sp.streamLine = sp.AtLine()
+		sp.streamOffset = sp.tokOffset
 	}
 }
 
@@ -127,6 +132,7 @@ func (sp *XMLStreamReader) addTextChild(text string) {
 
 func (sp *XMLStreamReader) parse() (*Node, error) {
 	for {
+		sp.tokOffset = sp.d.InputOffset()
 		tok, err := sp.d.Token()
 		if err != nil {
 			// including io.EOF
@@ -197,6 +203,24 @@ func (sp *XMLStreamReader) AtLine() int {
 	return int(reflect.ValueOf(sp.d).Elem().FieldByName("line").Int())
 }
 
+// StreamStartLine returns the line number where the stream node most recently returned by Read
+// started, i.e. the line of its start element.
+func (sp *XMLStreamReader) StreamStartLine() int {
+	return sp.streamLine
+}
+
+// InputOffset returns the byte offset of the current XML decoder position in the input, i.e. the end
+// of the last token read.
+func (sp *XMLStreamReader) InputOffset() int64 {
+	return sp.d.InputOffset()
+}
+
+// StreamStartOffset returns the byte offset where the stream node most recently returned by Read
+// started. Note the offset is exactly where its start element began.
+func (sp *XMLStreamReader) StreamStartOffset() int64 {
+	return sp.streamOffset
+}
+
 // NewXMLStreamReader creates a new instance of XML streaming reader.
 func NewXMLStreamReader(r io.Reader, xpathStr string) (*XMLStreamReader, error) {
 	xpathStr = strings.TrimSpace(xpathStr)
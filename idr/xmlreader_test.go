@@ -167,3 +167,20 @@ func TestXMLStreamReader_FailureInvalidAttributeNodeNamespacePrefix(t *testing.T
 	assert.Equal(t, "unknown namespace 'non_existing' on AttributeNode 'attr'", err.Error())
 	assert.Nil(t, n)
 }
+
+func TestXMLStreamReader_Offsets(t *testing.T) {
+	input := "<Root>\n  <A id=\"1\">x</A>\n  <B/>\n  <A>y</A>\n</Root>"
+	sp, err := NewXMLStreamReader(strings.NewReader(input), "/Root/A")
+	assert.NoError(t, err)
+	var ranges []string
+	for {
+		n, err := sp.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		ranges = append(ranges, input[sp.StreamStartOffset():sp.InputOffset()])
+		sp.Release(n)
+	}
+	assert.Equal(t, []string{`<A id="1">x</A>`, `<A>y</A>`}, ranges)
+}
@@ -0,0 +1,117 @@
+package input
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// ErrNoMatchingPart is returned when no part of a MIME payload matches the PartSelector.
+var ErrNoMatchingPart = errors.New("no matching MIME part found")
+
+// PartSelector specifies which part of a MIME/EML payload is selected as the input for a transform.
+// A part is selected only if it matches all the specified criteria. If no criteria is specified, the
+// first attachment is selected.
+type PartSelector struct {
+	// ContentType, if specified, is matched against the media type (e.g. "text/csv") of a part, case
+	// insensitively. A trailing "/*" matches all the subtypes, e.g. "application/*".
+	ContentType string
+	// FilenamePattern, if specified, is a path.Match pattern (e.g. "*.edi") matched against the file
+	// name of a part, case insensitively.
+	FilenamePattern string
+}
+
+// PartInfo contains information about the selected MIME part.
+type PartInfo struct {
+	ContentType string
+	Filename    string
+}
+
+// ExtractMIMEPart parses a MIME/EML payload, selects the first (depth-first) part that matches the
+// selector, and returns a reader of its decoded content, which can then be fed into
+// omniparser.Schema.NewTransform. The returned reader reads directly from r, so r must not be used
+// until the returned reader is done.
+func ExtractMIMEPart(r io.Reader, selector PartSelector) (io.Reader, *PartInfo, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read MIME payload: %s", err.Error())
+	}
+	return selectPart(textproto.MIMEHeader(msg.Header), msg.Body, selector)
+}
+
+func selectPart(header textproto.MIMEHeader, body io.Reader, selector PartSelector) (io.Reader, *PartInfo, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// Per RFC 2045, content without a valid Content-Type is plain text.
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil, nil, ErrNoMatchingPart
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to read MIME part: %s", err.Error())
+			}
+			r, info, err := selectPart(p.Header, p, selector)
+			if err != ErrNoMatchingPart {
+				return r, info, err
+			}
+		}
+	}
+	info := &PartInfo{ContentType: mediaType, Filename: partFilename(header, params)}
+	if !selector.match(info, header) {
+		return nil, nil, ErrNoMatchingPart
+	}
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		// Encoded lines are usually CRLF terminated, which base64 decoder ignores.
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	return body, info, nil
+}
+
+func partFilename(header textproto.MIMEHeader, contentTypeParams map[string]string) string {
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		if filename := params["filename"]; filename != "" {
+			return filename
+		}
+	}
+	return contentTypeParams["name"]
+}
+
+func (s PartSelector) match(info *PartInfo, header textproto.MIMEHeader) bool {
+	if s.ContentType == "" && s.FilenamePattern == "" {
+		disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+		return disposition == "attachment" || info.Filename != ""
+	}
+	if s.ContentType != "" {
+		ct := strings.ToLower(s.ContentType)
+		if strings.HasSuffix(ct, "/*") {
+			if !strings.HasPrefix(info.ContentType, strings.TrimSuffix(ct, "*")) {
+				return false
+			}
+		} else if info.ContentType != ct {
+			return false
+		}
+	}
+	if s.FilenamePattern != "" {
+		matched, err := path.Match(strings.ToLower(s.FilenamePattern), strings.ToLower(info.Filename))
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
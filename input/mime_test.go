@@ -0,0 +1,123 @@
+package input
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testEML = "From: partner@example.com\r\n" +
+	"To: inbound@example.com\r\n" +
+	"Subject: daily files\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"inner\"\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Please find attached.\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Please find attached.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/csv; name=\"orders.csv\"\r\n" +
+	"Content-Disposition: attachment; filename=\"Orders.CSV\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"YSxiLGMKMSwy\r\n" +
+	"LDMK\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/edi-x12; name=\"invoice.edi\"\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"ISA*00*=3D~\r\n" +
+	"--outer--\r\n"
+
+func TestExtractMIMEPart(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		selector PartSelector
+		expInfo  *PartInfo
+		expData  string
+		expErr   string
+	}{
+		{
+			name:     "no criteria: first attachment",
+			selector: PartSelector{},
+			expInfo:  &PartInfo{ContentType: "text/csv", Filename: "Orders.CSV"},
+			expData:  "a,b,c\n1,2,3\n",
+		},
+		{
+			name:     "by filename pattern, case insensitive",
+			selector: PartSelector{FilenamePattern: "*.csv"},
+			expInfo:  &PartInfo{ContentType: "text/csv", Filename: "Orders.CSV"},
+			expData:  "a,b,c\n1,2,3\n",
+		},
+		{
+			name:     "by content type with wildcard, filename from content type name",
+			selector: PartSelector{ContentType: "Application/*"},
+			expInfo:  &PartInfo{ContentType: "application/edi-x12", Filename: "invoice.edi"},
+			expData:  "ISA*00*=~",
+		},
+		{
+			name:     "nested part by content type",
+			selector: PartSelector{ContentType: "text/html"},
+			expInfo:  &PartInfo{ContentType: "text/html"},
+			expData:  "<p>Please find attached.</p>",
+		},
+		{
+			name:     "both criteria must match",
+			selector: PartSelector{ContentType: "text/csv", FilenamePattern: "*.edi"},
+			expErr:   ErrNoMatchingPart.Error(),
+		},
+		{
+			name:     "invalid pattern",
+			selector: PartSelector{FilenamePattern: "["},
+			expErr:   ErrNoMatchingPart.Error(),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r, info, err := ExtractMIMEPart(strings.NewReader(testEML), test.selector)
+			if test.expErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expErr, err.Error())
+				assert.Nil(t, r)
+				assert.Nil(t, info)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expInfo, info)
+			b, err := ioutil.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expData, string(b))
+		})
+	}
+}
+
+func TestExtractMIMEPart_NonMultipart(t *testing.T) {
+	r, info, err := ExtractMIMEPart(
+		strings.NewReader("Subject: x\r\n\r\nISA*00~"), PartSelector{ContentType: "text/plain"})
+	assert.NoError(t, err)
+	assert.Equal(t, &PartInfo{ContentType: "text/plain"}, info)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "ISA*00~", string(b))
+}
+
+func TestExtractMIMEPart_Failures(t *testing.T) {
+	_, _, err := ExtractMIMEPart(strings.NewReader(""), PartSelector{})
+	assert.Error(t, err)
+	assert.Equal(t, "unable to read MIME payload: EOF", err.Error())
+
+	_, _, err = ExtractMIMEPart(strings.NewReader(
+		"Content-Type: multipart/mixed; boundary=\"b\"\r\n\r\n--b\r\nbad header\r\n\r\n"), PartSelector{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read MIME part: ")
+}
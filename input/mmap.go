@@ -0,0 +1,124 @@
+package input
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// InMemory is implemented by inputs whose unread content is entirely addressable in memory, such as
+// a memory-mapped MappedFile. Readers that support it (e.g. the fixed-length reader) slice records
+// directly out of Bytes() instead of copying them through bufio buffers.
+type InMemory interface {
+	io.Reader
+	// Bytes returns the unread content, which must be treated as read-only, or nil if the content
+	// isn't in memory (e.g. a MappedFile that fell back to regular file reads).
+	Bytes() []byte
+}
+
+var errMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+// MappedFile is a local file opened for reading by OpenMapped, memory-mapped if possible.
+type MappedFile struct {
+	f    *os.File
+	data []byte // the mapped content; nil if the file isn't mapped.
+	off  int
+}
+
+// OpenMapped opens a local file for reading and memory-maps it, so the file content can be read
+// without read syscalls copying it out of the OS page cache, and, by readers supporting InMemory,
+// without further copies. It safely falls back to regular file reads if the file can't be mapped:
+// if it isn't a regular file (e.g. a pipe), is empty or too large for the address space, or if the
+// platform doesn't support mmap or mmap fails. Either way, the returned MappedFile must be closed by
+// the caller once the transform is done with it.
+//
+// Note the file must not be truncated while mapped: accessing mapped pages beyond the new end of
+// the file crashes the process with SIGBUS on most platforms.
+func OpenMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &MappedFile{f: f}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	size := info.Size()
+	if !info.Mode().IsRegular() || size <= 0 || int64(int(size)) != size {
+		return m, nil
+	}
+	if data, err := mmap(f, int(size)); err == nil {
+		m.data = data
+	}
+	return m, nil
+}
+
+// Mapped tells whether the file is memory-mapped, or has fallen back to regular file reads.
+func (m *MappedFile) Mapped() bool {
+	return m.data != nil
+}
+
+// Bytes implements InMemory, returning the unread content of a mapped file, or nil if the file isn't
+// mapped. The returned slice is only valid until Close.
+func (m *MappedFile) Bytes() []byte {
+	if m.data == nil {
+		return nil
+	}
+	return m.data[m.off:]
+}
+
+// Read implements io.Reader.
+func (m *MappedFile) Read(p []byte) (int, error) {
+	if m.data == nil {
+		return m.f.Read(p)
+	}
+	if m.off >= len(m.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.off:])
+	m.off += n
+	return n, nil
+}
+
+// Close unmaps, if mapped, and closes the file.
+func (m *MappedFile) Close() error {
+	var err error
+	if m.data != nil {
+		err = munmap(m.data)
+		m.data = nil
+	}
+	if closeErr := m.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// BytesReader is an InMemory io.Reader over a byte slice.
+type BytesReader struct {
+	b []byte
+}
+
+// NewBytesReader creates an InMemory io.Reader over b. b must not be modified while it's being read.
+func NewBytesReader(b []byte) *BytesReader {
+	return &BytesReader{b: b}
+}
+
+// Bytes implements InMemory, returning the unread content. It's never nil.
+func (r *BytesReader) Bytes() []byte {
+	if r.b == nil {
+		return []byte{}
+	}
+	return r.b
+}
+
+// Read implements io.Reader.
+func (r *BytesReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
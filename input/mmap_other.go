@@ -0,0 +1,15 @@
+//go:build !unix
+
+package input
+
+import (
+	"os"
+)
+
+func mmap(_ *os.File, _ int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmap(_ []byte) error {
+	return nil
+}
@@ -0,0 +1,85 @@
+package input
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "input.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestOpenMapped(t *testing.T) {
+	m, err := OpenMapped(writeTempFile(t, "line 1\nline 2\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, runtime.GOOS != "windows" && runtime.GOOS != "plan9" && runtime.GOOS != "js", m.Mapped())
+	if m.Mapped() {
+		assert.Equal(t, "line 1\nline 2\n", string(m.Bytes()))
+	}
+	p := make([]byte, 7)
+	n, err := m.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\n", string(p[:n]))
+	if m.Mapped() {
+		assert.Equal(t, "line 2\n", string(m.Bytes()))
+	}
+	b, err := ioutil.ReadAll(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "line 2\n", string(b))
+	assert.NoError(t, m.Close())
+	assert.False(t, m.Mapped())
+	assert.Nil(t, m.Bytes())
+}
+
+func TestOpenMapped_Fallback(t *testing.T) {
+	// empty file can't be mapped.
+	m, err := OpenMapped(writeTempFile(t, ""))
+	assert.NoError(t, err)
+	assert.False(t, m.Mapped())
+	assert.Nil(t, m.Bytes())
+	n, err := m.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, m.Close())
+	// neither can a non-regular file.
+	m, err = OpenMapped(t.TempDir())
+	assert.NoError(t, err)
+	assert.False(t, m.Mapped())
+	_, err = m.Read(make([]byte, 10))
+	assert.Error(t, err)
+	assert.NoError(t, m.Close())
+}
+
+func TestOpenMapped_Failure(t *testing.T) {
+	m, err := OpenMapped(filepath.Join(t.TempDir(), "non-existing"))
+	assert.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, m)
+}
+
+func TestBytesReader(t *testing.T) {
+	r := NewBytesReader(nil)
+	assert.Equal(t, []byte{}, r.Bytes())
+	n, err := r.Read(make([]byte, 10))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	r = NewBytesReader([]byte("abc"))
+	p := make([]byte, 2)
+	n, err = r.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", string(p[:n]))
+	assert.Equal(t, "c", string(r.Bytes()))
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", string(b))
+	assert.Equal(t, []byte{}, r.Bytes())
+}
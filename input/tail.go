@@ -0,0 +1,89 @@
+package input
+
+import (
+	"io"
+	"time"
+)
+
+// DefaultTailPollInterval is the default Tail.PollInterval.
+const DefaultTailPollInterval = time.Second
+
+// Tail configures a TailReader.
+type Tail struct {
+	// PollInterval is how often the input is polled for more data once all of its data is read.
+	// Default to DefaultTailPollInterval.
+	PollInterval time.Duration
+	// IdleTimeout, if positive, ends the tailing once no data has arrived for that long, i.e. the input
+	// is then considered complete. Otherwise, the input is tailed until Stop is closed.
+	IdleTimeout time.Duration
+	// Stop, if not nil, ends the tailing once closed: the data already in the input is still read, but
+	// io.EOF is returned as soon as there is no more.
+	Stop <-chan struct{}
+}
+
+// TailReader reads an input being appended to by another process, e.g. a file spooled by an upstream
+// system: instead of returning io.EOF at the end of the input, it waits for more data to be appended,
+// polling the input, until the input has been idle for Tail.IdleTimeout or Tail.Stop is closed.
+type TailReader struct {
+	r        io.Reader
+	tail     Tail
+	lastData time.Time // when data was last read, or when the TailReader was created.
+	timer    *time.Timer
+	stopped  bool // Stop has been closed.
+}
+
+// NewTailReader creates a TailReader of an input, e.g. an *os.File, whose Read calls return io.EOF at
+// the end of the data appended so far, and more data once appended.
+func NewTailReader(r io.Reader, tail Tail) *TailReader {
+	if tail.PollInterval <= 0 {
+		tail.PollInterval = DefaultTailPollInterval
+	}
+	return &TailReader{r: r, tail: tail, lastData: time.Now()}
+}
+
+// Read implements io.Reader. It blocks while there is no more data in the input, and returns io.EOF
+// once the tailing ends.
+func (t *TailReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		n, err := t.r.Read(p)
+		if n > 0 {
+			t.lastData = time.Now()
+			// io.EOF along with data only means there is no more data for now.
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if t.stopped {
+			return 0, io.EOF
+		}
+		wait := t.tail.PollInterval
+		if t.tail.IdleTimeout > 0 {
+			idle := time.Since(t.lastData)
+			if idle >= t.tail.IdleTimeout {
+				return 0, io.EOF
+			}
+			if remaining := t.tail.IdleTimeout - idle; remaining < wait {
+				wait = remaining
+			}
+		}
+		if t.timer == nil {
+			t.timer = time.NewTimer(wait)
+		} else {
+			t.timer.Reset(wait)
+		}
+		select {
+		case <-t.tail.Stop:
+			t.timer.Stop()
+			// reads once more, so the data appended right before the stop isn't missed.
+			t.stopped = true
+		case <-t.timer.C:
+		}
+	}
+}
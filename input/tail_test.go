@@ -0,0 +1,80 @@
+package input
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func appendFile(t *testing.T, path, content string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	_, err = f.WriteString(content)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+}
+
+func TestTailReader_IdleTimeout(t *testing.T) {
+	path := writeTempFile(t, "line 1\n")
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		appendFile(t, path, "line 2\n")
+		time.Sleep(20 * time.Millisecond)
+		appendFile(t, path, "line 3\n")
+	}()
+	start := time.Now()
+	r := NewTailReader(f, Tail{PollInterval: 5 * time.Millisecond, IdleTimeout: 200 * time.Millisecond})
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\nline 3\n", string(b))
+	// the tailing ends once idle for IdleTimeout after the last data.
+	assert.True(t, time.Since(start) >= 240*time.Millisecond)
+}
+
+func TestTailReader_Stop(t *testing.T) {
+	path := writeTempFile(t, "line 1\n")
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	stop := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		appendFile(t, path, "line 2\n")
+		close(stop)
+	}()
+	// no IdleTimeout: the tailing ends only once stopped, and the data appended before is read.
+	b, err := ioutil.ReadAll(NewTailReader(f, Tail{PollInterval: time.Hour, Stop: stop}))
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", string(b))
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failure") }
+
+func TestTailReader_Error(t *testing.T) {
+	n, err := NewTailReader(errReader{}, Tail{}).Read(make([]byte, 1))
+	assert.Equal(t, 0, n)
+	assert.Error(t, err)
+	assert.Equal(t, "read failure", err.Error())
+
+	r := NewTailReader(strings.NewReader("a"), Tail{IdleTimeout: time.Millisecond})
+	n, err = r.Read(nil)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(b))
+	n, err = r.Read(make([]byte, 1))
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,162 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// Limits limits the output of a Transform, e.g. for an API endpoint paging through a very large
+// transformed output: once a limit is reached, the Transform stops cleanly with an ErrLimitReached,
+// whose ResumeToken lets a new Transform of the same input and schema continue where it stopped.
+type Limits struct {
+	// MaxRecords is the max number of records returned. 0 means no limit.
+	MaxRecords int
+	// MaxBytes is the max total bytes of the records returned. 0 means no limit. At least one record
+	// is returned, even if it alone exceeds MaxBytes, so paging always makes progress.
+	MaxBytes int
+	// ResumeToken, if not empty, is the ResumeToken of the ErrLimitReached returned by a previous
+	// Transform of the same input and schema. The records read by the previous Transform are skipped.
+	ResumeToken string
+}
+
+// ErrLimitReached is returned by a Transform created by WithLimits once a limit is reached. This is
+// a fatal error: future calls to Read will always return the same error.
+type ErrLimitReached struct {
+	// ResumeToken is an opaque token for Limits.ResumeToken to continue the transform.
+	ResumeToken string
+}
+
+// Error implements the error interface.
+func (e ErrLimitReached) Error() string {
+	return fmt.Sprintf("transform limit reached, resume token: '%s'", e.ResumeToken)
+}
+
+// IsErrLimitReached tells if an error is of ErrLimitReached, or wraps one.
+func IsErrLimitReached(err error) bool {
+	var e ErrLimitReached
+	return errors.As(err, &e)
+}
+
+const resumeTokenPrefix = "v1."
+
+func resumeToken(consumed int) string {
+	return resumeTokenPrefix + strconv.Itoa(consumed)
+}
+
+func parseResumeToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	consumed, err := strconv.Atoi(strings.TrimPrefix(token, resumeTokenPrefix))
+	if !strings.HasPrefix(token, resumeTokenPrefix) || err != nil || consumed < 0 {
+		return 0, fmt.Errorf("invalid resume token '%s'", token)
+	}
+	return consumed, nil
+}
+
+// WithLimits wraps a Transform, freshly created and not yet read, so that it stops once the limits
+// are reached. Reads that skip the records consumed per limits.ResumeToken are seen by the wrapped
+// Transform's listeners as well.
+func WithLimits(t Transform, limits Limits) (Transform, error) {
+	if limits.MaxRecords < 0 || limits.MaxBytes < 0 {
+		return nil, fmt.Errorf("invalid limits: max records %d, max bytes %d", limits.MaxRecords, limits.MaxBytes)
+	}
+	skip, err := parseResumeToken(limits.ResumeToken)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedTransform{Transform: t, limits: limits, skip: skip, consumed: skip}, nil
+}
+
+type limitedTransform struct {
+	Transform
+	limits   Limits
+	skip     int // number of Reads left to skip per the resume token.
+	consumed int // number of Reads, successful or continuable, since the very first page.
+	records  int
+	bytes    int
+	err      error // ErrLimitReached, io.EOF or fatal error.
+}
+
+// Read implements Transform.Read.
+func (l *limitedTransform) Read() ([]byte, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	for ; l.skip > 0; l.skip-- {
+		if _, err := l.Transform.Read(); err != nil && !errs.IsErrTransformFailed(err) {
+			l.err = err
+			return nil, err
+		}
+	}
+	if l.limits.MaxRecords > 0 && l.records >= l.limits.MaxRecords {
+		// read ahead, so that a page ending right at the end of the input ends with io.EOF.
+		return l.readAhead()
+	}
+	record, err := l.Transform.Read()
+	switch {
+	case err == nil:
+		if l.limits.MaxBytes > 0 && l.records > 0 && l.bytes+len(record) > l.limits.MaxBytes {
+			// the record read is left to the next page.
+			l.err = ErrLimitReached{ResumeToken: resumeToken(l.consumed)}
+			return nil, l.err
+		}
+		l.consumed++
+		l.records++
+		l.bytes += len(record)
+	case errs.IsErrTransformFailed(err):
+		l.consumed++
+	default:
+		l.err = err
+	}
+	return record, err
+}
+
+func (l *limitedTransform) readAhead() ([]byte, error) {
+	_, err := l.Transform.Read()
+	switch {
+	case err == nil:
+		l.err = ErrLimitReached{ResumeToken: resumeToken(l.consumed)}
+		return nil, l.err
+	case errs.IsErrTransformFailed(err):
+		// continuable errors are returned, and consumed, as usual.
+		l.consumed++
+	default:
+		l.err = err
+	}
+	return nil, err
+}
+
+// RawRecord implements Transform.RawRecord.
+func (l *limitedTransform) RawRecord() (schemahandler.RawRecord, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.Transform.RawRecord()
+}
+
+// AddListener implements Listenable, registering the Listener with the wrapped Transform, if it's
+// Listenable.
+func (l *limitedTransform) AddListener(listener Listener) {
+	addListener(l.Transform, listener)
+}
+
+// Stats implements StatsReporter, returning the running totals of the wrapped Transform.
+func (l *limitedTransform) Stats() TransformStats {
+	return statsOf(l.Transform)
+}
+
+// Summary implements SummaryReporter, returning the summary of the wrapped Transform.
+func (l *limitedTransform) Summary() TransformSummary {
+	return summaryOf(l.Transform)
+}
+
+// Preview implements Previewer, previewing the wrapped Transform, not subject to the limits.
+func (l *limitedTransform) Preview(n int) (*Preview, error) {
+	return previewOf(l.Transform, n)
+}
@@ -0,0 +1,154 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+const limitsTestInput = `<a>` +
+	`<b><c>2020-01-01</c></b><b><c>2020-01-02</c></b><b><c>bad</c></b>` +
+	`<b><c>2020-01-03</c></b><b><c>2020-01-04</c></b>` +
+	`</a>`
+
+// readPages reads the input page by page with the limits, and returns the records (or "error" for
+// continuable errors) of each page.
+func readPages(t *testing.T, limits Limits) [][]string {
+	var pages [][]string
+	for {
+		tfm, err := newPushTestSchema(t).NewTransform(
+			"test-input", strings.NewReader(limitsTestInput), &transformctx.Ctx{})
+		assert.NoError(t, err)
+		tfm, err = WithLimits(tfm, limits)
+		assert.NoError(t, err)
+		var page []string
+		for {
+			record, err := tfm.Read()
+			if err == io.EOF {
+				return append(pages, page)
+			}
+			if IsErrLimitReached(err) {
+				pages = append(pages, page)
+				limits.ResumeToken = err.(ErrLimitReached).ResumeToken
+				// the limit reached is returned repeatedly.
+				_, err2 := tfm.Read()
+				assert.Equal(t, err, err2)
+				_, err2 = tfm.RawRecord()
+				assert.Equal(t, err, err2)
+				break
+			}
+			if errs.IsErrTransformFailed(err) {
+				page = append(page, "error")
+				continue
+			}
+			assert.NoError(t, err)
+			raw, err := tfm.RawRecord()
+			assert.NoError(t, err)
+			assert.NotNil(t, raw)
+			page = append(page, string(record)[6:16])
+		}
+	}
+}
+
+func TestWithLimits(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		limits   Limits
+		expected [][]string
+	}{
+		{
+			name:     "no limits",
+			expected: [][]string{{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"}},
+		},
+		{
+			name:     "max records",
+			limits:   Limits{MaxRecords: 2},
+			expected: [][]string{{"2020-01-01", "2020-01-02", "error"}, {"2020-01-03", "2020-01-04"}},
+		},
+		{
+			name:     "max records dividing input evenly ends with EOF",
+			limits:   Limits{MaxRecords: 4},
+			expected: [][]string{{"2020-01-01", "2020-01-02", "error", "2020-01-03", "2020-01-04"}},
+		},
+		{
+			name:   "max bytes",
+			limits: Limits{MaxBytes: 60},
+			// each record is `{"c":"2020-01-01T00:00:00"}`, 27 bytes.
+			expected: [][]string{{"2020-01-01", "2020-01-02", "error"}, {"2020-01-03", "2020-01-04"}},
+		},
+		{
+			name:     "max bytes too small still makes progress",
+			limits:   Limits{MaxBytes: 1},
+			expected: [][]string{{"2020-01-01"}, {"2020-01-02", "error"}, {"2020-01-03"}, {"2020-01-04"}},
+		},
+		{
+			name:     "both",
+			limits:   Limits{MaxRecords: 1, MaxBytes: 100},
+			expected: [][]string{{"2020-01-01"}, {"2020-01-02", "error"}, {"2020-01-03"}, {"2020-01-04"}},
+		},
+		{
+			name:     "resume",
+			limits:   Limits{MaxRecords: 10, ResumeToken: "v1.3"},
+			expected: [][]string{{"2020-01-03", "2020-01-04"}},
+		},
+		{
+			name:     "resume past the end",
+			limits:   Limits{ResumeToken: "v1.10"},
+			expected: [][]string{nil},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, readPages(t, test.limits))
+		})
+	}
+}
+
+func TestWithLimits_Failure(t *testing.T) {
+	tfm := &transform{ingester: &testIngester{}}
+	for _, test := range []struct {
+		name   string
+		limits Limits
+		err    string
+	}{
+		{name: "negative max records", limits: Limits{MaxRecords: -1}, err: "invalid limits: max records -1, max bytes 0"},
+		{name: "negative max bytes", limits: Limits{MaxBytes: -1}, err: "invalid limits: max records 0, max bytes -1"},
+		{name: "invalid token", limits: Limits{ResumeToken: "3"}, err: "invalid resume token '3'"},
+		{name: "invalid token number", limits: Limits{ResumeToken: "v1.x"}, err: "invalid resume token 'v1.x'"},
+		{name: "negative token number", limits: Limits{ResumeToken: "v1.-1"}, err: "invalid resume token 'v1.-1'"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			limited, err := WithLimits(tfm, test.limits)
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+			assert.Nil(t, limited)
+		})
+	}
+}
+
+func TestWithLimits_FatalErrorWhileSkipping(t *testing.T) {
+	tfm, err := WithLimits(&transform{ingester: &testIngester{
+		readCalls: []testReadCall{{result: []byte("1st good read")}, {err: errors.New("fatal error")}},
+	}}, Limits{ResumeToken: "v1.5"})
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		record, err := tfm.Read()
+		assert.Error(t, err)
+		assert.Equal(t, "fatal error", err.Error())
+		assert.Nil(t, record)
+	}
+}
+
+func TestIsErrLimitReached(t *testing.T) {
+	err := ErrLimitReached{ResumeToken: "v1.3"}
+	assert.True(t, IsErrLimitReached(err))
+	assert.Equal(t, "transform limit reached, resume token: 'v1.3'", err.Error())
+	assert.True(t, IsErrLimitReached(fmt.Errorf("page 2: %w", err)))
+	assert.False(t, IsErrLimitReached(io.EOF))
+}
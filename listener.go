@@ -0,0 +1,64 @@
+package omniparser
+
+import (
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// TransformStats are the running totals of a Transform.
+type TransformStats struct {
+	Emitted int // number of records successfully ingested and transformed.
+	Skipped int // number of records skipped due to continuable errors, i.e. errs.ErrTransformFailed.
+	// Templates and CustomFuncs are the invocation stats of the templates and custom funcs, keyed by
+	// their names, if transformctx.Ctx.CollectStats is on and the schema handler collects them (see
+	// schemahandler.StatsCollector); nil otherwise.
+	Templates   map[string]schemahandler.InvocationStats
+	CustomFuncs map[string]schemahandler.InvocationStats
+}
+
+// Listener receives the events of a Transform, so host applications can do auditing, metrics and
+// progress reporting without wrapping the Read loop. Callbacks are called synchronously from within
+// Read, on the same goroutine, and in the order the Listeners are added. seq is the 1-based sequence
+// number of the Read call.
+type Listener interface {
+	// OnRecordStart is called at the start of each Read call that attempts to ingest a record, before
+	// the outcome is known, i.e. the last OnRecordStart is followed by OnEOF or OnError, instead of
+	// OnRecordEnd or OnSkip.
+	OnRecordStart(seq int)
+	// OnRecordEnd is called when a record is successfully ingested and transformed. rawRecord is the
+	// same as what Transform.RawRecord returns; see schemahandler.RawBytesRecord for the lifetime of
+	// its raw bytes.
+	OnRecordEnd(seq int, rawRecord schemahandler.RawRecord, transformed []byte)
+	// OnSkip is called when a record fails to be ingested or transformed with a continuable error,
+	// i.e. errs.ErrTransformFailed, and is skipped.
+	OnSkip(seq int, err error)
+	// OnError is called, once, when the Transform fails with a fatal error.
+	OnError(seq int, err error)
+	// OnEOF is called, once, when the input stream is completely consumed.
+	OnEOF(stats TransformStats)
+}
+
+// WarningListener is an optional interface a Listener can implement to receive the warnings, i.e. the
+// problems in the input tolerated by the schema, e.g. a truncated last EDI segment (see
+// schemahandler.Warner). OnWarning is called for each warning raised during a Read call, before the
+// callback of the Read call's outcome, i.e. OnRecordEnd, OnSkip, OnError or OnEOF.
+type WarningListener interface {
+	OnWarning(seq int, err error)
+}
+
+// NopListener is a Listener that does nothing. Embed it to implement only the callbacks needed.
+type NopListener struct{}
+
+// OnRecordStart implements Listener.
+func (NopListener) OnRecordStart(int) {}
+
+// OnRecordEnd implements Listener.
+func (NopListener) OnRecordEnd(int, schemahandler.RawRecord, []byte) {}
+
+// OnSkip implements Listener.
+func (NopListener) OnSkip(int, error) {}
+
+// OnError implements Listener.
+func (NopListener) OnError(int, error) {}
+
+// OnEOF implements Listener.
+func (NopListener) OnEOF(TransformStats) {}
@@ -0,0 +1,149 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+type testListener struct {
+	NopListener
+	events []string
+}
+
+func (l *testListener) OnRecordStart(seq int) {
+	l.events = append(l.events, fmt.Sprintf("start %d", seq))
+}
+
+func (l *testListener) OnRecordEnd(seq int, rawRecord schemahandler.RawRecord, transformed []byte) {
+	l.events = append(l.events, fmt.Sprintf("end %d %s: %s", seq, rawRecord.Raw(), transformed))
+}
+
+func (l *testListener) OnSkip(seq int, err error) {
+	l.events = append(l.events, fmt.Sprintf("skip %d: %s (%t)", seq, err, errs.IsErrTransformFailed(err)))
+}
+
+func (l *testListener) OnError(seq int, err error) {
+	l.events = append(l.events, fmt.Sprintf("error %d: %s", seq, err))
+}
+
+func (l *testListener) OnEOF(stats TransformStats) {
+	l.events = append(l.events, fmt.Sprintf("eof {Emitted:%d Skipped:%d}", stats.Emitted, stats.Skipped))
+}
+
+func TestTransform_Listener_EndWithEOF(t *testing.T) {
+	continuableErr1 := errors.New("continuable error 1")
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: continuableErr1},
+				{result: []byte("2nd good read")},
+				{err: io.EOF},
+			},
+			continuableErrs: map[error]bool{continuableErr1: true},
+		},
+	}
+	l1, l2 := &testListener{}, &testListener{}
+	tfm.AddListener(l1)
+	tfm.AddListener(l2)
+	tfm.AddListener(NopListener{})
+	for i := 0; i < 5; i++ {
+		_, _ = tfm.Read()
+	}
+	expected := []string{
+		"start 1",
+		"end 1 raw record of '1st good read': 1st good read",
+		"start 2",
+		"skip 2: continuable error 1 (true)",
+		"start 3",
+		"end 3 raw record of '2nd good read': 2nd good read",
+		"start 4",
+		"eof {Emitted:2 Skipped:1}",
+	}
+	assert.Equal(t, expected, l1.events)
+	assert.Equal(t, expected, l2.events)
+}
+
+func TestTransform_Listener_EndWithNonContinuableError(t *testing.T) {
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: errors.New("fatal error")},
+			},
+		},
+	}
+	_, _ = tfm.Read()
+	// events before the listener is added aren't received.
+	l := &testListener{}
+	tfm.AddListener(l)
+	for i := 0; i < 3; i++ {
+		_, _ = tfm.Read()
+	}
+	assert.Equal(t, []string{"start 2", "error 2: fatal error"}, l.events)
+}
+
+type testWarningIngester struct {
+	testIngester
+	warnings [][]error
+}
+
+func (g *testWarningIngester) Warnings() []error {
+	return g.warnings[g.readCalled-1]
+}
+
+type testWarningListener struct {
+	testListener
+}
+
+func (l *testWarningListener) OnWarning(seq int, err error) {
+	l.events = append(l.events, fmt.Sprintf("warning %d: %s", seq, err))
+}
+
+func TestTransform_Listener_Warnings(t *testing.T) {
+	tfm := &transform{
+		ingester: &testWarningIngester{
+			testIngester: testIngester{
+				readCalls: []testReadCall{
+					{result: []byte("1st good read")},
+					{result: []byte("2nd good read")},
+					{err: io.EOF},
+				},
+			},
+			warnings: [][]error{nil, {errors.New("warning 1"), errors.New("warning 2")}, {errors.New("warning 3")}},
+		},
+	}
+	l1, l2 := &testWarningListener{}, &testListener{}
+	tfm.AddListener(l1)
+	tfm.AddListener(l2)
+	for i := 0; i < 3; i++ {
+		_, _ = tfm.Read()
+	}
+	assert.Equal(t, []string{
+		"start 1",
+		"end 1 raw record of '1st good read': 1st good read",
+		"start 2",
+		"warning 2: warning 1",
+		"warning 2: warning 2",
+		"end 2 raw record of '2nd good read': 2nd good read",
+		"start 3",
+		"warning 3: warning 3",
+		"eof {Emitted:2 Skipped:0}",
+	}, l1.events)
+	// listeners not implementing WarningListener don't receive the warnings.
+	assert.Equal(t, []string{
+		"start 1",
+		"end 1 raw record of '1st good read': 1st good read",
+		"start 2",
+		"end 2 raw record of '2nd good read': 2nd good read",
+		"start 3",
+		"eof {Emitted:2 Skipped:0}",
+	}, l2.events)
+}
@@ -0,0 +1,67 @@
+package omniparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// ManifestEntry is the manifest entry of one record transformed.
+type ManifestEntry struct {
+	// Seq is the 1-based sequence number of the Read call that returned the record.
+	Seq int `json:"seq"`
+	// Checksum is the hex encoded SHA-256 of the transformed record.
+	Checksum string `json:"checksum"`
+	// SourceChecksum is the checksum of the raw record, see schemahandler.RawRecord.
+	SourceChecksum string `json:"source_checksum"`
+	// Number, Begin and End are the 1-based ordinal of the raw record in the input, and the range of
+	// source positions it was ingested from, if reported; see schemahandler.RecordPositioner.
+	Number int `json:"number,omitempty"`
+	Begin  int `json:"begin,omitempty"`
+	End    int `json:"end,omitempty"`
+	// ByteBegin and ByteEnd are the range of bytes in the input the raw record was ingested from, if
+	// reported; see schemahandler.RecordOffsetter.
+	ByteBegin *int64 `json:"byte_begin,omitempty"`
+	ByteEnd   *int64 `json:"byte_end,omitempty"`
+}
+
+// ManifestWriter is a Listener that writes a manifest of the records transformed, one ManifestEntry
+// per line in JSON, so downstream can verify that a re-run of the same input produced byte-identical
+// results. The manifest of the same input and schema is always the same.
+type ManifestWriter struct {
+	NopListener
+	enc *json.Encoder
+	err error
+}
+
+// NewManifestWriter creates a ManifestWriter that writes the manifest into w.
+func NewManifestWriter(w io.Writer) *ManifestWriter {
+	return &ManifestWriter{enc: json.NewEncoder(w)}
+}
+
+// OnRecordEnd implements Listener.
+func (m *ManifestWriter) OnRecordEnd(seq int, rawRecord schemahandler.RawRecord, transformed []byte) {
+	if m.err != nil {
+		return
+	}
+	sum := sha256.Sum256(transformed)
+	entry := ManifestEntry{Seq: seq, Checksum: hex.EncodeToString(sum[:]), SourceChecksum: rawRecord.Checksum()}
+	if p, ok := rawRecord.(schemahandler.RecordPositioner); ok {
+		entry.Number, entry.Begin, entry.End = p.RecordPosition()
+	}
+	if o, ok := rawRecord.(schemahandler.RecordOffsetter); ok {
+		if begin, end, ok := o.RecordOffsets(); ok {
+			entry.ByteBegin, entry.ByteEnd = &begin, &end
+		}
+	}
+	m.err = m.enc.Encode(entry)
+}
+
+// Err returns the first error, if any, writing the manifest. Once an error occurs, no more entries
+// are written.
+func (m *ManifestWriter) Err() error {
+	return m.err
+}
@@ -0,0 +1,90 @@
+package omniparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestManifestWriter(t *testing.T) {
+	continuableErr := errors.New("continuable error")
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: continuableErr},
+				{result: []byte("2nd good read")},
+				{err: io.EOF},
+			},
+			continuableErrs: map[error]bool{continuableErr: true},
+		},
+	}
+	var buf bytes.Buffer
+	m := NewManifestWriter(&buf)
+	tfm.AddListener(m)
+	for i := 0; i < 4; i++ {
+		_, _ = tfm.Read()
+	}
+	assert.NoError(t, m.Err())
+	assert.Equal(t,
+		`{"seq":1,"checksum":"`+sha256Hex("1st good read")+`","source_checksum":"checksum of raw record of '1st good read'"}`+"\n"+
+			`{"seq":3,"checksum":"`+sha256Hex("2nd good read")+`","source_checksum":"checksum of raw record of '2nd good read'"}`+"\n",
+		buf.String())
+}
+
+func TestManifestWriter_SourcePositions(t *testing.T) {
+	manifest := func() string {
+		tfm, err := newPushTestSchema(t).NewTransform("test-input",
+			strings.NewReader("<a><b><c>2020-01-02</c></b><b><c>bad</c></b><b><c>2020-01-03</c></b></a>"),
+			&transformctx.Ctx{})
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		tfm.(Listenable).AddListener(NewManifestWriter(&buf))
+		for {
+			if _, err := tfm.Read(); err == io.EOF {
+				break
+			}
+		}
+		return buf.String()
+	}
+	m1 := manifest()
+	lines := strings.Split(strings.TrimSuffix(m1, "\n"), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"seq":1,"checksum":"`+sha256Hex(`{"c":"2020-01-02T00:00:00"}`)+`"`)
+	assert.Contains(t, lines[0], `"number":1`)
+	assert.Contains(t, lines[0], `"byte_begin":3,"byte_end":27`)
+	assert.Contains(t, lines[1], `"seq":3,"checksum":"`+sha256Hex(`{"c":"2020-01-03T00:00:00"}`)+`"`)
+	assert.Contains(t, lines[1], `"number":3`)
+	assert.Contains(t, lines[1], `"byte_begin":44,"byte_end":68`)
+	// the manifest of a re-run is identical.
+	assert.Equal(t, m1, manifest())
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("disk full")
+}
+
+func TestManifestWriter_WriteFailure(t *testing.T) {
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{{result: []byte("1st good read")}, {result: []byte("2nd good read")}},
+		},
+	}
+	w := &failingWriter{}
+	m := NewManifestWriter(w)
+	tfm.AddListener(m)
+	_, _ = tfm.Read()
+	_, _ = tfm.Read()
+	assert.Error(t, m.Err())
+	assert.Equal(t, "disk full", m.Err().Error())
+	assert.Equal(t, 1, w.writes)
+}
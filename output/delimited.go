@@ -0,0 +1,242 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jf-tech/go-corelib/times"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+// ProfileProperty is the name of the external property (see transformctx.Ctx.ExternalProperties)
+// used to select a Profile for a delimited output. If the property isn't set, DefaultProfile is used.
+const ProfileProperty = "output_profile"
+
+// DefaultProfile is the name of the Profile used when no profile is explicitly selected.
+const DefaultProfile = "default"
+
+const (
+	// QuoteMinimal quotes a value only if it contains the delimiter, a double quote, CR or LF.
+	QuoteMinimal = "minimal"
+	// QuoteAll quotes all values.
+	QuoteAll = "all"
+	// QuoteNone never quotes values. It is the profile writer's responsibility to make sure values
+	// contain no delimiter or line terminators.
+	QuoteNone = "none"
+)
+
+// Column describes a column of a delimited output.
+type Column struct {
+	// Name is the column name used in the header row.
+	Name string `json:"name"`
+	// Path is the '.' separated path to the value in a transformed JSON record, e.g. "address.city".
+	Path string `json:"path"`
+	// DateFormat, if specified, is the Go time layout the value, a datetime string, is formatted into.
+	DateFormat *string `json:"date_format,omitempty"`
+}
+
+// Profile describes how transformed JSON records are written into a delimited output for a particular
+// consumer/partner.
+type Profile struct {
+	Delimiter string   `json:"delimiter,omitempty"` // default ','.
+	Quote     string   `json:"quote,omitempty"`     // default QuoteMinimal.
+	Header    bool     `json:"header,omitempty"`
+	CRLF      bool     `json:"crlf,omitempty"`
+	Columns   []Column `json:"columns"`
+}
+
+// Profiles is a collection of named Profile.
+type Profiles map[string]*Profile
+
+// LoadProfiles reads in and validates Profiles from a JSON input.
+func LoadProfiles(r io.Reader) (Profiles, error) {
+	var profiles Profiles
+	if err := json.NewDecoder(r).Decode(&profiles); err != nil {
+		return nil, err
+	}
+	for name, p := range profiles {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("output profile '%s': %s", name, err.Error())
+		}
+	}
+	return profiles, nil
+}
+
+func (p *Profile) validate() error {
+	if p == nil {
+		return errors.New("profile is empty")
+	}
+	if len([]rune(p.Delimiter)) > 1 {
+		return fmt.Errorf("'delimiter' must be a single character, but got '%s'", p.Delimiter)
+	}
+	switch p.Quote {
+	case "", QuoteMinimal, QuoteAll, QuoteNone:
+	default:
+		return fmt.Errorf("unknown 'quote' policy '%s'", p.Quote)
+	}
+	if len(p.Columns) == 0 {
+		return errors.New("'columns' must not be empty")
+	}
+	for i, c := range p.Columns {
+		if c.Path == "" {
+			return fmt.Errorf("column[%d] '%s' is missing 'path'", i, c.Name)
+		}
+	}
+	return nil
+}
+
+// Select returns the Profile selected by the ProfileProperty external property in the ctx, or
+// DefaultProfile if the property isn't set.
+func (ps Profiles) Select(ctx *transformctx.Ctx) (*Profile, error) {
+	name := DefaultProfile
+	if ctx != nil {
+		if v, found := ctx.External(ProfileProperty); found {
+			name = v
+		}
+	}
+	p, found := ps[name]
+	if !found {
+		return nil, fmt.Errorf("output profile '%s' not found", name)
+	}
+	return p, nil
+}
+
+// DelimitedWriter writes transformed JSON records, such as the ones returned by
+// omniparser.Transform.Read, into a delimited output according to a Profile.
+type DelimitedWriter struct {
+	w             *bufio.Writer
+	profile       *Profile
+	delim         string
+	eol           string
+	headerWritten bool
+	line          strings.Builder
+}
+
+// NewDelimitedWriter creates a DelimitedWriter that writes into w using the Profile selected by ctx,
+// which typically is the same ctx passed to omniparser.Schema.NewTransform.
+func NewDelimitedWriter(w io.Writer, profiles Profiles, ctx *transformctx.Ctx) (*DelimitedWriter, error) {
+	p, err := profiles.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.validate(); err != nil {
+		return nil, err
+	}
+	dw := &DelimitedWriter{w: bufio.NewWriter(w), profile: p, delim: p.Delimiter, eol: "\n"}
+	if dw.delim == "" {
+		dw.delim = ","
+	}
+	if p.CRLF {
+		dw.eol = "\r\n"
+	}
+	return dw, nil
+}
+
+// Write writes a transformed JSON record as a line of the delimited output. The header row, if the
+// profile asks for it, is written before the first record.
+func (dw *DelimitedWriter) Write(record []byte) error {
+	if dw.profile.Header && !dw.headerWritten {
+		dw.headerWritten = true
+		dw.line.Reset()
+		for i, c := range dw.profile.Columns {
+			dw.writeValue(i, c.Name)
+		}
+		if err := dw.flushLine(); err != nil {
+			return err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(record))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	dw.line.Reset()
+	for i, c := range dw.profile.Columns {
+		s, err := columnValue(v, c)
+		if err != nil {
+			return fmt.Errorf("column '%s': %s", c.Name, err.Error())
+		}
+		dw.writeValue(i, s)
+	}
+	return dw.flushLine()
+}
+
+// Flush writes any buffered data into the underlying io.Writer.
+func (dw *DelimitedWriter) Flush() error {
+	return dw.w.Flush()
+}
+
+func (dw *DelimitedWriter) writeValue(i int, s string) {
+	if i > 0 {
+		dw.line.WriteString(dw.delim)
+	}
+	if !dw.needQuote(s) {
+		dw.line.WriteString(s)
+		return
+	}
+	dw.line.WriteByte('"')
+	dw.line.WriteString(strings.ReplaceAll(s, `"`, `""`))
+	dw.line.WriteByte('"')
+}
+
+func (dw *DelimitedWriter) needQuote(s string) bool {
+	switch dw.profile.Quote {
+	case QuoteAll:
+		return true
+	case QuoteNone:
+		return false
+	default:
+		return strings.Contains(s, dw.delim) || strings.ContainsAny(s, "\"\r\n")
+	}
+}
+
+func (dw *DelimitedWriter) flushLine() error {
+	dw.line.WriteString(dw.eol)
+	_, err := dw.w.WriteString(dw.line.String())
+	return err
+}
+
+func columnValue(record interface{}, c Column) (string, error) {
+	v := record
+	for _, step := range strings.Split(c.Path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		if v, ok = m[step]; !ok {
+			return "", nil
+		}
+	}
+	var s string
+	switch tv := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		s = tv
+	case json.Number:
+		s = tv.String()
+	case bool:
+		s = fmt.Sprint(tv)
+	default:
+		b, err := json.Marshal(tv)
+		if err != nil {
+			return "", err
+		}
+		s = string(b)
+	}
+	if c.DateFormat == nil || s == "" {
+		return s, nil
+	}
+	t, _, err := times.SmartParse(s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(*c.DateFormat), nil
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+const testProfiles = `
+{
+	"default": {
+		"header": true,
+		"columns": [
+			{ "name": "ID", "path": "id" },
+			{ "name": "Name", "path": "name" },
+			{ "name": "City", "path": "address.city" },
+			{ "name": "Date", "path": "date", "date_format": "2006-01-02" }
+		]
+	},
+	"partner_a": {
+		"delimiter": "|",
+		"quote": "all",
+		"crlf": true,
+		"columns": [
+			{ "name": "Date", "path": "date", "date_format": "01/02/2006" },
+			{ "name": "Amount", "path": "amount" },
+			{ "name": "ID", "path": "id" }
+		]
+	}
+}`
+
+var testRecords = []string{
+	`{"id":"1","name":"Smith, John","address":{"city":"Seattle"},"date":"2020-12-25T10:20:30Z","amount":12.50}`,
+	`{"id":"2","name":"say \"hi\"","date":"2021-01-02T00:00:00","amount":3,"flags":[1,2]}`,
+}
+
+func TestDelimitedWriter(t *testing.T) {
+	profiles, err := LoadProfiles(strings.NewReader(testProfiles))
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		name     string
+		ctx      *transformctx.Ctx
+		expected string
+	}{
+		{
+			name: "default profile",
+			ctx:  &transformctx.Ctx{},
+			expected: "ID,Name,City,Date\n" +
+				"1,\"Smith, John\",Seattle,2020-12-25\n" +
+				"2,\"say \"\"hi\"\"\",,2021-01-02\n",
+		},
+		{
+			name:     "partner profile",
+			ctx:      &transformctx.Ctx{ExternalProperties: map[string]string{ProfileProperty: "partner_a"}},
+			expected: "\"12/25/2020\"|\"12.50\"|\"1\"\r\n\"01/02/2021\"|\"3\"|\"2\"\r\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w, err := NewDelimitedWriter(&b, profiles, test.ctx)
+			assert.NoError(t, err)
+			for _, r := range testRecords {
+				assert.NoError(t, w.Write([]byte(r)))
+			}
+			assert.NoError(t, w.Flush())
+			assert.Equal(t, test.expected, b.String())
+		})
+	}
+}
+
+func TestDelimitedWriter_ObjectValueAndQuoteNone(t *testing.T) {
+	var b bytes.Buffer
+	w, err := NewDelimitedWriter(&b, Profiles{
+		DefaultProfile: {Delimiter: "\t", Quote: QuoteNone, Columns: []Column{{Path: "flags"}, {Path: "name"}}},
+	}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Write([]byte(testRecords[1])))
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "[1,2]\tsay \"hi\"\n", b.String())
+}
+
+func TestDelimitedWriter_Failures(t *testing.T) {
+	profiles, err := LoadProfiles(strings.NewReader(testProfiles))
+	assert.NoError(t, err)
+	w, err := NewDelimitedWriter(&bytes.Buffer{}, profiles,
+		&transformctx.Ctx{ExternalProperties: map[string]string{ProfileProperty: "unknown"}})
+	assert.Error(t, err)
+	assert.Equal(t, "output profile 'unknown' not found", err.Error())
+	assert.Nil(t, w)
+
+	w, err = NewDelimitedWriter(&bytes.Buffer{}, profiles, nil)
+	assert.NoError(t, err)
+	err = w.Write([]byte(`{"id":"1","date":"not a date"}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "column 'Date': ")
+	assert.Error(t, w.Write([]byte(`{`)))
+}
+
+func TestLoadProfiles_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		input  string
+		expErr string
+	}{
+		{name: "invalid json", input: `[`, expErr: "unexpected EOF"},
+		{name: "empty profile", input: `{"a": null}`, expErr: "output profile 'a': profile is empty"},
+		{
+			name:   "multi-char delimiter",
+			input:  `{"a": {"delimiter": "||", "columns": [{"path": "x"}]}}`,
+			expErr: "output profile 'a': 'delimiter' must be a single character, but got '||'",
+		},
+		{
+			name:   "unknown quote",
+			input:  `{"a": {"quote": "some", "columns": [{"path": "x"}]}}`,
+			expErr: "output profile 'a': unknown 'quote' policy 'some'",
+		},
+		{name: "no columns", input: `{"a": {}}`, expErr: "output profile 'a': 'columns' must not be empty"},
+		{
+			name:   "column missing path",
+			input:  `{"a": {"columns": [{"name": "x"}]}}`,
+			expErr: "output profile 'a': column[0] 'x' is missing 'path'",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			profiles, err := LoadProfiles(strings.NewReader(test.input))
+			assert.Error(t, err)
+			assert.Equal(t, test.expErr, err.Error())
+			assert.Nil(t, profiles)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrJSONArrayClosed is returned upon writing into a JSONArrayWriter already closed.
+var ErrJSONArrayClosed = errors.New("JSON array is already closed")
+
+// JSONArrayWriter is a RecordWriter writing transformed JSON records as a single JSON array, e.g. the
+// body of an HTTP response, incrementally: the opening bracket is written along with the first record,
+// each following record is preceded by a comma, and Close writes the closing bracket, or `[]` if no
+// record was written, so the output is well-formed JSON once closed, whatever the number of records.
+type JSONArrayWriter struct {
+	w       *bufio.Writer
+	records int
+	closed  bool
+}
+
+// NewJSONArrayWriter creates a JSONArrayWriter that writes into w. Nothing is written into w till the
+// first record is, or till Close.
+func NewJSONArrayWriter(w io.Writer) *JSONArrayWriter {
+	return &JSONArrayWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a transformed JSON record as the next element of the array. A record that isn't valid
+// JSON is rejected, and nothing is written, so the array stays well-formed.
+func (jw *JSONArrayWriter) Write(record []byte) error {
+	if jw.closed {
+		return ErrJSONArrayClosed
+	}
+	if !json.Valid(record) {
+		return errors.New("record isn't valid JSON")
+	}
+	sep := byte(',')
+	if jw.records == 0 {
+		sep = '['
+	}
+	if err := jw.w.WriteByte(sep); err != nil {
+		return err
+	}
+	jw.records++
+	_, err := jw.w.Write(record)
+	return err
+}
+
+// Records returns the number of records written so far.
+func (jw *JSONArrayWriter) Records() int {
+	return jw.records
+}
+
+// Flush writes any buffered data into the underlying io.Writer, e.g. for a client to receive the
+// records written so far. The array isn't closed.
+func (jw *JSONArrayWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+// Close writes the closing bracket, or `[]` if no record was written, and flushes. Once closed, Write
+// fails with ErrJSONArrayClosed, and Close is a no-op.
+func (jw *JSONArrayWriter) Close() error {
+	if jw.closed {
+		return nil
+	}
+	jw.closed = true
+	if jw.records == 0 {
+		if err := jw.w.WriteByte('['); err != nil {
+			return err
+		}
+	}
+	if err := jw.w.WriteByte(']'); err != nil {
+		return err
+	}
+	return jw.w.Flush()
+}
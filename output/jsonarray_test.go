@@ -0,0 +1,101 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONArrayWriter(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		records  []string
+		expected string
+	}{
+		{
+			name:     "no records",
+			expected: `[]`,
+		},
+		{
+			name:     "one record",
+			records:  []string{`{"a":1}`},
+			expected: `[{"a":1}]`,
+		},
+		{
+			name:     "multiple records",
+			records:  []string{`{"a":1}`, "\n{\n\t\"b\": [1, 2]\n}\n", `"c"`},
+			expected: "[{\"a\":1},\n{\n\t\"b\": [1, 2]\n}\n,\"c\"]",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			w := NewJSONArrayWriter(&b)
+			for _, r := range test.records {
+				assert.NoError(t, w.Write([]byte(r)))
+			}
+			assert.Equal(t, len(test.records), w.Records())
+			assert.NoError(t, w.Close())
+			assert.Equal(t, test.expected, b.String())
+			assert.True(t, json.Valid(b.Bytes()))
+		})
+	}
+}
+
+func TestJSONArrayWriter_Flush(t *testing.T) {
+	var b bytes.Buffer
+	w := NewJSONArrayWriter(&b)
+	// nothing is written till the first record is, so an empty array can still be closed as `[]`.
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "", b.String())
+	assert.NoError(t, w.Write([]byte(`{"a":1}`)))
+	assert.Equal(t, "", b.String())
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, `[{"a":1}`, b.String())
+	assert.NoError(t, w.Write([]byte(`{"a":2}`)))
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, `[{"a":1},{"a":2}`, b.String())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, `[{"a":1},{"a":2}]`, b.String())
+}
+
+func TestJSONArrayWriter_InvalidRecord(t *testing.T) {
+	var b bytes.Buffer
+	w := NewJSONArrayWriter(&b)
+	assert.NoError(t, w.Write([]byte(`{"a":1}`)))
+	for _, r := range []string{"", `{"a":`, `{"a":1}{"a":2}`} {
+		err := w.Write([]byte(r))
+		assert.Error(t, err)
+		assert.Equal(t, "record isn't valid JSON", err.Error())
+	}
+	assert.Equal(t, 1, w.Records())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, `[{"a":1}]`, b.String())
+}
+
+func TestJSONArrayWriter_Closed(t *testing.T) {
+	var b bytes.Buffer
+	w := NewJSONArrayWriter(&b)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, ErrJSONArrayClosed, w.Write([]byte(`{}`)))
+	assert.Equal(t, `[]`, b.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("write failure") }
+
+func TestJSONArrayWriter_WriteFailure(t *testing.T) {
+	w := NewJSONArrayWriter(failingWriter{})
+	assert.NoError(t, w.Write([]byte(`{"a":1}`)))
+	err := w.Flush()
+	assert.Error(t, err)
+	assert.Equal(t, "write failure", err.Error())
+	// bufio.Writer keeps failing once it has failed.
+	assert.Error(t, w.Write([]byte(`{"a":2}`)))
+	assert.Error(t, w.Close())
+	assert.Error(t, NewJSONArrayWriter(failingWriter{}).Close())
+}
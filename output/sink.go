@@ -0,0 +1,215 @@
+package output
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/logward/omniparser/errs"
+)
+
+// Sink is where the output of a transform is written into, with all-or-nothing delivery: what's
+// written is staged, and only delivered downstream upon Commit, e.g. once the transform has completed
+// successfully, or discarded upon Abort. Once committed or aborted, a Sink can't be written into
+// anymore, and further Commit and Abort calls are no-ops returning the same error, if any.
+type Sink interface {
+	io.Writer
+	// Commit delivers what's been written downstream.
+	Commit() error
+	// Abort discards what's been written.
+	Abort() error
+}
+
+// ErrSinkClosed is returned upon writing into a Sink already committed or aborted.
+var ErrSinkClosed = errors.New("sink is already committed or aborted")
+
+// stagedFileMode is the permissions of the files committed by StagedFile.
+const stagedFileMode = 0644
+
+// StagedFile is a Sink staging what's written into a temporary file in the same directory as the
+// destination file, so the destination file only appears, complete, upon Commit: the staging file is
+// fsync'ed, closed and renamed into the destination file, atomically replacing it if it exists, or
+// handed over to a finalize callback (see NewStagedFileFunc).
+type StagedFile struct {
+	f        *os.File
+	path     string
+	finalize func(stagingPath string) error
+	done     bool
+	err      error // the error of Commit or Abort, if any.
+}
+
+// NewStagedFile creates a StagedFile for the destination file at path.
+func NewStagedFile(path string) (*StagedFile, error) {
+	return newStagedFile(path, nil)
+}
+
+// NewStagedFileFunc creates a StagedFile whose Commit calls finalize with the path of the staging
+// file, fsync'ed and closed, instead of renaming it, e.g. to upload it to an object store. The staging
+// file is created in the same directory as path, and is removed once finalize returns, so finalize
+// must move it away if it's to be kept. If finalize fails, Commit fails with its error.
+func NewStagedFileFunc(path string, finalize func(stagingPath string) error) (*StagedFile, error) {
+	if finalize == nil {
+		return nil, errors.New("finalize is nil")
+	}
+	return newStagedFile(path, finalize)
+}
+
+func newStagedFile(path string, finalize func(string) error) (*StagedFile, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	// the staging file is hidden, so it isn't picked up by whatever watches the directory.
+	f, err := os.CreateTemp(dir, "."+name+".staging-*")
+	if err != nil {
+		return nil, err
+	}
+	return &StagedFile{f: f, path: path, finalize: finalize}, nil
+}
+
+// StagingPath returns the path of the staging file.
+func (s *StagedFile) StagingPath() string {
+	return s.f.Name()
+}
+
+// Write implements io.Writer, writing into the staging file.
+func (s *StagedFile) Write(p []byte) (int, error) {
+	if s.done {
+		return 0, ErrSinkClosed
+	}
+	return s.f.Write(p)
+}
+
+// Commit fsyncs and closes the staging file, and renames it into the destination file, or hands it
+// over to the finalize callback. If anything fails, the staging file is removed and the destination
+// file is left untouched.
+func (s *StagedFile) Commit() error {
+	if s.done {
+		return s.err
+	}
+	s.done = true
+	s.err = s.commit()
+	return s.err
+}
+
+func (s *StagedFile) commit() error {
+	stagingPath := s.f.Name()
+	defer os.Remove(stagingPath) // a no-op once renamed.
+	// temporary files are created owner only, while the destination file is meant to be shared.
+	if err := s.f.Chmod(stagedFileMode); err != nil {
+		_ = s.f.Close()
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		_ = s.f.Close()
+		return err
+	}
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	if s.finalize != nil {
+		return s.finalize(stagingPath)
+	}
+	if err := os.Rename(stagingPath, s.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(s.path))
+}
+
+// syncDir fsyncs a directory, so a rename in it survives a crash. Not all platforms support it, e.g.
+// Windows, in which case the error is ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	return nil
+}
+
+// Abort closes and removes the staging file, leaving the destination file untouched.
+func (s *StagedFile) Abort() error {
+	if s.done {
+		return s.err
+	}
+	s.done = true
+	_ = s.f.Close()
+	if err := os.Remove(s.f.Name()); err != nil && !os.IsNotExist(err) {
+		s.err = err
+	}
+	return s.err
+}
+
+// RecordReader reads transformed records, e.g. an omniparser.Transform.
+type RecordReader interface {
+	Read() ([]byte, error)
+}
+
+// RecordWriter writes transformed records, e.g. a DelimitedWriter.
+type RecordWriter interface {
+	Write(record []byte) error
+	// Flush writes any buffered data into the underlying io.Writer.
+	Flush() error
+}
+
+// JSONLinesWriter is a RecordWriter writing transformed JSON records as JSON lines, i.e. each one
+// followed by a '\n'.
+type JSONLinesWriter struct {
+	w *bufio.Writer
+}
+
+// NewJSONLinesWriter creates a JSONLinesWriter that writes into w.
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	return &JSONLinesWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a transformed JSON record as a line.
+func (jw *JSONLinesWriter) Write(record []byte) error {
+	if _, err := jw.w.Write(record); err != nil {
+		return err
+	}
+	return jw.w.WriteByte('\n')
+}
+
+// Flush writes any buffered data into the underlying io.Writer.
+func (jw *JSONLinesWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+// Deliver reads the records of r till io.EOF, writing each one with w, which writes into sink, then
+// flushes w, or closes it if it's an io.Closer, e.g. a JSONArrayWriter, and commits sink. Records
+// failing with continuable errors, i.e. errs.ErrTransformFailed, are skipped; upon any other error,
+// including the ones of w, sink is aborted, so nothing is delivered, and the error is returned. For a
+// failed record to abort the delivery too, use a Transform of a schema with
+// 'parser_settings.validate_first' set, or one wrapped by omniparser.WithErrorBudget.
+func Deliver(r RecordReader, w RecordWriter, sink Sink) error {
+	err := func() error {
+		for {
+			record, err := r.Read()
+			switch {
+			case err == io.EOF:
+				if c, ok := w.(io.Closer); ok {
+					return c.Close()
+				}
+				return w.Flush()
+			case errs.IsErrTransformFailed(err):
+				continue
+			case err != nil:
+				return err
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+	}()
+	if err != nil {
+		_ = sink.Abort()
+		return err
+	}
+	return sink.Commit()
+}
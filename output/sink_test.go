@@ -0,0 +1,196 @@
+package output
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+)
+
+func TestStagedFile_Commit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("old"), 0644))
+	s, err := NewStagedFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, dir, filepath.Dir(s.StagingPath()))
+	assert.True(t, strings.HasPrefix(filepath.Base(s.StagingPath()), ".out.csv.staging-"))
+	_, err = s.Write([]byte("new"))
+	assert.NoError(t, err)
+	// the destination file is untouched till committed.
+	b, _ := os.ReadFile(path)
+	assert.Equal(t, "old", string(b))
+	assert.NoError(t, s.Commit())
+	b, _ = os.ReadFile(path)
+	assert.Equal(t, "new", string(b))
+	if runtime.GOOS != "windows" {
+		fi, _ := os.Stat(path)
+		assert.Equal(t, os.FileMode(stagedFileMode), fi.Mode().Perm())
+	}
+	_, err = os.Stat(s.StagingPath())
+	assert.True(t, os.IsNotExist(err))
+	// once committed.
+	_, err = s.Write([]byte("more"))
+	assert.Equal(t, ErrSinkClosed, err)
+	assert.NoError(t, s.Commit())
+	assert.NoError(t, s.Abort())
+	b, _ = os.ReadFile(path)
+	assert.Equal(t, "new", string(b))
+}
+
+func TestStagedFile_Abort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+	s, err := NewStagedFile(path)
+	assert.NoError(t, err)
+	_, err = s.Write([]byte("new"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Abort())
+	assert.NoError(t, s.Abort())
+	assert.NoError(t, s.Commit())
+	_, err = s.Write([]byte("more"))
+	assert.Equal(t, ErrSinkClosed, err)
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestStagedFile_Finalize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	var finalized string
+	s, err := NewStagedFileFunc(path, func(stagingPath string) error {
+		b, err := os.ReadFile(stagingPath)
+		finalized = string(b)
+		return err
+	})
+	assert.NoError(t, err)
+	_, err = s.Write([]byte("new"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Commit())
+	assert.Equal(t, "new", finalized)
+	// the staging file is removed, and the destination file isn't created.
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	s, err = NewStagedFileFunc(path, func(string) error { return errors.New("upload failure") })
+	assert.NoError(t, err)
+	assert.Equal(t, "upload failure", s.Commit().Error())
+	assert.Equal(t, "upload failure", s.Commit().Error())
+	entries, _ = os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	s, err = NewStagedFileFunc(path, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "finalize is nil", err.Error())
+	assert.Nil(t, s)
+}
+
+func TestNewStagedFile_Failure(t *testing.T) {
+	s, err := NewStagedFile(filepath.Join(t.TempDir(), "not-exist", "out.csv"))
+	assert.Error(t, err)
+	assert.Nil(t, s)
+}
+
+type testRecordReader struct {
+	records []string
+	errs    []error
+}
+
+func (r *testRecordReader) Read() ([]byte, error) {
+	if len(r.records) == 0 {
+		return nil, io.EOF
+	}
+	record, err := r.records[0], r.errs[0]
+	r.records, r.errs = r.records[1:], r.errs[1:]
+	if err != nil {
+		return nil, err
+	}
+	return []byte(record), nil
+}
+
+type failingRecordWriter struct{}
+
+func (failingRecordWriter) Write([]byte) error { return errors.New("write failure") }
+
+func (failingRecordWriter) Flush() error { return nil }
+
+func TestDeliver(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		records  []string
+		errs     []error
+		writer   func(s Sink) RecordWriter
+		expected string
+		err      string
+	}{
+		{
+			name:     "json lines",
+			records:  []string{`{"a":1}`, "", `{"a":2}`},
+			errs:     []error{nil, errs.ErrTransformFailed("skipped"), nil},
+			writer:   func(s Sink) RecordWriter { return NewJSONLinesWriter(s) },
+			expected: "{\"a\":1}\n{\"a\":2}\n",
+		},
+		{
+			name:     "json array",
+			records:  []string{`{"a":1}`, "", `{"a":2}`},
+			errs:     []error{nil, errs.ErrTransformFailed("skipped"), nil},
+			writer:   func(s Sink) RecordWriter { return NewJSONArrayWriter(s) },
+			expected: `[{"a":1},{"a":2}]`,
+		},
+		{
+			name:     "empty json array",
+			writer:   func(s Sink) RecordWriter { return NewJSONArrayWriter(s) },
+			expected: `[]`,
+		},
+		{
+			name:    "delimited",
+			records: testRecords,
+			errs:    []error{nil, nil},
+			writer: func(s Sink) RecordWriter {
+				profiles, _ := LoadProfiles(strings.NewReader(testProfiles))
+				w, _ := NewDelimitedWriter(s, profiles, nil)
+				return w
+			},
+			expected: "ID,Name,City,Date\n1,\"Smith, John\",Seattle,2020-12-25\n2,\"say \"\"hi\"\"\",,2021-01-02\n",
+		},
+		{
+			name:    "fatal error",
+			records: []string{`{"a":1}`, ""},
+			errs:    []error{nil, errors.New("fatal error")},
+			writer:  func(s Sink) RecordWriter { return NewJSONLinesWriter(s) },
+			err:     "fatal error",
+		},
+		{
+			name:    "write failure",
+			records: []string{`{"a":1}`},
+			errs:    []error{nil},
+			writer:  func(Sink) RecordWriter { return failingRecordWriter{} },
+			err:     "write failure",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out")
+			s, err := NewStagedFile(path)
+			assert.NoError(t, err)
+			err = Deliver(&testRecordReader{records: test.records, errs: test.errs}, test.writer(s), s)
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				entries, _ := os.ReadDir(dir)
+				assert.Empty(t, entries)
+				return
+			}
+			assert.NoError(t, err)
+			b, _ := os.ReadFile(path)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
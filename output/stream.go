@@ -0,0 +1,222 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/logward/omniparser/errs"
+)
+
+const (
+	// StreamSSE streams the records as Server-Sent Events, i.e. a `text/event-stream` response.
+	StreamSSE = "sse"
+	// StreamNDJSON streams the records as newline-delimited JSON, i.e. an `application/x-ndjson`
+	// chunked response.
+	StreamNDJSON = "ndjson"
+)
+
+// DefaultStreamHeartbeat is the heartbeat interval of Stream if StreamOptions.Heartbeat isn't set.
+const DefaultStreamHeartbeat = 15 * time.Second
+
+// StreamOptions configures Stream.
+type StreamOptions struct {
+	// Format is StreamSSE, the default, or StreamNDJSON.
+	Format string
+	// Heartbeat is the interval of the heartbeats, which keep the response alive, e.g. through proxies
+	// timing idle connections out, while records take long to come. Default DefaultStreamHeartbeat; a
+	// negative interval disables them.
+	Heartbeat time.Duration
+}
+
+// ErrStreamNotFlushable is returned by Stream if the http.ResponseWriter isn't an http.Flusher, i.e.
+// can't stream.
+var ErrStreamNotFlushable = errors.New("response writer doesn't support flushing")
+
+type readResult struct {
+	record []byte
+	err    error
+}
+
+// Stream reads the records of r till io.EOF, streaming them into the response w as they come, each
+// flushed right away, e.g. for a UI to show the records of an uploaded file as it's being transformed.
+// Besides the records, the response has events for the errors, the heartbeats and the end:
+//
+// With StreamSSE, a record is a `record` event whose id is its 1-based number and whose data is the
+// record, an error is an `error` event whose data is `{"error":"...","continuable":true|false}`, a
+// heartbeat is a `: heartbeat` comment, and the end is an `end` event whose data is
+// `{"records":<n>,"errors":<n>}`.
+//
+// With StreamNDJSON, each line is a JSON object: `{"record":{...}}`, `{"error":"...","continuable":
+// true|false}`, `{"heartbeat":true}` or `{"end":true,"records":<n>,"errors":<n>}`.
+//
+// Continuable errors, i.e. errs.ErrTransformFailed, are streamed and skipped. Upon any other error of r,
+// or a record that isn't valid JSON, the error is streamed as a non-continuable one, with no end event,
+// and returned. Stream stops and returns
+// the error upon a failing write, e.g. the client having gone, or ctx being done, e.g. the context of
+// the http.Request; the pending Read call of r, if any, still completes in the background, so r must
+// not be used anymore.
+func Stream(ctx context.Context, w http.ResponseWriter, r RecordReader, opts StreamOptions) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrStreamNotFlushable
+	}
+	s := &streamer{w: w, flusher: flusher}
+	switch opts.Format {
+	case "", StreamSSE:
+		s.sse = true
+		w.Header().Set("Content-Type", "text/event-stream")
+	case StreamNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		return fmt.Errorf("unknown stream format '%s'", opts.Format)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	// asks reverse proxies, e.g. nginx, not to buffer the response.
+	w.Header().Set("X-Accel-Buffering", "no")
+	// sends the headers right away, for the client to know the stream is on even if the first record
+	// takes a while.
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	interval := opts.Heartbeat
+	if interval == 0 {
+		interval = DefaultStreamHeartbeat
+	}
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	// each Read call is made in a goroutine of its own, so heartbeats go on while it blocks, and ctx
+	// being done is noticed. The channel is buffered, so the goroutine never blocks once Stream has
+	// returned.
+	results := make(chan readResult, 1)
+	read := func() {
+		go func() {
+			record, err := r.Read()
+			results <- readResult{record: record, err: err}
+		}()
+	}
+	read()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-heartbeat:
+			if err := s.heartbeat(); err != nil {
+				return err
+			}
+		case res := <-results:
+			switch {
+			case res.err == io.EOF:
+				return s.end()
+			case errs.IsErrTransformFailed(res.err):
+				if err := s.error(res.err, true); err != nil {
+					return err
+				}
+			case res.err != nil:
+				if err := s.error(res.err, false); err != nil {
+					return err
+				}
+				return res.err
+			default:
+				if err := s.record(res.record); err != nil {
+					return err
+				}
+			}
+			read()
+		}
+	}
+}
+
+type streamer struct {
+	w       io.Writer
+	flusher http.Flusher
+	sse     bool
+	records int
+	errors  int
+	buf     bytes.Buffer
+}
+
+func (s *streamer) record(record []byte) error {
+	s.buf.Reset()
+	s.records++
+	if s.sse {
+		s.buf.WriteString("event: record\nid: " + strconv.Itoa(s.records) + "\ndata: ")
+	} else {
+		s.buf.WriteString(`{"record":`)
+	}
+	// compacted, so the record is on a single line.
+	if err := json.Compact(&s.buf, record); err != nil {
+		s.records--
+		err = fmt.Errorf("record %d is invalid JSON: %s", s.records+1, err.Error())
+		if writeErr := s.error(err, false); writeErr != nil {
+			return writeErr
+		}
+		return err
+	}
+	if s.sse {
+		s.buf.WriteString("\n\n")
+	} else {
+		s.buf.WriteString("}\n")
+	}
+	return s.flush()
+}
+
+func (s *streamer) error(err error, continuable bool) error {
+	s.errors++
+	b, _ := json.Marshal(struct {
+		Error       string `json:"error"`
+		Continuable bool   `json:"continuable"`
+	}{err.Error(), continuable})
+	return s.event("error", b)
+}
+
+func (s *streamer) heartbeat() error {
+	s.buf.Reset()
+	if s.sse {
+		s.buf.WriteString(": heartbeat\n\n")
+	} else {
+		s.buf.WriteString(`{"heartbeat":true}` + "\n")
+	}
+	return s.flush()
+}
+
+func (s *streamer) end() error {
+	b, _ := json.Marshal(struct {
+		End     bool `json:"end,omitempty"`
+		Records int  `json:"records"`
+		Errors  int  `json:"errors"`
+	}{!s.sse, s.records, s.errors})
+	return s.event("end", b)
+}
+
+// event writes an SSE event of the given name and data, or, with StreamNDJSON, the data, a JSON object,
+// as a line.
+func (s *streamer) event(name string, data []byte) error {
+	s.buf.Reset()
+	if s.sse {
+		s.buf.WriteString("event: " + name + "\ndata: ")
+		s.buf.Write(data)
+		s.buf.WriteString("\n\n")
+	} else {
+		s.buf.Write(data)
+		s.buf.WriteByte('\n')
+	}
+	return s.flush()
+}
+
+func (s *streamer) flush() error {
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,240 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+)
+
+func TestStream(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		opts        StreamOptions
+		records     []string
+		errs        []error
+		contentType string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:        "sse",
+			records:     []string{"{\n  \"a\": 1\n}", "", `{"a":3}`},
+			errs:        []error{nil, errs.ErrTransformFailed("bad record"), nil},
+			contentType: "text/event-stream",
+			expected: "event: record\nid: 1\ndata: {\"a\":1}\n\n" +
+				"event: error\ndata: {\"error\":\"bad record\",\"continuable\":true}\n\n" +
+				"event: record\nid: 2\ndata: {\"a\":3}\n\n" +
+				"event: end\ndata: {\"records\":2,\"errors\":1}\n\n",
+		},
+		{
+			name:        "ndjson",
+			opts:        StreamOptions{Format: StreamNDJSON},
+			records:     []string{"{\n  \"a\": 1\n}", "", `{"a":3}`},
+			errs:        []error{nil, errs.ErrTransformFailed("bad record"), nil},
+			contentType: "application/x-ndjson",
+			expected: "{\"record\":{\"a\":1}}\n" +
+				"{\"error\":\"bad record\",\"continuable\":true}\n" +
+				"{\"record\":{\"a\":3}}\n" +
+				"{\"end\":true,\"records\":2,\"errors\":1}\n",
+		},
+		{
+			name:        "no records",
+			contentType: "text/event-stream",
+			expected:    "event: end\ndata: {\"records\":0,\"errors\":0}\n\n",
+		},
+		{
+			name:        "fatal error",
+			records:     []string{`{"a":1}`, "", `{"a":3}`},
+			errs:        []error{nil, errors.New("corrupted input"), nil},
+			contentType: "text/event-stream",
+			expected: "event: record\nid: 1\ndata: {\"a\":1}\n\n" +
+				"event: error\ndata: {\"error\":\"corrupted input\",\"continuable\":false}\n\n",
+			expectedErr: "corrupted input",
+		},
+		{
+			name:        "invalid record",
+			opts:        StreamOptions{Format: StreamNDJSON},
+			records:     []string{`{"a":1}`, `{"a":`, `{"a":3}`},
+			errs:        []error{nil, nil, nil},
+			contentType: "application/x-ndjson",
+			expected: "{\"record\":{\"a\":1}}\n" +
+				"{\"error\":\"record 2 is invalid JSON: unexpected end of JSON input\",\"continuable\":false}\n",
+			expectedErr: "record 2 is invalid JSON: unexpected end of JSON input",
+		},
+		{
+			name:        "empty record",
+			records:     []string{""},
+			errs:        []error{nil},
+			contentType: "text/event-stream",
+			expected: "event: error\n" +
+				"data: {\"error\":\"record 1 is invalid JSON: unexpected end of JSON input\",\"continuable\":false}\n\n",
+			expectedErr: "record 1 is invalid JSON: unexpected end of JSON input",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := Stream(context.Background(), w, &testRecordReader{records: test.records, errs: test.errs}, test.opts)
+			if test.expectedErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.expectedErr, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.contentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			assert.Equal(t, "no", w.Header().Get("X-Accel-Buffering"))
+			assert.True(t, w.Flushed)
+			assert.Equal(t, test.expected, w.Body.String())
+		})
+	}
+}
+
+func TestStream_UnknownFormat(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Stream(context.Background(), w, &testRecordReader{}, StreamOptions{Format: "csv"})
+	assert.Error(t, err)
+	assert.Equal(t, "unknown stream format 'csv'", err.Error())
+	assert.False(t, w.Flushed)
+}
+
+type nonFlushableWriter struct {
+	http.ResponseWriter
+}
+
+func TestStream_NotFlushable(t *testing.T) {
+	err := Stream(context.Background(), nonFlushableWriter{httptest.NewRecorder()}, &testRecordReader{}, StreamOptions{})
+	assert.Equal(t, ErrStreamNotFlushable, err)
+}
+
+// slowRecordReader returns a record once released, then io.EOF.
+type slowRecordReader struct {
+	release chan struct{}
+	done    bool
+}
+
+func (r *slowRecordReader) Read() ([]byte, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	<-r.release
+	r.done = true
+	return []byte(`{"a":1}`), nil
+}
+
+// heartbeatRecorder is an httptest.ResponseRecorder releasing a slowRecordReader once heartbeats have
+// been written.
+type heartbeatRecorder struct {
+	*httptest.ResponseRecorder
+	r          *slowRecordReader
+	heartbeats int
+}
+
+func (w *heartbeatRecorder) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), "heartbeat") {
+		if w.heartbeats++; w.heartbeats == 2 {
+			close(w.r.release)
+		}
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestStream_Heartbeat(t *testing.T) {
+	for _, test := range []struct {
+		format    string
+		heartbeat string
+		expected  string
+	}{
+		{
+			format:    StreamSSE,
+			heartbeat: ": heartbeat\n\n",
+			expected: "event: record\nid: 1\ndata: {\"a\":1}\n\n" +
+				"event: end\ndata: {\"records\":1,\"errors\":0}\n\n",
+		},
+		{
+			format:    StreamNDJSON,
+			heartbeat: "{\"heartbeat\":true}\n",
+			expected: "{\"record\":{\"a\":1}}\n" +
+				"{\"end\":true,\"records\":1,\"errors\":0}\n",
+		},
+	} {
+		t.Run(test.format, func(t *testing.T) {
+			r := &slowRecordReader{release: make(chan struct{})}
+			w := &heartbeatRecorder{ResponseRecorder: httptest.NewRecorder(), r: r}
+			err := Stream(context.Background(), w, r, StreamOptions{Format: test.format, Heartbeat: time.Millisecond})
+			assert.NoError(t, err)
+			// more heartbeats may be written while the record is on its way.
+			body := w.Body.String()
+			assert.True(t, strings.HasPrefix(body, test.heartbeat+test.heartbeat))
+			assert.Equal(t, test.expected, strings.ReplaceAll(body, test.heartbeat, ""))
+		})
+	}
+}
+
+func TestStream_ContextDone(t *testing.T) {
+	r := &slowRecordReader{release: make(chan struct{})}
+	defer close(r.release)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	err := Stream(ctx, w, r, StreamOptions{Heartbeat: -1})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, "", w.Body.String())
+}
+
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingResponseWriter) Write([]byte) (int, error) { return 0, errors.New("write failure") }
+
+func TestStream_WriteFailure(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		r    RecordReader
+		opts StreamOptions
+	}{
+		{
+			name: "record",
+			r:    &testRecordReader{records: []string{`{"a":1}`}, errs: []error{nil}},
+		},
+		{
+			name: "error",
+			r:    &testRecordReader{records: []string{""}, errs: []error{errs.ErrTransformFailed("bad record")}},
+		},
+		{
+			name: "fatal error",
+			r:    &testRecordReader{records: []string{""}, errs: []error{errors.New("corrupted input")}},
+		},
+		{
+			name: "invalid record",
+			r:    &testRecordReader{records: []string{`{"a":`}, errs: []error{nil}},
+		},
+		{
+			name: "end",
+			r:    &testRecordReader{},
+		},
+		{
+			name: "heartbeat",
+			r:    &slowRecordReader{release: make(chan struct{})},
+			opts: StreamOptions{Heartbeat: time.Millisecond},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := Stream(context.Background(), failingResponseWriter{httptest.NewRecorder()}, test.r, test.opts)
+			assert.Error(t, err)
+			assert.Equal(t, "write failure", err.Error())
+			if r, ok := test.r.(*slowRecordReader); ok {
+				close(r.release)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package omniparser
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// PreviewRecord is a record read by Previewer.Preview, along with where it comes from in the input.
+type PreviewRecord struct {
+	// Number is the 1-based number of the Read call of the Transform the record is read by.
+	Number int `json:"number"`
+	// Output is the transformed record, unless it failed to transform.
+	Output json.RawMessage `json:"output,omitempty"`
+	// Error is the continuable error the record failed with, if any, in which case none of the fields
+	// below is set.
+	Error string `json:"error,omitempty"`
+	// Source is the JSON view of the raw record, e.g. of its IDR, if the schema handler supports it
+	// (see schemahandler.SourcePreviewer).
+	Source json.RawMessage `json:"source,omitempty"`
+	// Context is the JSON view of the data around the raw record in the input, e.g. of the EDI
+	// envelopes or the file header it's in, if any and supported by the schema handler. It's omitted
+	// if the same as the one of the previous record of the Preview.
+	Context json.RawMessage `json:"context,omitempty"`
+	// Raw is the raw bytes of the record as read from the input, if supported by the schema handler
+	// (see schemahandler.RawBytesRecord).
+	Raw string `json:"raw,omitempty"`
+}
+
+// Preview is the records read by Previewer.Preview.
+type Preview struct {
+	Records []PreviewRecord `json:"records"`
+	// EOF tells if the input has been read completely.
+	EOF bool `json:"eof"`
+}
+
+// Preview implements Previewer, reading up to n records, as Read does, and returning them along with
+// where they come from in the input, e.g. for the preview panes of mapping UIs, without reading the
+// whole input. The records that fail to transform are included with their errors. On a fatal error, the
+// records read so far are returned along with the error.
+func (o *transform) Preview(n int) (*Preview, error) {
+	preview := &Preview{Records: []PreviewRecord{}}
+	var lastContext string
+	for len(preview.Records) < n {
+		output, err := o.Read()
+		switch {
+		case err == io.EOF:
+			preview.EOF = true
+			return preview, nil
+		case errs.IsErrTransformFailed(err):
+			preview.Records = append(preview.Records, PreviewRecord{Number: o.seq, Error: err.Error()})
+			continue
+		case err != nil:
+			return preview, err
+		}
+		record := PreviewRecord{Number: o.seq, Output: output}
+		if previewer, ok := o.lastRawRecord.(schemahandler.SourcePreviewer); ok {
+			record.Source = json.RawMessage(previewer.SourceJSON())
+			if context := previewer.ContextJSON(); context != "" && context != lastContext {
+				record.Context = json.RawMessage(context)
+				lastContext = context
+			}
+		}
+		if rb, ok := o.lastRawRecord.(schemahandler.RawBytesRecord); ok {
+			record.Raw = string(rb.RawBytes())
+		}
+		preview.Records = append(preview.Records, record)
+	}
+	return preview, nil
+}
@@ -0,0 +1,51 @@
+package omniparser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bradleyjkemp/cupaloy"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/transformctx"
+)
+
+func TestTransform_Preview(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"c": { "xpath": "c", "type": "int" },
+				"h": { "xpath": "../h" }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	newTransform := func() Transform {
+		tfm, err := s.NewTransform("test-input",
+			strings.NewReader(`<a><h>header</h><b><c>1</c></b><b><c>x</c></b><b><c>3</c></b></a>`),
+			&transformctx.Ctx{})
+		assert.NoError(t, err)
+		return tfm
+	}
+
+	preview, err := newTransform().(Previewer).Preview(2)
+	assert.NoError(t, err)
+	b, err := json.MarshalIndent(preview, "", "\t")
+	assert.NoError(t, err)
+	cupaloy.SnapshotT(t, string(b))
+
+	tfm := newTransform()
+	preview, err = tfm.(Previewer).Preview(10)
+	assert.NoError(t, err)
+	assert.True(t, preview.EOF)
+	assert.Equal(t, 3, len(preview.Records))
+	assert.Equal(t, `{"c":3,"h":"header"}`, string(preview.Records[2].Output))
+	// the context is the same as the first record's.
+	assert.Nil(t, preview.Records[2].Context)
+
+	preview, err = tfm.(Previewer).Preview(10)
+	assert.NoError(t, err)
+	assert.Equal(t, &Preview{Records: []PreviewRecord{}, EOF: true}, preview)
+}
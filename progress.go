@@ -0,0 +1,83 @@
+package omniparser
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// noProgressDumpBytes is the max number of input bytes dumped in an ErrNoProgress.
+const noProgressDumpBytes = 256
+
+// ErrNoProgress is returned by a Transform once a Read call has read more input bytes than
+// transformctx.Ctx.MaxRecordBytes without finding a record. This is a fatal error: future calls to
+// Read will always return the same error.
+type ErrNoProgress struct {
+	// Offset is the 0-based offset in the input of the first byte read by the Read call.
+	Offset int64
+	// Bytes is the number of input bytes read by the Read call.
+	Bytes int64
+	// Dump is the hex dump, as per hex.Dump, of the first bytes read by the Read call.
+	Dump string
+}
+
+// Error implements the error interface.
+func (e ErrNoProgress) Error() string {
+	return fmt.Sprintf(
+		"no record found within %d bytes from input offset %d, check the delimiters and line endings "+
+			"of the schema against the input; input from offset %d:\n%s",
+		e.Bytes, e.Offset, e.Offset, e.Dump)
+}
+
+// IsErrNoProgress tells if an error is of ErrNoProgress.
+func IsErrNoProgress(err error) bool {
+	_, ok := err.(ErrNoProgress)
+	return ok
+}
+
+// progressWatchdog wraps the input of a Transform, and fails the reads once more than limit bytes
+// have been read since the Transform last made progress, i.e. returned from a Read call.
+type progressWatchdog struct {
+	r      io.Reader
+	limit  int64
+	armed  bool   // false while the ingester is being created.
+	offset int64  // input offset of the first byte read since the last progress.
+	read   int64  // number of bytes read since the last progress.
+	head   []byte // first bytes read since the last progress, for the dump.
+	err    error  // ErrNoProgress, once the limit is exceeded.
+}
+
+func (w *progressWatchdog) Read(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.r.Read(p)
+	if keep := noProgressDumpBytes - len(w.head); keep > 0 {
+		if keep > n {
+			keep = n
+		}
+		w.head = append(w.head, p[:keep]...)
+	}
+	if w.read += int64(n); w.armed && w.read > w.limit {
+		w.err = ErrNoProgress{Offset: w.offset, Bytes: w.read, Dump: hex.Dump(w.head)}
+		return 0, w.err
+	}
+	return n, err
+}
+
+// arm starts enforcing the limit, once the ingester is created. The input read while creating the
+// ingester, e.g. read ahead into its buffers, counts towards the first record, unless it exceeds the
+// limit: the ingester then reads the whole input upfront, e.g. for an `input_sort`.
+func (w *progressWatchdog) arm() {
+	w.armed = true
+	if w.read > w.limit {
+		w.progress()
+	}
+}
+
+// progress tells the Transform has made progress.
+func (w *progressWatchdog) progress() {
+	w.offset += w.read
+	w.read = 0
+	w.head = w.head[:0]
+}
@@ -0,0 +1,64 @@
+package omniparser
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func newProgressTestTransform(t *testing.T, input string, maxRecordBytes int64) Transform {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+		"file_declaration": {
+			"delimiter": ",",
+			"records": [ { "is_target": true, "columns": [ { "name": "A", "index": 1 } ] } ]
+		},
+		"transform_declarations": { "FINAL_OUTPUT": { "object": { "a": { "xpath": "A" } } } }
+	}`))
+	assert.NoError(t, err)
+	tfm, err := s.NewTransform("test-input", strings.NewReader(input),
+		&transformctx.Ctx{MaxRecordBytes: maxRecordBytes})
+	assert.NoError(t, err)
+	return tfm
+}
+
+func TestTransform_MaxRecordBytes(t *testing.T) {
+	// with "\r" line endings, which the schema doesn't expect, the whole input is a single line.
+	input := strings.Repeat("a1\r", 10000)
+	tfm := newProgressTestTransform(t, input, 8192)
+	_, err := tfm.Read()
+	assert.Error(t, err)
+	assert.True(t, IsErrNoProgress(err))
+	assert.False(t, errs.IsErrTransformFailed(err))
+	e := err.(ErrNoProgress)
+	assert.Equal(t, int64(0), e.Offset)
+	assert.True(t, e.Bytes > 8192)
+	assert.Contains(t, err.Error(), "no record found within")
+	assert.Contains(t, err.Error(), "input from offset 0:\n00000000  61 31 0d 61 31 0d")
+	assert.Equal(t, noProgressDumpBytes/16, strings.Count(e.Dump, "\n"))
+	// the fatal error is returned repeatedly.
+	_, err2 := tfm.Read()
+	assert.Equal(t, err, err2)
+	assert.Equal(t, err.Error(), tfm.(SummaryReporter).Summary().Error)
+}
+
+func TestTransform_MaxRecordBytes_Progress(t *testing.T) {
+	// the limit applies to each Read call: the whole input is way larger than it.
+	input := strings.Repeat("a1\n", 10000)
+	tfm := newProgressTestTransform(t, input, 64*1024)
+	records := 0
+	for {
+		_, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records++
+	}
+	assert.Equal(t, 10000, records)
+}
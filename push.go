@@ -0,0 +1,80 @@
+package omniparser
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+// NewPushTransform creates a Transform whose input is pushed by the caller, chunk by chunk, into the
+// returned writer, e.g. from an HTTP request body or a message stream, instead of being pulled from an
+// io.Reader. The input is piped straight into the transform as it parses, without being buffered: each
+// Write blocks until the transform has consumed all of the chunk, so a producer never runs ahead of the
+// consumer of the transform. Consequently, Write and the Read of the Transform must be called on
+// different goroutines.
+//
+// Close the writer once all the input is pushed, so that Read returns io.EOF after the last record; or
+// CloseWithError it to abort the transform, so that Read fails with the error. Once Read fails with a
+// fatal error or returns io.EOF, pending and future Writes fail too, so the producer can stop.
+//
+// The schema handler ingester is created upon the first Read, and any error in doing so, e.g. the
+// input isn't in the schema encoding, is returned by that Read as a fatal error.
+func NewPushTransform(s Schema, name string, ctx *transformctx.Ctx) (Transform, *io.PipeWriter) {
+	if ctx == nil {
+		ctx = &transformctx.Ctx{}
+	}
+	pr, pw := io.Pipe()
+	g := &pushIngester{name: name, ctxAwareErr: ctx.CtxAwareErr, pr: pr, create: func() (Transform, error) {
+		return s.NewTransform(name, pr, ctx)
+	}}
+	return &transform{ingester: g}, pw
+}
+
+// pushIngester adapts the Transform created lazily upon the first Read into an Ingester, so that the
+// Transform returned by NewPushTransform can be created before any input is pushed: creating a
+// Transform right away would block, as it peeks into the input.
+type pushIngester struct {
+	name        string
+	ctxAwareErr errs.CtxAwareErr // the caller's ctx.CtxAwareErr, if any.
+	pr          *io.PipeReader
+	create      func() (Transform, error)
+	t           Transform
+}
+
+func (g *pushIngester) Read() (schemahandler.RawRecord, []byte, error) {
+	if g.t == nil {
+		t, err := g.create()
+		if err != nil {
+			_ = g.pr.CloseWithError(err)
+			return nil, nil, err
+		}
+		g.t = t
+	}
+	transformed, err := g.t.Read()
+	switch {
+	case err == io.EOF:
+		_ = g.pr.Close()
+		return nil, nil, err
+	case err != nil && !errs.IsErrTransformFailed(err):
+		_ = g.pr.CloseWithError(err)
+		return nil, nil, err
+	case err != nil:
+		return nil, nil, err
+	}
+	rawRecord, err := g.t.RawRecord()
+	return rawRecord, transformed, err
+}
+
+func (g *pushIngester) IsContinuableError(err error) bool {
+	return errs.IsErrTransformFailed(err)
+}
+
+func (g *pushIngester) FmtErr(format string, args ...interface{}) error {
+	if g.ctxAwareErr != nil {
+		return g.ctxAwareErr.FmtErr(format, args...)
+	}
+	return fmt.Errorf("input '%s': %s", g.name, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,123 @@
+package omniparser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func newPushTestSchema(t *testing.T) Schema {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml" },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"c": { "custom_func": { "name": "dateTimeToRFC3339", "args": [ { "xpath": "c" }, { "const": "" }, { "const": "" } ] } }
+			} }
+		}
+	}`))
+	assert.NoError(t, err)
+	return s
+}
+
+func TestNewPushTransform(t *testing.T) {
+	ctx := &transformctx.Ctx{}
+	tfm, w := NewPushTransform(newPushTestSchema(t), "test-input", ctx)
+	go func() {
+		for _, chunk := range []string{"<a><b><c>2020-01", "-02</c></b><b><c>", "bad</c></b>", "<b><c>2020-01-03</c></b></a>"} {
+			if _, err := w.Write([]byte(chunk)); err != nil {
+				panic(err)
+			}
+		}
+		_ = w.Close()
+	}()
+	record, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":"2020-01-02T00:00:00"}`, string(record))
+	raw, err := tfm.RawRecord()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, raw.Checksum())
+	assert.Equal(t, "test-input", ctx.InputName)
+
+	record, err = tfm.Read()
+	assert.True(t, errs.IsErrTransformFailed(err))
+	assert.Contains(t, err.Error(), "test-input")
+	assert.Nil(t, record)
+
+	record, err = tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":"2020-01-03T00:00:00"}`, string(record))
+
+	record, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, record)
+	record, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, record)
+	_, err = w.Write([]byte("<a/>"))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
+
+func TestNewPushTransform_CloseWithError(t *testing.T) {
+	tfm, w := NewPushTransform(newPushTestSchema(t), "test-input", &transformctx.Ctx{})
+	go func() {
+		_, _ = w.Write([]byte("<a><b><c>2020-01-02</c></b>"))
+		_ = w.CloseWithError(errors.New("connection reset"))
+	}()
+	record, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":"2020-01-02T00:00:00"}`, string(record))
+	record, err = tfm.Read()
+	assert.Error(t, err)
+	assert.False(t, errs.IsErrTransformFailed(err))
+	assert.Contains(t, err.Error(), "connection reset")
+	assert.Nil(t, record)
+}
+
+func TestNewPushTransform_CreateFailure(t *testing.T) {
+	s, err := NewSchema("test-schema",
+		strings.NewReader(`{"parser_settings": {"version": "9999", "file_format_type": "exe" }}`),
+		Extension{
+			CreateSchemaHandler: func(_ *schemahandler.CreateCtx) (schemahandler.SchemaHandler, error) {
+				return testSchemaHandler{newIngesterErr: errors.New("ingester failure")}, nil
+			},
+		})
+	assert.NoError(t, err)
+	tfm, w := NewPushTransform(s, "test-input", &transformctx.Ctx{})
+	go func() {
+		_, _ = w.Write([]byte("data"))
+	}()
+	record, err := tfm.Read()
+	assert.Equal(t, "ingester failure", err.Error())
+	assert.Nil(t, record)
+	// the producer gets the same error.
+	_, err = w.Write([]byte("more data"))
+	assert.Equal(t, "ingester failure", err.Error())
+}
+
+func TestNewPushTransform_NilCtx(t *testing.T) {
+	tfm, w := NewPushTransform(newPushTestSchema(t), "test-input", nil)
+	go func() {
+		_, _ = w.Write([]byte("<a><b><c>2020-01-02</c></b></a>"))
+		_ = w.Close()
+	}()
+	record, err := tfm.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"c":"2020-01-02T00:00:00"}`, string(record))
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "input 'test-input': test", tfm.(*transform).ingester.FmtErr("test").Error())
+}
+
+func TestNewPushTransform_CtxAwareErr(t *testing.T) {
+	tfm, _ := NewPushTransform(newPushTestSchema(t), "test-input", &transformctx.Ctx{
+		CtxAwareErr: &testIngester{},
+	})
+	assert.Equal(t, "ctx formatted: test", tfm.(*transform).ingester.FmtErr("test").Error())
+}
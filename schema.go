@@ -1,6 +1,7 @@
 package omniparser
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"github.com/logward/omniparser/extensions/omniv21"
 	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
 	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/input"
 	"github.com/logward/omniparser/schemahandler"
 	"github.com/logward/omniparser/transformctx"
 	"github.com/logward/omniparser/validation"
@@ -79,6 +81,9 @@ func NewSchema(name string, schemaReader io.Reader, exts ...Extension) (Schema,
 	var h header.Header
 	// parser_settings has just been json schema validated. so unmarshaling will not go wrong.
 	_ = json.Unmarshal(content, &h)
+	if err := h.Validate(); err != nil {
+		return nil, fmt.Errorf("schema '%s' validation failed: %s", name, err.Error())
+	}
 
 	allExts := append([]Extension(nil), exts...)
 	allExts = append(allExts, defaultExt)
@@ -110,26 +115,77 @@ func NewSchema(name string, schemaReader io.Reader, exts ...Extension) (Schema,
 	return nil, errs.ErrSchemaNotSupported
 }
 
-// NewTransform creates and returns an instance of Transform for a given input stream.
+// NewTransform creates and returns an instance of Transform for a given input stream. A nil ctx is
+// the same as an empty one.
 func (s *schema) NewTransform(name string, input io.Reader, ctx *transformctx.Ctx) (Transform, error) {
-	br, err := ios.StripBOM(s.header.ParserSettings.WrapEncoding(input))
+	if ctx == nil {
+		ctx = &transformctx.Ctx{}
+	}
+	var watchdog *progressWatchdog
+	if ctx.MaxRecordBytes > 0 {
+		// wraps the raw input, so the offsets and the dump of an ErrNoProgress are the ones of the input
+		// as is, before any decoding.
+		watchdog = &progressWatchdog{r: input, limit: ctx.MaxRecordBytes}
+		input = watchdog
+	}
+	br, err := s.wrapInput(input)
 	if err != nil {
 		return nil, err
 	}
+	var dumper *errorDumper
+	if size := s.header.ParserSettings.ErrorDumpBytes; size > 0 {
+		dumper, br = newErrorDumper(br, size)
+	}
 	if ctx.InputName != name {
 		ctx.InputName = name
 	}
-	ingester, err := s.handler.NewIngester(ctx, br)
+	// The schema's external property declarations are set on the transform's own copy of ctx, so that
+	// a ctx reused across schemas doesn't carry them over.
+	tctx := *ctx
+	tctx.ExternalPropertyDecls = s.header.ExternalProperties
+	// fails fast, before ingesting anything, if the external properties the schema depends on are
+	// missing or invalid.
+	if err := tctx.ValidateExternalProperties(); err != nil {
+		return nil, fmt.Errorf("input '%s': %s", name, err.Error())
+	}
+	ingester, err := s.handler.NewIngester(&tctx, br)
 	if err != nil {
 		return nil, err
 	}
+	if watchdog != nil {
+		watchdog.arm()
+	}
+	if rewriter, ok := ingester.(schemahandler.InputRewriter); ok && rewriter.InputRewritten() {
+		dumper = nil
+	}
 	// If caller already specified a way to do context aware error formatting, use it;
 	// otherwise (vast majority cases), use the Ingester (which implements CtxAwareErr
 	// interface) created by the schema handler.
 	if ctx.CtxAwareErr == nil {
 		ctx.CtxAwareErr = ingester
 	}
-	return &transform{ingester: ingester}, nil
+	tctx.CtxAwareErr = ctx.CtxAwareErr
+	t := &transform{ingester: ingester, validation: ctx.Validation, watchdog: watchdog, dumper: dumper}
+	if s.header.ParserSettings.ValidateFirst {
+		return &validatedTransform{Transform: t}, nil
+	}
+	return t, nil
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// wrapInput makes sure the input is decoded into UTF-8 with BOM stripped. An in-memory input (e.g.
+// input.MappedFile) that needs no decoding stays in-memory, so readers can slice records directly out
+// of it.
+func (s *schema) wrapInput(in io.Reader) (io.Reader, error) {
+	if m, ok := in.(input.InMemory); ok {
+		if b := m.Bytes(); b != nil {
+			if b, ok := s.header.ParserSettings.UTF8Content(b); ok {
+				return input.NewBytesReader(bytes.TrimPrefix(b, utf8BOM)), nil
+			}
+		}
+	}
+	return ios.StripBOM(s.header.ParserSettings.WrapEncoding(in))
 }
 
 // Header returns the schema header.
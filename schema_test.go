@@ -3,15 +3,19 @@ package omniparser
 import (
 	"errors"
 	"io"
+	"io/ioutil"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/jf-tech/go-corelib/strs"
 	"github.com/jf-tech/go-corelib/testlib"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/header"
+	"github.com/logward/omniparser/input"
 	"github.com/logward/omniparser/schemahandler"
 	"github.com/logward/omniparser/transformctx"
 )
@@ -41,6 +45,24 @@ func TestNewSchema(t *testing.T) {
 			exts:   nil,
 			err:    "schema 'test-schema' validation failed:\nparser_settings: Additional property versionx is not allowed\nparser_settings: version is required",
 		},
+		{
+			name: "external property declared twice",
+			schema: `{
+				"parser_settings": {"version": "9999", "file_format_type": "exe" },
+				"external_properties": [ { "name": "a" }, { "name": "a", "type": "int" } ]
+			}`,
+			exts: nil,
+			err:  "schema 'test-schema' validation failed: external property 'a' is declared more than once",
+		},
+		{
+			name: "external property default not of its type",
+			schema: `{
+				"parser_settings": {"version": "9999", "file_format_type": "exe" },
+				"external_properties": [ { "name": "a", "type": "int", "default": "x" } ]
+			}`,
+			exts: nil,
+			err:  "schema 'test-schema' validation failed: external property 'a' must be of type 'int', but got 'x'",
+		},
 		{
 			name:   "no supported schema handler",
 			schema: `{"parser_settings": {"version": "9999", "file_format_type": "exe" }}`,
@@ -122,11 +144,39 @@ func TestSchema_NewTransform_StripBOMFailure(t *testing.T) {
 	assert.Nil(t, op)
 }
 
+func TestSchema_WrapInput(t *testing.T) {
+	readAll := func(r io.Reader) string {
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		return string(b)
+	}
+	s := &schema{}
+	// in-memory UTF-8 input stays in-memory, with BOM stripped.
+	r, err := s.wrapInput(input.NewBytesReader(append([]byte{0xEF, 0xBB, 0xBF}, "abc"...)))
+	assert.NoError(t, err)
+	assert.IsType(t, &input.BytesReader{}, r)
+	assert.Equal(t, "abc", string(r.(input.InMemory).Bytes()))
+	// in-memory input that needs decoding goes through WrapEncoding.
+	r, err = s.wrapInput(input.NewBytesReader([]byte{0xFF, 0xFE, 'a', 0, 'b', 0}))
+	assert.NoError(t, err)
+	assert.NotEqual(t, reflect.TypeOf(&input.BytesReader{}), reflect.TypeOf(r))
+	assert.Equal(t, "ab", readAll(r))
+	// not in-memory input.
+	r, err = s.wrapInput(strings.NewReader("abc"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, reflect.TypeOf(&input.BytesReader{}), reflect.TypeOf(r))
+	assert.Equal(t, "abc", readAll(r))
+}
+
 type testSchemaHandler struct {
 	newIngesterErr error
+	newIngesterCtx func(ctx *transformctx.Ctx)
 }
 
-func (t testSchemaHandler) NewIngester(_ *transformctx.Ctx, _ io.Reader) (schemahandler.Ingester, error) {
+func (t testSchemaHandler) NewIngester(ctx *transformctx.Ctx, _ io.Reader) (schemahandler.Ingester, error) {
+	if t.newIngesterCtx != nil {
+		t.newIngesterCtx(ctx)
+	}
 	if t.newIngesterErr != nil {
 		return nil, t.newIngesterErr
 	}
@@ -165,3 +215,62 @@ func TestSchema_NewTransform_NameAndCtxAwareErrOverwrite(t *testing.T) {
 	assert.Equal(t, h, s.Header())
 	assert.Equal(t, "test schema content", string(s.Content()))
 }
+
+func TestSchema_NewTransform_ExternalProperties(t *testing.T) {
+	s := &schema{
+		header: header.Header{
+			ParserSettings: header.ParserSettings{Version: "999", FileFormatType: "exe"},
+			ExternalProperties: []transformctx.ExternalPropertyDecl{
+				{Name: "partner_id"},
+				{Name: "batch_size", Type: transformctx.ExternalTypeInt, Default: strs.StrPtr("100")},
+			},
+		},
+		handler: testSchemaHandler{},
+	}
+	transform, err := s.NewTransform("test input", strings.NewReader("something"), &transformctx.Ctx{
+		ExternalProperties: map[string]string{"batch_size": "many"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t,
+		"input 'test input': invalid external properties: external property 'partner_id' is required but not set; "+
+			"external property 'batch_size' must be of type 'int', but got 'many'",
+		err.Error())
+	assert.Nil(t, transform)
+
+	var ingesterCtx *transformctx.Ctx
+	s.handler = testSchemaHandler{newIngesterCtx: func(ctx *transformctx.Ctx) { ingesterCtx = ctx }}
+	ctx := &transformctx.Ctx{ExternalProperties: map[string]string{"partner_id": "ACME"}}
+	transform, err = s.NewTransform("test input", strings.NewReader("something"), ctx)
+	assert.NoError(t, err)
+	assert.NotNil(t, transform)
+	batchSize, err := ingesterCtx.ExternalInt("batch_size")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), batchSize)
+	assert.Same(t, ctx.CtxAwareErr, ingesterCtx.CtxAwareErr)
+	// the caller's ctx doesn't carry the declarations over to the transforms of other schemas.
+	assert.Nil(t, ctx.ExternalPropertyDecls)
+	s2 := &schema{
+		header: header.Header{
+			ParserSettings:     header.ParserSettings{Version: "999", FileFormatType: "exe"},
+			ExternalProperties: []transformctx.ExternalPropertyDecl{{Name: "region", Default: strs.StrPtr("eu")}},
+		},
+		handler: testSchemaHandler{newIngesterCtx: func(ctx *transformctx.Ctx) { ingesterCtx = ctx }},
+	}
+	_, err = s2.NewTransform("test input", strings.NewReader("something"), ctx)
+	assert.NoError(t, err)
+	_, found := ingesterCtx.External("batch_size")
+	assert.False(t, found)
+}
+
+func TestSchema_NewTransform_NilCtx(t *testing.T) {
+	s := &schema{
+		header: header.Header{
+			ParserSettings:     header.ParserSettings{Version: "999", FileFormatType: "exe"},
+			ExternalProperties: []transformctx.ExternalPropertyDecl{{Name: "region", Default: strs.StrPtr("eu")}},
+		},
+		handler: testSchemaHandler{},
+	}
+	transform, err := s.NewTransform("test input", strings.NewReader("something"), nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transform)
+}
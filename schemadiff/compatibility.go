@@ -0,0 +1,64 @@
+package schemadiff
+
+// Compatibility is how compatible the output of a new version of a schema is with the output of the
+// old version, for the consumers of the output.
+type Compatibility string
+
+const (
+	// Compatible is an output of the same shape, i.e. the same fields of the same JSON types. The
+	// values may still differ, e.g. for XPathChanged or MappingChanged.
+	Compatible Compatibility = "compatible"
+	// Additive is an output with more fields, but otherwise of the same shape, which consumers
+	// ignoring unknown fields can take as is.
+	Additive Compatibility = "additive"
+	// Breaking is an output with fields removed, or of other JSON types, or whose JSON types can't be
+	// told statically, e.g. of a `custom_func` without `type`.
+	Breaking Compatibility = "breaking"
+)
+
+// Classification is the Compatibility of the output of a new version of a schema with the output of
+// the old version, and the changes it's made of.
+type Classification struct {
+	Compatibility Compatibility `json:"compatibility"`
+	// Changes are the changes to the output fields making the output Breaking, or, if Additive, the
+	// fields added; empty if Compatible.
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Classify classifies the output shapes, as inferred from the output fields (see
+// transform.OutputField), of the old and new versions of the schema, e.g. to gate the deployment of a
+// new version breaking its consumers.
+func (r *Report) Classify() Classification {
+	var additive, breaking []Change
+	for _, c := range r.Changes {
+		switch c.Kind {
+		case FieldAdded:
+			additive = append(additive, c)
+		case FieldRemoved:
+			breaking = append(breaking, c)
+		case FieldRetyped:
+			if oldType, newType := jsonType(c.Old), jsonType(c.New); oldType == "" || oldType != newType {
+				breaking = append(breaking, c)
+			}
+		}
+	}
+	switch {
+	case len(breaking) > 0:
+		return Classification{Compatibility: Breaking, Changes: breaking}
+	case len(additive) > 0:
+		return Classification{Compatibility: Additive, Changes: additive}
+	default:
+		return Classification{Compatibility: Compatible}
+	}
+}
+
+// jsonType returns the JSON type of the output fields of a type, as per transform.OutputField.Type, or
+// "" if unknown.
+func jsonType(t string) string {
+	switch t {
+	case "int", "float", "number":
+		return "number"
+	default:
+		return t
+	}
+}
@@ -0,0 +1,80 @@
+package schemadiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_Classify(t *testing.T) {
+	added := Change{Kind: FieldAdded, Path: "date", New: "string"}
+	for _, test := range []struct {
+		name     string
+		changes  []Change
+		expected Classification
+	}{
+		{
+			name:     "no changes",
+			expected: Classification{Compatibility: Compatible},
+		},
+		{
+			name: "values changed only",
+			changes: []Change{
+				{Kind: XPathChanged, Path: "a", Old: "A", New: "../A"},
+				{Kind: MappingChanged, Path: "b", Old: `const "b"`, New: `const "B"`},
+				{Kind: ValidationChanged, Path: "file_declaration.records[name=HDR].min", Old: "1", New: "0"},
+				{Kind: SettingChanged, Path: "file_declaration.delimiter", Old: `","`, New: `"|"`},
+			},
+			expected: Classification{Compatibility: Compatible},
+		},
+		{
+			name:     "retyped to the same JSON type",
+			changes:  []Change{{Kind: FieldRetyped, Path: "qty", Old: "int", New: "number"}},
+			expected: Classification{Compatibility: Compatible},
+		},
+		{
+			name:     "added",
+			changes:  []Change{{Kind: XPathChanged, Path: "a", Old: "A", New: "../A"}, added},
+			expected: Classification{Compatibility: Additive, Changes: []Change{added}},
+		},
+		{
+			name: "removed",
+			changes: []Change{
+				{Kind: FieldRemoved, Path: "meta", Old: "object"},
+				added,
+			},
+			expected: Classification{Compatibility: Breaking, Changes: []Change{
+				{Kind: FieldRemoved, Path: "meta", Old: "object"},
+			}},
+		},
+		{
+			name: "retyped",
+			changes: []Change{
+				{Kind: FieldRetyped, Path: "qty", Old: "string", New: "int"},
+				{Kind: FieldRetyped, Path: "a", Old: "", New: "string"},
+				{Kind: FieldRetyped, Path: "b", Old: "string", New: ""},
+				{Kind: FieldRetyped, Path: "c", Old: "float", New: "int"},
+			},
+			expected: Classification{Compatibility: Breaking, Changes: []Change{
+				{Kind: FieldRetyped, Path: "qty", Old: "string", New: "int"},
+				{Kind: FieldRetyped, Path: "a", Old: "", New: "string"},
+				{Kind: FieldRetyped, Path: "b", Old: "string", New: ""},
+			}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, (&Report{Changes: test.changes}).Classify())
+		})
+	}
+}
+
+func TestDiff_Classify(t *testing.T) {
+	report, err := Diff("old", []byte(oldSchema), "new", []byte(newSchema))
+	assert.NoError(t, err)
+	classification := report.Classify()
+	assert.Equal(t, Breaking, classification.Compatibility)
+	assert.Equal(t, []Change{
+		{Kind: FieldRemoved, Path: "meta", Old: "object"},
+		{Kind: FieldRetyped, Path: "qty", Old: "string", New: "int"},
+	}, classification.Changes)
+}
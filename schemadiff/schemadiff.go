@@ -0,0 +1,323 @@
+// Package schemadiff semantically diffs two versions of an omni.2.1 schema, producing a structured
+// report of the changes to the output fields and to the rest of the schema, e.g. for schema review and
+// approval workflows.
+package schemadiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/logward/omniparser"
+	"github.com/logward/omniparser/customfuncs"
+	"github.com/logward/omniparser/extensions/omniv21"
+	v21 "github.com/logward/omniparser/extensions/omniv21/customfuncs"
+	"github.com/logward/omniparser/extensions/omniv21/transform"
+)
+
+// Kind is the kind of a Change.
+type Kind string
+
+const (
+	// FieldAdded is an output field added. The fields under it aren't reported.
+	FieldAdded Kind = "field_added"
+	// FieldRemoved is an output field removed. The fields under it aren't reported.
+	FieldRemoved Kind = "field_removed"
+	// FieldRetyped is an output field whose type changed, e.g. from "string" to "int", or from
+	// "object" to "array". See transform.OutputField.Type.
+	FieldRetyped Kind = "field_retyped"
+	// XPathChanged is an output field whose `xpath` or `xpath_dynamic` changed.
+	XPathChanged Kind = "xpath_changed"
+	// MappingChanged is an output field whose value is computed differently other than by its xpath,
+	// e.g. from another `custom_func`, or with other args or options.
+	MappingChanged Kind = "mapping_changed"
+	// ValidationChanged is a change to the validations of the input in `file_declaration`, e.g. the
+	// `min`/`max` occurrences of a record or an EDI segment, or the `totals` of a record.
+	ValidationChanged Kind = "validation_changed"
+	// SettingChanged is any other change, e.g. to `parser_settings`, the rest of `file_declaration`,
+	// `constants` or `record_order`.
+	SettingChanged Kind = "setting_changed"
+)
+
+// Change is a change from the old version of a schema to the new one.
+type Change struct {
+	Kind Kind `json:"kind"`
+	// Path is, for the changes to output fields, the path of the field in the output, as per
+	// transform.OutputField.Path, where "" is the record itself, e.g. for a change to the `xpath` of
+	// `FINAL_OUTPUT`. For the other changes, it's the path of the setting in the schema, e.g.
+	// "file_declaration.records[name=HDR].min", where the elements of an array of named objects, such as
+	// records and segments, are identified by their names, and the others by their indexes.
+	Path string `json:"path"`
+	// Old and New are the values before and after the change, e.g. the types of an output field for
+	// FieldRetyped, or the JSON of a setting; Old is empty for additions, New for removals.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// String returns a one line description of the change, e.g. `field_retyped "items[].qty": "string" -> "int"`.
+func (c Change) String() string {
+	return fmt.Sprintf("%s %q: %q -> %q", c.Kind, c.Path, c.Old, c.New)
+}
+
+// Report is the changes from the old version of a schema to the new one: the output fields removed
+// first, then the other changes to the output fields, in the order of transform.OutputFields, then the
+// other changes, by their sections in the schema.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// validationKeys are the `file_declaration` keys of the settings validating the input.
+var validationKeys = map[string]bool{
+	"min":                      true,
+	"max":                      true,
+	"exceeds_max":              true,
+	"totals":                   true,
+	"strict_isa":               true,
+	"empty_segments":           true,
+	"inter_segment_whitespace": true,
+	"truncated_last_segment":   true,
+	"truncated_last_record":    true,
+	"duplicate_keys":           true,
+	"validation":               true,
+	"verify_checksum":          true,
+	"verify_controls":          true,
+}
+
+// sectionOrder is the order of the changes to the schema sections other than `transform_declarations`.
+// The changes to any other sections come after, in alphabetical order.
+var sectionOrder = []string{
+	"parser_settings", "external_properties", "file_declaration", "constants", "record_order", "input_sort", "file_header", "file_trailer",
+	"record_context",
+}
+
+// Diff semantically diffs the old and new versions of an omni.2.1 schema. Both are first validated the
+// same way omniparser.NewSchema does, with the same optional exts, whose custom funcs may be used by the
+// schemas.
+func Diff(oldName string, oldContent []byte, newName string, newContent []byte,
+	exts ...omniparser.Extension) (*Report, error) {
+	oldFields, err := outputFields(oldName, oldContent, exts)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := outputFields(newName, newContent, exts)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Changes: diffFields(oldFields, newFields)}
+	var oldSections, newSections map[string]interface{}
+	// both have just been validated as JSON.
+	_ = json.Unmarshal(oldContent, &oldSections)
+	_ = json.Unmarshal(newContent, &newSections)
+	delete(oldSections, "transform_declarations")
+	delete(newSections, "transform_declarations")
+	for _, section := range sections(oldSections, newSections) {
+		diffJSON(section, oldSections[section], newSections[section], &report.Changes)
+	}
+	return report, nil
+}
+
+func outputFields(name string, content []byte, exts []omniparser.Extension) ([]transform.OutputField, error) {
+	schema, err := omniparser.NewSchema(name, bytes.NewReader(content), exts...)
+	if err != nil {
+		return nil, err
+	}
+	if v := schema.Header().ParserSettings.Version; v != omniv21.Version() {
+		return nil, fmt.Errorf("schema '%s' is of version '%s', not '%s'", name, v, omniv21.Version())
+	}
+	funcs := []customfuncs.CustomFuncs{customfuncs.CommonCustomFuncs, v21.OmniV21CustomFuncs}
+	for _, ext := range exts {
+		funcs = append(funcs, ext.CustomFuncs)
+	}
+	finalOutputDecl, err := transform.ValidateTransformDeclarations(content, customfuncs.Merge(funcs...), nil)
+	if err != nil {
+		return nil, fmt.Errorf("schema '%s' 'transform_declarations' validation failed: %s", name, err.Error())
+	}
+	return transform.OutputFields(finalOutputDecl), nil
+}
+
+func diffFields(oldFields, newFields []transform.OutputField) []Change {
+	oldByPath := map[string]transform.OutputField{}
+	for _, f := range oldFields {
+		oldByPath[f.Path] = f
+	}
+	newByPath := map[string]transform.OutputField{}
+	for _, f := range newFields {
+		newByPath[f.Path] = f
+	}
+	var changes []Change
+	var removed, added []string
+	for _, f := range oldFields {
+		if _, found := newByPath[f.Path]; found || under(f.Path, removed) {
+			continue
+		}
+		removed = append(removed, f.Path)
+		changes = append(changes, Change{Kind: FieldRemoved, Path: f.Path, Old: f.Type})
+	}
+	for _, newField := range newFields {
+		oldField, found := oldByPath[newField.Path]
+		if !found {
+			if !under(newField.Path, added) {
+				added = append(added, newField.Path)
+				changes = append(changes, Change{Kind: FieldAdded, Path: newField.Path, New: newField.Type})
+			}
+			continue
+		}
+		if oldField.Type != newField.Type {
+			changes = append(changes,
+				Change{Kind: FieldRetyped, Path: newField.Path, Old: oldField.Type, New: newField.Type})
+		}
+		if oldField.XPath != newField.XPath {
+			changes = append(changes,
+				Change{Kind: XPathChanged, Path: newField.Path, Old: oldField.XPath, New: newField.XPath})
+		}
+		if oldField.Mapping != newField.Mapping {
+			changes = append(changes,
+				Change{Kind: MappingChanged, Path: newField.Path, Old: oldField.Mapping, New: newField.Mapping})
+		}
+	}
+	return changes
+}
+
+// under tells if an output field is under any of the given output fields, other than the record itself.
+func under(path string, parents []string) bool {
+	for _, parent := range parents {
+		if parent != "" && (strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[")) {
+			return true
+		}
+	}
+	return false
+}
+
+func sections(oldSections, newSections map[string]interface{}) []string {
+	var names, others []string
+	seen := map[string]bool{}
+	for _, name := range sectionOrder {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for _, m := range []map[string]interface{}{oldSections, newSections} {
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				others = append(others, name)
+			}
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// diffJSON appends the changes from old to new, the JSON values at path, to changes.
+func diffJSON(path string, old, new interface{}, changes *[]Change) {
+	if reflect.DeepEqual(old, new) {
+		return
+	}
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		var keys []string
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, found := oldMap[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key != "_comment" {
+				diffJSON(path+"."+key, oldMap[key], newMap[key], changes)
+			}
+		}
+		return
+	}
+	oldArray, oldIsArray := old.([]interface{})
+	newArray, newIsArray := new.([]interface{})
+	if oldIsArray && newIsArray {
+		diffArray(path, oldArray, newArray, changes)
+		return
+	}
+	*changes = append(*changes, Change{Kind: settingKind(path), Path: path, Old: toJSON(old), New: toJSON(new)})
+}
+
+// diffArray diffs the elements of arrays by their names if they're all objects of unique names, so
+// that inserting a record or a segment doesn't shift all the ones after it, or by their indexes
+// otherwise.
+func diffArray(path string, old, new []interface{}, changes *[]Change) {
+	oldNames, oldNamed := elemNames(old)
+	newNames, newNamed := elemNames(new)
+	if !oldNamed || !newNamed {
+		for i := 0; i < len(old) || i < len(new); i++ {
+			var oldElem, newElem interface{}
+			if i < len(old) {
+				oldElem = old[i]
+			}
+			if i < len(new) {
+				newElem = new[i]
+			}
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), oldElem, newElem, changes)
+		}
+		return
+	}
+	oldByName := map[string]interface{}{}
+	for i, name := range oldNames {
+		oldByName[name] = old[i]
+	}
+	newByName := map[string]interface{}{}
+	for i, name := range newNames {
+		newByName[name] = new[i]
+	}
+	for i, name := range oldNames {
+		if _, found := newByName[name]; !found {
+			diffJSON(fmt.Sprintf("%s[name=%s]", path, name), old[i], nil, changes)
+		}
+	}
+	for i, name := range newNames {
+		diffJSON(fmt.Sprintf("%s[name=%s]", path, name), oldByName[name], new[i], changes)
+	}
+}
+
+func elemNames(elems []interface{}) ([]string, bool) {
+	var names []string
+	seen := map[string]bool{}
+	for _, elem := range elems {
+		m, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" || seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, true
+}
+
+func settingKind(path string) Kind {
+	if !strings.HasPrefix(path, "file_declaration.") {
+		return SettingChanged
+	}
+	for _, segment := range strings.Split(path, ".") {
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			segment = segment[:i]
+		}
+		if validationKeys[segment] {
+			return ValidationChanged
+		}
+	}
+	return SettingChanged
+}
+
+func toJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
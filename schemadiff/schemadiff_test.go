@@ -0,0 +1,92 @@
+package schemadiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const oldSchema = `{
+	"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+	"file_declaration": {
+		"delimiter": ",",
+		"records": [
+			{ "name": "HDR", "header": "^H", "min": 1, "max": 1, "columns": [ { "name": "DATE", "index": 2 } ] },
+			{ "name": "ITEM", "header": "^I", "is_target": true, "columns": [
+				{ "name": "SKU", "index": 2 }, { "name": "QTY", "index": 3 }, { "name": "NOTE", "index": 4 }
+			]}
+		]
+	},
+	"transform_declarations": {
+		"FINAL_OUTPUT": { "object": {
+			"sku": { "xpath": "SKU" },
+			"qty": { "xpath": "QTY" },
+			"note": { "xpath": "NOTE" },
+			"meta": { "object": {
+				"source": { "const": "csv" },
+				"tags": { "array": [ { "xpath": "NOTE" } ] }
+			}},
+			"upper_sku": { "template": "upper_sku" }
+		}},
+		"upper_sku": { "custom_func": { "name": "upper", "args": [ { "xpath": "SKU" } ] } }
+	}
+}`
+
+const newSchema = `{
+	"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+	"file_declaration": {
+		"delimiter": "|",
+		"records": [
+			{ "name": "HDR", "header": "^H", "min": 0, "max": 1, "columns": [ { "name": "DATE", "index": 2 } ] },
+			{ "name": "ITEM", "header": "^I", "is_target": true, "columns": [
+				{ "name": "SKU", "index": 2 }, { "name": "QTY", "index": 3 }, { "name": "NOTE", "index": 5 }
+			]},
+			{ "name": "TRL", "header": "^T", "max": 1 }
+		]
+	},
+	"transform_declarations": {
+		"FINAL_OUTPUT": { "object": {
+			"sku": { "xpath": "SKU" },
+			"qty": { "xpath": "QTY", "type": "int" },
+			"note": { "xpath": "../NOTE" },
+			"date": { "xpath": "../HDR/DATE" },
+			"upper_sku": { "template": "upper_sku" }
+		}},
+		"upper_sku": { "custom_func": { "name": "lower", "args": [ { "xpath": "SKU" } ] } }
+	},
+	"record_order": { "key": { "xpath": "SKU" }, "window": 10 }
+}`
+
+func TestDiff(t *testing.T) {
+	report, err := Diff("old", []byte(oldSchema), "new", []byte(newSchema))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Kind: FieldRemoved, Path: "meta", Old: "object"},
+		{Kind: FieldAdded, Path: "date", New: "string"},
+		{Kind: XPathChanged, Path: "note", Old: "NOTE", New: "../NOTE"},
+		{Kind: FieldRetyped, Path: "qty", Old: "string", New: "int"},
+		{Kind: MappingChanged, Path: "upper_sku",
+			Old: `custom_func upper(xpath "SKU")`, New: `custom_func lower(xpath "SKU")`},
+		{Kind: SettingChanged, Path: "file_declaration.delimiter", Old: `","`, New: `"|"`},
+		{Kind: ValidationChanged, Path: "file_declaration.records[name=HDR].min", Old: "1", New: "0"},
+		{Kind: SettingChanged, Path: "file_declaration.records[name=ITEM].columns[name=NOTE].index",
+			Old: "4", New: "5"},
+		{Kind: SettingChanged, Path: "file_declaration.records[name=TRL]",
+			New: `{"header":"^T","max":1,"name":"TRL"}`},
+		{Kind: SettingChanged, Path: "record_order", New: `{"key":{"xpath":"SKU"},"window":10}`},
+	}, report.Changes)
+	assert.Equal(t, `field_retyped "qty": "string" -> "int"`, report.Changes[3].String())
+
+	report, err = Diff("old", []byte(oldSchema), "old again", []byte(oldSchema))
+	assert.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
+
+func TestDiff_InvalidSchema(t *testing.T) {
+	_, err := Diff("old", []byte(oldSchema), "new", []byte(`{}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "schema 'new'")
+
+	_, err = Diff("old", []byte(`{`), "new", []byte(newSchema))
+	assert.Error(t, err)
+}
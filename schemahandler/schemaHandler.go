@@ -2,6 +2,7 @@ package schemahandler
 
 import (
 	"io"
+	"time"
 
 	"github.com/logward/omniparser/customfuncs"
 	"github.com/logward/omniparser/errs"
@@ -37,6 +38,15 @@ type SchemaHandler interface {
 	NewIngester(ctx *transformctx.Ctx, input io.Reader) (Ingester, error)
 }
 
+// RecordDebugger is an optional interface a SchemaHandler can implement to support debugging how a
+// single record is transformed, e.g. by interactive mapping debuggers.
+type RecordDebugger interface {
+	// DebugRecord ingests the first record of the input stream and evaluates the target on it with
+	// tracing on. It returns the trace as JSON, in a schema handler specific format. Errors in the
+	// evaluation are recorded in the trace; the error returned is for failures in ingesting the record.
+	DebugRecord(ctx *transformctx.Ctx, input io.Reader, target string) ([]byte, error)
+}
+
 // RawRecord represents a raw record ingested from the input.
 type RawRecord interface {
 	// Raw returns the actual raw record that is version specific to each of the schema handlers.
@@ -45,6 +55,53 @@ type RawRecord interface {
 	Checksum() string
 }
 
+// RecordIDer is an optional interface a RawRecord can implement to provide a deterministic identifier.
+type RecordIDer interface {
+	// RecordID returns a UUIDv3 (MD5) deterministic identifier of the raw record, derived from its
+	// checksum, its ordinal in the input stream, and the range of source positions it was ingested
+	// from. Repeated ingestions of the same input yield the same record IDs, making them suitable
+	// for replays and deduplication across retries.
+	RecordID() string
+}
+
+// RecordPositioner is an optional interface a RawRecord can implement to expose where in the input it
+// was ingested from.
+type RecordPositioner interface {
+	// RecordPosition returns the 1-based ordinal of the raw record in the input stream, and the range
+	// of source positions (e.g. line numbers for flat files, segment numbers for EDI) it was ingested
+	// from, 1-based and inclusive. begin and end are 0 if the format doesn't report source positions.
+	RecordPosition() (ordinal, begin, end int)
+}
+
+// RecordOffsetter is an optional interface a RawRecord can implement to expose the range of bytes in
+// the input it was ingested from.
+type RecordOffsetter interface {
+	// RecordOffsets returns the 0-based byte offsets in the input of the first byte of the raw record
+	// and of right after its last byte. ok is false if the format doesn't report byte positions.
+	RecordOffsets() (begin, end int64, ok bool)
+}
+
+// RawBytesRecord is an optional interface a RawRecord can implement to expose its raw bytes as read
+// from the input.
+type RawBytesRecord interface {
+	// RawBytes returns the raw bytes of the record as read from the input, or nil if not available,
+	// e.g. for formats whose readers don't keep them. By default, the returned slice points into the
+	// reader's internal buffer: it's READONLY, and only valid until the next Read call of the
+	// transform. If transformctx.Ctx.OwnRawBytes is set, each call returns a new copy owned by the
+	// caller, which can be modified and retained, e.g. for archiving.
+	RawBytes() []byte
+}
+
+// SourcePreviewer is an optional interface a RawRecord can implement to show where it comes from in the
+// input, e.g. in the preview panes of mapping UIs.
+type SourcePreviewer interface {
+	// SourceJSON returns the JSON view of the raw record, e.g. of its IDR.
+	SourceJSON() string
+	// ContextJSON returns the JSON view of the data around the raw record in the input, without the
+	// record itself, e.g. of the EDI envelopes or the file header it's in, or "" if none.
+	ContextJSON() string
+}
+
 // Ingester is an interface of ingestion and transformation for a given input stream.
 type Ingester interface {
 	// Read is called repeatedly during the processing of an input stream. Each call it should return
@@ -67,3 +124,49 @@ type Ingester interface {
 	// context aware (such as input file name + line number) error formatting.
 	errs.CtxAwareErr
 }
+
+// Warner is an optional interface an Ingester can implement to expose the problems in the input, e.g. a
+// truncated last segment, tolerated during its most recent Read call, regardless of its outcome, e.g. even
+// if it returned io.EOF.
+type Warner interface {
+	// Warnings returns the warnings raised by the most recent Read call, or nil if there is none.
+	Warnings() []error
+}
+
+// SpillStats are the volume of data spilled to disk by an Ingester, e.g. when the records it buffers for
+// re-sequencing exceed their memory budget.
+type SpillStats struct {
+	// Records is the number of records spilled. A record spilled more than once counts more than once.
+	Records int `json:"records"`
+	// Bytes is the total size of the data spilled.
+	Bytes int64 `json:"bytes"`
+	// Files is the number of spill files created.
+	Files int `json:"files"`
+}
+
+// Spiller is an optional interface an Ingester can implement to expose the volume of data it has spilled
+// to disk so far.
+type Spiller interface {
+	SpillStats() SpillStats
+}
+
+// InvocationStats are the number of invocations of a template or a custom func, and their cumulative
+// latency.
+type InvocationStats struct {
+	Count    int           `json:"count"`
+	Duration time.Duration `json:"duration"`
+}
+
+// StatsCollector is an optional interface an Ingester can implement to expose, if
+// transformctx.Ctx.CollectStats is on, the InvocationStats of the templates and custom funcs it has
+// invoked so far, keyed by their names.
+type StatsCollector interface {
+	InvocationStats() (templates, customFuncs map[string]InvocationStats)
+}
+
+// InputRewriter is an optional interface an Ingester can implement to tell its readers don't read the
+// input as is, e.g. but a sorted copy of it, thus the positions in its input errors aren't the ones in
+// the input.
+type InputRewriter interface {
+	InputRewritten() bool
+}
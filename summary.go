@@ -0,0 +1,132 @@
+package omniparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// Error codes of TransformSummary.ErrorCounts.
+const (
+	// ErrCodeTransformFailed is for records that are read but fail to transform per the schema.
+	ErrCodeTransformFailed = "transform_failed"
+	// ErrCodeInvalidRecord is for records that fail to be read, e.g. malformed, while the rest of the
+	// input can still be read.
+	ErrCodeInvalidRecord = "invalid_record"
+	// ErrCodeFatal is for the fatal error, if any, the Transform stops with.
+	ErrCodeFatal = "fatal"
+)
+
+// TransformSummary is the summary of a Transform run, for batch jobs to log and persist consistent
+// run manifests. It's final once the Transform is done, i.e. Read has returned io.EOF or a fatal error;
+// before that, it contains the running totals.
+type TransformSummary struct {
+	// RecordsIn is the number of records read, successfully or not, i.e. RecordsOut + RecordsFailed.
+	RecordsIn int `json:"records_in"`
+	// RecordsOut is the number of records successfully ingested and transformed.
+	RecordsOut int `json:"records_out"`
+	// RecordsFailed is the number of records skipped due to continuable errors.
+	RecordsFailed int `json:"records_failed"`
+	// BytesOut is the total size of the transformed records.
+	BytesOut int64 `json:"bytes_out"`
+	// Duration is the time from the first Read call till the Transform is done, or till now if not yet.
+	Duration time.Duration `json:"duration_ns"`
+	// ErrorCounts contains the number of errors by their codes, e.g. ErrCodeTransformFailed.
+	ErrorCounts map[string]int `json:"error_counts,omitempty"`
+	// Checksum is the hex encoded SHA-256 of the concatenation of all the transformed records, in the
+	// order they're returned, so re-runs of the same input can be verified to have produced the same
+	// output.
+	Checksum string `json:"checksum"`
+	// Done tells if the Transform is done, i.e. Read has returned io.EOF or a fatal error.
+	Done bool `json:"done"`
+	// Error is the fatal error the Transform stopped with, if any.
+	Error string `json:"error,omitempty"`
+	// Spill is the volume of data spilled to disk, if any, e.g. by the `record_order` of an omni schema
+	// with `spill` set.
+	Spill *schemahandler.SpillStats `json:"spill,omitempty"`
+}
+
+// summarizer gathers the TransformSummary of a transform.
+type summarizer struct {
+	summary TransformSummary
+	start   time.Time
+	end     time.Time
+	hash    hash.Hash
+}
+
+func (s *summarizer) begin() {
+	if s.start.IsZero() {
+		s.start = time.Now()
+		s.hash = sha256.New()
+	}
+}
+
+// add adds the outcome of a Read call into the summary. ingesterErr is the error returned by the
+// ingester, before being wrapped into errs.ErrTransformFailed, if continuable.
+func (s *summarizer) add(transformed []byte, err, ingesterErr error) {
+	if s.summary.Done {
+		return
+	}
+	switch {
+	case err == nil:
+		s.summary.RecordsIn++
+		s.summary.RecordsOut++
+		s.summary.BytesOut += int64(len(transformed))
+		_, _ = s.hash.Write(transformed)
+	case err == io.EOF:
+		s.done()
+	case errs.IsErrTransformFailed(err):
+		s.summary.RecordsIn++
+		s.summary.RecordsFailed++
+		if errs.IsErrTransformFailed(ingesterErr) {
+			s.countErr(ErrCodeTransformFailed)
+		} else {
+			s.countErr(ErrCodeInvalidRecord)
+		}
+	default:
+		s.countErr(ErrCodeFatal)
+		s.summary.Error = err.Error()
+		s.done()
+	}
+}
+
+func (s *summarizer) countErr(code string) {
+	if s.summary.ErrorCounts == nil {
+		s.summary.ErrorCounts = map[string]int{}
+	}
+	s.summary.ErrorCounts[code]++
+}
+
+func (s *summarizer) done() {
+	s.summary.Done = true
+	s.end = time.Now()
+}
+
+// get returns a copy of the summary so far.
+func (s *summarizer) get() TransformSummary {
+	summary := s.summary
+	if len(s.summary.ErrorCounts) > 0 {
+		summary.ErrorCounts = make(map[string]int, len(s.summary.ErrorCounts))
+		for code, count := range s.summary.ErrorCounts {
+			summary.ErrorCounts[code] = count
+		}
+	}
+	hash := s.hash
+	if hash == nil {
+		hash = sha256.New()
+	}
+	summary.Checksum = hex.EncodeToString(hash.Sum(nil))
+	switch {
+	case s.start.IsZero():
+	case s.summary.Done:
+		summary.Duration = s.end.Sub(s.start)
+	default:
+		summary.Duration = time.Since(s.start)
+	}
+	return summary
+}
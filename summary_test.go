@@ -0,0 +1,116 @@
+package omniparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTransform_Summary_EndWithEOF(t *testing.T) {
+	continuableErr := errors.New("continuable error")
+	transformErr := errs.ErrTransformFailed("transform error")
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: continuableErr},
+				{err: transformErr},
+				{result: []byte("2nd good read")},
+				{err: io.EOF},
+			},
+			continuableErrs: map[error]bool{continuableErr: true, transformErr: true},
+		},
+	}
+	summary := tfm.Summary()
+	assert.False(t, summary.Done)
+	assert.Zero(t, summary.Duration)
+	assert.Equal(t, sha256Hex(""), summary.Checksum)
+
+	_, _ = tfm.Read()
+	summary = tfm.Summary()
+	assert.Equal(t, 1, summary.RecordsOut)
+	assert.False(t, summary.Done)
+	assert.Equal(t, sha256Hex("1st good read"), summary.Checksum)
+
+	for i := 0; i < 5; i++ {
+		_, _ = tfm.Read()
+	}
+	summary = tfm.Summary()
+	assert.True(t, summary.Duration >= 0)
+	summary.Duration = 0
+	assert.Equal(t, TransformSummary{
+		RecordsIn:     4,
+		RecordsOut:    2,
+		RecordsFailed: 2,
+		BytesOut:      int64(len("1st good read2nd good read")),
+		ErrorCounts:   map[string]int{ErrCodeInvalidRecord: 1, ErrCodeTransformFailed: 1},
+		Checksum:      sha256Hex("1st good read2nd good read"),
+		Done:          true,
+	}, summary)
+	// the summary is final once done.
+	assert.Equal(t, tfm.Summary().Duration, tfm.Summary().Duration)
+	// the summary returned is a copy.
+	summary.ErrorCounts[ErrCodeFatal] = 1
+	assert.Equal(t, 2, len(tfm.Summary().ErrorCounts))
+}
+
+func TestTransform_Summary_EndWithNonContinuableError(t *testing.T) {
+	tfm := &transform{
+		ingester: &testIngester{
+			readCalls: []testReadCall{
+				{result: []byte("1st good read")},
+				{err: errors.New("fatal error")},
+			},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = tfm.Read()
+	}
+	summary := tfm.Summary()
+	summary.Duration = 0
+	assert.Equal(t, TransformSummary{
+		RecordsIn:   1,
+		RecordsOut:  1,
+		BytesOut:    int64(len("1st good read")),
+		ErrorCounts: map[string]int{ErrCodeFatal: 1},
+		Checksum:    sha256Hex("1st good read"),
+		Done:        true,
+		Error:       "fatal error",
+	}, summary)
+	b, err := json.Marshal(summary)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"records_in":1,"records_out":1,"records_failed":0,"bytes_out":13,"duration_ns":0,`+
+			`"error_counts":{"fatal":1},"checksum":"`+sha256Hex("1st good read")+`","done":true,"error":"fatal error"}`,
+		string(b))
+}
+
+type testSpillingIngester struct {
+	testIngester
+	stats schemahandler.SpillStats
+}
+
+func (g *testSpillingIngester) SpillStats() schemahandler.SpillStats { return g.stats }
+
+func TestTransform_Summary_Spill(t *testing.T) {
+	g := &testSpillingIngester{testIngester: testIngester{readCalls: []testReadCall{{err: io.EOF}}}}
+	tfm := &transform{ingester: g}
+	// no spill stats if nothing is spilled.
+	assert.Nil(t, tfm.Summary().Spill)
+	g.stats = schemahandler.SpillStats{Records: 3, Bytes: 300, Files: 2}
+	_, _ = tfm.Read()
+	assert.Equal(t, &schemahandler.SpillStats{Records: 3, Bytes: 300, Files: 2}, tfm.Summary().Spill)
+}
@@ -2,9 +2,11 @@ package omniparser
 
 import (
 	"errors"
+	"io"
 
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
 )
 
 // Transform is an interface that represents one input stream ingestion and transform
@@ -16,7 +18,8 @@ type Transform interface {
 	// to Read should always return io.EOF.
 	// errs.ErrTransformFailed should be returned when a record ingestion and transformation
 	// failed and such failure isn't considered fatal. Future calls to Read will attempt
-	// new record ingestion and transformations.
+	// new record ingestion and transformations. Use errs.IsErrTransformFailed to tell such
+	// failures; their causes, e.g. errs.ErrInput, can be inspected with errors.As.
 	// Any other error returned is considered fatal and future calls to Read will always
 	// return the same error.
 	// Note if returned error isn't nil, then returned []byte will be nil.
@@ -26,10 +29,78 @@ type Transform interface {
 	RawRecord() (schemahandler.RawRecord, error)
 }
 
+// The following are optional interfaces a Transform can implement, which callers type-assert. The
+// Transforms created by Schema.NewTransform, WithErrorBudget and WithLimits implement all of them.
+
+// Listenable is an optional interface a Transform can implement to send the events of its Read calls
+// to Listeners.
+type Listenable interface {
+	// AddListener registers a Listener to receive the events of subsequent Read calls.
+	AddListener(l Listener)
+}
+
+// StatsReporter is an optional interface a Transform can implement to report its running totals.
+type StatsReporter interface {
+	// Stats returns the running totals of the Transform so far.
+	Stats() TransformStats
+}
+
+// SummaryReporter is an optional interface a Transform can implement to report the summary of its run.
+type SummaryReporter interface {
+	// Summary returns the summary of the Transform run, which is final once Read has returned io.EOF
+	// or a fatal error.
+	Summary() TransformSummary
+}
+
+// Previewer is an optional interface a Transform can implement to preview its first records.
+type Previewer interface {
+	// Preview reads up to n records, as Read does, and returns them along with where they come from
+	// in the input, e.g. for the preview panes of mapping UIs.
+	Preview(n int) (*Preview, error)
+}
+
+// addListener registers l with t, if t is Listenable.
+func addListener(t Transform, l Listener) {
+	if listenable, ok := t.(Listenable); ok {
+		listenable.AddListener(l)
+	}
+}
+
+// statsOf returns the running totals of t, or zero ones if t isn't a StatsReporter.
+func statsOf(t Transform) TransformStats {
+	if reporter, ok := t.(StatsReporter); ok {
+		return reporter.Stats()
+	}
+	return TransformStats{}
+}
+
+// summaryOf returns the summary of the run of t, or an empty one if t isn't a SummaryReporter.
+func summaryOf(t Transform) TransformSummary {
+	if reporter, ok := t.(SummaryReporter); ok {
+		return reporter.Summary()
+	}
+	return TransformSummary{}
+}
+
+// previewOf previews the first n records of t, or returns an error if t isn't a Previewer.
+func previewOf(t Transform, n int) (*Preview, error) {
+	if previewer, ok := t.(Previewer); ok {
+		return previewer.Preview(n)
+	}
+	return nil, errors.New("transform doesn't support preview")
+}
+
 type transform struct {
 	ingester      schemahandler.Ingester
 	lastRawRecord schemahandler.RawRecord
 	lastErr       error
+	listeners     []Listener
+	seq           int
+	stats         TransformStats
+	validation    *transformctx.ValidationResults
+	summarizer    summarizer
+	watchdog      *progressWatchdog // nil if there is no transformctx.Ctx.MaxRecordBytes.
+	dumper        *errorDumper      // nil if there is no 'parser_settings.error_dump_bytes'.
 }
 
 // Read returns a JSON byte slice representing one ingested and transformed record.
@@ -49,13 +120,30 @@ func (o *transform) Read() ([]byte, error) {
 	if o.lastErr != nil && !errs.IsErrTransformFailed(o.lastErr) {
 		return nil, o.lastErr
 	}
+	o.seq++
+	for _, l := range o.listeners {
+		l.OnRecordStart(o.seq)
+	}
+	o.summarizer.begin()
 	rawRecord, transformed, err := o.ingester.Read()
+	if o.watchdog != nil {
+		// readers may swallow the watchdog's error, or take it for a continuable one.
+		if o.watchdog.err != nil {
+			rawRecord, transformed, err = nil, nil, o.watchdog.err
+		}
+		o.watchdog.progress()
+	}
+	if err != nil && o.dumper != nil {
+		o.dumper.fill(err)
+	}
+	ingesterErr := err
 	if err != nil {
-		if o.ingester.IsContinuableError(err) {
+		if !IsErrNoProgress(err) && o.ingester.IsContinuableError(err) {
 			// If ingester error is continuable, wrap it into a standard generic ErrTransformFailed
-			// so caller has an easier time to deal with it. If fatal error, then leave it raw to the
+			// so caller has an easier time to deal with it, while still being able to inspect the
+			// cause, e.g. an errs.ErrInput, with errors.As. If fatal error, then leave it raw to the
 			// caller, so they can decide what it is and how to proceed.
-			err = errs.ErrTransformFailed(err.Error())
+			err = errs.WrapTransformFailed(err)
 		}
 		transformed = nil
 	}
@@ -65,9 +153,61 @@ func (o *transform) Read() ([]byte, error) {
 		o.lastRawRecord = nil
 	}
 	o.lastErr = err
+	o.summarizer.add(transformed, err, ingesterErr)
+	o.notify(rawRecord, transformed, err)
 	return transformed, err
 }
 
+func (o *transform) notify(rawRecord schemahandler.RawRecord, transformed []byte, err error) {
+	switch {
+	case err == nil:
+		o.stats.Emitted++
+	case errs.IsErrTransformFailed(err):
+		o.stats.Skipped++
+	}
+	o.validate(rawRecord, err)
+	var warnings []error
+	if warner, ok := o.ingester.(schemahandler.Warner); ok {
+		warnings = warner.Warnings()
+	}
+	for _, l := range o.listeners {
+		if wl, ok := l.(WarningListener); ok {
+			for _, w := range warnings {
+				wl.OnWarning(o.seq, w)
+			}
+		}
+		switch {
+		case err == nil:
+			l.OnRecordEnd(o.seq, rawRecord, transformed)
+		case err == io.EOF:
+			l.OnEOF(o.Stats())
+		case errs.IsErrTransformFailed(err):
+			l.OnSkip(o.seq, err)
+		default:
+			l.OnError(o.seq, err)
+		}
+	}
+}
+
+func (o *transform) validate(rawRecord schemahandler.RawRecord, err error) {
+	if o.validation == nil {
+		return
+	}
+	result := transformctx.ValidationResult{Number: o.seq}
+	switch {
+	case err == nil:
+		result.Accepted = true
+		if ider, ok := rawRecord.(schemahandler.RecordIDer); ok {
+			result.ID = ider.RecordID()
+		}
+	case errs.IsErrTransformFailed(err):
+		result.Error = err.Error()
+	default:
+		return
+	}
+	o.validation.Add(result)
+}
+
 // RawRecord returns the current raw record ingested from the input stream. If the last
 // Read call failed, or Read hasn't been called yet, it will return an error.
 func (o *transform) RawRecord() (schemahandler.RawRecord, error) {
@@ -79,3 +219,30 @@ func (o *transform) RawRecord() (schemahandler.RawRecord, error) {
 	}
 	return o.lastRawRecord, nil
 }
+
+// AddListener implements Listenable, registering a Listener to receive the events of subsequent Read
+// calls.
+func (o *transform) AddListener(l Listener) {
+	o.listeners = append(o.listeners, l)
+}
+
+// Stats implements StatsReporter, returning the running totals of the Transform so far.
+func (o *transform) Stats() TransformStats {
+	stats := o.stats
+	if collector, ok := o.ingester.(schemahandler.StatsCollector); ok {
+		stats.Templates, stats.CustomFuncs = collector.InvocationStats()
+	}
+	return stats
+}
+
+// Summary implements SummaryReporter, returning the summary of the Transform run, which is final once
+// Read has returned io.EOF or a fatal error.
+func (o *transform) Summary() TransformSummary {
+	summary := o.summarizer.get()
+	if spiller, ok := o.ingester.(schemahandler.Spiller); ok {
+		if stats := spiller.SpillStats(); stats.Records > 0 {
+			summary.Spill = &stats
+		}
+	}
+	return summary
+}
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/logward/omniparser/errs"
 	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
 )
 
 type testReadCall struct {
@@ -153,6 +156,26 @@ func TestTransform_Read_EndWithNonContinuableError(t *testing.T) {
 	assert.Nil(t, raw)
 }
 
+func TestTransform_Read_ContinuableErrorUnwrap(t *testing.T) {
+	inputErr := &errs.ErrInput{
+		Format: "csv2", Input: "test", Line: 2, Offset: -1, Reason: "bad", Err: errors.New("input 'test' line 2: bad")}
+	var tfm Transform = &transform{
+		ingester: &testIngester{
+			readCalls:       []testReadCall{{err: inputErr}, {err: io.EOF}},
+			continuableErrs: map[error]bool{inputErr: true},
+		},
+	}
+	_, err := tfm.Read()
+	assert.True(t, errs.IsErrTransformFailed(err))
+	assert.Equal(t, "input 'test' line 2: bad", err.Error())
+	var e *errs.ErrInput
+	assert.True(t, errors.As(err, &e))
+	assert.Same(t, inputErr, e)
+	assert.Equal(t, 2, e.Line)
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
 func TestTransform_RawRecord_CalledBeforeRead(t *testing.T) {
 	tfm := &transform{ingester: &testIngester{readCalls: []testReadCall{}}}
 	raw, err := tfm.RawRecord()
@@ -160,3 +183,141 @@ func TestTransform_RawRecord_CalledBeforeRead(t *testing.T) {
 	assert.Equal(t, "must call Read first", err.Error())
 	assert.Nil(t, raw)
 }
+
+func TestTransform_Read_Validation(t *testing.T) {
+	ctx := &transformctx.Ctx{Validation: &transformctx.ValidationResults{}}
+	tfm, err := newPushTestSchema(t).NewTransform("test-input",
+		strings.NewReader("<a><b><c>2020-01-02</c></b><b><c>bad</c></b><b><c>2020-01-03</c></b></a>"), ctx)
+	assert.NoError(t, err)
+	for {
+		_, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+	}
+	// io.EOF isn't a record, thus not gathered, no matter how many times it's returned.
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	v := ctx.Validation
+	assert.Equal(t, 3, v.Received)
+	assert.Equal(t, 2, v.Accepted)
+	assert.Equal(t, 3, len(v.Results))
+	assert.Equal(t, 1, v.Results[0].Number)
+	assert.True(t, v.Results[0].Accepted)
+	assert.NotEmpty(t, v.Results[0].ID)
+	assert.Empty(t, v.Results[0].Error)
+	assert.Equal(t, 2, v.Results[1].Number)
+	assert.False(t, v.Results[1].Accepted)
+	assert.Empty(t, v.Results[1].ID)
+	assert.Contains(t, v.Results[1].Error, "test-input")
+	assert.Equal(t, 3, v.Results[2].Number)
+	assert.True(t, v.Results[2].Accepted)
+	assert.NotEqual(t, v.Results[0].ID, v.Results[2].ID)
+}
+
+func TestTransform_Stats(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+		"file_declaration": {
+			"delimiter": ",",
+			"records": [ { "is_target": true, "columns": [ { "name": "A", "index": 1 } ] } ]
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": { "a": { "template": "upper_a" } } },
+			"upper_a": { "custom_func": { "name": "upper", "args": [ { "xpath": "A" } ] } }
+		}
+	}`))
+	assert.NoError(t, err)
+	for _, collect := range []bool{false, true} {
+		tfm, err := s.NewTransform("test-input", strings.NewReader("a\nb\nc\n"),
+			&transformctx.Ctx{CollectStats: collect})
+		assert.NoError(t, err)
+		for {
+			if _, err := tfm.Read(); err != nil {
+				assert.Equal(t, io.EOF, err)
+				break
+			}
+		}
+		stats := tfm.(StatsReporter).Stats()
+		assert.Equal(t, 3, stats.Emitted)
+		if !collect {
+			assert.Nil(t, stats.Templates)
+			assert.Nil(t, stats.CustomFuncs)
+			continue
+		}
+		assert.Equal(t, 3, stats.Templates["upper_a"].Count)
+		assert.Equal(t, 1, len(stats.Templates))
+		assert.Equal(t, 3, stats.CustomFuncs["upper"].Count)
+		assert.Equal(t, 1, len(stats.CustomFuncs))
+	}
+}
+
+func TestTransform_Determinism(t *testing.T) {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "csv2" },
+		"file_declaration": {
+			"delimiter": ",",
+			"records": [ { "is_target": true, "columns": [ { "name": "A", "index": 1 } ] } ]
+		},
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "object": {
+				"now": { "custom_func": { "name": "now" } },
+				"uuid": { "custom_func": { "name": "uuidv4" } },
+				"random": { "custom_func": { "name": "random", "args": [ { "const": "1" }, { "const": "1000000" } ] } },
+				"js": { "custom_func": { "name": "javascript", "args": [ { "const": "Math.random() + ',' + Date.now()" } ] } }
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	run := func(d *transformctx.Determinism) []string {
+		tfm, err := s.NewTransform("test-input", strings.NewReader("a\nb\n"), &transformctx.Ctx{Determinism: d})
+		assert.NoError(t, err)
+		var outputs []string
+		for {
+			output, err := tfm.Read()
+			if err != nil {
+				assert.Equal(t, io.EOF, err)
+				return outputs
+			}
+			outputs = append(outputs, string(output))
+		}
+	}
+	pinned := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	outputs := run(&transformctx.Determinism{Now: pinned, Seed: 42})
+	assert.Equal(t, 2, len(outputs))
+	assert.Contains(t, outputs[0], `"now":"2020-01-02T03:04:05Z"`)
+	assert.Contains(t, outputs[0], fmt.Sprintf(",%d", pinned.UnixNano()/int64(time.Millisecond)))
+	assert.NotEqual(t, outputs[0], outputs[1])
+	assert.Equal(t, outputs, run(&transformctx.Determinism{Now: pinned, Seed: 42}))
+	assert.NotEqual(t, outputs, run(&transformctx.Determinism{Now: pinned, Seed: 43}))
+	assert.NotEqual(t, run(nil), run(nil))
+}
+
+// minimalTransform only implements Transform, as an external implementation or a mock may.
+type minimalTransform struct {
+	Transform
+}
+
+func TestTransform_OptionalInterfaces(t *testing.T) {
+	var tfm Transform = &transform{ingester: &testIngester{}}
+	assert.Implements(t, (*Listenable)(nil), tfm)
+	assert.Implements(t, (*StatsReporter)(nil), tfm)
+	assert.Implements(t, (*SummaryReporter)(nil), tfm)
+	assert.Implements(t, (*Previewer)(nil), tfm)
+
+	minimal := minimalTransform{Transform: tfm}
+	_, ok := Transform(minimal).(Listenable)
+	assert.False(t, ok)
+	for _, wrap := range []func(Transform) (Transform, error){
+		func(t Transform) (Transform, error) { return WithErrorBudget(t, ErrorBudget{MaxFailed: 1}) },
+		func(t Transform) (Transform, error) { return WithLimits(t, Limits{MaxRecords: 1}) },
+	} {
+		wrapped, err := wrap(minimal)
+		assert.NoError(t, err)
+		wrapped.(Listenable).AddListener(NopListener{})
+		assert.Equal(t, TransformStats{}, wrapped.(StatsReporter).Stats())
+		assert.Equal(t, TransformSummary{}, wrapped.(SummaryReporter).Summary())
+		_, err = wrapped.(Previewer).Preview(1)
+		assert.EqualError(t, err, "transform doesn't support preview")
+	}
+}
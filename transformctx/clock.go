@@ -0,0 +1,32 @@
+package transformctx
+
+import "time"
+
+// Clock tells the current time to the custom funcs, such as `now` and `today`, so callers, e.g. tests
+// and replays, can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a func, e.g. time.Now, to a Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// Now returns the current time for the custom funcs: Determinism.Now if Determinism is set, or the time
+// of Clock if set, or time.Now otherwise.
+func (ctx *Ctx) Now() time.Time {
+	switch {
+	case ctx == nil:
+		return time.Now()
+	case ctx.Determinism != nil:
+		return ctx.Determinism.now()
+	case ctx.Clock != nil:
+		return ctx.Clock.Now()
+	default:
+		return time.Now()
+	}
+}
@@ -0,0 +1,26 @@
+package transformctx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCtx_Now(t *testing.T) {
+	var nilCtx *Ctx
+	assert.False(t, nilCtx.Now().IsZero())
+	assert.False(t, (&Ctx{}).Now().IsZero())
+
+	clockTime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return clockTime })
+	assert.Equal(t, clockTime, (&Ctx{Clock: clock}).Now())
+	clockTime = clockTime.Add(time.Hour)
+	assert.Equal(t, clockTime, (&Ctx{Clock: clock}).Now())
+
+	assert.Equal(t, time.Unix(0, 0).UTC(), (&Ctx{Determinism: &Determinism{}}).Now())
+	pinned := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, pinned, (&Ctx{Determinism: &Determinism{Now: pinned}}).Now())
+	// Determinism overrides Clock.
+	assert.Equal(t, pinned, (&Ctx{Clock: clock, Determinism: &Determinism{Now: pinned}}).Now())
+}
@@ -1,9 +1,118 @@
 package transformctx
 
 import (
+	"strings"
+	"time"
+
+	"github.com/logward/omniparser/calendar"
+	"github.com/logward/omniparser/codelist"
 	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/geo"
 )
 
+// TransportPropertyPrefix is the prefix of the names under which Transport metadata can be looked up
+// by External, e.g. "transport.message_id".
+const TransportPropertyPrefix = "transport."
+
+// Transport contains the metadata about how an input stream was delivered, such as via AS2 or SFTP.
+type Transport struct {
+	// Protocol is the transport protocol, e.g. "as2", "sftp", "s3".
+	Protocol string
+	// MessageID is the transport level message ID, e.g. AS2 Message-ID.
+	MessageID string
+	// Filename is the name of the file as delivered.
+	Filename string
+	// ReceivedAt is when the input stream was received.
+	ReceivedAt time.Time
+	// PartnerID identifies the trading partner who sent the input stream.
+	PartnerID string
+	// Extra contains any other transport metadata.
+	Extra map[string]string
+}
+
+func (t *Transport) lookup(name string) (string, bool) {
+	switch name {
+	case "protocol":
+		return t.Protocol, t.Protocol != ""
+	case "message_id":
+		return t.MessageID, t.MessageID != ""
+	case "filename":
+		return t.Filename, t.Filename != ""
+	case "received_at":
+		if t.ReceivedAt.IsZero() {
+			return "", false
+		}
+		return t.ReceivedAt.Format(time.RFC3339), true
+	case "partner_id":
+		return t.PartnerID, t.PartnerID != ""
+	default:
+		v, found := t.Extra[name]
+		return v, found
+	}
+}
+
+// RecordCounters contains the counters of the record currently being ingested and transformed,
+// useful for generating positional fields, e.g. line numbers, in the output.
+type RecordCounters struct {
+	// Number is the 1-based ordinal of the record in the input stream. 0 means the counters are
+	// not available.
+	Number int
+	// NumberInGroup is the 1-based ordinal of the record among the consecutive records sharing the
+	// same parent in the input, e.g. the transaction sets of an EDI functional group, or the
+	// envelopes of a fixed-length envelope_group.
+	NumberInGroup int
+	// Emitted is the number of records successfully transformed before the current one, i.e.
+	// excluding the records skipped due to errors.
+	Emitted int
+}
+
+// Sampling selects the records of the input a Transform transforms. All the records are still read,
+// as are the envelopes and the headers around them, so the ones sampled are transformed in their full
+// context, and keep their Counters, e.g. their Number in the input.
+type Sampling struct {
+	// Every, if positive, samples every Every-th record, starting from the first one, i.e. the records
+	// numbered 1, Every+1, 2*Every+1, etc.
+	Every int
+	// Rate, if positive and Every isn't, samples each record with the probability Rate, from 0 to 1,
+	// pseudo-randomly from Seed, so the same records are sampled across runs.
+	Rate float64
+	// Seed seeds the pseudo-random sampling of Rate.
+	Seed int64
+}
+
+// ValidationResult is the outcome of ingesting and transforming one record of an input stream.
+type ValidationResult struct {
+	// Number is the 1-based ordinal of the record in the input stream.
+	Number int `json:"number"`
+	// ID is the deterministic ID of the record, if accepted and its schema handler supports record IDs.
+	ID string `json:"id,omitempty"`
+	// Accepted is true if the record was successfully ingested and transformed.
+	Accepted bool `json:"accepted"`
+	// Error is the reason the record was rejected.
+	Error string `json:"error,omitempty"`
+}
+
+// ValidationResults gathers the outcomes of all the records of an input stream, from which application
+// level responses, such as X12 999 Implementation Acknowledgment and 824 Application Advice, can be
+// generated once the input stream is consumed. It marshals into JSON for such transforms.
+type ValidationResults struct {
+	// Received is the number of records ingested, accepted or not.
+	Received int `json:"received"`
+	// Accepted is the number of records successfully ingested and transformed.
+	Accepted int `json:"accepted"`
+	// Results contains the outcome of each record, in the order of the input stream.
+	Results []ValidationResult `json:"results"`
+}
+
+// Add adds the outcome of a record.
+func (v *ValidationResults) Add(r ValidationResult) {
+	v.Received++
+	if r.Accepted {
+		v.Accepted++
+	}
+	v.Results = append(v.Results, r)
+}
+
 // Ctx is the context object used throughout a Transform operation.
 type Ctx struct {
 	// InputName is the name of the input stream to be ingested and transformed.
@@ -21,10 +130,88 @@ type Ctx struct {
 	// param will be passed along with the Ctx object throughout all the stages and operations of
 	// a transform, including passing to all the `custom_func` and `custom_parse`.
 	CustomParam interface{}
+	// RecordID returns the deterministic ID of the record currently being ingested and transformed.
+	// There is no need for caller of NewTransform to set it: schema handlers that support record IDs
+	// set it on a per-record copy of the Ctx passed to `custom_func` and `custom_parse`; the Ctx
+	// given to NewTransform is never modified.
+	RecordID func() string
+	// Counters contains the counters of the record currently being ingested and transformed. There
+	// is no need for caller of NewTransform to set it: schema handlers that support record counters
+	// set it on the same per-record copy of the Ctx as RecordID.
+	Counters RecordCounters
+	// Transport contains optional transport metadata (AS2 message ID, filename, etc) of the input
+	// stream. It can be referenced by `external` transforms and the `externalProperty` custom func
+	// with names prefixed by TransportPropertyPrefix, e.g. "transport.partner_id", so output records
+	// can carry end-to-end provenance.
+	Transport *Transport
+	// OwnRawBytes, if true, makes RawBytes() of the raw records (see schemahandler.RawBytesRecord)
+	// return copies owned by the caller, which stay valid after subsequent Read calls. Otherwise,
+	// RawBytes() returns READONLY slices into the reader's internal buffer, invalidated by the next
+	// Read call, which avoids the copying when raw bytes are merely inspected.
+	OwnRawBytes bool
+	// RawRecordOnly, if true, makes the Transform only ingest the raw records, without transforming
+	// them, for callers using omniparser's readers as pure parsers: Read returns a nil output for
+	// each record successfully ingested, whose raw record, e.g. its IDR node for `omni.2.1` schemas, is
+	// available from RawRecord. Neither `transform_declarations` nor the schema level transforms, such
+	// as `record_order`, `file_header` and `file_trailer`, are evaluated.
+	RawRecordOnly bool
+	// Validation, if set, gathers the outcome of each record read by the Transform. Fatal errors and
+	// io.EOF aren't records and thus aren't gathered.
+	Validation *ValidationResults
+	// CodeLists contains the code lists referenced by the `codeListLookup` and `codeListValidate`
+	// custom funcs.
+	CodeLists *codelist.Registry
+	// Geo contains the UN/LOCODE locations and airports looked up by the `unLocodeLookup`,
+	// `iataAirportLookup` and `icaoAirportLookup` custom funcs. If nil, the built-in datasets (see
+	// geo.Builtin) are used.
+	Geo *geo.Registry
+	// Calendars contains the business calendars referenced by the `isBusinessDay`, `addBusinessDays` and
+	// `slaDeadline` custom funcs.
+	Calendars *calendar.Registry
+	// Profile contains the behavioral overrides of the trading partner the input stream is from.
+	Profile *Profile
+	// MaxRecordBytes, if positive, is the max number of input bytes a Read call of the Transform can
+	// read, e.g. while looking for the end of a record. Once exceeded, Read fails with a fatal
+	// omniparser.ErrNoProgress with a hex dump of the input where it got stuck, which typically
+	// happens when the delimiters or the line endings of the schema don't match the input.
+	MaxRecordBytes int64
+	// CollectStats, if true, makes the Transform collect the number of invocations and the cumulative
+	// latency of each template and custom func, available from omniparser.StatsReporter. It's off by
+	// default, as timing each invocation isn't free.
+	CollectStats bool
+	// Sampling, if set, makes the Transform transform only a sample of the records of the input, e.g.
+	// to profile the outputs of the mappings of a huge input quickly.
+	Sampling *Sampling
+	// Clock, if set, tells the current time to the custom funcs, such as `now` and `today`, instead of
+	// time.Now. It's overridden by Determinism.
+	Clock Clock
+	// ExternalPropertyDecls contains the external properties declared by the schema. There is no need
+	// for caller of NewTransform to set it: it's set from the schema's `external_properties` on the
+	// transform's own copy of the Ctx, and validated by NewTransform with ValidateExternalProperties.
+	// The declared defaults are looked up by External last.
+	ExternalPropertyDecls []ExternalPropertyDecl
+	// Determinism, if set, pins the values of the nondeterministic custom funcs, such as `now` and
+	// `uuidv4`, so the outputs are byte-identical across runs.
+	Determinism *Determinism
 }
 
-// External looks up, and returns an external property value, if exists.
+// External looks up, and returns an external property value, if exists. If not found in
+// ExternalProperties, it's looked up in the Profile's Properties, then names prefixed by
+// TransportPropertyPrefix are looked up in Transport, and finally in the defaults of the
+// ExternalPropertyDecls.
 func (ctx *Ctx) External(name string) (string, bool) {
-	v, found := ctx.ExternalProperties[name]
-	return v, found
+	if v, found := ctx.ExternalProperties[name]; found {
+		return v, found
+	}
+	if ctx.Profile != nil {
+		if v, found := ctx.Profile.Properties[name]; found {
+			return v, found
+		}
+	}
+	if ctx.Transport != nil && strings.HasPrefix(name, TransportPropertyPrefix) {
+		if v, found := ctx.Transport.lookup(strings.TrimPrefix(name, TransportPropertyPrefix)); found {
+			return v, found
+		}
+	}
+	return ctx.externalDefault(name)
 }
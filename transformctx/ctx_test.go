@@ -1,7 +1,9 @@
 package transformctx
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -50,3 +52,62 @@ func TestCtx_External(t *testing.T) {
 		})
 	}
 }
+
+func TestCtx_External_Transport(t *testing.T) {
+	ctx := &Ctx{
+		ExternalProperties: map[string]string{"transport.filename": "overridden.csv"},
+		Transport: &Transport{
+			Protocol:   "as2",
+			MessageID:  "<msg-1@partner>",
+			Filename:   "orders.csv",
+			ReceivedAt: time.Date(2020, 12, 25, 10, 20, 30, 0, time.UTC),
+			PartnerID:  "ACME",
+			Extra:      map[string]string{"mdn": "signed"},
+		},
+	}
+	for _, test := range []struct {
+		lookup        string
+		expectedValue string
+		expectedFound bool
+	}{
+		{lookup: "transport.protocol", expectedValue: "as2", expectedFound: true},
+		{lookup: "transport.message_id", expectedValue: "<msg-1@partner>", expectedFound: true},
+		{lookup: "transport.filename", expectedValue: "overridden.csv", expectedFound: true},
+		{lookup: "transport.received_at", expectedValue: "2020-12-25T10:20:30Z", expectedFound: true},
+		{lookup: "transport.partner_id", expectedValue: "ACME", expectedFound: true},
+		{lookup: "transport.mdn", expectedValue: "signed", expectedFound: true},
+		{lookup: "transport.unknown", expectedValue: "", expectedFound: false},
+		{lookup: "partner_id", expectedValue: "", expectedFound: false},
+	} {
+		t.Run(test.lookup, func(t *testing.T) {
+			v, found := ctx.External(test.lookup)
+			assert.Equal(t, test.expectedValue, v)
+			assert.Equal(t, test.expectedFound, found)
+		})
+	}
+	// RFC3339, without the fractional seconds.
+	v, found := (&Ctx{Transport: &Transport{
+		ReceivedAt: time.Date(2020, 12, 25, 10, 20, 30, 123456789, time.FixedZone("EST", -5*3600)),
+	}}).External("transport.received_at")
+	assert.Equal(t, "2020-12-25T10:20:30-05:00", v)
+	assert.True(t, found)
+	v, found = (&Ctx{Transport: &Transport{}}).External("transport.received_at")
+	assert.Equal(t, "", v)
+	assert.False(t, found)
+	_, found = (&Ctx{Transport: &Transport{}}).External("transport.message_id")
+	assert.False(t, found)
+}
+
+func TestValidationResults(t *testing.T) {
+	v := &ValidationResults{}
+	v.Add(ValidationResult{Number: 1, ID: "id1", Accepted: true})
+	v.Add(ValidationResult{Number: 2, Error: "bad record"})
+	assert.Equal(t, 2, v.Received)
+	assert.Equal(t, 1, v.Accepted)
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		`{"received":2,"accepted":1,"results":[`+
+			`{"number":1,"id":"id1","accepted":true},{"number":2,"accepted":false,"error":"bad record"}]}`,
+		string(b))
+}
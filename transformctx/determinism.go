@@ -0,0 +1,45 @@
+package transformctx
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Determinism pins the values of the nondeterministic custom funcs, i.e. the current time of `now`, and
+// the pseudo-random values of `uuidv4`, `random`, as well as of `Date` and `Math.random()` in
+// `javascript`, so the outputs of a transform are byte-identical across runs, e.g. for test snapshots
+// and replays of production runs. A Determinism must not be shared by concurrent transforms.
+type Determinism struct {
+	// Now is the current time. If zero, the Unix epoch is used.
+	Now time.Time
+	// Seed seeds the pseudo-random values.
+	Seed int64
+
+	rand   *rand.Rand
+	number int // Counters.Number of the record rand is seeded for.
+}
+
+func (d *Determinism) now() time.Time {
+	if d.Now.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return d.Now
+}
+
+// Rand returns, if Determinism is set, the source of the pseudo-random values of the custom funcs for
+// the current record, seeded from Determinism.Seed and the record's Counters.Number, so the values of a
+// record don't depend on the other records, e.g. when the record is debugged alone. It returns nil
+// otherwise, in which case the custom funcs use nondeterministic sources.
+func (ctx *Ctx) Rand() *rand.Rand {
+	if ctx == nil || ctx.Determinism == nil {
+		return nil
+	}
+	d := ctx.Determinism
+	if d.rand == nil || d.number != ctx.Counters.Number {
+		// mixes the record number into the seed with a large odd multiplier, so the sequences of
+		// consecutive records don't overlap.
+		d.rand = rand.New(rand.NewSource(d.Seed ^ int64(ctx.Counters.Number)*-0x61c8864680b583eb))
+		d.number = ctx.Counters.Number
+	}
+	return d.rand
+}
@@ -0,0 +1,27 @@
+package transformctx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCtx_Rand(t *testing.T) {
+	var nilCtx *Ctx
+	assert.Nil(t, nilCtx.Rand())
+	assert.Nil(t, (&Ctx{}).Rand())
+
+	values := func(d *Determinism, number int) []int64 {
+		ctx := &Ctx{Determinism: d, Counters: RecordCounters{Number: number}}
+		// the same source is used throughout a record.
+		return []int64{ctx.Rand().Int63(), ctx.Rand().Int63()}
+	}
+	d := &Determinism{Seed: 42}
+	record1, record2 := values(d, 1), values(d, 2)
+	assert.NotEqual(t, record1[0], record1[1])
+	assert.NotEqual(t, record1, record2)
+	// the values of a record don't depend on the records before it, or on the run.
+	assert.Equal(t, record2, values(&Determinism{Seed: 42}, 2))
+	assert.Equal(t, record1, values(d, 1))
+	assert.NotEqual(t, record1, values(&Determinism{Seed: 43}, 1))
+}
@@ -0,0 +1,135 @@
+package transformctx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The types of the external properties declared by schemas.
+const (
+	ExternalTypeString  = "string"
+	ExternalTypeInt     = "int"
+	ExternalTypeFloat   = "float"
+	ExternalTypeBoolean = "boolean"
+)
+
+// ExternalPropertyDecl declares an external property a schema depends on, in its `external_properties`,
+// so a transform missing it fails fast at NewTransform, instead of producing empty output fields.
+type ExternalPropertyDecl struct {
+	// Name is the name of the property, as looked up by External.
+	Name string `json:"name"`
+	// Type is the type of the value of the property, one of ExternalTypeString (the default),
+	// ExternalTypeInt, ExternalTypeFloat and ExternalTypeBoolean.
+	Type string `json:"type,omitempty"`
+	// Default, if set, is the value of the property if it isn't set, which makes the property optional.
+	Default *string `json:"default,omitempty"`
+}
+
+// Validate validates the declaration itself, i.e. that its Default, if any, is of its Type.
+func (d ExternalPropertyDecl) Validate() error {
+	if d.Default == nil {
+		return nil
+	}
+	_, err := parseExternal(d.Name, d.Type, *d.Default)
+	return err
+}
+
+func parseExternal(name, typ, v string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+	switch typ {
+	case "", ExternalTypeString:
+		return v, nil
+	case ExternalTypeInt:
+		parsed, err = strconv.ParseInt(v, 10, 64)
+	case ExternalTypeFloat:
+		parsed, err = strconv.ParseFloat(v, 64)
+	case ExternalTypeBoolean:
+		parsed, err = strconv.ParseBool(v)
+	default:
+		return nil, fmt.Errorf("external property '%s' is of unknown type '%s'", name, typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("external property '%s' must be of type '%s', but got '%s'", name, typ, v)
+	}
+	return parsed, nil
+}
+
+func (ctx *Ctx) externalDefault(name string) (string, bool) {
+	for _, decl := range ctx.ExternalPropertyDecls {
+		if decl.Name == name && decl.Default != nil {
+			return *decl.Default, true
+		}
+	}
+	return "", false
+}
+
+// ValidateExternalProperties validates that all the ExternalPropertyDecls without defaults are set, and
+// that all the ones set are of their declared types. The error, if any, lists all the offending
+// properties.
+func (ctx *Ctx) ValidateExternalProperties() error {
+	var msgs []string
+	for _, decl := range ctx.ExternalPropertyDecls {
+		v, found := ctx.External(decl.Name)
+		if !found {
+			msgs = append(msgs, fmt.Sprintf("external property '%s' is required but not set", decl.Name))
+			continue
+		}
+		if _, err := parseExternal(decl.Name, decl.Type, v); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("invalid external properties: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+func (ctx *Ctx) typedExternal(name, typ string) (interface{}, error) {
+	v, found := ctx.External(name)
+	if !found {
+		return nil, fmt.Errorf("external property '%s' is not set", name)
+	}
+	return parseExternal(name, typ, v)
+}
+
+// ExternalString returns the value of an external property, as looked up by External, or an error if
+// it isn't set.
+func (ctx *Ctx) ExternalString(name string) (string, error) {
+	v, err := ctx.typedExternal(name, ExternalTypeString)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// ExternalInt returns the value of an external property, as looked up by External, as an int64, or an
+// error if it isn't set or isn't an integer.
+func (ctx *Ctx) ExternalInt(name string) (int64, error) {
+	v, err := ctx.typedExternal(name, ExternalTypeInt)
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+// ExternalFloat returns the value of an external property, as looked up by External, as a float64, or
+// an error if it isn't set or isn't a number.
+func (ctx *Ctx) ExternalFloat(name string) (float64, error) {
+	v, err := ctx.typedExternal(name, ExternalTypeFloat)
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// ExternalBool returns the value of an external property, as looked up by External, as a bool, or an
+// error if it isn't set or isn't a boolean, e.g. "true", "false", "1" or "0".
+func (ctx *Ctx) ExternalBool(name string) (bool, error) {
+	v, err := ctx.typedExternal(name, ExternalTypeBoolean)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
@@ -0,0 +1,113 @@
+package transformctx
+
+import (
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalPropertyDecl_Validate(t *testing.T) {
+	assert.NoError(t, ExternalPropertyDecl{Name: "a", Type: ExternalTypeInt}.Validate())
+	assert.NoError(t, ExternalPropertyDecl{Name: "a", Default: strs.StrPtr("")}.Validate())
+	assert.NoError(t, ExternalPropertyDecl{Name: "a", Type: ExternalTypeFloat, Default: strs.StrPtr("1.5")}.Validate())
+	err := ExternalPropertyDecl{Name: "a", Type: ExternalTypeBoolean, Default: strs.StrPtr("yes")}.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'a' must be of type 'boolean', but got 'yes'", err.Error())
+	err = ExternalPropertyDecl{Name: "a", Type: "date", Default: strs.StrPtr("2020-01-01")}.Validate()
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'a' is of unknown type 'date'", err.Error())
+}
+
+func TestCtx_ValidateExternalProperties(t *testing.T) {
+	decls := []ExternalPropertyDecl{
+		{Name: "partner_id"},
+		{Name: "batch_size", Type: ExternalTypeInt, Default: strs.StrPtr("100")},
+		{Name: "transport.filename"},
+	}
+	for _, test := range []struct {
+		name string
+		ctx  *Ctx
+		err  string
+	}{
+		{
+			name: "no decls",
+			ctx:  &Ctx{},
+		},
+		{
+			name: "all set",
+			ctx: &Ctx{
+				ExternalPropertyDecls: decls,
+				ExternalProperties:    map[string]string{"partner_id": "ACME", "batch_size": "5"},
+				Transport:             &Transport{Filename: "a.edi"},
+			},
+		},
+		{
+			name: "defaults and profile",
+			ctx: &Ctx{
+				ExternalPropertyDecls: decls,
+				Profile:               &Profile{Properties: map[string]string{"partner_id": "ACME"}},
+				Transport:             &Transport{Filename: "a.edi"},
+			},
+		},
+		{
+			name: "missing and invalid",
+			ctx: &Ctx{
+				ExternalPropertyDecls: decls,
+				ExternalProperties:    map[string]string{"batch_size": "5.5"},
+			},
+			err: "invalid external properties: external property 'partner_id' is required but not set; " +
+				"external property 'batch_size' must be of type 'int', but got '5.5'; " +
+				"external property 'transport.filename' is required but not set",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.ctx.ValidateExternalProperties()
+			if test.err != "" {
+				assert.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCtx_TypedExternals(t *testing.T) {
+	ctx := &Ctx{
+		ExternalProperties: map[string]string{
+			"s": "abc", "i": "-42", "f": "3.25", "b": "true", "bad": "x",
+		},
+		ExternalPropertyDecls: []ExternalPropertyDecl{
+			{Name: "d", Type: ExternalTypeInt, Default: strs.StrPtr("7")},
+		},
+	}
+	s, err := ctx.ExternalString("s")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", s)
+	i, err := ctx.ExternalInt("i")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-42), i)
+	i, err = ctx.ExternalInt("d")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), i)
+	f, err := ctx.ExternalFloat("f")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.25, f)
+	b, err := ctx.ExternalBool("b")
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = ctx.ExternalString("missing")
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'missing' is not set", err.Error())
+	_, err = ctx.ExternalInt("bad")
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'bad' must be of type 'int', but got 'x'", err.Error())
+	_, err = ctx.ExternalFloat("bad")
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'bad' must be of type 'float', but got 'x'", err.Error())
+	_, err = ctx.ExternalBool("bad")
+	assert.Error(t, err)
+	assert.Equal(t, "external property 'bad' must be of type 'boolean', but got 'x'", err.Error())
+}
@@ -0,0 +1,82 @@
+package transformctx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Delimiters contains the delimiter overrides of a Profile. Nil fields keep the ones declared in the
+// schema.
+type Delimiters struct {
+	Segment    *string `json:"segment,omitempty"`
+	Element    *string `json:"element,omitempty"`
+	Component  *string `json:"component,omitempty"`
+	Repetition *string `json:"repetition,omitempty"`
+	Release    *string `json:"release,omitempty"`
+}
+
+// Profile contains the small behavioral differences of a particular trading partner, so that one
+// schema can serve many partners. It's attached to a Ctx at NewTransform time, and consulted by the
+// schema handlers, file formats and custom funcs that support it.
+type Profile struct {
+	// Name is the name of the profile, usually the partner ID.
+	Name string `json:"name,omitempty"`
+	// Delimiters overrides the delimiters declared in the schema, e.g. the EDI `segment_delimiter`.
+	Delimiters *Delimiters `json:"delimiters,omitempty"`
+	// CodeLists maps the code list names referenced in the schema (see CodeLists) to the ones to use
+	// instead, e.g. a partner specific variant of a standard code list.
+	CodeLists map[string]string `json:"code_lists,omitempty"`
+	// Strict, if set, overrides the validation strictness of the schema, e.g. the EDI `strict_isa`.
+	Strict *bool `json:"strict,omitempty"`
+	// Properties contains the external properties of the partner, e.g. the `output_profile` of a
+	// delimited output. They're looked up by External after ExternalProperties.
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// CodeListName returns the name of the code list to use in place of the one referenced in a schema.
+func (p *Profile) CodeListName(name string) string {
+	if p == nil {
+		return name
+	}
+	if mapped, found := p.CodeLists[name]; found {
+		return mapped
+	}
+	return name
+}
+
+func (p *Profile) validate() error {
+	if p == nil {
+		return errors.New("profile is empty")
+	}
+	if d := p.Delimiters; d != nil {
+		for name, delim := range map[string]*string{"segment": d.Segment, "element": d.Element} {
+			if delim != nil && *delim == "" {
+				return fmt.Errorf("'delimiters.%s' must not be empty", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Profiles is a collection of Profile keyed by partner.
+type Profiles map[string]*Profile
+
+// LoadProfiles reads in and validates Profiles from a JSON input. Profiles without a name are named
+// after their keys.
+func LoadProfiles(r io.Reader) (Profiles, error) {
+	var profiles Profiles
+	if err := json.NewDecoder(r).Decode(&profiles); err != nil {
+		return nil, err
+	}
+	for name, p := range profiles {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("profile '%s': %s", name, err.Error())
+		}
+		if p.Name == "" {
+			p.Name = name
+		}
+	}
+	return profiles, nil
+}
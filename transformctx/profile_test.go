@@ -0,0 +1,76 @@
+package transformctx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf-tech/go-corelib/strs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	profiles, err := LoadProfiles(strings.NewReader(`{
+		"acme": {
+			"delimiters": { "segment": "\n", "component": ">" },
+			"code_lists": { "x12.entity_identifier_code": "acme.entity_identifier_code" },
+			"strict": true,
+			"properties": { "output_profile": "acme_csv" }
+		},
+		"globex": { "name": "Globex Corp" }
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(profiles))
+	acme := profiles["acme"]
+	assert.Equal(t, "acme", acme.Name)
+	assert.Equal(t, &Delimiters{Segment: strs.StrPtr("\n"), Component: strs.StrPtr(">")}, acme.Delimiters)
+	assert.True(t, *acme.Strict)
+	assert.Equal(t, "Globex Corp", profiles["globex"].Name)
+	assert.Nil(t, profiles["globex"].Strict)
+}
+
+func TestLoadProfiles_Failure(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "invalid json", input: `[`, err: "unexpected EOF"},
+		{name: "null profile", input: `{ "acme": null }`, err: "profile 'acme': profile is empty"},
+		{
+			name:  "empty element delimiter",
+			input: `{ "acme": { "delimiters": { "element": "" } } }`,
+			err:   "profile 'acme': 'delimiters.element' must not be empty",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			profiles, err := LoadProfiles(strings.NewReader(test.input))
+			assert.Error(t, err)
+			assert.Equal(t, test.err, err.Error())
+			assert.Nil(t, profiles)
+		})
+	}
+}
+
+func TestProfile_CodeListName(t *testing.T) {
+	var nilProfile *Profile
+	assert.Equal(t, "a", nilProfile.CodeListName("a"))
+	p := &Profile{CodeLists: map[string]string{"a": "acme.a"}}
+	assert.Equal(t, "acme.a", p.CodeListName("a"))
+	assert.Equal(t, "b", p.CodeListName("b"))
+}
+
+func TestCtx_External_Profile(t *testing.T) {
+	ctx := &Ctx{
+		ExternalProperties: map[string]string{"a": "external"},
+		Profile: &Profile{Properties: map[string]string{
+			"a": "profile", "b": "profile", "transport.partner_id": "profile"}},
+		Transport: &Transport{PartnerID: "transport"},
+	}
+	for name, expected := range map[string]string{"a": "external", "b": "profile", "transport.partner_id": "profile"} {
+		v, found := ctx.External(name)
+		assert.True(t, found, name)
+		assert.Equal(t, expected, v, name)
+	}
+	_, found := ctx.External("c")
+	assert.False(t, found)
+}
@@ -0,0 +1,213 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+)
+
+// ErrValidationFailed is returned by the first Read call of a Transform of a schema with
+// 'parser_settings.validate_first' set, if any record of the input fails, in which case no record is
+// returned at all. This is a fatal error: future calls to Read will always return the same error.
+type ErrValidationFailed struct {
+	// Records is the number of records read, successfully or not, i.e. all the records of the input.
+	Records int
+	// Failed is the number of failed records.
+	Failed int
+	// FirstErrors are the messages of the first few errors of the failed records.
+	FirstErrors []string
+}
+
+// Error implements the error interface.
+func (e ErrValidationFailed) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "validation failed: %d of %d records failed", e.Failed, e.Records)
+	if len(e.FirstErrors) > 0 {
+		fmt.Fprintf(&sb, "; first errors: %s", strings.Join(e.FirstErrors, "; "))
+	}
+	return sb.String()
+}
+
+// IsErrValidationFailed tells if an error is of ErrValidationFailed, or wraps one.
+func IsErrValidationFailed(err error) bool {
+	var e ErrValidationFailed
+	return errors.As(err, &e)
+}
+
+// validatedTransform is the Transform of a schema with 'parser_settings.validate_first' set. Upon the
+// first Read call, it reads the whole input in a validation pass, keeping the transformed records,
+// then, if no record failed, returns the kept records in an output pass, so the input is parsed and
+// transformed once. Its Listeners receive the events of the output pass, i.e. no OnRecordEnd unless the
+// whole input is valid. Preview isn't subject to the validation pass.
+type validatedTransform struct {
+	Transform
+	validated bool
+	records   []validatedRecord
+	seq       int
+	emitted   int // number of records returned by the output pass.
+	current   schemahandler.RawRecord
+	err       error // ErrValidationFailed, fatal error or io.EOF.
+	listeners []Listener
+	summary   *TransformSummary // the summary upon the validation failure, if any.
+}
+
+type validatedRecord struct {
+	transformed []byte
+	rawRecord   schemahandler.RawRecord
+}
+
+// validatedRawRecord is what the validation pass keeps of a raw record, as the raw records, e.g. their
+// IDR, are released once the next one is read: Raw returns nil.
+type validatedRawRecord struct {
+	checksum string
+	recordID string
+}
+
+func (r *validatedRawRecord) Raw() interface{} { return nil }
+
+func (r *validatedRawRecord) Checksum() string { return r.checksum }
+
+// RecordID implements schemahandler.RecordIDer. It's empty if the schema handler doesn't provide
+// record IDs.
+func (r *validatedRawRecord) RecordID() string { return r.recordID }
+
+func newValidatedRawRecord(rawRecord schemahandler.RawRecord) schemahandler.RawRecord {
+	if rawRecord == nil {
+		return nil
+	}
+	r := &validatedRawRecord{checksum: rawRecord.Checksum()}
+	if ider, ok := rawRecord.(schemahandler.RecordIDer); ok {
+		r.recordID = ider.RecordID()
+	}
+	return r
+}
+
+// Read implements Transform.Read.
+func (v *validatedTransform) Read() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	v.seq++
+	for _, l := range v.listeners {
+		l.OnRecordStart(v.seq)
+	}
+	if !v.validated {
+		v.validated = true
+		if err := v.validate(); err != nil {
+			return nil, v.fail(err)
+		}
+	}
+	if v.seq > len(v.records) {
+		v.err, v.records, v.current = io.EOF, nil, nil
+		for _, l := range v.listeners {
+			l.OnEOF(v.Stats())
+		}
+		return nil, io.EOF
+	}
+	record := v.records[v.seq-1]
+	v.records[v.seq-1] = validatedRecord{}
+	v.current = record.rawRecord
+	v.emitted++
+	for _, l := range v.listeners {
+		l.OnRecordEnd(v.seq, record.rawRecord, record.transformed)
+	}
+	return record.transformed, nil
+}
+
+// validate reads the whole input, keeping the transformed records, and returns an ErrValidationFailed
+// if any record failed, or the fatal error, if any.
+func (v *validatedTransform) validate() error {
+	var failed int
+	var firstErrors []string
+	for {
+		transformed, err := v.Transform.Read()
+		switch {
+		case err == nil:
+			rawRecord, _ := v.Transform.RawRecord()
+			v.records = append(v.records, validatedRecord{
+				transformed: transformed,
+				rawRecord:   newValidatedRawRecord(rawRecord),
+			})
+		case errs.IsErrTransformFailed(err):
+			failed++
+			if len(firstErrors) < maxFirstErrors {
+				firstErrors = append(firstErrors, err.Error())
+			}
+		case err == io.EOF:
+			if failed > 0 {
+				return ErrValidationFailed{
+					Records:     len(v.records) + failed,
+					Failed:      failed,
+					FirstErrors: firstErrors,
+				}
+			}
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+func (v *validatedTransform) fail(err error) error {
+	v.err, v.records = err, nil
+	if IsErrValidationFailed(err) {
+		summary := summaryOf(v.Transform)
+		summary.Error = err.Error()
+		if summary.ErrorCounts == nil {
+			summary.ErrorCounts = map[string]int{}
+		}
+		summary.ErrorCounts[ErrCodeFatal]++
+		v.summary = &summary
+	}
+	for _, l := range v.listeners {
+		l.OnError(v.seq, err)
+	}
+	return err
+}
+
+// RawRecord implements Transform.RawRecord. The raw records returned are the ones kept by the
+// validation pass, whose Raw is nil.
+func (v *validatedTransform) RawRecord() (schemahandler.RawRecord, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	if v.current == nil {
+		return nil, errors.New("must call Read first")
+	}
+	return v.current, nil
+}
+
+// AddListener implements Listenable.
+func (v *validatedTransform) AddListener(l Listener) {
+	v.listeners = append(v.listeners, l)
+}
+
+// Stats implements StatsReporter. Emitted is the number of records returned by the output pass so
+// far.
+func (v *validatedTransform) Stats() TransformStats {
+	stats := statsOf(v.Transform)
+	stats.Emitted = v.emitted
+	return stats
+}
+
+// Summary implements SummaryReporter. It's the summary of the validation pass, which is only final
+// once Read has returned io.EOF or a fatal error.
+func (v *validatedTransform) Summary() TransformSummary {
+	if v.summary != nil {
+		return *v.summary
+	}
+	summary := summaryOf(v.Transform)
+	if v.err == nil {
+		summary.Done = false
+	}
+	return summary
+}
+
+// Preview implements Previewer, previewing the wrapped Transform, not subject to the validation pass.
+func (v *validatedTransform) Preview(n int) (*Preview, error) {
+	return previewOf(v.Transform, n)
+}
@@ -0,0 +1,141 @@
+package omniparser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/logward/omniparser/errs"
+	"github.com/logward/omniparser/schemahandler"
+	"github.com/logward/omniparser/transformctx"
+)
+
+func newValidateFirstTestSchema(t *testing.T) Schema {
+	s, err := NewSchema("test-schema", strings.NewReader(`{
+		"parser_settings": { "version": "omni.2.1", "file_format_type": "xml", "validate_first": true },
+		"transform_declarations": {
+			"FINAL_OUTPUT": { "xpath": "/a/b", "object": {
+				"c": { "custom_func": { "name": "dateTimeToRFC3339", "args": [ { "xpath": "c" }, { "const": "" }, { "const": "" } ] } }
+			} }
+		}
+	}`))
+	assert.NoError(t, err)
+	return s
+}
+
+func TestValidateFirst_Valid(t *testing.T) {
+	tfm, err := newValidateFirstTestSchema(t).NewTransform("test-input",
+		strings.NewReader(`<a><b><c>2020-01-01</c></b><b><c>2020-01-02</c></b></a>`), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	l := &testListener{}
+	tfm.(Listenable).AddListener(l)
+	_, err = tfm.RawRecord()
+	assert.Equal(t, "must call Read first", err.Error())
+	var records []string
+	for {
+		record, err := tfm.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		records = append(records, string(record))
+		raw, err := tfm.RawRecord()
+		assert.NoError(t, err)
+		assert.Nil(t, raw.Raw())
+		assert.NotEmpty(t, raw.Checksum())
+		assert.NotEmpty(t, raw.(schemahandler.RecordIDer).RecordID())
+		assert.False(t, tfm.(SummaryReporter).Summary().Done)
+	}
+	assert.Equal(t, []string{`{"c":"2020-01-01T00:00:00"}`, `{"c":"2020-01-02T00:00:00"}`}, records)
+	assert.Equal(t, []string{
+		`start 1`, `end 1 %!s(<nil>): {"c":"2020-01-01T00:00:00"}`,
+		`start 2`, `end 2 %!s(<nil>): {"c":"2020-01-02T00:00:00"}`,
+		`start 3`, `eof {Emitted:2 Skipped:0}`,
+	}, l.events)
+	summary := tfm.(SummaryReporter).Summary()
+	assert.True(t, summary.Done)
+	assert.Equal(t, 2, summary.RecordsOut)
+	_, err = tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	_, err = tfm.RawRecord()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestValidateFirst_Invalid(t *testing.T) {
+	tfm, err := newValidateFirstTestSchema(t).NewTransform(
+		"test-input", strings.NewReader(limitsTestInput), &transformctx.Ctx{})
+	assert.NoError(t, err)
+	l := &testListener{}
+	tfm.(Listenable).AddListener(l)
+	for i := 0; i < 2; i++ {
+		record, err := tfm.Read()
+		assert.Error(t, err)
+		assert.True(t, IsErrValidationFailed(err))
+		assert.True(t, strings.HasPrefix(err.Error(), "validation failed: 1 of 5 records failed; first errors: "))
+		assert.Nil(t, record)
+		assert.Len(t, err.(ErrValidationFailed).FirstErrors, 1)
+	}
+	_, err = tfm.RawRecord()
+	assert.True(t, IsErrValidationFailed(err))
+	assert.Equal(t, 0, tfm.(StatsReporter).Stats().Emitted)
+	assert.Equal(t, 1, tfm.(StatsReporter).Stats().Skipped)
+	summary := tfm.(SummaryReporter).Summary()
+	assert.True(t, summary.Done)
+	assert.True(t, strings.HasPrefix(summary.Error, "validation failed: "))
+	assert.Equal(t, 1, summary.ErrorCounts[ErrCodeFatal])
+	assert.Len(t, l.events, 2)
+	assert.Equal(t, "start 1", l.events[0])
+	assert.True(t, strings.HasPrefix(l.events[1], "error 1: validation failed: "))
+}
+
+func TestValidateFirst_FirstErrors(t *testing.T) {
+	continuableErr := errors.New("continuable error")
+	readCalls := []testReadCall{{result: []byte("1st good read")}}
+	for i := 0; i < 4; i++ {
+		readCalls = append(readCalls, testReadCall{err: continuableErr})
+	}
+	readCalls = append(readCalls, testReadCall{err: io.EOF})
+	tfm := &validatedTransform{Transform: &transform{ingester: &testIngester{
+		readCalls:       readCalls,
+		continuableErrs: map[error]bool{continuableErr: true},
+	}}}
+	_, err := tfm.Read()
+	assert.Equal(t,
+		"validation failed: 4 of 5 records failed; first errors: "+
+			"continuable error; continuable error; continuable error",
+		err.Error())
+}
+
+func TestValidateFirst_FatalError(t *testing.T) {
+	tfm := &validatedTransform{Transform: &transform{ingester: &testIngester{
+		readCalls: []testReadCall{{result: []byte("1st good read")}, {err: errors.New("fatal error")}},
+	}}}
+	for i := 0; i < 2; i++ {
+		record, err := tfm.Read()
+		assert.Error(t, err)
+		assert.Equal(t, "fatal error", err.Error())
+		assert.False(t, IsErrValidationFailed(err))
+		assert.Nil(t, record)
+	}
+	assert.Equal(t, "fatal error", tfm.Summary().Error)
+}
+
+func TestValidateFirst_Empty(t *testing.T) {
+	tfm := &validatedTransform{Transform: &transform{ingester: &testIngester{
+		readCalls: []testReadCall{{err: io.EOF}},
+	}}}
+	record, err := tfm.Read()
+	assert.Equal(t, io.EOF, err)
+	assert.Nil(t, record)
+	assert.True(t, tfm.Summary().Done)
+}
+
+func TestIsErrValidationFailed(t *testing.T) {
+	assert.True(t, IsErrValidationFailed(ErrValidationFailed{}))
+	assert.True(t, IsErrValidationFailed(fmt.Errorf("input 'test-input': %w", ErrValidationFailed{})))
+	assert.False(t, IsErrValidationFailed(errs.ErrTransformFailed("test")))
+}
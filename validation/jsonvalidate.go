@@ -10,8 +10,43 @@ import (
 	"github.com/xeipuuv/gojsonschema"
 )
 
+// Violation describes a single violation of a JSON schema.
+type Violation struct {
+	// Field is the path to the violating value, with object keys and array indexes delimited by
+	// ".", e.g. "transform_declarations.FINAL_OUTPUT.object", or "(root)" for the root value.
+	Field string
+	// Type is the kind of the violation, e.g. "required", "enum", "additional_property_not_allowed".
+	Type        string
+	Description string
+	// Details contains the violation specific parameters, e.g. "property" for "required".
+	Details map[string]interface{}
+	msg     string
+}
+
+// String returns the violation formatted as "<field>: <description>".
+func (v Violation) String() string { return v.msg }
+
+// ErrValidation is the error returned by SchemaValidate when a schema violates its JSON schema.
+type ErrValidation struct {
+	SchemaName string
+	Violations []Violation // sorted by their String().
+}
+
+// Error implements error interface.
+func (e *ErrValidation) Error() string {
+	if len(e.Violations) == 1 {
+		return fmt.Sprintf("schema '%s' validation failed: %s", e.SchemaName, e.Violations[0])
+	}
+	var msgs []string
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.String())
+	}
+	return fmt.Sprintf("schema '%s' validation failed:\n%s", e.SchemaName, strings.Join(msgs, "\n"))
+}
+
 // SchemaValidate validates a schema based on its JSON schema. Any validation error, if
-// present, is context formatted, i.e. schema name is prefixed in the error msg.
+// present, is context formatted, i.e. schema name is prefixed in the error msg. If the
+// schema violates the JSON schema, the error is an *ErrValidation.
 func SchemaValidate(schemaName string, schemaContent []byte, jsonSchema string) error {
 	jsonSchemaLoader := gojsonschema.NewStringLoader(jsonSchema)
 	targetSchemaLoader := gojsonschema.NewBytesLoader(schemaContent)
@@ -22,13 +57,16 @@ func SchemaValidate(schemaName string, schemaContent []byte, jsonSchema string)
 	if result.Valid() {
 		return nil
 	}
-	var errs []string
+	var violations []Violation
 	for _, err := range result.Errors() {
-		errs = append(errs, err.String())
-	}
-	sort.Strings(errs)
-	if len(errs) == 1 {
-		return fmt.Errorf("schema '%s' validation failed: %s", schemaName, errs[0])
+		violations = append(violations, Violation{
+			Field:       err.Field(),
+			Type:        err.Type(),
+			Description: err.Description(),
+			Details:     err.Details(),
+			msg:         err.String(),
+		})
 	}
-	return fmt.Errorf("schema '%s' validation failed:\n%s", schemaName, strings.Join(errs, "\n"))
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].msg < violations[j].msg })
+	return &ErrValidation{SchemaName: schemaName, Violations: violations}
 }
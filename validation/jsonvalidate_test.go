@@ -46,7 +46,7 @@ func TestSchemaValidate(t *testing.T) {
 						"encoding": "invalid"
 					}
 				}`,
-			expectedErr: `schema 'test-schema' validation failed: parser_settings.encoding: parser_settings.encoding must be one of the following: "utf-8", "iso-8859-1", "windows-1252"`,
+			expectedErr: `schema 'test-schema' validation failed: parser_settings.encoding: parser_settings.encoding must be one of the following: "utf-8", "iso-8859-1", "windows-1252", "utf-16le", "utf-16be", "auto"`,
 		},
 		{
 			name:       "multiple errors",
@@ -65,9 +65,37 @@ func TestSchemaValidate(t *testing.T) {
 			if test.expectedErr != "" {
 				assert.Error(t, err)
 				assert.Equal(t, test.expectedErr, err.Error())
+				if ev, ok := err.(*ErrValidation); ok {
+					assert.Equal(t, "test-schema", ev.SchemaName)
+					for _, v := range ev.Violations {
+						assert.Equal(t, v.Field+": "+v.Description, v.String())
+					}
+				}
 			} else {
 				assert.NoError(t, err)
 			}
 		})
 	}
 }
+
+func TestSchemaValidate_Violations(t *testing.T) {
+	err := SchemaValidate("test-schema", []byte(`{
+			"parser_settings": {
+				"version": "test-version",
+				"encoding": "invalid",
+				"unknown": "blah"
+			}
+		}`), JSONSchemaParserSettings)
+	assert.Error(t, err)
+	ev, ok := err.(*ErrValidation)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(ev.Violations))
+	assert.Equal(t, "parser_settings.encoding", ev.Violations[0].Field)
+	assert.Equal(t, "enum", ev.Violations[0].Type)
+	assert.Equal(t, "parser_settings", ev.Violations[1].Field)
+	assert.Equal(t, "additional_property_not_allowed", ev.Violations[1].Type)
+	assert.Equal(t, "unknown", ev.Violations[1].Details["property"])
+	assert.Equal(t, "parser_settings", ev.Violations[2].Field)
+	assert.Equal(t, "required", ev.Violations[2].Type)
+	assert.Equal(t, "file_format_type", ev.Violations[2].Details["property"])
+}
@@ -18,11 +18,31 @@ const (
                 "file_format_type": { "type": "string" },
                 "encoding": {
                     "type": "string",
-                    "enum": [ "utf-8", "iso-8859-1", "windows-1252" ]
-                }
+                    "enum": [ "utf-8", "iso-8859-1", "windows-1252", "utf-16le", "utf-16be", "auto" ]
+                },
+                "index_records": { "type": "boolean" },
+                "invalid_utf8": {
+                    "type": "string",
+                    "enum": [ "replace", "error", "bytes" ]
+                },
+                "error_dump_bytes": { "type": "integer", "minimum": 1, "maximum": 4096 },
+                "validate_first": { "type": "boolean" }
             },
             "required": [ "version", "file_format_type" ],
             "additionalProperties": false
+        },
+        "external_properties": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "name": { "type": "string", "minLength": 1 },
+                    "type": { "type": "string", "enum": [ "string", "int", "float", "boolean" ] },
+                    "default": { "type": "string" }
+                },
+                "required": [ "name" ],
+                "additionalProperties": false
+            }
         }
     },
     "required": [ "parser_settings" ]